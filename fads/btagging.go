@@ -6,13 +6,10 @@ package fads
 
 import (
 	"math"
-	"math/rand/v2"
 	"reflect"
-	"sync"
 
 	"go-hep.org/x/hep/fmom"
 	"go-hep.org/x/hep/fwk"
-	"gonum.org/v1/gonum/stat/distuv"
 )
 
 type btagclassifier struct {
@@ -49,19 +46,17 @@ type BTagging struct {
 	btag btagclassifier
 	eff  map[int]func(pt, eta float64) float64
 
-	seed uint64
-	src  *rand.Rand
-
-	flat   distuv.Uniform
-	flatmu sync.Mutex
+	rnd randsrc
 }
 
 func (tsk *BTagging) Configure(ctx fwk.Context) error {
 	var err error
 
-	err = tsk.DeclInPort(tsk.partons, reflect.TypeOf([]Candidate{}))
-	if err != nil {
-		return err
+	if tsk.partons != "" {
+		err = tsk.DeclInPort(tsk.partons, reflect.TypeOf([]Candidate{}))
+		if err != nil {
+			return err
+		}
 	}
 
 	err = tsk.DeclInPort(tsk.jets, reflect.TypeOf([]Candidate{}))
@@ -74,14 +69,12 @@ func (tsk *BTagging) Configure(ctx fwk.Context) error {
 		return err
 	}
 
-	tsk.src = rand.New(rand.NewPCG(tsk.seed, tsk.seed))
-	tsk.flat = distuv.Uniform{Min: 0, Max: 1, Src: tsk.src}
 	return err
 }
 
 func (tsk *BTagging) StartTask(ctx fwk.Context) error {
 	var err error
-
+	err = tsk.rnd.start(ctx)
 	return err
 }
 
@@ -97,14 +90,16 @@ func (tsk *BTagging) Process(ctx fwk.Context) error {
 	store := ctx.Store()
 	msg := ctx.Msg()
 
-	v, err := store.Get(tsk.partons)
-	if err != nil {
-		return err
+	var allpartons []Candidate
+	if tsk.partons != "" {
+		v, err := store.Get(tsk.partons)
+		if err != nil {
+			return err
+		}
+		allpartons = v.([]Candidate)
 	}
 
-	allpartons := v.([]Candidate)
-
-	v, err = store.Get(tsk.jets)
+	v, err := store.Get(tsk.jets)
 	if err != nil {
 		return err
 	}
@@ -112,9 +107,15 @@ func (tsk *BTagging) Process(ctx fwk.Context) error {
 
 	output := make([]Candidate, 0, len(jets))
 	defer func() {
-		err = store.Put(tsk.output, jets)
+		err = store.Put(tsk.output, output)
 	}()
 
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	msg.Debugf("partons: %d\n", len(allpartons))
 	msg.Debugf("jets:    %d\n", len(jets))
 
@@ -133,6 +134,18 @@ func (tsk *BTagging) Process(ctx fwk.Context) error {
 		eta := jet.Mom.Eta()
 		pt := jet.Mom.Pt()
 
+		if tsk.partons == "" {
+			// use the flavour assigned by JetFlavourAssociation.
+			switch jet.Flavour {
+			case 0:
+				pdgmax = -1
+			case 21:
+				pdgmax = 0
+			default:
+				pdgmax = int(jet.Flavour)
+			}
+		}
+
 		for j := range partons {
 			p := &partons[j]
 			pdg := int(p.Pid)
@@ -163,11 +176,9 @@ func (tsk *BTagging) Process(ctx fwk.Context) error {
 
 		// apply efficiency
 		tag := uint32(0)
-		tsk.flatmu.Lock()
-		if tsk.flat.Rand() <= eff(pt, eta) {
+		if src.Float64() <= eff(pt, eta) {
 			tag = 1
 		}
-		tsk.flatmu.Unlock()
 		jet.BTag |= tag << tsk.bit
 
 		output = append(output, *jet)
@@ -196,7 +207,7 @@ func newBTagging(typ, name string, mgr fwk.App) (fwk.Component, error) {
 			0: func(pt, eta float64) float64 { return 0 },
 		},
 
-		seed: 1234,
+		rnd: newRandSrc(1234),
 	}
 
 	err = tsk.DeclProp("Partons", &tsk.partons)
@@ -239,7 +250,12 @@ func newBTagging(typ, name string, mgr fwk.App) (fwk.Component, error) {
 		return nil, err
 	}
 
-	err = tsk.DeclProp("Seed", &tsk.seed)
+	err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
 	if err != nil {
 		return nil, err
 	}
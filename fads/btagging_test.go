@@ -0,0 +1,86 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"testing"
+)
+
+func TestBTagging(t *testing.T) {
+	parton := func(pid int32, eta, phi float64) Candidate {
+		c := newTrack(50, eta, phi)
+		c.Pid = pid
+		return c
+	}
+	jet := func(flavour int32, eta, phi float64) Candidate {
+		c := newTrack(50, eta, phi)
+		c.Flavour = flavour
+		return c
+	}
+
+	for _, tc := range []struct {
+		name    string
+		partons []Candidate // nil to use the flavour of the jets
+		jets    []Candidate
+		want    []uint32 // expected b-tag of the output jets
+	}{
+		{
+			name:    "partons",
+			partons: []Candidate{parton(5, 0, 0), parton(21, 1, 2)},
+			jets:    []Candidate{jet(0, 0.1, 0), jet(0, 1, 2), jet(0, -1, -2)},
+			want:    []uint32{1 << 3, 0, 0},
+		},
+		{
+			name: "flavours",
+			jets: []Candidate{jet(5, 0, 0), jet(4, 1, 2), jet(21, -1, -2)},
+			want: []uint32{1 << 3, 0, 0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := &BTagging{
+				jets:   "jets",
+				output: "output",
+				dR:     0.5,
+				bit:    3,
+				btag:   btagclassifier{PtMin: 1, EtaMax: 2.5},
+				eff: map[int]func(pt, eta float64) float64{
+					0: func(pt, eta float64) float64 { return 0 },
+					5: func(pt, eta float64) float64 { return 1 },
+				},
+				rnd: newRandSrc(1234),
+			}
+
+			ctx := newTestContext()
+			if tc.partons != nil {
+				tsk.partons = "partons"
+				ctx.store["partons"] = tc.partons
+			}
+			err := tsk.StartTask(ctx)
+			if err != nil {
+				t.Fatalf("could not start task: %+v", err)
+			}
+
+			ctx.store["jets"] = tc.jets
+			err = tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			output := ctx.store["output"].([]Candidate)
+			if got, want := len(output), len(tc.want); got != want {
+				t.Fatalf("invalid number of jets: got=%d, want=%d", got, want)
+			}
+			for i := range output {
+				// the tagged jets are stored, not the input ones.
+				if got, want := output[i].BTag, tc.want[i]; got != want {
+					t.Fatalf("invalid b-tag for jet %d: got=0x%x, want=0x%x", i, got, want)
+				}
+				if got := tc.jets[i].BTag; got != 0 {
+					t.Fatalf("input jet %d was modified: b-tag=0x%x", i, got)
+				}
+			}
+		})
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"math/rand/v2"
 	"reflect"
 	"sort"
-	"sync"
 
 	"go-hep.org/x/hep/fwk"
 	"gonum.org/v1/gonum/stat/distuv"
@@ -166,11 +165,7 @@ type Calorimeter struct {
 	eflowtracks string
 	eflowtowers string
 
-	seed uint64
-	src  *rand.Rand
-
-	gauss distuv.Normal
-	dmu   sync.Mutex
+	rnd randsrc
 }
 
 func (tsk *Calorimeter) Configure(ctx fwk.Context) error {
@@ -206,14 +201,12 @@ func (tsk *Calorimeter) Configure(ctx fwk.Context) error {
 		return err
 	}
 
-	tsk.src = rand.New(rand.NewPCG(tsk.seed, tsk.seed))
-	tsk.gauss = distuv.Normal{Mu: 0, Sigma: 1, Src: tsk.src}
 	return err
 }
 
 func (tsk *Calorimeter) StartTask(ctx fwk.Context) error {
 	var err error
-
+	err = tsk.rnd.start(ctx)
 	return err
 }
 
@@ -264,6 +257,12 @@ func (tsk *Calorimeter) Process(ctx fwk.Context) error {
 		err = store.Put(tsk.eflowtowers, eflowtowers)
 	}()
 
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	hits := make(map[int64][]int64)
 	twrecal := make([]float64, 0, len(parts))
 	twrhcal := make([]float64, 0, len(parts))
@@ -332,7 +331,9 @@ func (tsk *Calorimeter) Process(ctx fwk.Context) error {
 		trkhcal = append(trkhcal, frac.HCal)
 
 		if frac.ECal < 1e-9 && frac.HCal < 1e-9 {
-			// msg.Debugf("track[%d]= not enough ECal|HCal\n", i)
+			// tracks not seen by the calorimeters, e.g. muons,
+			// are energy-flow tracks on their own.
+			eflowtracks = append(eflowtracks, *track)
 			continue
 		}
 
@@ -397,7 +398,7 @@ func (tsk *Calorimeter) Process(ctx fwk.Context) error {
 		var (
 			calotrk = caloTrack{}
 			tower   Candidate
-			//twrtrks = make([]Candidate, 0, len(tracks)) // FIXME(sbinet)
+			twrtrks []Candidate
 		)
 
 		for _, hit := range hits[towerid] {
@@ -415,7 +416,7 @@ func (tsk *Calorimeter) Process(ctx fwk.Context) error {
 				)
 				calotrk.ECal.Add(ene*trkecal[n], t)
 				calotrk.HCal.Add(ene*trkhcal[n], t)
-				//twrtrks = append(twrtrks, *track) // FIXME(sbinet)
+				twrtrks = append(twrtrks, *track)
 
 			default:
 				if (flags & 2) != 0 { // photon hits
@@ -435,14 +436,14 @@ func (tsk *Calorimeter) Process(ctx fwk.Context) error {
 		}
 
 		ecalSigma := tsk.ecalres(calotower.Eta, calotower.ECal.Ene)
-		ecalEne := tsk.lognormal(calotower.ECal.Ene, ecalSigma)
+		ecalEne := lognormal(src, calotower.ECal.Ene, ecalSigma)
 		ecalTime := 0.0
 		if calotower.ECal.WeightTime >= 1e-9 {
 			ecalTime = calotower.ECal.Time / calotower.ECal.WeightTime
 		}
 
 		hcalSigma := tsk.hcalres(calotower.Eta, calotower.HCal.Ene)
-		hcalEne := tsk.lognormal(calotower.HCal.Ene, hcalSigma)
+		hcalEne := lognormal(src, calotower.HCal.Ene, hcalSigma)
 		hcalTime := 0.0
 		if calotower.HCal.WeightTime >= 1e-9 {
 			hcalTime = calotower.HCal.Time / calotower.HCal.WeightTime
@@ -453,8 +454,8 @@ func (tsk *Calorimeter) Process(ctx fwk.Context) error {
 		hsqrt := math.Sqrt(hcalEne)
 		time := (esqrt*ecalTime + hsqrt*hcalTime) / (esqrt + hsqrt)
 
-		eta = rand.Float64()*(calotower.Edges[1]-calotower.Edges[0]) + calotower.Edges[0]
-		phi = rand.Float64()*(calotower.Edges[3]-calotower.Edges[2]) + calotower.Edges[2]
+		eta = src.Float64()*(calotower.Edges[1]-calotower.Edges[0]) + calotower.Edges[0]
+		phi = src.Float64()*(calotower.Edges[3]-calotower.Edges[2]) + calotower.Edges[2]
 
 		pt := ene / math.Cosh(eta)
 
@@ -471,12 +472,29 @@ func (tsk *Calorimeter) Process(ctx fwk.Context) error {
 			}
 			towers = append(towers, tower)
 		}
+
+		// fill energy-flow candidates
+		eflowtracks = append(eflowtracks, twrtrks...)
+
+		// save ECal and/or HCal energy excess as an energy-flow tower
+		ecalEne = math.Max(ecalEne-calotrk.ECal.Ene, 0)
+		hcalEne = math.Max(hcalEne-calotrk.HCal.Ene, 0)
+		ene = ecalEne + hcalEne
+		if ene > 0 {
+			eflow := tower
+			eflow.Mom = newPtEtaPhiE(ene/math.Cosh(eta), eta, phi, ene)
+			eflow.Eem = ecalEne
+			eflow.Ehad = hcalEne
+			eflowtowers = append(eflowtowers, eflow)
+		}
 	}
 
 	return err
 }
 
-func (tsk *Calorimeter) lognormal(mean, sigma float64) float64 {
+// lognormal returns a number drawn from the log-normal distribution
+// of the provided mean and standard deviation.
+func lognormal(src *rand.Rand, mean, sigma float64) float64 {
 	if mean <= 0 {
 		return 0
 	}
@@ -484,10 +502,8 @@ func (tsk *Calorimeter) lognormal(mean, sigma float64) float64 {
 	b := math.Sqrt(math.Log(1 + (sigma*sigma)/(mean*mean)))
 	a := math.Log(mean) - 0.5*b*b
 
-	tsk.dmu.Lock()
-	bgauss := tsk.gauss.Rand()
-	tsk.dmu.Unlock()
-	return math.Exp(a + bgauss)
+	gauss := distuv.Normal{Mu: 0, Sigma: b, Src: src}
+	return math.Exp(a + gauss.Rand())
 }
 
 func newCalorimeter(typ, name string, mgr fwk.App) (fwk.Component, error) {
@@ -508,7 +524,7 @@ func newCalorimeter(typ, name string, mgr fwk.App) (fwk.Component, error) {
 		eflowtracks: "/fads/eflowtracks",
 		eflowtowers: "/fads/eflowtowers",
 
-		seed: 1234,
+		rnd: newRandSrc(1234),
 	}
 
 	// --
@@ -565,7 +581,12 @@ func newCalorimeter(typ, name string, mgr fwk.App) (fwk.Component, error) {
 		return nil, err
 	}
 
-	err = tsk.DeclProp("Seed", &tsk.seed)
+	err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
 	if err != nil {
 		return nil, err
 	}
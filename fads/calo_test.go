@@ -0,0 +1,109 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalorimeter(t *testing.T) {
+	particle := func(pid int32, ene, eta, phi float64) Candidate {
+		c := newTrack(ene/math.Cosh(eta), eta, phi)
+		c.Pid = pid
+		c.Pos = newPtEtaPhiE(1, eta, phi, 0)
+		return c
+	}
+
+	for _, tc := range []struct {
+		name        string
+		particles   []Candidate
+		tracks      []Candidate
+		towers      int
+		photons     int
+		eflowtracks int
+	}{
+		{
+			name:        "muon",
+			particles:   []Candidate{particle(13, 50, 0.05, 0.1)},
+			tracks:      []Candidate{particle(13, 50, 0.05, 0.1)},
+			eflowtracks: 1,
+		},
+		{
+			name:        "muon-outside",
+			particles:   []Candidate{particle(-13, 50, 2, 0.1)},
+			tracks:      []Candidate{particle(-13, 50, 2, 0.1)},
+			eflowtracks: 1,
+		},
+		{
+			name:        "pion",
+			particles:   []Candidate{particle(211, 50, 0.05, 0.1)},
+			tracks:      []Candidate{particle(211, 50, 0.05, 0.1)},
+			towers:      1,
+			eflowtracks: 1,
+		},
+		{
+			name:      "photon",
+			particles: []Candidate{particle(22, 50, 0.05, 0.1)},
+			towers:    1,
+			photons:   1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var etas, phis []float64
+			for i := -10; i <= 10; i++ {
+				etas = append(etas, 0.1*float64(i))
+			}
+			for i := -18; i <= 18; i++ {
+				phis = append(phis, float64(i)*math.Pi/18)
+			}
+
+			tsk := &Calorimeter{
+				bins: NewEtaPhiGrid([]EtaPhiBin{{EtaBins: etas, PhiBins: phis}}),
+				efrac: map[int]EneFrac{
+					0:  {ECal: 0, HCal: 1},
+					13: {ECal: 0, HCal: 0},
+					22: {ECal: 1, HCal: 0},
+				},
+				ecalres:     func(eta, ene float64) float64 { return 0 },
+				hcalres:     func(eta, ene float64) float64 { return 0 },
+				particles:   "particles",
+				tracks:      "tracks",
+				towers:      "towers",
+				photons:     "photons",
+				eflowtracks: "eflowtracks",
+				eflowtowers: "eflowtowers",
+				rnd:         newRandSrc(1234),
+			}
+
+			ctx := newTestContext()
+			err := tsk.StartTask(ctx)
+			if err != nil {
+				t.Fatalf("could not start task: %+v", err)
+			}
+
+			ctx.store["particles"] = tc.particles
+			ctx.store["tracks"] = tc.tracks
+			err = tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			for _, v := range []struct {
+				name string
+				want int
+			}{
+				{"towers", tc.towers},
+				{"photons", tc.photons},
+				{"eflowtracks", tc.eflowtracks},
+			} {
+				cands := ctx.store[v.name].([]Candidate)
+				if got, want := len(cands), v.want; got != want {
+					t.Fatalf("invalid number of %s: got=%d, want=%d", v.name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -24,6 +24,8 @@
 //	    	number of concurrent events to process (default -1)
 //	  -o string
 //	    	name of output events file (default "data.rio")
+//	  -seed uint
+//	    	seed of the random numbers service (default 1234)
 //	  -trace string
 //	    	path to file where to store traces
 //
@@ -57,6 +59,7 @@ import (
 	"go-hep.org/x/hep/fastjet"
 	"go-hep.org/x/hep/fwk"
 	"go-hep.org/x/hep/fwk/job"
+	_ "go-hep.org/x/hep/fwk/randsvc"
 	"go-hep.org/x/hep/fwk/rio"
 	"go-hep.org/x/hep/hepmc"
 )
@@ -68,6 +71,7 @@ var (
 	cpuprof = flag.Bool("cpu-prof", false, "enable CPU profiling")
 	ptrace  = flag.String("trace", "", "path to file where to store traces")
 	output  = flag.String("o", "data.rio", "name of output events file")
+	seed    = flag.Uint64("seed", 1234, "seed of the random numbers service")
 
 	abs  = math.Abs
 	sqrt = math.Sqrt
@@ -132,6 +136,16 @@ options:
 		"MsgLevel": job.MsgLevel(*lvl),
 	})
 
+	// reproducible per-event random numbers, whatever the number of
+	// concurrent events.
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/randsvc.rsvc",
+		Name: "randsvc",
+		Props: job.P{
+			"Seed": *seed,
+		},
+	})
+
 	// propagate particles in cylinder
 	app.Create(job.C{
 		Type: "go-hep.org/x/hep/fads.Propagator",
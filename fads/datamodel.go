@@ -113,6 +113,11 @@ type Jet struct {
 	BTag   byte // 0 or 1 for a jet that has been tagged as containing a heavy quark
 	TauTag byte // 0 or 1 for a jet that has been tagged as a tau
 
+	Flavour       int32 // jet flavour
+	FlavourAlgo   int32 // jet flavour, algorithmic definition
+	FlavourPhys   int32 // jet flavour, physics definition
+	HadronFlavour int32 // jet flavour, from the associated B and C hadrons
+
 	Constituents []Particle        // pointers to constituents
 	McParts      []*hepmc.Particle // pointers to generated particles
 }
@@ -175,10 +180,16 @@ type Candidate struct {
 	CandMass       float64 // particle mass
 
 	IsPU          byte   // 0 or 1 for particles from pile-up interactions
+	IsRecoPU      byte   // 0 or 1 for particles reconstructed as coming from pile-up interactions
 	IsConstituent byte   // 0 or 1 for particles being constituents
 	BTag          uint32 // b-tag information (bit-mask)
 	TauTag        uint32 // tau-tag information (bit-mask)
 
+	Flavour       int32 // jet flavour
+	FlavourAlgo   int32 // jet flavour, algorithmic definition
+	FlavourPhys   int32 // jet flavour, physics definition
+	HadronFlavour int32 // jet flavour, from the associated B and C hadrons
+
 	Eem  float64 // electromagnetic energy
 	Ehad float64 // hadronic energy
 
@@ -186,9 +197,23 @@ type Candidate struct {
 	DEta  float64
 	DPhi  float64
 
-	Mom  fmom.PxPyPzE
-	Pos  fmom.PxPyPzE
-	Area fmom.PxPyPzE
+	Xd, Yd, Zd float64 // coordinates of the point of closest approach to the beam axis
+	D0         float64 // transverse impact parameter
+	DZ         float64 // longitudinal impact parameter
+	ErrD0      float64 // transverse impact parameter error
+	ErrDZ      float64 // longitudinal impact parameter error
+
+	TrackResolution float64 // relative resolution on the track momentum
+
+	ClusterIndex int32   // index of the vertex this track was clustered into (-1 if none)
+	ClusterNDF   int32   // number of tracks clustered into this vertex
+	ClusterSigma float64 // width of the vertex cluster, in units of the tracks' errors
+	SumPt2       float64 // sum of the squared transverse momenta of the vertex tracks
+
+	Mom    fmom.PxPyPzE
+	Pos    fmom.PxPyPzE
+	PosErr fmom.PxPyPzE
+	Area   fmom.PxPyPzE
 
 	Candidates []Candidate
 }
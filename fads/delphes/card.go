@@ -0,0 +1,176 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package delphes
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Card is a Delphes detector card.
+type Card struct {
+	Modules []*Module // modules declared in the card, in declaration order
+
+	vars map[string]string // global variables
+}
+
+// Module is a module declared in a Delphes detector card.
+type Module struct {
+	Type string // type of the Delphes module (e.g. "ParticlePropagator")
+	Name string // name of the module (e.g. "ParticlePropagator")
+
+	params map[string]string
+}
+
+// ReadCard reads a Delphes detector card from r.
+// Files included via the Tcl 'source' command are resolved relative to
+// the current working directory.
+func ReadCard(r io.Reader) (*Card, error) {
+	return readCard(r, ".")
+}
+
+// Open reads the Delphes detector card from the named file.
+// Files included via the Tcl 'source' command are resolved relative to
+// the directory of the named file.
+func Open(fname string) (*Card, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readCard(f, filepath.Dir(fname))
+}
+
+func readCard(r io.Reader, dir string) (*Card, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("delphes: could not read card: %w", err)
+	}
+
+	card := &Card{
+		vars: make(map[string]string),
+	}
+	_, err = newInterp(card, dir).eval(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("delphes: could not evaluate card: %w", err)
+	}
+
+	return card, nil
+}
+
+// ExecutionPath returns the names of the modules to run.
+func (card *Card) ExecutionPath() []string {
+	return splitList(card.vars["ExecutionPath"])
+}
+
+// Var returns the value of the global variable name.
+func (card *Card) Var(name string) (string, bool) {
+	v, ok := card.vars[name]
+	return v, ok
+}
+
+// Module returns the module with the provided name, or nil.
+func (card *Card) Module(name string) *Module {
+	for _, mod := range card.Modules {
+		if mod.Name == name {
+			return mod
+		}
+	}
+	return nil
+}
+
+// Param returns the raw value of the named parameter.
+func (mod *Module) Param(name string) (string, bool) {
+	v, ok := mod.params[name]
+	return v, ok
+}
+
+// Params returns the sorted names of all the parameters of this module.
+func (mod *Module) Params() []string {
+	keys := make([]string, 0, len(mod.params))
+	for k := range mod.params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// List returns the named parameter as a list of values.
+func (mod *Module) List(name string) []string {
+	return splitList(mod.params[name])
+}
+
+// String returns the named parameter as a string.
+func (mod *Module) String(name string) (string, bool) {
+	v, ok := mod.params[name]
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSpace(v), true
+}
+
+// Float returns the named parameter as a float64.
+func (mod *Module) Float(name string) (float64, bool, error) {
+	v, ok := mod.String(name)
+	if !ok {
+		return 0, false, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, true, fmt.Errorf("delphes: module %q: invalid float parameter %s=%q", mod.Name, name, v)
+	}
+	return f, true, nil
+}
+
+// Int returns the named parameter as an int.
+func (mod *Module) Int(name string) (int, bool, error) {
+	v, ok := mod.String(name)
+	if !ok {
+		return 0, false, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(v, 64)
+		if ferr != nil || f != float64(int(f)) {
+			return 0, true, fmt.Errorf("delphes: module %q: invalid int parameter %s=%q", mod.Name, name, v)
+		}
+		i = int(f)
+	}
+	return i, true, nil
+}
+
+// Bool returns the named parameter as a bool.
+func (mod *Module) Bool(name string) (bool, bool, error) {
+	v, ok := mod.String(name)
+	if !ok {
+		return false, false, nil
+	}
+	switch strings.ToLower(v) {
+	case "1", "true", "yes", "on":
+		return true, true, nil
+	case "0", "false", "no", "off":
+		return false, true, nil
+	}
+	return false, true, fmt.Errorf("delphes: module %q: invalid bool parameter %s=%q", mod.Name, name, v)
+}
+
+// Formula returns the named parameter as a compiled formula.
+func (mod *Module) Formula(name string) (Formula, bool, error) {
+	v, ok := mod.String(name)
+	if !ok {
+		return Formula{}, false, nil
+	}
+	f, err := ParseFormula(v)
+	if err != nil {
+		return f, true, fmt.Errorf("delphes: module %q: invalid formula parameter %s: %w", mod.Name, name, err)
+	}
+	return f, true, nil
+}
@@ -0,0 +1,26 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package delphes reads Delphes detector cards and converts them into
+// fads tasks configurations.
+//
+// Delphes cards are Tcl scripts. Package delphes implements the subset of
+// Tcl used by Delphes cards (set, add, lappend, expr, for, foreach, if,
+// source and module) as well as the ROOT TFormula expressions used for
+// efficiencies and resolutions.
+//
+// The HepMC input stream and the fads.HepMcReader task are not part of
+// Delphes cards and must be created by the user.
+//
+// Example:
+//
+//	card, err := delphes.Open("delphes_card_ATLAS.tcl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = card.Create(app)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+package delphes // import "go-hep.org/x/hep/fads/delphes"
@@ -0,0 +1,836 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package delphes
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/fads"
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/job"
+	_ "go-hep.org/x/hep/fwk/randsvc"
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hepmc"
+)
+
+// testCard is a minimal card exercising the parser and the combined
+// Calorimeter module of older Delphes releases.
+// The full simulation tests run the official ATLAS card instead.
+const testCard = `
+# simple detector card
+set ExecutionPath {
+  ParticlePropagator
+  ChargedHadronTrackingEfficiency
+  ChargedHadronMomentumSmearing
+  TrackMerger
+  Calorimeter
+  EFlowMerger
+  FastJetFinder
+  JetFlavorAssociation
+  BTagging
+  JetEnergyScale
+  TreeWriter
+}
+
+module ParticlePropagator ParticlePropagator {
+  set InputArray Delphes/stableParticles
+  set OutputArray stableParticles
+  set ChargedHadronOutputArray chargedHadrons
+  set ElectronOutputArray electrons
+  set MuonOutputArray muons
+
+  # radius of the magnetic field coverage, in m
+  set Radius 1.15
+  # half-length of the magnetic field coverage, in m
+  set HalfLength 3.51
+  # magnetic field
+  set Bz 2.0
+}
+
+module Efficiency ChargedHadronTrackingEfficiency {
+  set InputArray ParticlePropagator/chargedHadrons
+  set OutputArray chargedHadrons
+
+  set EfficiencyFormula {                                                    (pt <= 0.1)   * (0.00) + \
+                                           (abs(eta) <= 1.5) * (pt > 0.1   && pt <= 1.0)   * (0.70) + \
+                                           (abs(eta) <= 1.5) * (pt > 1.0)                  * (0.95) + \
+                         (abs(eta) > 1.5 && abs(eta) <= 2.5) * (pt > 0.1   && pt <= 1.0)   * (0.60) + \
+                         (abs(eta) > 1.5 && abs(eta) <= 2.5) * (pt > 1.0)                  * (0.85) + \
+                         (abs(eta) > 2.5)                                                  * (0.00)}
+}
+
+module MomentumSmearing ChargedHadronMomentumSmearing {
+  set InputArray ChargedHadronTrackingEfficiency/chargedHadrons
+  set OutputArray chargedHadrons
+
+  set ResolutionFormula {                  (abs(eta) <= 1.5) * (pt > 0.1   && pt <= 1.0)   * (0.02) + \
+                                           (abs(eta) <= 1.5) * (pt > 1.0   && pt <= 1.0e1) * (0.01) + \
+                                           (abs(eta) <= 1.5) * (pt > 1.0e1 && pt <= 2.0e2) * (0.03) + \
+                                           (abs(eta) <= 1.5) * (pt > 2.0e2)                * (0.05) + \
+                         (abs(eta) > 1.5 && abs(eta) <= 2.5) * (pt > 0.1)                  * (0.05)}
+}
+
+module Merger TrackMerger {
+  add InputArray ChargedHadronMomentumSmearing/chargedHadrons
+  add InputArray ParticlePropagator/electrons
+  add InputArray ParticlePropagator/muons
+  set OutputArray tracks
+}
+
+module Calorimeter Calorimeter {
+  set ParticleInputArray ParticlePropagator/stableParticles
+  set TrackInputArray TrackMerger/tracks
+
+  set TowerOutputArray towers
+  set PhotonOutputArray photons
+
+  set EFlowTrackOutputArray eflowTracks
+  set EFlowPhotonOutputArray eflowPhotons
+  set EFlowNeutralHadronOutputArray eflowNeutralHadrons
+
+  set ECalEnergyMin 0.5
+  set HCalEnergyMin 1.0
+  set ECalEnergySignificanceMin 1.0
+  set HCalEnergySignificanceMin 1.0
+
+  set pi [expr {acos(-1)}]
+
+  # 10 degrees towers
+  set PhiBins {}
+  for {set i -18} {$i <= 18} {incr i} {
+    add PhiBins [expr {$i * $pi/18.0}]
+  }
+  foreach eta {-3.2 -2.5 -2.4 -2.3 -2.2 -2.1 -2 -1.9 -1.8 -1.7 -1.6 -1.5 -1.4 -1.3 -1.2 -1.1 -1 -0.9 -0.8 -0.7 -0.6 -0.5 -0.4 -0.3 -0.2 -0.1 0 0.1 0.2 0.3 0.4 0.5 0.6 0.7 0.8 0.9 1 1.1 1.2 1.3 1.4 1.5 1.6 1.7 1.8 1.9 2 2.1 2.2 2.3 2.4 2.5 2.6 3.3} {
+    add EtaPhiBins $eta $PhiBins
+  }
+
+  # 20 degrees towers
+  set PhiBins {}
+  for {set i -9} {$i <= 9} {incr i} {
+    add PhiBins [expr {$i * $pi/9.0}]
+  }
+  foreach eta {-4.9 -4.7 -4.5 -4.3 -4.1 -3.9 -3.7 -3.5 -3.3 -3 -2.8 -2.6 2.8 3 3.2 3.5 3.7 3.9 4.1 4.3 4.5 4.7 4.9} {
+    add EtaPhiBins $eta $PhiBins
+  }
+
+  # default energy fractions {abs(PDG code)} {fraction of energy deposited in ECAL}
+  add EnergyFraction {0} {0.0 1.0}
+  # energy fractions for e, gamma and pi0
+  add EnergyFraction {11} {1.0 0.0}
+  add EnergyFraction {22} {1.0 0.0}
+  add EnergyFraction {111} {1.0 0.0}
+  # energy fractions for muon, neutrinos and neutralinos
+  add EnergyFraction {12} {0.0 0.0}
+  add EnergyFraction {13} {0.0 0.0}
+  add EnergyFraction {14} {0.0 0.0}
+  add EnergyFraction {16} {0.0 0.0}
+  add EnergyFraction {1000022} {0.0 0.0}
+
+  set ECalResolutionFormula { (abs(eta) <= 3.2) * sqrt(energy^2*0.0017^2 + energy*0.101^2) + \
+                             (abs(eta) > 3.2 && abs(eta) <= 4.9) * sqrt(energy^2*0.0350^2 + energy*0.285^2)}
+
+  set HCalResolutionFormula { (abs(eta) <= 1.7) * sqrt(energy^2*0.0302^2 + energy*0.5205^2 + 1.59^2) + \
+                             (abs(eta) > 1.7 && abs(eta) <= 3.2) * sqrt(energy^2*0.0500^2 + energy*0.706^2) + \
+                             (abs(eta) > 3.2 && abs(eta) <= 4.9) * sqrt(energy^2*0.09420^2 + energy*1.00^2)}
+}
+
+module Merger EFlowMerger {
+  add InputArray Calorimeter/eflowTracks
+  add InputArray Calorimeter/eflowPhotons
+  add InputArray Calorimeter/eflowNeutralHadrons
+  set OutputArray eflow
+}
+
+module FastJetFinder FastJetFinder {
+  set InputArray EFlowMerger/eflow
+  set OutputArray jets
+
+  # algorithm: 1 CDFJetClu, 2 MidPoint, 3 SIScone, 4 kt, 5 Cambridge/Aachen, 6 antikt
+  set JetAlgorithm 6
+  set ParameterR 0.6
+  set JetPTMin 20.0
+}
+
+module JetFlavorAssociation JetFlavorAssociation {
+  set PartonInputArray Delphes/partons
+  set ParticleInputArray Delphes/allParticles
+  set JetInputArray FastJetFinder/jets
+
+  set DeltaR 0.5
+  set PartonPTMin 1.0
+  set PartonEtaMax 2.5
+}
+
+module BTagging BTagging {
+  set JetInputArray FastJetFinder/jets
+
+  set BitNumber 0
+  set DeltaR 0.5
+  set PartonPTMin 1.0
+  set PartonEtaMax 2.5
+
+  # default efficiency formula (misidentification rate)
+  add EfficiencyFormula {0} {0.001}
+  # efficiency formula for c-jets (misidentification rate)
+  add EfficiencyFormula {4} {                                      (pt <= 15.0) * (0.000) + \
+                                                (abs(eta) <= 1.2) * (pt > 15.0) * (0.2*tanh(pt*0.03 - 0.4)) + \
+                              (abs(eta) > 1.2 && abs(eta) <= 2.5) * (pt > 15.0) * (0.1*tanh(pt*0.03 - 0.4)) + \
+                              (abs(eta) > 2.5)                                  * (0.000)}
+  # efficiency formula for b-jets
+  add EfficiencyFormula {5} {                                      (pt <= 15.0) * (0.000) + \
+                                                (abs(eta) <= 1.2) * (pt > 15.0) * (0.5*tanh(pt*0.03 - 0.4)) + \
+                              (abs(eta) > 1.2 && abs(eta) <= 2.5) * (pt > 15.0) * (0.4*tanh(pt*0.03 - 0.4)) + \
+                              (abs(eta) > 2.5)                                  * (0.000)}
+}
+
+module EnergyScale JetEnergyScale {
+  set InputArray FastJetFinder/jets
+  set OutputArray jets
+
+  # scale formula for jets
+  set ScaleFormula {sqrt( (2.5 - 0.15*(abs(eta)))^2 / pt + 1.0 )}
+}
+
+module TreeWriter TreeWriter {
+  add Branch Delphes/allParticles Particle GenParticle
+  add Branch JetEnergyScale/jets Jet Jet
+  add Branch EFlowMerger/momentum MissingET MissingET
+  add Branch EFlowMerger/energy ScalarHT ScalarHT
+}
+`
+
+func TestFormula(t *testing.T) {
+	for _, tc := range []struct {
+		src                  string
+		pt, eta, phi, energy float64
+		want                 float64
+	}{
+		{src: "1.5", want: 1.5},
+		{src: "2 + 3 * 4", want: 14},
+		{src: "(2 + 3) * 4", want: 20},
+		{src: "2^3^2", want: 512},
+		{src: "-2^2", want: -4},
+		{src: "pt * 2", pt: 10, want: 20},
+		{src: "abs(eta) <= 1.5", eta: -1, want: 1},
+		{src: "abs(eta) <= 1.5 && pt > 1", eta: 2, pt: 2, want: 0},
+		{src: "sqrt(energy^2*0.0017^2 + energy*0.101^2)", energy: 100, want: math.Sqrt(100*100*0.0017*0.0017 + 100*0.101*0.101)},
+		{src: "TMath::Sqrt(pt) + TMath::Pi()", pt: 4, want: 2 + math.Pi},
+		{src: "pt > 1 ? 1 : 2", pt: 2, want: 1},
+		{src: "pow(pt, 2) + min(eta, phi)", pt: 3, eta: 1, phi: -1, want: 8},
+		{src: "1.0e1 * 0.5", want: 5},
+	} {
+		t.Run(tc.src, func(t *testing.T) {
+			f, err := ParseFormula(tc.src)
+			if err != nil {
+				t.Fatalf("could not parse formula: %+v", err)
+			}
+			got := f.Eval(tc.pt, tc.eta, tc.phi, tc.energy)
+			if math.Abs(got-tc.want) > 1e-12 {
+				t.Fatalf("invalid value: got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+
+	for _, src := range []string{
+		"",
+		"1 +",
+		"(1",
+		"foo",
+		"sqrt(1, 2)",
+		"$x",
+	} {
+		t.Run("invalid-"+src, func(t *testing.T) {
+			_, err := ParseFormula(src)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadCard(t *testing.T) {
+	card, err := ReadCard(strings.NewReader(testCard))
+	if err != nil {
+		t.Fatalf("could not read card: %+v", err)
+	}
+
+	want := []string{
+		"ParticlePropagator",
+		"ChargedHadronTrackingEfficiency",
+		"ChargedHadronMomentumSmearing",
+		"TrackMerger",
+		"Calorimeter",
+		"EFlowMerger",
+		"FastJetFinder",
+		"JetFlavorAssociation",
+		"BTagging",
+		"JetEnergyScale",
+		"TreeWriter",
+	}
+	if got := card.ExecutionPath(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid execution path:\ngot= %q\nwant=%q", got, want)
+	}
+
+	if got, want := len(card.Modules), len(want); got != want {
+		t.Fatalf("invalid number of modules: got=%d, want=%d", got, want)
+	}
+
+	calo := card.Module("Calorimeter")
+	if calo == nil {
+		t.Fatalf("could not find calorimeter module")
+	}
+	if got, want := len(calo.List("PhiBins")), 19; got != want {
+		t.Fatalf("invalid number of phi bins: got=%d, want=%d", got, want)
+	}
+	if got, want := len(calo.List("EtaPhiBins")), 2*(54+23); got != want {
+		t.Fatalf("invalid number of eta-phi bins: got=%d, want=%d", got, want)
+	}
+	if got, want := len(calo.List("EnergyFraction")), 2*9; got != want {
+		t.Fatalf("invalid number of energy fractions: got=%d, want=%d", got, want)
+	}
+
+	prop := card.Module("ParticlePropagator")
+	if got, want := prop.Params(), []string{
+		"Bz",
+		"ChargedHadronOutputArray",
+		"ElectronOutputArray",
+		"HalfLength",
+		"InputArray",
+		"MuonOutputArray",
+		"OutputArray",
+		"Radius",
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid parameters:\ngot= %q\nwant=%q", got, want)
+	}
+	bz, ok, err := prop.Float("Bz")
+	if err != nil || !ok || bz != 2 {
+		t.Fatalf("invalid Bz: got=%v (ok=%v, err=%v)", bz, ok, err)
+	}
+
+	cfgs, err := card.Configs()
+	if err != nil {
+		t.Fatalf("could not create configurations: %+v", err)
+	}
+	// a ParticleFlow task is added for the calorimeter.
+	if got, want := len(cfgs), len(want)+1; got != want {
+		t.Fatalf("invalid number of configurations: got=%d, want=%d", got, want)
+	}
+
+	jes := cfgs[len(cfgs)-2]
+	if got, want := jes.Props["Input"], "/fads/BTagging/jets"; got != want {
+		t.Fatalf("invalid in-place aliasing: got=%v, want=%v", got, want)
+	}
+
+	btag := cfgs[len(cfgs)-3]
+	if got, want := btag.Props["Jets"], "/fads/JetFlavorAssociation/jets"; got != want {
+		t.Fatalf("invalid b-tagging jets: got=%v, want=%v", got, want)
+	}
+	if got, want := btag.Props["Partons"], ""; got != want {
+		t.Fatalf("invalid b-tagging partons: got=%q, want=%q", got, want)
+	}
+
+	merger := cfgs[3]
+	if got, want := merger.Props["Inputs"], []string{
+		"/fads/ChargedHadronMomentumSmearing/chargedHadrons",
+		"/fads/ParticlePropagator/electrons",
+		"/fads/ParticlePropagator/muons",
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid merger inputs:\ngot= %q\nwant=%q", got, want)
+	}
+
+	pflow := cfgs[5]
+	if got, want := pflow.Type, "go-hep.org/x/hep/fads.ParticleFlow"; got != want {
+		t.Fatalf("invalid particle-flow task: got=%q, want=%q", got, want)
+	}
+	if got, want := pflow.Props["EFlowPhotons"], "/fads/Calorimeter/eflowPhotons"; got != want {
+		t.Fatalf("invalid particle-flow photons: got=%v, want=%v", got, want)
+	}
+	if got, want := pflow.Props["EFlowTracks"], "/fads/Calorimeter/eflowTracks"; got != want {
+		t.Fatalf("invalid particle-flow tracks: got=%v, want=%v", got, want)
+	}
+
+	eff := cfgs[1].Props["Eff"].(func(pt, eta float64) float64)
+	if got, want := eff(10, 0.5), 0.95; got != want {
+		t.Fatalf("invalid efficiency: got=%v, want=%v", got, want)
+	}
+}
+
+func TestOpenCard(t *testing.T) {
+	card, err := Open(atlasCard)
+	if err != nil {
+		t.Fatalf("could not open card: %+v", err)
+	}
+
+	path := card.ExecutionPath()
+	if got, want := len(path), 32; got != want {
+		t.Fatalf("invalid execution path length: got=%d, want=%d", got, want)
+	}
+
+	cfgs, err := card.Configs()
+	if err != nil {
+		t.Fatalf("could not create configurations: %+v", err)
+	}
+	if got, want := len(cfgs), len(path); got != want {
+		t.Fatalf("invalid number of configurations: got=%d, want=%d", got, want)
+	}
+
+	byName := make(map[string]job.C, len(cfgs))
+	for _, cfg := range cfgs {
+		byName[cfg.Name] = cfg
+	}
+
+	for _, tc := range []struct {
+		name string
+		typ  string
+	}{
+		{"ECal", "go-hep.org/x/hep/fads.SimpleCalorimeter"},
+		{"HCal", "go-hep.org/x/hep/fads.SimpleCalorimeter"},
+		{"ElectronFilter", "go-hep.org/x/hep/fads.PdgCodeFilter"},
+		{"NeutrinoFilter", "go-hep.org/x/hep/fads.PdgCodeFilter"},
+	} {
+		if got := byName[tc.name].Type; got != tc.typ {
+			t.Fatalf("invalid type for %s: got=%q, want=%q", tc.name, got, tc.typ)
+		}
+	}
+
+	ecal := byName["ECal"].Props
+	if got, want := ecal["IsECal"], true; got != want {
+		t.Fatalf("invalid ECal flag: got=%v, want=%v", got, want)
+	}
+	if got, want := ecal["EFlowTowers"], "/fads/ECal/eflowPhotons"; got != want {
+		t.Fatalf("invalid ECal energy-flow towers: got=%v, want=%v", got, want)
+	}
+	fracs := ecal["EnergyFraction"].(map[int]float64)
+	if got, want := len(fracs), 15; got != want {
+		t.Fatalf("invalid number of energy fractions: got=%d, want=%d", got, want)
+	}
+	if got, want := fracs[310], 0.3; got != want {
+		t.Fatalf("invalid K0s energy fraction: got=%v, want=%v", got, want)
+	}
+	grid := ecal["EtaPhiBins"].(fads.EtaPhiGrid)
+	for _, tc := range []struct {
+		eta  float64
+		want bool
+	}{
+		{eta: 0.01, want: true},
+		{eta: -2.0, want: true},
+		{eta: 4.5, want: true},
+		{eta: 5.0, want: false},
+	} {
+		if _, _, got := grid.EtaPhiIndex(tc.eta, 0.1); got != tc.want {
+			t.Fatalf("invalid ECal coverage at eta=%v: got=%v, want=%v", tc.eta, got, tc.want)
+		}
+	}
+
+	hcal := byName["HCal"].Props
+	if got, want := hcal["Tracks"], "/fads/ECal/eflowTracks"; got != want {
+		t.Fatalf("invalid HCal tracks: got=%v, want=%v", got, want)
+	}
+
+	efilter := byName["ElectronFilter"].Props
+	if got, want := efilter["Invert"], true; got != want {
+		t.Fatalf("invalid electron filter inversion: got=%v, want=%v", got, want)
+	}
+	if got, want := efilter["PdgCodes"], []int{11, -11}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid electron filter codes: got=%v, want=%v", got, want)
+	}
+}
+
+func TestReadCardErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		card string
+	}{
+		{
+			name: "unbalanced-braces",
+			card: "set ExecutionPath {A",
+		},
+		{
+			name: "unknown-variable",
+			card: "set x $y",
+		},
+		{
+			name: "unknown-command",
+			card: "foo bar",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadCard(strings.NewReader(tc.card))
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		name string
+		card string
+	}{
+		{
+			name: "missing-module",
+			card: "set ExecutionPath {A}",
+		},
+		{
+			name: "unknown-module",
+			card: "set ExecutionPath {A}\nmodule Foo A {}",
+		},
+		{
+			name: "invalid-formula",
+			card: "set ExecutionPath {A}\nmodule Efficiency A {\n set EfficiencyFormula {1 +}\n}",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			card, err := ReadCard(strings.NewReader(tc.card))
+			if err != nil {
+				t.Fatalf("could not read card: %+v", err)
+			}
+			_, err = card.Configs()
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
+
+// atlasCard is the unmodified ATLAS detector card distributed with Delphes.
+const atlasCard = "testdata/delphes_card_ATLAS.tcl"
+
+// runCard runs the ATLAS card over the events created by input, with the
+// provided number of concurrent events, and returns the name of the
+// output ROOT file.
+func runCard(t *testing.T, nprocs int, input func(app *job.Job)) string {
+	t.Helper()
+
+	card, err := Open(atlasCard)
+	if err != nil {
+		t.Fatalf("could not read card: %+v", err)
+	}
+
+	app := job.NewJob(nil, job.P{
+		"EvtMax":   int64(5),
+		"NProcs":   nprocs,
+		"MsgLevel": job.MsgLevel("ERROR"),
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/randsvc.rsvc",
+		Name: "randsvc",
+		Props: job.P{
+			"Seed": uint64(1234),
+		},
+	})
+
+	input(app)
+
+	err = card.Create(app.App())
+	if err != nil {
+		t.Fatalf("could not create tasks from card: %+v", err)
+	}
+
+	fname := filepath.Join(t.TempDir(), "delphes.root")
+	app.SetProp(app.App().Component("TreeWriter"), "Output", fname)
+
+	err = app.App().Run()
+	if err != nil {
+		t.Fatalf("could not run application: %+v", err)
+	}
+
+	return fname
+}
+
+// hepmcInput reads the events of the HepMC test file.
+func hepmcInput(app *job.Job) {
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.InputStream",
+		Name: "hepmc-streamer",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{
+					Name: "/fads/McEvent",
+					Type: reflect.TypeOf(hepmc.Event{}),
+				},
+			},
+			"Streamer": &fads.HepMcStreamer{
+				Name: "../testdata/hepmc.data",
+			},
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fads.HepMcReader",
+		Name: "hepmc-reader",
+		Props: job.P{
+			"Input": "/fads/McEvent",
+		},
+	})
+}
+
+func TestCreate(t *testing.T) {
+	fname := runCard(t, 0, hepmcInput)
+
+	f, err := groot.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open output file: %+v", err)
+	}
+	defer f.Close()
+
+	o, err := riofs.Dir(f).Get("Delphes")
+	if err != nil {
+		t.Fatalf("could not retrieve output tree: %+v", err)
+	}
+	tree := o.(rtree.Tree)
+	if got, want := tree.Entries(), int64(5); got != want {
+		t.Fatalf("invalid number of entries: got=%d, want=%d", got, want)
+	}
+
+	var (
+		njets int32
+		jetpt []float32
+		flav  []int32
+		nmet  int32
+		met   []float32
+	)
+	r, err := rtree.NewReader(tree, []rtree.ReadVar{
+		{Name: "Jet_size", Value: &njets},
+		{Name: "Jet.PT", Value: &jetpt},
+		{Name: "Jet.Flavor", Value: &flav},
+		{Name: "MissingET_size", Value: &nmet},
+		{Name: "MissingET.MET", Value: &met},
+	})
+	if err != nil {
+		t.Fatalf("could not create tree reader: %+v", err)
+	}
+	defer r.Close()
+
+	err = r.Read(func(ctx rtree.RCtx) error {
+		if got, want := len(jetpt), int(njets); got != want {
+			t.Fatalf("entry %d: invalid number of jets: got=%d, want=%d", ctx.Entry, got, want)
+		}
+		for _, pt := range jetpt {
+			if pt < 20 {
+				t.Fatalf("entry %d: invalid jet pt=%v", ctx.Entry, pt)
+			}
+		}
+		for _, v := range flav {
+			switch v {
+			case 0, 1, 2, 3, 4, 5, 21:
+			default:
+				t.Fatalf("entry %d: invalid jet flavour=%d", ctx.Entry, v)
+			}
+		}
+		if nmet != 1 || len(met) != 1 {
+			t.Fatalf("entry %d: invalid missing-et: n=%d, met=%v", ctx.Entry, nmet, met)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not read tree: %+v", err)
+	}
+}
+
+func TestReproducibility(t *testing.T) {
+	// dump returns the content of each event of the output tree.
+	// events are sorted as concurrent events may be written in any order.
+	dump := func(fname string) []string {
+		f, err := groot.Open(fname)
+		if err != nil {
+			t.Fatalf("could not open output file: %+v", err)
+		}
+		defer f.Close()
+
+		o, err := riofs.Dir(f).Get("Delphes")
+		if err != nil {
+			t.Fatalf("could not retrieve output tree: %+v", err)
+		}
+		tree := o.(rtree.Tree)
+
+		rvars := rtree.NewReadVars(tree)
+		r, err := rtree.NewReader(tree, rvars)
+		if err != nil {
+			t.Fatalf("could not create tree reader: %+v", err)
+		}
+		defer r.Close()
+
+		var evts []string
+		err = r.Read(func(ctx rtree.RCtx) error {
+			var o strings.Builder
+			for _, rv := range rvars {
+				fmt.Fprintf(&o, "%s: %v\n", rv.Name, reflect.ValueOf(rv.Value).Elem().Interface())
+			}
+			evts = append(evts, o.String())
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("could not read tree: %+v", err)
+		}
+		sort.Strings(evts)
+		return evts
+	}
+
+	want := dump(runCard(t, 1, hepmcInput))
+	got := dump(runCard(t, 4, hepmcInput))
+	if len(got) != len(want) {
+		t.Fatalf("invalid number of events: got=%d, want=%d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("event content differs with concurrent events:\ngot:\n%s\nwant:\n%s", got[i], want[i])
+		}
+	}
+}
+
+// gunJets are the partons initiating the jets produced by jetGun.
+var gunJets = []struct {
+	pid      int32
+	eta, phi float64
+}{
+	{pid: 5, eta: 0.3, phi: 0.5},
+	{pid: 21, eta: -0.5, phi: -2.5},
+	{pid: 2, eta: 1.0, phi: 2.0},
+}
+
+// jetGun produces events made of well separated jets, each initiated by
+// one of the gunJets hard-process partons and made of a few stable
+// particles collinear to it.
+type jetGun struct {
+	fwk.TaskBase
+}
+
+func (tsk *jetGun) Configure(ctx fwk.Context) error {
+	for _, name := range []string{"/fads/AllParticles", "/fads/StableParticles", "/fads/Partons"} {
+		err := tsk.DeclOutPort(name, reflect.TypeOf([]fads.Candidate{}))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tsk *jetGun) StartTask(ctx fwk.Context) error { return nil }
+func (tsk *jetGun) StopTask(ctx fwk.Context) error  { return nil }
+
+func (tsk *jetGun) Process(ctx fwk.Context) error {
+	const pt = 60
+
+	newCand := func(pid, status, charge int32, mass, pt, eta, phi float64) fads.Candidate {
+		c := fads.Candidate{
+			Pid:        pid,
+			Status:     status,
+			CandCharge: charge,
+			CandMass:   mass,
+		}
+		c.Mom.SetPtEtaPhiM(pt, eta, phi, mass)
+		return c
+	}
+
+	var (
+		partons []fads.Candidate
+		stables []fads.Candidate
+	)
+	for _, jet := range gunJets {
+		partons = append(partons, newCand(jet.pid, 23, 0, 0, pt, jet.eta, jet.phi))
+		stables = append(stables,
+			newCand(+211, 1, +1, 0.13957, 0.5*pt, jet.eta, jet.phi),
+			newCand(-211, 1, -1, 0.13957, 0.3*pt, jet.eta+0.05, jet.phi),
+			newCand(22, 1, 0, 0, 0.2*pt, jet.eta, jet.phi+0.05),
+		)
+	}
+	all := append(append([]fads.Candidate{}, partons...), stables...)
+
+	store := ctx.Store()
+	err := store.Put("/fads/AllParticles", all)
+	if err != nil {
+		return err
+	}
+	err = store.Put("/fads/StableParticles", stables)
+	if err != nil {
+		return err
+	}
+	return store.Put("/fads/Partons", partons)
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(jetGun{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			return &jetGun{TaskBase: fwk.NewTask(typ, name, mgr)}, nil
+		},
+	)
+}
+
+func TestJetFlavours(t *testing.T) {
+	fname := runCard(t, 0, func(app *job.Job) {
+		app.Create(job.C{
+			Type: "go-hep.org/x/hep/fads/delphes.jetGun",
+			Name: "jet-gun",
+		})
+	})
+
+	f, err := groot.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open output file: %+v", err)
+	}
+	defer f.Close()
+
+	o, err := riofs.Dir(f).Get("Delphes")
+	if err != nil {
+		t.Fatalf("could not retrieve output tree: %+v", err)
+	}
+	tree := o.(rtree.Tree)
+
+	var (
+		eta  []float32
+		phi  []float32
+		flav []int32
+		phys []int32
+	)
+	r, err := rtree.NewReader(tree, []rtree.ReadVar{
+		{Name: "Jet.Eta", Value: &eta},
+		{Name: "Jet.Phi", Value: &phi},
+		{Name: "Jet.Flavor", Value: &flav},
+		{Name: "Jet.FlavorPhys", Value: &phys},
+	})
+	if err != nil {
+		t.Fatalf("could not create tree reader: %+v", err)
+	}
+	defer r.Close()
+
+	err = r.Read(func(ctx rtree.RCtx) error {
+		if got, want := len(eta), len(gunJets); got != want {
+			t.Fatalf("entry %d: invalid number of jets: got=%d, want=%d", ctx.Entry, got, want)
+		}
+		for _, jet := range gunJets {
+			i := -1
+			for j := range eta {
+				deta := float64(eta[j]) - jet.eta
+				dphi := math.Remainder(float64(phi[j])-jet.phi, 2*math.Pi)
+				if math.Hypot(deta, dphi) < 0.3 {
+					i = j
+					break
+				}
+			}
+			if i < 0 {
+				t.Fatalf("entry %d: no jet matching parton %d", ctx.Entry, jet.pid)
+			}
+			if got, want := flav[i], jet.pid; got != want {
+				t.Fatalf("entry %d: invalid flavour: got=%d, want=%d", ctx.Entry, got, want)
+			}
+			if got, want := phys[i], jet.pid; got != want {
+				t.Fatalf("entry %d: invalid physics flavour: got=%d, want=%d", ctx.Entry, got, want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not read tree: %+v", err)
+	}
+}
@@ -0,0 +1,544 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package delphes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Formula is a compiled Delphes formula, as used in the
+// EfficiencyFormula or ResolutionFormula parameters of detector cards.
+//
+// Formulas may refer to the pt, eta, phi and energy variables,
+// use the usual arithmetic, comparison and logical operators
+// (boolean values evaluate to 0 or 1) and the math functions from
+// ROOT's TMath namespace.
+type Formula struct {
+	src  string
+	eval func(vs *[nvars]float64) float64
+}
+
+const (
+	varPt = iota
+	varEta
+	varPhi
+	varEnergy
+	nvars
+)
+
+// ParseFormula compiles the provided Delphes formula.
+func ParseFormula(src string) (Formula, error) {
+	p := newExprParser(src, nil)
+	eval, err := p.parse()
+	if err != nil {
+		return Formula{}, fmt.Errorf("delphes: could not parse formula %q: %w", src, err)
+	}
+	return Formula{src: src, eval: eval}, nil
+}
+
+// String returns the source of the formula.
+func (f Formula) String() string {
+	return f.src
+}
+
+// Eval evaluates the formula for the provided variables.
+func (f Formula) Eval(pt, eta, phi, energy float64) float64 {
+	vs := [nvars]float64{pt, eta, phi, energy}
+	return f.eval(&vs)
+}
+
+// evalExpr evaluates a Tcl expr expression, where variables are
+// resolved with the provided lookup function.
+func evalExpr(src string, lookup func(name string) (string, error)) (float64, error) {
+	p := newExprParser(src, lookup)
+	eval, err := p.parse()
+	if err != nil {
+		return 0, fmt.Errorf("delphes: could not parse expression %q: %w", src, err)
+	}
+	var vs [nvars]float64
+	return eval(&vs), nil
+}
+
+type exprToken struct {
+	kind rune // 'n': number, 'i': identifier, 'v': variable, 'o': operator, 0: EOF
+	str  string
+	num  float64
+}
+
+type exprParser struct {
+	src    string
+	toks   []exprToken
+	pos    int
+	lookup func(name string) (string, error)
+}
+
+func newExprParser(src string, lookup func(name string) (string, error)) *exprParser {
+	return &exprParser{src: src, lookup: lookup}
+}
+
+func (p *exprParser) parse() (func(vs *[nvars]float64) float64, error) {
+	err := p.scan()
+	if err != nil {
+		return nil, err
+	}
+	eval, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != 0 {
+		return nil, fmt.Errorf("unexpected token %q", tok.str)
+	}
+	return eval, nil
+}
+
+var exprOps = []string{
+	"**", "&&", "||", "==", "!=", "<=", ">=",
+	"+", "-", "*", "/", "%", "^", "<", ">", "!", "(", ")", ",", "?", ":",
+}
+
+func (p *exprParser) scan() error {
+	src := p.src
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.') {
+				j++
+			}
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				k := j + 1
+				if k < len(src) && (src[k] == '+' || src[k] == '-') {
+					k++
+				}
+				if k < len(src) && isDigit(src[k]) {
+					j = k
+					for j < len(src) && isDigit(src[j]) {
+						j++
+					}
+				}
+			}
+			v, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return fmt.Errorf("invalid number %q: %w", src[i:j], err)
+			}
+			p.toks = append(p.toks, exprToken{kind: 'n', str: src[i:j], num: v})
+			i = j
+
+		case c == '$':
+			i++
+			var name string
+			if i < len(src) && src[i] == '{' {
+				j := strings.IndexByte(src[i:], '}')
+				if j < 0 {
+					return fmt.Errorf("missing close-brace for variable name")
+				}
+				name = src[i+1 : i+j]
+				i += j + 1
+			} else {
+				j := i
+				for j < len(src) && isIdent(src[j]) {
+					j++
+				}
+				name = src[i:j]
+				i = j
+			}
+			if name == "" {
+				return fmt.Errorf("invalid empty variable name")
+			}
+			p.toks = append(p.toks, exprToken{kind: 'v', str: name})
+
+		case isIdent(src[i]):
+			j := i
+			for j < len(src) {
+				if isIdent(src[j]) {
+					j++
+					continue
+				}
+				if strings.HasPrefix(src[j:], "::") {
+					j += 2
+					continue
+				}
+				break
+			}
+			p.toks = append(p.toks, exprToken{kind: 'i', str: src[i:j]})
+			i = j
+
+		default:
+			op := ""
+			for _, o := range exprOps {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("invalid character %q", c)
+			}
+			p.toks = append(p.toks, exprToken{kind: 'o', str: op})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func (p *exprParser) peek() exprToken {
+	if p.pos >= len(p.toks) {
+		return exprToken{}
+	}
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != 'o' {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.str == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		tok := p.peek()
+		if tok.kind == 0 {
+			return fmt.Errorf("expected %q, got end of expression", op)
+		}
+		return fmt.Errorf("expected %q, got %q", op, tok.str)
+	}
+	return nil
+}
+
+type evalFunc = func(vs *[nvars]float64) float64
+
+func b2f(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func (p *exprParser) parseTernary() (evalFunc, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return cond, nil
+	}
+	lhs, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	err = p.expect(":")
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return func(vs *[nvars]float64) float64 {
+		if cond(vs) != 0 {
+			return lhs(vs)
+		}
+		return rhs(vs)
+	}, nil
+}
+
+// binary operators, by increasing precedence.
+var exprBinOps = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(lvl int) (evalFunc, error) {
+	if lvl == len(exprBinOps) {
+		return p.parseUnary()
+	}
+	lhs, err := p.parseBinary(lvl + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(exprBinOps[lvl]...)
+		if !ok {
+			return lhs, nil
+		}
+		rhs, err := p.parseBinary(lvl + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = binop(op, lhs, rhs)
+	}
+}
+
+func binop(op string, x, y evalFunc) evalFunc {
+	switch op {
+	case "||":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) != 0 || y(vs) != 0) }
+	case "&&":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) != 0 && y(vs) != 0) }
+	case "==":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) == y(vs)) }
+	case "!=":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) != y(vs)) }
+	case "<":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) < y(vs)) }
+	case "<=":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) <= y(vs)) }
+	case ">":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) > y(vs)) }
+	case ">=":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) >= y(vs)) }
+	case "+":
+		return func(vs *[nvars]float64) float64 { return x(vs) + y(vs) }
+	case "-":
+		return func(vs *[nvars]float64) float64 { return x(vs) - y(vs) }
+	case "*":
+		return func(vs *[nvars]float64) float64 { return x(vs) * y(vs) }
+	case "/":
+		return func(vs *[nvars]float64) float64 { return x(vs) / y(vs) }
+	case "%":
+		return func(vs *[nvars]float64) float64 { return math.Mod(x(vs), y(vs)) }
+	case "^", "**":
+		return func(vs *[nvars]float64) float64 { return math.Pow(x(vs), y(vs)) }
+	}
+	panic("delphes: invalid binary operator " + op)
+}
+
+func (p *exprParser) parseUnary() (evalFunc, error) {
+	op, ok := p.accept("-", "+", "!")
+	if !ok {
+		return p.parsePow()
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "-":
+		return func(vs *[nvars]float64) float64 { return -x(vs) }, nil
+	case "!":
+		return func(vs *[nvars]float64) float64 { return b2f(x(vs) == 0) }, nil
+	}
+	return x, nil
+}
+
+func (p *exprParser) parsePow() (evalFunc, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("^", "**")
+	if !ok {
+		return x, nil
+	}
+	y, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return binop(op, x, y), nil
+}
+
+func (p *exprParser) parsePrimary() (evalFunc, error) {
+	tok := p.next()
+	switch tok.kind {
+	case 'n':
+		v := tok.num
+		return func(*[nvars]float64) float64 { return v }, nil
+
+	case 'v':
+		if p.lookup == nil {
+			return nil, fmt.Errorf("invalid variable reference $%s", tok.str)
+		}
+		str, err := p.lookup(tok.str)
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q is not a number (%q)", tok.str, str)
+		}
+		return func(*[nvars]float64) float64 { return v }, nil
+
+	case 'i':
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(tok.str)
+		}
+		return p.ident(tok.str)
+
+	case 'o':
+		if tok.str == "(" {
+			x, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			err = p.expect(")")
+			if err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+		return nil, fmt.Errorf("unexpected operator %q", tok.str)
+	}
+	return nil, fmt.Errorf("unexpected end of expression")
+}
+
+func (p *exprParser) ident(name string) (evalFunc, error) {
+	idx := -1
+	switch name {
+	case "pt":
+		idx = varPt
+	case "eta":
+		idx = varEta
+	case "phi":
+		idx = varPhi
+	case "energy", "e":
+		idx = varEnergy
+	case "pi":
+		return func(*[nvars]float64) float64 { return math.Pi }, nil
+	}
+	if idx < 0 || p.lookup != nil {
+		return nil, fmt.Errorf("unknown identifier %q", name)
+	}
+	return func(vs *[nvars]float64) float64 { return vs[idx] }, nil
+}
+
+var exprFuncs1 = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"fabs":  math.Abs,
+	"sqrt":  math.Sqrt,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"log10": math.Log10,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+	"sinh":  math.Sinh,
+	"cosh":  math.Cosh,
+	"tanh":  math.Tanh,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+	"int":   math.Trunc,
+	"double": func(x float64) float64 {
+		return x
+	},
+
+	"TMath::Abs":   math.Abs,
+	"TMath::Sqrt":  math.Sqrt,
+	"TMath::Exp":   math.Exp,
+	"TMath::Log":   math.Log,
+	"TMath::Log10": math.Log10,
+	"TMath::Sin":   math.Sin,
+	"TMath::Cos":   math.Cos,
+	"TMath::Tan":   math.Tan,
+	"TMath::ASin":  math.Asin,
+	"TMath::ACos":  math.Acos,
+	"TMath::ATan":  math.Atan,
+	"TMath::SinH":  math.Sinh,
+	"TMath::CosH":  math.Cosh,
+	"TMath::TanH":  math.Tanh,
+	"TMath::Erf":   math.Erf,
+	"TMath::Erfc":  math.Erfc,
+}
+
+var exprFuncs2 = map[string]func(x, y float64) float64{
+	"pow":          math.Pow,
+	"atan2":        math.Atan2,
+	"fmod":         math.Mod,
+	"min":          math.Min,
+	"max":          math.Max,
+	"hypot":        math.Hypot,
+	"TMath::Power": math.Pow,
+	"TMath::ATan2": math.Atan2,
+	"TMath::Min":   math.Min,
+	"TMath::Max":   math.Max,
+	"TMath::Hypot": math.Hypot,
+}
+
+func (p *exprParser) parseCall(name string) (evalFunc, error) {
+	var args []evalFunc
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			err = p.expect(")")
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	switch name {
+	case "TMath::Pi":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("invalid number of arguments to %s (got=%d, want=0)", name, len(args))
+		}
+		return func(*[nvars]float64) float64 { return math.Pi }, nil
+	}
+
+	if fct, ok := exprFuncs1[name]; ok {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("invalid number of arguments to %s (got=%d, want=1)", name, len(args))
+		}
+		x := args[0]
+		return func(vs *[nvars]float64) float64 { return fct(x(vs)) }, nil
+	}
+
+	if fct, ok := exprFuncs2[name]; ok {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("invalid number of arguments to %s (got=%d, want=2)", name, len(args))
+		}
+		x := args[0]
+		y := args[1]
+		return func(vs *[nvars]float64) float64 { return fct(x(vs), y(vs)) }, nil
+	}
+
+	return nil, fmt.Errorf("unknown function %q", name)
+}
@@ -0,0 +1,876 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package delphes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-hep.org/x/hep/fads"
+	"go-hep.org/x/hep/fastjet"
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/job"
+)
+
+const pkg = "go-hep.org/x/hep/fads."
+
+// converter converts the parameters of a Delphes module into the
+// properties of a fads task.
+type converter struct {
+	typ  string // fads task type
+	conv func(b *builder)
+
+	// inplace holds the name of the Delphes parameter of the input
+	// collection that the Delphes module modifies in place.
+	// Modules that run later in the execution path and read this
+	// collection are connected to the output of the fads task instead.
+	inplace string
+	output  string // fads property holding the output of an in-place module
+}
+
+var converters = map[string]converter{
+	"ParticlePropagator": {
+		typ: "Propagator",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "Delphes/stableParticles")
+			b.output("OutputArray", "Output", "stableParticles")
+			b.output("ChargedHadronOutputArray", "ChargedHadrons", "chargedHadrons")
+			b.output("ElectronOutputArray", "Electrons", "electrons")
+			b.output("MuonOutputArray", "Muons", "muons")
+			b.float("Radius", "Radius")
+			b.float("HalfLength", "HalfLength")
+			b.float("Bz", "Bz")
+		},
+	},
+	"Efficiency": {
+		typ: "Efficiency",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "ParticlePropagator/stableParticles")
+			b.output("OutputArray", "Output", "stableParticles")
+			b.ptEtaFormula("EfficiencyFormula", "Eff")
+		},
+	},
+	"MomentumSmearing": {
+		typ: "MomentumSmearing",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "ParticlePropagator/stableParticles")
+			b.output("OutputArray", "Output", "stableParticles")
+			b.ptEtaFormula("ResolutionFormula", "Resolution")
+		},
+	},
+	"EnergySmearing": {
+		typ: "EnergySmearing",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "ParticlePropagator/stableParticles")
+			b.output("OutputArray", "Output", "stableParticles")
+			b.etaEneFormula("ResolutionFormula", "Resolution")
+		},
+	},
+	"ImpactParameterSmearing": {
+		typ: "ImpactParameterSmearing",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "ParticlePropagator/stableParticles")
+			b.output("OutputArray", "Output", "stableParticles")
+			b.ptEtaFormula("ResolutionFormula", "Resolution")
+		},
+	},
+	"Merger": {
+		typ: "Merger",
+		conv: func(b *builder) {
+			b.inputs("InputArray", "Inputs")
+			b.output("OutputArray", "Output", "candidates")
+			b.output("MomentumOutputArray", "MomentumOutput", "momentum")
+			b.output("EnergyOutputArray", "EnergyOutput", "energy")
+		},
+	},
+	"Calorimeter": {
+		typ: "Calorimeter",
+		conv: func(b *builder) {
+			b.input("ParticleInputArray", "Particles", "ParticlePropagator/particles")
+			b.input("TrackInputArray", "Tracks", "ParticlePropagator/tracks")
+			b.output("TowerOutputArray", "Towers", "towers")
+			b.output("PhotonOutputArray", "Photons", "photons")
+			b.output("EFlowTrackOutputArray", "EFlowTracks", "eflowTracks")
+			b.output("EFlowTowerOutputArray", "EFlowTowers", "eflowTowers")
+			b.etaPhiBins("EtaPhiBins", "EtaPhiBins")
+			b.energyFractions("EnergyFraction", "EnergyFraction")
+			b.etaEneFormula("ECalResolutionFormula", "ECalResolution")
+			b.etaEneFormula("HCalResolutionFormula", "HCalResolution")
+			b.particleFlow()
+		},
+	},
+	"SimpleCalorimeter": {
+		typ: "SimpleCalorimeter",
+		conv: func(b *builder) {
+			b.input("ParticleInputArray", "Particles", "ParticlePropagator/particles")
+			b.input("TrackInputArray", "Tracks", "ParticlePropagator/tracks")
+			b.output("TowerOutputArray", "Towers", "towers")
+			b.output("EFlowTrackOutputArray", "EFlowTracks", "eflowTracks")
+			b.output("EFlowTowerOutputArray", "EFlowTowers", "eflowTowers")
+			b.etaPhiBins("EtaPhiBins", "EtaPhiBins")
+			b.energyFraction("EnergyFraction", "EnergyFraction")
+			b.etaEneFormula("ResolutionFormula", "Resolution")
+			b.bool("IsEcal", "IsECal")
+			b.float("EnergyMin", "EnergyMin")
+			b.float("EnergySignificanceMin", "EnergySignificanceMin")
+			b.bool("SmearTowerCenter", "SmearTowerCenter")
+		},
+	},
+	"PdgCodeFilter": {
+		typ: "PdgCodeFilter",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "Delphes/allParticles")
+			b.output("OutputArray", "Output", "filteredParticles")
+			b.float("PTMin", "PtMin")
+			b.ints("PdgCode", "PdgCodes")
+			b.bool("Invert", "Invert")
+			b.bool("RequireStatus", "RequireStatus")
+			b.int("Status", "Status")
+			b.bool("RequireCharge", "RequireCharge")
+			b.int("Charge", "Charge")
+			b.bool("RequireNotPileup", "RequireNotPileUp")
+		},
+	},
+	"StatusPidFilter": {
+		typ: "StatusPidFilter",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "Delphes/allParticles")
+			b.output("OutputArray", "Output", "filteredParticles")
+			b.float("PTMin", "PtMin")
+		},
+	},
+	"Isolation": {
+		typ: "Isolation",
+		conv: func(b *builder) {
+			b.input("CandidateInputArray", "Candidates", "Calorimeter/electrons")
+			b.input("IsolationInputArray", "Isolations", "Delphes/partons")
+			b.optInput("RhoInputArray", "Rhos")
+			b.output("OutputArray", "Output", "electrons")
+			b.float("DeltaRMax", "DeltaRMax")
+			b.float("PTMin", "PtMin")
+			b.float("PTRatioMax", "PtRatioMax")
+			b.float("PTSumMax", "PtSumMax")
+			b.bool("UsePTSum", "UsePtSum")
+		},
+	},
+	"FastJetFinder": {
+		typ: "FastJetFinder",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "Calorimeter/towers")
+			b.output("OutputArray", "Output", "jets")
+			b.output("RhoOutputArray", "Rho", "rho")
+			b.jetAlgorithm("JetAlgorithm", "JetAlgorithm")
+			b.float("ParameterR", "ParameterR")
+			b.float("JetPTMin", "JetPtMin")
+			b.float("ConeRadius", "ConeRadius")
+			b.float("SeedThreshold", "SeedThreshold")
+			b.float("ConeAreaFraction", "ConeAreaFraction")
+			b.int("MaxIterations", "MaxIterations")
+			b.int("MaxPairSize", "MaxPairSize")
+			b.int("Iratch", "Iratch")
+			b.int("AdjacencyCut", "AdjacencyCut")
+			b.float("OverlapThreshold", "OverlapThreshold")
+			b.int("AreaAlgorithm", "AreaAlgorithm")
+			b.bool("ComputeRho", "ComputeRho")
+			b.float("GhostEtaMax", "GhostEtaMax")
+			b.int("Repeat", "Repeat")
+			b.float("GhostArea", "GhostArea")
+			b.float("GridScatter", "GridScatter")
+			b.float("PtScatter", "PtScatter")
+			b.float("MeanGhostPt", "MeanGhostPt")
+			b.float("EffectiveRfact", "EffectiveRfact")
+			b.rhoEtaRange("RhoEtaRange", "RhoEtaRange")
+		},
+	},
+	"EnergyScale": {
+		typ: "EnergyScale",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "FastJetFinder/jets")
+			b.output("OutputArray", "Output", "jets")
+			b.ptEtaFormula("ScaleFormula", "Scale")
+		},
+	},
+	"BTagging": {
+		typ: "BTagging",
+		conv: func(b *builder) {
+			// recent Delphes versions use the flavour of the jets,
+			// as assigned by JetFlavorAssociation.
+			b.props["Partons"] = ""
+			b.optInput("PartonInputArray", "Partons")
+			b.input("JetInputArray", "Jets", "FastJetFinder/jets")
+			b.output("", "Output", "jets")
+			b.uint("BitNumber", "BitNumber")
+			b.float("DeltaR", "DeltaR")
+			b.float("PartonPTMin", "PartonPtMin")
+			b.float("PartonEtaMax", "PartonEtaMax")
+			b.efficiencies("EfficiencyFormula", "Eff")
+		},
+		inplace: "JetInputArray",
+		output:  "Output",
+	},
+	"JetFlavorAssociation": {
+		typ: "JetFlavourAssociation",
+		conv: func(b *builder) {
+			b.input("PartonInputArray", "Partons", "Delphes/partons")
+			b.input("ParticleInputArray", "Particles", "Delphes/allParticles")
+			b.input("JetInputArray", "Jets", "FastJetFinder/jets")
+			b.output("", "Output", "jets")
+			b.props["GhostAssociation"] = false
+			b.float("DeltaR", "DeltaR")
+			b.float("PartonPTMin", "PartonPtMin")
+			b.float("PartonEtaMax", "PartonEtaMax")
+		},
+		inplace: "JetInputArray",
+		output:  "Output",
+	},
+	"TauTagging": {
+		typ: "TauTagging",
+		conv: func(b *builder) {
+			b.input("ParticleInputArray", "Particles", "Delphes/allParticles")
+			b.input("PartonInputArray", "Partons", "Delphes/partons")
+			b.input("JetInputArray", "Jets", "FastJetFinder/jets")
+			b.output("", "Output", "jets")
+			b.float("DeltaR", "DeltaR")
+			b.float("TauPTMin", "TauPtMin")
+			b.float("TauEtaMax", "TauEtaMax")
+			b.efficiencies("EfficiencyFormula", "Eff")
+		},
+		inplace: "JetInputArray",
+		output:  "Output",
+	},
+	"TrackCountingBTagging": {
+		typ: "TrackCountingBTagging",
+		conv: func(b *builder) {
+			b.input("TrackInputArray", "Tracks", "Calorimeter/eflowTracks")
+			b.input("JetInputArray", "Jets", "FastJetFinder/jets")
+			b.output("", "Output", "jets")
+			b.uint("BitNumber", "BitNumber")
+			b.float("TrackPtMin", "TrackPtMin")
+			b.float("DeltaR", "DeltaR")
+			b.float("TrackIPMax", "TrackIPMax")
+			b.float("SigMin", "SigMin")
+			b.int("Ntracks", "Ntracks")
+		},
+		inplace: "JetInputArray",
+		output:  "Output",
+	},
+	"UniqueObjectFinder": {
+		typ: "UniqueObjectFinder",
+		conv: func(b *builder) {
+			b.pairs("InputArray", "Keys")
+		},
+	},
+	"PileUpMerger": {
+		typ: "PileUpMerger",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "Delphes/stableParticles")
+			b.output("OutputArray", "Output", "stableParticles")
+			b.output("VertexOutputArray", "VertexOutput", "vertices")
+			b.string("PileUpFile", "PileUpFile")
+			b.float("MeanPileUp", "MeanPileUp")
+			b.pileUpDistribution("PileUpDistribution", "PileUpDistribution")
+			b.float("ZVertexSpread", "ZVertexSpread")
+			b.float("TVertexSpread", "TVertexSpread")
+		},
+	},
+	"TrackSmearing": {
+		typ: "TrackSmearing",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "ParticlePropagator/stableParticles")
+			b.output("OutputArray", "Output", "stableParticles")
+			b.ptEtaFormula("D0ResolutionFormula", "D0Resolution")
+			b.ptEtaFormula("DZResolutionFormula", "DZResolution")
+			b.ptEtaFormula("PResolutionFormula", "PResolution")
+			b.ptEtaFormula("CtgThetaResolutionFormula", "CtgThetaResolution")
+			b.ptEtaFormula("PhiResolutionFormula", "PhiResolution")
+			b.bool("ApplyToPileUp", "ApplyToPileUp")
+		},
+	},
+	"VertexFinder": {
+		typ: "VertexFinder",
+		conv: func(b *builder) {
+			b.input("InputArray", "Input", "TrackSmearing/tracks")
+			b.output("OutputArray", "Output", "tracks")
+			b.output("VertexOutputArray", "VertexOutput", "vertices")
+			b.float("Sigma", "Sigma")
+			b.float("MinPT", "MinPt")
+			b.float("MaxEta", "MaxEta")
+			b.float("SeedMinPT", "SeedMinPt")
+			b.int("MinNDF", "MinNDF")
+			b.bool("GrowSeeds", "GrowSeeds")
+		},
+	},
+	"TreeWriter": {
+		typ: "TreeWriter",
+		conv: func(b *builder) {
+			b.treeBranches("Branch", "Branches")
+		},
+	},
+	"TrackPileUpSubtractor": {
+		typ: "TrackPileUpSubtractor",
+		conv: func(b *builder) {
+			b.input("VertexInputArray", "Vertices", "PileUpMerger/vertices")
+			b.pairs("InputArray", "Keys")
+			b.float("ZVertexResolution", "ZVertexResolution")
+			b.float("PTMin", "PtMin")
+		},
+	},
+}
+
+// Configs returns the fads tasks configurations corresponding to the
+// modules of the execution path of the card.
+//
+// Collections produced by the Delphes reader ("Delphes/allParticles",
+// "Delphes/stableParticles" and "Delphes/partons") are mapped to the
+// default outputs of the fads.HepMcReader task.
+// The output collection "coll" of a module "Mod" is mapped to "/fads/Mod/coll".
+func (card *Card) Configs() ([]job.C, error) {
+	var (
+		path    = card.ExecutionPath()
+		cfgs    = make([]job.C, 0, len(path))
+		aliases = make(map[string]string)
+	)
+
+	for _, name := range path {
+		mod := card.Module(name)
+		if mod == nil {
+			return nil, fmt.Errorf("delphes: no module %q declared in card", name)
+		}
+		cnv, ok := converters[mod.Type]
+		if !ok {
+			return nil, fmt.Errorf("delphes: module %q has unsupported type %q", mod.Name, mod.Type)
+		}
+
+		b := &builder{
+			mod:     mod,
+			props:   make(job.P),
+			aliases: aliases,
+		}
+		cnv.conv(b)
+		if b.err != nil {
+			return nil, b.err
+		}
+
+		if cnv.inplace != "" {
+			in := b.key(b.param(cnv.inplace, ""))
+			aliases[in] = b.props[cnv.output].(string)
+		}
+
+		cfgs = append(cfgs, job.C{
+			Type:  pkg + cnv.typ,
+			Name:  mod.Name,
+			Props: b.props,
+		})
+		cfgs = append(cfgs, b.extra...)
+	}
+
+	return cfgs, nil
+}
+
+// Create creates and configures, inside the provided application,
+// the fads tasks corresponding to the execution path of the card.
+func (card *Card) Create(app fwk.App) error {
+	cfgs, err := card.Configs()
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range cfgs {
+		c, err := app.New(cfg.Type, cfg.Name)
+		if err != nil {
+			return fmt.Errorf("delphes: could not create %s:%s: %w", cfg.Type, cfg.Name, err)
+		}
+
+		keys := make([]string, 0, len(cfg.Props))
+		for k := range cfg.Props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if !app.HasProp(c, k) {
+				return fmt.Errorf("delphes: component %s:%s has no property named %q", cfg.Type, cfg.Name, k)
+			}
+			err = app.SetProp(c, k, cfg.Props[k])
+			if err != nil {
+				return fmt.Errorf("delphes: could not set property %q of %s:%s: %w", k, cfg.Type, cfg.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// builder accumulates the fads properties of a Delphes module.
+type builder struct {
+	mod     *Module
+	props   job.P
+	aliases map[string]string
+	extra   []job.C // additional tasks needed to emulate the module
+	err     error
+}
+
+func (b *builder) errorf(format string, args ...any) {
+	if b.err != nil {
+		return
+	}
+	b.err = fmt.Errorf("delphes: module %q: "+format, append([]any{b.mod.Name}, args...)...)
+}
+
+func (b *builder) fail(err error) {
+	if b.err != nil {
+		return
+	}
+	b.err = err
+}
+
+func (b *builder) param(name, def string) string {
+	v, ok := b.mod.String(name)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// key returns the fads name of the Delphes collection name.
+func (b *builder) key(name string) string {
+	switch name {
+	case "Delphes/allParticles":
+		return "/fads/AllParticles"
+	case "Delphes/stableParticles":
+		return "/fads/StableParticles"
+	case "Delphes/partons":
+		return "/fads/Partons"
+	}
+	return "/fads/" + name
+}
+
+// ref returns the fads name of the input Delphes collection name,
+// taking into account collections modified in place.
+func (b *builder) ref(name string) string {
+	key := b.key(name)
+	if v, ok := b.aliases[key]; ok {
+		return v
+	}
+	return key
+}
+
+func (b *builder) input(dk, fk, def string) {
+	b.props[fk] = b.ref(b.param(dk, def))
+}
+
+func (b *builder) optInput(dk, fk string) {
+	v := b.param(dk, "")
+	if v == "" {
+		return
+	}
+	b.props[fk] = b.ref(v)
+}
+
+func (b *builder) inputs(dk, fk string) {
+	list := b.mod.List(dk)
+	vs := make([]string, len(list))
+	for i, v := range list {
+		vs[i] = b.ref(v)
+	}
+	b.props[fk] = vs
+}
+
+func (b *builder) output(dk, fk, def string) {
+	v := def
+	if dk != "" {
+		v = b.param(dk, def)
+	}
+	b.props[fk] = "/fads/" + b.mod.Name + "/" + v
+}
+
+func (b *builder) pairs(dk, fk string) {
+	list := b.mod.List(dk)
+	if len(list)%2 != 0 {
+		b.errorf("parameter %s needs an even number of elements (got %d)", dk, len(list))
+		return
+	}
+	pairs := make([]fads.ObjPair, 0, len(list)/2)
+	for i := 0; i < len(list); i += 2 {
+		pairs = append(pairs, fads.ObjPair{
+			In:  b.ref(list[i]),
+			Out: "/fads/" + b.mod.Name + "/" + list[i+1],
+		})
+	}
+	b.props[fk] = pairs
+}
+
+func (b *builder) string(dk, fk string) {
+	v, ok := b.mod.String(dk)
+	if !ok {
+		return
+	}
+	b.props[fk] = v
+}
+
+func (b *builder) float(dk, fk string) {
+	v, ok, err := b.mod.Float(dk)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	b.props[fk] = v
+}
+
+func (b *builder) int(dk, fk string) {
+	v, ok, err := b.mod.Int(dk)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	b.props[fk] = v
+}
+
+func (b *builder) uint(dk, fk string) {
+	v, ok, err := b.mod.Int(dk)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if v < 0 {
+		b.errorf("invalid negative parameter %s=%d", dk, v)
+		return
+	}
+	b.props[fk] = uint(v)
+}
+
+func (b *builder) bool(dk, fk string) {
+	v, ok, err := b.mod.Bool(dk)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	b.props[fk] = v
+}
+
+// ptEtaFormula converts a Delphes formula into a func(pt, eta float64) float64.
+func (b *builder) ptEtaFormula(dk, fk string) {
+	f, ok, err := b.mod.Formula(dk)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	b.props[fk] = ptEtaFunc(f)
+}
+
+// etaEneFormula converts a Delphes formula into a func(eta, ene float64) float64.
+func (b *builder) etaEneFormula(dk, fk string) {
+	f, ok, err := b.mod.Formula(dk)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	b.props[fk] = func(eta, ene float64) float64 {
+		return f.Eval(ene/math.Cosh(eta), eta, 0, ene)
+	}
+}
+
+func ptEtaFunc(f Formula) func(pt, eta float64) float64 {
+	return func(pt, eta float64) float64 {
+		return f.Eval(pt, eta, 0, pt*math.Cosh(eta))
+	}
+}
+
+// efficiencies converts a list of (PDG-ID, formula) pairs into a map of
+// efficiency functions.
+func (b *builder) efficiencies(dk, fk string) {
+	list := b.mod.List(dk)
+	if len(list)%2 != 0 {
+		b.errorf("parameter %s needs an even number of elements (got %d)", dk, len(list))
+		return
+	}
+	effs := make(map[int]func(pt, eta float64) float64, len(list)/2)
+	for i := 0; i < len(list); i += 2 {
+		pid, err := strconv.Atoi(strings.TrimSpace(list[i]))
+		if err != nil {
+			b.errorf("invalid PDG-ID %q in parameter %s", list[i], dk)
+			return
+		}
+		f, err := ParseFormula(list[i+1])
+		if err != nil {
+			b.errorf("invalid formula in parameter %s: %w", dk, err)
+			return
+		}
+		effs[pid] = ptEtaFunc(f)
+	}
+	if len(effs) == 0 {
+		return
+	}
+	b.props[fk] = effs
+}
+
+// ints converts a list of integer values.
+func (b *builder) ints(dk, fk string) {
+	list := b.mod.List(dk)
+	if len(list) == 0 {
+		return
+	}
+	vs := make([]int, len(list))
+	for i, s := range list {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			b.errorf("invalid integer value %q in parameter %s", s, dk)
+			return
+		}
+		vs[i] = v
+	}
+	b.props[fk] = vs
+}
+
+// floats parses a list of floating point values.
+func (b *builder) floats(dk, v string) []float64 {
+	list := splitList(v)
+	vs := make([]float64, len(list))
+	for i, s := range list {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			b.errorf("invalid float value %q in parameter %s", s, dk)
+			return nil
+		}
+		vs[i] = f
+	}
+	return vs
+}
+
+// etaPhiBins converts a list of (eta, phi-bins) pairs into a fads.EtaPhiGrid.
+func (b *builder) etaPhiBins(dk, fk string) {
+	list := b.mod.List(dk)
+	if len(list) == 0 {
+		return
+	}
+	if len(list)%2 != 0 {
+		b.errorf("parameter %s needs an even number of elements (got %d)", dk, len(list))
+		return
+	}
+	bins := make([]fads.EtaPhiBin, 0, len(list)/2)
+	for i := 0; i < len(list); i += 2 {
+		eta := b.floats(dk, list[i])
+		phi := b.floats(dk, list[i+1])
+		if b.err != nil {
+			return
+		}
+		bins = append(bins, fads.EtaPhiBin{
+			EtaBins: eta,
+			PhiBins: phi,
+		})
+	}
+	b.props[fk] = fads.NewEtaPhiGrid(bins)
+}
+
+// energyFractions converts a list of (PDG-ID, {ECal HCal}) pairs into
+// a map of energy fractions.
+func (b *builder) energyFractions(dk, fk string) {
+	list := b.mod.List(dk)
+	if len(list) == 0 {
+		return
+	}
+	if len(list)%2 != 0 {
+		b.errorf("parameter %s needs an even number of elements (got %d)", dk, len(list))
+		return
+	}
+	fracs := make(map[int]fads.EneFrac, len(list)/2)
+	for i := 0; i < len(list); i += 2 {
+		pid, err := strconv.Atoi(strings.TrimSpace(list[i]))
+		if err != nil {
+			b.errorf("invalid PDG-ID %q in parameter %s", list[i], dk)
+			return
+		}
+		vs := b.floats(dk, list[i+1])
+		if b.err != nil {
+			return
+		}
+		if len(vs) < 2 {
+			b.errorf("invalid energy fractions %q for PDG-ID %d", list[i+1], pid)
+			return
+		}
+		fracs[pid] = fads.EneFrac{ECal: vs[0], HCal: vs[1]}
+	}
+	b.props[fk] = fracs
+}
+
+// energyFraction converts a list of (PDG-ID, fraction) pairs into
+// a map of energy fractions.
+func (b *builder) energyFraction(dk, fk string) {
+	list := b.mod.List(dk)
+	if len(list) == 0 {
+		return
+	}
+	if len(list)%2 != 0 {
+		b.errorf("parameter %s needs an even number of elements (got %d)", dk, len(list))
+		return
+	}
+	fracs := make(map[int]float64, len(list)/2)
+	for i := 0; i < len(list); i += 2 {
+		pid, err := strconv.Atoi(strings.TrimSpace(list[i]))
+		if err != nil {
+			b.errorf("invalid PDG-ID %q in parameter %s", list[i], dk)
+			return
+		}
+		vs := b.floats(dk, list[i+1])
+		if b.err != nil {
+			return
+		}
+		if len(vs) != 1 {
+			b.errorf("invalid energy fraction %q for PDG-ID %d", list[i+1], pid)
+			return
+		}
+		fracs[pid] = vs[0]
+	}
+	b.props[fk] = fracs
+}
+
+// rhoEtaRange converts a list of (eta-min, eta-max) pairs into a map.
+func (b *builder) rhoEtaRange(dk, fk string) {
+	vs := b.floats(dk, b.mod.params[dk])
+	if b.err != nil || len(vs) == 0 {
+		return
+	}
+	if len(vs)%2 != 0 {
+		b.errorf("parameter %s needs an even number of elements (got %d)", dk, len(vs))
+		return
+	}
+	ranges := make(map[float64]float64, len(vs)/2)
+	for i := 0; i < len(vs); i += 2 {
+		ranges[vs[i]] = vs[i+1]
+	}
+	b.props[fk] = ranges
+}
+
+// particleFlow adds a fads.ParticleFlow task when the calorimeter module
+// declares energy-flow photons or neutral hadrons outputs.
+// The energy-flow tracks of the Delphes calorimeter are then produced
+// by the particle-flow task.
+func (b *builder) particleFlow() {
+	_, pho := b.mod.Param("EFlowPhotonOutputArray")
+	_, neu := b.mod.Param("EFlowNeutralHadronOutputArray")
+	if !pho && !neu {
+		return
+	}
+
+	pf := &builder{
+		mod:     b.mod,
+		props:   make(job.P),
+		aliases: b.aliases,
+	}
+	pf.props["Tracks"] = b.props["Tracks"]
+	pf.props["Towers"] = b.props["Towers"]
+	pf.props["EFlowTracks"] = b.props["EFlowTracks"]
+	pf.output("EFlowPhotonOutputArray", "EFlowPhotons", "eflowPhotons")
+	pf.output("EFlowNeutralHadronOutputArray", "EFlowNeutralHadrons", "eflowNeutralHadrons")
+	for _, k := range []string{"EnergyFraction", "ECalResolution", "HCalResolution"} {
+		if v, ok := b.props[k]; ok {
+			pf.props[k] = v
+		}
+	}
+	pf.float("ECalEnergyMin", "ECalEnergyMin")
+	pf.float("HCalEnergyMin", "HCalEnergyMin")
+	pf.float("ECalEnergySignificanceMin", "ECalEnergySignificanceMin")
+	pf.float("HCalEnergySignificanceMin", "HCalEnergySignificanceMin")
+	if pf.err != nil {
+		b.fail(pf.err)
+		return
+	}
+
+	b.props["EFlowTracks"] = "/fads/" + b.mod.Name + "/calo/eflowTracks"
+	b.props["EFlowTowers"] = "/fads/" + b.mod.Name + "/calo/eflowTowers"
+	b.extra = append(b.extra, job.C{
+		Type:  pkg + "ParticleFlow",
+		Name:  b.mod.Name + "ParticleFlow",
+		Props: pf.props,
+	})
+}
+
+// treeBranches converts a list of (input, name, class) triplets into
+// the branches of a fads.TreeWriter.
+// Branches with a Delphes class not supported by fads are skipped.
+func (b *builder) treeBranches(dk, fk string) {
+	list := b.mod.List(dk)
+	if len(list)%3 != 0 {
+		b.errorf("parameter %s needs a multiple of 3 elements (got %d)", dk, len(list))
+		return
+	}
+	branches := make([]fads.TreeBranch, 0, len(list)/3)
+	for i := 0; i < len(list); i += 3 {
+		switch class := list[i+2]; class {
+		case "Jet", "Electron", "Muon", "Photon", "MissingET", "ScalarHT":
+			branches = append(branches, fads.TreeBranch{
+				Input: b.ref(list[i]),
+				Name:  list[i+1],
+				Class: class,
+			})
+		}
+	}
+	b.props[fk] = branches
+}
+
+// jetAlgorithm converts the Delphes jet algorithm identifier.
+func (b *builder) jetAlgorithm(dk, fk string) {
+	v, ok, err := b.mod.Int(dk)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	var alg fastjet.JetAlgorithm
+	switch v {
+	case 4:
+		alg = fastjet.KtAlgorithm
+	case 5:
+		alg = fastjet.CambridgeAlgorithm
+	case 6:
+		alg = fastjet.AntiKtAlgorithm
+	default:
+		b.errorf("unsupported jet algorithm %d", v)
+		return
+	}
+	b.props[fk] = alg
+}
+
+func (b *builder) pileUpDistribution(dk, fk string) {
+	v, ok, err := b.mod.Int(dk)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	if !ok {
+		return
+	}
+	dist := fads.PileUpDistribution(v)
+	switch dist {
+	case fads.PileUpPoisson, fads.PileUpUniform, fads.PileUpFixed:
+	default:
+		b.errorf("unsupported pile-up distribution %d", v)
+		return
+	}
+	b.props[fk] = dist
+}
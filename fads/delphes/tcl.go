@@ -0,0 +1,546 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package delphes
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// interp is a minimal Tcl interpreter, supporting the subset of Tcl
+// used by Delphes detector cards.
+type interp struct {
+	card *Card
+	mod  *Module // current module, nil at global scope
+	dir  string  // directory used to resolve 'source' commands
+
+	depth int
+}
+
+const maxDepth = 1000
+
+func newInterp(card *Card, dir string) *interp {
+	return &interp{card: card, dir: dir}
+}
+
+func (ip *interp) get(name string) (string, error) {
+	if ip.mod != nil {
+		if v, ok := ip.mod.params[name]; ok {
+			return v, nil
+		}
+	}
+	if v, ok := ip.card.vars[name]; ok {
+		return v, nil
+	}
+	if name == "pi" {
+		return strconv.FormatFloat(math.Pi, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("can't read %q: no such variable", name)
+}
+
+func (ip *interp) set(name, value string) {
+	if ip.mod != nil {
+		ip.mod.params[name] = value
+		return
+	}
+	ip.card.vars[name] = value
+}
+
+// eval evaluates the provided Tcl script and returns the result of
+// the last command.
+func (ip *interp) eval(script string) (string, error) {
+	ip.depth++
+	defer func() { ip.depth-- }()
+	if ip.depth > maxDepth {
+		return "", fmt.Errorf("too many nested evaluations")
+	}
+
+	var (
+		res string
+		err error
+		pos = 0
+	)
+	for pos < len(script) {
+		var words []string
+		words, pos, err = ip.parseCommand(script, pos)
+		if err != nil {
+			return "", err
+		}
+		if len(words) == 0 {
+			continue
+		}
+		res, err = ip.call(words)
+		if err != nil {
+			return "", err
+		}
+	}
+	return res, nil
+}
+
+// parseCommand parses and substitutes the words of the command
+// starting at position pos.
+// parseCommand returns the words and the position of the next command.
+func (ip *interp) parseCommand(src string, pos int) ([]string, int, error) {
+	var words []string
+
+	// skip leading blanks, empty commands and comments.
+	for pos < len(src) {
+		switch c := src[pos]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';':
+			pos++
+			continue
+		case c == '\\' && pos+1 < len(src) && src[pos+1] == '\n':
+			pos += 2
+			continue
+		case c == '#':
+			for pos < len(src) && src[pos] != '\n' {
+				if src[pos] == '\\' && pos+1 < len(src) {
+					pos++
+				}
+				pos++
+			}
+			continue
+		}
+		break
+	}
+
+	for pos < len(src) {
+		c := src[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			pos++
+			continue
+		case c == '\\' && pos+1 < len(src) && src[pos+1] == '\n':
+			pos += 2
+			continue
+		case c == '\n' || c == ';':
+			return words, pos + 1, nil
+		}
+
+		var (
+			word string
+			err  error
+		)
+		switch c {
+		case '{':
+			end, err := matchBrace(src, pos)
+			if err != nil {
+				return nil, pos, err
+			}
+			// backslash-newline sequences are the only substitution
+			// performed inside braces.
+			word = joinLines(src[pos+1 : end])
+			pos = end + 1
+		case '"':
+			word, pos, err = ip.subst(src, pos+1, func(c byte) bool { return c == '"' })
+			if err != nil {
+				return nil, pos, err
+			}
+			if pos >= len(src) {
+				return nil, pos, fmt.Errorf("missing close-quote")
+			}
+			pos++
+		default:
+			word, pos, err = ip.subst(src, pos, func(c byte) bool {
+				switch c {
+				case ' ', '\t', '\r', '\n', ';':
+					return true
+				}
+				return false
+			})
+			if err != nil {
+				return nil, pos, err
+			}
+		}
+		words = append(words, word)
+	}
+	return words, pos, nil
+}
+
+// joinLines replaces backslash-newline sequences, and the blanks
+// following them, with a single space.
+func joinLines(s string) string {
+	if !strings.Contains(s, "\\\n") {
+		return s
+	}
+	var o strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] == '\n' {
+			i += 2
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+				i++
+			}
+			i--
+			o.WriteByte(' ')
+			continue
+		}
+		o.WriteByte(s[i])
+	}
+	return o.String()
+}
+
+// matchBrace returns the position of the brace closing the one at pos.
+func matchBrace(src string, pos int) (int, error) {
+	depth := 0
+	for i := pos; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return -1, fmt.Errorf("missing close-brace")
+}
+
+// matchBracket returns the position of the bracket closing the one at pos.
+func matchBracket(src string, pos int) (int, error) {
+	depth := 0
+	for i := pos; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '{':
+			end, err := matchBrace(src, i)
+			if err != nil {
+				return -1, err
+			}
+			i = end
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return -1, fmt.Errorf("missing close-bracket")
+}
+
+// subst performs variable, command and backslash substitutions,
+// starting at pos until the stop function reports the end of the word.
+func (ip *interp) subst(src string, pos int, stop func(c byte) bool) (string, int, error) {
+	var o strings.Builder
+	for pos < len(src) && !stop(src[pos]) {
+		switch c := src[pos]; c {
+		case '\\':
+			pos++
+			if pos >= len(src) {
+				o.WriteByte('\\')
+				continue
+			}
+			switch c := src[pos]; c {
+			case 'n':
+				o.WriteByte('\n')
+			case 't':
+				o.WriteByte('\t')
+			case '\n':
+				o.WriteByte(' ')
+			default:
+				o.WriteByte(c)
+			}
+			pos++
+
+		case '$':
+			var name string
+			beg := pos + 1
+			switch {
+			case beg < len(src) && src[beg] == '{':
+				end := strings.IndexByte(src[beg:], '}')
+				if end < 0 {
+					return "", pos, fmt.Errorf("missing close-brace for variable name")
+				}
+				name = src[beg+1 : beg+end]
+				pos = beg + end + 1
+			default:
+				end := beg
+				for end < len(src) && isIdent(src[end]) {
+					end++
+				}
+				name = src[beg:end]
+				pos = end
+			}
+			if name == "" {
+				o.WriteByte('$')
+				continue
+			}
+			v, err := ip.get(name)
+			if err != nil {
+				return "", pos, err
+			}
+			o.WriteString(v)
+
+		case '[':
+			end, err := matchBracket(src, pos)
+			if err != nil {
+				return "", pos, err
+			}
+			v, err := ip.eval(src[pos+1 : end])
+			if err != nil {
+				return "", pos, err
+			}
+			o.WriteString(v)
+			pos = end + 1
+
+		default:
+			o.WriteByte(c)
+			pos++
+		}
+	}
+	return o.String(), pos, nil
+}
+
+func (ip *interp) call(words []string) (string, error) {
+	name := words[0]
+	args := words[1:]
+	switch name {
+	case "set":
+		switch len(args) {
+		case 1:
+			return ip.get(args[0])
+		case 2:
+			ip.set(args[0], args[1])
+			return args[1], nil
+		}
+		return "", fmt.Errorf("wrong # args: should be \"set varName ?newValue?\"")
+
+	case "add", "lappend":
+		if len(args) < 1 {
+			return "", fmt.Errorf("wrong # args: should be \"%s varName ?value ...?\"", name)
+		}
+		list, _ := ip.get(args[0])
+		elems := splitList(list)
+		elems = append(elems, args[1:]...)
+		v := joinList(elems)
+		ip.set(args[0], v)
+		return v, nil
+
+	case "incr":
+		if len(args) < 1 || len(args) > 2 {
+			return "", fmt.Errorf("wrong # args: should be \"incr varName ?increment?\"")
+		}
+		v, err := ip.get(args[0])
+		if err != nil {
+			return "", err
+		}
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("expected integer but got %q", v)
+		}
+		inc := int64(1)
+		if len(args) == 2 {
+			inc, err = strconv.ParseInt(strings.TrimSpace(args[1]), 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("expected integer but got %q", args[1])
+			}
+		}
+		v = strconv.FormatInt(i+inc, 10)
+		ip.set(args[0], v)
+		return v, nil
+
+	case "expr":
+		v, err := evalExpr(strings.Join(args, " "), ip.get)
+		if err != nil {
+			return "", err
+		}
+		return formatNumber(v), nil
+
+	case "for":
+		if len(args) != 4 {
+			return "", fmt.Errorf("wrong # args: should be \"for start test next command\"")
+		}
+		_, err := ip.eval(args[0])
+		if err != nil {
+			return "", err
+		}
+		for {
+			ok, err := evalExpr(args[1], ip.get)
+			if err != nil {
+				return "", err
+			}
+			if ok == 0 {
+				break
+			}
+			_, err = ip.eval(args[3])
+			if err != nil {
+				return "", err
+			}
+			_, err = ip.eval(args[2])
+			if err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+
+	case "foreach":
+		if len(args) != 3 {
+			return "", fmt.Errorf("wrong # args: should be \"foreach varName list body\"")
+		}
+		for _, v := range splitList(args[1]) {
+			ip.set(args[0], v)
+			_, err := ip.eval(args[2])
+			if err != nil {
+				return "", err
+			}
+		}
+		return "", nil
+
+	case "if":
+		for len(args) > 0 {
+			ok, err := evalExpr(args[0], ip.get)
+			if err != nil {
+				return "", err
+			}
+			args = args[1:]
+			if len(args) > 0 && args[0] == "then" {
+				args = args[1:]
+			}
+			if len(args) == 0 {
+				return "", fmt.Errorf("wrong # args: no script following expression")
+			}
+			body := args[0]
+			args = args[1:]
+			if ok != 0 {
+				return ip.eval(body)
+			}
+			if len(args) == 0 {
+				break
+			}
+			switch args[0] {
+			case "elseif":
+				args = args[1:]
+			case "else":
+				if len(args) != 2 {
+					return "", fmt.Errorf("wrong # args: no script following \"else\"")
+				}
+				return ip.eval(args[1])
+			default:
+				return "", fmt.Errorf("invalid if clause %q", args[0])
+			}
+		}
+		return "", nil
+
+	case "list":
+		return joinList(args), nil
+
+	case "puts":
+		return "", nil
+
+	case "source":
+		if len(args) != 1 {
+			return "", fmt.Errorf("wrong # args: should be \"source fileName\"")
+		}
+		fname := args[0]
+		if !filepath.IsAbs(fname) {
+			fname = filepath.Join(ip.dir, fname)
+		}
+		raw, err := os.ReadFile(fname)
+		if err != nil {
+			return "", err
+		}
+		return ip.eval(string(raw))
+
+	case "module":
+		if len(args) < 2 || len(args) > 3 {
+			return "", fmt.Errorf("wrong # args: should be \"module type name ?body?\"")
+		}
+		if ip.mod != nil {
+			return "", fmt.Errorf("nested module declaration %q", args[1])
+		}
+		mod := ip.card.Module(args[1])
+		switch {
+		case mod == nil:
+			mod = &Module{
+				Type:   args[0],
+				Name:   args[1],
+				params: make(map[string]string),
+			}
+			ip.card.Modules = append(ip.card.Modules, mod)
+		case mod.Type != args[0]:
+			return "", fmt.Errorf("module %q redeclared with type %q (was %q)", args[1], args[0], mod.Type)
+		}
+		if len(args) == 2 {
+			return "", nil
+		}
+		ip.mod = mod
+		defer func() { ip.mod = nil }()
+		_, err := ip.eval(args[2])
+		if err != nil {
+			return "", fmt.Errorf("module %q: %w", mod.Name, err)
+		}
+		return "", nil
+	}
+
+	return "", fmt.Errorf("invalid command name %q", name)
+}
+
+func formatNumber(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// splitList splits a Tcl list into its elements.
+func splitList(list string) []string {
+	var (
+		elems []string
+		pos   = 0
+	)
+	for pos < len(list) {
+		switch list[pos] {
+		case ' ', '\t', '\r', '\n':
+			pos++
+			continue
+		case '{':
+			end, err := matchBrace(list, pos)
+			if err == nil {
+				elems = append(elems, list[pos+1:end])
+				pos = end + 1
+				continue
+			}
+		case '"':
+			end := strings.IndexByte(list[pos+1:], '"')
+			if end >= 0 {
+				elems = append(elems, list[pos+1:pos+1+end])
+				pos += end + 2
+				continue
+			}
+		}
+		end := pos
+		for end < len(list) && !strings.ContainsRune(" \t\r\n", rune(list[end])) {
+			end++
+		}
+		elems = append(elems, list[pos:end])
+		pos = end
+	}
+	return elems
+}
+
+// joinList creates a Tcl list from the provided elements.
+func joinList(elems []string) string {
+	var o strings.Builder
+	for i, elem := range elems {
+		if i > 0 {
+			o.WriteByte(' ')
+		}
+		if elem == "" || strings.ContainsAny(elem, " \t\r\n{}\"") {
+			o.WriteString("{" + elem + "}")
+			continue
+		}
+		o.WriteString(elem)
+	}
+	return o.String()
+}
@@ -5,12 +5,9 @@
 package fads
 
 import (
-	"math/rand/v2"
 	"reflect"
-	"sync"
 
 	"go-hep.org/x/hep/fwk"
-	"gonum.org/v1/gonum/stat/distuv"
 )
 
 type Efficiency struct {
@@ -19,10 +16,8 @@ type Efficiency struct {
 	input  string
 	output string
 
-	eff  func(pt, eta float64) float64
-	seed uint64
-	dist distuv.Uniform
-	dmu  sync.Mutex
+	eff func(pt, eta float64) float64
+	rnd randsrc
 }
 
 func (tsk *Efficiency) Configure(ctx fwk.Context) error {
@@ -42,8 +37,7 @@ func (tsk *Efficiency) Configure(ctx fwk.Context) error {
 
 func (tsk *Efficiency) StartTask(ctx fwk.Context) error {
 	var err error
-	src := rand.New(rand.NewPCG(tsk.seed, tsk.seed))
-	tsk.dist = distuv.Uniform{Min: 0, Max: 1, Src: src}
+	err = tsk.rnd.start(ctx)
 	return err
 }
 
@@ -71,15 +65,19 @@ func (tsk *Efficiency) Process(ctx fwk.Context) error {
 		err = store.Put(tsk.output, output)
 	}()
 
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	for i := range input {
 		cand := &input[i]
 		eta := cand.Pos.Eta()
 		pt := cand.Mom.Pt()
 
 		// apply efficiency
-		tsk.dmu.Lock()
-		eff := tsk.dist.Rand()
-		tsk.dmu.Unlock()
+		eff := src.Float64()
 		max := tsk.eff(pt, eta)
 		if eff > max {
 			continue
@@ -100,7 +98,7 @@ func newEfficiency(typ, name string, mgr fwk.App) (fwk.Component, error) {
 		input:    "InputParticles",
 		output:   "OutputParticles",
 		eff:      func(x, y float64) float64 { return 1 },
-		seed:     1234,
+		rnd:      newRandSrc(1234),
 	}
 	err = tsk.DeclProp("Input", &tsk.input)
 	if err != nil {
@@ -117,7 +115,12 @@ func newEfficiency(typ, name string, mgr fwk.App) (fwk.Component, error) {
 		return nil, err
 	}
 
-	err = tsk.DeclProp("Seed", &tsk.seed)
+	err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,68 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"fmt"
+	"math"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/fwk"
+)
+
+// testContext runs a single task, outside of any application.
+// It has no services, so tasks use their own random generator.
+type testContext struct {
+	store testStore
+	msg   fwk.MsgStream
+}
+
+func newTestContext() *testContext {
+	return &testContext{
+		store: make(testStore),
+		msg:   fwk.NewMsgStream("test", fwk.LvlError, nil),
+	}
+}
+
+func (ctx *testContext) ID() int64          { return 0 }
+func (ctx *testContext) Slot() int          { return 0 }
+func (ctx *testContext) Store() fwk.Store   { return ctx.store }
+func (ctx *testContext) Msg() fwk.MsgStream { return ctx.msg }
+func (ctx *testContext) Svc(n string) (fwk.Svc, error) {
+	return nil, fmt.Errorf("no such service %q", n)
+}
+
+type testStore map[string]any
+
+func (store testStore) Get(key string) (any, error) {
+	v, ok := store[key]
+	if !ok {
+		return nil, fmt.Errorf("no such key %q", key)
+	}
+	return v, nil
+}
+
+func (store testStore) Put(key string, v any) error {
+	store[key] = v
+	return nil
+}
+
+func (store testStore) Has(key string) bool {
+	_, ok := store[key]
+	return ok
+}
+
+// newTrack returns a track with the given transverse momentum,
+// pseudo-rapidity and azimuthal angle.
+func newTrack(pt, eta, phi float64) Candidate {
+	return Candidate{
+		CandCharge: 1,
+		Mom:        newPtEtaPhiE(pt, eta, phi, pt*math.Cosh(eta)),
+	}
+}
+
+func newPos(x, y, z float64) fmom.PxPyPzE {
+	return fmom.NewPxPyPzE(x, y, z, 0)
+}
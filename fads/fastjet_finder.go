@@ -20,6 +20,7 @@ type FastJetFinder struct {
 	fwk.TaskBase
 
 	input  string
+	inputs []string // collections to cluster together, replacing input when not empty
 	output string
 	rho    string
 
@@ -57,9 +58,15 @@ type FastJetFinder struct {
 func (tsk *FastJetFinder) Configure(ctx fwk.Context) error {
 	var err error
 
-	err = tsk.DeclInPort(tsk.input, reflect.TypeOf([]Candidate{}))
-	if err != nil {
-		return err
+	if len(tsk.inputs) == 0 {
+		tsk.inputs = []string{tsk.input}
+	}
+
+	for _, input := range tsk.inputs {
+		err = tsk.DeclInPort(input, reflect.TypeOf([]Candidate{}))
+		if err != nil {
+			return err
+		}
 	}
 
 	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
@@ -102,11 +109,23 @@ func (tsk *FastJetFinder) Process(ctx fwk.Context) error {
 
 	store := ctx.Store()
 
-	v, err := store.Get(tsk.input)
-	if err != nil {
-		return err
+	var input []Candidate
+	switch len(tsk.inputs) {
+	case 1:
+		v, err := store.Get(tsk.inputs[0])
+		if err != nil {
+			return err
+		}
+		input = v.([]Candidate)
+	default:
+		for _, k := range tsk.inputs {
+			v, err := store.Get(k)
+			if err != nil {
+				return err
+			}
+			input = append(input, v.([]Candidate)...)
+		}
 	}
-	input := v.([]Candidate)
 
 	output := make([]Candidate, 0)
 	defer func() {
@@ -246,6 +265,11 @@ func newFastJetFinder(typ, name string, mgr fwk.App) (fwk.Component, error) {
 		return nil, err
 	}
 
+	err = tsk.DeclProp("Inputs", &tsk.inputs)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tsk.DeclProp("Rho", &tsk.rho)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,161 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// ImpactParameterSmearing smears the point of closest approach of tracks
+// to the beam axis and computes their (smeared) impact parameters.
+type ImpactParameterSmearing struct {
+	fwk.TaskBase
+
+	input  string
+	output string
+
+	smear func(pt, eta float64) float64
+	rnd   randsrc
+}
+
+func (tsk *ImpactParameterSmearing) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *ImpactParameterSmearing) StartTask(ctx fwk.Context) error {
+	var err error
+	err = tsk.rnd.start(ctx)
+	return err
+}
+
+func (tsk *ImpactParameterSmearing) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *ImpactParameterSmearing) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	input := v.([]Candidate)
+	msg.Debugf(">>> input: %v\n", len(input))
+
+	output := make([]Candidate, 0, len(input))
+	defer func() {
+		err = store.Put(tsk.output, output)
+	}()
+
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for i := range input {
+		cand := &input[i]
+
+		// take the momentum before any smearing was applied,
+		// otherwise the impact parameter would be smeared twice.
+		part := cand
+		if len(cand.Candidates) > 0 {
+			part = &cand.Candidates[0]
+		}
+		eta := part.Mom.Eta()
+		pt := part.Mom.Pt()
+		px := part.Mom.Px()
+		py := part.Mom.Py()
+
+		sigma := tsk.smear(pt, eta)
+
+		// apply smearing
+		gauss := distuv.Normal{Mu: 0, Sigma: sigma, Src: src}
+		xd := cand.Xd + gauss.Rand()
+		yd := cand.Yd + gauss.Rand()
+		zd := cand.Zd + gauss.Rand()
+		errd0 := gauss.Rand()
+
+		mother := cand
+		c := cand.Clone()
+		c.Xd = xd
+		c.Yd = yd
+		c.Zd = zd
+		c.D0 = (xd*py - yd*px) / pt
+		c.DZ = zd
+		c.ErrD0 = errd0
+		c.ErrDZ = sigma
+		c.Add(mother)
+
+		output = append(output, *c)
+	}
+
+	msg.Debugf(">>> smeared: %v\n", len(output))
+
+	return err
+}
+
+func newImpactParameterSmearing(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &ImpactParameterSmearing{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+		input:    "InputTracks",
+		output:   "OutputTracks",
+		smear:    func(pt, eta float64) float64 { return 0 },
+		rnd:      newRandSrc(1234),
+	}
+
+	err = tsk.DeclProp("Input", &tsk.input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Output", &tsk.output)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Resolution", &tsk.smear)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(ImpactParameterSmearing{}), newImpactParameterSmearing)
+}
@@ -0,0 +1,108 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestImpactParameterSmearing(t *testing.T) {
+	track := func(pt, phi, xd, yd, zd float64) Candidate {
+		c := newTrack(pt, 0.5, phi)
+		c.Xd = xd
+		c.Yd = yd
+		c.Zd = zd
+		return c
+	}
+
+	for _, tc := range []struct {
+		name  string
+		cand  Candidate
+		sigma float64
+		d0    float64 // expected D0 when sigma is zero
+	}{
+		{
+			name: "along-y",
+			cand: track(10, 0, 0, 0.5, 1),
+			d0:   -0.5,
+		},
+		{
+			name: "along-x",
+			cand: track(10, math.Pi/2, 0.5, 0, 1),
+			d0:   0.5,
+		},
+		{
+			name: "mother",
+			cand: func() Candidate {
+				c := track(10, math.Pi/2, 0.5, 0, 1)
+				m := track(10, 0, 0, 0, 0)
+				c.Add(&m)
+				return c
+			}(),
+			d0: 0,
+		},
+		{
+			name:  "smeared",
+			cand:  track(10, 0, 0, 0.5, 1),
+			sigma: 0.01,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			run := func() Candidate {
+				tsk := &ImpactParameterSmearing{
+					input:  "input",
+					output: "output",
+					smear:  func(pt, eta float64) float64 { return tc.sigma },
+					rnd:    newRandSrc(1234),
+				}
+
+				ctx := newTestContext()
+				err := tsk.StartTask(ctx)
+				if err != nil {
+					t.Fatalf("could not start task: %+v", err)
+				}
+
+				ctx.store["input"] = []Candidate{tc.cand}
+				err = tsk.Process(ctx)
+				if err != nil {
+					t.Fatalf("could not process event: %+v", err)
+				}
+
+				output := ctx.store["output"].([]Candidate)
+				if len(output) != 1 {
+					t.Fatalf("invalid number of tracks: got=%d, want=1", len(output))
+				}
+				return output[0]
+			}
+
+			c := run()
+			if got, want := c.ErrDZ, tc.sigma; got != want {
+				t.Fatalf("invalid ErrDZ: got=%v, want=%v", got, want)
+			}
+			if got, want := len(c.Candidates), len(tc.cand.Candidates)+1; got != want {
+				t.Fatalf("invalid number of mothers: got=%d, want=%d", got, want)
+			}
+
+			if tc.sigma == 0 {
+				if got, want := c.D0, tc.d0; math.Abs(got-want) > 1e-12 {
+					t.Fatalf("invalid D0: got=%v, want=%v", got, want)
+				}
+				if got, want := c.DZ, tc.cand.Zd; got != want {
+					t.Fatalf("invalid DZ: got=%v, want=%v", got, want)
+				}
+				return
+			}
+
+			if c.DZ == tc.cand.Zd {
+				t.Fatalf("DZ was not smeared")
+			}
+			if o := run(); !reflect.DeepEqual(c, o) {
+				t.Fatalf("smearing is not reproducible:\ngot= %+v\nwant=%+v", o, c)
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"go-hep.org/x/hep/fastjet"
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/fwk"
+)
+
+// JetFlavourAssociation assigns flavour labels to jets.
+//
+// Three flavour definitions are provided:
+//   - the algorithmic flavour (FlavourAlgo) is the flavour of the heaviest
+//     parton (b, then c) associated to the jet, or the flavour of the
+//     hardest associated parton when there is no heavy-flavour parton,
+//   - the physics flavour (FlavourPhys) is the flavour of the hard-process
+//     parton matched to the jet within DeltaR, when there is exactly one
+//     such parton,
+//   - the hadron flavour (HadronFlavour) is 5 (resp. 4) when a B (resp. C)
+//     hadron is associated to the jet, and 0 otherwise.
+//
+// Gluons are labelled 21 and jets without any associated parton are
+// labelled 0.
+// The Flavour of a jet is its algorithmic flavour.
+//
+// Partons and hadrons are associated to jets either via ghost-association,
+// where they are added with an infinitesimal momentum to the jets'
+// constituents before reclustering them, or by requiring them to lie within
+// DeltaR of the jet axis.
+// Hard-process partons are partons with a HepMC status of 3 (Pythia-6)
+// or 23 (Pythia-8).
+type JetFlavourAssociation struct {
+	fwk.TaskBase
+
+	partons   string
+	particles string
+	jets      string
+	output    string
+
+	dR     float64
+	ghosts bool
+
+	jetAlg fastjet.JetAlgorithm
+	paramR float64
+	jetDef fastjet.JetDefinition
+
+	classifier btagclassifier
+}
+
+func (tsk *JetFlavourAssociation) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.partons, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclInPort(tsk.particles, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclInPort(tsk.jets, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	if tsk.ghosts {
+		switch tsk.jetAlg {
+		case fastjet.KtAlgorithm, fastjet.CambridgeAlgorithm, fastjet.AntiKtAlgorithm:
+		default:
+			return fmt.Errorf("fads: jet-flavour-association: invalid jet algorithm %v", tsk.jetAlg)
+		}
+		tsk.jetDef = fastjet.NewJetDefinition(tsk.jetAlg, tsk.paramR, fastjet.EScheme, fastjet.BestStrategy)
+	}
+
+	return err
+}
+
+func (tsk *JetFlavourAssociation) StartTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *JetFlavourAssociation) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+// jetFlavourInfo holds the partons and hadrons associated to a jet.
+type jetFlavourInfo struct {
+	partons []*Candidate
+	hadrons []*Candidate
+}
+
+func (tsk *JetFlavourAssociation) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.partons)
+	if err != nil {
+		return err
+	}
+	allpartons := v.([]Candidate)
+
+	v, err = store.Get(tsk.particles)
+	if err != nil {
+		return err
+	}
+	particles := v.([]Candidate)
+
+	v, err = store.Get(tsk.jets)
+	if err != nil {
+		return err
+	}
+	jets := v.([]Candidate)
+
+	msg.Debugf(">>> partons: %d, particles: %d, jets: %d\n", len(allpartons), len(particles), len(jets))
+
+	output := make([]Candidate, 0, len(jets))
+	defer func() {
+		err = store.Put(tsk.output, output)
+	}()
+
+	partons := make([]*Candidate, 0, len(allpartons))
+	hard := make([]*Candidate, 0, 4)
+	for i := range allpartons {
+		p := &allpartons[i]
+		if tsk.classifier.Category(p) < 0 {
+			continue
+		}
+		partons = append(partons, p)
+		if p.Status == 3 || p.Status == 23 {
+			hard = append(hard, p)
+		}
+	}
+
+	hadrons := make([]*Candidate, 0)
+	for i := range particles {
+		p := &particles[i]
+		if hadronFlavour(p.Pid) == 0 || p.Mom.Pt() <= 0 {
+			continue
+		}
+		hadrons = append(hadrons, p)
+	}
+
+	var infos []jetFlavourInfo
+	switch {
+	case tsk.ghosts:
+		infos, err = tsk.ghostAssociation(jets, partons, hadrons)
+		if err != nil {
+			return err
+		}
+	default:
+		infos = make([]jetFlavourInfo, len(jets))
+		for i := range jets {
+			jet := &jets[i]
+			for _, p := range partons {
+				if fmom.DeltaR(&jet.Mom, &p.Mom) < tsk.dR {
+					infos[i].partons = append(infos[i].partons, p)
+				}
+			}
+			for _, h := range hadrons {
+				if fmom.DeltaR(&jet.Mom, &h.Mom) < tsk.dR {
+					infos[i].hadrons = append(infos[i].hadrons, h)
+				}
+			}
+		}
+	}
+
+	for i := range jets {
+		jet := jets[i]
+		info := &infos[i]
+
+		jet.FlavourAlgo = algoFlavour(info.partons)
+		jet.FlavourPhys = tsk.physFlavour(&jet, hard)
+		jet.HadronFlavour = 0
+		for _, h := range info.hadrons {
+			if flav := hadronFlavour(h.Pid); flav > jet.HadronFlavour {
+				jet.HadronFlavour = flav
+			}
+		}
+		jet.Flavour = jet.FlavourAlgo
+
+		output = append(output, jet)
+	}
+
+	return err
+}
+
+// ghostAssociation associates partons and hadrons to jets by reclustering
+// the jets' constituents together with ghost partons and hadrons.
+func (tsk *JetFlavourAssociation) ghostAssociation(jets []Candidate, partons, hadrons []*Candidate) ([]jetFlavourInfo, error) {
+	const scale = 1e-18
+
+	infos := make([]jetFlavourInfo, len(jets))
+	ghosts := make([]*Candidate, 0, len(partons)+len(hadrons))
+	ghosts = append(ghosts, partons...)
+	ghosts = append(ghosts, hadrons...)
+
+	n := len(ghosts)
+	for i := range jets {
+		n += len(jets[i].Candidates)
+	}
+
+	// real constituents are labelled with the index of their jet,
+	// ghosts with -(1+index of the ghost).
+	injets := make([]fastjet.Jet, 0, n)
+	for i := range jets {
+		jet := &jets[i]
+		for j := range jet.Candidates {
+			mom := &jet.Candidates[j].Mom
+			cst := fastjet.NewJet(mom.Px(), mom.Py(), mom.Pz(), mom.E())
+			cst.UserInfo = i
+			injets = append(injets, cst)
+		}
+	}
+	for i, g := range ghosts {
+		mom := &g.Mom
+		ghost := fastjet.NewJet(scale*mom.Px(), scale*mom.Py(), scale*mom.Pz(), scale*mom.E())
+		ghost.UserInfo = -(1 + i)
+		injets = append(injets, ghost)
+	}
+
+	bldr, err := fastjet.NewClusterSequence(injets, tsk.jetDef)
+	if err != nil {
+		return nil, err
+	}
+
+	outjets, err := bldr.InclusiveJets(0)
+	if err != nil {
+		return nil, err
+	}
+
+	pts := make(map[int]float64)
+	for i := range outjets {
+		csts, err := bldr.Constituents(&outjets[i])
+		if err != nil {
+			return nil, err
+		}
+
+		// label the reclustered jet with the input jet contributing
+		// the largest share of its transverse momentum.
+		clear(pts)
+		for j := range csts {
+			if idx := csts[j].UserInfo.(int); idx >= 0 {
+				pts[idx] += csts[j].Pt()
+			}
+		}
+		ijet := -1
+		ptmax := 0.0
+		for idx, pt := range pts {
+			if ijet < 0 || pt > ptmax || (pt == ptmax && idx < ijet) {
+				ijet = idx
+				ptmax = pt
+			}
+		}
+		if ijet < 0 {
+			// jet made only of ghosts.
+			continue
+		}
+
+		info := &infos[ijet]
+		for j := range csts {
+			idx := csts[j].UserInfo.(int)
+			if idx >= 0 {
+				continue
+			}
+			idx = -idx - 1
+			switch {
+			case idx < len(partons):
+				info.partons = append(info.partons, ghosts[idx])
+			default:
+				info.hadrons = append(info.hadrons, ghosts[idx])
+			}
+		}
+	}
+
+	return infos, nil
+}
+
+// algoFlavour returns the algorithmic flavour of a jet, given its
+// associated partons.
+func algoFlavour(partons []*Candidate) int32 {
+	var (
+		flav  int32
+		ptmax = -1.0
+	)
+	for _, p := range partons {
+		pdg := absPid(p.Pid)
+		if pdg == 5 || pdg == 4 {
+			if flav != 5 && (flav != 4 || pdg == 5) {
+				flav = pdg
+				ptmax = math.Inf(+1)
+			}
+			continue
+		}
+		if pt := p.Mom.Pt(); pt > ptmax {
+			flav = pdg
+			ptmax = pt
+		}
+	}
+	return flav
+}
+
+// physFlavour returns the physics flavour of a jet, given the
+// hard-process partons of the event.
+func (tsk *JetFlavourAssociation) physFlavour(jet *Candidate, hard []*Candidate) int32 {
+	var (
+		flav int32
+		n    int
+	)
+	for _, p := range hard {
+		if fmom.DeltaR(&jet.Mom, &p.Mom) >= tsk.dR {
+			continue
+		}
+		n++
+		flav = absPid(p.Pid)
+	}
+	if n != 1 {
+		return 0
+	}
+	return flav
+}
+
+// hadronFlavour returns 5 for B hadrons, 4 for C hadrons and 0 otherwise.
+func hadronFlavour(pid int32) int32 {
+	pdg := absPid(pid)
+	if pdg < 100 {
+		return 0
+	}
+	q1 := (pdg / 1000) % 10 // heaviest quark of baryons
+	q2 := (pdg / 100) % 10  // heaviest quark of mesons
+	switch {
+	case q1 == 5 || q2 == 5:
+		return 5
+	case q1 == 4 || q2 == 4:
+		return 4
+	}
+	return 0
+}
+
+func absPid(pid int32) int32 {
+	if pid < 0 {
+		return -pid
+	}
+	return pid
+}
+
+func newJetFlavourAssociation(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &JetFlavourAssociation{
+		TaskBase:  fwk.NewTask(typ, name, mgr),
+		partons:   "InputPartons",
+		particles: "InputParticles",
+		jets:      "InputJets",
+		output:    "OutputJets",
+
+		dR:     0.5,
+		ghosts: true,
+		jetAlg: fastjet.AntiKtAlgorithm,
+		paramR: 0.5,
+
+		classifier: btagclassifier{
+			PtMin:  0.0,
+			EtaMax: 2.5,
+		},
+	}
+
+	err = tsk.DeclProp("Partons", &tsk.partons)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Particles", &tsk.particles)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Jets", &tsk.jets)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Output", &tsk.output)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("DeltaR", &tsk.dR)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("GhostAssociation", &tsk.ghosts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("JetAlgorithm", &tsk.jetAlg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("ParameterR", &tsk.paramR)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PartonPtMin", &tsk.classifier.PtMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PartonEtaMax", &tsk.classifier.EtaMax)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(JetFlavourAssociation{}), newJetFlavourAssociation)
+}
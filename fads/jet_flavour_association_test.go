@@ -0,0 +1,105 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"reflect"
+	"testing"
+
+	"go-hep.org/x/hep/fastjet"
+)
+
+func TestGhostAssociation(t *testing.T) {
+	particle := func(pid int32, pt, eta, phi float64) Candidate {
+		c := newTrack(pt, eta, phi)
+		c.Pid = pid
+		return c
+	}
+	jet := func(csts ...Candidate) Candidate {
+		var jet Candidate
+		for i := range csts {
+			jet.Add(&csts[i])
+		}
+		return jet
+	}
+
+	jets := []Candidate{
+		jet(
+			// a soft constituent, lying within the second jet, comes
+			// first in the reclustering.
+			particle(211, 1, 0.05, 2.05),
+			particle(211, 40, 0, 0),
+			particle(211, 10, 0.1, 0.1),
+		),
+		jet(
+			particle(211, 30, 0, 2),
+		),
+	}
+
+	for _, tc := range []struct {
+		name   string
+		ghost  Candidate
+		hadron bool
+		jet    int // index of the expected jet, or -1
+	}{
+		{
+			name:  "b-quark",
+			ghost: particle(5, 50, 0.05, 0.05),
+			jet:   0,
+		},
+		{
+			name:  "c-quark",
+			ghost: particle(4, 20, 0, 2.02),
+			jet:   1,
+		},
+		{
+			name:   "b-hadron",
+			ghost:  particle(511, 20, -0.05, 1.98),
+			hadron: true,
+			jet:    1,
+		},
+		{
+			name:  "gluon-outside",
+			ghost: particle(21, 20, 0, -2),
+			jet:   -1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := &JetFlavourAssociation{
+				jetDef: fastjet.NewJetDefinition(fastjet.AntiKtAlgorithm, 0.5, fastjet.EScheme, fastjet.BestStrategy),
+			}
+
+			var partons, hadrons []*Candidate
+			switch {
+			case tc.hadron:
+				hadrons = append(hadrons, &tc.ghost)
+			default:
+				partons = append(partons, &tc.ghost)
+			}
+
+			infos, err := tsk.ghostAssociation(jets, partons, hadrons)
+			if err != nil {
+				t.Fatalf("could not associate ghosts: %+v", err)
+			}
+			if got, want := len(infos), len(jets); got != want {
+				t.Fatalf("invalid number of jets: got=%d, want=%d", got, want)
+			}
+
+			for i, info := range infos {
+				var want []*Candidate
+				if i == tc.jet {
+					want = []*Candidate{&tc.ghost}
+				}
+				got := info.partons
+				if tc.hadron {
+					got = info.hadrons
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("jet %d: invalid association: got=%v, want=%v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -6,9 +6,7 @@ package fads
 
 import (
 	"math"
-	"math/rand/v2"
 	"reflect"
-	"sync"
 
 	"go-hep.org/x/hep/fmom"
 	"go-hep.org/x/hep/fwk"
@@ -22,9 +20,7 @@ type MomentumSmearing struct {
 	output string
 
 	smear func(x, y float64) float64
-	seed  uint64
-	src   *rand.Rand
-	srcmu sync.Mutex
+	rnd   randsrc
 }
 
 func (tsk *MomentumSmearing) Configure(ctx fwk.Context) error {
@@ -45,7 +41,7 @@ func (tsk *MomentumSmearing) Configure(ctx fwk.Context) error {
 
 func (tsk *MomentumSmearing) StartTask(ctx fwk.Context) error {
 	var err error
-	tsk.src = rand.New(rand.NewPCG(tsk.seed, tsk.seed))
+	err = tsk.rnd.start(ctx)
 	return err
 }
 
@@ -73,16 +69,21 @@ func (tsk *MomentumSmearing) Process(ctx fwk.Context) error {
 		err = store.Put(tsk.output, output)
 	}()
 
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	for i := range input {
 		cand := &input[i]
 		eta := cand.Pos.Eta()
 		pt := cand.Mom.Pt()
 
 		// apply smearing
-		tsk.srcmu.Lock()
-		smearPt := distuv.Normal{Mu: pt, Sigma: tsk.smear(pt, eta) * pt, Src: tsk.src}
+		res := tsk.smear(pt, eta)
+		smearPt := distuv.Normal{Mu: pt, Sigma: res * pt, Src: src}
 		pt = smearPt.Rand()
-		tsk.srcmu.Unlock()
 
 		if pt <= 0 {
 			continue
@@ -98,6 +99,7 @@ func (tsk *MomentumSmearing) Process(ctx fwk.Context) error {
 		pzs := pt * math.Sinh(eta)
 		es := pt * math.Cosh(eta)
 		c.Mom = fmom.NewPxPyPzE(pxs, pys, pzs, es)
+		c.TrackResolution = res
 		c.Add(mother)
 
 		output = append(output, *c)
@@ -117,7 +119,7 @@ func init() {
 				input:    "InputParticles",
 				output:   "OutputParticles",
 				smear:    func(x, y float64) float64 { return 0 },
-				seed:     1234,
+				rnd:      newRandSrc(1234),
 			}
 
 			err = tsk.DeclProp("Input", &tsk.input)
@@ -135,7 +137,12 @@ func init() {
 				return nil, err
 			}
 
-			err = tsk.DeclProp("Seed", &tsk.seed)
+			err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+			if err != nil {
+				return nil, err
+			}
+
+			err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
 			if err != nil {
 				return nil, err
 			}
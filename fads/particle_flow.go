@@ -0,0 +1,382 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"reflect"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/fwk"
+)
+
+// ParticleFlow combines tracks and calorimeter towers into energy-flow
+// tracks, photons and neutral hadrons.
+//
+// Tracks are associated to the tower they hit and their energy is split
+// between the ECal and the HCal according to EnergyFraction.
+// The resolution on the energy of a track is given by its TrackResolution,
+// as set by MomentumSmearing, or by the TrackResolution property when the
+// track has none.
+// The ECal (resp. HCal) energy of a tower exceeding the energy of its
+// associated tracks is turned into an energy-flow photon (resp. neutral
+// hadron) when it is above ECalEnergyMin (resp. HCalEnergyMin) and
+// significant with regard to the tracks and calorimeter resolutions.
+// Otherwise, the associated tracks are rescaled so that their energy
+// matches the best combination of the tracking and calorimeter measurements.
+// A track depositing energy in both calorimeters is rescaled by the
+// average of the ECal and HCal factors, weighted by its energy fractions.
+//
+// Tracks not depositing energy in the calorimeters or not associated to
+// any tower are passed through as energy-flow tracks.
+type ParticleFlow struct {
+	fwk.TaskBase
+
+	tracks   string
+	towers   string
+	eftracks string
+	photons  string
+	neutrals string
+
+	efrac   map[int]EneFrac
+	ecalres func(eta, ene float64) float64
+	hcalres func(eta, ene float64) float64
+	trkres  func(pt, eta float64) float64
+
+	ecalMin    float64
+	hcalMin    float64
+	ecalSigMin float64
+	hcalSigMin float64
+}
+
+func (tsk *ParticleFlow) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.tracks, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclInPort(tsk.towers, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.eftracks, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.photons, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.neutrals, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *ParticleFlow) StartTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *ParticleFlow) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+// eflowCalo holds the tracks associated to a calorimeter of a tower.
+type eflowCalo struct {
+	trks   []int   // indices of the associated tracks
+	ene    float64 // energy of the associated tracks
+	sigma2 float64 // squared resolution on the energy of the associated tracks
+}
+
+// add associates the track i, depositing the energy ene with
+// the resolution sigma, to the calorimeter.
+func (calo *eflowCalo) add(i int, ene, sigma float64) {
+	if ene <= 0 {
+		return
+	}
+	calo.trks = append(calo.trks, i)
+	calo.ene += ene
+	calo.sigma2 += sigma * sigma
+}
+
+func (tsk *ParticleFlow) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.tracks)
+	if err != nil {
+		return err
+	}
+	tracks := v.([]Candidate)
+	msg.Debugf(">>> tracks: %v\n", len(tracks))
+
+	v, err = store.Get(tsk.towers)
+	if err != nil {
+		return err
+	}
+	towers := v.([]Candidate)
+	msg.Debugf(">>> towers: %v\n", len(towers))
+
+	eftracks := make([]Candidate, 0, len(tracks))
+	photons := make([]Candidate, 0, len(towers))
+	neutrals := make([]Candidate, 0, len(towers))
+	defer func() {
+		err = store.Put(tsk.eftracks, eftracks)
+		if err != nil {
+			return
+		}
+		err = store.Put(tsk.photons, photons)
+		if err != nil {
+			return
+		}
+		err = store.Put(tsk.neutrals, neutrals)
+	}()
+
+	ecals := make([]eflowCalo, len(towers))
+	hcals := make([]eflowCalo, len(towers))
+
+	// associate tracks to towers.
+	// as in Delphes, the energy of a track is split between the ECal and
+	// the HCal of its tower according to its energy fractions.
+	fracs := make([]EneFrac, len(tracks))
+	escales := make([]float64, len(tracks))
+	hscales := make([]float64, len(tracks))
+	for i := range tracks {
+		trk := &tracks[i]
+		escales[i] = 1
+		hscales[i] = 1
+
+		abspid := trk.Pid
+		if abspid < 0 {
+			abspid = -abspid
+		}
+
+		frac, ok := tsk.efrac[int(abspid)]
+		if !ok {
+			frac = tsk.efrac[0]
+		}
+
+		itwr := -1
+		if frac.ECal >= 1e-9 || frac.HCal >= 1e-9 {
+			itwr = findTower(towers, trk.Pos.Eta(), trk.Pos.Phi())
+		}
+		if itwr < 0 {
+			continue
+		}
+		fracs[i] = frac
+
+		res := trk.TrackResolution
+		if res <= 0 {
+			res = tsk.trkres(trk.Mom.Pt(), trk.Mom.Eta())
+		}
+		ene := trk.Mom.E()
+		ecals[itwr].add(i, ene*frac.ECal, res*ene*frac.ECal)
+		hcals[itwr].add(i, ene*frac.HCal, res*ene*frac.HCal)
+	}
+
+	// create neutral candidates or rescale tracks
+	for i := range towers {
+		twr := &towers[i]
+		eta := twr.Mom.Eta()
+		phi := twr.Mom.Phi()
+
+		ecal := &ecals[i]
+		ene, scale, ok := tsk.eflow(ecal, twr.Eem, tsk.ecalres(eta, twr.Eem), tsk.ecalMin, tsk.ecalSigMin)
+		if ok {
+			photon := *twr
+			photon.Mom = newPtEtaPhiE(ene/math.Cosh(eta), eta, phi, ene)
+			photon.Eem = ene
+			photon.Ehad = 0
+			photon.Pid = 22
+			photons = append(photons, photon)
+		}
+		for _, j := range ecal.trks {
+			escales[j] = scale
+		}
+
+		hcal := &hcals[i]
+		ene, scale, ok = tsk.eflow(hcal, twr.Ehad, tsk.hcalres(eta, twr.Ehad), tsk.hcalMin, tsk.hcalSigMin)
+		if ok {
+			neutral := *twr
+			neutral.Mom = newPtEtaPhiE(ene/math.Cosh(eta), eta, phi, ene)
+			neutral.Eem = 0
+			neutral.Ehad = ene
+			neutral.Pid = 0
+			neutrals = append(neutrals, neutral)
+		}
+		for _, j := range hcal.trks {
+			hscales[j] = scale
+		}
+	}
+
+	for i := range tracks {
+		trk := tracks[i]
+		frac := fracs[i]
+		if sum := frac.ECal + frac.HCal; sum > 0 {
+			scale := (frac.ECal*escales[i] + frac.HCal*hscales[i]) / sum
+			if scale != 1 {
+				mom := &trk.Mom
+				trk.Mom = fmom.NewPxPyPzE(
+					scale*mom.Px(), scale*mom.Py(), scale*mom.Pz(), scale*mom.E(),
+				)
+			}
+		}
+		eftracks = append(eftracks, trk)
+	}
+
+	msg.Debugf(">>> eflow: tracks=%d photons=%d neutral-hadrons=%d\n",
+		len(eftracks), len(photons), len(neutrals),
+	)
+
+	return err
+}
+
+// eflow computes the neutral energy excess of a calorimeter with the
+// measured energy ene and resolution sigma.
+// eflow returns the excess and true when it is significant.
+// Otherwise, eflow returns the factor by which the associated tracks
+// should be rescaled to match the best estimate of the energy, combining
+// the tracking and calorimeter measurements.
+func (tsk *ParticleFlow) eflow(calo *eflowCalo, ene, sigma, emin, sigmin float64) (float64, float64, bool) {
+	excess := math.Max(ene-calo.ene, 0)
+	significance := 0.0
+	if excess > 0 {
+		// with perfect resolutions, any excess is significant.
+		significance = math.Inf(+1)
+		if sigma2 := calo.sigma2 + sigma*sigma; sigma2 > 0 {
+			significance = excess / math.Sqrt(sigma2)
+		}
+	}
+	if excess > emin && significance > sigmin {
+		return excess, 1, true
+	}
+
+	// tracks with a perfect resolution are not rescaled.
+	if calo.ene <= 0 || calo.sigma2 <= 0 {
+		return 0, 1, false
+	}
+
+	wtrk := 1 / calo.sigma2
+	wcalo := 0.0
+	if sigma > 0 {
+		wcalo = 1 / (sigma * sigma)
+	}
+	best := (wtrk*calo.ene + wcalo*ene) / (wtrk + wcalo)
+	return 0, best / calo.ene, false
+}
+
+// findTower returns the index of the tower whose edges contain
+// the provided eta/phi pair, or -1.
+func findTower(towers []Candidate, eta, phi float64) int {
+	for i := range towers {
+		edges := &towers[i].Edges
+		if edges[0] < eta && eta <= edges[1] && edges[2] < phi && phi <= edges[3] {
+			return i
+		}
+	}
+	return -1
+}
+
+func newParticleFlow(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &ParticleFlow{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+
+		tracks:   "/fads/tracks",
+		towers:   "/fads/towers",
+		eftracks: "/fads/eflowtracks",
+		photons:  "/fads/eflowphotons",
+		neutrals: "/fads/eflowneutralhadrons",
+
+		efrac:   make(map[int]EneFrac),
+		ecalres: func(eta, ene float64) float64 { return 0 },
+		hcalres: func(eta, ene float64) float64 { return 0 },
+		trkres:  func(pt, eta float64) float64 { return 0 },
+	}
+
+	err = tsk.DeclProp("Tracks", &tsk.tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Towers", &tsk.towers)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EFlowTracks", &tsk.eftracks)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EFlowPhotons", &tsk.photons)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EFlowNeutralHadrons", &tsk.neutrals)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EnergyFraction", &tsk.efrac)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("ECalResolution", &tsk.ecalres)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("HCalResolution", &tsk.hcalres)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("TrackResolution", &tsk.trkres)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("ECalEnergyMin", &tsk.ecalMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("HCalEnergyMin", &tsk.hcalMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("ECalEnergySignificanceMin", &tsk.ecalSigMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("HCalEnergySignificanceMin", &tsk.hcalSigMin)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(ParticleFlow{}), newParticleFlow)
+}
@@ -0,0 +1,153 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParticleFlow(t *testing.T) {
+	track := func(pid int32, ene, eta, res float64) Candidate {
+		c := newTrack(ene/math.Cosh(eta), eta, 0)
+		c.Pid = pid
+		c.Pos = newPtEtaPhiE(1, eta, 0, 0)
+		c.TrackResolution = res
+		return c
+	}
+	tower := func(eem, ehad float64) Candidate {
+		return Candidate{
+			Mom:   newPtEtaPhiE(eem+ehad, 0, 0, eem+ehad),
+			Eem:   eem,
+			Ehad:  ehad,
+			Edges: [4]float64{-0.5, 0.5, -0.5, 0.5},
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		track    Candidate
+		tower    Candidate
+		calores  float64 // absolute calorimeter resolution
+		trkE     float64 // expected energy of the energy-flow track
+		photons  []float64
+		neutrals []float64
+	}{
+		{
+			name:     "neutral-excess",
+			track:    track(211, 10, 0, 0.01),
+			tower:    tower(0, 30),
+			trkE:     10,
+			neutrals: []float64{20},
+		},
+		{
+			name:  "perfect-resolution",
+			track: track(211, 10, 0, 0),
+			tower: tower(0, 10),
+			trkE:  10,
+		},
+		{
+			name:  "perfect-resolution-below-min",
+			track: track(211, 10, 0, 0),
+			tower: tower(0, 10.5),
+			trkE:  10,
+		},
+		{
+			name:    "rescale",
+			track:   track(211, 10, 0, 0.1),
+			tower:   tower(0, 11),
+			calores: 1,
+			trkE:    10.5,
+		},
+		{
+			name:     "split",
+			track:    track(321, 10, 0, 0),
+			tower:    tower(3, 12),
+			trkE:     10,
+			neutrals: []float64{5},
+		},
+		{
+			name:    "split-rescale",
+			track:   track(321, 10, 0, 0.1),
+			tower:   tower(2.4, 7),
+			calores: 0.3,
+			// ECal: the average of 3 and 2.4, i.e. a 0.9 factor.
+			// HCal: the track and tower energies agree.
+			trkE: 10 * (0.3*0.9 + 0.7*1),
+		},
+		{
+			name:    "muon",
+			track:   track(13, 10, 0, 0.01),
+			tower:   tower(2, 0),
+			trkE:    10,
+			photons: []float64{2},
+		},
+		{
+			name:     "outside",
+			track:    track(211, 10, 2, 0.01),
+			tower:    tower(0, 10),
+			trkE:     10,
+			neutrals: []float64{10},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := &ParticleFlow{
+				tracks:   "tracks",
+				towers:   "towers",
+				eftracks: "eftracks",
+				photons:  "photons",
+				neutrals: "neutrals",
+				efrac: map[int]EneFrac{
+					0:   {ECal: 0, HCal: 1},
+					11:  {ECal: 1, HCal: 0},
+					13:  {ECal: 0, HCal: 0},
+					321: {ECal: 0.3, HCal: 0.7},
+				},
+				ecalres:    func(eta, ene float64) float64 { return tc.calores },
+				hcalres:    func(eta, ene float64) float64 { return tc.calores },
+				trkres:     func(pt, eta float64) float64 { return 0 },
+				ecalMin:    0.5,
+				hcalMin:    0.5,
+				ecalSigMin: 2,
+				hcalSigMin: 2,
+			}
+
+			ctx := newTestContext()
+			ctx.store["tracks"] = []Candidate{tc.track}
+			ctx.store["towers"] = []Candidate{tc.tower}
+
+			err := tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			eftracks := ctx.store["eftracks"].([]Candidate)
+			if len(eftracks) != 1 {
+				t.Fatalf("invalid number of tracks: got=%d, want=1", len(eftracks))
+			}
+			if got, want := eftracks[0].Mom.E(), tc.trkE; math.Abs(got-want) > 1e-9 {
+				t.Fatalf("invalid track energy: got=%v, want=%v", got, want)
+			}
+
+			for _, v := range []struct {
+				name string
+				want []float64
+			}{
+				{"photons", tc.photons},
+				{"neutrals", tc.neutrals},
+			} {
+				cands := ctx.store[v.name].([]Candidate)
+				if got, want := len(cands), len(v.want); got != want {
+					t.Fatalf("invalid number of %s: got=%d, want=%d", v.name, got, want)
+				}
+				for i, c := range cands {
+					if got, want := c.Mom.E(), v.want[i]; math.Abs(got-want) > 1e-9 {
+						t.Fatalf("invalid %s energy: got=%v, want=%v", v.name, got, want)
+					}
+				}
+			}
+		})
+	}
+}
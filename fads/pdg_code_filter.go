@@ -0,0 +1,184 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// PdgCodeFilter removes the candidates whose PDG code is listed in PdgCodes.
+// When Invert is true, only the candidates whose PDG code is listed are kept.
+//
+// Candidates with a transverse momentum below PtMin are always removed,
+// as well as, when requested, the candidates with another status or charge
+// than the required ones and the candidates from pile-up interactions.
+type PdgCodeFilter struct {
+	fwk.TaskBase
+
+	input  string
+	output string
+
+	ptMin  float64
+	pdgs   []int
+	invert bool
+
+	requireStatus bool
+	status        int
+	requireCharge bool
+	charge        int
+	requireNotPU  bool
+}
+
+func (tsk *PdgCodeFilter) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *PdgCodeFilter) StartTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *PdgCodeFilter) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *PdgCodeFilter) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	input := v.([]Candidate)
+	msg.Debugf(">>> input: %v\n", len(input))
+
+	output := make([]Candidate, 0, len(input))
+	for i := range input {
+		cand := &input[i]
+		if cand.Mom.Pt() < tsk.ptMin {
+			continue
+		}
+		if tsk.requireStatus && int(cand.Status) != tsk.status {
+			continue
+		}
+		if tsk.requireCharge && int(cand.CandCharge) != tsk.charge {
+			continue
+		}
+		if tsk.requireNotPU && cand.IsPU != 0 {
+			continue
+		}
+
+		pass := true
+		for _, pdg := range tsk.pdgs {
+			if int(cand.Pid) == pdg {
+				pass = false
+				break
+			}
+		}
+		if tsk.invert {
+			pass = !pass
+		}
+		if !pass {
+			continue
+		}
+
+		output = append(output, *cand)
+	}
+
+	msg.Debugf(">>> output: %v\n", len(output))
+
+	err = store.Put(tsk.output, output)
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func newPdgCodeFilter(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &PdgCodeFilter{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+		input:    "InputParticles",
+		output:   "OutputParticles",
+	}
+
+	err = tsk.DeclProp("Input", &tsk.input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Output", &tsk.output)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PtMin", &tsk.ptMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PdgCodes", &tsk.pdgs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Invert", &tsk.invert)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RequireStatus", &tsk.requireStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Status", &tsk.status)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RequireCharge", &tsk.requireCharge)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Charge", &tsk.charge)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RequireNotPileUp", &tsk.requireNotPU)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(PdgCodeFilter{}), newPdgCodeFilter)
+}
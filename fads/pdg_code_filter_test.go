@@ -0,0 +1,95 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"testing"
+)
+
+func TestPdgCodeFilter(t *testing.T) {
+	cand := func(pid, status, charge int32, pu byte, pt float64) Candidate {
+		c := newTrack(pt, 0, 0)
+		c.Pid = pid
+		c.Status = status
+		c.CandCharge = charge
+		c.IsPU = pu
+		return c
+	}
+
+	for _, tc := range []struct {
+		name string
+		tsk  PdgCodeFilter
+		cand Candidate
+		kept bool
+	}{
+		{
+			name: "listed",
+			tsk:  PdgCodeFilter{pdgs: []int{12, -12}},
+			cand: cand(12, 1, 0, 0, 10),
+		},
+		{
+			name: "signed",
+			tsk:  PdgCodeFilter{pdgs: []int{11}},
+			cand: cand(-11, 1, -1, 0, 10),
+			kept: true,
+		},
+		{
+			name: "not-listed",
+			tsk:  PdgCodeFilter{pdgs: []int{12, -12}},
+			cand: cand(211, 1, 1, 0, 10),
+			kept: true,
+		},
+		{
+			name: "invert-listed",
+			tsk:  PdgCodeFilter{pdgs: []int{11, -11}, invert: true},
+			cand: cand(11, 1, -1, 0, 10),
+			kept: true,
+		},
+		{
+			name: "invert-not-listed",
+			tsk:  PdgCodeFilter{pdgs: []int{11, -11}, invert: true},
+			cand: cand(13, 1, -1, 0, 10),
+		},
+		{
+			name: "low-pt",
+			tsk:  PdgCodeFilter{ptMin: 1},
+			cand: cand(211, 1, 1, 0, 0.5),
+		},
+		{
+			name: "status",
+			tsk:  PdgCodeFilter{requireStatus: true, status: 1},
+			cand: cand(211, 2, 1, 0, 10),
+		},
+		{
+			name: "charge",
+			tsk:  PdgCodeFilter{requireCharge: true, charge: 1},
+			cand: cand(-211, 1, -1, 0, 10),
+		},
+		{
+			name: "pile-up",
+			tsk:  PdgCodeFilter{requireNotPU: true},
+			cand: cand(211, 1, 1, 1, 10),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := tc.tsk
+			tsk.input = "input"
+			tsk.output = "output"
+
+			ctx := newTestContext()
+			ctx.store["input"] = []Candidate{tc.cand}
+
+			err := tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			output := ctx.store["output"].([]Candidate)
+			if got, want := len(output) == 1, tc.kept; got != want {
+				t.Fatalf("invalid selection: got=%v, want=%v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/hepmc"
+	"go-hep.org/x/hep/heppdt"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// PileUpDistribution describes how the number of pile-up interactions
+// overlaid on each event is drawn.
+type PileUpDistribution int
+
+const (
+	PileUpPoisson PileUpDistribution = iota // Poisson distribution of mean MeanPileUp
+	PileUpUniform                           // uniform distribution in [0, 2*MeanPileUp]
+	PileUpFixed                             // exactly MeanPileUp interactions
+)
+
+// PileUpMerger overlays minimum-bias events, read from a HepMC file,
+// on top of the hard-scattering event.
+// Each pile-up interaction is shifted along the beam axis and in time
+// according to the vertex spread and randomly rotated in phi.
+type PileUpMerger struct {
+	fwk.TaskBase
+
+	input   string
+	output  string
+	vtxs    string
+	fname   string
+	dist    PileUpDistribution
+	mean    float64
+	zspread float64 // vertex spread along the beam axis, in meters
+	tspread float64 // vertex spread in time, in seconds
+
+	evts [][]Candidate // minimum-bias events
+
+	rnd randsrc
+}
+
+func (tsk *PileUpMerger) Configure(ctx fwk.Context) error {
+	var err error
+
+	if tsk.mean < 0 {
+		return fmt.Errorf("%s: invalid mean pile-up value (%v)", tsk.Name(), tsk.mean)
+	}
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.vtxs, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *PileUpMerger) StartTask(ctx fwk.Context) error {
+	var err error
+	err = tsk.rnd.start(ctx)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(tsk.fname)
+	if err != nil {
+		return fmt.Errorf("%s: could not open pile-up file: %w", tsk.Name(), err)
+	}
+	defer f.Close()
+
+	dec := hepmc.NewDecoder(bufio.NewReader(f))
+	for {
+		var evt hepmc.Event
+		err = dec.Decode(&evt)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%s: could not decode pile-up event: %w", tsk.Name(), err)
+		}
+
+		parts := make(hepmc.Particles, 0, len(evt.Particles))
+		for _, p := range evt.Particles {
+			if p.Status != 1 {
+				continue
+			}
+			parts = append(parts, p)
+		}
+		sort.Sort(parts)
+
+		cands := make([]Candidate, 0, len(parts))
+		for _, p := range parts {
+			c := Candidate{
+				Pid:        int32(p.PdgID),
+				Status:     int32(p.Status),
+				CandCharge: -999,
+				CandMass:   -999.9,
+				IsPU:       1,
+				Mom:        fmom.PxPyPzE(p.Momentum),
+			}
+			if pdg := heppdt.ParticleByID(heppdt.PID(p.PdgID)); pdg != nil {
+				c.CandCharge = int32(pdg.Charge)
+				c.CandMass = pdg.Mass
+			}
+			if vtx := p.ProdVertex; vtx != nil {
+				c.Pos = fmom.PxPyPzE(vtx.Position)
+			}
+			cands = append(cands, c)
+		}
+		tsk.evts = append(tsk.evts, cands)
+	}
+	err = nil
+
+	if len(tsk.evts) == 0 && tsk.mean > 0 {
+		return fmt.Errorf("%s: no pile-up event in %q", tsk.Name(), tsk.fname)
+	}
+
+	return err
+}
+
+func (tsk *PileUpMerger) StopTask(ctx fwk.Context) error {
+	var err error
+
+	tsk.evts = nil
+	return err
+}
+
+func (tsk *PileUpMerger) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	input := v.([]Candidate)
+	msg.Debugf(">>> input: %v\n", len(input))
+
+	output := make([]Candidate, 0, len(input))
+	vtxs := make([]Candidate, 0)
+	defer func() {
+		err = store.Put(tsk.output, output)
+		if err != nil {
+			return
+		}
+		err = store.Put(tsk.vtxs, vtxs)
+	}()
+
+	const cLight = 2.99792458e8
+
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	gauss := distuv.Normal{Mu: 0, Sigma: 1, Src: src}
+	dz := gauss.Rand() * tsk.zspread * 1e3
+	dt := gauss.Rand() * tsk.tspread * cLight * 1e3
+
+	// add main event
+	var (
+		vx, vy  float64
+		sumpt2  float64
+		ntracks int32
+	)
+	for i := range input {
+		c := input[i]
+		vx += c.Pos.X()
+		vy += c.Pos.Y()
+		c.Pos = fmom.NewPxPyPzE(c.Pos.X(), c.Pos.Y(), c.Pos.Z()+dz, c.Pos.T()+dt)
+		if c.CandCharge != 0 && c.CandCharge != -999 {
+			pt := c.Mom.Pt()
+			sumpt2 += pt * pt
+			ntracks++
+		}
+		output = append(output, c)
+	}
+	if n := float64(len(input)); n > 0 {
+		vx /= n
+		vy /= n
+	}
+	vtxs = append(vtxs, Candidate{
+		Pos:          fmom.NewPxPyPzE(vx, vy, dz, dt),
+		ClusterIndex: 0,
+		ClusterNDF:   ntracks,
+		SumPt2:       sumpt2,
+	})
+
+	// add pile-up interactions
+	npu := 0
+	switch tsk.dist {
+	case PileUpPoisson:
+		if tsk.mean > 0 {
+			npu = int(distuv.Poisson{Lambda: tsk.mean, Src: src}.Rand())
+		}
+	case PileUpUniform:
+		npu = src.IntN(int(2*tsk.mean) + 1)
+	case PileUpFixed:
+		npu = int(tsk.mean)
+	default:
+		return fmt.Errorf("%s: invalid pile-up distribution (%d)", tsk.Name(), tsk.dist)
+	}
+
+	for ipu := range npu {
+		evt := tsk.evts[src.IntN(len(tsk.evts))]
+		dz := gauss.Rand() * tsk.zspread * 1e3
+		dt := gauss.Rand() * tsk.tspread * cLight * 1e3
+		dphi := src.Float64()*2*math.Pi - math.Pi
+		sin, cos := math.Sincos(dphi)
+
+		vx, vy = 0, 0
+		sumpt2 = 0
+		ntracks = 0
+		for i := range evt {
+			c := evt[i]
+			px := c.Mom.Px()*cos - c.Mom.Py()*sin
+			py := c.Mom.Px()*sin + c.Mom.Py()*cos
+			c.Mom = fmom.NewPxPyPzE(px, py, c.Mom.Pz(), c.Mom.E())
+
+			x := c.Pos.X()*cos - c.Pos.Y()*sin
+			y := c.Pos.X()*sin + c.Pos.Y()*cos
+			c.Pos = fmom.NewPxPyPzE(x, y, c.Pos.Z()+dz, c.Pos.T()+dt)
+
+			vx += x
+			vy += y
+			if c.CandCharge != 0 && c.CandCharge != -999 {
+				pt := c.Mom.Pt()
+				sumpt2 += pt * pt
+				ntracks++
+			}
+			output = append(output, c)
+		}
+		if n := float64(len(evt)); n > 0 {
+			vx /= n
+			vy /= n
+		}
+		vtxs = append(vtxs, Candidate{
+			IsPU:         1,
+			Pos:          fmom.NewPxPyPzE(vx, vy, dz, dt),
+			ClusterIndex: int32(ipu + 1),
+			ClusterNDF:   ntracks,
+			SumPt2:       sumpt2,
+		})
+	}
+
+	msg.Debugf(">>> pile-up: %v\n", npu)
+	msg.Debugf(">>> output:  %v\n", len(output))
+
+	return err
+}
+
+func newPileUpMerger(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &PileUpMerger{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+		input:    "InputParticles",
+		output:   "OutputParticles",
+		vtxs:     "Vertices",
+		fname:    "MinBias.hepmc",
+		dist:     PileUpPoisson,
+		mean:     10,
+		zspread:  0.15,
+		tspread:  1.5e-9,
+		rnd:      newRandSrc(1234),
+	}
+
+	err = tsk.DeclProp("Input", &tsk.input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Output", &tsk.output)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("VertexOutput", &tsk.vtxs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PileUpFile", &tsk.fname)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PileUpDistribution", &tsk.dist)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("MeanPileUp", &tsk.mean)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("ZVertexSpread", &tsk.zspread)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("TVertexSpread", &tsk.tspread)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(PileUpMerger{}), newPileUpMerger)
+}
@@ -0,0 +1,115 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"testing"
+)
+
+func TestPileUpMerger(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		dist  PileUpDistribution
+		mean  float64
+		npu   []int // allowed numbers of pile-up interactions
+		vtxZ0 bool  // whether the main vertex stays at z=0
+	}{
+		{
+			name:  "no-pileup",
+			dist:  PileUpFixed,
+			mean:  0,
+			npu:   []int{0},
+			vtxZ0: true,
+		},
+		{
+			name: "fixed",
+			dist: PileUpFixed,
+			mean: 3,
+			npu:  []int{3},
+		},
+		{
+			name: "uniform",
+			dist: PileUpUniform,
+			mean: 1,
+			npu:  []int{0, 1, 2},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			zspread := 0.05
+			if tc.vtxZ0 {
+				zspread = 0
+			}
+			tsk := &PileUpMerger{
+				input:   "input",
+				output:  "output",
+				vtxs:    "vtxs",
+				fname:   "testdata/hepmc.data",
+				dist:    tc.dist,
+				mean:    tc.mean,
+				zspread: zspread,
+				rnd:     newRandSrc(1234),
+			}
+
+			ctx := newTestContext()
+			err := tsk.StartTask(ctx)
+			if err != nil {
+				t.Fatalf("could not start task: %+v", err)
+			}
+			if len(tsk.evts) == 0 {
+				t.Fatalf("no pile-up event read")
+			}
+
+			input := []Candidate{newTrack(10, 0, 0), newTrack(20, 1, 1)}
+			ctx.store["input"] = input
+			err = tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			vtxs := ctx.store["vtxs"].([]Candidate)
+			output := ctx.store["output"].([]Candidate)
+
+			npu := len(vtxs) - 1
+			ok := false
+			for _, n := range tc.npu {
+				ok = ok || n == npu
+			}
+			if !ok {
+				t.Fatalf("invalid number of pile-up interactions: got=%d, want=%v", npu, tc.npu)
+			}
+
+			if vtxs[0].IsPU != 0 {
+				t.Fatalf("main vertex flagged as pile-up")
+			}
+			if got := vtxs[0].Pos.Z(); tc.vtxZ0 && got != 0 {
+				t.Fatalf("invalid main vertex z: got=%v, want=0", got)
+			}
+			for i, vtx := range vtxs[1:] {
+				if vtx.IsPU != 1 {
+					t.Fatalf("pile-up vertex %d not flagged as pile-up", i+1)
+				}
+				if got, want := vtx.ClusterIndex, int32(i+1); got != want {
+					t.Fatalf("invalid cluster index: got=%d, want=%d", got, want)
+				}
+			}
+
+			if npu == 0 && len(output) != len(input) {
+				t.Fatalf("invalid output size: got=%d, want=%d", len(output), len(input))
+			}
+			if npu > 0 && len(output) <= len(input) {
+				t.Fatalf("no pile-up particle in output (n=%d)", len(output))
+			}
+			for i, c := range output {
+				want := byte(0)
+				if i >= len(input) {
+					want = 1
+				}
+				if c.IsPU != want {
+					t.Fatalf("particle %d: invalid IsPU: got=%d, want=%d", i, c.IsPU, want)
+				}
+			}
+		})
+	}
+}
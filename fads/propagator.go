@@ -182,9 +182,18 @@ func (tsk *Propagator) Process(ctx fwk.Context) error {
 			yt := y + py*t
 			zt = z + pz*t
 
+			// coordinates of closest approach to the beam axis
+			td := -(px*x + py*y) / pt2
+			xd := x + px*td
+			yd := y + py*td
+			zd := z + pz*td
+
 			mother := cand
 			c := cand.Clone()
 			c.Pos = fmom.NewPxPyPzE(xt*1e3, yt*1e3, zt*1e3, cand.Pos.T()+t*e*1e3)
+			c.Xd = xd * 1e3
+			c.Yd = yd * 1e3
+			c.Zd = zd * 1e3
 			c.Add(mother)
 
 			output = append(output, *c)
@@ -226,6 +235,19 @@ func (tsk *Propagator) Process(ctx fwk.Context) error {
 				phi += math.Pi
 			}
 
+			// coordinates of closest approach to the track circle in the transverse plane
+			var (
+				rcu = math.Abs(r)
+				rc2 = rc * rc
+				xd  = -999.0
+				yd  = -999.0
+			)
+			if rc2 > 0 {
+				xd = (xc*xc*xc - xc*rcu*rc + xc*yc*yc) / rc2
+				yd = yc * (-rcu*rc + rc2) / rc2
+			}
+			zd := z + (math.Hypot(xd, yd)-math.Hypot(x, y))*pz/pt
+
 			// 3. time evaluation t = TMath::Min(t_r, t_z)
 			//    t_r : time to exit from the sides
 			//    t_z : time to exit from the front or the back
@@ -299,6 +321,9 @@ func (tsk *Propagator) Process(ctx fwk.Context) error {
 				mother := cand
 				c := cand.Clone()
 				c.Pos = fmom.NewPxPyPzE(xt*1e3, yt*1e3, zt*1e3, cand.Pos.T()+t*cLight*1e3)
+				c.Xd = xd * 1e3
+				c.Yd = yd * 1e3
+				c.Zd = zd * 1e3
 				c.Add(mother)
 
 				output = append(output, *c)
@@ -0,0 +1,73 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// randsrc provides the pseudo-random numbers of a task.
+//
+// Numbers are drawn from the per-event stream of the task, handed out by
+// the fwk.RandSvc of the application, so results do not depend on the
+// number of events processed concurrently.
+// Tasks fall back on their own generator, seeded with their Seed
+// property, when the application has no such service.
+type randsrc struct {
+	seed uint64 // seed of the generator of the task
+	rsvc string // name of the fwk.RandSvc service
+
+	svc fwk.RandSvc
+	mu  sync.Mutex
+	src *rand.Rand
+}
+
+func newRandSrc(seed uint64) randsrc {
+	return randsrc{
+		seed: seed,
+		rsvc: "randsvc",
+	}
+}
+
+func (r *randsrc) start(ctx fwk.Context) error {
+	r.src = rand.New(rand.NewPCG(r.seed, r.seed))
+	r.svc = nil
+	if r.rsvc == "" {
+		return nil
+	}
+
+	svc, err := ctx.Svc(r.rsvc)
+	if err != nil {
+		// no such service. use the generator of the task.
+		return nil
+	}
+
+	rsvc, ok := svc.(fwk.RandSvc)
+	if !ok {
+		return fmt.Errorf("fads: service [%s] is not a fwk.RandSvc (type=%T)", r.rsvc, svc)
+	}
+	r.svc = rsvc
+	return nil
+}
+
+// source returns the source of pseudo-random numbers of the named task
+// for the event being processed by ctx, together with the function
+// releasing that source.
+func (r *randsrc) source(ctx fwk.Context, name string) (*rand.Rand, func(), error) {
+	if r.svc != nil {
+		rs, err := r.svc.Stream(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rs.Rand, func() {}, nil
+	}
+
+	r.mu.Lock()
+	return r.src, r.mu.Unlock, nil
+}
@@ -0,0 +1,414 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/fwk"
+)
+
+// SimpleCalorimeter simulates a single calorimeter, electromagnetic or
+// hadronic, as used by the recent Delphes detector cards where the ECal and
+// HCal towers are produced by two SimpleCalorimeter modules.
+//
+// Particles deposit the fraction of their energy given by EnergyFraction
+// in the towers. Tracks with a non-zero energy fraction are associated to
+// the towers they hit, the other ones being passed on as energy-flow tracks.
+//
+// For each tower, the energy excess over the energy of the associated tracks
+// is saved as an energy-flow tower when it is significant. Otherwise, the
+// energy of the associated tracks is rescaled to the combination of the
+// calorimeter and tracker measurements.
+type SimpleCalorimeter struct {
+	fwk.TaskBase
+
+	efrac map[int]float64
+	bins  EtaPhiGrid
+	res   func(eta, ene float64) float64
+
+	isECal   bool
+	eneMin   float64
+	sigMin   float64
+	smearPos bool
+
+	particles   string
+	tracks      string
+	towers      string
+	eflowtracks string
+	eflowtowers string
+
+	rnd randsrc
+}
+
+func (tsk *SimpleCalorimeter) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.particles, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclInPort(tsk.tracks, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.towers, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.eflowtracks, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.eflowtowers, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *SimpleCalorimeter) StartTask(ctx fwk.Context) error {
+	var err error
+	err = tsk.rnd.start(ctx)
+	return err
+}
+
+func (tsk *SimpleCalorimeter) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+// fraction returns the fraction of energy deposited by the particle pid.
+func (tsk *SimpleCalorimeter) fraction(pid int32) float64 {
+	frac, ok := tsk.efrac[int(absPid(pid))]
+	if !ok {
+		frac = tsk.efrac[0]
+	}
+	return frac
+}
+
+func (tsk *SimpleCalorimeter) Process(ctx fwk.Context) error {
+	var err error
+
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.particles)
+	if err != nil {
+		return err
+	}
+	parts := v.([]Candidate)
+	msg.Debugf(">>> particles: %v\n", len(parts))
+
+	v, err = store.Get(tsk.tracks)
+	if err != nil {
+		return err
+	}
+	tracks := v.([]Candidate)
+	msg.Debugf(">>> tracks: %v\n", len(tracks))
+
+	towers := make([]Candidate, 0, len(tracks))
+	defer func() {
+		err = store.Put(tsk.towers, towers)
+	}()
+
+	eflowtracks := make([]Candidate, 0, len(tracks))
+	defer func() {
+		err = store.Put(tsk.eflowtracks, eflowtracks)
+	}()
+
+	eflowtowers := make([]Candidate, 0, len(tracks))
+	defer func() {
+		err = store.Put(tsk.eflowtowers, eflowtowers)
+	}()
+
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	hits := make(map[int64][]int64)
+	partfracs := make([]float64, len(parts))
+	trkfracs := make([]float64, len(tracks))
+
+	// process particles
+	for i := range parts {
+		part := &parts[i]
+		frac := tsk.fraction(part.Pid)
+		partfracs[i] = frac
+		if frac < 1e-9 {
+			continue
+		}
+
+		etabin, phibin, ok := tsk.bins.EtaPhiIndex(part.Pos.Eta(), part.Pos.Phi())
+		if !ok {
+			continue
+		}
+
+		// make tower hit:
+		// {16-bits: eta bin-id} {16-bits: phi bin-id} {8-bits: flags}
+		// {24-bits: particle number}
+		hit := (int64(etabin) << 48) | (int64(phibin) << 32) | int64(i)
+		towerid := hit >> 32
+		hits[towerid] = append(hits[towerid], hit)
+	}
+
+	// process tracks
+	for i := range tracks {
+		track := &tracks[i]
+		frac := tsk.fraction(track.Pid)
+		trkfracs[i] = frac
+		if frac < 1e-9 {
+			// tracks not seen by the calorimeter, e.g. muons.
+			eflowtracks = append(eflowtracks, *track)
+			continue
+		}
+
+		etabin, phibin, ok := tsk.bins.EtaPhiIndex(track.Pos.Eta(), track.Pos.Phi())
+		if !ok {
+			continue
+		}
+
+		flags := int64(1)
+		hit := (int64(etabin) << 48) | (int64(phibin) << 32) | (flags << 24) | int64(i)
+		towerid := hit >> 32
+		hits[towerid] = append(hits[towerid], hit)
+	}
+
+	twrhits := make([]int64, 0, len(hits))
+	for towerid, hits := range hits {
+		sort.Sort(int64Slice(hits))
+		twrhits = append(twrhits, towerid)
+	}
+	sort.Sort(int64Slice(twrhits))
+
+	// process hits
+	for _, towerid := range twrhits {
+		iphi := (towerid >> 00) & 0x000000000000FFFF
+		ieta := (towerid >> 16) & 0x000000000000FFFF
+
+		eta, phi, ok := tsk.bins.EtaPhiBin(int(ieta), int(iphi))
+		if !ok {
+			return fmt.Errorf("simple-calorimeter: no valid eta/phi bin (ieta=%d iphi=%d)", ieta, iphi)
+		}
+
+		etabins := tsk.bins.eta
+		phibins := tsk.bins.phi[etabins[ieta]]
+		edges := [4]float64{
+			etabins[ieta-1],
+			etabins[ieta],
+			phibins[iphi-1],
+			phibins[iphi],
+		}
+
+		var (
+			calo     etwData
+			trkEne   float64
+			trkSigma float64
+			tower    Candidate
+			twrtrks  []Candidate
+		)
+
+		for _, hit := range hits[towerid] {
+			flags := (hit >> 24) & 0x00000000000000FF
+			n := hit & 0x0000000000FFFFFF
+
+			switch {
+			case (flags & 1) != 0: // track hits
+				track := &tracks[n]
+				ene := track.Mom.E()
+				trkEne += ene * trkfracs[n]
+				sigma := track.TrackResolution * ene
+				trkSigma += sigma * sigma
+				twrtrks = append(twrtrks, *track)
+
+			default:
+				part := &parts[n]
+				calo.Add(part.Mom.E()*partfracs[n], part.Pos.T())
+				tower.Add(part)
+			}
+		}
+
+		sigma := tsk.res(eta, calo.Ene)
+		ene := lognormal(src, calo.Ene, sigma)
+		time := 0.0
+		if calo.WeightTime >= 1e-9 {
+			time = calo.Time / calo.WeightTime
+		}
+
+		sigma = tsk.res(eta, ene)
+		if ene < tsk.eneMin || ene < tsk.sigMin*sigma {
+			ene = 0
+		}
+
+		if tsk.smearPos {
+			eta = src.Float64()*(edges[1]-edges[0]) + edges[0]
+			phi = src.Float64()*(edges[3]-edges[2]) + edges[2]
+		}
+
+		tower.Pos = newPtEtaPhiE(1, eta, phi, time)
+		tower.Mom = newPtEtaPhiE(ene/math.Cosh(eta), eta, phi, ene)
+		tower.Eem, tower.Ehad = 0, ene
+		if tsk.isECal {
+			tower.Eem, tower.Ehad = ene, 0
+		}
+		tower.Edges = edges
+
+		if ene > 0 {
+			towers = append(towers, tower)
+		}
+
+		// fill energy-flow candidates
+		trkSigma = math.Sqrt(trkSigma)
+		neutralEne := math.Max(ene-trkEne, 0)
+		neutralSig := neutralEne / math.Sqrt(trkSigma*trkSigma+sigma*sigma)
+
+		switch {
+		case neutralEne > tsk.eneMin && neutralSig > tsk.sigMin:
+			// significant neutral excess: save it as an energy-flow tower.
+			eflow := tower
+			eflow.Mom = newPtEtaPhiE(neutralEne/math.Cosh(eta), eta, phi, neutralEne)
+			eflow.Eem, eflow.Ehad = 0, neutralEne
+			eflow.Pid = 0
+			if tsk.isECal {
+				eflow.Eem, eflow.Ehad = neutralEne, 0
+				eflow.Pid = 22
+			}
+			eflowtowers = append(eflowtowers, eflow)
+			eflowtracks = append(eflowtracks, twrtrks...)
+
+		case trkEne > 0:
+			// rescale the tracks to the best estimate of their energy,
+			// combining the calorimeter and tracker measurements.
+			wtrk := 0.0
+			if trkSigma > 0 {
+				wtrk = 1 / (trkSigma * trkSigma)
+			}
+			wcalo := 0.0
+			if sigma > 0 {
+				wcalo = 1 / (sigma * sigma)
+			}
+			scale := 1.0
+			if wtrk+wcalo > 0 {
+				scale = (wtrk*trkEne + wcalo*ene) / (wtrk + wcalo) / trkEne
+			}
+			for i := range twrtrks {
+				trk := &twrtrks[i]
+				trk.Mom = fmom.NewPxPyPzE(scale*trk.Mom.Px(), scale*trk.Mom.Py(), scale*trk.Mom.Pz(), scale*trk.Mom.E())
+			}
+			eflowtracks = append(eflowtracks, twrtrks...)
+		}
+	}
+
+	return err
+}
+
+func newSimpleCalorimeter(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &SimpleCalorimeter{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+
+		bins:     NewEtaPhiGrid(nil),
+		efrac:    make(map[int]float64),
+		res:      func(eta, ene float64) float64 { return 0 },
+		smearPos: true,
+
+		particles:   "/fads/particles",
+		tracks:      "/fads/tracks",
+		towers:      "/fads/towers",
+		eflowtracks: "/fads/eflowtracks",
+		eflowtowers: "/fads/eflowtowers",
+
+		rnd: newRandSrc(1234),
+	}
+
+	err = tsk.DeclProp("EtaPhiBins", &tsk.bins)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EnergyFraction", &tsk.efrac)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Resolution", &tsk.res)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("IsECal", &tsk.isECal)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EnergyMin", &tsk.eneMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EnergySignificanceMin", &tsk.sigMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("SmearTowerCenter", &tsk.smearPos)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Particles", &tsk.particles)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Tracks", &tsk.tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Towers", &tsk.towers)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EFlowTracks", &tsk.eflowtracks)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("EFlowTowers", &tsk.eflowtowers)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(SimpleCalorimeter{}), newSimpleCalorimeter)
+}
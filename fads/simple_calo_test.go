@@ -0,0 +1,141 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimpleCalorimeter(t *testing.T) {
+	particle := func(pid int32, ene, eta, phi float64) Candidate {
+		c := newTrack(ene/math.Cosh(eta), eta, phi)
+		c.Pid = pid
+		c.Pos = newPtEtaPhiE(1, eta, phi, 0)
+		return c
+	}
+
+	newECal := func() *SimpleCalorimeter {
+		var etas, phis []float64
+		for i := -10; i <= 10; i++ {
+			etas = append(etas, 0.1*float64(i))
+		}
+		for i := -18; i <= 18; i++ {
+			phis = append(phis, float64(i)*math.Pi/18)
+		}
+		return &SimpleCalorimeter{
+			bins: NewEtaPhiGrid([]EtaPhiBin{{EtaBins: etas, PhiBins: phis}}),
+			efrac: map[int]float64{
+				0:  0,
+				11: 1,
+				22: 1,
+			},
+			res:         func(eta, ene float64) float64 { return 0 },
+			isECal:      true,
+			eneMin:      0.5,
+			sigMin:      2,
+			particles:   "particles",
+			tracks:      "tracks",
+			towers:      "towers",
+			eflowtracks: "eflowtracks",
+			eflowtowers: "eflowtowers",
+			rnd:         newRandSrc(1234),
+		}
+	}
+
+	for _, tc := range []struct {
+		name        string
+		particles   []Candidate
+		tracks      []Candidate
+		towers      int
+		eflowtracks int
+		eflowtowers int
+		pid         int32
+	}{
+		{
+			name:        "photon",
+			particles:   []Candidate{particle(22, 50, 0.05, 0.1)},
+			towers:      1,
+			eflowtowers: 1,
+			pid:         22,
+		},
+		{
+			name:        "muon",
+			particles:   []Candidate{particle(13, 50, 0.05, 0.1)},
+			tracks:      []Candidate{particle(13, 50, 0.05, 0.1)},
+			eflowtracks: 1,
+		},
+		{
+			name:        "electron",
+			particles:   []Candidate{particle(11, 50, 0.05, 0.1)},
+			tracks:      []Candidate{particle(11, 50, 0.05, 0.1)},
+			towers:      1,
+			eflowtracks: 1,
+		},
+		{
+			name: "electron-and-photon",
+			particles: []Candidate{
+				particle(11, 50, 0.05, 0.1),
+				particle(22, 20, 0.05, 0.1),
+			},
+			tracks:      []Candidate{particle(11, 50, 0.05, 0.1)},
+			towers:      1,
+			eflowtracks: 1,
+			eflowtowers: 1,
+			pid:         22,
+		},
+		{
+			name:      "outside",
+			particles: []Candidate{particle(22, 50, 2, 0.1)},
+		},
+		{
+			name:      "below-threshold",
+			particles: []Candidate{particle(22, 0.1, 0.05, 0.1)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := newECal()
+
+			ctx := newTestContext()
+			ctx.store["particles"] = tc.particles
+			ctx.store["tracks"] = tc.tracks
+
+			err := tsk.StartTask(ctx)
+			if err != nil {
+				t.Fatalf("could not start task: %+v", err)
+			}
+
+			err = tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			towers := ctx.store["towers"].([]Candidate)
+			if got, want := len(towers), tc.towers; got != want {
+				t.Fatalf("invalid number of towers: got=%d, want=%d", got, want)
+			}
+			for _, twr := range towers {
+				if twr.Ehad != 0 || twr.Eem != twr.Mom.E() {
+					t.Fatalf("invalid ECal tower energies: eem=%v, ehad=%v", twr.Eem, twr.Ehad)
+				}
+			}
+
+			eflowtracks := ctx.store["eflowtracks"].([]Candidate)
+			if got, want := len(eflowtracks), tc.eflowtracks; got != want {
+				t.Fatalf("invalid number of energy-flow tracks: got=%d, want=%d", got, want)
+			}
+
+			eflowtowers := ctx.store["eflowtowers"].([]Candidate)
+			if got, want := len(eflowtowers), tc.eflowtowers; got != want {
+				t.Fatalf("invalid number of energy-flow towers: got=%d, want=%d", got, want)
+			}
+			for _, twr := range eflowtowers {
+				if got, want := twr.Pid, tc.pid; got != want {
+					t.Fatalf("invalid energy-flow tower pid: got=%d, want=%d", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// StatusPidFilter keeps the generated particles of interest of an event,
+// as Delphes does to reduce the size of the stored generator record:
+// hard-scattering particles, leptons, heavy quarks, gauge bosons, stable
+// photons, SUSY particles and B or C hadrons.
+// Particles with a transverse momentum below PtMin are removed.
+type StatusPidFilter struct {
+	fwk.TaskBase
+
+	input  string
+	output string
+
+	ptMin float64
+}
+
+func (tsk *StatusPidFilter) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *StatusPidFilter) StartTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *StatusPidFilter) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *StatusPidFilter) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	input := v.([]Candidate)
+	msg.Debugf(">>> input: %v\n", len(input))
+
+	output := make([]Candidate, 0, len(input))
+	for i := range input {
+		cand := &input[i]
+		if cand.Mom.Pt() < tsk.ptMin {
+			continue
+		}
+		if !isStatusPidSelected(cand.Status, cand.Pid) {
+			continue
+		}
+		output = append(output, *cand)
+	}
+
+	msg.Debugf(">>> output: %v\n", len(output))
+
+	err = store.Put(tsk.output, output)
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+// isStatusPidSelected returns whether a particle with the provided status
+// and PDG code is kept by the StatusPidFilter.
+func isStatusPidSelected(status, pid int32) bool {
+	pdg := absPid(pid)
+	switch {
+	case pdg >= 1000001 && pdg <= 1000039: // SUSY particles
+		return true
+	case status == 3: // hard-scattering particles (Pythia 6)
+		return true
+	case status > 20 && status < 30: // hard-scattering particles (Pythia 8)
+		return true
+	case pdg > 10 && pdg < 17: // leptons and neutrinos
+		return true
+	case pdg == 4 || pdg == 5 || pdg == 6: // heavy quarks
+		return true
+	case pdg > 22 && pdg < 43: // gauge bosons and other fundamental bosons
+		return true
+	case pdg == 22 && status == 1: // stable photons
+		return true
+	case hadronFlavour(pid) != 0: // B and C hadrons
+		return true
+	}
+	return false
+}
+
+func newStatusPidFilter(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &StatusPidFilter{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+		input:    "InputParticles",
+		output:   "OutputParticles",
+		ptMin:    0.5,
+	}
+
+	err = tsk.DeclProp("Input", &tsk.input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Output", &tsk.output)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PtMin", &tsk.ptMin)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(StatusPidFilter{}), newStatusPidFilter)
+}
@@ -0,0 +1,59 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"testing"
+)
+
+func TestStatusPidFilter(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		pid    int32
+		status int32
+		pt     float64
+		kept   bool
+	}{
+		{name: "hard-pythia6", pid: 1, status: 3, pt: 10, kept: true},
+		{name: "hard-pythia8", pid: 21, status: 23, pt: 10, kept: true},
+		{name: "electron", pid: -11, status: 1, pt: 10, kept: true},
+		{name: "neutrino", pid: 14, status: 1, pt: 10, kept: true},
+		{name: "b-quark", pid: -5, status: 71, pt: 10, kept: true},
+		{name: "light-quark", pid: 2, status: 71, pt: 10},
+		{name: "w-boson", pid: 24, status: 62, pt: 10, kept: true},
+		{name: "stable-photon", pid: 22, status: 1, pt: 10, kept: true},
+		{name: "decayed-photon", pid: 22, status: 2, pt: 10},
+		{name: "neutralino", pid: 1000022, status: 1, pt: 10, kept: true},
+		{name: "b-hadron", pid: 511, status: 2, pt: 10, kept: true},
+		{name: "c-hadron", pid: -421, status: 2, pt: 10, kept: true},
+		{name: "pion", pid: 211, status: 1, pt: 10},
+		{name: "low-pt", pid: 11, status: 1, pt: 0.1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := &StatusPidFilter{
+				input:  "input",
+				output: "output",
+				ptMin:  0.5,
+			}
+
+			c := newTrack(tc.pt, 0, 0)
+			c.Pid = tc.pid
+			c.Status = tc.status
+
+			ctx := newTestContext()
+			ctx.store["input"] = []Candidate{c}
+
+			err := tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			output := ctx.store["output"].([]Candidate)
+			if got, want := len(output) == 1, tc.kept; got != want {
+				t.Fatalf("invalid selection: got=%v, want=%v", got, want)
+			}
+		})
+	}
+}
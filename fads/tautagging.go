@@ -6,13 +6,10 @@ package fads
 
 import (
 	"math"
-	"math/rand/v2"
 	"reflect"
-	"sync"
 
 	"go-hep.org/x/hep/fmom"
 	"go-hep.org/x/hep/fwk"
-	"gonum.org/v1/gonum/stat/distuv"
 )
 
 type tauclassifier struct {
@@ -65,11 +62,7 @@ type TauTagging struct {
 	tag tauclassifier
 	eff map[int]func(pt, eta float64) float64
 
-	seed uint64
-	src  *rand.Rand
-
-	flatmu sync.Mutex
-	flat   distuv.Uniform
+	rnd randsrc
 }
 
 func (tsk *TauTagging) Configure(ctx fwk.Context) error {
@@ -90,14 +83,12 @@ func (tsk *TauTagging) Configure(ctx fwk.Context) error {
 		return err
 	}
 
-	tsk.src = rand.New(rand.NewPCG(tsk.seed, tsk.seed))
-	tsk.flat = distuv.Uniform{Min: 0, Max: 1, Src: tsk.src}
 	return err
 }
 
 func (tsk *TauTagging) StartTask(ctx fwk.Context) error {
 	var err error
-
+	err = tsk.rnd.start(ctx)
 	return err
 }
 
@@ -138,6 +129,12 @@ func (tsk *TauTagging) Process(ctx fwk.Context) error {
 		err = store.Put(tsk.output, output)
 	}()
 
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	msg.Debugf("particles: %d\n", len(particles))
 	msg.Debugf("partons:   %d\n", len(allpartons))
 	msg.Debugf("jets:      %d\n", len(jets))
@@ -158,11 +155,9 @@ func (tsk *TauTagging) Process(ctx fwk.Context) error {
 		pt := jet.Mom.Pt()
 
 		charge := int32(-1)
-		tsk.flatmu.Lock()
-		if tsk.flat.Rand() > 0.5 {
+		if src.Float64() > 0.5 {
 			charge = 1
 		}
-		tsk.flatmu.Unlock()
 
 		for j := range taus {
 			mc := &taus[j]
@@ -193,11 +188,9 @@ func (tsk *TauTagging) Process(ctx fwk.Context) error {
 
 		// apply efficiency
 		tag := uint32(0)
-		tsk.flatmu.Lock()
-		if tsk.flat.Rand() <= eff(pt, eta) {
+		if src.Float64() <= eff(pt, eta) {
 			tag = 1
 		}
-		tsk.flatmu.Unlock()
 		jet.TauTag = tag
 		jet.CandCharge = charge
 
@@ -227,7 +220,7 @@ func newTauTagging(typ, name string, mgr fwk.App) (fwk.Component, error) {
 			0: func(pt, eta float64) float64 { return 0 },
 		},
 
-		seed: 1234,
+		rnd: newRandSrc(1234),
 	}
 
 	err = tsk.DeclProp("Particles", &tsk.particles)
@@ -270,7 +263,12 @@ func newTauTagging(typ, name string, mgr fwk.App) (fwk.Component, error) {
 		return nil, err
 	}
 
-	err = tsk.DeclProp("Seed", &tsk.seed)
+	err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,200 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"reflect"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/fwk"
+)
+
+// TrackCountingBTagging tags jets as b-jets when they contain at least
+// a given number of tracks with a large signed impact parameter significance.
+type TrackCountingBTagging struct {
+	fwk.TaskBase
+
+	tracks string
+	jets   string
+	output string
+
+	bit    uint
+	ptMin  float64
+	dR     float64
+	ipMax  float64
+	sigMin float64
+	ntrks  int
+}
+
+func (tsk *TrackCountingBTagging) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.tracks, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclInPort(tsk.jets, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *TrackCountingBTagging) StartTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *TrackCountingBTagging) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *TrackCountingBTagging) Process(ctx fwk.Context) error {
+	var err error
+
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.tracks)
+	if err != nil {
+		return err
+	}
+	tracks := v.([]Candidate)
+
+	v, err = store.Get(tsk.jets)
+	if err != nil {
+		return err
+	}
+	jets := v.([]Candidate)
+
+	msg.Debugf("tracks: %d\n", len(tracks))
+	msg.Debugf("jets:   %d\n", len(jets))
+
+	output := make([]Candidate, 0, len(jets))
+	defer func() {
+		err = store.Put(tsk.output, output)
+	}()
+
+	for i := range jets {
+		jet := jets[i].Clone()
+		jpx := jet.Mom.Px()
+		jpy := jet.Mom.Py()
+
+		n := 0
+		for j := range tracks {
+			trk := &tracks[j]
+			if trk.Mom.Pt() < tsk.ptMin {
+				continue
+			}
+			if fmom.DeltaR(&jet.Mom, &trk.Mom) > tsk.dR {
+				continue
+			}
+			d0 := math.Abs(trk.D0)
+			if d0 > tsk.ipMax {
+				continue
+			}
+
+			sign := -1.0
+			if jpx*trk.Xd+jpy*trk.Yd > 0 {
+				sign = +1.0
+			}
+
+			ip := sign * d0
+			sip := ip / math.Abs(trk.ErrD0)
+			if sip > tsk.sigMin {
+				n++
+			}
+		}
+
+		if n >= tsk.ntrks {
+			jet.BTag |= 1 << tsk.bit
+		}
+
+		output = append(output, *jet)
+	}
+
+	msg.Debugf("output: %d\n", len(output))
+	return err
+}
+
+func newTrackCountingBTagging(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &TrackCountingBTagging{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+		tracks:   "InputTracks",
+		jets:     "InputJets",
+		output:   "OutputJets",
+
+		bit:    1,
+		ptMin:  1.0,
+		dR:     0.3,
+		ipMax:  2.0,
+		sigMin: 6.5,
+		ntrks:  3,
+	}
+
+	err = tsk.DeclProp("Tracks", &tsk.tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Jets", &tsk.jets)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Output", &tsk.output)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("BitNumber", &tsk.bit)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("TrackPtMin", &tsk.ptMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("DeltaR", &tsk.dR)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("TrackIPMax", &tsk.ipMax)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("SigMin", &tsk.sigMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Ntracks", &tsk.ntrks)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(TrackCountingBTagging{}), newTrackCountingBTagging)
+}
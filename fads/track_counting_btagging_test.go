@@ -0,0 +1,87 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"testing"
+)
+
+func TestTrackCountingBTagging(t *testing.T) {
+	// displaced track, with a significance of 10.
+	track := func(pt, eta, d0, xd float64) Candidate {
+		c := newTrack(pt, eta, 0)
+		c.D0 = d0
+		c.ErrD0 = 0.01
+		c.Xd = xd
+		return c
+	}
+
+	for _, tc := range []struct {
+		name   string
+		tracks []Candidate
+		want   uint32
+	}{
+		{
+			name:   "tagged",
+			tracks: []Candidate{track(5, 0.1, 0.1, 1), track(5, -0.1, -0.1, 1)},
+			want:   1 << 2,
+		},
+		{
+			name:   "one-track",
+			tracks: []Candidate{track(5, 0.1, 0.1, 1)},
+		},
+		{
+			name:   "outside-cone",
+			tracks: []Candidate{track(5, 0.1, 0.1, 1), track(5, 1, 0.1, 1)},
+		},
+		{
+			name:   "negative-ip",
+			tracks: []Candidate{track(5, 0.1, 0.1, 1), track(5, -0.1, 0.1, -1)},
+		},
+		{
+			name:   "low-pt",
+			tracks: []Candidate{track(5, 0.1, 0.1, 1), track(0.5, -0.1, 0.1, 1)},
+		},
+		{
+			name:   "large-ip",
+			tracks: []Candidate{track(5, 0.1, 0.1, 1), track(5, -0.1, 3, 1)},
+		},
+		{
+			name:   "low-significance",
+			tracks: []Candidate{track(5, 0.1, 0.1, 1), track(5, -0.1, 0.01, 1)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := &TrackCountingBTagging{
+				tracks: "tracks",
+				jets:   "jets",
+				output: "output",
+				bit:    2,
+				ptMin:  1,
+				dR:     0.3,
+				ipMax:  2,
+				sigMin: 6.5,
+				ntrks:  2,
+			}
+
+			ctx := newTestContext()
+			ctx.store["tracks"] = tc.tracks
+			ctx.store["jets"] = []Candidate{newTrack(50, 0, 0)}
+
+			err := tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			output := ctx.store["output"].([]Candidate)
+			if len(output) != 1 {
+				t.Fatalf("invalid number of jets: got=%d, want=1", len(output))
+			}
+			if got, want := output[0].BTag, tc.want; got != want {
+				t.Fatalf("invalid b-tag: got=%b, want=%b", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// TrackPileUpSubtractor removes charged pile-up particles whose vertex
+// is incompatible with the primary vertex.
+//
+// The primary vertex is the first vertex, not flagged as pile-up,
+// of the input vertex collection.
+// Pile-up subtraction is assumed to be perfect for charged particles
+// further away than ZVertexResolution from the primary vertex.
+type TrackPileUpSubtractor struct {
+	fwk.TaskBase
+
+	vtxs  string
+	colls []ObjPair
+
+	zres  float64 // vertex resolution along the beam axis, in meters
+	ptMin float64
+}
+
+func (tsk *TrackPileUpSubtractor) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.vtxs, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range tsk.colls {
+		err = tsk.DeclInPort(pair.In, reflect.TypeOf([]Candidate{}))
+		if err != nil {
+			return err
+		}
+
+		err = tsk.DeclOutPort(pair.Out, reflect.TypeOf([]Candidate{}))
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (tsk *TrackPileUpSubtractor) StartTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *TrackPileUpSubtractor) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *TrackPileUpSubtractor) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.vtxs)
+	if err != nil {
+		return err
+	}
+	vtxs := v.([]Candidate)
+
+	zvtx := 0.0
+	for i := range vtxs {
+		vtx := &vtxs[i]
+		if vtx.IsPU == 0 {
+			zvtx = vtx.Pos.Z()
+			break
+		}
+	}
+
+	for _, pair := range tsk.colls {
+		v, err := store.Get(pair.In)
+		if err != nil {
+			return err
+		}
+		input := v.([]Candidate)
+
+		output := make([]Candidate, 0, len(input))
+		for i := range input {
+			c := input[i]
+			part := &c
+			if len(c.Candidates) > 0 {
+				part = &c.Candidates[0]
+			}
+			z := part.Pos.Z()
+
+			if c.CandCharge != 0 && c.IsPU != 0 && math.Abs(z-zvtx) > tsk.zres*1e3 {
+				continue
+			}
+
+			if c.Mom.Pt() > tsk.ptMin {
+				c.IsRecoPU = 0
+				output = append(output, c)
+			}
+		}
+
+		msg.Debugf(">>> %s: %d -> %d\n", pair.Out, len(input), len(output))
+
+		err = store.Put(pair.Out, output)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func newTrackPileUpSubtractor(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &TrackPileUpSubtractor{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+		vtxs:     "Vertices",
+		colls:    make([]ObjPair, 0),
+		zres:     1e-4,
+		ptMin:    0,
+	}
+
+	err = tsk.DeclProp("Vertices", &tsk.vtxs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Keys", &tsk.colls)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("ZVertexResolution", &tsk.zres)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PtMin", &tsk.ptMin)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(TrackPileUpSubtractor{}), newTrackPileUpSubtractor)
+}
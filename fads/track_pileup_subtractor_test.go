@@ -0,0 +1,86 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"testing"
+)
+
+func TestTrackPileUpSubtractor(t *testing.T) {
+	track := func(z float64, charge int32, pu byte, pt float64) Candidate {
+		c := newTrack(pt, 0, 0)
+		c.CandCharge = charge
+		c.IsPU = pu
+		c.Pos = newPos(0, 0, z)
+		return c
+	}
+
+	for _, tc := range []struct {
+		name string
+		cand Candidate
+		kept bool
+	}{
+		{
+			name: "pu-close",
+			cand: track(1.05, 1, 1, 10),
+			kept: true,
+		},
+		{
+			name: "pu-far",
+			cand: track(3, 1, 1, 10),
+		},
+		{
+			name: "pu-neutral",
+			cand: track(3, 0, 1, 10),
+			kept: true,
+		},
+		{
+			name: "hard-far",
+			cand: track(3, 1, 0, 10),
+			kept: true,
+		},
+		{
+			name: "pu-far-mother",
+			cand: func() Candidate {
+				c := track(1, 1, 1, 10)
+				c.Add(&Candidate{Pos: newPos(0, 0, 3)})
+				return c
+			}(),
+		},
+		{
+			name: "low-pt",
+			cand: track(1, 1, 0, 0.5),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := &TrackPileUpSubtractor{
+				vtxs:  "vtxs",
+				colls: []ObjPair{{In: "input", Out: "output"}},
+				zres:  1e-4,
+				ptMin: 1,
+			}
+
+			ctx := newTestContext()
+			ctx.store["vtxs"] = []Candidate{
+				{IsPU: 1, Pos: newPos(0, 0, 5)},
+				{IsPU: 0, Pos: newPos(0, 0, 1)},
+			}
+			ctx.store["input"] = []Candidate{tc.cand}
+
+			err := tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			output := ctx.store["output"].([]Candidate)
+			if got, want := len(output) == 1, tc.kept; got != want {
+				t.Fatalf("invalid selection: got=%v, want=%v", got, want)
+			}
+			if tc.kept && output[0].IsRecoPU != 0 {
+				t.Fatalf("invalid output IsRecoPU: got=%d, want=0", output[0].IsRecoPU)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// TrackSmearing smears the helix parameters of tracks: the transverse and
+// longitudinal impact parameters, the momentum, the cotangent of the polar
+// angle and the azimuthal angle.
+//
+// The resolutions are functions of the transverse momentum and of the
+// pseudo-rapidity of the particle before any smearing. The momentum
+// resolution is relative, the other ones are absolute.
+// Tracks from pile-up interactions are only smeared when ApplyToPileUp is true.
+type TrackSmearing struct {
+	fwk.TaskBase
+
+	input  string
+	output string
+
+	d0res  func(pt, eta float64) float64
+	dzres  func(pt, eta float64) float64
+	pres   func(pt, eta float64) float64
+	ctgres func(pt, eta float64) float64
+	phires func(pt, eta float64) float64
+
+	applyToPU bool
+
+	rnd randsrc
+}
+
+func (tsk *TrackSmearing) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *TrackSmearing) StartTask(ctx fwk.Context) error {
+	var err error
+	err = tsk.rnd.start(ctx)
+	return err
+}
+
+func (tsk *TrackSmearing) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *TrackSmearing) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	input := v.([]Candidate)
+	msg.Debugf(">>> input: %v\n", len(input))
+
+	output := make([]Candidate, 0, len(input))
+	defer func() {
+		err = store.Put(tsk.output, output)
+	}()
+
+	src, release, err := tsk.rnd.source(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	gauss := func(mean, sigma float64) float64 {
+		if sigma <= 0 {
+			return mean
+		}
+		return distuv.Normal{Mu: mean, Sigma: sigma, Src: src}.Rand()
+	}
+
+	for i := range input {
+		cand := &input[i]
+		if cand.IsPU != 0 && !tsk.applyToPU {
+			output = append(output, *cand)
+			continue
+		}
+
+		// take the momentum before any smearing was applied,
+		// otherwise the impact parameters would be smeared twice.
+		part := cand
+		if len(cand.Candidates) > 0 {
+			part = &cand.Candidates[0]
+		}
+		pt := part.Mom.Pt()
+		eta := part.Mom.Eta()
+
+		var (
+			d0Err  = tsk.d0res(pt, eta)
+			dzErr  = tsk.dzres(pt, eta)
+			pErr   = tsk.pres(pt, eta)
+			ctgErr = tsk.ctgres(pt, eta)
+			phiErr = tsk.phires(pt, eta)
+
+			p   = cand.Mom.P()
+			ctg = cand.Mom.Pz() / cand.Mom.Pt()
+			phi = cand.Mom.Phi()
+			m   = cand.Mom.M()
+		)
+
+		d0 := gauss(cand.D0, d0Err)
+		dz := gauss(cand.DZ, dzErr)
+		p = gauss(p, pErr*p)
+		ctg = gauss(ctg, ctgErr)
+		phi = gauss(phi, phiErr)
+		if p <= 0 {
+			continue
+		}
+
+		theta := math.Acos(ctg / math.Sqrt(1+ctg*ctg))
+
+		mother := cand
+		c := cand.Clone()
+		c.Mom = newPtEtaPhiE(p*math.Sin(theta), -math.Log(math.Tan(0.5*theta)), phi, math.Sqrt(p*p+m*m))
+		c.D0 = d0
+		c.DZ = dz
+		c.Xd = d0 * math.Sin(phi)
+		c.Yd = -d0 * math.Cos(phi)
+		c.Zd = dz
+		c.ErrD0 = d0Err
+		c.ErrDZ = dzErr
+		c.TrackResolution = pErr
+		c.Add(mother)
+
+		output = append(output, *c)
+	}
+
+	msg.Debugf(">>> smeared: %v\n", len(output))
+
+	return err
+}
+
+func newTrackSmearing(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	zero := func(pt, eta float64) float64 { return 0 }
+	tsk := &TrackSmearing{
+		TaskBase:  fwk.NewTask(typ, name, mgr),
+		input:     "InputTracks",
+		output:    "OutputTracks",
+		d0res:     zero,
+		dzres:     zero,
+		pres:      zero,
+		ctgres:    zero,
+		phires:    zero,
+		applyToPU: true,
+		rnd:       newRandSrc(1234),
+	}
+
+	err = tsk.DeclProp("Input", &tsk.input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Output", &tsk.output)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("D0Resolution", &tsk.d0res)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("DZResolution", &tsk.dzres)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PResolution", &tsk.pres)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("CtgThetaResolution", &tsk.ctgres)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("PhiResolution", &tsk.phires)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("ApplyToPileUp", &tsk.applyToPU)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Seed", &tsk.rnd.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("RandSvc", &tsk.rnd.rsvc)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(TrackSmearing{}), newTrackSmearing)
+}
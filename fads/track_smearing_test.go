@@ -0,0 +1,134 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrackSmearing(t *testing.T) {
+	const (
+		pt  = 10.0
+		eta = 0.5
+		phi = 1.0
+		d0  = 0.1
+		dz  = 2.0
+	)
+
+	track := func(pu byte) Candidate {
+		c := newTrack(pt, eta, phi)
+		c.D0 = d0
+		c.DZ = dz
+		c.IsPU = pu
+		return c
+	}
+
+	for _, tc := range []struct {
+		name      string
+		cand      Candidate
+		sigma     float64
+		applyToPU bool
+		smeared   bool
+	}{
+		{
+			name:      "no-smearing",
+			cand:      track(0),
+			applyToPU: true,
+		},
+		{
+			name:      "smeared",
+			cand:      track(0),
+			sigma:     0.01,
+			applyToPU: true,
+			smeared:   true,
+		},
+		{
+			name:      "pile-up",
+			cand:      track(1),
+			sigma:     0.01,
+			applyToPU: true,
+			smeared:   true,
+		},
+		{
+			name:  "pile-up-untouched",
+			cand:  track(1),
+			sigma: 0.01,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res := func(pt, eta float64) float64 { return tc.sigma }
+			run := func() Candidate {
+				tsk := &TrackSmearing{
+					input:     "input",
+					output:    "output",
+					d0res:     res,
+					dzres:     res,
+					pres:      res,
+					ctgres:    res,
+					phires:    res,
+					applyToPU: tc.applyToPU,
+					rnd:       newRandSrc(1234),
+				}
+
+				ctx := newTestContext()
+				ctx.store["input"] = []Candidate{tc.cand}
+
+				err := tsk.StartTask(ctx)
+				if err != nil {
+					t.Fatalf("could not start task: %+v", err)
+				}
+
+				err = tsk.Process(ctx)
+				if err != nil {
+					t.Fatalf("could not process event: %+v", err)
+				}
+
+				output := ctx.store["output"].([]Candidate)
+				if got, want := len(output), 1; got != want {
+					t.Fatalf("invalid number of tracks: got=%d, want=%d", got, want)
+				}
+				return output[0]
+			}
+
+			got := run()
+			if got, want := got.D0 != d0, tc.smeared; got != want {
+				t.Fatalf("invalid D0 smearing: got=%v, want=%v", got, want)
+			}
+			if got, want := got.Mom.Pt() != tc.cand.Mom.Pt(), tc.smeared; got != want {
+				t.Fatalf("invalid momentum smearing: got=%v, want=%v", got, want)
+			}
+			if tc.cand.IsPU != 0 && !tc.applyToPU {
+				return
+			}
+
+			if got, want := len(got.Candidates), 1; got != want {
+				t.Fatalf("invalid number of mothers: got=%d, want=%d", got, want)
+			}
+			if got, want := got.ErrD0, tc.sigma; got != want {
+				t.Fatalf("invalid D0 error: got=%v, want=%v", got, want)
+			}
+			if got, want := got.Zd, got.DZ; got != want {
+				t.Fatalf("invalid Zd: got=%v, want=%v", got, want)
+			}
+			if got, want := math.Hypot(got.Xd, got.Yd), math.Abs(got.D0); math.Abs(got-want) > 1e-12 {
+				t.Fatalf("invalid transverse position: got=%v, want=%v", got, want)
+			}
+
+			if !tc.smeared {
+				const eps = 1e-9
+				if math.Abs(got.Mom.Pt()-pt) > eps || math.Abs(got.Mom.Eta()-eta) > eps || math.Abs(got.Mom.Phi()-phi) > eps {
+					t.Fatalf("invalid momentum: got=(%v, %v, %v), want=(%v, %v, %v)",
+						got.Mom.Pt(), got.Mom.Eta(), got.Mom.Phi(), pt, eta, phi,
+					)
+				}
+			}
+
+			if again := run(); again.Mom != got.Mom || again.D0 != got.D0 {
+				t.Fatalf("smearing is not reproducible")
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// TreeBranch describes a branch of the tree written by TreeWriter.
+type TreeBranch struct {
+	Input string // name of the input collection
+	Name  string // name of the branch (e.g. "Jet")
+	Class string // Delphes class of the branch
+}
+
+// TreeWriter is a flat ntuple writer for fads collections.
+//
+// Each branch is written as flat variable-length arrays: a "<Name>_size"
+// branch holding the number of elements of the collection and one
+// "<Name>.<Leaf>" array per data member of the Delphes class.
+// Branch and leaf names follow those of a split Delphes TClonesArray, so
+// TTree::Draw and TTreeReader expressions such as "Jet.PT" work.
+// The layout is not Delphes-compatible, though: the tree holds neither
+// TClonesArrays nor Delphes objects, so macros based on ExRootTreeReader
+// or on the Delphes classes can not read it.
+//
+// Supported classes are "Jet", "Electron", "Muon" and "Photon", whose
+// inputs are []Candidate collections, and "MissingET" and "ScalarHT",
+// whose inputs are the Candidate values produced by the Merger task
+// (respectively its MomentumOutput and EnergyOutput).
+type TreeWriter struct {
+	fwk.TaskBase
+
+	fname    string
+	tname    string
+	branches []TreeBranch
+
+	mu    sync.Mutex
+	f     *riofs.File
+	tree  rtree.Writer
+	bufs  []*treeBuffer
+	nevts int64
+}
+
+// treeLeaf describes a data member of a Delphes class.
+type treeLeaf struct {
+	name string
+	kind reflect.Kind // reflect.Float32, reflect.Int32 or reflect.Uint32
+	get  func(c *Candidate) float64
+}
+
+// treeClass describes a Delphes class.
+type treeClass struct {
+	slice  bool // whether the input is a []Candidate or a Candidate
+	leaves []treeLeaf
+}
+
+var treeClasses = map[string]treeClass{
+	"Jet": {
+		slice: true,
+		leaves: []treeLeaf{
+			{"PT", reflect.Float32, candPt},
+			{"Eta", reflect.Float32, candEta},
+			{"Phi", reflect.Float32, candPhi},
+			{"T", reflect.Float32, candT},
+			{"Mass", reflect.Float32, func(c *Candidate) float64 { return c.Mom.M() }},
+			{"DeltaEta", reflect.Float32, func(c *Candidate) float64 { return c.DEta }},
+			{"DeltaPhi", reflect.Float32, func(c *Candidate) float64 { return c.DPhi }},
+			{"Flavor", reflect.Int32, func(c *Candidate) float64 { return float64(c.Flavour) }},
+			{"FlavorAlgo", reflect.Int32, func(c *Candidate) float64 { return float64(c.FlavourAlgo) }},
+			{"FlavorPhys", reflect.Int32, func(c *Candidate) float64 { return float64(c.FlavourPhys) }},
+			{"BTag", reflect.Uint32, func(c *Candidate) float64 { return float64(c.BTag) }},
+			{"TauTag", reflect.Uint32, func(c *Candidate) float64 { return float64(c.TauTag) }},
+			{"Charge", reflect.Int32, jetCharge},
+			{"EhadOverEem", reflect.Float32, jetEhadOverEem},
+			{"NCharged", reflect.Int32, func(c *Candidate) float64 { return float64(jetNCharged(c)) }},
+			{"NNeutrals", reflect.Int32, func(c *Candidate) float64 { return float64(len(c.Candidates) - jetNCharged(c)) }},
+		},
+	},
+	"Electron": {
+		slice: true,
+		leaves: []treeLeaf{
+			{"PT", reflect.Float32, candPt},
+			{"Eta", reflect.Float32, candEta},
+			{"Phi", reflect.Float32, candPhi},
+			{"T", reflect.Float32, candT},
+			{"Charge", reflect.Int32, func(c *Candidate) float64 { return float64(c.CandCharge) }},
+			{"EhadOverEem", reflect.Float32, candEhadOverEem},
+		},
+	},
+	"Muon": {
+		slice: true,
+		leaves: []treeLeaf{
+			{"PT", reflect.Float32, candPt},
+			{"Eta", reflect.Float32, candEta},
+			{"Phi", reflect.Float32, candPhi},
+			{"T", reflect.Float32, candT},
+			{"Charge", reflect.Int32, func(c *Candidate) float64 { return float64(c.CandCharge) }},
+		},
+	},
+	"Photon": {
+		slice: true,
+		leaves: []treeLeaf{
+			{"PT", reflect.Float32, candPt},
+			{"Eta", reflect.Float32, candEta},
+			{"Phi", reflect.Float32, candPhi},
+			{"E", reflect.Float32, func(c *Candidate) float64 { return c.Mom.E() }},
+			{"T", reflect.Float32, candT},
+			{"EhadOverEem", reflect.Float32, candEhadOverEem},
+		},
+	},
+	"MissingET": {
+		leaves: []treeLeaf{
+			{"MET", reflect.Float32, candPt},
+			{"Eta", reflect.Float32, func(c *Candidate) float64 { mom := missingEt(c); return mom.Eta() }},
+			{"Phi", reflect.Float32, func(c *Candidate) float64 { mom := missingEt(c); return mom.Phi() }},
+		},
+	},
+	"ScalarHT": {
+		leaves: []treeLeaf{
+			{"HT", reflect.Float32, candPt},
+		},
+	},
+}
+
+func candPt(c *Candidate) float64  { return c.Mom.Pt() }
+func candEta(c *Candidate) float64 { return c.Mom.Eta() }
+func candPhi(c *Candidate) float64 { return c.Mom.Phi() }
+
+// candT returns the time of the candidate, in seconds.
+func candT(c *Candidate) float64 {
+	const cLight = 2.99792458e8
+	return c.Pos.T() * 1e-3 / cLight
+}
+
+func candEhadOverEem(c *Candidate) float64 {
+	if c.Eem <= 0 {
+		return 999.9
+	}
+	return c.Ehad / c.Eem
+}
+
+func missingEt(c *Candidate) fmom.PxPyPzE {
+	return fmom.NewPxPyPzE(-c.Mom.Px(), -c.Mom.Py(), -c.Mom.Pz(), c.Mom.E())
+}
+
+func jetCharge(c *Candidate) float64 {
+	charge := int32(0)
+	for i := range c.Candidates {
+		charge += c.Candidates[i].CandCharge
+	}
+	return float64(charge)
+}
+
+func jetNCharged(c *Candidate) int {
+	n := 0
+	for i := range c.Candidates {
+		if c.Candidates[i].CandCharge != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func jetEhadOverEem(c *Candidate) float64 {
+	eem := 0.0
+	ehad := 0.0
+	for i := range c.Candidates {
+		eem += c.Candidates[i].Eem
+		ehad += c.Candidates[i].Ehad
+	}
+	if eem <= 0 {
+		return 999.9
+	}
+	return ehad / eem
+}
+
+// treeBuffer holds the data of a branch for the current event.
+type treeBuffer struct {
+	branch TreeBranch
+	class  treeClass
+
+	n   int32
+	f32 [][]float32
+	i32 [][]int32
+	u32 [][]uint32
+}
+
+func newTreeBuffer(branch TreeBranch, class treeClass) *treeBuffer {
+	buf := &treeBuffer{
+		branch: branch,
+		class:  class,
+	}
+	for _, leaf := range class.leaves {
+		switch leaf.kind {
+		case reflect.Float32:
+			buf.f32 = append(buf.f32, nil)
+		case reflect.Int32:
+			buf.i32 = append(buf.i32, nil)
+		case reflect.Uint32:
+			buf.u32 = append(buf.u32, nil)
+		}
+	}
+	return buf
+}
+
+func (buf *treeBuffer) wvars() []rtree.WriteVar {
+	var (
+		size  = buf.branch.Name + "_size"
+		wvars = make([]rtree.WriteVar, 0, len(buf.class.leaves)+1)
+		f32   = 0
+		i32   = 0
+		u32   = 0
+	)
+	wvars = append(wvars, rtree.WriteVar{Name: size, Value: &buf.n})
+	for _, leaf := range buf.class.leaves {
+		wvar := rtree.WriteVar{Name: buf.branch.Name + "." + leaf.name, Count: size}
+		switch leaf.kind {
+		case reflect.Float32:
+			wvar.Value = &buf.f32[f32]
+			f32++
+		case reflect.Int32:
+			wvar.Value = &buf.i32[i32]
+			i32++
+		case reflect.Uint32:
+			wvar.Value = &buf.u32[u32]
+			u32++
+		}
+		wvars = append(wvars, wvar)
+	}
+	return wvars
+}
+
+func (buf *treeBuffer) fill(cands []Candidate) {
+	buf.n = int32(len(cands))
+	for i := range buf.f32 {
+		buf.f32[i] = buf.f32[i][:0]
+	}
+	for i := range buf.i32 {
+		buf.i32[i] = buf.i32[i][:0]
+	}
+	for i := range buf.u32 {
+		buf.u32[i] = buf.u32[i][:0]
+	}
+
+	for i := range cands {
+		c := &cands[i]
+		f32 := 0
+		i32 := 0
+		u32 := 0
+		for _, leaf := range buf.class.leaves {
+			v := leaf.get(c)
+			switch leaf.kind {
+			case reflect.Float32:
+				buf.f32[f32] = append(buf.f32[f32], float32(v))
+				f32++
+			case reflect.Int32:
+				buf.i32[i32] = append(buf.i32[i32], int32(v))
+				i32++
+			case reflect.Uint32:
+				buf.u32[u32] = append(buf.u32[u32], uint32(v))
+				u32++
+			}
+		}
+	}
+}
+
+func (tsk *TreeWriter) Configure(ctx fwk.Context) error {
+	var err error
+
+	tsk.bufs = make([]*treeBuffer, 0, len(tsk.branches))
+	for _, branch := range tsk.branches {
+		class, ok := treeClasses[branch.Class]
+		if !ok {
+			return fmt.Errorf("fads: tree-writer [%s]: unknown class %q for branch %q",
+				tsk.Name(), branch.Class, branch.Name,
+			)
+		}
+
+		typ := reflect.TypeOf(Candidate{})
+		if class.slice {
+			typ = reflect.TypeOf([]Candidate{})
+		}
+		err = tsk.DeclInPort(branch.Input, typ)
+		if err != nil {
+			return err
+		}
+
+		tsk.bufs = append(tsk.bufs, newTreeBuffer(branch, class))
+	}
+
+	return err
+}
+
+func (tsk *TreeWriter) StartTask(ctx fwk.Context) error {
+	var err error
+
+	tsk.f, err = groot.Create(tsk.fname)
+	if err != nil {
+		return fmt.Errorf("fads: could not create output ROOT file %q: %w", tsk.fname, err)
+	}
+
+	var wvars []rtree.WriteVar
+	for _, buf := range tsk.bufs {
+		wvars = append(wvars, buf.wvars()...)
+	}
+
+	tsk.tree, err = rtree.NewWriter(tsk.f, tsk.tname, wvars, rtree.WithTitle("Analysis tree"))
+	if err != nil {
+		return fmt.Errorf("fads: could not create output ROOT tree %q: %w", tsk.tname, err)
+	}
+
+	return err
+}
+
+func (tsk *TreeWriter) StopTask(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.tree.Close()
+	if err != nil {
+		return fmt.Errorf("fads: could not close output ROOT tree %q: %w", tsk.tname, err)
+	}
+
+	err = tsk.f.Close()
+	if err != nil {
+		return fmt.Errorf("fads: could not close output ROOT file %q: %w", tsk.fname, err)
+	}
+
+	ctx.Msg().Infof("wrote %d events to %s\n", tsk.nevts, tsk.fname)
+
+	return err
+}
+
+func (tsk *TreeWriter) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+
+	inputs := make([][]Candidate, len(tsk.bufs))
+	for i, buf := range tsk.bufs {
+		v, err := store.Get(buf.branch.Input)
+		if err != nil {
+			return err
+		}
+		switch v := v.(type) {
+		case []Candidate:
+			inputs[i] = v
+		case Candidate:
+			inputs[i] = []Candidate{v}
+		}
+	}
+
+	tsk.mu.Lock()
+	defer tsk.mu.Unlock()
+
+	for i, buf := range tsk.bufs {
+		buf.fill(inputs[i])
+	}
+
+	_, err = tsk.tree.Write()
+	if err != nil {
+		return fmt.Errorf("fads: could not write event to ROOT tree: %w", err)
+	}
+	tsk.nevts++
+
+	return err
+}
+
+func newTreeWriter(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &TreeWriter{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+		fname:    "delphes.root",
+		tname:    "Delphes",
+		branches: make([]TreeBranch, 0),
+	}
+
+	err = tsk.DeclProp("Output", &tsk.fname)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Tree", &tsk.tname)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Branches", &tsk.branches)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(TreeWriter{}), newTreeWriter)
+}
@@ -0,0 +1,144 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+func TestTreeWriterJets(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "jets.root")
+
+	branch := TreeBranch{Input: "jets", Name: "Jet", Class: "Jet"}
+	tsk := &TreeWriter{
+		fname: fname,
+		tname: "Delphes",
+		bufs:  []*treeBuffer{newTreeBuffer(branch, treeClasses["Jet"])},
+	}
+
+	jets := []Candidate{
+		newTrack(50, 0, 0),
+		newTrack(30, 1, 2),
+	}
+	jets[0].Flavour = 5
+	jets[0].FlavourAlgo = -5
+	jets[0].FlavourPhys = 5
+	jets[0].BTag = 0x5
+	jets[1].Flavour = 21
+	jets[1].FlavourAlgo = -4
+	jets[1].FlavourPhys = 0
+
+	ctx := newTestContext()
+	ctx.store["jets"] = jets
+
+	err := tsk.StartTask(ctx)
+	if err != nil {
+		t.Fatalf("could not start task: %+v", err)
+	}
+	err = tsk.Process(ctx)
+	if err != nil {
+		t.Fatalf("could not process event: %+v", err)
+	}
+	err = tsk.StopTask(ctx)
+	if err != nil {
+		t.Fatalf("could not stop task: %+v", err)
+	}
+
+	f, err := groot.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open output file: %+v", err)
+	}
+	defer f.Close()
+
+	o, err := riofs.Dir(f).Get("Delphes")
+	if err != nil {
+		t.Fatalf("could not retrieve output tree: %+v", err)
+	}
+
+	var (
+		flav []int32
+		algo []int32
+		phys []int32
+		btag []uint32
+	)
+	r, err := rtree.NewReader(o.(rtree.Tree), []rtree.ReadVar{
+		{Name: "Jet.Flavor", Value: &flav},
+		{Name: "Jet.FlavorAlgo", Value: &algo},
+		{Name: "Jet.FlavorPhys", Value: &phys},
+		{Name: "Jet.BTag", Value: &btag},
+	})
+	if err != nil {
+		t.Fatalf("could not create tree reader: %+v", err)
+	}
+	defer r.Close()
+
+	err = r.Read(func(ctx rtree.RCtx) error {
+		for _, v := range []struct {
+			name      string
+			got, want any
+		}{
+			{"Flavor", flav, []int32{5, 21}},
+			{"FlavorAlgo", algo, []int32{-5, -4}},
+			{"FlavorPhys", phys, []int32{5, 0}},
+			{"BTag", btag, []uint32{0x5, 0}},
+		} {
+			if !reflect.DeepEqual(v.got, v.want) {
+				t.Fatalf("invalid Jet.%s: got=%v, want=%v", v.name, v.got, v.want)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not read tree: %+v", err)
+	}
+}
+
+func TestEhadOverEem(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cand Candidate
+		want float64
+	}{
+		{
+			name: "no-energy",
+			cand: Candidate{},
+			want: 999.9,
+		},
+		{
+			name: "no-eem",
+			cand: Candidate{Ehad: 10},
+			want: 999.9,
+		},
+		{
+			name: "no-ehad",
+			cand: Candidate{Eem: 10},
+			want: 0,
+		},
+		{
+			name: "both",
+			cand: Candidate{Eem: 4, Ehad: 1},
+			want: 0.25,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, want := candEhadOverEem(&tc.cand), tc.want; got != want {
+				t.Fatalf("invalid cand Ehad/Eem: got=%v, want=%v", got, want)
+			}
+
+			// a jet made of the candidate has the same ratio.
+			jet := Candidate{Eem: 1, Ehad: 1}
+			jet.Add(&tc.cand)
+			if got, want := jetEhadOverEem(&jet), tc.want; got != want {
+				t.Fatalf("invalid jet Ehad/Eem: got=%v, want=%v", got, want)
+			}
+		})
+	}
+}
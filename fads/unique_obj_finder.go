@@ -88,14 +88,15 @@ func (tsk *UniqueObjectFinder) Process(ctx fwk.Context) error {
 		output := pair.Out
 		for i := range input {
 			cand := &input[i]
-			unique := false
+			// earlier collections take precedence over later ones.
+			unique := true
 		uniqueloop:
 			for jcol := range icol {
-				jcands := colls[jcol].In
+				jcands := colls[jcol].Out
 				for j := range jcands {
 					jcand := &jcands[j]
 					if cand.Overlaps(jcand) {
-						unique = true
+						unique = false
 						break uniqueloop
 					}
 				}
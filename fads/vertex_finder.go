@@ -0,0 +1,359 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"reflect"
+	"sort"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/fwk"
+)
+
+// VertexFinder clusters tracks into vertices along the beam axis.
+//
+// Clusters are seeded by high transverse momentum tracks and are grown
+// by adding all the tracks compatible, within Sigma standard deviations,
+// with the current cluster position.
+// Clusters with less than MinNDF tracks are discarded.
+//
+// As in Delphes, compatible clusters are then merged and each track is
+// reassigned to the closest cluster it is compatible with.
+// Clusters left with less than MinNDF tracks are discarded.
+// Vertices are sorted by decreasing sum of squared transverse momenta.
+type VertexFinder struct {
+	fwk.TaskBase
+
+	input  string
+	output string
+	vtxs   string
+
+	sigma     float64
+	ptMin     float64
+	etaMax    float64
+	seedPtMin float64
+	ndfMin    int
+	grow      bool
+}
+
+func (tsk *VertexFinder) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.vtxs, reflect.TypeOf([]Candidate{}))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *VertexFinder) StartTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (tsk *VertexFinder) StopTask(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+type vtxCluster struct {
+	trks   []int
+	z      float64
+	ez     float64
+	sumw   float64
+	sumwz  float64
+	sumpt2 float64
+}
+
+func (c *vtxCluster) add(i int, z, ez, pt float64) {
+	w := 1 / (ez * ez)
+	c.trks = append(c.trks, i)
+	c.sumw += w
+	c.sumwz += w * z
+	c.sumpt2 += pt * pt
+	c.z = c.sumwz / c.sumw
+	c.ez = 1 / math.Sqrt(c.sumw)
+}
+
+func (tsk *VertexFinder) Process(ctx fwk.Context) error {
+	var err error
+	store := ctx.Store()
+	msg := ctx.Msg()
+
+	v, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	input := v.([]Candidate)
+	msg.Debugf(">>> input: %v\n", len(input))
+
+	output := make([]Candidate, 0, len(input))
+	vtxs := make([]Candidate, 0)
+	defer func() {
+		err = store.Put(tsk.output, output)
+		if err != nil {
+			return
+		}
+		err = store.Put(tsk.vtxs, vtxs)
+	}()
+
+	ez := func(c *Candidate) float64 {
+		// tracks without a longitudinal impact parameter error
+		// are given a unit weight.
+		if c.ErrDZ <= 0 {
+			return 1
+		}
+		return c.ErrDZ
+	}
+
+	// select tracks
+	clusters := make([]int, len(input))
+	trks := make([]int, 0, len(input))
+	for i := range input {
+		clusters[i] = -1
+		c := &input[i]
+		if c.Mom.Pt() < tsk.ptMin || math.Abs(c.Mom.Eta()) > tsk.etaMax {
+			continue
+		}
+		trks = append(trks, i)
+	}
+
+	// create seeds
+	seeds := make([]int, 0, len(trks))
+	for _, i := range trks {
+		if input[i].Mom.Pt() < tsk.seedPtMin {
+			continue
+		}
+		seeds = append(seeds, i)
+	}
+	sort.SliceStable(seeds, func(i, j int) bool {
+		return input[seeds[i]].Mom.Pt() > input[seeds[j]].Mom.Pt()
+	})
+
+	// grow clusters
+	vclusters := make([]vtxCluster, 0, len(seeds))
+	for _, seed := range seeds {
+		if clusters[seed] >= 0 {
+			continue
+		}
+		id := len(vclusters)
+		var vc vtxCluster
+		s := &input[seed]
+		vc.add(seed, s.DZ, ez(s), s.Mom.Pt())
+		clusters[seed] = id
+
+		for {
+			added := false
+			for _, i := range trks {
+				if clusters[i] >= 0 {
+					continue
+				}
+				c := &input[i]
+				ezc := ez(c)
+				if math.Abs(c.DZ-vc.z) >= tsk.sigma*math.Hypot(ezc, vc.ez) {
+					continue
+				}
+				vc.add(i, c.DZ, ezc, c.Mom.Pt())
+				clusters[i] = id
+				added = true
+			}
+			if !added || !tsk.grow {
+				break
+			}
+		}
+
+		if len(vc.trks) < tsk.ndfMin {
+			for _, i := range vc.trks {
+				clusters[i] = -1
+			}
+			continue
+		}
+		vclusters = append(vclusters, vc)
+	}
+
+	fit := func(trks []int) vtxCluster {
+		var vc vtxCluster
+		for _, i := range trks {
+			c := &input[i]
+			vc.add(i, c.DZ, ez(c), c.Mom.Pt())
+		}
+		return vc
+	}
+
+	// merge compatible clusters
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(vclusters) && !merged; i++ {
+			for j := i + 1; j < len(vclusters); j++ {
+				vi := &vclusters[i]
+				vj := &vclusters[j]
+				if math.Abs(vi.z-vj.z) >= tsk.sigma*math.Hypot(vi.ez, vj.ez) {
+					continue
+				}
+				trks := append(append([]int(nil), vi.trks...), vj.trks...)
+				vclusters[i] = fit(trks)
+				vclusters = append(vclusters[:j], vclusters[j+1:]...)
+				merged = true
+				break
+			}
+		}
+	}
+
+	// reassign tracks to their closest compatible cluster
+	assigned := make([][]int, len(vclusters))
+	for _, i := range trks {
+		c := &input[i]
+		ezc := ez(c)
+		best := -1
+		dmin := tsk.sigma
+		for j := range vclusters {
+			vc := &vclusters[j]
+			d := math.Abs(c.DZ-vc.z) / math.Hypot(ezc, vc.ez)
+			if d < dmin {
+				best = j
+				dmin = d
+			}
+		}
+		if best >= 0 {
+			assigned[best] = append(assigned[best], i)
+		}
+	}
+
+	vclusters = vclusters[:0]
+	for _, trks := range assigned {
+		if len(trks) == 0 || len(trks) < tsk.ndfMin {
+			continue
+		}
+		vclusters = append(vclusters, fit(trks))
+	}
+
+	for i := range clusters {
+		clusters[i] = -1
+	}
+
+	sort.SliceStable(vclusters, func(i, j int) bool {
+		return vclusters[i].sumpt2 > vclusters[j].sumpt2
+	})
+
+	for ivtx := range vclusters {
+		vc := &vclusters[ivtx]
+		pull2 := 0.0
+		for _, i := range vc.trks {
+			c := &input[i]
+			dz := (c.DZ - vc.z) / ez(c)
+			pull2 += dz * dz
+			clusters[i] = ivtx
+		}
+		ndf := int32(len(vc.trks))
+		vtxs = append(vtxs, Candidate{
+			Pos:          fmom.NewPxPyPzE(0, 0, vc.z, 0),
+			PosErr:       fmom.NewPxPyPzE(0, 0, vc.ez, 0),
+			ClusterIndex: int32(ivtx),
+			ClusterNDF:   ndf,
+			ClusterSigma: math.Sqrt(pull2 / float64(ndf)),
+			SumPt2:       vc.sumpt2,
+		})
+	}
+
+	for i := range input {
+		c := input[i]
+		c.ClusterIndex = int32(clusters[i])
+		if idx := clusters[i]; idx >= 0 {
+			vtx := &vtxs[idx]
+			c.ClusterNDF = vtx.ClusterNDF
+			c.ClusterSigma = vtx.ClusterSigma
+			c.SumPt2 = vtx.SumPt2
+		}
+		output = append(output, c)
+	}
+
+	msg.Debugf(">>> vertices: %v\n", len(vtxs))
+
+	return err
+}
+
+func newVertexFinder(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+
+	tsk := &VertexFinder{
+		TaskBase: fwk.NewTask(typ, name, mgr),
+		input:    "InputTracks",
+		output:   "OutputTracks",
+		vtxs:     "Vertices",
+
+		sigma:     3.0,
+		ptMin:     0.1,
+		etaMax:    10.0,
+		seedPtMin: 5.0,
+		ndfMin:    4,
+		grow:      true,
+	}
+
+	err = tsk.DeclProp("Input", &tsk.input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Output", &tsk.output)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("VertexOutput", &tsk.vtxs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("Sigma", &tsk.sigma)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("MinPt", &tsk.ptMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("MaxEta", &tsk.etaMax)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("SeedMinPt", &tsk.seedPtMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("MinNDF", &tsk.ndfMin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tsk.DeclProp("GrowSeeds", &tsk.grow)
+	if err != nil {
+		return nil, err
+	}
+
+	return tsk, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(VertexFinder{}), newVertexFinder)
+}
@@ -0,0 +1,186 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fads
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestVertexFinder(t *testing.T) {
+	track := func(pt, dz float64) Candidate {
+		c := newTrack(pt, 0, 0)
+		c.DZ = dz
+		c.ErrDZ = 0.01
+		return c
+	}
+
+	input := []Candidate{
+		track(10, 0),     // seed of vertex A
+		track(2, 0.005),  // vertex A
+		track(1, -0.005), // vertex A
+		track(6, 5),      // seed of vertex B
+		track(1, 5.01),   // vertex B
+		track(1, 20),     // not compatible with any seed
+		track(7, -10),    // lone seed
+		track(0.05, 0),   // below pt threshold
+	}
+
+	for _, tc := range []struct {
+		name     string
+		ndfMin   int
+		vtxs     []float64 // expected z position of vertices
+		clusters []int32   // expected cluster index of tracks
+	}{
+		{
+			name:     "ndf-1",
+			ndfMin:   1,
+			vtxs:     []float64{0, -10, 5},
+			clusters: []int32{0, 0, 0, 2, 2, -1, 1, -1},
+		},
+		{
+			name:     "ndf-2",
+			ndfMin:   2,
+			vtxs:     []float64{0, 5},
+			clusters: []int32{0, 0, 0, 1, 1, -1, -1, -1},
+		},
+		{
+			name:     "ndf-3",
+			ndfMin:   3,
+			vtxs:     []float64{0},
+			clusters: []int32{0, 0, 0, -1, -1, -1, -1, -1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := &VertexFinder{
+				input:     "input",
+				output:    "output",
+				vtxs:      "vtxs",
+				sigma:     3,
+				ptMin:     0.1,
+				etaMax:    2.5,
+				seedPtMin: 5,
+				ndfMin:    tc.ndfMin,
+				grow:      true,
+			}
+
+			ctx := newTestContext()
+			ctx.store["input"] = input
+
+			err := tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			vtxs := ctx.store["vtxs"].([]Candidate)
+			if got, want := len(vtxs), len(tc.vtxs); got != want {
+				t.Fatalf("invalid number of vertices: got=%d, want=%d", got, want)
+			}
+			for i, vtx := range vtxs {
+				if got, want := vtx.Pos.Z(), tc.vtxs[i]; math.Abs(got-want) > 0.01 {
+					t.Fatalf("vertex %d: invalid z: got=%v, want=%v", i, got, want)
+				}
+				if got, want := vtx.ClusterIndex, int32(i); got != want {
+					t.Fatalf("vertex %d: invalid cluster index: got=%d, want=%d", i, got, want)
+				}
+			}
+
+			output := ctx.store["output"].([]Candidate)
+			clusters := make([]int32, len(output))
+			for i, c := range output {
+				clusters[i] = c.ClusterIndex
+			}
+			if !reflect.DeepEqual(clusters, tc.clusters) {
+				t.Fatalf("invalid clusters:\ngot= %v\nwant=%v", clusters, tc.clusters)
+			}
+		})
+	}
+}
+
+func TestVertexFinderOverlaps(t *testing.T) {
+	track := func(pt, dz, edz float64) Candidate {
+		c := newTrack(pt, 0, 0)
+		c.DZ = dz
+		c.ErrDZ = edz
+		return c
+	}
+
+	for _, tc := range []struct {
+		name     string
+		input    []Candidate
+		grow     bool
+		vtxs     []float64 // expected z position of vertices
+		clusters []int32   // expected cluster index of tracks
+	}{
+		{
+			// the second seed is only compatible with the first cluster
+			// once that cluster has been pulled towards it.
+			name: "merge",
+			input: []Candidate{
+				track(10, 0, 0.01),
+				track(8, 0.05, 0.01),
+				track(1, 0.03, 0.005),
+			},
+			grow:     false,
+			vtxs:     []float64{0.17 / 6},
+			clusters: []int32{0, 0, 0},
+		},
+		{
+			// the last track is first picked up by the leading seed,
+			// but it is closer to the second one.
+			name: "reassign",
+			input: []Candidate{
+				track(10, 0, 0.01),
+				track(8, 0.1, 0.01),
+				track(1, 0.06, 0.02),
+			},
+			grow:     true,
+			vtxs:     []float64{0, 0.092},
+			clusters: []int32{0, 1, 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tsk := &VertexFinder{
+				input:     "input",
+				output:    "output",
+				vtxs:      "vtxs",
+				sigma:     3,
+				ptMin:     0.1,
+				etaMax:    2.5,
+				seedPtMin: 5,
+				ndfMin:    1,
+				grow:      tc.grow,
+			}
+
+			ctx := newTestContext()
+			ctx.store["input"] = tc.input
+
+			err := tsk.Process(ctx)
+			if err != nil {
+				t.Fatalf("could not process event: %+v", err)
+			}
+
+			vtxs := ctx.store["vtxs"].([]Candidate)
+			if got, want := len(vtxs), len(tc.vtxs); got != want {
+				t.Fatalf("invalid number of vertices: got=%d, want=%d", got, want)
+			}
+			for i, vtx := range vtxs {
+				if got, want := vtx.Pos.Z(), tc.vtxs[i]; math.Abs(got-want) > 1e-6 {
+					t.Fatalf("vertex %d: invalid z: got=%v, want=%v", i, got, want)
+				}
+			}
+
+			output := ctx.store["output"].([]Candidate)
+			clusters := make([]int32, len(output))
+			for i, c := range output {
+				clusters[i] = c.ClusterIndex
+			}
+			if !reflect.DeepEqual(clusters, tc.clusters) {
+				t.Fatalf("invalid clusters:\ngot= %v\nwant=%v", clusters, tc.clusters)
+			}
+		})
+	}
+}
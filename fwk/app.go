@@ -13,6 +13,8 @@ import (
 	"runtime"
 	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"go-hep.org/x/hep/fwk/fsm"
@@ -24,11 +26,29 @@ type appmgr struct {
 
 	props map[string]map[string]any
 	dflow *dflowsvc
+	flow  *ctrlflow
 	store *datastore
 	msg   msgstream
 
 	evtmax int64
 	nprocs int
+	nslots int            // number of in-flight events for the scheduler
+	card   map[string]int // number of instances of clonable tasks
+
+	policy   ErrorPolicy            // default error policy of tasks
+	policies map[string]ErrorPolicy // error policies of tasks
+	maxfail  int                    // maximum number of failed events. 0 if unlimited.
+	errs     *errstack
+
+	nworkers int   // number of worker processes. 0 if single-process.
+	evtrange int64 // number of consecutive events processed by a worker process
+	worker   int   // index of the current worker process. -1 if not a worker.
+
+	clones map[string][]Task      // clones of clonable tasks
+	locks  map[string]*sync.Mutex // locks of non thread-safe tasks
+	mons   []MonSvc               // monitoring services
+	incs   []incHandler           // incident handlers
+	isvc   IncidentSvc            // incident service
 
 	comps   map[string]Component
 	tsks    []Task
@@ -60,9 +80,20 @@ func NewApp() App {
 		),
 		evtmax: -1,
 		nprocs: -1,
-		comps:  make(map[string]Component),
-		tsks:   make([]Task, 0),
-		svcs:   make([]Svc, 0),
+		nslots: 0,
+		card:   make(map[string]int),
+
+		policy:   Abort,
+		policies: make(map[string]ErrorPolicy),
+		maxfail:  0,
+
+		nworkers: 0,
+		evtrange: 1,
+		worker:   -1,
+
+		comps: make(map[string]Component),
+		tsks:  make([]Task, 0),
+		svcs:  make([]Svc, 0),
 	}
 
 	svc, err := app.New("go-hep.org/x/hep/fwk.datastore", "evtstore")
@@ -103,6 +134,48 @@ func NewApp() App {
 		return nil
 	}
 
+	err = app.DeclProp(app, "EvtSlots", &app.nslots)
+	if err != nil {
+		app.msg.Errorf("fwk.NewApp: could not declare property 'EvtSlots': %w\n", err)
+		return nil
+	}
+
+	err = app.DeclProp(app, "Cardinality", &app.card)
+	if err != nil {
+		app.msg.Errorf("fwk.NewApp: could not declare property 'Cardinality': %w\n", err)
+		return nil
+	}
+
+	err = app.DeclProp(app, "ErrorPolicy", &app.policy)
+	if err != nil {
+		app.msg.Errorf("fwk.NewApp: could not declare property 'ErrorPolicy': %w\n", err)
+		return nil
+	}
+
+	err = app.DeclProp(app, "ErrorPolicies", &app.policies)
+	if err != nil {
+		app.msg.Errorf("fwk.NewApp: could not declare property 'ErrorPolicies': %w\n", err)
+		return nil
+	}
+
+	err = app.DeclProp(app, "MaxFailures", &app.maxfail)
+	if err != nil {
+		app.msg.Errorf("fwk.NewApp: could not declare property 'MaxFailures': %w\n", err)
+		return nil
+	}
+
+	err = app.DeclProp(app, "NWorkers", &app.nworkers)
+	if err != nil {
+		app.msg.Errorf("fwk.NewApp: could not declare property 'NWorkers': %w\n", err)
+		return nil
+	}
+
+	err = app.DeclProp(app, "EvtRange", &app.evtrange)
+	if err != nil {
+		app.msg.Errorf("fwk.NewApp: could not declare property 'EvtRange': %w\n", err)
+		return nil
+	}
+
 	err = app.DeclProp(app, "MsgLevel", &app.msg.lvl)
 	if err != nil {
 		app.msg.Errorf("fwk.NewApp: could not declare property 'MsgLevel': %w\n", err)
@@ -362,6 +435,13 @@ func (app *appmgr) Run() error {
 		}
 	}
 
+	if app.state == fsm.Configured && app.nworkers > 0 && app.worker < 0 {
+		err = app.runWorkers(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	if app.state == fsm.Configured {
 		err = app.start(ctx)
 		if err != nil {
@@ -428,6 +508,18 @@ func (app *appmgr) configure(ctx Context) error {
 		app.nprocs = runtime.NumCPU()
 	}
 
+	if app.evtrange <= 0 {
+		return fmt.Errorf("fwk: invalid EvtRange (%d)", app.evtrange)
+	}
+
+	app.worker = -1
+	if i, ok := Worker(); ok && app.nworkers > 0 {
+		if i >= app.nworkers {
+			return fmt.Errorf("fwk: invalid worker index %d (NWorkers=%d)", i, app.nworkers)
+		}
+		app.worker = i
+	}
+
 	tsks := make([]ctxType, len(app.tsks))
 	for j, tsk := range app.tsks {
 		tsks[j] = ctxType{
@@ -490,12 +582,63 @@ func (app *appmgr) start(ctx Context) error {
 	var err error
 	defer app.msg.flush()
 	app.state = fsm.Starting
+	app.mons = nil
+	app.isvc = nil
 	for i, svc := range app.svcs {
 		app.msg.Debugf("starting [%s]...\n", svc.Name())
 		err = svc.StartSvc(app.ctxs[1][i])
 		if err != nil {
 			return err
 		}
+		if mon, ok := svc.(MonSvc); ok {
+			app.mons = append(app.mons, mon)
+		}
+		if isvc, ok := svc.(IncidentSvc); ok && app.isvc == nil {
+			app.isvc = isvc
+		}
+	}
+
+	app.flow, err = newCtrlFlow(app)
+	if err != nil {
+		return err
+	}
+	app.errs = newErrStack(app.maxfail)
+
+	app.clones = make(map[string][]Task)
+	app.locks = make(map[string]*sync.Mutex)
+	for i, tsk := range app.tsks {
+		switch concurrency(tsk) {
+		case ThreadSafe:
+			continue
+		case Clonable:
+			if app.nslots <= 0 {
+				break
+			}
+			for range app.cardinality(tsk) - 1 {
+				clone, err := app.clone(tsk, app.ctxs[0][i])
+				if err != nil {
+					return err
+				}
+				app.clones[tsk.Name()] = append(app.clones[tsk.Name()], clone)
+			}
+		}
+		app.locks[tsk.Name()] = new(sync.Mutex)
+	}
+
+	app.incs = nil
+	for i, tsk := range app.tsks {
+		if h, ok := tsk.(IncidentHandler); ok {
+			app.incs = append(app.incs, incHandler{ctx: app.ctxs[0][i], h: h})
+		}
+		for _, clone := range app.clones[tsk.Name()] {
+			if h, ok := clone.(IncidentHandler); ok {
+				app.incs = append(app.incs, incHandler{ctx: app.ctxs[0][i], h: h})
+			}
+		}
+	}
+	err = app.subscribeIncidents()
+	if err != nil {
+		return err
 	}
 
 	for i, tsk := range app.tsks {
@@ -504,6 +647,12 @@ func (app *appmgr) start(ctx Context) error {
 		if err != nil {
 			return err
 		}
+		for _, clone := range app.clones[tsk.Name()] {
+			err = clone.StartTask(app.ctxs[0][i])
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	app.state = fsm.Started
@@ -517,15 +666,21 @@ func (app *appmgr) run(ctx Context) error {
 
 	maxprocs := runtime.GOMAXPROCS(app.nprocs)
 
-	switch app.nprocs {
-	case 0:
+	switch {
+	case app.nprocs == 0:
 		err = app.runSequential(ctx)
+	case app.nslots > 0:
+		err = app.runScheduler(ctx)
 	default:
 		err = app.runConcurrent(ctx)
 	}
 
 	runtime.GOMAXPROCS(maxprocs)
 
+	if err != nil && err != io.EOF {
+		err = app.errs.join(err)
+	}
+
 	return err
 }
 
@@ -570,18 +725,37 @@ func (app *appmgr) runSequential(ctx Context) error {
 			evtCancel()
 			return err
 		}
-		err = app.istream.Process(ctxs[0])
+		err = app.readEvent(ctxs[0], nil)
 		if err != nil {
 			evtCancel()
 			store.close()
 			app.msg.flush()
 			return err
 		}
+		if !app.owns(ievt) {
+			// processed by another worker process.
+			evtCancel()
+			store.close()
+			continue
+		}
 		run := taskrunner{
 			ievt:   ievt,
 			errc:   make(chan error, len(app.tsks)),
 			evtctx: evtctx,
+			evt:    newEvtFlow(app.tsks),
+			flow:   app.flow,
+			errs:   app.errs,
+			locks:  app.locks,
+			mons:   app.mons,
 		}
+		evt := ctxType{
+			id:    ievt,
+			slot:  0,
+			store: &store,
+			msg:   app.msg,
+			ctx:   evtctx,
+		}
+		run.beginEvent(evt)
 		for i, tsk := range app.tsks {
 			go run.run(i, ctxs[i], tsk)
 		}
@@ -599,6 +773,10 @@ func (app *appmgr) runSequential(ctx Context) error {
 				break errloop
 			}
 		}
+		if app.flow != nil {
+			app.flow.record(run.evt)
+		}
+		run.endEvent(evt)
 		evtCancel()
 		store.close()
 		app.msg.flush()
@@ -618,6 +796,8 @@ func (app *appmgr) runConcurrent(ctx Context) error {
 		done:   make(chan struct{}),
 		errc:   make(chan error),
 		runctx: runctx,
+
+		inflight: new(sync.WaitGroup),
 	}
 
 	istream, err := app.startInputStream()
@@ -660,7 +840,10 @@ func (app *appmgr) runConcurrent(ctx Context) error {
 				ctx:   evtctx,
 			}
 
-			err = app.istream.Process(ctx)
+			err = app.readEvent(ctx, func() error {
+				ctrl.inflight.Wait()
+				return nil
+			})
 			if err != nil {
 				if err != io.EOF {
 					ctrl.errc <- err
@@ -669,6 +852,13 @@ func (app *appmgr) runConcurrent(ctx Context) error {
 				evtCancel()
 				return
 			}
+			if !app.owns(ievt) {
+				// processed by another worker process.
+				store.close()
+				evtCancel()
+				continue
+			}
+			ctrl.inflight.Add(1)
 			ctrl.evts <- ctx
 			evtCancel()
 		}
@@ -684,8 +874,7 @@ ctrl:
 				continue
 			}
 			if eworker != nil && err == nil {
-				// only record first error.
-				// FIXME(sbinet) record all of them (errstack)
+				// errors of tasks are recorded by the errstack.
 				err = eworker
 			}
 
@@ -755,13 +944,19 @@ func (app *appmgr) startOutputStreams() (StreamControl, error) {
 		Quit: make(chan struct{}),
 	}
 
-	// start output streams
+	// start output streams.
+	// each output stream is given its own channels so events are
+	// written out by the stream that selected them.
 	for _, tsk := range app.tsks {
-		in, ok := tsk.(*OutputStream)
+		out, ok := tsk.(*OutputStream)
 		if !ok {
 			continue
 		}
-		err = in.connect(ctrl)
+		err = out.connect(StreamControl{
+			Ctx:  make(chan Context),
+			Err:  make(chan error), // FIXME: impl. back-pressure
+			Quit: ctrl.Quit,
+		})
 		if err != nil {
 			return ctrl, err
 		}
@@ -780,6 +975,11 @@ func (app *appmgr) stop(ctx Context) error {
 		if err != nil {
 			return err
 		}
+		// notify the incidents reported while closing the input stream.
+		err = app.fireIncidents(ctx, nil)
+		if err != nil {
+			return err
+		}
 	}
 
 	for i, tsk := range app.tsks {
@@ -787,6 +987,24 @@ func (app *appmgr) stop(ctx Context) error {
 		if err != nil {
 			return err
 		}
+		for _, clone := range app.clones[tsk.Name()] {
+			err = clone.StopTask(app.ctxs[0][i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if app.flow != nil && app.flow.filtering() {
+		for _, line := range strings.Split(strings.TrimSpace(app.flow.summary()), "\n") {
+			app.msg.Infof("%s\n", line)
+		}
+	}
+
+	if app.errs != nil && app.errs.len() > 0 {
+		for _, line := range strings.Split(strings.TrimSpace(app.errs.summary()), "\n") {
+			app.msg.Warnf("%s\n", line)
+		}
 	}
 
 	for i, svc := range app.svcs {
@@ -810,11 +1028,26 @@ func (app *appmgr) shutdown(ctx Context) error {
 
 	app.props = nil
 	app.dflow = nil
+	app.flow = nil
+	app.errs = nil
+	app.clones = nil
+	app.locks = nil
+	app.mons = nil
+	app.incs = nil
+	app.isvc = nil
 	app.store = nil
 
 	return err
 }
 
+// errpolicy returns the error policy of the named task.
+func (app *appmgr) errpolicy(name string) ErrorPolicy {
+	if p, ok := app.policies[name]; ok {
+		return p
+	}
+	return app.policy
+}
+
 func (app *appmgr) Msg() MsgStream {
 	return app.msg
 }
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 
 	"codeberg.org/gonuts/commander"
+	"go-hep.org/x/hep/fwk/job"
 	"go-hep.org/x/hep/fwk/utils/builder"
 )
 
@@ -28,6 +29,8 @@ ex:
  $ fwk-app run config1.go config2.go
  $ fwk-app run ./some-dir
  $ fwk-app run -l=INFO -nprocs=4 -evtmax=-1 config.go
+ $ fwk-app run -set=app.EvtMax=10 -set=t1.Input=ints config.go
+ $ fwk-app run -job=job.yaml config.go
 `,
 		Flag: *flag.NewFlagSet("fwk-app-run", flag.ExitOnError),
 	}
@@ -40,6 +43,8 @@ ex:
 	cmd.Flag.Int("evtmax", -1, "number of events to process")
 	cmd.Flag.Int("nprocs", 0, "number of concurrent events to process")
 	cmd.Flag.Bool("cpu-prof", false, "enable CPU profiling")
+	cmd.Flag.String("job", "", "job description file (YAML or TOML) replacing the setup functions")
+	cmd.Flag.Var(new(job.Overrides), "set", "override a property (component.Property=value, app.Property=value)")
 	return cmd
 }
 
@@ -49,7 +54,7 @@ func fwk_run_cmd_run(cmd *commander.Command, args []string) error {
 	n := "fwk-app-" + cmd.Name()
 
 	subargs := make([]string, 0, len(args))
-	for _, nn := range []string{"l", "evtmax", "nprocs", "cpu-prof"} {
+	for _, nn := range []string{"l", "evtmax", "nprocs", "cpu-prof", "job"} {
 		val := cmd.Flag.Lookup(nn)
 		if val == nil {
 			continue
@@ -59,6 +64,9 @@ func fwk_run_cmd_run(cmd *commander.Command, args []string) error {
 			fmt.Sprintf("-"+nn+"=%v", val.Value.(flag.Getter).Get()),
 		)
 	}
+	for _, o := range *cmd.Flag.Lookup("set").Value.(*job.Overrides) {
+		subargs = append(subargs, "-set="+o)
+	}
 
 	fnames := make([]string, 0, len(args))
 	for _, arg := range args {
@@ -0,0 +1,133 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package condsvc
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Kind describes how the intervals of validity of a condition are indexed.
+type Kind int
+
+const (
+	// RunLumiKind conditions are indexed by run number and luminosity block.
+	// See RunLumi.
+	RunLumiKind Kind = iota
+
+	// TimeKind conditions are indexed by timestamps, in nanoseconds since
+	// the Unix epoch.
+	TimeKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case RunLumiKind:
+		return "run-lumi"
+	case TimeKind:
+		return "time"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// RunLumi returns the key of the luminosity block lumi of the run run.
+func RunLumi(run, lumi uint32) uint64 {
+	return uint64(run)<<32 | uint64(lumi)
+}
+
+// IOV is an interval of validity [Since, Until).
+// An Until value of zero denotes an open-ended interval.
+type IOV struct {
+	Since uint64 `json:"since"`
+	Until uint64 `json:"until,omitempty"`
+}
+
+// Contains returns whether key is inside the interval of validity.
+func (iov IOV) Contains(key uint64) bool {
+	return iov.Since <= key && key < iov.until()
+}
+
+func (iov IOV) until() uint64 {
+	if iov.Until == 0 {
+		return math.MaxUint64
+	}
+	return iov.Until
+}
+
+func (iov IOV) String() string {
+	if iov.Until == 0 {
+		return fmt.Sprintf("[%d, +inf)", iov.Since)
+	}
+	return fmt.Sprintf("[%d, %d)", iov.Since, iov.Until)
+}
+
+// Backend is a source of conditions payloads.
+//
+// Payloads of different intervals of validity may be loaded concurrently.
+type Backend interface {
+	// IOVs returns the intervals of validity of the named condition,
+	// sorted by increasing Since values.
+	IOVs(name string) ([]IOV, error)
+
+	// Load loads the payload of the named condition for the interval of
+	// validity iov, as a value of type typ.
+	Load(name string, iov IOV, typ reflect.Type) (any, error)
+
+	// Close closes the backend.
+	Close() error
+}
+
+// Open opens the conditions database fname.
+// The backend is selected from the extension of the file name:
+//   - ".json": a JSON document (see NewJSON),
+//   - ".root": a ROOT file of objects (see NewROOT),
+//   - otherwise: a ql embedded database (see OpenQL).
+func Open(fname string) (Backend, error) {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".json":
+		return OpenJSON(fname)
+	case ".root":
+		return OpenROOT(fname)
+	default:
+		db, err := OpenQL(fname)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+}
+
+// find returns the index of the interval of validity holding key.
+func find(iovs []IOV, key uint64) (int, bool) {
+	lo, hi := 0, len(iovs)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if iovs[mid].Since <= key {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	i := lo - 1
+	if i < 0 || !iovs[i].Contains(key) {
+		return -1, false
+	}
+	return i, true
+}
+
+// convert returns v as a value of type typ.
+func convert(name string, v any, typ reflect.Type) (any, error) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Type().AssignableTo(typ):
+		return v, nil
+	case rv.Kind() == reflect.Ptr && rv.Elem().Type().AssignableTo(typ):
+		return rv.Elem().Interface(), nil
+	}
+	return nil, fmt.Errorf("condsvc: condition [%s] has type %T, want %v", name, v, typ)
+}
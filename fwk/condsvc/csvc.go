@@ -0,0 +1,355 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package condsvc provides a fwk.CondSvc serving conditions payloads,
+// valid over intervals of runs, luminosity blocks or time, from a JSON
+// document, a ql embedded database or a ROOT file.
+//
+// Payloads are cached across events and are only reloaded when an event
+// crosses the boundary of their interval of validity.
+package condsvc // import "go-hep.org/x/hep/fwk/condsvc"
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-hep.org/x/hep/fwk"
+	"golang.org/x/sync/singleflight"
+)
+
+type csvc struct {
+	fwk.SvcBase
+
+	fname string  // name of the conditions database
+	db    Backend // conditions database
+	owned bool    // whether the database has been opened by the service
+
+	kinds   map[string]Kind // kinds of the conditions. RunLumiKind by default.
+	run     int64           // run number, when not read from the event store
+	runKey  string          // event store key of the run number
+	lumiKey string          // event store key of the luminosity block
+	timeKey string          // event store key of the event timestamp
+	ncache  int             // number of payloads cached per condition
+
+	mu    sync.RWMutex
+	conds map[string]*cond
+}
+
+// cond holds the state of a declared condition.
+type cond struct {
+	name  string
+	typ   reflect.Type
+	kind  Kind
+	users []string
+
+	mu    sync.Mutex
+	iovs  []IOV
+	cache []payload // most recently used first
+	nload int       // number of payloads loaded from the database
+
+	loads singleflight.Group // in-flight loads, keyed by IOV index
+}
+
+type payload struct {
+	iov IOV
+	v   any
+}
+
+func (svc *csvc) Configure(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (svc *csvc) StartSvc(ctx fwk.Context) error {
+	var err error
+
+	if svc.db == nil {
+		if svc.fname == "" {
+			return fmt.Errorf("%s: no conditions database", svc.Name())
+		}
+		svc.db, err = Open(svc.fname)
+		if err != nil {
+			return err
+		}
+		svc.owned = true
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	names := make([]string, 0, len(svc.conds))
+	for name := range svc.conds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := svc.conds[name]
+		c.iovs, err = svc.db.IOVs(name)
+		if err != nil {
+			return err
+		}
+		c.cache = make([]payload, 0, max(svc.ncache, 1))
+		c.nload = 0
+	}
+
+	return err
+}
+
+func (svc *csvc) StopSvc(ctx fwk.Context) error {
+	var err error
+
+	svc.mu.RLock()
+	names := make([]string, 0, len(svc.conds))
+	for name := range svc.conds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msg := ctx.Msg()
+	for _, name := range names {
+		c := svc.conds[name]
+		msg.Debugf("condition [%s]: iovs=%d loads=%d\n", name, len(c.iovs), c.nload)
+	}
+	svc.mu.RUnlock()
+
+	if svc.owned {
+		err = svc.db.Close()
+		svc.db = nil
+		svc.owned = false
+	}
+
+	return err
+}
+
+// DeclCond declares that component c reads the condition named name,
+// with type t.
+func (svc *csvc) DeclCond(c fwk.Component, name string, t reflect.Type) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	cnd, ok := svc.conds[name]
+	if !ok {
+		cnd = &cond{
+			name: name,
+			typ:  t,
+			kind: svc.kinds[name],
+		}
+		svc.conds[name] = cnd
+	}
+
+	if cnd.typ != t {
+		return fmt.Errorf(
+			"condsvc: detected type inconsistency for condition [%s]:\n component=%q type=%v\n component=%q type=%v",
+			name,
+			cnd.users[0], cnd.typ,
+			c.Name(), t,
+		)
+	}
+	cnd.users = append(cnd.users, c.Name())
+
+	return nil
+}
+
+// Get returns the payload of the named condition, valid for the event
+// being processed by ctx.
+func (svc *csvc) Get(ctx fwk.Context, name string) (any, error) {
+	svc.mu.RLock()
+	c, ok := svc.conds[name]
+	svc.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("condsvc: condition [%s] was not declared", name)
+	}
+
+	key, err := svc.key(ctx, c.kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.get(svc.db, key)
+}
+
+// key returns the key of the event being processed by ctx, for
+// conditions of the provided kind.
+func (svc *csvc) key(ctx fwk.Context, kind Kind) (uint64, error) {
+	switch kind {
+	case RunLumiKind:
+		run := uint64(svc.run)
+		if svc.runKey != "" {
+			v, err := svc.get(ctx, svc.runKey)
+			if err != nil {
+				return 0, err
+			}
+			run = v
+		}
+		lumi := uint64(0)
+		if svc.lumiKey != "" {
+			v, err := svc.get(ctx, svc.lumiKey)
+			if err != nil {
+				return 0, err
+			}
+			lumi = v
+		}
+		return RunLumi(uint32(run), uint32(lumi)), nil
+
+	case TimeKind:
+		if svc.timeKey == "" {
+			return 0, fmt.Errorf("%s: no event store key for timestamps", svc.Name())
+		}
+		return svc.get(ctx, svc.timeKey)
+	}
+	return 0, fmt.Errorf("%s: invalid conditions kind %v", svc.Name(), kind)
+}
+
+// get returns the value of the event store key k, as a key.
+func (svc *csvc) get(ctx fwk.Context, k string) (uint64, error) {
+	v, err := ctx.Store().Get(k)
+	if err != nil {
+		return 0, err
+	}
+	switch v := v.(type) {
+	case int:
+		return uint64(v), nil
+	case int32:
+		return uint64(v), nil
+	case int64:
+		return uint64(v), nil
+	case uint:
+		return uint64(v), nil
+	case uint32:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case time.Time:
+		return uint64(v.UnixNano()), nil
+	}
+	return 0, fmt.Errorf("%s: invalid type %T for event store key [%s]", svc.Name(), v, k)
+}
+
+// get returns the payload valid for key, loading it from the database
+// if it is not cached.
+//
+// The lock of the condition is not held while loading payloads, so
+// events needing cached payloads are not blocked by slow databases.
+// Concurrent requests for the same IOV share a single load.
+func (c *cond) get(db Backend, key uint64) (any, error) {
+	c.mu.Lock()
+	v, ok := c.lookup(key)
+	if ok {
+		c.mu.Unlock()
+		return v, nil
+	}
+
+	i, ok := find(c.iovs, key)
+	if !ok {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("condsvc: no IOV for condition [%s] and key %d", c.name, key)
+	}
+	iov := c.iovs[i]
+	c.mu.Unlock()
+
+	v, err, _ := c.loads.Do(strconv.Itoa(i), func() (any, error) {
+		c.mu.Lock()
+		v, ok := c.lookup(key)
+		c.mu.Unlock()
+		if ok {
+			// loaded by a request which completed in the meantime.
+			return v, nil
+		}
+
+		v, err := db.Load(c.name, iov, c.typ)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.nload++
+
+		if len(c.cache) < cap(c.cache) {
+			c.cache = c.cache[:len(c.cache)+1]
+		}
+		copy(c.cache[1:], c.cache)
+		c.cache[0] = payload{iov: iov, v: v}
+
+		return v, nil
+	})
+	return v, err
+}
+
+// lookup returns the cached payload valid for key, if any, and marks it
+// as the most recently used one.
+// lookup must be called with c.mu held.
+func (c *cond) lookup(key uint64) (any, bool) {
+	for i, p := range c.cache {
+		if p.iov.Contains(key) {
+			copy(c.cache[1:i+1], c.cache[:i])
+			c.cache[0] = p
+			return p.v, true
+		}
+	}
+	return nil, false
+}
+
+func newcsvc(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+	svc := &csvc{
+		SvcBase: fwk.NewSvc(typ, name, mgr),
+		kinds:   make(map[string]Kind),
+		ncache:  2,
+		conds:   make(map[string]*cond),
+	}
+
+	err = svc.DeclProp("DB", &svc.fname)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("Backend", &svc.db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("Kinds", &svc.kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("RunNumber", &svc.run)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("RunKey", &svc.runKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("LumiKey", &svc.lumiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("TimeKey", &svc.timeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("CacheSize", &svc.ncache)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(csvc{}), newcsvc)
+}
+
+var _ fwk.CondSvc = (*csvc)(nil)
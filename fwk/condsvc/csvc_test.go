@@ -0,0 +1,376 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package condsvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/job"
+	"go-hep.org/x/hep/groot/rbase"
+	"go-hep.org/x/hep/groot/riofs"
+)
+
+const nentries = 100
+
+type calib struct {
+	Scale float64 `json:"scale"`
+}
+
+// iovs are the intervals of validity of the test condition.
+// runs change every 10 events, starting at run 1.
+var iovs = []IOV{
+	{Since: RunLumi(1, 0), Until: RunLumi(5, 0)},
+	{Since: RunLumi(5, 0), Until: RunLumi(8, 0)},
+	{Since: RunLumi(8, 0)},
+}
+
+func scale(i int) float64 { return float64(i+1) * 1.5 }
+
+func want(run int64) float64 {
+	i, ok := find(iovs, RunLumi(uint32(run), 0))
+	if !ok {
+		panic(fmt.Errorf("no IOV for run %d", run))
+	}
+	return scale(i)
+}
+
+// counter counts the payloads loaded from a backend.
+type counter struct {
+	Backend
+	mu sync.Mutex
+	n  int
+}
+
+func (c *counter) Load(name string, iov IOV, typ reflect.Type) (any, error) {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+	return c.Backend.Load(name, iov, typ)
+}
+
+func run(t *testing.T, nprocs int, db Backend, typ reflect.Type, value func(v any) float64) int {
+	t.Helper()
+
+	app := job.NewJob(nil, job.P{
+		"EvtMax":   int64(nentries),
+		"NProcs":   nprocs,
+		"MsgLevel": job.MsgLevel("ERROR"),
+	})
+
+	cnt := &counter{Backend: db}
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/condsvc.csvc",
+		Name: "condsvc",
+		Props: job.P{
+			"Backend": Backend(cnt),
+			"RunKey":  "run",
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/condsvc.testrun",
+		Name: "run-gen",
+	})
+
+	for i := range 2 {
+		app.Create(job.C{
+			Type: "go-hep.org/x/hep/fwk/condsvc.testcond",
+			Name: fmt.Sprintf("t%d", i),
+			Props: job.P{
+				"Type": typ,
+				"Check": func(run int64, v any) error {
+					if got, want := value(v), want(run); got != want {
+						return fmt.Errorf("invalid payload for run %d: got=%v, want=%v", run, got, want)
+					}
+					return nil
+				},
+			},
+		})
+	}
+
+	err := app.App().Run()
+	if err != nil {
+		t.Fatalf("could not run application (nprocs=%d): %+v", nprocs, err)
+	}
+
+	return cnt.n
+}
+
+func testBackend(t *testing.T, db Backend, typ reflect.Type, value func(v any) float64) {
+	for _, nprocs := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("nprocs=%d", nprocs), func(t *testing.T) {
+			n := run(t, nprocs, db, typ, value)
+			switch {
+			case nprocs == 0 && n != len(iovs):
+				t.Fatalf("invalid number of loads: got=%d, want=%d", n, len(iovs))
+			case n < len(iovs):
+				t.Fatalf("invalid number of loads: got=%d, want>=%d", n, len(iovs))
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	doc := new(strings.Builder)
+	doc.WriteString(`{"calib": [`)
+	for i, iov := range iovs {
+		if i > 0 {
+			doc.WriteString(",")
+		}
+		fmt.Fprintf(doc, `{"since": {"run": %d}, `, iov.Since>>32)
+		if iov.Until != 0 {
+			fmt.Fprintf(doc, `"until": {"run": %d}, `, iov.Until>>32)
+		}
+		fmt.Fprintf(doc, `"payload": {"scale": %v}}`, scale(i))
+	}
+	doc.WriteString(`]}`)
+
+	fname := filepath.Join(t.TempDir(), "conds.json")
+	err := os.WriteFile(fname, []byte(doc.String()), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not open JSON db: %+v", err)
+	}
+	defer db.Close()
+
+	testBackend(t, db, reflect.TypeOf(calib{}), func(v any) float64 {
+		return v.(calib).Scale
+	})
+}
+
+func TestQL(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "conds.db")
+	db, err := OpenQL(fname)
+	if err != nil {
+		t.Fatalf("could not create ql db: %+v", err)
+	}
+	defer db.Close()
+
+	// insert out of order.
+	for _, i := range []int{2, 0, 1} {
+		err = db.Put("calib", iovs[i], calib{Scale: scale(i)})
+		if err != nil {
+			t.Fatalf("could not store payload: %+v", err)
+		}
+	}
+
+	testBackend(t, db, reflect.TypeOf(calib{}), func(v any) float64 {
+		return v.(calib).Scale
+	})
+}
+
+func TestROOT(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "conds.root")
+	w, err := riofs.Create(fname)
+	if err != nil {
+		t.Fatalf("could not create ROOT file: %+v", err)
+	}
+	for i, iov := range iovs {
+		err = riofs.Dir(w).Put("calib/"+KeyName(iov), rbase.NewObjString(fmt.Sprint(scale(i))))
+		if err != nil {
+			t.Fatalf("could not store payload: %+v", err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("could not close ROOT file: %+v", err)
+	}
+
+	db, err := Open(fname)
+	if err != nil {
+		t.Fatalf("could not open ROOT db: %+v", err)
+	}
+	defer db.Close()
+
+	testBackend(t, db, reflect.TypeOf((*rbase.ObjString)(nil)), func(v any) float64 {
+		var f float64
+		_, err := fmt.Sscan(v.(*rbase.ObjString).String(), &f)
+		if err != nil {
+			panic(err)
+		}
+		return f
+	})
+}
+
+// slowdb is a backend whose loads of the second IOV block until released.
+type slowdb struct {
+	Backend
+	release chan struct{}
+	loading chan struct{}
+
+	mu sync.Mutex
+	n  int // number of loads of the second IOV
+}
+
+func (db *slowdb) Load(name string, iov IOV, typ reflect.Type) (any, error) {
+	if iov != iovs[1] {
+		return iov.Since, nil
+	}
+	db.mu.Lock()
+	db.n++
+	db.mu.Unlock()
+	db.loading <- struct{}{}
+	<-db.release
+	return iov.Since, nil
+}
+
+func TestConcurrentLoads(t *testing.T) {
+	db := &slowdb{
+		release: make(chan struct{}),
+		loading: make(chan struct{}, 2),
+	}
+	c := &cond{
+		name:  "calib",
+		iovs:  iovs,
+		cache: make([]payload, 0, 2),
+	}
+
+	_, err := c.get(db, RunLumi(1, 0))
+	if err != nil {
+		t.Fatalf("could not load first payload: %+v", err)
+	}
+
+	var grp sync.WaitGroup
+	for range 2 {
+		grp.Add(1)
+		go func() {
+			defer grp.Done()
+			v, err := c.get(db, RunLumi(6, 0))
+			if err != nil {
+				t.Errorf("could not load second payload: %+v", err)
+				return
+			}
+			if v != iovs[1].Since {
+				t.Errorf("invalid second payload: %v", v)
+			}
+		}()
+	}
+	<-db.loading
+
+	// cached payloads are served while the second payload is being loaded.
+	v, err := c.get(db, RunLumi(2, 0))
+	if err != nil {
+		t.Fatalf("could not get cached payload: %+v", err)
+	}
+	if v != iovs[0].Since {
+		t.Fatalf("invalid cached payload: %v", v)
+	}
+
+	close(db.release)
+	grp.Wait()
+
+	if db.n != 1 {
+		t.Fatalf("invalid number of loads of the second payload: got=%d, want=1", db.n)
+	}
+	if c.nload != 2 {
+		t.Fatalf("invalid number of loads: got=%d, want=2", c.nload)
+	}
+}
+
+func TestUndeclared(t *testing.T) {
+	svc := &csvc{conds: make(map[string]*cond)}
+	_, err := svc.Get(nil, "calib")
+	if err == nil {
+		t.Fatalf("expected an error for an undeclared condition")
+	}
+}
+
+// testrun puts the run number of the current event in the store.
+type testrun struct {
+	fwk.TaskBase
+}
+
+func (tsk *testrun) Configure(ctx fwk.Context) error {
+	return tsk.DeclOutPort("run", reflect.TypeOf(int64(0)))
+}
+
+func (tsk *testrun) StartTask(ctx fwk.Context) error { return nil }
+func (tsk *testrun) StopTask(ctx fwk.Context) error  { return nil }
+
+func (tsk *testrun) Process(ctx fwk.Context) error {
+	return ctx.Store().Put("run", 1+ctx.ID()/10)
+}
+
+// testcond checks the payload of the "calib" condition.
+type testcond struct {
+	fwk.TaskBase
+
+	typ   reflect.Type
+	check func(run int64, v any) error
+	csvc  fwk.CondSvc
+}
+
+func (tsk *testcond) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort("run", reflect.TypeOf(int64(0)))
+	if err != nil {
+		return err
+	}
+
+	svc, err := ctx.Svc("condsvc")
+	if err != nil {
+		return err
+	}
+	tsk.csvc = svc.(fwk.CondSvc)
+
+	return tsk.csvc.DeclCond(tsk, "calib", tsk.typ)
+}
+
+func (tsk *testcond) StartTask(ctx fwk.Context) error { return nil }
+func (tsk *testcond) StopTask(ctx fwk.Context) error  { return nil }
+
+func (tsk *testcond) Process(ctx fwk.Context) error {
+	v, err := ctx.Store().Get("run")
+	if err != nil {
+		return err
+	}
+
+	payload, err := tsk.csvc.Get(ctx, "calib")
+	if err != nil {
+		return err
+	}
+	return tsk.check(v.(int64), payload)
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(testrun{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			return &testrun{TaskBase: fwk.NewTask(typ, name, mgr)}, nil
+		},
+	)
+
+	fwk.Register(reflect.TypeOf(testcond{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			var err error
+			tsk := &testcond{
+				TaskBase: fwk.NewTask(typ, name, mgr),
+			}
+
+			err = tsk.DeclProp("Type", &tsk.typ)
+			if err != nil {
+				return nil, err
+			}
+
+			err = tsk.DeclProp("Check", &tsk.check)
+			if err != nil {
+				return nil, err
+			}
+
+			return tsk, err
+		},
+	)
+}
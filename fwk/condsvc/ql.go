@@ -0,0 +1,133 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package condsvc
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	_ "modernc.org/ql/driver"
+)
+
+// QL is a conditions database stored in a local ql embedded database
+// file, holding a single table:
+//
+//	CREATE TABLE conditions (name string, since int64, until int64, payload blob);
+//
+// Payloads are stored as JSON documents.
+type QL struct {
+	db *sql.DB
+}
+
+// OpenQL opens the ql conditions database fname.
+// The database is created if it does not exist.
+func OpenQL(fname string) (*QL, error) {
+	db, err := sql.Open("ql", fname)
+	if err != nil {
+		return nil, fmt.Errorf("condsvc: could not open ql conditions %q: %w", fname, err)
+	}
+	// ql databases do not support concurrent connections.
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("condsvc: could not create ql transaction: %w", err)
+	}
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS conditions (name string, since int64, until int64, payload blob);`)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, fmt.Errorf("condsvc: could not create ql conditions table: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("condsvc: could not commit ql conditions table: %w", err)
+	}
+
+	return &QL{db: db}, nil
+}
+
+// Put stores the payload of the named condition, valid over iov.
+func (db *QL) Put(name string, iov IOV, payload any) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("condsvc: could not encode payload of condition [%s]: %w", name, err)
+	}
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("condsvc: could not create ql transaction: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO conditions VALUES ($1, $2, $3, $4);`,
+		name, int64(iov.Since), int64(iov.Until), raw,
+	)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("condsvc: could not insert condition [%s]: %w", name, err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("condsvc: could not commit condition [%s]: %w", name, err)
+	}
+	return nil
+}
+
+func (db *QL) IOVs(name string) ([]IOV, error) {
+	rows, err := db.db.Query(
+		`SELECT since, until FROM conditions WHERE name == $1 ORDER BY since;`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("condsvc: could not query condition [%s]: %w", name, err)
+	}
+	defer rows.Close()
+
+	var iovs []IOV
+	for rows.Next() {
+		var since, until int64
+		err = rows.Scan(&since, &until)
+		if err != nil {
+			return nil, fmt.Errorf("condsvc: could not scan condition [%s]: %w", name, err)
+		}
+		iovs = append(iovs, IOV{Since: uint64(since), Until: uint64(until)})
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("condsvc: could not query condition [%s]: %w", name, err)
+	}
+	if len(iovs) == 0 {
+		return nil, fmt.Errorf("condsvc: no condition [%s]", name)
+	}
+	return iovs, nil
+}
+
+func (db *QL) Load(name string, iov IOV, typ reflect.Type) (any, error) {
+	var raw []byte
+	err := db.db.QueryRow(
+		`SELECT payload FROM conditions WHERE name == $1 && since == $2 && until == $3;`,
+		name, int64(iov.Since), int64(iov.Until),
+	).Scan(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("condsvc: no payload for condition [%s] and IOV %v: %w", name, iov, err)
+	}
+
+	ptr := reflect.New(typ)
+	err = json.Unmarshal(raw, ptr.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("condsvc: could not decode payload of condition [%s] for IOV %v: %w", name, iov, err)
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+func (db *QL) Close() error {
+	return db.db.Close()
+}
+
+var _ Backend = (*QL)(nil)
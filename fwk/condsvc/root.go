@@ -0,0 +1,107 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package condsvc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-hep.org/x/hep/groot/riofs"
+)
+
+// rootDB is a conditions database stored in a ROOT file.
+// Each condition is a directory holding one object per interval of
+// validity, stored under a key named "<since>_<until>".
+type rootDB struct {
+	f   *riofs.File
+	dir riofs.Directory
+
+	mu sync.Mutex // serializes reads of the ROOT file
+}
+
+// NewROOT creates a conditions database from the ROOT directory dir.
+func NewROOT(dir riofs.Directory) Backend {
+	return &rootDB{dir: riofs.Dir(dir)}
+}
+
+// OpenROOT opens the ROOT conditions database fname.
+func OpenROOT(fname string) (Backend, error) {
+	f, err := riofs.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("condsvc: could not open ROOT conditions: %w", err)
+	}
+	return &rootDB{f: f, dir: riofs.Dir(f)}, nil
+}
+
+// KeyName returns the name of the ROOT key holding the payload valid
+// over iov.
+func KeyName(iov IOV) string {
+	return strconv.FormatUint(iov.Since, 10) + "_" + strconv.FormatUint(iov.Until, 10)
+}
+
+func (db *rootDB) IOVs(name string) ([]IOV, error) {
+	obj, err := db.dir.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("condsvc: no condition [%s]: %w", name, err)
+	}
+	dir, ok := obj.(riofs.Directory)
+	if !ok {
+		return nil, fmt.Errorf("condsvc: condition [%s] is not a ROOT directory (type=%T)", name, obj)
+	}
+
+	var (
+		iovs []IOV
+		seen = make(map[string]bool)
+	)
+	for _, k := range dir.Keys() {
+		if seen[k.Name()] {
+			continue // older cycle.
+		}
+		seen[k.Name()] = true
+
+		since, until, ok := strings.Cut(k.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("condsvc: invalid key name %q for condition [%s]", k.Name(), name)
+		}
+		var iov IOV
+		iov.Since, err = strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("condsvc: invalid key name %q for condition [%s]: %w", k.Name(), name, err)
+		}
+		iov.Until, err = strconv.ParseUint(until, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("condsvc: invalid key name %q for condition [%s]: %w", k.Name(), name, err)
+		}
+		iovs = append(iovs, iov)
+	}
+	sort.Slice(iovs, func(i, j int) bool {
+		return iovs[i].Since < iovs[j].Since
+	})
+	return iovs, nil
+}
+
+func (db *rootDB) Load(name string, iov IOV, typ reflect.Type) (any, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	obj, err := db.dir.Get(name + "/" + KeyName(iov))
+	if err != nil {
+		return nil, fmt.Errorf("condsvc: no payload for condition [%s] and IOV %v: %w", name, iov, err)
+	}
+	return convert(name, obj, typ)
+}
+
+func (db *rootDB) Close() error {
+	if db.f == nil {
+		return nil
+	}
+	return db.f.Close()
+}
+
+var _ Backend = (*rootDB)(nil)
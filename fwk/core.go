@@ -7,6 +7,7 @@ package fwk
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"go-hep.org/x/hep/fwk/fsm"
 )
@@ -209,6 +210,33 @@ func (lvl Level) String() string {
 	panic(fmt.Errorf("fwk.Level: invalid fwk.Level value [%d]", int(lvl)))
 }
 
+// MarshalText implements encoding.TextMarshaler.
+func (lvl Level) MarshalText() ([]byte, error) {
+	switch lvl {
+	case LvlDebug, LvlInfo, LvlWarning, LvlError:
+		return []byte(lvl.String()), nil
+	}
+	return nil, fmt.Errorf("fwk.Level: invalid fwk.Level value [%d]", int(lvl))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// Valid values are: "DEBUG", "INFO", "WARNING"|"WARN" and "ERROR"|"ERR".
+func (lvl *Level) UnmarshalText(text []byte) error {
+	switch strings.ToUpper(string(text)) {
+	case "DEBUG":
+		*lvl = LvlDebug
+	case "INFO":
+		*lvl = LvlInfo
+	case "WARNING", "WARN":
+		*lvl = LvlWarning
+	case "ERROR", "ERR":
+		*lvl = LvlError
+	default:
+		return fmt.Errorf("fwk.Level: invalid fwk.Level string %q", text)
+	}
+	return nil
+}
+
 // MsgStream provides access to verbosity-defined formated messages, a la fmt.Printf.
 type MsgStream interface {
 	Debugf(format string, a ...any)
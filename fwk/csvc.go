@@ -0,0 +1,25 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+import (
+	"reflect"
+)
+
+// CondSvc is the interface providing access to conditions data, such as
+// calibration constants or alignment parameters, which are valid over an
+// interval of runs, luminosity blocks or time.
+type CondSvc interface {
+	Svc
+
+	// DeclCond declares that component c reads the condition named name,
+	// with type t.
+	// DeclCond should be called during the Configure stage, like DeclInPort.
+	DeclCond(c Component, name string, t reflect.Type) error
+
+	// Get returns the payload of the named condition, valid for the event
+	// being processed by ctx.
+	Get(ctx Context, name string) (any, error)
+}
@@ -17,6 +17,8 @@ type ctxType struct {
 	mgr   App
 
 	ctx context.Context
+	evt *evtflow  // filter decisions of all tasks for this event
+	dec *decision // filter decision of the current task for this event
 }
 
 func (ctx ctxType) ID() int64 {
@@ -35,6 +37,17 @@ func (ctx ctxType) Msg() MsgStream {
 	return ctx.msg
 }
 
+func (ctx ctxType) setFilterPassed(pass bool) {
+	if ctx.dec == nil {
+		return
+	}
+	ctx.dec.pass = pass
+}
+
+func (ctx ctxType) flow() (context.Context, *evtflow) {
+	return ctx.ctx, ctx.evt
+}
+
 func (ctx ctxType) Svc(n string) (Svc, error) {
 	if ctx.mgr == nil {
 		return nil, fmt.Errorf("fwk: no fwk.App available to this Context")
@@ -0,0 +1,198 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrorPolicy describes how an application reacts to a task failing to
+// process an event.
+type ErrorPolicy int
+
+const (
+	// Abort stops the application at the first error.
+	// Abort is the default error policy.
+	Abort ErrorPolicy = iota
+
+	// SkipEvent records the error and stops the processing of the
+	// failed event: the tasks not yet executed for that event are skipped
+	// and the event is not written out by output streams.
+	SkipEvent
+
+	// Continue records the error and carries on with the processing of
+	// the event. Only the tasks depending on the failed task are skipped.
+	Continue
+)
+
+func (p ErrorPolicy) String() string {
+	switch p {
+	case Abort:
+		return "abort"
+	case SkipEvent:
+		return "skip-event"
+	case Continue:
+		return "continue"
+	}
+	return fmt.Sprintf("ErrorPolicy(%d)", int(p))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p ErrorPolicy) MarshalText() ([]byte, error) {
+	switch p {
+	case Abort, SkipEvent, Continue:
+		return []byte(p.String()), nil
+	}
+	return nil, fmt.Errorf("fwk: invalid error policy %d", int(p))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *ErrorPolicy) UnmarshalText(text []byte) error {
+	for _, v := range []ErrorPolicy{Abort, SkipEvent, Continue} {
+		if string(text) == v.String() {
+			*p = v
+			return nil
+		}
+	}
+	return fmt.Errorf("fwk: invalid error policy %q", text)
+}
+
+// EventError is the error returned when a task failed to process an event.
+type EventError struct {
+	ID   int64  // ID of the failed event
+	Task string // name of the failed task
+	Err  error  // underlying error
+}
+
+func (e *EventError) Error() string {
+	return fmt.Sprintf("fwk: task [%s] failed to process event %d: %v", e.Task, e.ID, e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is the list of errors returned by an application when more than
+// one error occurred during the event loop.
+type Errors []error
+
+func (errs Errors) Error() string {
+	var o strings.Builder
+	fmt.Fprintf(&o, "fwk: %d errors occurred:", len(errs))
+	for _, err := range errs {
+		fmt.Fprintf(&o, "\n\t* %v", err)
+	}
+	return o.String()
+}
+
+func (errs Errors) Unwrap() []error {
+	return errs
+}
+
+// maxErrs is the maximum number of errors retained by an errstack.
+// Errors beyond that limit are only counted.
+const maxErrs = 64
+
+// errstack records the errors which occurred during the event loop.
+type errstack struct {
+	max int // maximum number of failed events. 0 if unlimited.
+
+	mu    sync.Mutex
+	errs  []error        // first maxErrs errors
+	nerrs int            // number of errors
+	nevts int            // number of failed events
+	ntsks map[string]int // number of errors per task
+	abort error          // error reported once too many events failed
+}
+
+func newErrStack(max int) *errstack {
+	return &errstack{
+		max:   max,
+		ntsks: make(map[string]int),
+	}
+}
+
+// push records err for the event evt.
+// push returns an error when the maximum number of failed events
+// has been exceeded.
+func (stk *errstack) push(evt *evtflow, err error) error {
+	stk.mu.Lock()
+	defer stk.mu.Unlock()
+
+	if stk.abort != nil {
+		return stk.abort
+	}
+
+	stk.nerrs++
+	if len(stk.errs) < maxErrs {
+		stk.errs = append(stk.errs, err)
+	}
+	var eerr *EventError
+	if errors.As(err, &eerr) {
+		stk.ntsks[eerr.Task]++
+	}
+	if evt != nil && evt.failed.CompareAndSwap(false, true) {
+		stk.nevts++
+	}
+	if stk.max > 0 && stk.nevts > stk.max {
+		stk.abort = fmt.Errorf("fwk: too many failed events (MaxFailures=%d)", stk.max)
+		stk.nerrs++
+		stk.errs = append(stk.errs, stk.abort)
+		return stk.abort
+	}
+	return nil
+}
+
+// join returns the error of the application, given err, the error
+// which stopped the event loop.
+func (stk *errstack) join(err error) error {
+	stk.mu.Lock()
+	defer stk.mu.Unlock()
+
+	errs := make(Errors, 0, len(stk.errs)+2)
+	errs = append(errs, stk.errs...)
+	if n := stk.nerrs - len(stk.errs); n > 0 {
+		errs = append(errs, fmt.Errorf("fwk: %d more errors omitted", n))
+	}
+	if !slices.ContainsFunc(errs, func(e error) bool { return errors.Is(e, err) }) {
+		errs = append(errs, err)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errs
+}
+
+// summary returns a summary of the errors recorded per task.
+func (stk *errstack) summary() string {
+	stk.mu.Lock()
+	defer stk.mu.Unlock()
+
+	names := make([]string, 0, len(stk.ntsks))
+	width := 0
+	for name := range stk.ntsks {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+
+	var o strings.Builder
+	fmt.Fprintf(&o, "errors summary (errors=%d, failed events=%d):\n", stk.nerrs, stk.nevts)
+	for _, name := range names {
+		fmt.Fprintf(&o, "%-*s errors=%8d\n", width, name, stk.ntsks[name])
+	}
+	return o.String()
+}
+
+func (stk *errstack) len() int {
+	stk.mu.Lock()
+	defer stk.mu.Unlock()
+	return stk.nerrs
+}
@@ -0,0 +1,248 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/internal/fwktest"
+	"go-hep.org/x/hep/fwk/job"
+)
+
+func newErrApp(nprocs, nslots int, props job.P) (*job.Job, *bytes.Buffer) {
+	const max = 100
+	p := job.P{
+		"EvtMax":   int64(-1),
+		"NProcs":   nprocs,
+		"EvtSlots": nslots,
+		"MsgLevel": job.MsgLevel("ERROR"),
+	}
+	for k, v := range props {
+		p[k] = v
+	}
+	app := job.NewJob(nil, p)
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.InputStream",
+		Name: "input",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{
+					Name: "ints",
+					Type: reflect.TypeOf(int64(1)),
+				},
+			},
+			"Streamer": &fwktest.InputStream{
+				R: newTestReader(max),
+			},
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.faulty",
+		Name: "bad",
+		Props: job.P{
+			"Input":  "ints",
+			"Output": "good-ints",
+			"Modulo": int64(10),
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+		Name: "sq",
+		Props: job.P{
+			"Input":  "good-ints",
+			"Output": "sq-ints",
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.reducer",
+		Name: "reducer",
+		Props: job.P{
+			"Input": "sq-ints",
+			"Sum":   getsumsqIf(max, func(i int64) bool { return i%10 != 0 }),
+		},
+	})
+
+	out := new(bytes.Buffer)
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.OutputStream",
+		Name: "output",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{
+					Name: "ints",
+					Type: reflect.TypeOf(int64(1)),
+				},
+			},
+			"Streamer": &fwktest.OutputStream{W: out},
+		},
+	})
+
+	return app, out
+}
+
+func TestErrorPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		nprocs int
+		nslots int
+	}{
+		{0, 0},
+		{1, 0},
+		{4, 0},
+		{4, 4},
+	} {
+		name := fmt.Sprintf("nprocs=%d-nslots=%d", tc.nprocs, tc.nslots)
+		t.Run("abort-"+name, func(t *testing.T) {
+			app, _ := newErrApp(tc.nprocs, tc.nslots, nil)
+			err := app.App().Run()
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			var eerr *fwk.EventError
+			if !errors.As(err, &eerr) {
+				t.Fatalf("invalid error type %T: %+v", err, err)
+			}
+			if got, want := eerr.Task, "bad"; got != want {
+				t.Fatalf("invalid failed task: got=%q, want=%q", got, want)
+			}
+			if eerr.ID%10 != 0 {
+				t.Fatalf("invalid failed event: %d", eerr.ID)
+			}
+		})
+
+		t.Run("skip-event-"+name, func(t *testing.T) {
+			app, out := newErrApp(tc.nprocs, tc.nslots, job.P{
+				"ErrorPolicies": map[string]fwk.ErrorPolicy{"bad": fwk.SkipEvent},
+			})
+			err := app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run application: %+v", err)
+			}
+			vs := strings.Fields(out.String())
+			if got, want := len(vs), 90; got != want {
+				t.Fatalf("invalid number of events written: got=%d, want=%d", got, want)
+			}
+			for _, v := range vs {
+				if strings.HasSuffix(v, "0") {
+					t.Fatalf("failed event %s should have been skipped", v)
+				}
+			}
+		})
+
+		t.Run("continue-"+name, func(t *testing.T) {
+			app, out := newErrApp(tc.nprocs, tc.nslots, job.P{
+				"ErrorPolicy": fwk.Continue,
+			})
+			app.SetProp(app.App().GetTask("bad"), "Panic", true)
+			err := app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run application: %+v", err)
+			}
+			if got, want := len(strings.Fields(out.String())), 100; got != want {
+				t.Fatalf("invalid number of events written: got=%d, want=%d", got, want)
+			}
+		})
+
+		t.Run("max-failures-"+name, func(t *testing.T) {
+			app, _ := newErrApp(tc.nprocs, tc.nslots, job.P{
+				"ErrorPolicy": fwk.SkipEvent,
+				"MaxFailures": 5,
+			})
+			err := app.App().Run()
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			var errs fwk.Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("invalid error type %T: %+v", err, err)
+			}
+			if got, want := len(errs), 7; got < want {
+				t.Fatalf("invalid number of errors: got=%d, want>=%d", got, want)
+			}
+			nmax := 0
+			for _, err := range errs {
+				var eerr *fwk.EventError
+				switch {
+				case errors.As(err, &eerr):
+					// ok.
+				case err.Error() == "fwk: too many failed events (MaxFailures=5)":
+					nmax++
+				default:
+					t.Fatalf("invalid error: %+v", err)
+				}
+			}
+			if nmax != 1 {
+				t.Fatalf("invalid number of max-failures errors: got=%d, want=1", nmax)
+			}
+		})
+	}
+}
+
+func TestErrorsLimit(t *testing.T) {
+	app, _ := newErrApp(0, 0, job.P{
+		"ErrorPolicy": fwk.SkipEvent,
+		"MaxFailures": 90,
+	})
+	app.SetProp(app.App().GetTask("bad"), "Modulo", int64(1))
+	app.SetProp(app.App().GetTask("reducer"), "Sum", int64(0))
+
+	err := app.App().Run()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	var errs fwk.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("invalid error type %T: %+v", err, err)
+	}
+
+	var (
+		nevts = 0
+		nmax  = 0
+		nomit = 0
+	)
+	for _, err := range errs {
+		var eerr *fwk.EventError
+		switch {
+		case errors.As(err, &eerr):
+			nevts++
+		case err.Error() == "fwk: too many failed events (MaxFailures=90)":
+			nmax++
+		case err.Error() == "fwk: 27 more errors omitted":
+			nomit++
+		default:
+			t.Fatalf("invalid error: %+v", err)
+		}
+	}
+	if got, want := nevts, 64; got != want {
+		t.Fatalf("invalid number of retained errors: got=%d, want=%d", got, want)
+	}
+	if nmax != 1 || nomit != 1 {
+		t.Fatalf("invalid errors: max-failures=%d, omitted=%d", nmax, nomit)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	app, _ := newErrApp(0, 0, job.P{
+		"ErrorPolicy": fwk.Abort,
+	})
+	app.SetProp(app.App().GetTask("bad"), "Panic", true)
+
+	err := app.App().Run()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if got, want := err.Error(), "fwk: task [bad] failed to process event 0: panic: corrupted value 0"; got != want {
+		t.Fatalf("invalid error.\ngot= %v\nwant=%v", got, want)
+	}
+}
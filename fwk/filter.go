@@ -0,0 +1,482 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go-hep.org/x/hep/fwk/utils/tarjan"
+)
+
+// SetFilterPassed records whether the current task accepted the event
+// processed by ctx.
+// Tasks accept all events by default.
+//
+// SetFilterPassed is a no-op for contexts which were not created by
+// the fwk application, such as contexts created in tests.
+func SetFilterPassed(ctx Context, pass bool) {
+	c, ok := ctx.(filterContext)
+	if !ok {
+		return
+	}
+	c.setFilterPassed(pass)
+}
+
+// filterContext is the interface of contexts holding the filter
+// decisions of the event being processed.
+type filterContext interface {
+	setFilterPassed(pass bool)
+	flow() (context.Context, *evtflow)
+}
+
+// decision is the filter decision of a task for a given event.
+type decision struct {
+	done chan struct{} // closed when the task has been processed or skipped
+	run  bool          // whether the task has been successfully executed
+	pass bool          // whether the task accepted the event
+	fail bool          // whether the task failed to process the event
+}
+
+// passed is the decision of tasks not taking part in the event loop,
+// such as the input stream.
+var passed = &decision{run: true, pass: true}
+
+// evtflow holds the filter decisions of all the tasks for a given event.
+type evtflow struct {
+	decs map[string]*decision
+
+	failed atomic.Bool // whether a task failed to process the event
+	skip   atomic.Bool // whether the processing of the event has been stopped
+}
+
+func newEvtFlow(tsks []Task) *evtflow {
+	evt := &evtflow{
+		decs: make(map[string]*decision, len(tsks)),
+	}
+	for _, tsk := range tsks {
+		evt.decs[tsk.Name()] = &decision{
+			done: make(chan struct{}),
+			pass: true,
+		}
+	}
+	return evt
+}
+
+// wait waits for the decision of the named task.
+func (evt *evtflow) wait(ctx context.Context, name string) (*decision, error) {
+	dec, ok := evt.decs[name]
+	if !ok {
+		return passed, nil
+	}
+	select {
+	case <-dec.done:
+		return dec, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// enabled returns whether the task described by flow should be executed.
+func (evt *evtflow) enabled(ctx context.Context, flow *taskflow) (bool, error) {
+	if flow == nil {
+		return !evt.skip.Load(), nil
+	}
+
+	all := func(gates []gate) (bool, error) {
+		for _, g := range gates {
+			dec, err := evt.wait(ctx, g.name)
+			if err != nil {
+				return false, err
+			}
+			if !g.eval(dec) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	ok, err := all(flow.deps)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	if len(flow.paths) > 0 {
+		ok = false
+		for _, gates := range flow.paths {
+			ok, err = all(gates)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				break
+			}
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	if len(flow.sel) > 0 {
+		ok = false
+		for _, g := range flow.sel {
+			ok, err = all([]gate{g})
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				break
+			}
+		}
+	}
+
+	return ok && !evt.skip.Load(), nil
+}
+
+type gateKind int
+
+const (
+	gateRun  gateKind = iota // the task must have been executed
+	gatePass                 // the task must have been executed and have accepted the event
+	gateFail                 // the task must have been executed and have rejected the event
+	gateDone                 // the task must have been processed or skipped
+)
+
+// gate is a condition on the decision of a task.
+type gate struct {
+	name string
+	kind gateKind
+}
+
+func (g gate) eval(dec *decision) bool {
+	if g.kind == gateDone {
+		return true
+	}
+	if !dec.run {
+		return false
+	}
+	switch g.kind {
+	case gatePass:
+		return dec.pass
+	case gateFail:
+		return !dec.pass
+	}
+	return true
+}
+
+// taskflow describes the conditions under which a task is executed.
+type taskflow struct {
+	policy ErrorPolicy // error policy of the task
+
+	deps  []gate   // producers of the inputs of the task. all must have been executed.
+	paths [][]gate // sets of conditions from the sequences holding the task. one set must be fulfilled.
+	sel   []gate   // paths selected by an output stream. one must have accepted the event.
+}
+
+func (flow *taskflow) names() []string {
+	var names []string
+	for _, g := range flow.deps {
+		names = append(names, g.name)
+	}
+	for _, gates := range flow.paths {
+		for _, g := range gates {
+			names = append(names, g.name)
+		}
+	}
+	for _, g := range flow.sel {
+		names = append(names, g.name)
+	}
+	return names
+}
+
+// ctrlflow models the control flow of an application: which tasks
+// should be executed for a given event, according to the decisions of
+// filters and sequences.
+type ctrlflow struct {
+	tsks  map[string]*taskflow
+	seqs  []*Sequence
+	order []string // names of tasks, in application order
+
+	mu    sync.Mutex
+	nevts int64
+	stats map[string]*cutstat
+}
+
+// cutstat holds the number of times a task has been executed and has
+// accepted events.
+type cutstat struct {
+	run  int64
+	pass int64
+}
+
+type membership struct {
+	seq *Sequence
+	idx int
+}
+
+func newCtrlFlow(app *appmgr) (*ctrlflow, error) {
+	flow := &ctrlflow{
+		tsks:  make(map[string]*taskflow, len(app.tsks)),
+		order: make([]string, 0, len(app.tsks)),
+		stats: make(map[string]*cutstat, len(app.tsks)),
+	}
+
+	parents := make(map[string][]membership)
+	for _, tsk := range app.tsks {
+		flow.order = append(flow.order, tsk.Name())
+		flow.stats[tsk.Name()] = &cutstat{}
+
+		seq, ok := tsk.(*Sequence)
+		if !ok {
+			continue
+		}
+		flow.seqs = append(flow.seqs, seq)
+		for i, m := range seq.members {
+			name, _ := seq.member(m)
+			if !app.HasTask(name) {
+				return nil, fmt.Errorf("fwk: sequence [%s] has an unknown member [%s]", seq.Name(), name)
+			}
+			parents[name] = append(parents[name], membership{seq, i})
+		}
+	}
+
+	producers := make(map[string]string)
+	for name, node := range app.dflow.nodes {
+		for k := range node.out {
+			producers[k] = name
+		}
+	}
+
+	paths := make(map[string][][]gate)
+	visiting := make(map[string]bool)
+	var alts func(name string) ([][]gate, error)
+	alts = func(name string) ([][]gate, error) {
+		if v, ok := paths[name]; ok {
+			return v, nil
+		}
+		if visiting[name] {
+			return nil, fmt.Errorf("fwk: sequence [%s] contains itself", name)
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		var out [][]gate
+		for _, p := range parents[name] {
+			var gates []gate
+			for _, m := range p.seq.members[:p.idx] {
+				mname, neg := p.seq.member(m)
+				kind := gatePass
+				if neg != (p.seq.mode == "OR") {
+					kind = gateFail
+				}
+				gates = append(gates, gate{name: mname, kind: kind})
+			}
+
+			palts, err := alts(p.seq.Name())
+			if err != nil {
+				return nil, err
+			}
+			if len(palts) == 0 {
+				out = append(out, gates)
+				continue
+			}
+			for _, pgates := range palts {
+				v := make([]gate, 0, len(gates)+len(pgates))
+				v = append(v, gates...)
+				v = append(v, pgates...)
+				out = append(out, v)
+			}
+		}
+		paths[name] = out
+		return out, nil
+	}
+
+	for _, tsk := range app.tsks {
+		name := tsk.Name()
+		tflow := &taskflow{policy: app.errpolicy(name)}
+
+		if node, ok := app.dflow.nodes[name]; ok {
+			keys := make([]string, 0, len(node.in))
+			for k := range node.in {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if p, ok := producers[k]; ok && p != name {
+					tflow.deps = append(tflow.deps, gate{name: p, kind: gateRun})
+				}
+			}
+		}
+
+		v, err := alts(name)
+		if err != nil {
+			return nil, err
+		}
+		tflow.paths = v
+
+		if o, ok := tsk.(*OutputStream); ok {
+			for _, path := range o.paths {
+				seq, ok := app.GetTask(path).(*Sequence)
+				if !ok || seq == nil {
+					return nil, fmt.Errorf("fwk: output stream [%s] selects an unknown path [%s]", name, path)
+				}
+				tflow.sel = append(tflow.sel, gate{name: path, kind: gatePass})
+			}
+
+			// events are written out once all the tasks which may
+			// stop the processing of these events are done.
+			for _, t := range app.tsks {
+				if _, ok := t.(*OutputStream); ok || app.errpolicy(t.Name()) != SkipEvent {
+					continue
+				}
+				tflow.deps = append(tflow.deps, gate{name: t.Name(), kind: gateDone})
+			}
+		}
+
+		flow.tsks[name] = tflow
+	}
+
+	// detect cycles between data-flow and control-flow dependencies.
+	graph := make(map[any][]any, len(flow.order))
+	for _, name := range flow.order {
+		graph[name] = []any{}
+		for _, dep := range flow.tsks[name].names() {
+			graph[name] = append(graph[name], dep)
+		}
+	}
+	for _, seq := range flow.seqs {
+		for _, m := range seq.members {
+			name, _ := seq.member(m)
+			graph[seq.Name()] = append(graph[seq.Name()], name)
+		}
+	}
+
+	for _, cycle := range tarjan.Connections(graph) {
+		if len(cycle) > 1 {
+			names := make([]string, len(cycle))
+			for i, v := range cycle {
+				names[i] = v.(string)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("fwk: cycle detected in control flow: %v", names)
+		}
+	}
+
+	return flow, nil
+}
+
+// record accumulates the decisions of a fully processed event.
+func (flow *ctrlflow) record(evt *evtflow) {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+
+	flow.nevts++
+	for name, dec := range evt.decs {
+		st, ok := flow.stats[name]
+		if !ok {
+			continue
+		}
+		if dec.run {
+			st.run++
+			if dec.pass {
+				st.pass++
+			}
+		}
+	}
+}
+
+// filtering returns whether any task rejected any event.
+func (flow *ctrlflow) filtering() bool {
+	if len(flow.seqs) > 0 {
+		return true
+	}
+	for _, st := range flow.stats {
+		if st.pass != st.run {
+			return true
+		}
+	}
+	return false
+}
+
+// summary returns the cut-flow summary of the application.
+func (flow *ctrlflow) summary() string {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+
+	var (
+		o     strings.Builder
+		width = 0
+		seen  = make(map[string]bool)
+		inseq = make(map[string]bool)
+	)
+	for _, seq := range flow.seqs {
+		for _, m := range seq.members {
+			name, _ := seq.member(m)
+			inseq[name] = true
+		}
+	}
+	for _, name := range flow.order {
+		width = max(width, len(name)+1+2*len(flow.seqs))
+	}
+
+	line := func(depth int, label, name string) {
+		st := flow.stats[name]
+		pass := st.pass
+		if strings.HasPrefix(label, "!") {
+			pass = st.run - st.pass
+		}
+		eff := 0.0
+		if st.run > 0 {
+			eff = 100 * float64(pass) / float64(st.run)
+		}
+		fmt.Fprintf(&o, "%*s%-*s run=%8d pass=%8d (%6.2f%%)\n",
+			2*depth, "", width-2*depth, label, st.run, pass, eff,
+		)
+	}
+
+	var walk func(depth int, seq *Sequence)
+	walk = func(depth int, seq *Sequence) {
+		line(depth, seq.Name(), seq.Name())
+		seen[seq.Name()] = true
+		for _, m := range seq.members {
+			name, _ := seq.member(m)
+			if sub, ok := flow.find(name); ok && !seen[name] {
+				walk(depth+1, sub)
+				continue
+			}
+			line(depth+1, m, name)
+			seen[name] = true
+		}
+	}
+
+	fmt.Fprintf(&o, "cut-flow summary (nevts=%d):\n", flow.nevts)
+	for _, seq := range flow.seqs {
+		if inseq[seq.Name()] {
+			continue
+		}
+		walk(0, seq)
+	}
+	for _, name := range flow.order {
+		st := flow.stats[name]
+		if seen[name] || st.pass == st.run {
+			continue
+		}
+		line(0, name, name)
+	}
+	return o.String()
+}
+
+func (flow *ctrlflow) find(name string) (*Sequence, bool) {
+	for _, seq := range flow.seqs {
+		if seq.Name() == name {
+			return seq, true
+		}
+	}
+	return nil, false
+}
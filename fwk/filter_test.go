@@ -0,0 +1,433 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk_test
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/internal/fwktest"
+	"go-hep.org/x/hep/fwk/job"
+)
+
+func getsumsqIf(n int64, sel func(i int64) bool) int64 {
+	sum := int64(0)
+	for i := range n {
+		if sel(i) {
+			sum += i * i
+		}
+	}
+	return sum
+}
+
+func TestSequences(t *testing.T) {
+	const max = 100
+	for _, nprocs := range []int{0, 1, 2, 4} {
+		t.Run(fmt.Sprintf("nprocs=%d", nprocs), func(t *testing.T) {
+			app := newapp(-1, nprocs)
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.InputStream",
+				Name: "input",
+				Props: job.P{
+					"Ports": []fwk.Port{
+						{
+							Name: "ints",
+							Type: reflect.TypeOf(int64(1)),
+						},
+					},
+					"Streamer": &fwktest.InputStream{
+						R: newTestReader(max),
+					},
+				},
+			})
+
+			for _, mod := range []int64{2, 3} {
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk/internal/fwktest.filter",
+					Name: fmt.Sprintf("f%d", mod),
+					Props: job.P{
+						"Input":  "ints",
+						"Modulo": mod,
+					},
+				})
+			}
+
+			for _, tc := range []struct {
+				name    string
+				mode    string
+				members []string
+				sel     func(i int64) bool
+			}{
+				{
+					name:    "evens",
+					members: []string{"f2"},
+					sel:     func(i int64) bool { return i%2 == 0 },
+				},
+				{
+					name:    "odds",
+					members: []string{"!f2"},
+					sel:     func(i int64) bool { return i%2 != 0 },
+				},
+				{
+					name:    "and",
+					mode:    "AND",
+					members: []string{"f2", "f3"},
+					sel:     func(i int64) bool { return i%2 == 0 && i%3 == 0 },
+				},
+				{
+					name:    "or",
+					mode:    "OR",
+					members: []string{"f2", "f3"},
+					sel:     func(i int64) bool { return i%2 == 0 || i%3 == 0 },
+				},
+				{
+					name:    "not-or",
+					mode:    "OR",
+					members: []string{"!f2", "!f3"},
+					sel:     func(i int64) bool { return i%2 != 0 || i%3 != 0 },
+				},
+			} {
+				filter := tc.name + "-filter"
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk.Sequence",
+					Name: filter,
+					Props: job.P{
+						"Members": tc.members,
+						"Mode":    cmp.Or(tc.mode, "AND"),
+					},
+				})
+
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+					Name: tc.name + "-sq",
+					Props: job.P{
+						"Input":  "ints",
+						"Output": tc.name + "-ints",
+					},
+				})
+
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk/internal/fwktest.reducer",
+					Name: tc.name + "-reducer",
+					Props: job.P{
+						"Input": tc.name + "-ints",
+						"Sum":   getsumsqIf(max, tc.sel),
+					},
+				})
+
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk.Sequence",
+					Name: tc.name,
+					Props: job.P{
+						"Members": []string{filter, tc.name + "-sq", tc.name + "-reducer"},
+					},
+				})
+			}
+
+			err := app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run application: %+v", err)
+			}
+		})
+	}
+}
+
+func TestSequenceDataFlow(t *testing.T) {
+	const max = 100
+	for _, nprocs := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("nprocs=%d", nprocs), func(t *testing.T) {
+			app := newapp(-1, nprocs)
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.InputStream",
+				Name: "input",
+				Props: job.P{
+					"Ports": []fwk.Port{
+						{
+							Name: "ints",
+							Type: reflect.TypeOf(int64(1)),
+						},
+					},
+					"Streamer": &fwktest.InputStream{
+						R: newTestReader(max),
+					},
+				},
+			})
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/internal/fwktest.filter",
+				Name: "f5",
+				Props: job.P{
+					"Input":  "ints",
+					"Modulo": int64(5),
+				},
+			})
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+				Name: "sq",
+				Props: job.P{
+					"Input":  "ints",
+					"Output": "sq-ints",
+				},
+			})
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.Sequence",
+				Name: "path",
+				Props: job.P{
+					"Members": []string{"f5", "sq"},
+				},
+			})
+
+			// reducer is not part of any sequence but consumes the
+			// output of a task which is not executed for all events.
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/internal/fwktest.reducer",
+				Name: "reducer",
+				Props: job.P{
+					"Input": "sq-ints",
+					"Sum":   getsumsqIf(max, func(i int64) bool { return i%5 == 0 }),
+				},
+			})
+
+			err := app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run application: %+v", err)
+			}
+		})
+	}
+}
+
+func TestOutputStreamPaths(t *testing.T) {
+	const max = 100
+	for _, nprocs := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("nprocs=%d", nprocs), func(t *testing.T) {
+			app := newapp(-1, nprocs)
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.InputStream",
+				Name: "input",
+				Props: job.P{
+					"Ports": []fwk.Port{
+						{
+							Name: "ints",
+							Type: reflect.TypeOf(int64(1)),
+						},
+					},
+					"Streamer": &fwktest.InputStream{
+						R: newTestReader(max),
+					},
+				},
+			})
+
+			for _, mod := range []int64{3, 7} {
+				name := fmt.Sprintf("f%d", mod)
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk/internal/fwktest.filter",
+					Name: name,
+					Props: job.P{
+						"Input":  "ints",
+						"Modulo": mod,
+					},
+				})
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk.Sequence",
+					Name: "path-" + name,
+					Props: job.P{
+						"Members": []string{name},
+					},
+				})
+			}
+
+			var (
+				all = new(bytes.Buffer)
+				sel = new(bytes.Buffer)
+			)
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.OutputStream",
+				Name: "output-all",
+				Props: job.P{
+					"Ports": []fwk.Port{
+						{
+							Name: "ints",
+							Type: reflect.TypeOf(int64(1)),
+						},
+					},
+					"Streamer": &fwktest.OutputStream{W: all},
+				},
+			})
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.OutputStream",
+				Name: "output-sel",
+				Props: job.P{
+					"Ports": []fwk.Port{
+						{
+							Name: "ints",
+							Type: reflect.TypeOf(int64(1)),
+						},
+					},
+					"Streamer": &fwktest.OutputStream{W: sel},
+					"Paths":    []string{"path-f3", "path-f7"},
+				},
+			})
+
+			err := app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run application: %+v", err)
+			}
+
+			if got, want := len(strings.Fields(all.String())), max; got != want {
+				t.Fatalf("invalid number of events written: got=%d, want=%d", got, want)
+			}
+
+			want := 0
+			for i := range max {
+				if i%3 == 0 || i%7 == 0 {
+					want++
+				}
+			}
+			vs := strings.Fields(sel.String())
+			if got := len(vs); got != want {
+				t.Fatalf("invalid number of selected events written: got=%d, want=%d", got, want)
+			}
+			for _, v := range vs {
+				var i int
+				_, err := fmt.Sscanf(v, "%d", &i)
+				if err != nil {
+					t.Fatalf("could not parse value %q: %+v", v, err)
+				}
+				if i%3 != 0 && i%7 != 0 {
+					t.Fatalf("event %d should have been filtered out", i)
+				}
+			}
+		})
+	}
+}
+
+func TestSequenceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		seqs  []job.C
+		paths []string
+		want  string
+	}{
+		{
+			name: "unknown-member",
+			seqs: []job.C{{
+				Name:  "seq",
+				Props: job.P{"Members": []string{"f2", "not-there"}},
+			}},
+			want: "fwk: sequence [seq] has an unknown member [not-there]",
+		},
+		{
+			name: "invalid-mode",
+			seqs: []job.C{{
+				Name:  "seq",
+				Props: job.P{"Members": []string{"f2"}, "Mode": "XOR"},
+			}},
+			want: `fwk: sequence [seq] has an invalid mode "XOR" (want AND or OR)`,
+		},
+		{
+			name: "cycle",
+			seqs: []job.C{
+				{
+					Name:  "seq1",
+					Props: job.P{"Members": []string{"f2", "seq2"}},
+				},
+				{
+					Name:  "seq2",
+					Props: job.P{"Members": []string{"seq1"}},
+				},
+			},
+			want: "fwk: sequence [seq1] contains itself",
+		},
+		{
+			name:  "unknown-path",
+			paths: []string{"f2"},
+			want:  "fwk: output stream [output] selects an unknown path [f2]",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			app := newapp(1, 0)
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/internal/fwktest.task1",
+				Name: "t1",
+				Props: job.P{
+					"Ints1": "ints",
+					"Ints2": "ints2",
+				},
+			})
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/internal/fwktest.filter",
+				Name: "f2",
+				Props: job.P{
+					"Input":  "ints",
+					"Modulo": int64(2),
+				},
+			})
+			for _, seq := range tc.seqs {
+				seq.Type = "go-hep.org/x/hep/fwk.Sequence"
+				app.Create(seq)
+			}
+			if tc.paths != nil {
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk.OutputStream",
+					Name: "output",
+					Props: job.P{
+						"Ports": []fwk.Port{
+							{
+								Name: "ints",
+								Type: reflect.TypeOf(int64(1)),
+							},
+						},
+						"Streamer": &fwktest.OutputStream{W: new(bytes.Buffer)},
+						"Paths":    tc.paths,
+					},
+				})
+			}
+
+			err := app.App().Run()
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if got, want := err.Error(), tc.want; got != want {
+				t.Fatalf("invalid error.\ngot= %v\nwant=%v", got, want)
+			}
+		})
+	}
+}
+
+// userContext is a fwk.Context implemented outside of fwk.
+type userContext struct {
+	fwk.Context
+}
+
+func TestFilterForeignContext(t *testing.T) {
+	app := newapp(-1, 0)
+	seq := app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.Sequence",
+		Name: "seq",
+		Props: job.P{
+			"Members": []string{"f2"},
+		},
+	}).(*fwk.Sequence)
+
+	ctx := userContext{}
+
+	// filter decisions of contexts not created by fwk are ignored.
+	fwk.SetFilterPassed(ctx, false)
+
+	err := seq.Process(ctx)
+	if err != nil {
+		t.Fatalf("could not process sequence with a foreign context: %+v", err)
+	}
+}
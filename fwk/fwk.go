@@ -86,4 +86,64 @@
 //
 //	   return err
 //	}
+//
+// Tasks can act as filters and veto events, by recording their decision
+// for the current event:
+//
+//	func (tsk *MyFilter) Process(ctx fwk.Context) error {
+//	   // ...
+//	   fwk.SetFilterPassed(ctx, len(eles) >= 2)
+//	   return nil
+//	}
+//
+// Filters are combined into named sequences (or paths) with fwk.Sequence,
+// which evaluates the logical AND (or OR) of the (possibly negated)
+// decisions of its members.
+// Members of a sequence following a filter which vetoed an event are not
+// executed for that event, and neither are the tasks consuming their outputs.
+// OutputStreams can select the paths whose accepted events are written out.
+// A cut-flow summary is printed when the application stops.
+//
+// Setting the application property "EvtSlots" to a non-zero value enables
+// the task scheduler: up to "EvtSlots" events are kept in flight and every
+// task whose inputs are available is dispatched onto a shared pool of
+// "NProcs" goroutines, whatever event it belongs to.
+// Tasks declare how they may be executed by implementing fwk.ConcurrentTask:
+//   - fwk.ThreadSafe tasks (the default) process many events concurrently;
+//   - fwk.Clonable tasks are cloned, each clone processing one event at a time;
+//   - fwk.SingleInstance tasks process one event at a time.
+//
+// The number of clones of a task can be tuned with the "Cardinality"
+// application property.
+//
+// By default, the first error returned (or panic raised) by a task aborts
+// the application.
+// The "ErrorPolicy" application property (and its per-task counterpart,
+// "ErrorPolicies") may instead let the application skip the failed event
+// (fwk.SkipEvent) or only the tasks depending on the failed task
+// (fwk.Continue).
+// Errors are then recorded, together with the ID of the failed event, and
+// the application is aborted once more than "MaxFailures" events failed.
+//
+// Services implementing fwk.MonSvc are notified at the boundaries of each
+// event and around the processing of each task, for example to monitor
+// the performances of the application (see go-hep.org/x/hep/fwk/perfsvc).
+//
+// Input streamers implementing fwk.IncidentSource report incidents, such as
+// run and file boundaries, to the tasks implementing fwk.IncidentHandler
+// (see go-hep.org/x/hep/fwk/rio).
+// With a fwk.IncidentSvc, any component may subscribe to incidents of a
+// given type or fire its own incidents (see go-hep.org/x/hep/fwk/incsvc).
+//
+// Setting the application property "NWorkers" to a non-zero value runs the
+// application in as many worker processes, spawned from the same executable
+// with the same arguments.
+// Every worker reads all the input events but only processes ranges of
+// "EvtRange" consecutive events, in turn.
+// Components writing output files name them with fwk.OutputFile, so each
+// worker writes its own files, and implement fwk.OutputMerger to merge
+// these files once all the workers are done (see go-hep.org/x/hep/fwk/hbooksvc
+// and go-hep.org/x/hep/fwk/rio).
+// Worker processes are independent: tasks need not be safe for concurrent
+// use, and results which are not written out are not merged.
 package fwk // import "go-hep.org/x/hep/fwk"
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 
@@ -89,9 +90,10 @@ func (svc *hsvc) StartSvc(ctx fwk.Context) error {
 				return fmt.Errorf("%s: duplicate write-stream %q", svc.Name(), name)
 			}
 			// FIXME(sbinet): handle remote/local files + protocols
-			f, err := os.Create(stream.Name)
+			fname := fwk.OutputFile(stream.Name)
+			f, err := os.Create(fname)
 			if err != nil {
-				return fmt.Errorf("error creating file [%s]: %w", stream.Name, err)
+				return fmt.Errorf("error creating file [%s]: %w", fname, err)
 			}
 			w, err := rio.NewWriter(f)
 			if err != nil {
@@ -100,7 +102,7 @@ func (svc *hsvc) StartSvc(ctx fwk.Context) error {
 
 			svc.w[name] = ostream{
 				name:  name,
-				fname: stream.Name,
+				fname: fname,
 				f:     f,
 				w:     w,
 			}
@@ -148,6 +150,199 @@ func (svc *hsvc) StopSvc(ctx fwk.Context) error {
 	return err
 }
 
+// MergeOutputs merges the histograms written by the worker processes of
+// a multi-process application into the write-streams of the service.
+// Histograms and profiles are summed, scatters are concatenated.
+func (svc *hsvc) MergeOutputs(ctx fwk.Context, nworkers int) error {
+	names := make([]string, 0, len(svc.streams))
+	for name, stream := range svc.streams {
+		if stream.Mode != Write {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := svc.merge(name, svc.streams[name].Name, nworkers)
+		if err != nil {
+			return fmt.Errorf("%s: could not merge stream %q: %w", svc.Name(), name, err)
+		}
+	}
+	return nil
+}
+
+// merge merges the files written by the nworkers worker processes into
+// the file fname and removes them.
+func (svc *hsvc) merge(name, fname string, nworkers int) error {
+	var (
+		keys  []string
+		hists = make(map[string]fwk.Hist)
+		files = make([]string, nworkers)
+	)
+
+	for i := range files {
+		files[i] = fwk.WorkerFile(fname, i)
+		objs, err := readHists(files[i])
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			key := obj.Name()
+			old, dup := hists[key]
+			if !dup {
+				keys = append(keys, key)
+				hists[key] = obj
+				continue
+			}
+			hists[key], err = mergeHist(old, obj)
+			if err != nil {
+				return fmt.Errorf("could not merge %q from %q: %w", key, files[i], err)
+			}
+		}
+	}
+
+	f, err := os.Create(fname)
+	if err != nil {
+		return fmt.Errorf("error creating file [%s]: %w", fname, err)
+	}
+	defer f.Close()
+
+	w, err := rio.NewWriter(f)
+	if err != nil {
+		return fmt.Errorf("error creating rio-stream [%s]: %w", fname, err)
+	}
+
+	stream := ostream{
+		name:  name,
+		fname: fname,
+		f:     f,
+		w:     w,
+		objs:  make([]fwk.Hist, len(keys)),
+	}
+	for i, key := range keys {
+		stream.objs[i] = hists[key]
+	}
+
+	err = stream.write()
+	if err != nil {
+		return err
+	}
+
+	err = stream.close()
+	if err != nil {
+		return err
+	}
+
+	for _, fname := range files {
+		err = os.Remove(fname)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readHists reads all the histograms stored in the named rio file.
+func readHists(fname string) ([]fwk.Hist, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := rio.Open(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not open rio file [%s]: %w", fname, err)
+	}
+	defer r.Close()
+
+	var objs []fwk.Hist
+	for _, key := range r.Keys() {
+		if len(key.Blocks) != 1 {
+			return nil, fmt.Errorf("invalid record [%s] in file [%s]", key.Name, fname)
+		}
+
+		var (
+			id  = fwk.HID(key.Name)
+			obj fwk.Hist
+			ptr any
+		)
+		switch typ := key.Blocks[0].Type; typ {
+		case "*go-hep.org/x/hep/hbook.H1D":
+			h := fwk.H1D{ID: id, Hist: new(hbook.H1D)}
+			obj, ptr = h, h.Hist
+		case "*go-hep.org/x/hep/hbook.H2D":
+			h := fwk.H2D{ID: id, Hist: new(hbook.H2D)}
+			obj, ptr = h, h.Hist
+		case "*go-hep.org/x/hep/hbook.P1D":
+			h := fwk.P1D{ID: id, Profile: new(hbook.P1D)}
+			obj, ptr = h, h.Profile
+		case "*go-hep.org/x/hep/hbook.S2D":
+			h := fwk.S2D{ID: id, Scatter: new(hbook.S2D)}
+			obj, ptr = h, h.Scatter
+		default:
+			return nil, fmt.Errorf("invalid type %q for record [%s] in file [%s]", typ, key.Name, fname)
+		}
+
+		err = r.Get(key.Name, ptr)
+		if err != nil {
+			return nil, fmt.Errorf("could not read record [%s] from file [%s]: %w", key.Name, fname, err)
+		}
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// mergeHist merges the histograms h1 and h2.
+func mergeHist(h1, h2 fwk.Hist) (o fwk.Hist, err error) {
+	defer func() {
+		e := recover()
+		if e == nil {
+			return
+		}
+		switch e := e.(type) {
+		case error:
+			err = e
+		default:
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+
+	switch h1 := h1.(type) {
+	case fwk.H1D:
+		h2, ok := h2.(fwk.H1D)
+		if !ok {
+			break
+		}
+		h1.Hist = hbook.AddH1D(h1.Hist, h2.Hist)
+		return h1, nil
+	case fwk.H2D:
+		h2, ok := h2.(fwk.H2D)
+		if !ok {
+			break
+		}
+		h1.Hist = hbook.AddH2D(h1.Hist, h2.Hist)
+		return h1, nil
+	case fwk.P1D:
+		h2, ok := h2.(fwk.P1D)
+		if !ok {
+			break
+		}
+		h1.Profile = hbook.AddP1D(h1.Profile, h2.Profile)
+		return h1, nil
+	case fwk.S2D:
+		h2, ok := h2.(fwk.S2D)
+		if !ok {
+			break
+		}
+		h1.Scatter.Fill(h2.Scatter.Points()...)
+		return h1, nil
+	}
+	return nil, fmt.Errorf("type mismatch (%T, %T)", h1.Value(), h2.Value())
+}
+
 func (svc *hsvc) BookH1D(name string, nbins int, low, high float64) (fwk.H1D, error) {
 	var err error
 	var h fwk.H1D
@@ -454,4 +649,7 @@ func init() {
 	fwk.Register(reflect.TypeOf(hsvc{}), newhsvc)
 }
 
-var _ fwk.HistSvc = (*hsvc)(nil)
+var (
+	_ fwk.HistSvc      = (*hsvc)(nil)
+	_ fwk.OutputMerger = (*hsvc)(nil)
+)
@@ -13,6 +13,7 @@ import (
 
 	"go-hep.org/x/hep/fwk"
 	"go-hep.org/x/hep/fwk/job"
+	"go-hep.org/x/hep/hbook"
 )
 
 const (
@@ -229,3 +230,132 @@ func newtesthsvc(typ, name string, mgr fwk.App) (fwk.Component, error) {
 func init() {
 	fwk.Register(reflect.TypeOf(testhsvc{}), newtesthsvc)
 }
+
+func TestHbookSvcMerge(t *testing.T) {
+	const (
+		fname    = "hist-merge.rio"
+		nworkers = 2
+	)
+
+	for i := range nworkers {
+		app := newapp(nentries, 0)
+		app.Create(job.C{
+			Type: "go-hep.org/x/hep/fwk/hbooksvc.testhsvc",
+			Name: "t000",
+			Props: job.P{
+				"Stream": "/my-hist",
+			},
+		})
+		app.Create(job.C{
+			Type: "go-hep.org/x/hep/fwk/hbooksvc.hsvc",
+			Name: "histsvc",
+			Props: job.P{
+				"Streams": map[string]Stream{
+					"/my-hist": {
+						Name: fwk.WorkerFile(fname, i),
+						Mode: Write,
+					},
+				},
+			},
+		})
+		err := app.App().Run()
+		if err != nil {
+			t.Fatalf("could not run worker %d: %+v", i, err)
+		}
+	}
+	defer os.Remove(fname)
+
+	app := newapp(nentries, 0)
+	svc := app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/hbooksvc.hsvc",
+		Name: "histsvc",
+		Props: job.P{
+			"Streams": map[string]Stream{
+				"/my-hist": {
+					Name: fname,
+					Mode: Write,
+				},
+			},
+		},
+	}).(fwk.OutputMerger)
+
+	err := svc.MergeOutputs(nil, nworkers)
+	if err != nil {
+		t.Fatalf("could not merge outputs: %+v", err)
+	}
+
+	for i := range nworkers {
+		_, err := os.Stat(fwk.WorkerFile(fname, i))
+		if !os.IsNotExist(err) {
+			t.Fatalf("output of worker %d was not removed: %v", i, err)
+		}
+	}
+
+	hists, err := readHists(fname)
+	if err != nil {
+		t.Fatalf("could not read merged histograms: %+v", err)
+	}
+	if got, want := len(hists), 1; got != want {
+		t.Fatalf("invalid number of histograms: got=%d, want=%d", got, want)
+	}
+	h := hists[0].(fwk.H1D)
+	if got, want := h.Name(), "h1d-t000"; got != want {
+		t.Fatalf("invalid histogram name: got=%q, want=%q", got, want)
+	}
+	if got, want := h.Hist.Entries(), int64(nworkers*nentries); got != want {
+		t.Fatalf("invalid number of entries: got=%d, want=%d", got, want)
+	}
+	if got, want := h.Hist.XMean(), 49.5; got != want {
+		t.Fatalf("invalid mean: got=%v, want=%v", got, want)
+	}
+}
+
+func TestHbookMergeHist(t *testing.T) {
+	h2 := func(x float64) fwk.H2D {
+		h := fwk.H2D{ID: "h2", Hist: hbook.NewH2D(10, 0, 10, 10, 0, 10)}
+		h.Hist.Fill(x, x, 1)
+		return h
+	}
+	p1 := func(x float64) fwk.P1D {
+		p := fwk.P1D{ID: "p1", Profile: hbook.NewP1D(10, 0, 10)}
+		p.Profile.Fill(x, x, 1)
+		return p
+	}
+	s2 := func(x float64) fwk.S2D {
+		return fwk.S2D{ID: "s2", Scatter: hbook.NewS2D(hbook.Point2D{X: x, Y: x})}
+	}
+
+	o, err := mergeHist(h2(1), h2(2))
+	if err != nil {
+		t.Fatalf("could not merge H2D: %+v", err)
+	}
+	if got, want := o.(fwk.H2D).Hist.Entries(), int64(2); got != want {
+		t.Fatalf("invalid H2D entries: got=%d, want=%d", got, want)
+	}
+
+	o, err = mergeHist(p1(1), p1(2))
+	if err != nil {
+		t.Fatalf("could not merge P1D: %+v", err)
+	}
+	if got, want := o.(fwk.P1D).Profile.Entries(), int64(2); got != want {
+		t.Fatalf("invalid P1D entries: got=%d, want=%d", got, want)
+	}
+
+	o, err = mergeHist(s2(1), s2(2))
+	if err != nil {
+		t.Fatalf("could not merge S2D: %+v", err)
+	}
+	if got, want := o.(fwk.S2D).Scatter.Len(), 2; got != want {
+		t.Fatalf("invalid S2D points: got=%d, want=%d", got, want)
+	}
+
+	_, err = mergeHist(h2(1), p1(1))
+	if err == nil {
+		t.Fatalf("expected an error merging H2D and P1D")
+	}
+
+	_, err = mergeHist(h2(1), fwk.H2D{ID: "h2", Hist: hbook.NewH2D(5, 0, 10, 10, 0, 10)})
+	if err == nil {
+		t.Fatalf("expected an error merging H2D with different binnings")
+	}
+}
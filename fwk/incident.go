@@ -0,0 +1,135 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+import (
+	"fmt"
+)
+
+// Types of the incidents reported by input streams.
+const (
+	BeginRun  = "BeginRun"  // a new run starts with the next event
+	EndRun    = "EndRun"    // the current run ended with the previous event
+	BeginFile = "BeginFile" // a new input file has been opened
+	EndFile   = "EndFile"   // the current input file has been closed
+)
+
+// Incident describes an occurrence of interest during the processing of
+// the event stream, such as a run or a file boundary.
+type Incident struct {
+	Type   string // type of the incident (e.g. BeginRun)
+	Source string // name of the component which fired the incident
+	Run    int64  // run number, for run incidents
+	File   string // file name, for file incidents
+	Data   any    // payload of custom incidents
+}
+
+func (inc Incident) String() string {
+	switch inc.Type {
+	case BeginRun, EndRun:
+		return fmt.Sprintf("%s{src=%q, run=%d}", inc.Type, inc.Source, inc.Run)
+	case BeginFile, EndFile:
+		return fmt.Sprintf("%s{src=%q, file=%q}", inc.Type, inc.Source, inc.File)
+	}
+	return fmt.Sprintf("%s{src=%q}", inc.Type, inc.Source)
+}
+
+// IncidentHandler is implemented by tasks which want to be notified of
+// incidents.
+// Tasks implementing IncidentHandler are notified of all the incidents
+// reported by the input stream and, when an IncidentSvc is available,
+// of all the incidents fired through it.
+//
+// Incidents are delivered in the order the input stream reads the data:
+// BeginRun and BeginFile incidents are delivered before the first event
+// of the run or file is processed.
+// When events are processed concurrently (NProcs > 0), the incidents
+// reported by the input stream are only delivered once all the events read
+// before them have been processed: an EndRun handler sees every event of
+// its run.
+// Incidents fired by tasks through the IncidentSvc are delivered right
+// away: HandleIncident must then be safe to call concurrently with Process.
+type IncidentHandler interface {
+	HandleIncident(ctx Context, inc Incident) error
+}
+
+// IncidentSource is implemented by input streamers which report incidents
+// about the data they read, such as run or file boundaries.
+type IncidentSource interface {
+	// Incidents returns the incidents which occurred since the last call
+	// to Incidents.
+	Incidents() []Incident
+}
+
+// incHandler is an incident handler, with the context it is notified with.
+type incHandler struct {
+	ctx Context
+	h   IncidentHandler
+}
+
+// readEvent reads the next event from the input stream and notifies the
+// incident handlers of the incidents reported while reading it.
+// If incidents were reported, wait is first called to let the events
+// already in flight finish.
+func (app *appmgr) readEvent(ctx Context, wait func() error) error {
+	err := app.istream.Process(ctx)
+	if ierr := app.fireIncidents(ctx, wait); ierr != nil {
+		return ierr
+	}
+	return err
+}
+
+// subscribeIncidents subscribes the incident handlers to all the incidents
+// fired through the incident service, if any.
+func (app *appmgr) subscribeIncidents() error {
+	if app.isvc == nil {
+		return nil
+	}
+	for _, h := range app.incs {
+		err := app.isvc.Subscribe("", IncidentFunc(func(_ Context, inc Incident) error {
+			return h.h.HandleIncident(h.ctx, inc)
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fireIncidents notifies the incident handlers of the incidents reported
+// by the input stream, once wait, if any, has returned.
+func (app *appmgr) fireIncidents(ctx Context, wait func() error) error {
+	in, ok := app.istream.(*InputStream)
+	if !ok {
+		return nil
+	}
+	incs := in.incidents()
+	if len(incs) == 0 {
+		return nil
+	}
+	if wait != nil {
+		err := wait()
+		if err != nil {
+			return err
+		}
+	}
+	for _, inc := range incs {
+		app.msg.Debugf("incident %v\n", inc)
+		if app.isvc != nil {
+			err := app.isvc.Fire(ctx, inc)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for _, h := range app.incs {
+			err := h.h.HandleIncident(h.ctx, inc)
+			if err != nil {
+				return fmt.Errorf("fwk: could not handle incident %v: %w", inc, err)
+			}
+		}
+	}
+	return nil
+}
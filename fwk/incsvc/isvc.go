@@ -0,0 +1,136 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package incsvc provides a fwk.IncidentSvc dispatching incidents, such as
+// run and file boundaries or user-defined incidents, to the components
+// which subscribed to them.
+//
+// Components subscribe to incidents during their Configure or Start
+// stages:
+//
+//	func (tsk *MyTask) Configure(ctx fwk.Context) error {
+//		svc, err := ctx.Svc("incsvc")
+//		if err != nil {
+//			return err
+//		}
+//		tsk.isvc = svc.(fwk.IncidentSvc)
+//		return tsk.isvc.Subscribe(fwk.BeginRun, fwk.IncidentFunc(tsk.beginRun))
+//	}
+//
+// and may fire their own incidents during the event loop:
+//
+//	err := tsk.isvc.Fire(ctx, fwk.Incident{Type: "LumiBlock", Source: tsk.Name(), Data: lumi})
+package incsvc // import "go-hep.org/x/hep/fwk/incsvc"
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+type isvc struct {
+	fwk.SvcBase
+
+	trace bool // whether to log the fired incidents
+
+	mu    sync.RWMutex
+	subs  map[string][]fwk.IncidentHandler // handlers, by incident type
+	all   []fwk.IncidentHandler            // handlers of all the incidents
+	nincs map[string]int                   // number of fired incidents, by type
+}
+
+func (svc *isvc) Configure(ctx fwk.Context) error {
+	return nil
+}
+
+func (svc *isvc) StartSvc(ctx fwk.Context) error {
+	return nil
+}
+
+func (svc *isvc) StopSvc(ctx fwk.Context) error {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	types := make([]string, 0, len(svc.nincs))
+	for typ := range svc.nincs {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		ctx.Msg().Debugf("incidents %-12s fired %d times\n", typ, svc.nincs[typ])
+	}
+	return nil
+}
+
+// Subscribe registers h to be notified of the incidents of type typ.
+// An empty type subscribes h to all the incidents.
+func (svc *isvc) Subscribe(typ string, h fwk.IncidentHandler) error {
+	if h == nil {
+		return fmt.Errorf("%s: nil incident handler", svc.Name())
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if typ == "" {
+		svc.all = append(svc.all, h)
+		return nil
+	}
+	svc.subs[typ] = append(svc.subs[typ], h)
+	return nil
+}
+
+// Fire notifies the handlers subscribed to the type of inc, in their
+// order of subscription.
+// Handlers subscribed to all the incidents are notified last.
+func (svc *isvc) Fire(ctx fwk.Context, inc fwk.Incident) error {
+	if inc.Type == "" {
+		return fmt.Errorf("%s: incident with no type (source=%q)", svc.Name(), inc.Source)
+	}
+
+	svc.mu.Lock()
+	svc.nincs[inc.Type]++
+	subs := svc.subs[inc.Type]
+	all := svc.all
+	svc.mu.Unlock()
+
+	if svc.trace {
+		ctx.Msg().Infof("incident %v\n", inc)
+	}
+
+	for _, hs := range [][]fwk.IncidentHandler{subs, all} {
+		for _, h := range hs {
+			err := h.HandleIncident(ctx, inc)
+			if err != nil {
+				return fmt.Errorf("%s: could not handle incident %v: %w", svc.Name(), inc, err)
+			}
+		}
+	}
+	return nil
+}
+
+func newisvc(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+	svc := &isvc{
+		SvcBase: fwk.NewSvc(typ, name, mgr),
+		subs:    make(map[string][]fwk.IncidentHandler),
+		nincs:   make(map[string]int),
+	}
+
+	err = svc.DeclProp("Trace", &svc.trace)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(isvc{}), newisvc)
+}
+
+var _ fwk.IncidentSvc = (*isvc)(nil)
@@ -0,0 +1,343 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package incsvc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/job"
+)
+
+const lumiBlock = "LumiBlock"
+
+func TestIncidentSvc(t *testing.T) {
+	const (
+		nevts   = 10
+		nperrun = 4
+	)
+
+	for _, tc := range []struct {
+		nprocs int
+		nslots int
+	}{
+		{0, 0},
+		{4, 0},
+		{4, 4},
+	} {
+		t.Run(fmt.Sprintf("nprocs=%d-nslots=%d", tc.nprocs, tc.nslots), func(t *testing.T) {
+			app := job.NewJob(nil, job.P{
+				"EvtMax":   int64(nevts),
+				"NProcs":   tc.nprocs,
+				"EvtSlots": tc.nslots,
+				"MsgLevel": job.MsgLevel("ERROR"),
+			})
+
+			isvc := app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/incsvc.isvc",
+				Name: "incsvc",
+			}).(fwk.IncidentSvc)
+
+			lumi := app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/incsvc.lumisvc",
+				Name: "lumisvc",
+			}).(*lumisvc)
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.InputStream",
+				Name: "input",
+				Props: job.P{
+					"Ports": []fwk.Port{
+						{Name: "evt", Type: reflect.TypeOf(int64(0))},
+					},
+					"Streamer": &runStreamer{nperrun: nperrun},
+				},
+			})
+
+			booker := app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/incsvc.booker",
+				Name: "booker",
+			}).(*booker)
+
+			var (
+				mu    sync.Mutex
+				nincs = make(map[string]int)
+			)
+			err := isvc.Subscribe("", fwk.IncidentFunc(func(ctx fwk.Context, inc fwk.Incident) error {
+				mu.Lock()
+				defer mu.Unlock()
+				nincs[inc.Type]++
+				return nil
+			}))
+			if err != nil {
+				t.Fatalf("could not subscribe: %+v", err)
+			}
+
+			err = app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run job: %+v", err)
+			}
+
+			want := []string{
+				"BeginFile", "BeginRun(0)",
+				"EndRun(0)", "BeginRun(1)",
+				"EndRun(1)", "BeginRun(2)",
+				"EndRun(2)", "EndFile",
+			}
+			if got := booker.incs; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid incidents:\ngot= %q\nwant=%q", got, want)
+			}
+			if got, want := booker.nlumi, nevts; got != want {
+				t.Fatalf("invalid number of lumi-blocks seen by booker: got=%d, want=%d", got, want)
+			}
+
+			if got, want := lumi.runs, []int64{0, 1, 2}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid runs: got=%v, want=%v", got, want)
+			}
+			if got, want := lumi.lumi, map[int64]float64{0: 4, 1: 4, 2: 2}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid luminosity: got=%v, want=%v", got, want)
+			}
+
+			wantIncs := map[string]int{
+				fwk.BeginFile: 1, fwk.EndFile: 1,
+				fwk.BeginRun: 3, fwk.EndRun: 3,
+				lumiBlock: nevts,
+			}
+			if !reflect.DeepEqual(nincs, wantIncs) {
+				t.Fatalf("invalid incidents:\ngot= %v\nwant=%v", nincs, wantIncs)
+			}
+		})
+	}
+}
+
+func TestIncidentSvcErrors(t *testing.T) {
+	app := job.NewJob(nil, job.P{
+		"EvtMax":   int64(10),
+		"NProcs":   0,
+		"MsgLevel": job.MsgLevel("ERROR"),
+	})
+
+	isvc := app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/incsvc.isvc",
+		Name: "incsvc",
+	}).(fwk.IncidentSvc)
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.InputStream",
+		Name: "input",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{Name: "evt", Type: reflect.TypeOf(int64(0))},
+			},
+			"Streamer": &runStreamer{nperrun: 4},
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/incsvc.booker",
+		Name: "booker",
+	})
+
+	err := isvc.Subscribe(fwk.BeginRun, nil)
+	if err == nil {
+		t.Fatalf("expected an error subscribing a nil handler")
+	}
+
+	err = isvc.Fire(nil, fwk.Incident{Source: "test"})
+	if err == nil {
+		t.Fatalf("expected an error firing an incident without type")
+	}
+
+	err = isvc.Subscribe(fwk.BeginRun, fwk.IncidentFunc(func(ctx fwk.Context, inc fwk.Incident) error {
+		if inc.Run == 1 {
+			return fmt.Errorf("no run 1")
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("could not subscribe: %+v", err)
+	}
+
+	err = app.App().Run()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if want := "no run 1"; !strings.Contains(err.Error(), want) {
+		t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, want)
+	}
+}
+
+// runStreamer produces events in runs of nperrun events.
+type runStreamer struct {
+	nperrun int64
+
+	ievt int64
+	incs []fwk.Incident
+}
+
+func (s *runStreamer) Connect(ports []fwk.Port) error {
+	s.incs = append(s.incs, fwk.Incident{Type: fwk.BeginFile, File: "file"})
+	return nil
+}
+
+func (s *runStreamer) Read(ctx fwk.Context) error {
+	if s.ievt%s.nperrun == 0 {
+		run := s.ievt / s.nperrun
+		if run > 0 {
+			s.incs = append(s.incs, fwk.Incident{Type: fwk.EndRun, Run: run - 1})
+		}
+		s.incs = append(s.incs, fwk.Incident{Type: fwk.BeginRun, Run: run})
+	}
+	err := ctx.Store().Put("evt", s.ievt)
+	s.ievt++
+	return err
+}
+
+func (s *runStreamer) Disconnect() error {
+	s.incs = append(s.incs,
+		fwk.Incident{Type: fwk.EndRun, Run: (s.ievt - 1) / s.nperrun},
+		fwk.Incident{Type: fwk.EndFile, File: "file"},
+	)
+	return nil
+}
+
+func (s *runStreamer) Incidents() []fwk.Incident {
+	incs := s.incs
+	s.incs = nil
+	return incs
+}
+
+// lumisvc accounts the luminosity of each run.
+type lumisvc struct {
+	fwk.SvcBase
+
+	mu   sync.Mutex
+	run  int64
+	runs []int64
+	lumi map[int64]float64
+}
+
+func (svc *lumisvc) Configure(ctx fwk.Context) error {
+	isvc, err := ctx.Svc("incsvc")
+	if err != nil {
+		return err
+	}
+
+	for _, typ := range []string{fwk.BeginRun, fwk.EndRun, lumiBlock} {
+		err = isvc.(fwk.IncidentSvc).Subscribe(typ, svc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *lumisvc) StartSvc(ctx fwk.Context) error { return nil }
+func (svc *lumisvc) StopSvc(ctx fwk.Context) error  { return nil }
+
+func (svc *lumisvc) HandleIncident(ctx fwk.Context, inc fwk.Incident) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	switch inc.Type {
+	case fwk.BeginRun:
+		svc.run = inc.Run
+		svc.runs = append(svc.runs, inc.Run)
+	case fwk.EndRun:
+		if inc.Run != svc.run {
+			return fmt.Errorf("end of run %d during run %d", inc.Run, svc.run)
+		}
+	case lumiBlock:
+		lumi := inc.Data.(lumiData)
+		svc.lumi[lumi.run] += lumi.lumi
+	}
+	return nil
+}
+
+type lumiData struct {
+	run  int64
+	lumi float64
+}
+
+// booker records the incidents it is notified of and fires a lumi-block
+// incident for each event.
+type booker struct {
+	fwk.TaskBase
+
+	isvc fwk.IncidentSvc
+
+	mu    sync.Mutex
+	incs  []string
+	nlumi int
+}
+
+func (tsk *booker) Configure(ctx fwk.Context) error {
+	svc, err := ctx.Svc("incsvc")
+	if err != nil {
+		return err
+	}
+	tsk.isvc = svc.(fwk.IncidentSvc)
+	return tsk.DeclInPort("evt", reflect.TypeOf(int64(0)))
+}
+
+func (tsk *booker) StartTask(ctx fwk.Context) error { return nil }
+func (tsk *booker) StopTask(ctx fwk.Context) error  { return nil }
+
+func (tsk *booker) Process(ctx fwk.Context) error {
+	v, err := ctx.Store().Get("evt")
+	if err != nil {
+		return err
+	}
+	return tsk.isvc.Fire(ctx, fwk.Incident{
+		Type:   lumiBlock,
+		Source: tsk.Name(),
+		Data:   lumiData{run: v.(int64) / 4, lumi: 1},
+	})
+}
+
+func (tsk *booker) HandleIncident(ctx fwk.Context, inc fwk.Incident) error {
+	tsk.mu.Lock()
+	defer tsk.mu.Unlock()
+
+	switch inc.Type {
+	case fwk.BeginRun, fwk.EndRun:
+		if inc.Source != "input" {
+			return fmt.Errorf("invalid incident source %q", inc.Source)
+		}
+		tsk.incs = append(tsk.incs, fmt.Sprintf("%s(%d)", inc.Type, inc.Run))
+	case lumiBlock:
+		tsk.nlumi++
+	default:
+		tsk.incs = append(tsk.incs, inc.Type)
+	}
+	return nil
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(lumisvc{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			return &lumisvc{
+				SvcBase: fwk.NewSvc(typ, name, mgr),
+				lumi:    make(map[int64]float64),
+			}, nil
+		},
+	)
+
+	fwk.Register(reflect.TypeOf(booker{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			return &booker{TaskBase: fwk.NewTask(typ, name, mgr)}, nil
+		},
+	)
+}
+
+var (
+	_ fwk.IncidentSource  = (*runStreamer)(nil)
+	_ fwk.IncidentHandler = (*lumisvc)(nil)
+	_ fwk.IncidentHandler = (*booker)(nil)
+)
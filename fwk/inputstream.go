@@ -18,6 +18,8 @@ import (
 //
 // InputStream declares a property 'Streamer', a fwk.InputStreamer,
 // which will be used to actually read data from.
+// Incidents reported by streamers implementing fwk.IncidentSource are
+// delivered to the tasks implementing fwk.IncidentHandler.
 type InputStream struct {
 	TaskBase
 
@@ -65,6 +67,21 @@ func (tsk *InputStream) disconnect() error {
 	return tsk.streamer.Disconnect()
 }
 
+// incidents returns the incidents reported by the underlying InputStreamer.
+func (tsk *InputStream) incidents() []Incident {
+	src, ok := tsk.streamer.(IncidentSource)
+	if !ok {
+		return nil
+	}
+	incs := src.Incidents()
+	for i := range incs {
+		if incs[i].Source == "" {
+			incs[i].Source = tsk.Name()
+		}
+	}
+	return incs
+}
+
 func (tsk *InputStream) read() {
 	for {
 		select {
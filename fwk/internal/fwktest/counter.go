@@ -0,0 +1,130 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwktest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// CounterStats collects statistics about the concurrent execution
+// of counter tasks.
+type CounterStats struct {
+	mu        sync.Mutex
+	inflight  int
+	Instances int // number of started instances
+	MaxConc   int // maximum number of events processed concurrently
+	NEvts     int // number of processed events
+}
+
+// counter is a task counting how many events are processed concurrently.
+type counter struct {
+	fwk.TaskBase
+
+	input string
+	conc  fwk.Concurrency
+	stats *CounterStats
+
+	inflight int // number of events processed by this instance
+	mu       sync.Mutex
+}
+
+func (tsk *counter) Concurrency() fwk.Concurrency {
+	return tsk.conc
+}
+
+func (tsk *counter) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf(int64(1)))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *counter) StartTask(ctx fwk.Context) error {
+	tsk.stats.mu.Lock()
+	tsk.stats.Instances++
+	tsk.stats.mu.Unlock()
+	return nil
+}
+
+func (tsk *counter) StopTask(ctx fwk.Context) error {
+	return nil
+}
+
+func (tsk *counter) Process(ctx fwk.Context) error {
+	store := ctx.Store()
+	_, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	tsk.mu.Lock()
+	tsk.inflight++
+	n := tsk.inflight
+	tsk.mu.Unlock()
+
+	defer func() {
+		tsk.mu.Lock()
+		tsk.inflight--
+		tsk.mu.Unlock()
+	}()
+
+	if n > 1 && tsk.conc != fwk.ThreadSafe {
+		return fmt.Errorf("%s: %d events processed concurrently by a %v task", tsk.Name(), n, tsk.conc)
+	}
+
+	tsk.stats.mu.Lock()
+	tsk.stats.inflight++
+	tsk.stats.NEvts++
+	tsk.stats.MaxConc = max(tsk.stats.MaxConc, tsk.stats.inflight)
+	tsk.stats.mu.Unlock()
+
+	time.Sleep(100 * time.Microsecond)
+
+	tsk.stats.mu.Lock()
+	tsk.stats.inflight--
+	tsk.stats.mu.Unlock()
+
+	return nil
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(counter{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			var err error
+			tsk := &counter{
+				TaskBase: fwk.NewTask(typ, name, mgr),
+				input:    "ints1",
+				conc:     fwk.ThreadSafe,
+				stats:    &CounterStats{},
+			}
+
+			err = tsk.DeclProp("Input", &tsk.input)
+			if err != nil {
+				return nil, err
+			}
+
+			err = tsk.DeclProp("Concurrency", &tsk.conc)
+			if err != nil {
+				return nil, err
+			}
+
+			err = tsk.DeclProp("Stats", &tsk.stats)
+			if err != nil {
+				return nil, err
+			}
+
+			return tsk, err
+		},
+	)
+}
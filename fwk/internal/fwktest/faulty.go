@@ -0,0 +1,101 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwktest
+
+import (
+	"fmt"
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// faulty copies its input to its output and fails for input values
+// which are multiples of Modulo.
+type faulty struct {
+	fwk.TaskBase
+
+	input  string
+	output string
+	mod    int64
+	panics bool
+}
+
+func (tsk *faulty) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf(int64(1)))
+	if err != nil {
+		return err
+	}
+
+	err = tsk.DeclOutPort(tsk.output, reflect.TypeOf(int64(1)))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *faulty) StartTask(ctx fwk.Context) error {
+	return nil
+}
+
+func (tsk *faulty) StopTask(ctx fwk.Context) error {
+	return nil
+}
+
+func (tsk *faulty) Process(ctx fwk.Context) error {
+	store := ctx.Store()
+	v, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	i := v.(int64)
+	if i%tsk.mod == 0 {
+		if tsk.panics {
+			panic(fmt.Errorf("corrupted value %d", i))
+		}
+		return fmt.Errorf("corrupted value %d", i)
+	}
+
+	return store.Put(tsk.output, i)
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(faulty{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			var err error
+			tsk := &faulty{
+				TaskBase: fwk.NewTask(typ, name, mgr),
+				input:    "ints1",
+				output:   "ints1-faulty",
+				mod:      1,
+			}
+
+			err = tsk.DeclProp("Input", &tsk.input)
+			if err != nil {
+				return nil, err
+			}
+
+			err = tsk.DeclProp("Output", &tsk.output)
+			if err != nil {
+				return nil, err
+			}
+
+			err = tsk.DeclProp("Modulo", &tsk.mod)
+			if err != nil {
+				return nil, err
+			}
+
+			err = tsk.DeclProp("Panic", &tsk.panics)
+			if err != nil {
+				return nil, err
+			}
+
+			return tsk, err
+		},
+	)
+}
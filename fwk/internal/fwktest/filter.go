@@ -0,0 +1,74 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwktest
+
+import (
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// filter accepts events whose input value is a multiple of Modulo.
+type filter struct {
+	fwk.TaskBase
+
+	input string
+	mod   int64
+}
+
+func (tsk *filter) Configure(ctx fwk.Context) error {
+	var err error
+
+	err = tsk.DeclInPort(tsk.input, reflect.TypeOf(int64(1)))
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (tsk *filter) StartTask(ctx fwk.Context) error {
+	return nil
+}
+
+func (tsk *filter) StopTask(ctx fwk.Context) error {
+	return nil
+}
+
+func (tsk *filter) Process(ctx fwk.Context) error {
+	store := ctx.Store()
+	v, err := store.Get(tsk.input)
+	if err != nil {
+		return err
+	}
+
+	fwk.SetFilterPassed(ctx, v.(int64)%tsk.mod == 0)
+	return nil
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(filter{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			var err error
+			tsk := &filter{
+				TaskBase: fwk.NewTask(typ, name, mgr),
+				input:    "ints1",
+				mod:      1,
+			}
+
+			err = tsk.DeclProp("Input", &tsk.input)
+			if err != nil {
+				return nil, err
+			}
+
+			err = tsk.DeclProp("Modulo", &tsk.mod)
+			if err != nil {
+				return nil, err
+			}
+
+			return tsk, err
+		},
+	)
+}
@@ -0,0 +1,35 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+// IncidentSvc is the interface of services dispatching incidents to the
+// components which subscribed to them.
+//
+// When an IncidentSvc is available, the application fires the incidents
+// reported by the input stream through it, and subscribes the tasks
+// implementing IncidentHandler to all the incidents.
+// Components may also fire their own incidents, e.g. at the boundaries
+// of luminosity blocks.
+type IncidentSvc interface {
+	Svc
+
+	// Subscribe registers h to be notified of the incidents of type typ.
+	// An empty type subscribes h to all the incidents.
+	// Subscribe should be called during the Configure or Start stages.
+	Subscribe(typ string, h IncidentHandler) error
+
+	// Fire synchronously notifies the handlers subscribed to the type
+	// of inc, in their order of subscription.
+	// Fire may be called concurrently.
+	Fire(ctx Context, inc Incident) error
+}
+
+// IncidentFunc adapts a function to an IncidentHandler.
+type IncidentFunc func(ctx Context, inc Incident) error
+
+// HandleIncident calls f(ctx, inc).
+func (f IncidentFunc) HandleIncident(ctx Context, inc Incident) error {
+	return f(ctx, inc)
+}
@@ -0,0 +1,571 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package job
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go-hep.org/x/hep/fwk"
+	"gopkg.in/yaml.v3"
+)
+
+// document is a declarative description of a job, as held in a
+// configuration file:
+//
+//	include:
+//	  - common.yaml
+//	app:
+//	  EvtMax: 100
+//	  NProcs: 4
+//	  MsgLevel: INFO
+//	components:
+//	  - name: input
+//	    type: go-hep.org/x/hep/fwk.InputStream
+//	    props:
+//	      Ports:
+//	        - {Name: ints, Type: int64}
+//	      Streamer:
+//	        type: "*go-hep.org/x/hep/fwk/rio.InputStreamer"
+//	        value: {Names: [input.rio]}
+//	  - name: t1
+//	    type: go-hep.org/x/hep/fwk/internal/fwktest.task2
+//	    props: {Input: ints, Output: sq-ints}
+//	  - name: t1 # no type: modify the properties of an existing component.
+//	    props: {Output: squared-ints}
+//
+// Included documents are processed before the including document.
+// Interface values are described by the name of their concrete type,
+// registered with RegisterType, and by their value.
+type document struct {
+	Include    []string       `yaml:"include,omitempty" toml:"include,omitempty"`
+	App        map[string]any `yaml:"app,omitempty" toml:"app,omitempty"`
+	Components []component    `yaml:"components,omitempty" toml:"components,omitempty"`
+}
+
+type component struct {
+	Name  string         `yaml:"name" toml:"name"`
+	Type  string         `yaml:"type,omitempty" toml:"type,omitempty"`
+	Props map[string]any `yaml:"props,omitempty" toml:"props,omitempty"`
+}
+
+// codec is a configuration file format.
+type codec struct {
+	unmarshal func(data []byte, doc *document) error
+	marshal   func(w io.Writer, doc *document) error
+}
+
+// codecOf returns the configuration file format of fname.
+func codecOf(fname string) (codec, error) {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".yaml", ".yml":
+		return yamlCodec, nil
+	case ".toml":
+		return tomlCodec, nil
+	}
+	return codec{}, fmt.Errorf("fwk/job: unknown configuration file format for %q", fname)
+}
+
+// Overrides is a list of "component.Property=value" overrides of the
+// properties of a job description.
+// The properties of the application are overridden with "app.Property=value".
+// Values are given in YAML (eg "t1.Ints=[1, 2, 3]").
+//
+// Overrides implements flag.Value, so it may be filled from the
+// command line:
+//
+//	var ovrs job.Overrides
+//	flag.Var(&ovrs, "set", "override a property (component.Property=value)")
+type Overrides []string
+
+func (o *Overrides) String() string {
+	if o == nil {
+		return ""
+	}
+	return strings.Join(*o, ", ")
+}
+
+// Set appends the override v.
+func (o *Overrides) Set(v string) error {
+	_, _, _, err := parseOverride(v)
+	if err != nil {
+		return err
+	}
+	*o = append(*o, v)
+	return nil
+}
+
+func parseOverride(v string) (name, prop string, value any, err error) {
+	k, raw, ok := strings.Cut(v, "=")
+	if !ok {
+		return "", "", nil, fmt.Errorf("fwk/job: invalid override %q (missing '=')", v)
+	}
+	name, prop, ok = strings.Cut(strings.TrimSpace(k), ".")
+	if !ok || name == "" || prop == "" {
+		return "", "", nil, fmt.Errorf("fwk/job: invalid override %q (want component.Property=value)", v)
+	}
+	err = yaml.Unmarshal([]byte(raw), &value)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("fwk/job: could not decode value of override %q: %w", v, err)
+	}
+	return name, prop, value, nil
+}
+
+// LoadFile loads the job description stored in the configuration file
+// fname, with the provided overrides applied.
+// The format of the file (YAML or TOML) is selected from its extension.
+func LoadFile(fname string, overrides ...string) ([]Stmt, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("fwk/job: could not open job description: %w", err)
+	}
+	defer f.Close()
+
+	cdc, err := codecOf(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeStmts(f, cdc, filepath.Dir(fname), overrides)
+}
+
+// Exec executes the statements stmts, as loaded from a job description,
+// and returns the resulting Job.
+func Exec(stmts []Stmt) (job *Job, err error) {
+	if len(stmts) == 0 || stmts[0].Type != StmtNewApp {
+		return nil, fmt.Errorf("fwk/job: first statement is not a %v statement", StmtNewApp)
+	}
+
+	defer func() {
+		e := recover()
+		if e == nil {
+			return
+		}
+		switch e := e.(type) {
+		case error:
+			err = e
+		default:
+			err = fmt.Errorf("fwk/job: %v", e)
+		}
+		job = nil
+	}()
+
+	job = New(stmts[0].Data.Props)
+	for _, stmt := range stmts[1:] {
+		switch stmt.Type {
+		case StmtCreate:
+			job.Create(stmt.Data)
+		case StmtSetProp:
+			c := job.component(stmt.Data.Name)
+			if c == nil {
+				return nil, fmt.Errorf("fwk/job: no component named %q", stmt.Data.Name)
+			}
+			keys := make([]string, 0, len(stmt.Data.Props))
+			for k := range stmt.Data.Props {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				job.SetProp(c, k, stmt.Data.Props[k])
+			}
+		default:
+			return nil, fmt.Errorf("fwk/job: invalid statement type %v", stmt.Type)
+		}
+	}
+	return job, nil
+}
+
+// Setup creates the Job of a fwk-based command.
+//
+// When fname is empty, the Job is created with the application properties
+// props and is configured by calling the setup functions.
+// Otherwise, the Job is created from the job description stored in the
+// configuration file fname, whose application properties take precedence
+// over props, and the setup functions are not called.
+// The overrides are applied last, whatever the origin of the Job.
+func Setup(props P, fname string, overrides []string, setups ...func(*Job)) (*Job, error) {
+	if fname != "" {
+		stmts, err := LoadFile(fname, overrides...)
+		if err != nil {
+			return nil, err
+		}
+		app := make(P, len(props)+len(stmts[0].Data.Props))
+		maps.Copy(app, props)
+		maps.Copy(app, stmts[0].Data.Props)
+		stmts[0].Data.Props = app
+		return Exec(stmts)
+	}
+
+	job := New(props)
+	for _, setup := range setups {
+		setup(job)
+	}
+	err := job.Override(overrides...)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Override applies the "component.Property=value" overrides to the
+// components of the job.
+// See Overrides for the syntax of overrides.
+func (job *Job) Override(overrides ...string) error {
+	for _, o := range overrides {
+		name, prop, value, err := parseOverride(o)
+		if err != nil {
+			return err
+		}
+		c := job.component(name)
+		if c == nil {
+			return fmt.Errorf("fwk/job: no component named %q for override %q", name, o)
+		}
+		props, err := decodeProps(job.app, c, map[string]any{prop: value})
+		if err != nil {
+			return err
+		}
+		job.stmts = append(job.stmts, Stmt{
+			Type: StmtSetProp,
+			Data: C{Type: c.Type(), Name: c.Name(), Props: props},
+		})
+	}
+	return nil
+}
+
+// component returns the named component of the job, or the application
+// itself when name is "app" or the name of the application.
+func (job *Job) component(name string) fwk.Component {
+	if name == appName || name == job.app.Name() {
+		return job.app
+	}
+	return job.app.Component(name)
+}
+
+// decodeInto decodes the job description read from r into the *[]Stmt ptr.
+func decodeInto(ptr any, r io.Reader, cdc codec, dir string, overrides []string) error {
+	stmts, ok := ptr.(*[]Stmt)
+	if !ok {
+		return fmt.Errorf("fwk/job: expected a *[]job.Stmt as input. got %T", ptr)
+	}
+	v, err := decodeStmts(r, cdc, dir, overrides)
+	if err != nil {
+		return err
+	}
+	*stmts = v
+	return nil
+}
+
+// decodeStmts decodes the job description read from r, resolving
+// includes relative to dir and applying the provided overrides.
+func decodeStmts(r io.Reader, cdc codec, dir string, overrides []string) ([]Stmt, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("fwk/job: could not read job description: %w", err)
+	}
+
+	var doc document
+	err = cdc.unmarshal(raw, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err = resolve(doc, dir, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range overrides {
+		name, prop, value, err := parseOverride(o)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case appName:
+			if doc.App == nil {
+				doc.App = make(map[string]any)
+			}
+			doc.App[prop] = value
+		default:
+			doc.Components = append(doc.Components, component{
+				Name:  name,
+				Props: map[string]any{prop: value},
+			})
+		}
+	}
+
+	return doc.stmts()
+}
+
+// appName is the name of the application in overrides.
+const appName = "app"
+
+// resolve returns the document doc merged with the documents it
+// includes. Included files are resolved relative to dir.
+func resolve(doc document, dir string, stack []string) (document, error) {
+	var out document
+	for _, inc := range doc.Include {
+		fname := inc
+		if !filepath.IsAbs(fname) {
+			fname = filepath.Join(dir, fname)
+		}
+		for _, name := range stack {
+			if name == fname {
+				return out, fmt.Errorf("fwk/job: include cycle detected with %q", fname)
+			}
+		}
+
+		cdc, err := codecOf(fname)
+		if err != nil {
+			return out, err
+		}
+
+		raw, err := os.ReadFile(fname)
+		if err != nil {
+			return out, fmt.Errorf("fwk/job: could not read included file: %w", err)
+		}
+
+		var sub document
+		err = cdc.unmarshal(raw, &sub)
+		if err != nil {
+			return out, fmt.Errorf("fwk/job: could not decode included file %q: %w", fname, err)
+		}
+
+		sub, err = resolve(sub, filepath.Dir(fname), append(stack, fname))
+		if err != nil {
+			return out, err
+		}
+		out.merge(sub)
+	}
+
+	doc.Include = nil
+	out.merge(doc)
+	return out, nil
+}
+
+// merge appends the content of o to doc.
+func (doc *document) merge(o document) {
+	if len(o.App) > 0 && doc.App == nil {
+		doc.App = make(map[string]any, len(o.App))
+	}
+	for k, v := range o.App {
+		doc.App[k] = v
+	}
+	doc.Components = append(doc.Components, o.Components...)
+}
+
+// stmts returns the statements described by doc, validated against
+// the properties declared by the components.
+func (doc *document) stmts() ([]Stmt, error) {
+	app := fwk.NewApp()
+
+	props, err := decodeProps(app, app, doc.App)
+	if err != nil {
+		return nil, err
+	}
+	stmts := []Stmt{{
+		Type: StmtNewApp,
+		Data: C{
+			Name:  app.Name(),
+			Type:  app.Type(),
+			Props: props,
+		},
+	}}
+
+	comps := make(map[string]fwk.Component, len(doc.Components))
+	for _, cfg := range doc.Components {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("fwk/job: component with no name (type=%q)", cfg.Type)
+		}
+		c, dup := comps[cfg.Name]
+		switch {
+		case cfg.Type == "" && !dup:
+			return nil, fmt.Errorf("fwk/job: no component named %q", cfg.Name)
+
+		case cfg.Type == "":
+			keys := make([]string, 0, len(cfg.Props))
+			for k := range cfg.Props {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				props, err := decodeProps(app, c, map[string]any{k: cfg.Props[k]})
+				if err != nil {
+					return nil, err
+				}
+				stmts = append(stmts, Stmt{
+					Type: StmtSetProp,
+					Data: C{Name: c.Name(), Type: c.Type(), Props: props},
+				})
+			}
+
+		case dup:
+			return nil, fmt.Errorf("fwk/job: component %q already defined (type=%q)", cfg.Name, c.Type())
+
+		default:
+			c, err = app.New(cfg.Type, cfg.Name)
+			if err != nil {
+				return nil, fmt.Errorf("fwk/job: could not create component [%s:%s]: %w", cfg.Type, cfg.Name, err)
+			}
+			comps[cfg.Name] = c
+			props, err := decodeProps(app, c, cfg.Props)
+			if err != nil {
+				return nil, err
+			}
+			if props == nil {
+				props = P{}
+			}
+			stmts = append(stmts, Stmt{
+				Type: StmtCreate,
+				Data: C{Name: c.Name(), Type: c.Type(), Props: props},
+			})
+		}
+	}
+
+	return stmts, nil
+}
+
+// decodeProps converts the properties of the component c, as read
+// from a configuration file, into values of their declared types.
+func decodeProps(app fwk.App, c fwk.Component, props map[string]any) (P, error) {
+	if len(props) == 0 {
+		return nil, nil
+	}
+
+	o := make(P, len(props))
+	for name, v := range props {
+		if !app.HasProp(c, name) {
+			return nil, fmt.Errorf("fwk/job: component [%s:%s] has no property named %q", c.Type(), c.Name(), name)
+		}
+
+		cur, err := app.GetProp(c, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var typ reflect.Type
+		switch tv, ok := typedValue(v); {
+		case ok:
+			// interface value with an explicit concrete type.
+			typ, err = lookupType(tv.typ)
+			if err != nil {
+				return nil, fmt.Errorf("fwk/job: invalid property %q of component [%s:%s]: %w", name, c.Type(), c.Name(), err)
+			}
+			v = tv.value
+		case cur != nil:
+			typ = reflect.TypeOf(cur)
+		}
+
+		if typ != nil {
+			rv, err := decodeValue(v, typ)
+			if err != nil {
+				return nil, fmt.Errorf("fwk/job: invalid property %q of component [%s:%s]: %w", name, c.Type(), c.Name(), err)
+			}
+			v = rv.Interface()
+		}
+
+		if v != nil {
+			err = app.SetProp(c, name, v)
+			if err != nil {
+				return nil, fmt.Errorf("fwk/job: invalid property %q of component [%s:%s]: %w", name, c.Type(), c.Name(), err)
+			}
+		}
+		o[name] = v
+	}
+
+	return o, nil
+}
+
+// encodeStmts converts the statements stmts into a document.
+func encodeStmts(data any) (*document, error) {
+	stmts, ok := data.([]Stmt)
+	if !ok {
+		return nil, fmt.Errorf("fwk/job: expected a []job.Stmt as input. got %T", data)
+	}
+
+	var (
+		doc   document
+		app   = fwk.NewApp()
+		comps = make(map[string]fwk.Component)
+	)
+	for _, stmt := range stmts {
+		var c fwk.Component
+		switch stmt.Type {
+		case StmtNewApp:
+			c = app
+		case StmtCreate:
+			c, _ = app.New(stmt.Data.Type, stmt.Data.Name)
+			comps[stmt.Data.Name] = c
+		case StmtSetProp:
+			c = comps[stmt.Data.Name]
+		}
+
+		props, err := encodeProps(app, c, stmt.Data.Props)
+		if err != nil {
+			return nil, fmt.Errorf("fwk/job: could not encode properties of [%s:%s]: %w", stmt.Data.Type, stmt.Data.Name, err)
+		}
+
+		switch stmt.Type {
+		case StmtNewApp:
+			if doc.App == nil {
+				doc.App = props
+				continue
+			}
+			for k, v := range props {
+				doc.App[k] = v
+			}
+		case StmtCreate:
+			doc.Components = append(doc.Components, component{
+				Name:  stmt.Data.Name,
+				Type:  stmt.Data.Type,
+				Props: props,
+			})
+		case StmtSetProp:
+			doc.Components = append(doc.Components, component{
+				Name:  stmt.Data.Name,
+				Props: props,
+			})
+		default:
+			return nil, fmt.Errorf("fwk/job: invalid statement type (%#v)", stmt.Type)
+		}
+	}
+	return &doc, nil
+}
+
+// encodeProps converts the properties of the component c into values
+// suitable for a configuration file.
+// Values whose type can not be inferred from the default value of the
+// property (eg values of interfaces) are recorded with their type.
+func encodeProps(app fwk.App, c fwk.Component, props P) (map[string]any, error) {
+	if len(props) == 0 {
+		return nil, nil
+	}
+	o := make(map[string]any, len(props))
+	for k, v := range props {
+		value, err := encodeValue(reflect.ValueOf(v))
+		if err != nil {
+			return nil, err
+		}
+		o[k] = value
+
+		if v == nil || c == nil || !app.HasProp(c, k) {
+			continue
+		}
+		def, err := app.GetProp(c, k)
+		if err != nil || reflect.TypeOf(def) == reflect.TypeOf(v) {
+			continue
+		}
+		o[k] = map[string]any{
+			"type":  typeName(reflect.TypeOf(v)),
+			"value": value,
+		}
+	}
+	return o, nil
+}
@@ -0,0 +1,449 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package job_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/internal/fwktest"
+	"go-hep.org/x/hep/fwk/job"
+	"go-hep.org/x/hep/fwk/rio"
+)
+
+func newTestJob() *job.Job {
+	app := job.NewJob(fwk.NewApp(), job.P{
+		"EvtMax":   int64(10),
+		"NProcs":   42,
+		"MsgLevel": job.MsgLevel("ERROR"),
+		"ErrorPolicies": map[string]fwk.ErrorPolicy{
+			"t1": fwk.Continue,
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.InputStream",
+		Name: "input",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{Name: "ints", Type: reflect.TypeOf(int64(0))},
+				{Name: "floats", Type: reflect.TypeOf([]float64(nil))},
+			},
+			"Streamer": &rio.InputStreamer{
+				Names: []string{"in-1.rio", "in-2.rio"},
+			},
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.task1",
+		Name: "t0",
+		Props: job.P{
+			"Ints1": "t0-ints1",
+			"Ints2": "t0-ints2",
+			"Int1":  int64(-1),
+		},
+	})
+
+	t1 := app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+		Name: "t1",
+		Props: job.P{
+			"Input":  "t0-ints1",
+			"Output": "t1-ints1",
+		},
+	})
+	app.SetProp(t1, "Output", "t1-ints1-massaged")
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.svc1",
+		Name: "svc1",
+		Props: job.P{
+			"Int":    fwktest.MyInt(12),
+			"Struct": fwktest.MyStruct{I: 12},
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.OutputStream",
+		Name: "output",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{Name: "t1-ints1-massaged", Type: reflect.TypeOf(int64(0))},
+			},
+			"Streamer": &rio.OutputStreamer{
+				Name: "out.rio",
+			},
+		},
+	})
+
+	return app
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		enc  func(w *bytes.Buffer) job.Encoder
+		dec  func(r *bytes.Buffer) job.Decoder
+	}{
+		{
+			name: "yaml",
+			enc:  func(w *bytes.Buffer) job.Encoder { return job.NewYAMLEncoder(w) },
+			dec:  func(r *bytes.Buffer) job.Decoder { return job.NewYAMLDecoder(r) },
+		},
+		{
+			name: "toml",
+			enc:  func(w *bytes.Buffer) job.Encoder { return job.NewTOMLEncoder(w) },
+			dec:  func(r *bytes.Buffer) job.Decoder { return job.NewTOMLDecoder(r) },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := newTestJob().Stmts()
+
+			buf := new(bytes.Buffer)
+			err := job.Save(want, tc.enc(buf))
+			if err != nil {
+				t.Fatalf("could not save job: %+v", err)
+			}
+			raw := buf.String()
+
+			got, err := job.Load(tc.dec(buf))
+			if err != nil {
+				t.Fatalf("could not load job: %+v\n%s", err, raw)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid round-trip:\ngot= %#v\nwant=%#v\n%s", got, want, raw)
+			}
+
+			app, err := job.Exec(got)
+			if err != nil {
+				t.Fatalf("could not execute statements: %+v", err)
+			}
+			if !reflect.DeepEqual(app.Stmts(), want) {
+				t.Fatalf("invalid statements:\ngot= %#v\nwant=%#v", app.Stmts(), want)
+			}
+		})
+	}
+}
+
+func TestConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		fname := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(fname), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(fname, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fname
+	}
+
+	write("common/base.yaml", `
+app:
+  EvtMax: 100
+  MsgLevel: ERROR
+components:
+  - name: t0
+    type: go-hep.org/x/hep/fwk/internal/fwktest.task1
+    props: {Ints1: t0-ints1, Ints2: t0-ints2}
+`)
+	fname := write("job.toml", `
+include = ["common/base.yaml"]
+
+[app]
+NProcs = 0
+
+[[components]]
+name = "t1"
+type = "go-hep.org/x/hep/fwk/internal/fwktest.task2"
+[components.props]
+Input = "t0-ints1"
+Output = "t1-ints1"
+
+[[components]]
+name = "t0"
+[components.props]
+Ints2 = "ints2"
+`)
+
+	stmts, err := job.LoadFile(fname, "app.EvtMax=5", "t1.Output=sq-ints")
+	if err != nil {
+		t.Fatalf("could not load job description: %+v", err)
+	}
+
+	want := []job.Stmt{
+		{
+			Type: job.StmtNewApp,
+			Data: job.C{
+				Name: "app",
+				Type: "go-hep.org/x/hep/fwk.appmgr",
+				Props: job.P{
+					"EvtMax":   int64(5),
+					"NProcs":   0,
+					"MsgLevel": fwk.LvlError,
+				},
+			},
+		},
+		{
+			Type: job.StmtCreate,
+			Data: job.C{
+				Name:  "t0",
+				Type:  "go-hep.org/x/hep/fwk/internal/fwktest.task1",
+				Props: job.P{"Ints1": "t0-ints1", "Ints2": "t0-ints2"},
+			},
+		},
+		{
+			Type: job.StmtCreate,
+			Data: job.C{
+				Name:  "t1",
+				Type:  "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+				Props: job.P{"Input": "t0-ints1", "Output": "t1-ints1"},
+			},
+		},
+		{
+			Type: job.StmtSetProp,
+			Data: job.C{
+				Name:  "t0",
+				Type:  "go-hep.org/x/hep/fwk/internal/fwktest.task1",
+				Props: job.P{"Ints2": "ints2"},
+			},
+		},
+		{
+			Type: job.StmtSetProp,
+			Data: job.C{
+				Name:  "t1",
+				Type:  "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+				Props: job.P{"Output": "sq-ints"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(stmts, want) {
+		t.Fatalf("invalid statements:\ngot= %#v\nwant=%#v", stmts, want)
+	}
+
+	app, err := job.Exec(stmts)
+	if err != nil {
+		t.Fatalf("could not execute statements: %+v", err)
+	}
+	err = app.App().Run()
+	if err != nil {
+		t.Fatalf("could not run job: %+v", err)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	const task1 = "go-hep.org/x/hep/fwk/internal/fwktest.task1"
+	for _, tc := range []struct {
+		name string
+		cfg  string
+		ovrs []string
+		want string
+	}{
+		{
+			name: "unknown-field",
+			cfg:  "apps: {EvtMax: 1}",
+			want: "field apps not found",
+		},
+		{
+			name: "unknown-app-prop",
+			cfg:  "app: {EvtMaxx: 1}",
+			want: `has no property named "EvtMaxx"`,
+		},
+		{
+			name: "invalid-app-prop",
+			cfg:  "app: {EvtMax: foo}",
+			want: `invalid property "EvtMax"`,
+		},
+		{
+			name: "invalid-level",
+			cfg:  "app: {MsgLevel: LOUD}",
+			want: `invalid fwk.Level string "LOUD"`,
+		},
+		{
+			name: "unknown-type",
+			cfg:  "components: [{name: t0, type: not.registered}]",
+			want: "could not create component [not.registered:t0]",
+		},
+		{
+			name: "unknown-prop",
+			cfg:  "components: [{name: t0, type: " + task1 + ", props: {Ints3: foo}}]",
+			want: `has no property named "Ints3"`,
+		},
+		{
+			name: "invalid-prop",
+			cfg:  "components: [{name: t0, type: " + task1 + ", props: {Ints1: [1, 2]}}]",
+			want: `invalid property "Ints1"`,
+		},
+		{
+			name: "duplicate",
+			cfg:  "components: [{name: t0, type: " + task1 + "}, {name: t0, type: " + task1 + "}]",
+			want: `component "t0" already defined`,
+		},
+		{
+			name: "unknown-component",
+			cfg:  "components: [{name: t0, props: {Ints1: foo}}]",
+			want: `no component named "t0"`,
+		},
+		{
+			name: "unknown-port-type",
+			cfg: `
+components:
+  - name: input
+    type: go-hep.org/x/hep/fwk.InputStream
+    props: {Ports: [{Name: ints, Type: not.registered}]}
+`,
+			want: `no type named "not.registered" registered`,
+		},
+		{
+			name: "invalid-override",
+			cfg:  "app: {EvtMax: 1}",
+			ovrs: []string{"app.EvtMax=foo"},
+			want: `invalid property "EvtMax"`,
+		},
+		{
+			name: "unknown-override",
+			cfg:  "app: {EvtMax: 1}",
+			ovrs: []string{"t0.Ints1=foo"},
+			want: `no component named "t0"`,
+		},
+		{
+			name: "include-cycle",
+			cfg:  "include: [cycle.yaml]",
+			want: "include cycle detected",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.WriteFile(filepath.Join(dir, "cycle.yaml"), []byte("include: [cycle.yaml]"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dec := job.NewYAMLDecoder(strings.NewReader(tc.cfg))
+			dec.Dir = dir
+			dec.Overrides = tc.ovrs
+			_, err = job.Load(dec)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	var ovrs job.Overrides
+	for _, v := range []string{"app.EvtMax=10", "t0.Ints=[1, 2]"} {
+		err := ovrs.Set(v)
+		if err != nil {
+			t.Fatalf("could not set override %q: %+v", v, err)
+		}
+	}
+	if got, want := ovrs.String(), "app.EvtMax=10, t0.Ints=[1, 2]"; got != want {
+		t.Fatalf("invalid overrides: got=%q, want=%q", got, want)
+	}
+
+	for _, v := range []string{"EvtMax=10", "app.EvtMax", ".EvtMax=1", "app.EvtMax=[1"} {
+		err := ovrs.Set(v)
+		if err == nil {
+			t.Fatalf("expected an error for override %q", v)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	props := job.P{
+		"EvtMax":   int64(-1),
+		"NProcs":   2,
+		"MsgLevel": job.MsgLevel("ERROR"),
+	}
+	setup := func(app *job.Job) {
+		app.Create(job.C{
+			Type:  "go-hep.org/x/hep/fwk/internal/fwktest.task1",
+			Name:  "t0",
+			Props: job.P{"Ints1": "t0-ints1", "Ints2": "t0-ints2"},
+		})
+	}
+
+	fname := filepath.Join(t.TempDir(), "job.yaml")
+	err := os.WriteFile(fname, []byte(`
+app:
+  NProcs: 4
+components:
+  - name: t0
+    type: go-hep.org/x/hep/fwk/internal/fwktest.task1
+    props: {Ints1: t0-ints1, Ints2: t0-ints2}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		fname  string
+		nprocs int
+	}{
+		{name: "setup-funcs", nprocs: 2},
+		{name: "job-file", fname: fname, nprocs: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			app, err := job.Setup(props, tc.fname, []string{"app.EvtMax=0", "t0.Int1=42"}, setup)
+			if err != nil {
+				t.Fatalf("could not setup job: %+v", err)
+			}
+
+			get := func(c fwk.Component, name string) any {
+				t.Helper()
+				v, err := app.App().GetProp(c, name)
+				if err != nil {
+					t.Fatalf("could not get property %q: %+v", name, err)
+				}
+				return v
+			}
+
+			if got, want := get(app.App(), "EvtMax"), int64(0); got != want {
+				t.Fatalf("invalid EvtMax: got=%v, want=%v", got, want)
+			}
+			if got, want := get(app.App(), "NProcs"), tc.nprocs; got != want {
+				t.Fatalf("invalid NProcs: got=%v, want=%v", got, want)
+			}
+			if got, want := get(app.App().Component("t0"), "Int1"), int64(42); got != want {
+				t.Fatalf("invalid t0.Int1: got=%v, want=%v", got, want)
+			}
+
+			// overrides are recorded with the statements of the job.
+			re, err := job.Exec(app.Stmts())
+			if err != nil {
+				t.Fatalf("could not execute statements: %+v", err)
+			}
+			if !reflect.DeepEqual(re.Stmts(), app.Stmts()) {
+				t.Fatalf("invalid statements:\ngot= %#v\nwant=%#v", re.Stmts(), app.Stmts())
+			}
+
+			err = app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run job: %+v", err)
+			}
+		})
+	}
+
+	for _, ovr := range []string{"t1.Int1=42", "t0.Nope=1", "t0.Int1=[1"} {
+		_, err := job.Setup(props, "", []string{ovr}, setup)
+		if err == nil {
+			t.Fatalf("expected an error for override %q", ovr)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package job
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BurntSushi/toml"
+)
+
+var tomlCodec = codec{
+	unmarshal: func(data []byte, doc *document) error {
+		meta, err := toml.Decode(string(data), doc)
+		if err != nil {
+			return fmt.Errorf("fwk/job: could not decode TOML job description: %w", err)
+		}
+		var keys []toml.Key
+		for _, key := range meta.Undecoded() {
+			// properties are free-form: they are validated later on,
+			// against the properties declared by the components.
+			switch {
+			case key[0] == "app":
+			case len(key) > 1 && key[0] == "components" && key[1] == "props":
+			default:
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			return fmt.Errorf("fwk/job: unknown fields in TOML job description: %v", keys)
+		}
+		return nil
+	},
+	marshal: func(w io.Writer, doc *document) error {
+		// TOML has no null value: drop nil properties.
+		out := *doc
+		out.App = tomlProps(doc.App)
+		out.Components = make([]component, len(doc.Components))
+		for i, c := range doc.Components {
+			c.Props = tomlProps(c.Props)
+			out.Components[i] = c
+		}
+		enc := toml.NewEncoder(w)
+		enc.Indent = "  "
+		err := enc.Encode(out)
+		if err != nil {
+			return fmt.Errorf("fwk/job: could not encode TOML job description: %w", err)
+		}
+		return nil
+	},
+}
+
+// tomlProps returns the properties props, without their nil values.
+func tomlProps(props map[string]any) map[string]any {
+	if props == nil {
+		return nil
+	}
+	o := make(map[string]any, len(props))
+	for k, v := range props {
+		if v == nil {
+			continue
+		}
+		if m, ok := v.(map[string]any); ok {
+			v = tomlProps(m)
+		}
+		o[k] = v
+	}
+	return o
+}
+
+// NewTOMLEncoder returns a new encoder that writes job descriptions
+// in TOML to w.
+func NewTOMLEncoder(w io.Writer) *TOMLEncoder {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &TOMLEncoder{w: w}
+}
+
+// A TOMLEncoder writes job descriptions in TOML to an output stream.
+type TOMLEncoder struct {
+	w io.Writer
+}
+
+// Encode encodes the []Stmt data into the underlying io.Writer.
+func (enc *TOMLEncoder) Encode(data any) error {
+	doc, err := encodeStmts(data)
+	if err != nil {
+		return err
+	}
+	return tomlCodec.marshal(enc.w, doc)
+}
+
+// NewTOMLDecoder returns a new decoder that reads job descriptions
+// in TOML from r.
+func NewTOMLDecoder(r io.Reader) *TOMLDecoder {
+	return &TOMLDecoder{r: r, Dir: "."}
+}
+
+// A TOMLDecoder reads job descriptions in TOML from an input stream.
+type TOMLDecoder struct {
+	r io.Reader
+
+	Dir       string    // directory against which included files are resolved
+	Overrides Overrides // overrides of the properties of the job description
+}
+
+// Decode decodes a job description into the *[]Stmt ptr.
+// Properties are validated against the properties declared by the
+// components.
+func (dec *TOMLDecoder) Decode(ptr any) error {
+	return decodeInto(ptr, dec.r, tomlCodec, dec.Dir, dec.Overrides)
+}
+
+var (
+	_ Encoder = (*TOMLEncoder)(nil)
+	_ Decoder = (*TOMLDecoder)(nil)
+)
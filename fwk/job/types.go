@@ -0,0 +1,586 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package job
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var types = struct {
+	sync.RWMutex
+	db map[string]reflect.Type
+}{
+	db: make(map[string]reflect.Type),
+}
+
+// RegisterType registers the type t with the configuration files
+// machinery, under its fully qualified name
+// (eg "go-hep.org/x/hep/fwk/rio.InputStreamer").
+//
+// Registered types can be used as the types of ports and as the
+// concrete values of properties holding interfaces.
+// Predeclared types are always available.
+func RegisterType(t reflect.Type) {
+	name := typeName(t)
+	types.Lock()
+	defer types.Unlock()
+	if old, dup := types.db[name]; dup && old != t {
+		panic(fmt.Errorf("fwk/job: type %q already registered", name))
+	}
+	types.db[name] = t
+}
+
+func init() {
+	for _, v := range []any{
+		false,
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		complex64(0), complex128(0),
+		"",
+	} {
+		RegisterType(reflect.TypeOf(v))
+	}
+}
+
+var rtypeType = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+
+// typeName returns the fully qualified name of t.
+func typeName(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+		return t.PkgPath() + "." + t.Name()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	case reflect.Array:
+		return "[" + strconv.Itoa(t.Len()) + "]" + typeName(t.Elem())
+	case reflect.Map:
+		return "map[" + typeName(t.Key()) + "]" + typeName(t.Elem())
+	}
+	return t.String()
+}
+
+// lookupType returns the type named name.
+func lookupType(name string) (reflect.Type, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case strings.HasPrefix(name, "*"):
+		elem, err := lookupType(name[1:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(elem), nil
+
+	case strings.HasPrefix(name, "[]"):
+		elem, err := lookupType(name[2:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+
+	case strings.HasPrefix(name, "["):
+		n, elem, ok := strings.Cut(name[1:], "]")
+		if !ok {
+			return nil, fmt.Errorf("fwk/job: invalid type name %q", name)
+		}
+		size, err := strconv.Atoi(n)
+		if err != nil {
+			return nil, fmt.Errorf("fwk/job: invalid type name %q: %w", name, err)
+		}
+		t, err := lookupType(elem)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(size, t), nil
+
+	case strings.HasPrefix(name, "map["):
+		k, v, ok := strings.Cut(name[len("map["):], "]")
+		if !ok {
+			return nil, fmt.Errorf("fwk/job: invalid type name %q", name)
+		}
+		key, err := lookupType(k)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := lookupType(v)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, elem), nil
+	}
+
+	switch name {
+	case "byte":
+		name = "uint8"
+	case "rune":
+		name = "int32"
+	}
+
+	types.RLock()
+	defer types.RUnlock()
+	t, ok := types.db[name]
+	if !ok {
+		return nil, fmt.Errorf("fwk/job: no type named %q registered", name)
+	}
+	return t, nil
+}
+
+// encodeValue converts v into a value made only of booleans, numbers,
+// strings, slices and maps of strings, suitable for a configuration file.
+//
+// Types are encoded as their names and interface values as a
+// {"type": name, "value": value} map.
+func encodeValue(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if v.Type() == rtypeType || v.Type().Implements(rtypeType) {
+		if v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+		return typeName(v.Interface().(reflect.Type)), nil
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok && v.Kind() != reflect.Interface {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		txt, err := m.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(txt), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		elem := v.Elem()
+		if v.NumMethod() == 0 {
+			return encodeValue(elem)
+		}
+		value, err := encodeValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"type":  typeName(elem.Type()),
+			"value": value,
+		}, nil
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(v.Elem())
+
+	case reflect.Bool:
+		return v.Bool(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			return time.Duration(v.Int()).String(), nil
+		}
+		return v.Int(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, 128), nil
+
+	case reflect.String:
+		return v.String(), nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		o := make([]any, v.Len())
+		for i := range o {
+			elem, err := encodeValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			o[i] = elem
+		}
+		return o, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		o := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := encodeValue(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			elem, err := encodeValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			o[fmt.Sprint(k)] = elem
+		}
+		return o, nil
+
+	case reflect.Struct:
+		var (
+			t = v.Type()
+			o = make(map[string]any, t.NumField())
+		)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			elem, err := encodeValue(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			o[f.Name] = elem
+		}
+		return o, nil
+	}
+
+	return nil, fmt.Errorf("fwk/job: can not encode value of type %v", v.Type())
+}
+
+// decodeValue converts the value v, decoded from a configuration file,
+// into a value of type t.
+func decodeValue(v any, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) && t.Kind() != reflect.Interface {
+		return rv, nil
+	}
+
+	if t == rtypeType {
+		name, ok := v.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("fwk/job: invalid type name %v (type=%T)", v, v)
+		}
+		typ, err := lookupType(name)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		o := reflect.New(rtypeType).Elem()
+		o.Set(reflect.ValueOf(typ))
+		return o, nil
+	}
+
+	if txt, ok := v.(string); ok && reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		o := reflect.New(t)
+		err := o.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(txt))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return o.Elem(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if m, ok := typedValue(v); ok {
+			typ, err := lookupType(m.typ)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if !typ.Implements(t) {
+				return reflect.Value{}, fmt.Errorf("fwk/job: type %v does not implement %v", typ, t)
+			}
+			elem, err := decodeValue(m.value, typ)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			o := reflect.New(t).Elem()
+			o.Set(elem)
+			return o, nil
+		}
+		if t.NumMethod() == 0 {
+			o := reflect.New(t).Elem()
+			o.Set(rv)
+			return o, nil
+		}
+		return reflect.Value{}, fmt.Errorf("fwk/job: missing concrete type for value of type %v", t)
+
+	case reflect.Ptr:
+		elem, err := decodeValue(v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		o := reflect.New(t.Elem())
+		o.Elem().Set(elem)
+		return o, nil
+
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		return reflect.ValueOf(b).Convert(t), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s, ok := v.(string); ok && t == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("fwk/job: could not decode duration: %w", err)
+			}
+			return reflect.ValueOf(d), nil
+		}
+		i, ok := toInt(rv)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		o := reflect.New(t).Elem()
+		if o.OverflowInt(i) {
+			return reflect.Value{}, fmt.Errorf("fwk/job: value %v overflows %v", v, t)
+		}
+		o.SetInt(i)
+		return o, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, ok := toUint(rv)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		o := reflect.New(t).Elem()
+		if o.OverflowUint(u) {
+			return reflect.Value{}, fmt.Errorf("fwk/job: value %v overflows %v", v, t)
+		}
+		o.SetUint(u)
+		return o, nil
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch {
+		case rv.CanFloat():
+			f = rv.Float()
+		case rv.CanInt():
+			f = float64(rv.Int())
+		case rv.CanUint():
+			f = float64(rv.Uint())
+		default:
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		return reflect.ValueOf(f).Convert(t), nil
+
+	case reflect.Complex64, reflect.Complex128:
+		s, ok := v.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		c, err := strconv.ParseComplex(s, 128)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("fwk/job: could not decode complex: %w", err)
+		}
+		return reflect.ValueOf(c).Convert(t), nil
+
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		return reflect.ValueOf(s).Convert(t), nil
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		var o reflect.Value
+		switch t.Kind() {
+		case reflect.Slice:
+			o = reflect.MakeSlice(t, rv.Len(), rv.Len())
+		default:
+			if rv.Len() != t.Len() {
+				return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %d values into a %v", rv.Len(), t)
+			}
+			o = reflect.New(t).Elem()
+		}
+		for i := range rv.Len() {
+			elem, err := decodeValue(rv.Index(i).Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			o.Index(i).Set(elem)
+		}
+		return o, nil
+
+	case reflect.Map:
+		if rv.Kind() != reflect.Map {
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		o := reflect.MakeMapWithSize(t, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := decodeKey(iter.Key().Interface(), t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			elem, err := decodeValue(iter.Value().Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			o.SetMapIndex(key, elem)
+		}
+		return o, nil
+
+	case reflect.Struct:
+		if rv.Kind() != reflect.Map {
+			return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+		}
+		type field struct {
+			name  string
+			value any
+		}
+		var (
+			o      = reflect.New(t).Elem()
+			fields = make([]field, 0, rv.Len())
+			iter   = rv.MapRange()
+		)
+		for iter.Next() {
+			fields = append(fields, field{
+				name:  fmt.Sprint(iter.Key().Interface()),
+				value: iter.Value().Interface(),
+			})
+		}
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].name < fields[j].name
+		})
+		for _, fv := range fields {
+			f, ok := t.FieldByNameFunc(func(name string) bool {
+				return strings.EqualFold(name, fv.name)
+			})
+			if !ok || !f.IsExported() {
+				return reflect.Value{}, fmt.Errorf("fwk/job: type %v has no field %q", t, fv.name)
+			}
+			elem, err := decodeValue(fv.value, f.Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("fwk/job: could not decode field %q of %v: %w", fv.name, t, err)
+			}
+			o.FieldByIndex(f.Index).Set(elem)
+		}
+		return o, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("fwk/job: can not decode %v (type=%T) into a %v", v, v, t)
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// decodeKey converts the map key k into a value of type t.
+func decodeKey(k any, t reflect.Type) (reflect.Value, error) {
+	s, ok := k.(string)
+	if !ok {
+		return decodeValue(k, t)
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("fwk/job: invalid map key %q: %w", s, err)
+		}
+		return decodeValue(i, t)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("fwk/job: invalid map key %q: %w", s, err)
+		}
+		return decodeValue(u, t)
+	}
+	return decodeValue(s, t)
+}
+
+// typed is an interface value held in a configuration file.
+type typed struct {
+	typ   string
+	value any
+}
+
+// typedValue returns whether v is a {"type": name, "value": value} map.
+func typedValue(v any) (typed, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Len() != 2 || rv.Type().Key().Kind() != reflect.String {
+		return typed{}, false
+	}
+	var (
+		typ   = rv.MapIndex(reflect.ValueOf("type").Convert(rv.Type().Key()))
+		value = rv.MapIndex(reflect.ValueOf("value").Convert(rv.Type().Key()))
+	)
+	if !typ.IsValid() || !value.IsValid() {
+		return typed{}, false
+	}
+	name, ok := typ.Interface().(string)
+	if !ok {
+		return typed{}, false
+	}
+	return typed{typ: name, value: value.Interface()}, true
+}
+
+func toInt(v reflect.Value) (int64, bool) {
+	switch {
+	case v.CanInt():
+		return v.Int(), true
+	case v.CanUint():
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(u), true
+	case v.CanFloat():
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(f), true
+	}
+	return 0, false
+}
+
+func toUint(v reflect.Value) (uint64, bool) {
+	switch {
+	case v.CanUint():
+		return v.Uint(), true
+	case v.CanInt():
+		i := v.Int()
+		if i < 0 {
+			return 0, false
+		}
+		return uint64(i), true
+	case v.CanFloat():
+		f := v.Float()
+		if f != math.Trunc(f) || f < 0 || f > math.MaxUint64 {
+			return 0, false
+		}
+		return uint64(f), true
+	}
+	return 0, false
+}
@@ -0,0 +1,84 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package job
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+var yamlCodec = codec{
+	unmarshal: func(data []byte, doc *document) error {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err := dec.Decode(doc)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("fwk/job: could not decode YAML job description: %w", err)
+		}
+		return nil
+	},
+	marshal: func(w io.Writer, doc *document) error {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		err := enc.Encode(doc)
+		if err != nil {
+			return fmt.Errorf("fwk/job: could not encode YAML job description: %w", err)
+		}
+		return enc.Close()
+	},
+}
+
+// NewYAMLEncoder returns a new encoder that writes job descriptions
+// in YAML to w.
+func NewYAMLEncoder(w io.Writer) *YAMLEncoder {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &YAMLEncoder{w: w}
+}
+
+// A YAMLEncoder writes job descriptions in YAML to an output stream.
+type YAMLEncoder struct {
+	w io.Writer
+}
+
+// Encode encodes the []Stmt data into the underlying io.Writer.
+func (enc *YAMLEncoder) Encode(data any) error {
+	doc, err := encodeStmts(data)
+	if err != nil {
+		return err
+	}
+	return yamlCodec.marshal(enc.w, doc)
+}
+
+// NewYAMLDecoder returns a new decoder that reads job descriptions
+// in YAML from r.
+func NewYAMLDecoder(r io.Reader) *YAMLDecoder {
+	return &YAMLDecoder{r: r, Dir: "."}
+}
+
+// A YAMLDecoder reads job descriptions in YAML from an input stream.
+type YAMLDecoder struct {
+	r io.Reader
+
+	Dir       string    // directory against which included files are resolved
+	Overrides Overrides // overrides of the properties of the job description
+}
+
+// Decode decodes a job description into the *[]Stmt ptr.
+// Properties are validated against the properties declared by the
+// components.
+func (dec *YAMLDecoder) Decode(ptr any) error {
+	return decodeInto(ptr, dec.r, yamlCodec, dec.Dir, dec.Overrides)
+}
+
+var (
+	_ Encoder = (*YAMLEncoder)(nil)
+	_ Decoder = (*YAMLDecoder)(nil)
+)
@@ -0,0 +1,149 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-hep.org/x/hep/fwk/fsm"
+)
+
+// workerEnv is the environment variable holding the index of a worker
+// process of a multi-process application.
+const workerEnv = "FWK_WORKER"
+
+// OutputMerger is implemented by components writing output files.
+//
+// In multi-process mode (NWorkers > 0), each worker process writes its own
+// output files (see OutputFile).
+// Once all the workers are done, the main process calls MergeOutputs to
+// merge the files written by the nworkers workers into the final outputs
+// and remove them.
+type OutputMerger interface {
+	MergeOutputs(ctx Context, nworkers int) error
+}
+
+// Worker returns the index of the current worker process of a
+// multi-process application, and whether the current process is such a
+// worker.
+func Worker() (int, bool) {
+	v, ok := os.LookupEnv(workerEnv)
+	if !ok {
+		return -1, false
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return -1, false
+	}
+	return i, true
+}
+
+// OutputFile returns the name of the file to write in place of the named
+// output file.
+// In a worker process of a multi-process application, OutputFile returns
+// the per-worker name of the file (see WorkerFile).
+// Otherwise, name is returned unchanged.
+func OutputFile(name string) string {
+	i, ok := Worker()
+	if !ok {
+		return name
+	}
+	return WorkerFile(name, i)
+}
+
+// WorkerFile returns the name of the file written by the i-th worker
+// process in place of the named output file.
+//
+// eg: WorkerFile("out.rio", 1) -> "out.worker-001.rio"
+func WorkerFile(name string, i int) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s.worker-%03d%s", strings.TrimSuffix(name, ext), i, ext)
+}
+
+// owns returns whether the event ievt is to be processed by the current
+// process.
+// Worker processes process ranges of EvtRange consecutive events, in turn.
+func (app *appmgr) owns(ievt int64) bool {
+	if app.worker < 0 {
+		return true
+	}
+	return (ievt/app.evtrange)%int64(app.nworkers) == int64(app.worker)
+}
+
+// runWorkers runs the application in app.nworkers worker processes and
+// merges their outputs.
+func (app *appmgr) runWorkers(ctx Context) error {
+	var err error
+	defer app.msg.flush()
+	app.state = fsm.Running
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("fwk: could not locate executable: %w", err)
+	}
+
+	start := time.Now()
+	cmds := make([]*exec.Cmd, app.nworkers)
+	for i := range cmds {
+		cmd := exec.Command(exe, os.Args[1:]...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", workerEnv, i))
+		err = cmd.Start()
+		if err != nil {
+			for _, cmd := range cmds[:i] {
+				_ = cmd.Process.Kill()
+				_ = cmd.Wait()
+			}
+			return fmt.Errorf("fwk: could not start worker %d: %w", i, err)
+		}
+		app.msg.Infof("started worker %d (pid=%d)\n", i, cmd.Process.Pid)
+		cmds[i] = cmd
+	}
+
+	for i, cmd := range cmds {
+		werr := cmd.Wait()
+		if werr != nil && err == nil {
+			err = fmt.Errorf("fwk: worker %d failed: %w", i, werr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	app.msg.Infof("workers done: %d/%d (%v)\n", len(cmds), app.nworkers, time.Since(start))
+
+	for i, svc := range app.svcs {
+		m, ok := svc.(OutputMerger)
+		if !ok {
+			continue
+		}
+		app.msg.Debugf("merging outputs of [%s]...\n", svc.Name())
+		err = m.MergeOutputs(app.ctxs[1][i], app.nworkers)
+		if err != nil {
+			return fmt.Errorf("fwk: could not merge outputs of %q: %w", svc.Name(), err)
+		}
+	}
+
+	for i, tsk := range app.tsks {
+		m, ok := tsk.(OutputMerger)
+		if !ok {
+			continue
+		}
+		app.msg.Debugf("merging outputs of [%s]...\n", tsk.Name())
+		err = m.MergeOutputs(app.ctxs[0][i], app.nworkers)
+		if err != nil {
+			return fmt.Errorf("fwk: could not merge outputs of %q: %w", tsk.Name(), err)
+		}
+	}
+
+	app.state = fsm.Stopped
+	return err
+}
@@ -0,0 +1,263 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/internal/fwktest"
+	"go-hep.org/x/hep/fwk/job"
+)
+
+// mpEnv holds the configuration of the multi-process test application,
+// for the worker processes.
+const mpEnv = "FWK_MP_TEST"
+
+func TestMain(m *testing.M) {
+	if cfg, ok := os.LookupEnv(mpEnv); ok {
+		if _, ok := fwk.Worker(); ok {
+			// worker process of a multi-process test application.
+			err := newMPApp(cfg).App().Run()
+			if err != nil {
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+	os.Exit(m.Run())
+}
+
+// newMPApp creates a multi-process application from its configuration:
+// "output-file,nprocs,nslots,faulty".
+func newMPApp(cfg string) *job.Job {
+	var (
+		toks      = strings.Split(cfg, ",")
+		oname     = toks[0]
+		nprocs, _ = strconv.Atoi(toks[1])
+		nslots, _ = strconv.Atoi(toks[2])
+		faulty, _ = strconv.ParseBool(toks[3])
+	)
+
+	app := job.NewJob(nil, job.P{
+		"EvtMax":   int64(25),
+		"NProcs":   nprocs,
+		"EvtSlots": nslots,
+		"NWorkers": 3,
+		"EvtRange": int64(4),
+		"MsgLevel": job.MsgLevel("ERROR"),
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.InputStream",
+		Name: "input",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{Name: "ints", Type: reflect.TypeOf(int64(1))},
+			},
+			"Streamer": &fwktest.InputStream{
+				R: newTestReader(100),
+			},
+		},
+	})
+
+	switch {
+	case faulty:
+		app.Create(job.C{
+			Type: "go-hep.org/x/hep/fwk/internal/fwktest.faulty",
+			Name: "faulty",
+			Props: job.P{
+				"Input":  "ints",
+				"Output": "ints-sq",
+				"Modulo": int64(13),
+			},
+		})
+	default:
+		app.Create(job.C{
+			Type: "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+			Name: "t2",
+			Props: job.P{
+				"Input":  "ints",
+				"Output": "ints-sq",
+			},
+		})
+	}
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.OutputStream",
+		Name: "output",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{Name: "ints-sq", Type: reflect.TypeOf(int64(1))},
+			},
+			"Streamer": &textStreamer{Name: oname},
+		},
+	})
+
+	return app
+}
+
+func TestMultiProcess(t *testing.T) {
+	for _, tc := range []struct {
+		nprocs int
+		nslots int
+	}{
+		{0, 0},
+		{4, 0},
+		{4, 4},
+	} {
+		t.Run(fmt.Sprintf("nprocs=%d-nslots=%d", tc.nprocs, tc.nslots), func(t *testing.T) {
+			oname := filepath.Join(t.TempDir(), "out.txt")
+			cfg := fmt.Sprintf("%s,%d,%d,%v", oname, tc.nprocs, tc.nslots, false)
+			t.Setenv(mpEnv, cfg)
+
+			err := newMPApp(cfg).App().Run()
+			if err != nil {
+				t.Fatalf("could not run job: %+v", err)
+			}
+
+			f, err := os.Open(oname)
+			if err != nil {
+				t.Fatalf("could not open merged output: %+v", err)
+			}
+			defer f.Close()
+
+			var ids []int
+			scan := bufio.NewScanner(f)
+			for scan.Scan() {
+				var id, worker, v int
+				_, err := fmt.Sscanf(scan.Text(), "%d %d %d", &id, &worker, &v)
+				if err != nil {
+					t.Fatalf("could not parse line %q: %+v", scan.Text(), err)
+				}
+				if want := (id / 4) % 3; worker != want {
+					t.Fatalf("event %d processed by worker %d, want=%d", id, worker, want)
+				}
+				if v != id*id {
+					t.Fatalf("invalid value for event %d: %d", id, v)
+				}
+				ids = append(ids, id)
+			}
+			if err := scan.Err(); err != nil {
+				t.Fatalf("could not scan merged output: %+v", err)
+			}
+
+			sort.Ints(ids)
+			want := make([]int, 25)
+			for i := range want {
+				want[i] = i
+			}
+			if !reflect.DeepEqual(ids, want) {
+				t.Fatalf("invalid events:\ngot= %v\nwant=%v", ids, want)
+			}
+
+			for i := range 3 {
+				_, err := os.Stat(fwk.WorkerFile(oname, i))
+				if !os.IsNotExist(err) {
+					t.Fatalf("output of worker %d was not removed: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiProcessWorkerFailure(t *testing.T) {
+	oname := filepath.Join(t.TempDir(), "out.txt")
+	cfg := fmt.Sprintf("%s,%d,%d,%v", oname, 0, 0, true)
+	t.Setenv(mpEnv, cfg)
+
+	err := newMPApp(cfg).App().Run()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if want := "fwk: worker 0 failed"; !strings.Contains(err.Error(), want) {
+		t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, want)
+	}
+
+	_, err = os.Stat(oname)
+	if !os.IsNotExist(err) {
+		t.Fatalf("merged output should not have been created: %v", err)
+	}
+}
+
+func TestWorkerFile(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		i    int
+		want string
+	}{
+		{"out.rio", 0, "out.worker-000.rio"},
+		{"out.rio", 12, "out.worker-012.rio"},
+		{"dir/out", 1, "dir/out.worker-001"},
+		{"out.tar.gz", 2, "out.tar.worker-002.gz"},
+	} {
+		if got := fwk.WorkerFile(tc.name, tc.i); got != tc.want {
+			t.Errorf("WorkerFile(%q, %d): got=%q, want=%q", tc.name, tc.i, got, tc.want)
+		}
+	}
+
+	if got, want := fwk.OutputFile("out.rio"), "out.rio"; got != want {
+		t.Errorf("invalid output file: got=%q, want=%q", got, want)
+	}
+}
+
+// textStreamer writes the ID, the worker index and the value of each event
+// to a text file.
+type textStreamer struct {
+	Name string
+
+	f     *os.File
+	input string
+}
+
+func (out *textStreamer) Connect(ports []fwk.Port) error {
+	var err error
+	out.input = ports[0].Name
+	out.f, err = os.Create(fwk.OutputFile(out.Name))
+	return err
+}
+
+func (out *textStreamer) Write(ctx fwk.Context) error {
+	v, err := ctx.Store().Get(out.input)
+	if err != nil {
+		return err
+	}
+	worker, _ := fwk.Worker()
+	_, err = fmt.Fprintf(out.f, "%d %d %d\n", ctx.ID(), worker, v.(int64))
+	return err
+}
+
+func (out *textStreamer) Disconnect() error {
+	return out.f.Close()
+}
+
+func (out *textStreamer) MergeOutputs(ctx fwk.Context, nworkers int) error {
+	for i := range nworkers {
+		name := fwk.WorkerFile(out.Name, i)
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out.f, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		err = os.Remove(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+// MonSvc is the interface of services monitoring the execution of the
+// event loop.
+//
+// The application notifies all its MonSvc services at the beginning and
+// the end of each event and before and after each task processes an
+// event.
+type MonSvc interface {
+	Svc
+
+	// BeginEvent is called before the tasks process the event of ctx.
+	BeginEvent(ctx Context)
+
+	// EndEvent is called after all the tasks processed the event of ctx.
+	EndEvent(ctx Context)
+
+	// BeginTask is called before task tsk processes the event of ctx,
+	// on the goroutine running the task.
+	BeginTask(ctx Context, tsk Task)
+
+	// EndTask is called after task tsk processed the event of ctx,
+	// on the same goroutine as BeginTask, with the error returned
+	// by the task.
+	EndTask(ctx Context, tsk Task, err error)
+}
+
+// beginEvent notifies the monitoring services of a new event.
+func (run taskrunner) beginEvent(ctx Context) {
+	for _, mon := range run.mons {
+		mon.BeginEvent(ctx)
+	}
+}
+
+// endEvent notifies the monitoring services of the end of an event.
+func (run taskrunner) endEvent(ctx Context) {
+	for _, mon := range run.mons {
+		mon.EndEvent(ctx)
+	}
+}
@@ -5,6 +5,7 @@
 package fwk
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -18,11 +19,20 @@ import (
 //
 // OutputStream declares a property 'Streamer', a fwk.OutputStreamer,
 // which will be used to actually write data to.
+//
+// OutputStream declares a property 'Paths', a []string, holding the names
+// of the fwk.Sequence values selecting the events to write out.
+// An event is written out if any of these sequences accepted it.
+// All events are written out when 'Paths' is empty.
+//
+// In multi-process mode, OutputStream merges the outputs of the worker
+// processes when its OutputStreamer implements OutputMerger.
 type OutputStream struct {
 	TaskBase
 
 	streamer OutputStreamer
 	ctrl     StreamControl
+	paths    []string
 }
 
 // Configure declares the input ports defined by the 'Ports' property.
@@ -68,6 +78,31 @@ func (tsk *OutputStream) disconnect() error {
 	return tsk.streamer.Disconnect()
 }
 
+// MergeOutputs merges the outputs written by the worker processes of a
+// multi-process application, if the underlying OutputStreamer implements
+// OutputMerger.
+// The OutputStreamer is connected before merging, and disconnected once
+// the merge is done.
+func (tsk *OutputStream) MergeOutputs(ctx Context, nworkers int) error {
+	m, ok := tsk.streamer.(OutputMerger)
+	if !ok {
+		return fmt.Errorf("fwk: output streamer %T can not merge outputs", tsk.streamer)
+	}
+
+	err := tsk.streamer.Connect(tsk.ctrl.Ports)
+	if err != nil {
+		return err
+	}
+
+	err = m.MergeOutputs(ctx, nworkers)
+	if err != nil {
+		_ = tsk.streamer.Disconnect()
+		return err
+	}
+
+	return tsk.streamer.Disconnect()
+}
+
 func (tsk *OutputStream) write() {
 	for {
 		select {
@@ -116,6 +151,11 @@ func newOutputStream(typ, name string, mgr App) (Component, error) {
 		return nil, err
 	}
 
+	err = tsk.DeclProp("Paths", &tsk.paths)
+	if err != nil {
+		return nil, err
+	}
+
 	return tsk, err
 }
 
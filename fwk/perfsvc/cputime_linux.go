@@ -0,0 +1,24 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package perfsvc
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const hasCPUTime = true
+
+// cpuTime returns the CPU time (user+system) consumed by the current
+// OS thread.
+func cpuTime() time.Duration {
+	var ru unix.Rusage
+	err := unix.Getrusage(unix.RUSAGE_THREAD, &ru)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
@@ -0,0 +1,19 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package perfsvc
+
+import (
+	"time"
+)
+
+const hasCPUTime = false
+
+// cpuTime returns the CPU time consumed by the current OS thread.
+// Per-thread CPU time is not available on this platform.
+func cpuTime() time.Duration {
+	return 0
+}
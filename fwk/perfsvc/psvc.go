@@ -0,0 +1,426 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package perfsvc provides a fwk.MonSvc monitoring the performances of
+// the tasks of an application.
+//
+// The service records, for each task, the wall time, the CPU time and the
+// memory allocations spent processing events, together with the wall
+// time and the throughput of events.
+// A summary table is written when the service is stopped.
+// Timings can also be histogrammed through a fwk.HistSvc and the spans of
+// events and tasks can be written as a Chrome trace-event JSON document,
+// to be visualized with chrome://tracing or https://ui.perfetto.dev.
+//
+// CPU times are measured per OS thread and are only available on Linux.
+// Memory allocations are measured from process-wide counters: they are
+// exact only when tasks do not run concurrently.
+package perfsvc // import "go-hep.org/x/hep/fwk/perfsvc"
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+type psvc struct {
+	fwk.SvcBase
+	mgr fwk.App
+
+	summary string  // name of the file where to write the summary table
+	trace   string  // name of the file where to write the Chrome trace
+	hsvc    string  // name of the fwk.HistSvc service
+	hstream string  // name of the hbook stream of the histograms
+	hmax    float64 // upper bound of the timing histograms, in ms
+
+	mu    sync.Mutex
+	t0    time.Time
+	beg   time.Time // begin of the first event
+	end   time.Time // end of the last event
+	evts  stats
+	tsks  map[string]*stats
+	order []string // names of the tasks, in order of first appearance
+	marks map[key]mark
+	tr    *tracer
+
+	hists fwk.HistSvc
+	hids  map[string]hids
+}
+
+// key identifies a task processing an event.
+type key struct {
+	name string
+	evt  int64
+}
+
+// mark holds the state of counters when a task or an event began.
+type mark struct {
+	t     time.Time
+	cpu   time.Duration
+	bytes uint64
+	objs  uint64
+}
+
+func now() mark {
+	var samples = [...]metrics.Sample{
+		{Name: "/gc/heap/allocs:bytes"},
+		{Name: "/gc/heap/allocs:objects"},
+	}
+	metrics.Read(samples[:])
+	return mark{
+		t:     time.Now(),
+		cpu:   cpuTime(),
+		bytes: samples[0].Value.Uint64(),
+		objs:  samples[1].Value.Uint64(),
+	}
+}
+
+// stats holds aggregated performance counters.
+type stats struct {
+	n     int64 // number of calls
+	fail  int64 // number of failed calls
+	wall  time.Duration
+	min   time.Duration
+	max   time.Duration
+	cpu   time.Duration
+	bytes uint64
+	objs  uint64
+}
+
+func (st *stats) add(beg, end mark, err error) {
+	wall := end.t.Sub(beg.t)
+	if st.n == 0 || wall < st.min {
+		st.min = wall
+	}
+	st.max = max(st.max, wall)
+	st.n++
+	if err != nil {
+		st.fail++
+	}
+	st.wall += wall
+	st.cpu += end.cpu - beg.cpu
+	st.bytes += end.bytes - beg.bytes
+	st.objs += end.objs - beg.objs
+}
+
+func (st *stats) mean() time.Duration {
+	if st.n == 0 {
+		return 0
+	}
+	return st.wall / time.Duration(st.n)
+}
+
+// hids holds the identifiers of the histograms of a task.
+type hids struct {
+	wall fwk.HID
+	cpu  fwk.HID
+}
+
+func (svc *psvc) Configure(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (svc *psvc) StartSvc(ctx fwk.Context) error {
+	var err error
+
+	svc.t0 = time.Now()
+	svc.beg = time.Time{}
+	svc.end = time.Time{}
+	svc.evts = stats{}
+	svc.tsks = make(map[string]*stats)
+	svc.order = nil
+	svc.marks = make(map[key]mark)
+	svc.tr = nil
+	if svc.trace != "" {
+		svc.tr = newTracer(svc.t0)
+	}
+
+	svc.hists = nil
+	svc.hids = make(map[string]hids)
+	if svc.hsvc == "" {
+		return err
+	}
+
+	hsvc, err := ctx.Svc(svc.hsvc)
+	if err != nil {
+		return err
+	}
+	hists, ok := hsvc.(fwk.HistSvc)
+	if !ok {
+		return fmt.Errorf("%s: service [%s] is not a fwk.HistSvc (type=%T)", svc.Name(), svc.hsvc, hsvc)
+	}
+	svc.hists = hists
+
+	book := func(name string) (hids, error) {
+		var ids hids
+		dir := path.Join("/", svc.hstream, "perf", name)
+		wall, err := hists.BookH1D(path.Join(dir, "wall"), 100, 0, svc.hmax)
+		if err != nil {
+			return ids, err
+		}
+		ids.wall = wall.ID
+		if !hasCPUTime {
+			return ids, nil
+		}
+		cpu, err := hists.BookH1D(path.Join(dir, "cpu"), 100, 0, svc.hmax)
+		if err != nil {
+			return ids, err
+		}
+		ids.cpu = cpu.ID
+		return ids, nil
+	}
+
+	svc.hids[""], err = book("events")
+	if err != nil {
+		return err
+	}
+	for _, tsk := range svc.mgr.Tasks() {
+		svc.hids[tsk.Name()], err = book(path.Join("tasks", tsk.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (svc *psvc) StopSvc(ctx fwk.Context) error {
+	var err error
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	switch svc.summary {
+	case "":
+		for _, line := range strings.Split(strings.TrimSpace(svc.table()), "\n") {
+			ctx.Msg().Infof("%s\n", line)
+		}
+	default:
+		err = svc.write(svc.summary, func(w io.Writer) error {
+			_, err := io.WriteString(w, svc.table())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if svc.tr != nil {
+		err = svc.write(svc.trace, svc.tr.write)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (svc *psvc) write(fname string, fct func(w io.Writer) error) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return fmt.Errorf("%s: could not create %q: %w", svc.Name(), fname, err)
+	}
+	defer f.Close()
+
+	err = fct(f)
+	if err != nil {
+		return fmt.Errorf("%s: could not write %q: %w", svc.Name(), fname, err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: could not close %q: %w", svc.Name(), fname, err)
+	}
+	return nil
+}
+
+// table returns the summary table of the performance counters.
+func (svc *psvc) table() string {
+	var (
+		o    strings.Builder
+		wall = svc.end.Sub(svc.beg)
+		rate = 0.0
+	)
+	if wall > 0 {
+		rate = float64(svc.evts.n) / wall.Seconds()
+	}
+	fmt.Fprintf(&o, "events: %d, wall: %v, throughput: %.3f evt/s\n", svc.evts.n, wall, rate)
+
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+	}
+	cpu := func(st *stats) string {
+		if !hasCPUTime {
+			return "n/a"
+		}
+		return ms(st.cpu)
+	}
+	perEvt := func(st *stats, v uint64) string {
+		if st.n == 0 {
+			return "0"
+		}
+		return fmt.Sprintf("%d", v/uint64(st.n))
+	}
+
+	w := tabwriter.NewWriter(&o, 8, 4, 1, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "name\tcalls\tfails\twall [ms]\tmean [ms]\tmin [ms]\tmax [ms]\tcpu [ms]\tbytes/call\tallocs/call\t\n")
+	line := func(name string, st *stats) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			name, st.n, st.fail,
+			ms(st.wall), ms(st.mean()), ms(st.min), ms(st.max),
+			cpu(st),
+			perEvt(st, st.bytes), perEvt(st, st.objs),
+		)
+	}
+	line("<events>", &svc.evts)
+	for _, name := range svc.order {
+		line(name, svc.tsks[name])
+	}
+	w.Flush()
+
+	return o.String()
+}
+
+func (svc *psvc) BeginEvent(ctx fwk.Context) {
+	beg := now()
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if svc.beg.IsZero() {
+		svc.beg = beg.t
+	}
+	svc.marks[key{evt: ctx.ID()}] = beg
+}
+
+func (svc *psvc) EndEvent(ctx fwk.Context) {
+	end := now()
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	k := key{evt: ctx.ID()}
+	beg, ok := svc.marks[k]
+	if !ok {
+		return
+	}
+	delete(svc.marks, k)
+
+	svc.end = end.t
+	svc.evts.add(beg, end, nil)
+	svc.fill(svc.hids[""], beg, end)
+	if svc.tr != nil {
+		svc.tr.event(ctx.Slot(), ctx.ID(), beg.t, end.t)
+	}
+}
+
+func (svc *psvc) BeginTask(ctx fwk.Context, tsk fwk.Task) {
+	// CPU time is measured per OS thread.
+	runtime.LockOSThread()
+	beg := now()
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.marks[key{name: tsk.Name(), evt: ctx.ID()}] = beg
+}
+
+func (svc *psvc) EndTask(ctx fwk.Context, tsk fwk.Task, err error) {
+	end := now()
+	runtime.UnlockOSThread()
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	name := tsk.Name()
+	k := key{name: name, evt: ctx.ID()}
+	beg, ok := svc.marks[k]
+	if !ok {
+		return
+	}
+	delete(svc.marks, k)
+
+	st, ok := svc.tsks[name]
+	if !ok {
+		st = new(stats)
+		svc.tsks[name] = st
+		svc.order = append(svc.order, name)
+	}
+	st.add(beg, end, err)
+	svc.fill(svc.hids[name], beg, end)
+	if svc.tr != nil {
+		svc.tr.task(ctx.Slot(), ctx.ID(), name, beg.t, end.t, err)
+	}
+}
+
+// fill fills the timing histograms ids.
+func (svc *psvc) fill(ids hids, beg, end mark) {
+	if svc.hists == nil {
+		return
+	}
+	const ms = float64(time.Millisecond)
+	if ids.wall != "" {
+		svc.hists.FillH1D(ids.wall, float64(end.t.Sub(beg.t))/ms, 1)
+	}
+	if ids.cpu != "" {
+		svc.hists.FillH1D(ids.cpu, float64(end.cpu-beg.cpu)/ms, 1)
+	}
+}
+
+func newpsvc(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+	svc := &psvc{
+		SvcBase: fwk.NewSvc(typ, name, mgr),
+		mgr:     mgr,
+		hstream: "perf",
+		hmax:    100,
+		tsks:    make(map[string]*stats),
+		marks:   make(map[key]mark),
+		hids:    make(map[string]hids),
+	}
+
+	err = svc.DeclProp("Summary", &svc.summary)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("Trace", &svc.trace)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("HistSvc", &svc.hsvc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("HistStream", &svc.hstream)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("HistMax", &svc.hmax)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(psvc{}), newpsvc)
+}
+
+var _ fwk.MonSvc = (*psvc)(nil)
@@ -0,0 +1,207 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package perfsvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/hbooksvc"
+	_ "go-hep.org/x/hep/fwk/internal/fwktest"
+	"go-hep.org/x/hep/fwk/job"
+)
+
+const nentries = 100
+
+func TestPerfSvc(t *testing.T) {
+	for _, tc := range []struct {
+		nprocs int
+		nslots int
+	}{
+		{0, 0},
+		{4, 0},
+		{4, 4},
+	} {
+		t.Run(fmt.Sprintf("nprocs=%d-nslots=%d", tc.nprocs, tc.nslots), func(t *testing.T) {
+			var (
+				dir     = t.TempDir()
+				summary = filepath.Join(dir, "perf.txt")
+				trace   = filepath.Join(dir, "trace.json")
+				hists   = filepath.Join(dir, "perf.rio")
+			)
+
+			app := job.NewJob(nil, job.P{
+				"EvtMax":        int64(nentries),
+				"NProcs":        tc.nprocs,
+				"EvtSlots":      tc.nslots,
+				"MsgLevel":      job.MsgLevel("ERROR"),
+				"ErrorPolicies": map[string]fwk.ErrorPolicy{"bad": fwk.Continue},
+			})
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/hbooksvc.hsvc",
+				Name: "histsvc",
+				Props: job.P{
+					"Streams": map[string]hbooksvc.Stream{
+						"/perf": {Name: hists, Mode: hbooksvc.Write},
+					},
+				},
+			})
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/perfsvc.psvc",
+				Name: "perfsvc",
+				Props: job.P{
+					"Summary": summary,
+					"Trace":   trace,
+					"HistSvc": "histsvc",
+				},
+			})
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/perfsvc.testperf",
+				Name: "gen",
+			})
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/internal/fwktest.faulty",
+				Name: "bad",
+				Props: job.P{
+					"Input":  "ints",
+					"Output": "good-ints",
+					"Modulo": int64(10),
+				},
+			})
+
+			err := app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run application: %+v", err)
+			}
+
+			checkSummary(t, summary)
+			checkTrace(t, trace)
+
+			fi, err := os.Stat(hists)
+			if err != nil {
+				t.Fatalf("could not stat histograms file: %+v", err)
+			}
+			if fi.Size() == 0 {
+				t.Fatalf("empty histograms file")
+			}
+		})
+	}
+}
+
+func checkSummary(t *testing.T, fname string) {
+	t.Helper()
+
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatalf("could not read summary: %+v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if got, want := lines[0], fmt.Sprintf("events: %d,", nentries); !strings.HasPrefix(got, want) {
+		t.Fatalf("invalid summary header:\ngot= %q\nwant=%q", got, want)
+	}
+
+	want := map[string][2]int{
+		"<events>": {nentries, 0},
+		"gen":      {nentries, 0},
+		"bad":      {nentries, nentries / 10},
+	}
+	got := make(map[string][2]int)
+	for _, line := range lines[2:] {
+		fields := strings.Fields(line)
+		calls, err := strconv.Atoi(fields[1])
+		if err != nil {
+			t.Fatalf("could not parse line %q: %+v", line, err)
+		}
+		fails, err := strconv.Atoi(fields[2])
+		if err != nil {
+			t.Fatalf("could not parse line %q: %+v", line, err)
+		}
+		got[fields[0]] = [2]int{calls, fails}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid summary:\ngot= %v\nwant=%v\n%s", got, want, raw)
+	}
+}
+
+func checkTrace(t *testing.T, fname string) {
+	t.Helper()
+
+	f, err := os.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open trace: %+v", err)
+	}
+	defer f.Close()
+
+	var trace struct {
+		Events []traceEvent `json:"traceEvents"`
+	}
+	err = json.NewDecoder(f).Decode(&trace)
+	if err != nil {
+		t.Fatalf("could not decode trace: %+v", err)
+	}
+
+	var (
+		evts = make(map[string]int)
+		errs = 0
+	)
+	for _, evt := range trace.Events {
+		if evt.Ph != "X" {
+			continue
+		}
+		switch evt.Cat {
+		case "event":
+			evts["<events>"]++
+		case "task":
+			evts[evt.Name]++
+		}
+		if _, ok := evt.Args["error"]; ok {
+			errs++
+		}
+	}
+
+	want := map[string]int{"<events>": nentries, "gen": nentries, "bad": nentries}
+	if !reflect.DeepEqual(evts, want) {
+		t.Fatalf("invalid trace events:\ngot= %v\nwant=%v", evts, want)
+	}
+	if got, want := errs, nentries/10; got != want {
+		t.Fatalf("invalid number of failed tasks: got=%d, want=%d", got, want)
+	}
+}
+
+// testperf puts the event number in the store.
+type testperf struct {
+	fwk.TaskBase
+}
+
+func (tsk *testperf) Configure(ctx fwk.Context) error {
+	return tsk.DeclOutPort("ints", reflect.TypeOf(int64(0)))
+}
+
+func (tsk *testperf) StartTask(ctx fwk.Context) error { return nil }
+func (tsk *testperf) StopTask(ctx fwk.Context) error  { return nil }
+
+func (tsk *testperf) Process(ctx fwk.Context) error {
+	return ctx.Store().Put("ints", ctx.ID())
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(testperf{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			return &testperf{TaskBase: fwk.NewTask(typ, name, mgr)}, nil
+		},
+	)
+}
@@ -0,0 +1,130 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package perfsvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// traceEvent is an event of the Chrome trace-event format.
+//
+// See https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type traceEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`            // timestamp, in microseconds
+	Dur  float64        `json:"dur,omitempty"` // duration, in microseconds
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// tracer records the spans of events and tasks.
+// Spans of a given event slot are grouped under the same process, with
+// one thread for the events and one thread per task.
+type tracer struct {
+	t0    time.Time
+	tids  map[string]int // thread ids of tasks
+	names []string       // names of tasks, by thread id
+	slots map[int]bool
+	evts  []traceEvent
+}
+
+func newTracer(t0 time.Time) *tracer {
+	return &tracer{
+		t0:    t0,
+		tids:  make(map[string]int),
+		names: []string{"events"},
+		slots: make(map[int]bool),
+	}
+}
+
+func (tr *tracer) us(t time.Time) float64 {
+	return float64(t.Sub(tr.t0).Nanoseconds()) / 1e3
+}
+
+func (tr *tracer) event(slot int, id int64, beg, end time.Time) {
+	tr.slots[slot] = true
+	tr.evts = append(tr.evts, traceEvent{
+		Name: fmt.Sprintf("evt-%d", id),
+		Cat:  "event",
+		Ph:   "X",
+		Ts:   tr.us(beg),
+		Dur:  tr.us(end) - tr.us(beg),
+		Pid:  slot,
+		Tid:  0,
+		Args: map[string]any{"evt": id},
+	})
+}
+
+func (tr *tracer) task(slot int, id int64, name string, beg, end time.Time, err error) {
+	tid, ok := tr.tids[name]
+	if !ok {
+		tid = len(tr.names)
+		tr.tids[name] = tid
+		tr.names = append(tr.names, name)
+	}
+	tr.slots[slot] = true
+
+	args := map[string]any{"evt": id}
+	if err != nil {
+		args["error"] = err.Error()
+	}
+	tr.evts = append(tr.evts, traceEvent{
+		Name: name,
+		Cat:  "task",
+		Ph:   "X",
+		Ts:   tr.us(beg),
+		Dur:  tr.us(end) - tr.us(beg),
+		Pid:  slot,
+		Tid:  tid,
+		Args: args,
+	})
+}
+
+// write writes the recorded spans as a Chrome trace-event JSON document.
+func (tr *tracer) write(w io.Writer) error {
+	slots := make([]int, 0, len(tr.slots))
+	for slot := range tr.slots {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+
+	evts := make([]traceEvent, 0, len(slots)*(1+len(tr.names))+len(tr.evts))
+	for _, slot := range slots {
+		evts = append(evts, traceEvent{
+			Name: "process_name",
+			Ph:   "M",
+			Pid:  slot,
+			Args: map[string]any{"name": fmt.Sprintf("slot-%03d", slot)},
+		})
+		for tid, name := range tr.names {
+			evts = append(evts, traceEvent{
+				Name: "thread_name",
+				Ph:   "M",
+				Pid:  slot,
+				Tid:  tid,
+				Args: map[string]any{"name": name},
+			})
+		}
+	}
+	evts = append(evts, tr.evts...)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	err := enc.Encode(struct {
+		Events []traceEvent `json:"traceEvents"`
+		Unit   string       `json:"displayTimeUnit"`
+	}{evts, "ms"})
+	if err != nil {
+		return fmt.Errorf("perfsvc: could not encode trace events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package randsvc provides a fwk.RandSvc handing out reproducible
+// per-event and per-task streams of pseudo-random numbers.
+package randsvc // import "go-hep.org/x/hep/fwk/randsvc"
+
+import (
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// key identifies the stream of a task for a given event slot.
+type key struct {
+	task string
+	slot int
+}
+
+type stream struct {
+	evt int64 // ID of the event the stream has been seeded for
+	rs  *fwk.RandStream
+}
+
+// state is the serialized state of the service.
+type state struct {
+	Seed    uint64
+	Run     int64
+	Streams []streamState
+}
+
+type streamState struct {
+	Task  string
+	Slot  int
+	Event int64
+	State []byte
+}
+
+type rsvc struct {
+	fwk.SvcBase
+
+	seed uint64 // global seed of the application
+	run  int64  // run number
+
+	save string // name of the file where to checkpoint the service when stopped
+	load string // name of the file from which to restore the service when started
+
+	mu      sync.Mutex
+	streams map[key]*stream
+}
+
+func (svc *rsvc) Configure(ctx fwk.Context) error {
+	var err error
+
+	return err
+}
+
+func (svc *rsvc) StartSvc(ctx fwk.Context) error {
+	var err error
+
+	if svc.load == "" {
+		return err
+	}
+
+	f, err := os.Open(svc.load)
+	if err != nil {
+		return fmt.Errorf("%s: could not open checkpoint file: %w", svc.Name(), err)
+	}
+	defer f.Close()
+
+	return svc.Restore(f)
+}
+
+func (svc *rsvc) StopSvc(ctx fwk.Context) error {
+	var err error
+
+	if svc.save == "" {
+		return err
+	}
+
+	f, err := os.Create(svc.save)
+	if err != nil {
+		return fmt.Errorf("%s: could not create checkpoint file: %w", svc.Name(), err)
+	}
+	defer f.Close()
+
+	err = svc.Checkpoint(f)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// Stream returns the stream of pseudo-random numbers of the named task
+// for the event being processed by ctx.
+//
+// Within an event, successive calls return the same stream, so draws
+// carry on where the previous call left them.
+func (svc *rsvc) Stream(ctx fwk.Context, name string) (*fwk.RandStream, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	k := key{task: name, slot: ctx.Slot()}
+	s, ok := svc.streams[k]
+	if !ok {
+		s = &stream{evt: -1, rs: fwk.NewRandStream(0, 0)}
+		svc.streams[k] = s
+	}
+	if evt := ctx.ID(); s.evt != evt {
+		s.evt = evt
+		s.rs.Seed(svc.seeds(evt, name))
+	}
+	return s.rs, nil
+}
+
+// seeds returns the seeds of the stream of the named task for the
+// event evt.
+func (svc *rsvc) seeds(evt int64, name string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return mix(svc.seed ^ h.Sum64()), mix(mix(uint64(svc.run)) ^ uint64(evt))
+}
+
+// mix is the splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Checkpoint saves the seed, the run number and the state of the
+// streams of the service to w.
+func (svc *rsvc) Checkpoint(w io.Writer) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	st := state{
+		Seed:    svc.seed,
+		Run:     svc.run,
+		Streams: make([]streamState, 0, len(svc.streams)),
+	}
+	for k, s := range svc.streams {
+		raw, err := s.rs.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%s: could not save state of stream [%s]: %w", svc.Name(), k.task, err)
+		}
+		st.Streams = append(st.Streams, streamState{
+			Task:  k.task,
+			Slot:  k.slot,
+			Event: s.evt,
+			State: raw,
+		})
+	}
+	sort.Slice(st.Streams, func(i, j int) bool {
+		si := st.Streams[i]
+		sj := st.Streams[j]
+		if si.Task != sj.Task {
+			return si.Task < sj.Task
+		}
+		return si.Slot < sj.Slot
+	})
+
+	err := gob.NewEncoder(w).Encode(st)
+	if err != nil {
+		return fmt.Errorf("%s: could not checkpoint service: %w", svc.Name(), err)
+	}
+	return nil
+}
+
+// Restore restores the seed, the run number and the state of the
+// streams of the service from r.
+func (svc *rsvc) Restore(r io.Reader) error {
+	var st state
+	err := gob.NewDecoder(r).Decode(&st)
+	if err != nil {
+		return fmt.Errorf("%s: could not restore service: %w", svc.Name(), err)
+	}
+
+	streams := make(map[key]*stream, len(st.Streams))
+	for _, v := range st.Streams {
+		rs := fwk.NewRandStream(0, 0)
+		err = rs.UnmarshalBinary(v.State)
+		if err != nil {
+			return fmt.Errorf("%s: could not restore state of stream [%s]: %w", svc.Name(), v.Task, err)
+		}
+		streams[key{task: v.Task, slot: v.Slot}] = &stream{evt: v.Event, rs: rs}
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.seed = st.Seed
+	svc.run = st.Run
+	svc.streams = streams
+	return nil
+}
+
+func newrsvc(typ, name string, mgr fwk.App) (fwk.Component, error) {
+	var err error
+	svc := &rsvc{
+		SvcBase: fwk.NewSvc(typ, name, mgr),
+		seed:    1234,
+		run:     0,
+		streams: make(map[key]*stream),
+	}
+
+	err = svc.DeclProp("Seed", &svc.seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("RunNumber", &svc.run)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("Checkpoint", &svc.save)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.DeclProp("Restore", &svc.load)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc, err
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(rsvc{}), newrsvc)
+}
+
+var _ fwk.RandSvc = (*rsvc)(nil)
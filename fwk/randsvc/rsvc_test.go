@@ -0,0 +1,224 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package randsvc
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/job"
+)
+
+const nentries = 100
+
+// draws collects the numbers drawn by tasks for each event.
+type draws struct {
+	mu sync.Mutex
+	m  map[string][]float64
+}
+
+func (d *draws) add(tsk string, evt int64, vs []float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.m[fmt.Sprintf("%s-%d", tsk, evt)] = vs
+}
+
+func run(t *testing.T, nprocs, nslots int, props job.P) map[string][]float64 {
+	t.Helper()
+
+	app := job.NewJob(nil, job.P{
+		"EvtMax":   int64(nentries),
+		"NProcs":   nprocs,
+		"EvtSlots": nslots,
+		"MsgLevel": job.MsgLevel("ERROR"),
+	})
+
+	app.Create(job.C{
+		Type:  "go-hep.org/x/hep/fwk/randsvc.rsvc",
+		Name:  "randsvc",
+		Props: props,
+	})
+
+	out := &draws{m: make(map[string][]float64)}
+	for i := range 4 {
+		app.Create(job.C{
+			Type: "go-hep.org/x/hep/fwk/randsvc.testrsvc",
+			Name: fmt.Sprintf("t%d", i),
+			Props: job.P{
+				"Draws": out,
+			},
+		})
+	}
+
+	err := app.App().Run()
+	if err != nil {
+		t.Fatalf("could not run application (nprocs=%d, nslots=%d): %+v", nprocs, nslots, err)
+	}
+
+	return out.m
+}
+
+func TestReproducibility(t *testing.T) {
+	want := run(t, 0, 0, nil)
+	if got, want := len(want), 4*nentries; got != want {
+		t.Fatalf("invalid number of draws: got=%d, want=%d", got, want)
+	}
+
+	for _, tc := range []struct {
+		nprocs int
+		nslots int
+	}{
+		{1, 0},
+		{4, 0},
+		{8, 0},
+		{4, 4},
+	} {
+		t.Run(fmt.Sprintf("nprocs=%d-nslots=%d", tc.nprocs, tc.nslots), func(t *testing.T) {
+			got := run(t, tc.nprocs, tc.nslots, nil)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("draws are not reproducible")
+			}
+		})
+	}
+
+	got := run(t, 0, 0, job.P{"RunNumber": int64(2)})
+	for k, v := range got {
+		if reflect.DeepEqual(v, want[k]) {
+			t.Fatalf("draws for %s should depend on the run number", k)
+		}
+	}
+
+	got = run(t, 0, 0, job.P{"Seed": uint64(42)})
+	for k, v := range got {
+		if reflect.DeepEqual(v, want[k]) {
+			t.Fatalf("draws for %s should depend on the seed", k)
+		}
+	}
+}
+
+type context struct {
+	id   int64
+	slot int
+}
+
+func (ctx context) ID() int64                     { return ctx.id }
+func (ctx context) Slot() int                     { return ctx.slot }
+func (ctx context) Store() fwk.Store              { return nil }
+func (ctx context) Msg() fwk.MsgStream            { return nil }
+func (ctx context) Svc(n string) (fwk.Svc, error) { return nil, nil }
+
+func TestCheckpoint(t *testing.T) {
+	svc := &rsvc{
+		seed:    1,
+		run:     3,
+		streams: make(map[key]*stream),
+	}
+
+	ctx := context{id: 42, slot: 1}
+	rs, err := svc.Stream(ctx, "task")
+	if err != nil {
+		t.Fatalf("could not create stream: %+v", err)
+	}
+	_ = rs.Float64()
+
+	buf := new(bytes.Buffer)
+	err = svc.Checkpoint(buf)
+	if err != nil {
+		t.Fatalf("could not checkpoint: %+v", err)
+	}
+
+	want := []float64{rs.Float64(), rs.Float64(), rs.Float64()}
+
+	restored := &rsvc{streams: make(map[key]*stream)}
+	err = restored.Restore(buf)
+	if err != nil {
+		t.Fatalf("could not restore: %+v", err)
+	}
+
+	if restored.seed != svc.seed || restored.run != svc.run {
+		t.Fatalf("invalid restored state: seed=%d run=%d", restored.seed, restored.run)
+	}
+
+	rs, err = restored.Stream(ctx, "task")
+	if err != nil {
+		t.Fatalf("could not get restored stream: %+v", err)
+	}
+	got := []float64{rs.Float64(), rs.Float64(), rs.Float64()}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid restored stream:\ngot= %v\nwant=%v", got, want)
+	}
+
+	// a new event re-seeds the stream.
+	ctx.id++
+	v1, _ := svc.Stream(ctx, "task")
+	v2, _ := restored.Stream(ctx, "task")
+	if x1, x2 := v1.Float64(), v2.Float64(); x1 != x2 {
+		t.Fatalf("invalid stream for next event: %v != %v", x1, x2)
+	}
+}
+
+type testrsvc struct {
+	fwk.TaskBase
+
+	rsvc  fwk.RandSvc
+	draws *draws
+}
+
+func (tsk *testrsvc) Configure(ctx fwk.Context) error {
+	return nil
+}
+
+func (tsk *testrsvc) StartTask(ctx fwk.Context) error {
+	svc, err := ctx.Svc("randsvc")
+	if err != nil {
+		return err
+	}
+	tsk.rsvc = svc.(fwk.RandSvc)
+	return nil
+}
+
+func (tsk *testrsvc) StopTask(ctx fwk.Context) error {
+	return nil
+}
+
+func (tsk *testrsvc) Process(ctx fwk.Context) error {
+	rs, err := tsk.rsvc.Stream(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	vs := []float64{rs.Float64(), rs.NormFloat64()}
+
+	// successive requests within an event carry on the same stream.
+	rs, err = tsk.rsvc.Stream(ctx, tsk.Name())
+	if err != nil {
+		return err
+	}
+	vs = append(vs, rs.ExpFloat64())
+
+	tsk.draws.add(tsk.Name(), ctx.ID(), vs)
+	return nil
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(testrsvc{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			var err error
+			tsk := &testrsvc{
+				TaskBase: fwk.NewTask(typ, name, mgr),
+			}
+
+			err = tsk.DeclProp("Draws", &tsk.draws)
+			if err != nil {
+				return nil, err
+			}
+
+			return tsk, err
+		},
+	)
+}
@@ -0,0 +1,172 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rio
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"go-hep.org/x/hep/fwk"
+)
+
+// source reads events from a single input file.
+type source interface {
+	// read reads the next event of the file and puts its data in store.
+	// read returns io.EOF when the file is exhausted.
+	read(store fwk.Store) error
+
+	// run returns the run number of the last event read, if known.
+	run() (int64, bool)
+
+	Close() error
+}
+
+// chain iterates over a list of input files as a single logical stream,
+// recording file and run boundaries as incidents.
+type chain struct {
+	names []string                          // names of the input files
+	open  func(name string) (source, error) // opens an input file
+	cur   int                               // index of the next file to open
+	src   source                            // current input file
+
+	run   int64 // current run number
+	inrun bool  // whether a run is in progress
+	incs  []fwk.Incident
+}
+
+// newChain creates a chain over the files names, expanding glob patterns.
+func newChain(names []string, open func(name string) (source, error)) (*chain, error) {
+	files, err := expand(names)
+	if err != nil {
+		return nil, err
+	}
+	c := &chain{names: files, open: open}
+	err = c.next()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// expand expands the glob patterns of names, in order.
+func expand(names []string) ([]string, error) {
+	var files []string
+	for _, name := range names {
+		if !strings.ContainsAny(name, "*?[") {
+			files = append(files, name)
+			continue
+		}
+		matches, err := filepath.Glob(name)
+		if err != nil {
+			return nil, fmt.Errorf("fwk/rio: invalid input file pattern %q: %w", name, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("fwk/rio: no input file matching %q", name)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("fwk/rio: no input file")
+	}
+	return files, nil
+}
+
+// next closes the current input file and opens the next one, if any.
+func (c *chain) next() error {
+	err := c.closeFile()
+	if err != nil {
+		return err
+	}
+	if c.cur >= len(c.names) {
+		return nil
+	}
+
+	name := c.names[c.cur]
+	c.cur++
+	c.src, err = c.open(name)
+	if err != nil {
+		return fmt.Errorf("fwk/rio: could not open input file %q: %w", name, err)
+	}
+	c.fire(fwk.Incident{Type: fwk.BeginFile, File: name})
+	return nil
+}
+
+// read reads the next event of the chain into store.
+// read returns io.EOF when all the files have been exhausted.
+func (c *chain) read(store fwk.Store) error {
+	for c.src != nil {
+		err := c.src.read(store)
+		switch err {
+		case nil:
+			if run, ok := c.src.run(); ok && (!c.inrun || run != c.run) {
+				c.endRun()
+				c.run = run
+				c.inrun = true
+				c.fire(fwk.Incident{Type: fwk.BeginRun, Run: run})
+			}
+			return nil
+		case io.EOF:
+			err = c.next()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("fwk/rio: could not read input file %q: %w", c.names[c.cur-1], err)
+		}
+	}
+	c.endRun()
+	return io.EOF
+}
+
+// close closes the chain, ending the current file and run.
+func (c *chain) close() error {
+	err := c.closeFile()
+	c.endRun()
+	return err
+}
+
+func (c *chain) closeFile() error {
+	if c.src == nil {
+		return nil
+	}
+	src := c.src
+	c.src = nil
+	c.fire(fwk.Incident{Type: fwk.EndFile, File: c.names[c.cur-1]})
+	return src.Close()
+}
+
+func (c *chain) endRun() {
+	if !c.inrun {
+		return
+	}
+	c.inrun = false
+	c.fire(fwk.Incident{Type: fwk.EndRun, Run: c.run})
+}
+
+func (c *chain) fire(inc fwk.Incident) {
+	c.incs = append(c.incs, inc)
+}
+
+// Incidents returns the file and run boundaries crossed since the last call.
+func (c *chain) Incidents() []fwk.Incident {
+	incs := c.incs
+	c.incs = nil
+	return incs
+}
+
+// runOf returns the run number held by v.
+func runOf(v any) (int64, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	}
+	return 0, fmt.Errorf("fwk/rio: invalid run number type %T", v)
+}
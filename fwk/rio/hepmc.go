@@ -0,0 +1,128 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rio
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/hepmc"
+)
+
+// HepMCInputStreamer reads data from a (set of) HepMC ASCII file(s).
+//
+// Input ports must be of type hepmc.Event or *hepmc.Event: they are
+// populated with the event read from the input files.
+//
+// The input files are read one after the other, as a single stream.
+// Names may hold glob patterns (e.g. "data-*.hepmc").
+//
+// HepMCInputStreamer reports the file boundaries as fwk.BeginFile and
+// fwk.EndFile incidents.
+// HepMC events carry no run number: no run incidents are reported.
+type HepMCInputStreamer struct {
+	Names []string // input filenames or glob patterns
+
+	ports []fwk.Port // input ports to populate
+	chain *chain     // chain of input files
+}
+
+var (
+	hepmcEvent    = reflect.TypeOf(hepmc.Event{})
+	hepmcEventPtr = reflect.TypeOf((*hepmc.Event)(nil))
+)
+
+func (input *HepMCInputStreamer) Connect(ports []fwk.Port) error {
+	var err error
+
+	for _, port := range ports {
+		switch port.Type {
+		case hepmcEvent, hepmcEventPtr:
+		default:
+			return fmt.Errorf(
+				"fwk/rio: invalid HepMC port %q (type=%v)",
+				port.Name, port.Type,
+			)
+		}
+	}
+	input.ports = make([]fwk.Port, len(ports))
+	copy(input.ports, ports)
+
+	input.chain, err = newChain(input.Names, input.open)
+	return err
+}
+
+func (input *HepMCInputStreamer) open(name string) (source, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &hepmcSource{
+		f:     f,
+		dec:   hepmc.NewDecoder(f),
+		ports: input.ports,
+	}, nil
+}
+
+func (input *HepMCInputStreamer) Read(ctx fwk.Context) error {
+	return input.chain.read(ctx.Store())
+}
+
+// Incidents returns the file boundaries crossed since the last call.
+func (input *HepMCInputStreamer) Incidents() []fwk.Incident {
+	if input.chain == nil {
+		return nil
+	}
+	return input.chain.Incidents()
+}
+
+func (input *HepMCInputStreamer) Disconnect() error {
+	if input.chain == nil {
+		return nil
+	}
+	return input.chain.close()
+}
+
+// hepmcSource reads events from a single HepMC file.
+type hepmcSource struct {
+	f     *os.File
+	dec   *hepmc.Decoder
+	ports []fwk.Port
+}
+
+func (src *hepmcSource) read(store fwk.Store) error {
+	evt := new(hepmc.Event)
+	err := src.dec.Decode(evt)
+	if err != nil {
+		return err
+	}
+
+	for _, port := range src.ports {
+		var v any = evt
+		if port.Type == hepmcEvent {
+			v = *evt
+		}
+		err = store.Put(port.Name, v)
+		if err != nil {
+			return fmt.Errorf("store-put error: %w", err)
+		}
+	}
+	return nil
+}
+
+func (src *hepmcSource) run() (int64, bool) {
+	return 0, false
+}
+
+func (src *hepmcSource) Close() error {
+	return src.f.Close()
+}
+
+var (
+	_ fwk.InputStreamer  = (*HepMCInputStreamer)(nil)
+	_ fwk.IncidentSource = (*HepMCInputStreamer)(nil)
+)
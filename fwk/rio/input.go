@@ -14,61 +14,120 @@ import (
 	"go-hep.org/x/hep/rio"
 )
 
-// InputStreamer reads data from a (set of) rio-stream(s)
+// InputStreamer reads data from a (set of) rio-stream(s).
+//
+// The input files are read one after the other, as a single stream.
+// Names may hold glob patterns (e.g. "data-*.rio").
+//
+// InputStreamer reports the file boundaries as fwk.BeginFile and
+// fwk.EndFile incidents.
+// When Run is set, InputStreamer reports the run boundaries as
+// fwk.BeginRun and fwk.EndRun incidents.
 type InputStreamer struct {
-	Names []string            // input filenames
-	r     io.ReadCloser       // underlying input file(s)
-	rio   *rio.Reader         // input rio-stream
-	scan  *rio.Scanner        // input records-scanner
-	ports map[string]fwk.Port // input ports to read/populate
+	Names []string // input filenames or glob patterns
+	Run   string   // name of the port holding the run number (optional)
+
+	ports []fwk.Port // input ports to read/populate
+	chain *chain     // chain of input files
 }
 
 func (input *InputStreamer) Connect(ports []fwk.Port) error {
 	var err error
 
-	input.ports = make(map[string]fwk.Port, len(ports))
+	input.ports = make([]fwk.Port, len(ports))
+	copy(input.ports, ports)
 
-	// FIXME(sbinet): handle multi-reader
-	// FIXME(sbinet): handle local/remote files, protocols
-	input.r, err = os.Open(input.Names[0])
+	err = checkRun(input.Run, ports)
 	if err != nil {
 		return err
 	}
 
-	input.rio, err = rio.NewReader(input.r)
+	// FIXME(sbinet): handle local/remote files, protocols
+	input.chain, err = newChain(input.Names, input.open)
+	return err
+}
+
+func (input *InputStreamer) open(name string) (source, error) {
+	f, err := os.Open(name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	src := &rioSource{
+		f:     f,
+		ports: make(map[string]fwk.Port, len(input.ports)),
+		rkey:  input.Run,
+	}
+
+	src.r, err = rio.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
 	}
 
 	recnames := make([]rio.Selector, 0, len(input.ports))
-	for _, port := range ports {
-		input.ports[port.Name] = port
-		rec := input.rio.Record(port.Name)
+	for _, port := range input.ports {
+		src.ports[port.Name] = port
+		rec := src.r.Record(port.Name)
 		err = rec.Connect(port.Name, reflect.New(port.Type))
 		if err != nil {
-			return err
+			src.Close()
+			return nil, err
 		}
 		recnames = append(recnames, rio.Selector{Name: port.Name, Unpack: true})
 	}
 
-	input.scan = rio.NewScanner(input.rio)
-	input.scan.Select(recnames)
-	return err
+	src.scan = rio.NewScanner(src.r)
+	src.scan.Select(recnames)
+	return src, nil
 }
 
 func (input *InputStreamer) Read(ctx fwk.Context) error {
-	store := ctx.Store()
-	recs := make(map[string]struct{}, len(input.ports))
-	for range len(input.ports) {
-		if !input.scan.Scan() {
-			err := input.scan.Err()
-			if err == nil {
+	return input.chain.read(ctx.Store())
+}
+
+// Incidents returns the file and run boundaries crossed since the last call.
+func (input *InputStreamer) Incidents() []fwk.Incident {
+	if input.chain == nil {
+		return nil
+	}
+	return input.chain.Incidents()
+}
+
+func (input *InputStreamer) Disconnect() error {
+	if input.chain == nil {
+		return nil
+	}
+	return input.chain.close()
+}
+
+// rioSource reads events from a single rio-stream.
+type rioSource struct {
+	f     *os.File            // underlying input file
+	r     *rio.Reader         // input rio-stream
+	scan  *rio.Scanner        // input records-scanner
+	ports map[string]fwk.Port // input ports to read/populate
+
+	rkey  string // name of the port holding the run number
+	runnb int64  // run number of the last event
+}
+
+func (src *rioSource) read(store fwk.Store) error {
+	recs := make(map[string]struct{}, len(src.ports))
+	for range len(src.ports) {
+		if !src.scan.Scan() {
+			err := src.scan.Err()
+			if err != nil {
+				return err
+			}
+			if len(recs) == 0 {
 				return io.EOF
 			}
+			break
 		}
-		rec := input.scan.Record()
+		rec := src.scan.Record()
 		blk := rec.Block(rec.Name())
-		obj := reflect.New(input.ports[rec.Name()].Type).Elem()
+		obj := reflect.New(src.ports[rec.Name()].Type).Elem()
 		err := blk.Read(obj.Addr().Interface())
 		if err != nil {
 			return fmt.Errorf("block-read error: %w", err)
@@ -77,30 +136,52 @@ func (input *InputStreamer) Read(ctx fwk.Context) error {
 		if err != nil {
 			return fmt.Errorf("store-put error: %w", err)
 		}
+		if rec.Name() == src.rkey {
+			src.runnb, err = runOf(obj.Interface())
+			if err != nil {
+				return err
+			}
+		}
 		recs[rec.Name()] = struct{}{}
 	}
 
-	if len(recs) != len(input.ports) {
-		return fmt.Errorf("fwk.rio: expected inputs: %d, got: %d", len(input.ports), len(recs))
+	if len(recs) != len(src.ports) {
+		return fmt.Errorf("fwk.rio: expected inputs: %d, got: %d", len(src.ports), len(recs))
 	}
 
 	return nil
 }
 
-func (input *InputStreamer) Disconnect() error {
-	var err error
+func (src *rioSource) run() (int64, bool) {
+	return src.runnb, src.rkey != ""
+}
+
+func (src *rioSource) Close() error {
 	// make sure we don't leak filedescriptors
-	defer input.r.Close()
+	defer src.f.Close()
 
-	err = input.rio.Close()
+	err := src.r.Close()
 	if err != nil {
 		return err
 	}
 
-	err = input.r.Close()
-	if err != nil {
-		return err
-	}
+	return src.f.Close()
+}
 
-	return err
+// checkRun checks the port named run, if any, is one of the ports.
+func checkRun(run string, ports []fwk.Port) error {
+	if run == "" {
+		return nil
+	}
+	for _, port := range ports {
+		if port.Name == run {
+			return nil
+		}
+	}
+	return fmt.Errorf("fwk/rio: no input port named %q for the run number", run)
 }
+
+var (
+	_ fwk.InputStreamer  = (*InputStreamer)(nil)
+	_ fwk.IncidentSource = (*InputStreamer)(nil)
+)
@@ -0,0 +1,142 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/lcio"
+)
+
+// LCIOInputStreamer reads data from a (set of) LCIO file(s).
+//
+// Input ports of type lcio.Event or *lcio.Event are populated with the
+// whole event.
+// Other input ports are populated with the event collection with the
+// same name, e.g. a port of type *lcio.McParticleContainer named
+// "MCParticle".
+//
+// The input files are read one after the other, as a single stream.
+// Names may hold glob patterns (e.g. "data-*.slcio").
+//
+// LCIOInputStreamer reports the file boundaries as fwk.BeginFile and
+// fwk.EndFile incidents and the run boundaries as fwk.BeginRun and
+// fwk.EndRun incidents.
+type LCIOInputStreamer struct {
+	Names []string // input filenames or glob patterns
+
+	ports []fwk.Port // input ports to read/populate
+	chain *chain     // chain of input files
+}
+
+var (
+	lcioEvent    = reflect.TypeOf(lcio.Event{})
+	lcioEventPtr = reflect.TypeOf((*lcio.Event)(nil))
+)
+
+func (input *LCIOInputStreamer) Connect(ports []fwk.Port) error {
+	var err error
+
+	input.ports = make([]fwk.Port, len(ports))
+	copy(input.ports, ports)
+
+	input.chain, err = newChain(input.Names, input.open)
+	return err
+}
+
+func (input *LCIOInputStreamer) open(name string) (source, error) {
+	r, err := lcio.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &lcioSource{r: r, ports: input.ports}, nil
+}
+
+func (input *LCIOInputStreamer) Read(ctx fwk.Context) error {
+	return input.chain.read(ctx.Store())
+}
+
+// Incidents returns the file and run boundaries crossed since the last call.
+func (input *LCIOInputStreamer) Incidents() []fwk.Incident {
+	if input.chain == nil {
+		return nil
+	}
+	return input.chain.Incidents()
+}
+
+func (input *LCIOInputStreamer) Disconnect() error {
+	if input.chain == nil {
+		return nil
+	}
+	return input.chain.close()
+}
+
+// lcioSource reads events from a single LCIO file.
+type lcioSource struct {
+	r     *lcio.Reader
+	ports []fwk.Port
+	runnb int64 // run number of the last event
+}
+
+func (src *lcioSource) read(store fwk.Store) error {
+	if !src.r.Next() {
+		err := src.r.Err()
+		if err == nil || errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return err
+	}
+
+	evt := src.r.Event()
+	src.runnb = int64(evt.RunNumber)
+	for _, port := range src.ports {
+		var v any
+		switch port.Type {
+		case lcioEvent:
+			v = evt
+		case lcioEventPtr:
+			v = &evt
+		default:
+			coll := evt.Get(port.Name)
+			if coll == nil {
+				return fmt.Errorf("fwk/rio: no LCIO collection named %q", port.Name)
+			}
+			rv := reflect.ValueOf(coll)
+			switch {
+			case rv.Type() == port.Type:
+				v = coll
+			case rv.Kind() == reflect.Pointer && rv.Type().Elem() == port.Type:
+				v = rv.Elem().Interface()
+			default:
+				return fmt.Errorf(
+					"fwk/rio: LCIO collection %q has type %T, want %v",
+					port.Name, coll, port.Type,
+				)
+			}
+		}
+		err := store.Put(port.Name, v)
+		if err != nil {
+			return fmt.Errorf("store-put error: %w", err)
+		}
+	}
+	return nil
+}
+
+func (src *lcioSource) run() (int64, bool) {
+	return src.runnb, true
+}
+
+func (src *lcioSource) Close() error {
+	return src.r.Close()
+}
+
+var (
+	_ fwk.InputStreamer  = (*LCIOInputStreamer)(nil)
+	_ fwk.IncidentSource = (*LCIOInputStreamer)(nil)
+)
@@ -8,38 +8,67 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 
 	"go-hep.org/x/hep/fwk"
 	"go-hep.org/x/hep/rio"
 )
 
 // OutputStreamer writes data to a rio-stream.
+//
+// When MaxEvents or MaxBytes is set, OutputStreamer rolls over to a new
+// file once the current one holds MaxEvents events or MaxBytes bytes.
+// The first file is named after Name, the following ones are numbered:
+// "out.rio", "out_001.rio", "out_002.rio", ...
+//
+// In a worker process of a multi-process fwk application, OutputStreamer
+// writes to the per-worker files named after fwk.OutputFile(Name).
+// The main process then concatenates the events of all the workers into
+// the final output files, with MergeOutputs.
 type OutputStreamer struct {
-	Name  string         // output filename
-	w     io.WriteCloser // underlying output file
-	rio   *rio.Writer    // output rio-stream
-	recs  []*rio.Record  // list of connected records to write out
+	Name      string // output filename
+	MaxEvents int64  // maximum number of events per file (optional)
+	MaxBytes  int64  // maximum size of a file, in bytes (optional)
+
+	w     *os.File      // underlying output file
+	n     countWriter   // number of bytes written to the current file
+	nevts int64         // number of events written to the current file
+	nfile int           // number of files created
+	rio   *rio.Writer   // output rio-stream
+	recs  []*rio.Record // list of connected records to write out
 	ports []fwk.Port
 }
 
 func (o *OutputStreamer) Connect(ports []fwk.Port) error {
-	var err error
-
 	o.ports = make([]fwk.Port, len(ports))
 	copy(o.ports, ports)
+	o.nfile = 0
 
 	// FIXME(sbinet): handle local/remote files, protocols
-	o.w, err = os.Create(o.Name)
+	return o.open()
+}
+
+// open creates the next output file.
+func (o *OutputStreamer) open() error {
+	var err error
+
+	name := rolloverName(fwk.OutputFile(o.Name), o.nfile)
+	o.w, err = os.Create(name)
 	if err != nil {
 		return err
 	}
+	o.nfile++
+	o.nevts = 0
+	o.n = countWriter{w: o.w}
 
-	o.rio, err = rio.NewWriter(o.w)
+	o.rio, err = rio.NewWriter(&o.n)
 	if err != nil {
 		return err
 	}
 
+	o.recs = o.recs[:0]
 	for _, port := range o.ports {
 		rec := o.rio.Record(port.Name)
 		err = rec.Connect(port.Name, reflect.New(port.Type))
@@ -52,16 +81,23 @@ func (o *OutputStreamer) Connect(ports []fwk.Port) error {
 	return err
 }
 
-func (o *OutputStreamer) Disconnect() error {
+// close closes the current output file.
+func (o *OutputStreamer) close() error {
+	if o.w == nil {
+		return nil
+	}
+
 	// make sure we don't leak filedescriptors
 	defer o.w.Close()
 
 	err := o.rio.Close()
+	o.rio = nil
 	if err != nil {
 		return err
 	}
 
 	err = o.w.Close()
+	o.w = nil
 	if err != nil {
 		return err
 	}
@@ -69,9 +105,24 @@ func (o *OutputStreamer) Disconnect() error {
 	return err
 }
 
+func (o *OutputStreamer) Disconnect() error {
+	return o.close()
+}
+
 func (o *OutputStreamer) Write(ctx fwk.Context) error {
+	return o.write(ctx.Store())
+}
+
+// write writes the data of an event from store to the current file.
+func (o *OutputStreamer) write(store fwk.Store) error {
 	var err error
-	store := ctx.Store()
+
+	if o.w == nil {
+		err = o.open()
+		if err != nil {
+			return err
+		}
+	}
 
 	for i, rec := range o.recs {
 		port := o.ports[i]
@@ -102,5 +153,124 @@ func (o *OutputStreamer) Write(ctx fwk.Context) error {
 			return err
 		}
 	}
+
+	o.nevts++
+	if (o.MaxEvents > 0 && o.nevts >= o.MaxEvents) ||
+		(o.MaxBytes > 0 && o.n.n >= o.MaxBytes) {
+		// the next file is only created when there is an event to write.
+		err = o.close()
+	}
+
 	return err
 }
+
+// MergeOutputs writes the events of the files written by the nworkers
+// worker processes of a multi-process application, worker after worker,
+// and removes these files.
+// The OutputStreamer must be connected.
+func (o *OutputStreamer) MergeOutputs(ctx fwk.Context, nworkers int) error {
+	var names []string
+	for i := range nworkers {
+		name := fwk.WorkerFile(o.Name, i)
+		for j := 0; ; j++ {
+			fname := rolloverName(name, j)
+			_, err := os.Stat(fname)
+			if err != nil {
+				if os.IsNotExist(err) && j > 0 {
+					break
+				}
+				return fmt.Errorf("fwk/rio: could not find output of worker %d: %w", i, err)
+			}
+			names = append(names, fname)
+		}
+	}
+
+	input := InputStreamer{ports: o.ports}
+	for _, name := range names {
+		src, err := input.open(name)
+		if err != nil {
+			return fmt.Errorf("fwk/rio: could not open input file %q: %w", name, err)
+		}
+
+		for {
+			store := make(mapStore, len(o.ports))
+			err = src.read(store)
+			if err != nil {
+				break
+			}
+			err = o.write(store)
+			if err != nil {
+				break
+			}
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		if err != nil {
+			src.Close()
+			return fmt.Errorf("fwk/rio: could not merge %q: %w", name, err)
+		}
+
+		err = src.Close()
+		if err != nil {
+			return fmt.Errorf("fwk/rio: could not close %q: %w", name, err)
+		}
+	}
+
+	for _, name := range names {
+		err := os.Remove(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rolloverName returns the name of the i-th output file of a stream
+// named name.
+func rolloverName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s_%03d%s", strings.TrimSuffix(name, ext), i, ext)
+}
+
+// countWriter counts the number of bytes written to w.
+type countWriter struct {
+	w *os.File
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// mapStore is a simple fwk.Store, used to transfer events between streams.
+type mapStore map[string]any
+
+func (store mapStore) Get(k string) (any, error) {
+	v, ok := store[k]
+	if !ok {
+		return nil, fmt.Errorf("fwk/rio: no such key %q", k)
+	}
+	return v, nil
+}
+
+func (store mapStore) Put(k string, v any) error {
+	store[k] = v
+	return nil
+}
+
+func (store mapStore) Has(k string) bool {
+	_, ok := store[k]
+	return ok
+}
+
+var (
+	_ fwk.OutputStreamer = (*OutputStreamer)(nil)
+	_ fwk.OutputMerger   = (*OutputStreamer)(nil)
+)
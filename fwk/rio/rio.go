@@ -2,4 +2,21 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// Package rio provides fwk input and output streamers for rio, ROOT,
+// LCIO and HepMC files.
 package rio // import "go-hep.org/x/hep/fwk/rio"
+
+import (
+	"reflect"
+
+	"go-hep.org/x/hep/fwk/job"
+)
+
+func init() {
+	// make streamers available to job configuration files.
+	job.RegisterType(reflect.TypeOf(InputStreamer{}))
+	job.RegisterType(reflect.TypeOf(OutputStreamer{}))
+	job.RegisterType(reflect.TypeOf(ROOTInputStreamer{}))
+	job.RegisterType(reflect.TypeOf(LCIOInputStreamer{}))
+	job.RegisterType(reflect.TypeOf(HepMCInputStreamer{}))
+}
@@ -0,0 +1,658 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/job"
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hepmc"
+	"go-hep.org/x/hep/lcio"
+)
+
+var (
+	int64Type = reflect.TypeOf(int64(0))
+	f64sType  = reflect.TypeOf([]float64(nil))
+)
+
+func TestRIOChain(t *testing.T) {
+	const (
+		nevts   = 10
+		nperrun = 4
+	)
+
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "out.rio")
+	ports := []fwk.Port{
+		{Name: "run", Type: int64Type},
+		{Name: "evt", Type: int64Type},
+		{Name: "xs", Type: f64sType},
+	}
+
+	app := job.NewJob(nil, job.P{
+		"EvtMax":   int64(nevts),
+		"NProcs":   0,
+		"MsgLevel": job.MsgLevel("ERROR"),
+	})
+	app.Create(job.C{
+		Type:  "go-hep.org/x/hep/fwk/rio.producer",
+		Name:  "producer",
+		Props: job.P{"NPerRun": int64(nperrun)},
+	})
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.OutputStream",
+		Name: "output",
+		Props: job.P{
+			"Ports": ports,
+			"Streamer": &OutputStreamer{
+				Name:      fname,
+				MaxEvents: 3,
+			},
+		},
+	})
+	err := app.App().Run()
+	if err != nil {
+		t.Fatalf("could not write rio files: %+v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "out*.rio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		fname,
+		filepath.Join(dir, "out_001.rio"),
+		filepath.Join(dir, "out_002.rio"),
+		filepath.Join(dir, "out_003.rio"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("invalid rolled over files:\ngot= %q\nwant=%q", files, want)
+	}
+
+	for _, tc := range []struct {
+		nprocs int
+		nslots int
+	}{
+		{0, 0},
+		{4, 0},
+		{4, 4},
+	} {
+		t.Run(fmt.Sprintf("nprocs=%d-nslots=%d", tc.nprocs, tc.nslots), func(t *testing.T) {
+			log := new(incLog)
+			app := job.NewJob(nil, job.P{
+				"EvtMax":   int64(-1),
+				"NProcs":   tc.nprocs,
+				"EvtSlots": tc.nslots,
+				"MsgLevel": job.MsgLevel("ERROR"),
+			})
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.InputStream",
+				Name: "input",
+				Props: job.P{
+					"Ports": ports,
+					"Streamer": &InputStreamer{
+						Names: []string{filepath.Join(dir, "out*.rio")},
+						Run:   "run",
+					},
+				},
+			})
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/rio.recorder",
+				Name: "recorder",
+				Props: job.P{
+					"Log":     log,
+					"NPerRun": int64(nperrun),
+					// keep events in flight across run boundaries.
+					"Delay": time.Millisecond,
+				},
+			})
+			err := app.App().Run()
+			if err != nil {
+				t.Fatalf("could not read rio files: %+v", err)
+			}
+
+			if got, want := log.nevts, nevts; got != want {
+				t.Fatalf("invalid number of events: got=%d, want=%d", got, want)
+			}
+			// EndRun is only delivered once all the events of the run
+			// have been processed.
+			if got, want := log.ends, map[int64]int{0: 4, 1: 4, 2: 2}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid number of events at end of run:\ngot= %v\nwant=%v", got, want)
+			}
+			want := []string{
+				"BeginFile(out.rio)", "BeginRun(0)",
+				"EndFile(out.rio)", "BeginFile(out_001.rio)",
+				"EndRun(0)", "BeginRun(1)",
+				"EndFile(out_001.rio)", "BeginFile(out_002.rio)",
+				"EndRun(1)", "BeginRun(2)",
+				"EndFile(out_002.rio)", "BeginFile(out_003.rio)",
+				"EndFile(out_003.rio)", "EndRun(2)",
+			}
+			if !reflect.DeepEqual(log.incs, want) {
+				t.Fatalf("invalid incidents:\ngot= %q\nwant=%q", log.incs, want)
+			}
+		})
+	}
+}
+
+func TestRIOChainErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name     string
+		streamer fwk.InputStreamer
+		want     string
+	}{
+		{
+			name:     "no-match",
+			streamer: &InputStreamer{Names: []string{filepath.Join(dir, "*.rio")}},
+			want:     "no input file matching",
+		},
+		{
+			name:     "no-file",
+			streamer: &InputStreamer{Names: []string{filepath.Join(dir, "not-there.rio")}},
+			want:     "could not open input file",
+		},
+		{
+			name:     "no-run-port",
+			streamer: &InputStreamer{Names: []string{"data.rio"}, Run: "runnbr"},
+			want:     `no input port named "runnbr"`,
+		},
+		{
+			name:     "invalid-hepmc-port",
+			streamer: &HepMCInputStreamer{Names: []string{"data.hepmc"}},
+			want:     "invalid HepMC port",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.streamer.Connect([]fwk.Port{{Name: "evt", Type: int64Type}})
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("invalid error:\ngot= %v\nwant=%v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestROOTChain(t *testing.T) {
+	dir := t.TempDir()
+	for i, runs := range [][]int32{{1, 1, 1}, {1, 2, 2, 3}} {
+		fname := filepath.Join(dir, fmt.Sprintf("data-%d.root", i))
+		f, err := groot.Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			run int32
+			xs  []float64
+		)
+		w, err := rtree.NewWriter(f, "tree", []rtree.WriteVar{
+			{Name: "run", Value: &run},
+			{Name: "xs", Value: &xs},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, v := range runs {
+			run = v
+			xs = []float64{float64(i), float64(j)}
+			_, err = w.Write()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		log  = new(incLog)
+		vals [][]float64
+	)
+	input := &ROOTInputStreamer{
+		Names: []string{filepath.Join(dir, "data-*.root")},
+		Tree:  "tree",
+		Run:   "run",
+	}
+	read(t, input, []fwk.Port{
+		{Name: "run", Type: reflect.TypeOf(int32(0))},
+		{Name: "xs", Type: f64sType},
+	}, log, func(store fwk.Store) {
+		v, err := store.Get("xs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals = append(vals, v.([]float64))
+	})
+
+	want := []string{
+		"BeginFile(data-0.root)", "BeginRun(1)",
+		"EndFile(data-0.root)", "BeginFile(data-1.root)",
+		"EndRun(1)", "BeginRun(2)",
+		"EndRun(2)", "BeginRun(3)",
+		"EndFile(data-1.root)", "EndRun(3)",
+	}
+	if !reflect.DeepEqual(log.incs, want) {
+		t.Fatalf("invalid incidents:\ngot= %q\nwant=%q", log.incs, want)
+	}
+
+	wantVals := [][]float64{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {1, 3}}
+	if !reflect.DeepEqual(vals, wantVals) {
+		t.Fatalf("invalid values:\ngot= %v\nwant=%v", vals, wantVals)
+	}
+}
+
+func TestLCIOChain(t *testing.T) {
+	const fname = "../../lcio/testdata/event_golden.slcio"
+
+	var (
+		log  = new(incLog)
+		evts []int32
+	)
+	input := &LCIOInputStreamer{
+		Names: []string{fname, fname},
+	}
+	read(t, input, []fwk.Port{
+		{Name: "evt", Type: reflect.TypeOf(lcio.Event{})},
+		{Name: "McParticles", Type: reflect.TypeOf(lcio.McParticleContainer{})},
+	}, log, func(store fwk.Store) {
+		v, err := store.Get("evt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		evts = append(evts, v.(lcio.Event).EventNumber)
+		v, err = store.Get("McParticles")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(v.(lcio.McParticleContainer).Particles) == 0 {
+			t.Fatalf("no MC particles")
+		}
+	})
+
+	want := []string{
+		"BeginFile(event_golden.slcio)", "BeginRun(42)",
+		"EndFile(event_golden.slcio)", "BeginFile(event_golden.slcio)",
+		"EndFile(event_golden.slcio)", "EndRun(42)",
+	}
+	if !reflect.DeepEqual(log.incs, want) {
+		t.Fatalf("invalid incidents:\ngot= %q\nwant=%q", log.incs, want)
+	}
+	if want := []int32{52, 52}; !reflect.DeepEqual(evts, want) {
+		t.Fatalf("invalid events: got=%v, want=%v", evts, want)
+	}
+}
+
+func TestHepMCChain(t *testing.T) {
+	var (
+		log   = new(incLog)
+		nevts = 0
+	)
+	input := &HepMCInputStreamer{
+		Names: []string{
+			"../../hepmc/testdata/small.hepmc",
+			"../../hepmc/testdata/test.hepmc",
+		},
+	}
+	read(t, input, []fwk.Port{
+		{Name: "evt", Type: reflect.TypeOf((*hepmc.Event)(nil))},
+	}, log, func(store fwk.Store) {
+		v, err := store.Get("evt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(v.(*hepmc.Event).Particles) == 0 {
+			t.Fatalf("no particles")
+		}
+		nevts++
+	})
+
+	want := []string{
+		"BeginFile(small.hepmc)",
+		"EndFile(small.hepmc)", "BeginFile(test.hepmc)",
+		"EndFile(test.hepmc)",
+	}
+	if !reflect.DeepEqual(log.incs, want) {
+		t.Fatalf("invalid incidents:\ngot= %q\nwant=%q", log.incs, want)
+	}
+	if got, want := nevts, 1+6; got != want {
+		t.Fatalf("invalid number of events: got=%d, want=%d", got, want)
+	}
+}
+
+func TestRIOMergeOutputs(t *testing.T) {
+	const (
+		nevts    = 5
+		nworkers = 2
+	)
+
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "out.rio")
+	ports := []fwk.Port{
+		{Name: "run", Type: int64Type},
+		{Name: "evt", Type: int64Type},
+		{Name: "xs", Type: f64sType},
+	}
+
+	for i := range nworkers {
+		app := job.NewJob(nil, job.P{
+			"EvtMax":   int64(nevts),
+			"NProcs":   0,
+			"MsgLevel": job.MsgLevel("ERROR"),
+		})
+		app.Create(job.C{
+			Type:  "go-hep.org/x/hep/fwk/rio.producer",
+			Name:  "producer",
+			Props: job.P{"NPerRun": int64(nevts)},
+		})
+		app.Create(job.C{
+			Type: "go-hep.org/x/hep/fwk.OutputStream",
+			Name: "output",
+			Props: job.P{
+				"Ports": ports,
+				"Streamer": &OutputStreamer{
+					Name:      fwk.WorkerFile(fname, i),
+					MaxEvents: 3,
+				},
+			},
+		})
+		err := app.App().Run()
+		if err != nil {
+			t.Fatalf("could not write rio files of worker %d: %+v", i, err)
+		}
+	}
+
+	app := job.NewJob(nil, job.P{
+		"MsgLevel": job.MsgLevel("ERROR"),
+	})
+	out := app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.OutputStream",
+		Name: "output",
+		Props: job.P{
+			"Ports": ports,
+			"Streamer": &OutputStreamer{
+				Name:      fname,
+				MaxEvents: 4,
+			},
+		},
+	}).(fwk.OutputMerger)
+
+	err := out.MergeOutputs(nil, nworkers)
+	if err != nil {
+		t.Fatalf("could not merge outputs: %+v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "out*.rio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		fname,
+		filepath.Join(dir, "out_001.rio"),
+		filepath.Join(dir, "out_002.rio"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("invalid merged files:\ngot= %q\nwant=%q", files, want)
+	}
+
+	var evts []int64
+	read(t, &InputStreamer{Names: files}, ports, new(incLog), func(store fwk.Store) {
+		v, err := store.Get("evt")
+		if err != nil {
+			t.Fatalf("could not get event number: %+v", err)
+		}
+		evts = append(evts, v.(int64))
+	})
+	if got, want := evts, []int64{0, 1, 2, 3, 4, 0, 1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid merged events:\ngot= %v\nwant=%v", got, want)
+	}
+
+	err = out.MergeOutputs(nil, nworkers)
+	if err == nil {
+		t.Fatalf("expected an error merging removed outputs")
+	}
+}
+
+// read reads all the events of the input streamer, outside of a fwk
+// application.
+func read(t *testing.T, input fwk.InputStreamer, ports []fwk.Port, log *incLog, f func(store fwk.Store)) {
+	t.Helper()
+
+	err := input.Connect(ports)
+	if err != nil {
+		t.Fatalf("could not connect input streamer: %+v", err)
+	}
+
+	src := input.(fwk.IncidentSource)
+	for {
+		store := make(mapStore)
+		err := input.Read(testContext{store})
+		for _, inc := range src.Incidents() {
+			log.add(inc)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			t.Fatalf("could not read event: %+v", err)
+		}
+		f(store)
+	}
+
+	err = input.Disconnect()
+	if err != nil {
+		t.Fatalf("could not disconnect input streamer: %+v", err)
+	}
+	for _, inc := range src.Incidents() {
+		log.add(inc)
+	}
+}
+
+type testContext struct {
+	store fwk.Store
+}
+
+func (ctx testContext) ID() int64                     { return 0 }
+func (ctx testContext) Slot() int                     { return 0 }
+func (ctx testContext) Store() fwk.Store              { return ctx.store }
+func (ctx testContext) Msg() fwk.MsgStream            { return nil }
+func (ctx testContext) Svc(n string) (fwk.Svc, error) { return nil, nil }
+
+// incLog records incidents and events.
+type incLog struct {
+	mu    sync.Mutex
+	incs  []string
+	nevts int
+	runs  map[int64]int // number of events processed for each run
+	ends  map[int64]int // number of events processed when each run ended
+}
+
+func (log *incLog) add(inc fwk.Incident) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if inc.Type == fwk.EndRun {
+		if log.ends == nil {
+			log.ends = make(map[int64]int)
+		}
+		log.ends[inc.Run] = log.runs[inc.Run]
+	}
+	switch inc.Type {
+	case fwk.BeginRun, fwk.EndRun:
+		log.incs = append(log.incs, fmt.Sprintf("%s(%d)", inc.Type, inc.Run))
+	default:
+		log.incs = append(log.incs, fmt.Sprintf("%s(%s)", inc.Type, filepath.Base(inc.File)))
+	}
+}
+
+// producer produces events with a run number, an event number and
+// a slice of values.
+type producer struct {
+	fwk.TaskBase
+
+	nperrun int64
+}
+
+func (tsk *producer) Configure(ctx fwk.Context) error {
+	for _, port := range []fwk.Port{
+		{Name: "run", Type: int64Type},
+		{Name: "evt", Type: int64Type},
+		{Name: "xs", Type: f64sType},
+	} {
+		err := tsk.DeclOutPort(port.Name, port.Type)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tsk *producer) StartTask(ctx fwk.Context) error { return nil }
+func (tsk *producer) StopTask(ctx fwk.Context) error  { return nil }
+
+func (tsk *producer) Process(ctx fwk.Context) error {
+	var (
+		store = ctx.Store()
+		id    = ctx.ID()
+	)
+	err := store.Put("run", id/tsk.nperrun)
+	if err != nil {
+		return err
+	}
+	err = store.Put("evt", id)
+	if err != nil {
+		return err
+	}
+	return store.Put("xs", []float64{float64(id), float64(id + 1)})
+}
+
+// recorder records incidents and checks the content of events.
+type recorder struct {
+	fwk.TaskBase
+
+	log     *incLog
+	nperrun int64
+	delay   time.Duration
+}
+
+func (tsk *recorder) Configure(ctx fwk.Context) error {
+	for _, port := range []fwk.Port{
+		{Name: "run", Type: int64Type},
+		{Name: "evt", Type: int64Type},
+		{Name: "xs", Type: f64sType},
+	} {
+		err := tsk.DeclInPort(port.Name, port.Type)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tsk *recorder) StartTask(ctx fwk.Context) error { return nil }
+func (tsk *recorder) StopTask(ctx fwk.Context) error  { return nil }
+
+func (tsk *recorder) Process(ctx fwk.Context) error {
+	store := ctx.Store()
+	run, err := store.Get("run")
+	if err != nil {
+		return err
+	}
+	evt, err := store.Get("evt")
+	if err != nil {
+		return err
+	}
+	xs, err := store.Get("xs")
+	if err != nil {
+		return err
+	}
+
+	id := evt.(int64)
+	if got, want := run.(int64), id/tsk.nperrun; got != want {
+		return fmt.Errorf("invalid run for event %d: got=%d, want=%d", id, got, want)
+	}
+	if got, want := xs.([]float64), []float64{float64(id), float64(id + 1)}; !slices.Equal(got, want) {
+		return fmt.Errorf("invalid xs for event %d: got=%v, want=%v", id, got, want)
+	}
+
+	time.Sleep(tsk.delay)
+
+	tsk.log.mu.Lock()
+	defer tsk.log.mu.Unlock()
+	tsk.log.nevts++
+	if tsk.log.runs == nil {
+		tsk.log.runs = make(map[int64]int)
+	}
+	tsk.log.runs[run.(int64)]++
+	return nil
+}
+
+func (tsk *recorder) HandleIncident(ctx fwk.Context, inc fwk.Incident) error {
+	if inc.Source != "input" {
+		return fmt.Errorf("invalid incident source %q", inc.Source)
+	}
+	tsk.log.add(inc)
+	return nil
+}
+
+func init() {
+	fwk.Register(reflect.TypeOf(producer{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			tsk := &producer{
+				TaskBase: fwk.NewTask(typ, name, mgr),
+				nperrun:  1,
+			}
+			err := tsk.DeclProp("NPerRun", &tsk.nperrun)
+			if err != nil {
+				return nil, err
+			}
+			return tsk, nil
+		},
+	)
+
+	fwk.Register(reflect.TypeOf(recorder{}),
+		func(typ, name string, mgr fwk.App) (fwk.Component, error) {
+			tsk := &recorder{
+				TaskBase: fwk.NewTask(typ, name, mgr),
+				nperrun:  1,
+			}
+			err := tsk.DeclProp("Log", &tsk.log)
+			if err != nil {
+				return nil, err
+			}
+			err = tsk.DeclProp("NPerRun", &tsk.nperrun)
+			if err != nil {
+				return nil, err
+			}
+			err = tsk.DeclProp("Delay", &tsk.delay)
+			if err != nil {
+				return nil, err
+			}
+			return tsk, nil
+		},
+	)
+}
+
+var (
+	_ fwk.IncidentHandler = (*recorder)(nil)
+)
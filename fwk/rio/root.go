@@ -0,0 +1,259 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// ROOTInputStreamer reads data from a (set of) ROOT tree(s).
+//
+// Each input port is read from the branch with the same name.
+// The input files are read one after the other, as a single stream.
+// Names may hold glob patterns (e.g. "data-*.root").
+//
+// ROOTInputStreamer reports the file boundaries as fwk.BeginFile and
+// fwk.EndFile incidents.
+// When Run is set, ROOTInputStreamer reports the run boundaries as
+// fwk.BeginRun and fwk.EndRun incidents.
+type ROOTInputStreamer struct {
+	Names []string // input filenames or glob patterns
+	Tree  string   // name of the tree to read
+	Run   string   // name of the port holding the run number (optional)
+
+	ports []fwk.Port // input ports to read/populate
+	chain *chain     // chain of input files
+}
+
+func (input *ROOTInputStreamer) Connect(ports []fwk.Port) error {
+	var err error
+
+	input.ports = make([]fwk.Port, len(ports))
+	copy(input.ports, ports)
+
+	err = checkRun(input.Run, ports)
+	if err != nil {
+		return err
+	}
+
+	input.chain, err = newChain(input.Names, input.open)
+	return err
+}
+
+func (input *ROOTInputStreamer) open(name string) (source, error) {
+	f, err := groot.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := riofs.Dir(f).Get(input.Tree)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	tree, ok := obj.(rtree.Tree)
+	if !ok {
+		f.Close()
+		return nil, fmt.Errorf("fwk/rio: object %q is not a tree (type=%T)", input.Tree, obj)
+	}
+
+	src := &rootSource{
+		f:     f,
+		ports: input.ports,
+		vals:  make([]reflect.Value, len(input.ports)),
+		rkey:  input.Run,
+		quit:  make(chan struct{}),
+		ack:   make(chan struct{}),
+		evts:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	rvars := make([]rtree.ReadVar, len(input.ports))
+	for i, port := range input.ports {
+		src.vals[i] = reflect.New(port.Type)
+		rvars[i] = rtree.ReadVar{Name: port.Name, Value: src.vals[i].Interface()}
+	}
+
+	src.r, err = rtree.NewReader(tree, rvars)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	go src.loop()
+	return src, nil
+}
+
+func (input *ROOTInputStreamer) Read(ctx fwk.Context) error {
+	return input.chain.read(ctx.Store())
+}
+
+// Incidents returns the file and run boundaries crossed since the last call.
+func (input *ROOTInputStreamer) Incidents() []fwk.Incident {
+	if input.chain == nil {
+		return nil
+	}
+	return input.chain.Incidents()
+}
+
+func (input *ROOTInputStreamer) Disconnect() error {
+	if input.chain == nil {
+		return nil
+	}
+	return input.chain.close()
+}
+
+// errStop stops the iteration over the entries of a tree.
+var errStop = errors.New("fwk/rio: stop")
+
+// rootSource reads events from a single ROOT tree.
+//
+// rtree.Reader drives the iteration over the entries of the tree:
+// the reader runs in its own goroutine and waits for the entry it just
+// read to be consumed before reading the next one.
+type rootSource struct {
+	f     *riofs.File
+	r     *rtree.Reader
+	ports []fwk.Port
+	vals  []reflect.Value // pointers to the values filled by the reader
+
+	rkey  string // name of the port holding the run number
+	runnb int64  // run number of the last event
+
+	quit chan struct{} // closed to stop the reader
+	evts chan struct{} // signals an entry is available, closed at the end
+	ack  chan struct{} // signals an entry has been consumed
+	done chan struct{} // closed when the reader has stopped
+	err  error         // error of the reader
+}
+
+func (src *rootSource) loop() {
+	defer close(src.done)
+	err := src.r.Read(func(rtree.RCtx) error {
+		select {
+		case src.evts <- struct{}{}:
+		case <-src.quit:
+			return errStop
+		}
+		select {
+		case <-src.ack:
+			return nil
+		case <-src.quit:
+			return errStop
+		}
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		src.err = err
+	}
+	close(src.evts)
+}
+
+func (src *rootSource) read(store fwk.Store) error {
+	_, ok := <-src.evts
+	if !ok {
+		if src.err != nil {
+			return src.err
+		}
+		return io.EOF
+	}
+	defer func() { src.ack <- struct{}{} }()
+
+	for i, port := range src.ports {
+		// the reader re-uses its values: give a copy to the store.
+		v := clone(src.vals[i].Elem()).Interface()
+		err := store.Put(port.Name, v)
+		if err != nil {
+			return fmt.Errorf("store-put error: %w", err)
+		}
+		if port.Name == src.rkey {
+			src.runnb, err = runOf(v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (src *rootSource) run() (int64, bool) {
+	return src.runnb, src.rkey != ""
+}
+
+func (src *rootSource) Close() error {
+	close(src.quit)
+	<-src.done
+
+	// make sure we don't leak filedescriptors
+	defer src.f.Close()
+
+	err := src.r.Close()
+	if err != nil {
+		return err
+	}
+	return src.f.Close()
+}
+
+// clone returns a deep copy of v.
+func clone(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		o := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			o.Index(i).Set(clone(v.Index(i)))
+		}
+		return o
+	case reflect.Array:
+		o := reflect.New(v.Type()).Elem()
+		for i := range v.Len() {
+			o.Index(i).Set(clone(v.Index(i)))
+		}
+		return o
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		o := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			o.SetMapIndex(clone(iter.Key()), clone(iter.Value()))
+		}
+		return o
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		o := reflect.New(v.Type().Elem())
+		o.Elem().Set(clone(v.Elem()))
+		return o
+	case reflect.Struct:
+		o := reflect.New(v.Type()).Elem()
+		o.Set(v)
+		for i := range v.NumField() {
+			if !o.Field(i).CanSet() {
+				continue
+			}
+			o.Field(i).Set(clone(v.Field(i)))
+		}
+		return o
+	}
+	return v
+}
+
+var (
+	_ fwk.InputStreamer  = (*ROOTInputStreamer)(nil)
+	_ fwk.IncidentSource = (*ROOTInputStreamer)(nil)
+)
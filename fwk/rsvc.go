@@ -0,0 +1,69 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+import (
+	"encoding"
+	"io"
+	"math/rand/v2"
+)
+
+// RandStream is a stream of pseudo-random numbers.
+// The state of a RandStream can be saved and restored with its
+// MarshalBinary and UnmarshalBinary methods.
+type RandStream struct {
+	*rand.Rand
+	src *rand.PCG
+}
+
+// NewRandStream creates a new stream of pseudo-random numbers,
+// seeded with the provided values.
+func NewRandStream(seed1, seed2 uint64) *RandStream {
+	src := rand.NewPCG(seed1, seed2)
+	return &RandStream{
+		Rand: rand.New(src),
+		src:  src,
+	}
+}
+
+// Seed resets the stream to behave the same way as NewRandStream(seed1, seed2).
+func (s *RandStream) Seed(seed1, seed2 uint64) {
+	s.src.Seed(seed1, seed2)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *RandStream) MarshalBinary() ([]byte, error) {
+	return s.src.MarshalBinary()
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (s *RandStream) UnmarshalBinary(data []byte) error {
+	return s.src.UnmarshalBinary(data)
+}
+
+// RandSvc is the interface providing reproducible streams of
+// pseudo-random numbers, independently of the number of events processed
+// concurrently.
+type RandSvc interface {
+	Svc
+
+	// Stream returns the stream of pseudo-random numbers of the named task
+	// for the event being processed by ctx.
+	// The stream is seeded deterministically from the run number, the
+	// event ID and the name of the task, the first time it is requested
+	// for that event.
+	Stream(ctx Context, name string) (*RandStream, error)
+
+	// Checkpoint saves the state of the service and of its streams to w.
+	Checkpoint(w io.Writer) error
+
+	// Restore restores the state of the service and of its streams from r.
+	Restore(r io.Reader) error
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*RandStream)(nil)
+	_ encoding.BinaryUnmarshaler = (*RandStream)(nil)
+)
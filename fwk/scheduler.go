@@ -0,0 +1,557 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Concurrency describes how a task may process multiple events concurrently.
+type Concurrency int
+
+const (
+	// ThreadSafe tasks may process any number of events concurrently.
+	// Tasks are ThreadSafe by default.
+	ThreadSafe Concurrency = iota
+
+	// Clonable tasks process one event at a time, but may be cloned to
+	// process multiple events concurrently.
+	// Clones are created from the factory of the task and are given
+	// the properties of the original task.
+	Clonable
+
+	// SingleInstance tasks process one event at a time.
+	SingleInstance
+)
+
+func (c Concurrency) String() string {
+	switch c {
+	case ThreadSafe:
+		return "thread-safe"
+	case Clonable:
+		return "clonable"
+	case SingleInstance:
+		return "single-instance"
+	}
+	return fmt.Sprintf("Concurrency(%d)", int(c))
+}
+
+// ConcurrentTask is the interface implemented by tasks declaring how they
+// may process multiple events concurrently.
+type ConcurrentTask interface {
+	Task
+	Concurrency() Concurrency
+}
+
+func concurrency(tsk Task) Concurrency {
+	if tsk, ok := tsk.(ConcurrentTask); ok {
+		return tsk.Concurrency()
+	}
+	return ThreadSafe
+}
+
+// clonemgr is the fwk.App used to create clones of tasks.
+// Properties declared by clones are kept private to the clones and
+// ports declared by clones are ignored: they are the ports of the
+// original task.
+type clonemgr struct {
+	*appmgr
+	props map[string]any
+}
+
+func (mgr *clonemgr) DeclProp(c Component, name string, ptr any) error {
+	if reflect.TypeOf(ptr).Kind() != reflect.Ptr {
+		return fmt.Errorf(
+			"fwk.DeclProp: component [%s] didn't pass a pointer for the property [%s] (type=%T)",
+			c.Name(),
+			name,
+			ptr,
+		)
+	}
+	mgr.props[name] = ptr
+	return nil
+}
+
+func (mgr *clonemgr) SetProp(c Component, name string, value any) error {
+	ptr, ok := mgr.props[name]
+	if !ok {
+		return fmt.Errorf("fwk.SetProp: component [%s] didn't declare any property with name [%s]", c.Name(), name)
+	}
+	rv := reflect.ValueOf(value)
+	dst := reflect.ValueOf(ptr).Elem()
+	if !rv.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf(
+			"fwk.SetProp: component [%s] has property [%s] with type [%s]. got value=%v (type=%s)",
+			c.Name(), name, dst.Type().Name(), value, rv.Type().Name(),
+		)
+	}
+	dst.Set(rv)
+	return nil
+}
+
+func (mgr *clonemgr) GetProp(c Component, name string) (any, error) {
+	ptr, ok := mgr.props[name]
+	if !ok {
+		return nil, fmt.Errorf("fwk.GetProp: component [%s] didn't declare any property with name [%s]", c.Name(), name)
+	}
+	return reflect.ValueOf(ptr).Elem().Interface(), nil
+}
+
+func (mgr *clonemgr) HasProp(c Component, name string) bool {
+	_, ok := mgr.props[name]
+	return ok
+}
+
+func (mgr *clonemgr) DeclInPort(c Component, name string, t reflect.Type) error {
+	return nil
+}
+
+func (mgr *clonemgr) DeclOutPort(c Component, name string, t reflect.Type) error {
+	return nil
+}
+
+// clone creates and configures a clone of the provided task.
+func (app *appmgr) clone(tsk Task, ctx ctxType) (Task, error) {
+	fct, ok := gFactory[tsk.Type()]
+	if !ok {
+		return nil, fmt.Errorf("fwk: no component with type [%s] registered", tsk.Type())
+	}
+
+	mgr := &clonemgr{
+		appmgr: app,
+		props:  make(map[string]any),
+	}
+	c, err := fct(tsk.Type(), tsk.Name(), mgr)
+	if err != nil {
+		return nil, fmt.Errorf("fwk: could not clone [%s:%s]: %w", tsk.Type(), tsk.Name(), err)
+	}
+
+	for name, src := range app.props[tsk.Name()] {
+		dst, ok := mgr.props[name]
+		if !ok {
+			continue
+		}
+		reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+	}
+
+	clone, ok := c.(Task)
+	if !ok {
+		return nil, fmt.Errorf("fwk: clone of [%s:%s] is not a task (type=%T)", tsk.Type(), tsk.Name(), c)
+	}
+
+	if cfg, ok := clone.(Configurer); ok {
+		ctx.mgr = mgr
+		err = cfg.Configure(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fwk: could not configure clone of [%s:%s]: %w", tsk.Type(), tsk.Name(), err)
+		}
+	}
+
+	return clone, nil
+}
+
+// cardinality returns the number of instances of the provided task
+// that may process events concurrently, or -1 if unlimited.
+func (app *appmgr) cardinality(tsk Task) int {
+	switch concurrency(tsk) {
+	case Clonable:
+		if n, ok := app.card[tsk.Name()]; ok && n > 0 {
+			return n
+		}
+		return max(app.nslots, 1)
+	case SingleInstance:
+		return 1
+	}
+	return -1
+}
+
+// scheduler dispatches the tasks of multiple in-flight events onto a
+// shared pool of goroutines, as soon as their data-flow and control-flow
+// dependencies have been fulfilled.
+type scheduler struct {
+	app   *appmgr
+	tsks  []Task
+	users [][]int // indices of the tasks depending on each task
+	ndeps []int   // number of dependencies of each task
+	insts []*instances
+	slots []*evtslot
+	keys  []string
+
+	jobs chan schedjob
+	done chan schedjob
+
+	ievt     int64
+	eof      bool
+	inflight int
+}
+
+// instances holds the idle instances of a task.
+type instances struct {
+	tsk  Task
+	card int // number of instances. -1 if unlimited.
+	idle []Task
+}
+
+func (insts *instances) acquire() (Task, bool) {
+	if insts.card < 0 {
+		return insts.tsk, true
+	}
+	n := len(insts.idle)
+	if n == 0 {
+		return nil, false
+	}
+	tsk := insts.idle[n-1]
+	insts.idle = insts.idle[:n-1]
+	return tsk, true
+}
+
+func (insts *instances) release(tsk Task) {
+	if insts.card < 0 {
+		return
+	}
+	insts.idle = append(insts.idle, tsk)
+}
+
+// evtslot holds the state of an in-flight event.
+type evtslot struct {
+	slot   int
+	busy   bool
+	ctx    context.Context
+	cancel context.CancelFunc
+	run    taskrunner
+	evt    ctxType // context of the event being processed
+	store  datastore
+	ctxs   []ctxType
+
+	npend []int // number of pending dependencies of each task
+	ready []int // tasks ready to be executed, waiting for an idle instance
+	ndone int
+}
+
+// schedjob is a task to execute for a given event slot.
+type schedjob struct {
+	slot *evtslot
+	itsk int
+	tsk  Task
+	err  error
+}
+
+func newScheduler(app *appmgr) *scheduler {
+	n := len(app.tsks)
+	sched := &scheduler{
+		app:   app,
+		tsks:  app.tsks,
+		users: make([][]int, n),
+		ndeps: make([]int, n),
+		insts: make([]*instances, n),
+		slots: make([]*evtslot, max(app.nslots, 1)),
+		keys:  app.dflow.keys(),
+	}
+
+	idx := make(map[string]int, n)
+	for i, tsk := range sched.tsks {
+		idx[tsk.Name()] = i
+	}
+
+	for i, tsk := range sched.tsks {
+		var deps []string
+		if flow := app.flow.tsks[tsk.Name()]; flow != nil {
+			deps = append(deps, flow.names()...)
+		}
+		if seq, ok := tsk.(*Sequence); ok {
+			for _, m := range seq.members {
+				name, _ := seq.member(m)
+				deps = append(deps, name)
+			}
+		}
+		seen := make(map[int]bool, len(deps))
+		for _, dep := range deps {
+			j, ok := idx[dep]
+			if !ok || j == i || seen[j] {
+				continue
+			}
+			seen[j] = true
+			sched.users[j] = append(sched.users[j], i)
+			sched.ndeps[i]++
+		}
+
+		insts := &instances{
+			tsk:  tsk,
+			card: app.cardinality(tsk),
+		}
+		if insts.card > 0 {
+			insts.idle = append(insts.idle, tsk)
+			insts.idle = append(insts.idle, app.clones[tsk.Name()]...)
+		}
+		sched.insts[i] = insts
+	}
+
+	for i := range sched.slots {
+		slot := &evtslot{
+			slot:  i,
+			store: *app.store,
+			ctxs:  make([]ctxType, n),
+			npend: make([]int, n),
+		}
+		slot.store.store = make(map[string]achan, len(sched.keys))
+		for j, tsk := range sched.tsks {
+			slot.ctxs[j] = ctxType{
+				id:    -1,
+				slot:  i,
+				store: &slot.store,
+				msg:   newMsgStream(tsk.Name(), app.msg.lvl, nil),
+				mgr:   nil, // nobody's supposed to access mgr's state during event-loop
+			}
+		}
+		sched.slots[i] = slot
+	}
+
+	// jobs are never blocking the scheduler.
+	sched.jobs = make(chan schedjob, len(sched.slots)*n+1)
+	sched.done = make(chan schedjob, len(sched.slots)*n+1)
+
+	return sched
+}
+
+// worker executes tasks until the jobs channel is closed.
+func (sched *scheduler) worker() {
+	for job := range sched.jobs {
+		ctx := job.slot.ctxs[job.itsk]
+		ctx.id = job.slot.run.ievt
+		job.err = job.slot.run.process(ctx, job.tsk)
+		ctx.msg.flush()
+		sched.done <- job
+	}
+}
+
+func (sched *scheduler) run(runctx context.Context) error {
+	var err error
+
+	for range max(sched.app.nprocs, 1) {
+		go sched.worker()
+	}
+	defer close(sched.jobs)
+
+	err = sched.fill(runctx)
+	if err != nil {
+		return sched.abort(err)
+	}
+
+	for sched.inflight > 0 {
+		err = sched.step(runctx)
+		if err != nil {
+			return sched.abort(err)
+		}
+
+		err = sched.fill(runctx)
+		if err != nil {
+			return sched.abort(err)
+		}
+	}
+
+	return err
+}
+
+// step waits for the next task to complete, and dispatches the tasks
+// which were waiting for it.
+func (sched *scheduler) step(runctx context.Context) error {
+	var job schedjob
+	select {
+	case job = <-sched.done:
+	case <-runctx.Done():
+		return runctx.Err()
+	}
+	sched.inflight--
+
+	if job.err != nil {
+		return job.err
+	}
+
+	slot := job.slot
+	sched.insts[job.itsk].release(job.tsk)
+	slot.ndone++
+	for _, u := range sched.users[job.itsk] {
+		slot.npend[u]--
+		if slot.npend[u] == 0 {
+			slot.ready = append(slot.ready, u)
+		}
+	}
+
+	if slot.ndone == len(sched.tsks) {
+		sched.app.flow.record(slot.run.evt)
+		slot.run.endEvent(slot.evt)
+		slot.store.close()
+		slot.cancel()
+		slot.busy = false
+		sched.app.msg.flush()
+	}
+
+	// a task instance was released: dispatch tasks of all events.
+	for _, slot := range sched.slots {
+		if slot.busy {
+			sched.dispatch(slot)
+		}
+	}
+	return nil
+}
+
+// fill reads the next events into the idle slots.
+func (sched *scheduler) fill(runctx context.Context) error {
+	for _, slot := range sched.slots {
+		if slot.busy {
+			continue
+		}
+		err := sched.load(runctx, slot)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drain processes the in-flight events until they have all completed.
+func (sched *scheduler) drain(runctx context.Context) error {
+	for sched.inflight > 0 {
+		err := sched.step(runctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads the next event into the provided slot.
+func (sched *scheduler) load(runctx context.Context, slot *evtslot) error {
+	app := sched.app
+	for !sched.eof && sched.ievt < app.evtmax {
+		ievt := sched.ievt
+		sched.ievt++
+
+		err := slot.store.reset(sched.keys)
+		if err != nil {
+			return err
+		}
+
+		slot.ctx, slot.cancel = context.WithCancel(runctx)
+		ctx := ctxType{
+			id:    ievt,
+			slot:  slot.slot,
+			store: &slot.store,
+			msg:   newMsgStream(app.istream.Name(), app.msg.lvl, nil),
+			mgr:   nil, // nobody's supposed to access mgr's state during event-loop
+			ctx:   slot.ctx,
+		}
+
+		// run and file boundaries are delivered once the events read
+		// before them have been processed.
+		err = app.readEvent(ctx, func() error {
+			return sched.drain(runctx)
+		})
+		if err != nil {
+			slot.store.close()
+			slot.cancel()
+			if err == io.EOF {
+				sched.eof = true
+				return nil
+			}
+			return err
+		}
+		if !app.owns(ievt) {
+			// processed by another worker process.
+			slot.store.close()
+			slot.cancel()
+			continue
+		}
+
+		slot.run = taskrunner{
+			ievt:   ievt,
+			evtctx: slot.ctx,
+			evt:    newEvtFlow(sched.tsks),
+			flow:   app.flow,
+			errs:   app.errs,
+			mons:   app.mons,
+		}
+
+		slot.run.beginEvent(ctx)
+		if len(sched.tsks) == 0 {
+			app.flow.record(slot.run.evt)
+			slot.run.endEvent(ctx)
+			slot.store.close()
+			slot.cancel()
+			continue
+		}
+		slot.evt = ctx
+
+		slot.busy = true
+		slot.ndone = 0
+		slot.ready = slot.ready[:0]
+		for i := range sched.tsks {
+			slot.npend[i] = sched.ndeps[i]
+			if slot.npend[i] == 0 {
+				slot.ready = append(slot.ready, i)
+			}
+		}
+		sched.dispatch(slot)
+		return nil
+	}
+	return nil
+}
+
+// dispatch sends the ready tasks of the provided slot to the pool of
+// workers, provided an instance of these tasks is available.
+func (sched *scheduler) dispatch(slot *evtslot) {
+	ready := slot.ready[:0]
+	for _, itsk := range slot.ready {
+		tsk, ok := sched.insts[itsk].acquire()
+		if !ok {
+			ready = append(ready, itsk)
+			continue
+		}
+		sched.inflight++
+		sched.jobs <- schedjob{slot: slot, itsk: itsk, tsk: tsk}
+	}
+	slot.ready = ready
+}
+
+// abort cancels all in-flight events and waits for their tasks to finish.
+func (sched *scheduler) abort(err error) error {
+	for _, slot := range sched.slots {
+		if !slot.busy {
+			continue
+		}
+		slot.store.close()
+		slot.cancel()
+		slot.busy = false
+	}
+	for ; sched.inflight > 0; sched.inflight-- {
+		<-sched.done
+	}
+	return err
+}
+
+func (app *appmgr) runScheduler(ctx Context) error {
+	var err error
+
+	runctx, runCancel := context.WithCancel(context.Background())
+	defer runCancel()
+
+	istream, err := app.startInputStream()
+	if err != nil {
+		return err
+	}
+	defer close(istream.Quit)
+
+	ostream, err := app.startOutputStreams()
+	if err != nil {
+		return err
+	}
+	defer close(ostream.Quit)
+
+	sched := newScheduler(app)
+	return sched.run(runctx)
+}
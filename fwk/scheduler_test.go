@@ -0,0 +1,274 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk/internal/fwktest"
+	"go-hep.org/x/hep/fwk/job"
+)
+
+func newSchedApp(evtmax int64, nprocs, nslots int) *job.Job {
+	return job.NewJob(nil, job.P{
+		"EvtMax":   evtmax,
+		"NProcs":   nprocs,
+		"EvtSlots": nslots,
+		"MsgLevel": job.MsgLevel("ERROR"),
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	const max = 1000
+	for _, evtmax := range []int64{0, 1, 10, -1} {
+		for _, nprocs := range []int{1, 2, 4, 8} {
+			for _, nslots := range []int{1, 2, 4, 8} {
+				nmax := evtmax
+				if nmax < 0 {
+					nmax = max
+				}
+
+				app := newSchedApp(evtmax, nprocs, nslots)
+
+				out := new(bytes.Buffer)
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk.OutputStream",
+					Name: "output",
+					Props: job.P{
+						"Ports": []fwk.Port{
+							{
+								Name: "t1-ints1-massaged",
+								Type: reflect.TypeOf(int64(1)),
+							},
+						},
+						"Streamer": &fwktest.OutputStream{W: out},
+					},
+				})
+
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+					Name: "t2",
+					Props: job.P{
+						"Input":  "t1-ints1",
+						"Output": "t1-ints1-massaged",
+					},
+				})
+
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk/internal/fwktest.reducer",
+					Name: "reducer",
+					Props: job.P{
+						"Input": "t1-ints1-massaged",
+						"Sum":   getsumsq(nmax),
+					},
+				})
+
+				app.Create(job.C{
+					Type: "go-hep.org/x/hep/fwk.InputStream",
+					Name: "input",
+					Props: job.P{
+						"Ports": []fwk.Port{
+							{
+								Name: "t1-ints1",
+								Type: reflect.TypeOf(int64(1)),
+							},
+						},
+						"Streamer": &fwktest.InputStream{
+							R: newTestReader(max),
+						},
+					},
+				})
+
+				err := app.App().Run()
+				if err != nil {
+					t.Fatalf("error (evtmax=%d nprocs=%d nslots=%d): %v", evtmax, nprocs, nslots, err)
+				}
+
+				if got, want := len(strings.Fields(out.String())), int(nmax); got != want {
+					t.Fatalf("invalid number of events written (evtmax=%d nprocs=%d nslots=%d): got=%d, want=%d",
+						evtmax, nprocs, nslots, got, want,
+					)
+				}
+			}
+		}
+	}
+}
+
+func TestSchedulerSequences(t *testing.T) {
+	const max = 200
+	app := newSchedApp(-1, 4, 4)
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.InputStream",
+		Name: "input",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{
+					Name: "ints",
+					Type: reflect.TypeOf(int64(1)),
+				},
+			},
+			"Streamer": &fwktest.InputStream{
+				R: newTestReader(max),
+			},
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.filter",
+		Name: "f3",
+		Props: job.P{
+			"Input":  "ints",
+			"Modulo": int64(3),
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.task2",
+		Name: "sq",
+		Props: job.P{
+			"Input":  "ints",
+			"Output": "sq-ints",
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.reducer",
+		Name: "reducer",
+		Props: job.P{
+			"Input": "sq-ints",
+			"Sum":   getsumsqIf(max, func(i int64) bool { return i%3 != 0 }),
+		},
+	})
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.Sequence",
+		Name: "path",
+		Props: job.P{
+			"Members": []string{"!f3", "sq"},
+		},
+	})
+
+	err := app.App().Run()
+	if err != nil {
+		t.Fatalf("could not run application: %+v", err)
+	}
+}
+
+func TestSchedulerCardinality(t *testing.T) {
+	const (
+		max    = 200
+		nslots = 4
+	)
+	for _, tc := range []struct {
+		conc  fwk.Concurrency
+		card  map[string]int
+		insts int
+		conc1 bool // whether at most one event is processed at a time
+	}{
+		{conc: fwk.ThreadSafe, insts: 1},
+		{conc: fwk.SingleInstance, insts: 1, conc1: true},
+		{conc: fwk.Clonable, insts: nslots},
+		{conc: fwk.Clonable, card: map[string]int{"counter": 2}, insts: 2},
+	} {
+		t.Run(fmt.Sprintf("%v-%d", tc.conc, tc.insts), func(t *testing.T) {
+			app := newSchedApp(-1, 8, nslots)
+			if tc.card != nil {
+				app.SetProp(app.App(), "Cardinality", tc.card)
+			}
+
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk.InputStream",
+				Name: "input",
+				Props: job.P{
+					"Ports": []fwk.Port{
+						{
+							Name: "ints",
+							Type: reflect.TypeOf(int64(1)),
+						},
+					},
+					"Streamer": &fwktest.InputStream{
+						R: newTestReader(max),
+					},
+				},
+			})
+
+			stats := &fwktest.CounterStats{}
+			app.Create(job.C{
+				Type: "go-hep.org/x/hep/fwk/internal/fwktest.counter",
+				Name: "counter",
+				Props: job.P{
+					"Input":       "ints",
+					"Concurrency": tc.conc,
+					"Stats":       stats,
+				},
+			})
+
+			err := app.App().Run()
+			if err != nil {
+				t.Fatalf("could not run application: %+v", err)
+			}
+
+			if got, want := stats.NEvts, max; got != want {
+				t.Fatalf("invalid number of events: got=%d, want=%d", got, want)
+			}
+			if got, want := stats.Instances, tc.insts; got != want {
+				t.Fatalf("invalid number of instances: got=%d, want=%d", got, want)
+			}
+			if got, want := stats.MaxConc, tc.insts; tc.conc != fwk.ThreadSafe && got > want {
+				t.Fatalf("invalid concurrency: got=%d, want<=%d", got, want)
+			}
+			if tc.conc1 && stats.MaxConc != 1 {
+				t.Fatalf("invalid concurrency: got=%d, want=1", stats.MaxConc)
+			}
+		})
+	}
+}
+
+func TestSingleInstanceConcApp(t *testing.T) {
+	const max = 200
+	app := newapp(-1, 4)
+
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk.InputStream",
+		Name: "input",
+		Props: job.P{
+			"Ports": []fwk.Port{
+				{
+					Name: "ints",
+					Type: reflect.TypeOf(int64(1)),
+				},
+			},
+			"Streamer": &fwktest.InputStream{
+				R: newTestReader(max),
+			},
+		},
+	})
+
+	stats := &fwktest.CounterStats{}
+	app.Create(job.C{
+		Type: "go-hep.org/x/hep/fwk/internal/fwktest.counter",
+		Name: "counter",
+		Props: job.P{
+			"Input":       "ints",
+			"Concurrency": fwk.SingleInstance,
+			"Stats":       stats,
+		},
+	})
+
+	err := app.App().Run()
+	if err != nil {
+		t.Fatalf("could not run application: %+v", err)
+	}
+
+	if got, want := stats.MaxConc, 1; got != want {
+		t.Fatalf("invalid concurrency: got=%d, want=%d", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fwk
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Sequence is a named sequence of tasks (a path), whose filter decision
+// combines the filter decisions of its members.
+//
+// Sequence declares a property 'Members', a []string, holding the names of
+// the tasks (or sequences) of the sequence.
+// Prefixing a member name with '!' negates its filter decision.
+//
+// Sequence declares a property 'Mode', a string, which can be either:
+//   - "AND" (the default): the sequence accepts an event if all its members
+//     accept it. Members following a member rejecting the event are not
+//     executed for that event.
+//   - "OR": the sequence accepts an event if any of its members accepts it.
+//     Members following a member accepting the event are not executed for
+//     that event.
+//
+// Tasks whose inputs are produced by tasks that were not executed for
+// a given event are not executed either.
+type Sequence struct {
+	TaskBase
+
+	members []string
+	mode    string
+}
+
+// Configure checks the configuration of the sequence.
+func (seq *Sequence) Configure(ctx Context) error {
+	var err error
+
+	seq.mode = strings.ToUpper(seq.mode)
+	switch seq.mode {
+	case "AND", "OR":
+	default:
+		return fmt.Errorf("fwk: sequence [%s] has an invalid mode %q (want AND or OR)", seq.Name(), seq.mode)
+	}
+
+	for _, m := range seq.members {
+		name, _ := seq.member(m)
+		if name == "" {
+			return fmt.Errorf("fwk: sequence [%s] has an empty member name", seq.Name())
+		}
+	}
+
+	return err
+}
+
+// StartTask starts the sequence.
+func (seq *Sequence) StartTask(ctx Context) error {
+	return nil
+}
+
+// StopTask stops the sequence.
+func (seq *Sequence) StopTask(ctx Context) error {
+	return nil
+}
+
+// Process waits for the decisions of the members of the sequence and
+// combines them into the decision of the sequence.
+func (seq *Sequence) Process(ctx Context) error {
+	c, ok := ctx.(filterContext)
+	if !ok {
+		return nil
+	}
+	cctx, evt := c.flow()
+	if evt == nil {
+		return nil
+	}
+
+	pass := seq.mode == "AND"
+	for _, m := range seq.members {
+		name, neg := seq.member(m)
+		dec, err := evt.wait(cctx, name)
+		if err != nil {
+			return err
+		}
+		v := dec.run && dec.pass != neg
+		switch seq.mode {
+		case "AND":
+			if !v {
+				pass = false
+			}
+		case "OR":
+			if v {
+				pass = true
+			}
+		}
+		if pass != (seq.mode == "AND") {
+			break
+		}
+	}
+
+	SetFilterPassed(ctx, pass)
+	return nil
+}
+
+// member returns the name of the member m and whether its decision
+// should be negated.
+func (seq *Sequence) member(m string) (string, bool) {
+	name := strings.TrimPrefix(m, "!")
+	return strings.TrimSpace(name), name != m
+}
+
+func newSequence(typ, name string, mgr App) (Component, error) {
+	var err error
+
+	seq := &Sequence{
+		TaskBase: NewTask(typ, name, mgr),
+		members:  nil,
+		mode:     "AND",
+	}
+
+	err = seq.DeclProp("Members", &seq.members)
+	if err != nil {
+		return nil, err
+	}
+
+	err = seq.DeclProp("Mode", &seq.mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return seq, err
+}
+
+func init() {
+	Register(reflect.TypeOf(Sequence{}), newSequence)
+}
@@ -21,7 +21,7 @@ import (
 	"os"
 	"runtime/pprof"
 
-	_ "go-hep.org/x/hep/fwk"
+	"go-hep.org/x/hep/fwk"
 	"go-hep.org/x/hep/fwk/job"
 )
 
@@ -29,9 +29,16 @@ var (
 	g_lvl      = flag.String("l", "INFO", "log level (DEBUG|INFO|WARN|ERROR)")
 	g_evtmax   = flag.Int("evtmax", -1, "number of events to process")
 	g_nprocs   = flag.Int("nprocs", 0, "number of concurrent events to process")
+	g_nworkers = flag.Int("nworkers", 0, "number of worker processes")
 	g_cpu_prof = flag.Bool("cpu-prof", false, "enable CPU profiling")
+	g_job      = flag.String("job", "", "job description file (YAML or TOML) replacing the setup functions")
+	g_set      job.Overrides
 )
 
+func init() {
+	flag.Var(&g_set, "set", "override a property (component.Property=value, app.Property=value)")
+}
+
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, {{.Usage | gen_usage}}, os.Args[0])
@@ -42,7 +49,7 @@ func main() {
 
 	fmt.Printf("::: {{.Name}}...\n")
 	if *g_cpu_prof {
-		f, err := os.Create("cpu.prof")
+		f, err := os.Create(fwk.OutputFile("cpu.prof"))
 		if err != nil {
 			panic(err)
 		}
@@ -51,13 +58,20 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
-	app := job.New(job.P{
-		"EvtMax":   int64(*g_evtmax),
-		"NProcs":   *g_nprocs,
-		"MsgLevel": job.MsgLevel(*g_lvl),
-	})
-
-    {{with .SetupFuncs}}{{. | gen_setups}}{{end}}
+	app, err := job.Setup(
+		job.P{
+			"EvtMax":   int64(*g_evtmax),
+			"NProcs":   *g_nprocs,
+			"NWorkers": *g_nworkers,
+			"MsgLevel": job.MsgLevel(*g_lvl),
+		},
+		*g_job, g_set,
+{{with .SetupFuncs}}{{. | gen_setups}}{{end}}
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "::: {{.Name}}: could not setup job: %+v\n", err)
+		os.Exit(1)
+	}
 
 	app.Run()
 	fmt.Printf("::: {{.Name}}... [done]\n")
@@ -80,7 +94,7 @@ func gen_setups(setups []string) string {
 	str := make([]string, 0, len(setups))
 	for _, setup := range setups {
 		str = append(str,
-			"\t"+setup+"(app)",
+			"\t\t"+setup+",",
 		)
 	}
 	return strings.Join(str, "\n")
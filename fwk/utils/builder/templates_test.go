@@ -0,0 +1,43 @@
+// Copyright ©2017 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package builder
+
+import (
+	"go/format"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	o := new(strings.Builder)
+	err := render(o, tmpl, struct {
+		Usage      string
+		Name       string
+		SetupFuncs []string
+	}{
+		Usage:      "Usage: %[1]s [options]",
+		Name:       "fwk-test",
+		SetupFuncs: []string{"setup1", "setup2"},
+	})
+	if err != nil {
+		t.Fatalf("could not render main: %+v", err)
+	}
+
+	src := o.String()
+	_, err = format.Source([]byte(src))
+	if err != nil {
+		t.Fatalf("invalid main: %+v\n%s", err, src)
+	}
+
+	for _, want := range []string{
+		`flag.String("job", ""`,
+		`flag.Var(&g_set, "set"`,
+		"*g_job, g_set,\n\t\tsetup1,\n\t\tsetup2,\n\t)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("main does not contain %q:\n%s", want, src)
+		}
+	}
+}
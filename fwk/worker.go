@@ -7,6 +7,8 @@ package fwk
 import (
 	"context"
 	"fmt"
+	"runtime/debug"
+	"sync"
 )
 
 type workercontrol struct {
@@ -14,19 +16,27 @@ type workercontrol struct {
 	done   chan struct{}
 	errc   chan error
 	runctx context.Context
+
+	inflight *sync.WaitGroup // events sent to the workers and not yet processed
 }
 
 type worker struct {
 	slot int
 	keys []string
 	//store datastore
-	ctxs []ctxType
-	msg  msgstream
+	ctxs  []ctxType
+	msg   msgstream
+	flow  *ctrlflow
+	errs  *errstack
+	locks map[string]*sync.Mutex
+	mons  []MonSvc
 
 	evts   <-chan ctxType
 	done   chan<- struct{}
 	errc   chan<- error
 	runctx context.Context
+
+	inflight *sync.WaitGroup
 }
 
 func newWorker(i int, app *appmgr, ctrl *workercontrol) *worker {
@@ -35,10 +45,16 @@ func newWorker(i int, app *appmgr, ctrl *workercontrol) *worker {
 		keys:   app.dflow.keys(),
 		ctxs:   make([]ctxType, len(app.tsks)),
 		msg:    newMsgStream(fmt.Sprintf("%s-worker-%03d", app.name, i), app.msg.lvl, nil),
+		flow:   app.flow,
+		errs:   app.errs,
+		locks:  app.locks,
+		mons:   app.mons,
 		evts:   ctrl.evts,
 		done:   ctrl.done,
 		errc:   ctrl.errc,
 		runctx: ctrl.runctx,
+
+		inflight: ctrl.inflight,
 	}
 	for j, tsk := range app.tsks {
 		wrk.ctxs[j] = ctxType{
@@ -66,6 +82,7 @@ func (wrk *worker) run(tsks []Task) {
 				return
 			}
 			wrk.runTask(wrk.runctx, ievt, tsks)
+			wrk.inflight.Done()
 
 		case <-wrk.runctx.Done():
 			//wrk.store.close()
@@ -85,7 +102,14 @@ func (wrk *worker) runTask(ctx context.Context, ievt ctxType, tsks []Task) {
 		ievt:   ievt.ID(),
 		errc:   make(chan error, len(tsks)),
 		evtctx: evtctx,
+		evt:    newEvtFlow(tsks),
+		flow:   wrk.flow,
+		errs:   wrk.errs,
+		locks:  wrk.locks,
+		mons:   wrk.mons,
 	}
+	ievt.slot = wrk.slot
+	evt.beginEvent(ievt)
 	for i, tsk := range tsks {
 		ctx := wrk.ctxs[i]
 		ctx.store = evtstore
@@ -117,6 +141,11 @@ errloop:
 			return
 		}
 	}
+	if wrk.flow != nil {
+		wrk.flow.record(evt.evt)
+	}
+	evt.endEvent(ievt)
+
 	err := evtstore.reset(wrk.keys)
 	evtstore.close()
 	wrk.msg.flush()
@@ -131,16 +160,97 @@ type taskrunner struct {
 	errc   chan error
 	evtctx context.Context
 
-	ievt int64
+	ievt  int64
+	evt   *evtflow
+	flow  *ctrlflow
+	errs  *errstack
+	locks map[string]*sync.Mutex // locks of non thread-safe tasks
+	mons  []MonSvc               // monitoring services
 }
 
 func (run taskrunner) run(i int, ctx ctxType, tsk Task) {
 	ctx.id = run.ievt
 	select {
-	case run.errc <- tsk.Process(ctx):
+	case run.errc <- run.process(ctx, tsk):
 		// FIXME(sbinet) dont be so eager to flush...
 		ctx.msg.flush()
 	case <-run.evtctx.Done():
 		ctx.msg.flush()
 	}
 }
+
+// process runs the task if the filter decisions of the tasks it
+// depends on allow it, and records its own filter decision.
+// Errors are handled according to the error policy of the task.
+func (run taskrunner) process(ctx ctxType, tsk Task) error {
+	if run.evt == nil || run.flow == nil {
+		return run.exec(ctx, tsk)
+	}
+
+	dec := run.evt.decs[tsk.Name()]
+	defer close(dec.done)
+
+	flow := run.flow.tsks[tsk.Name()]
+	ok, err := run.evt.enabled(run.evtctx, flow)
+	if err != nil || !ok {
+		return err
+	}
+
+	if mu := run.locks[tsk.Name()]; mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+
+	ctx.ctx = run.evtctx
+	ctx.evt = run.evt
+	ctx.dec = dec
+	dec.run = true
+	err = run.exec(ctx, tsk)
+	if err == nil {
+		return nil
+	}
+
+	dec.run = false
+	dec.fail = true
+	err = &EventError{ID: ctx.id, Task: tsk.Name(), Err: err}
+	if run.errs == nil {
+		return err
+	}
+
+	policy := Abort
+	if flow != nil {
+		policy = flow.policy
+	}
+	switch policy {
+	case SkipEvent:
+		run.evt.skip.Store(true)
+	case Continue:
+		// ok.
+	default:
+		_ = run.errs.push(run.evt, err)
+		return err
+	}
+
+	ctx.msg.Errorf("%v (policy=%v)\n", err, policy)
+	return run.errs.push(run.evt, err)
+}
+
+// exec runs the task, converting panics into errors.
+// Monitoring services are notified before and after the task runs.
+func (run taskrunner) exec(ctx ctxType, tsk Task) (err error) {
+	for _, mon := range run.mons {
+		mon.BeginTask(ctx, tsk)
+	}
+	defer func() {
+		for _, mon := range run.mons {
+			mon.EndTask(ctx, tsk, err)
+		}
+	}()
+	defer func() {
+		if e := recover(); e != nil {
+			ctx.msg.Debugf("panic: %v\n%s\n", e, debug.Stack())
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+	return tsk.Process(ctx)
+}
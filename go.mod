@@ -10,6 +10,7 @@ require (
 	codeberg.org/sbinet/npyio v0.11.0
 	git.sr.ht/~sbinet/epok v0.5.0
 	git.sr.ht/~sbinet/go-arrow v0.3.0
+	github.com/BurntSushi/toml v1.6.0
 	github.com/google/go-cmp v0.7.0
 	github.com/hashicorp/go-uuid v1.0.3
 	github.com/jcmturner/gokrb5/v8 v8.4.4
@@ -22,6 +23,7 @@ require (
 	golang.org/x/crypto v0.38.0
 	golang.org/x/image v0.27.0
 	golang.org/x/sync v0.14.0
+	golang.org/x/sys v0.33.0
 	golang.org/x/text v0.25.0
 	golang.org/x/tools v0.33.0
 	gonum.org/v1/gonum v0.16.0
@@ -53,7 +55,6 @@ require (
 	github.com/teambition/rrule-go v1.8.2 // indirect
 	golang.org/x/mod v0.24.0 // indirect
 	golang.org/x/net v0.40.0 // indirect
-	golang.org/x/sys v0.33.0 // indirect
 	modernc.org/b v1.1.0 // indirect
 	modernc.org/db v1.0.14 // indirect
 	modernc.org/file v1.0.10 // indirect
@@ -0,0 +1,273 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package riofs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FS returns a file system (an fs.FS) for the hierarchy of keys rooted at the directory dir.
+//
+// The directories of the file system are the ROOT directories and its regular files
+// are the other keys. The content of a regular file is the uncompressed payload of
+// the object of its key, as returned by Key.Bytes, and the Sys method of its fs.FileInfo
+// returns the *Key.
+// Only the highest cycle of each key is listed. Other cycles may be opened
+// with the "name;cycle" syntax.
+//
+// The returned file system implements fs.ReadDirFS and fs.StatFS.
+//
+// Example:
+//
+//	f, err := riofs.Open("file.root")
+//	err = fs.WalkDir(riofs.FS(f), ".", func(path string, d fs.DirEntry, err error) error { ... })
+func FS(dir Directory) fs.FS {
+	return &dirFS{dir: dir}
+}
+
+type dirFS struct {
+	dir Directory
+}
+
+// entry is a file or a directory of a dirFS.
+type entry struct {
+	info fsInfo
+	dir  Directory // dir is the ROOT directory of a directory entry, nil otherwise.
+}
+
+// lookup returns the named entry.
+func (fsys *dirFS) lookup(op, name string) (entry, error) {
+	if !fs.ValidPath(name) {
+		return entry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return entry{info: fsInfo{name: "."}, dir: fsys.dir}, nil
+	}
+
+	var (
+		dir   = fsys.dir
+		elems = strings.Split(name, "/")
+	)
+	for i, elem := range elems {
+		k := findKey(dir, elem)
+		if k == nil {
+			return entry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+
+		ent := entry{info: fsInfo{name: elem, key: k}}
+		if ent.info.IsDir() {
+			obj, err := k.Object()
+			if err != nil {
+				return entry{}, &fs.PathError{Op: op, Path: name, Err: err}
+			}
+			ent.dir = obj.(Directory)
+		}
+		if i == len(elems)-1 {
+			return ent, nil
+		}
+		if ent.dir == nil {
+			return entry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		dir = ent.dir
+	}
+	panic("unreachable")
+}
+
+// findKey returns the key of dir identified by namecycle, or nil.
+// The highest cycle is selected when none is provided.
+func findKey(dir Directory, namecycle string) *Key {
+	if strings.Count(namecycle, ";") > 1 {
+		return nil
+	}
+	name, cycle := decodeNameCycle(namecycle)
+
+	var (
+		keys = dir.Keys()
+		key  *Key
+	)
+	for i := range keys {
+		k := &keys[i]
+		if k.Name() != name {
+			continue
+		}
+		switch {
+		case cycle != 9999:
+			if k.cycle == cycle {
+				return k
+			}
+		case key == nil || k.cycle > key.cycle:
+			key = k
+		}
+	}
+	return key
+}
+
+// Open implements fs.FS.
+func (fsys *dirFS) Open(name string) (fs.File, error) {
+	ent, err := fsys.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if ent.dir != nil {
+		return &fsDir{fsys: fsys, name: name, ent: ent}, nil
+	}
+
+	raw, err := ent.info.key.Bytes()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &fsFile{Reader: bytes.NewReader(raw), info: ent.info}, nil
+}
+
+// Stat implements fs.StatFS.
+func (fsys *dirFS) Stat(name string) (fs.FileInfo, error) {
+	ent, err := fsys.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return ent.info, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (fsys *dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	ent, err := fsys.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if ent.dir == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return readDir(ent.dir), nil
+}
+
+// readDir returns the entries of dir, sorted by name.
+func readDir(dir Directory) []fs.DirEntry {
+	var (
+		keys = dir.Keys()
+		set  = make(map[string]*Key, len(keys))
+	)
+	for i := range keys {
+		k := &keys[i]
+		if cur, dup := set[k.Name()]; dup && k.cycle < cur.cycle {
+			continue
+		}
+		set[k.Name()] = k
+	}
+
+	ents := make([]fs.DirEntry, 0, len(set))
+	for name, k := range set {
+		ents = append(ents, fs.FileInfoToDirEntry(fsInfo{name: name, key: k}))
+	}
+	sort.Slice(ents, func(i, j int) bool { return ents[i].Name() < ents[j].Name() })
+	return ents
+}
+
+// fsInfo describes a file or a directory of a dirFS.
+type fsInfo struct {
+	name string
+	key  *Key // key is the key of the entry, nil for the top-level directory.
+}
+
+func (fi fsInfo) Name() string { return fi.name }
+
+func (fi fsInfo) Size() int64 {
+	if fi.IsDir() {
+		return 0
+	}
+	return int64(fi.key.ObjLen())
+}
+
+func (fi fsInfo) Mode() fs.FileMode {
+	if fi.IsDir() {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (fi fsInfo) ModTime() time.Time {
+	if fi.key == nil {
+		return time.Time{}
+	}
+	return fi.key.datetime
+}
+
+func (fi fsInfo) IsDir() bool {
+	if fi.key == nil {
+		return true
+	}
+	switch fi.key.ClassName() {
+	case "TDirectory", "TDirectoryFile":
+		return true
+	}
+	return false
+}
+
+func (fi fsInfo) Sys() any {
+	if fi.key == nil {
+		return nil
+	}
+	return fi.key
+}
+
+// fsFile is a regular file of a dirFS.
+type fsFile struct {
+	*bytes.Reader
+	info fsInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *fsFile) Close() error               { return nil }
+
+// fsDir is a directory of a dirFS.
+type fsDir struct {
+	fsys *dirFS
+	name string
+	ent  entry
+	ents []fs.DirEntry // ents are the entries not yet returned by ReadDir.
+	read bool          // read indicates whether the entries have been listed.
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.ent.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		d.ents = readDir(d.ent.dir)
+		d.read = true
+	}
+
+	if n <= 0 {
+		ents := d.ents
+		d.ents = nil
+		return ents, nil
+	}
+	if len(d.ents) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.ents))
+	ents := d.ents[:n:n]
+	d.ents = d.ents[n:]
+	return ents, nil
+}
+
+var (
+	_ fs.ReadDirFS   = (*dirFS)(nil)
+	_ fs.StatFS      = (*dirFS)(nil)
+	_ fs.ReadDirFile = (*fsDir)(nil)
+	_ io.ReaderAt    = (*fsFile)(nil)
+	_ io.Seeker      = (*fsFile)(nil)
+)
@@ -0,0 +1,127 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package riofs
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"go-hep.org/x/hep/groot/rbase"
+	"go-hep.org/x/hep/groot/rbytes"
+)
+
+func TestFS(t *testing.T) {
+	f, err := Open("../testdata/dirs-6.14.00.root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fsys := FS(f)
+	err = fstest.TestFS(fsys, "dir1/dir11/h1", "dir2", "dir3")
+	if err != nil {
+		t.Fatalf("invalid file system: %v", err)
+	}
+
+	var got []string
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not walk file system: %v", err)
+	}
+	want := []string{".", "dir1", "dir1/dir11", "dir1/dir11/h1", "dir2", "dir3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid walk:\ngot = %q\nwant= %q", got, want)
+	}
+
+	fi, err := fs.Stat(fsys, "dir1/dir11/h1")
+	if err != nil {
+		t.Fatalf("could not stat file: %v", err)
+	}
+	key, ok := fi.Sys().(*Key)
+	if !ok || key.ClassName() != "TH1F" {
+		t.Fatalf("invalid key: %#v", fi.Sys())
+	}
+
+	for _, tc := range []struct {
+		name string
+		err  error
+	}{
+		{name: "/dir1", err: fs.ErrInvalid},
+		{name: "dir1/", err: fs.ErrInvalid},
+		{name: "missing", err: fs.ErrNotExist},
+		{name: "dir1/dir11/h1/missing", err: fs.ErrNotExist},
+		{name: "dir1;1;2", err: fs.ErrNotExist},
+	} {
+		_, err := fsys.Open(tc.name)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%q: invalid error: got=%v, want=%v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestFSCycles(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "cycles.root")
+	w, err := Create(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"cycle-1", "cycle-2"} {
+		err = w.Put("obj", rbase.NewObjString(v))
+		if err != nil {
+			t.Fatalf("could not put object: %v", err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %v", err)
+	}
+
+	f, err := Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fsys := FS(f)
+	ents, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatalf("could not read directory: %v", err)
+	}
+	if len(ents) != 1 || ents[0].Name() != "obj" {
+		t.Fatalf("invalid entries: %v", ents)
+	}
+
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{name: "obj", want: "cycle-2"},
+		{name: "obj;2", want: "cycle-2"},
+		{name: "obj;1", want: "cycle-1"},
+	} {
+		raw, err := fs.ReadFile(fsys, tc.name)
+		if err != nil {
+			t.Fatalf("could not read %q: %v", tc.name, err)
+		}
+		var obj rbase.ObjString
+		err = obj.UnmarshalROOT(rbytes.NewRBuffer(raw, nil, 0, nil))
+		if err != nil {
+			t.Fatalf("could not unmarshal %q: %v", tc.name, err)
+		}
+		if got := obj.String(); got != tc.want {
+			t.Fatalf("invalid content of %q: got=%q, want=%q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -13,6 +13,8 @@ import (
 func init() {
 	riofs.Register("root", openFile)
 	riofs.Register("xroot", openFile)
+	riofs.Register("roots", openFile)
+	riofs.Register("xroots", openFile)
 }
 
 func openFile(path string) (riofs.Reader, error) {
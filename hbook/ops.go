@@ -157,3 +157,60 @@ func AddH1D(h1, h2 *H1D) *H1D {
 func SubH1D(h1, h2 *H1D) *H1D {
 	return AddScaledH1D(h1, -1, h2)
 }
+
+// AddH2D returns the bin-by-bin summed histogram of h1 and h2
+// assuming their statistical uncertainties are uncorrelated.
+func AddH2D(h1, h2 *H2D) *H2D {
+	if h1.Binning.Nx != h2.Binning.Nx || h1.Binning.Ny != h2.Binning.Ny {
+		panic(fmt.Errorf("hbook: h1 and h2 have different number of bins"))
+	}
+
+	if h1.XMin() != h2.XMin() || h1.XMax() != h2.XMax() ||
+		h1.YMin() != h2.YMin() || h1.YMax() != h2.YMax() {
+		panic(fmt.Errorf("hbook: h1 and h2 have different range"))
+	}
+
+	o := &H2D{
+		Binning: h1.Binning,
+		Ann:     h1.Ann.clone(),
+	}
+	o.Binning.Bins = append([]Bin2D(nil), h1.Binning.Bins...)
+	o.Binning.XEdges = append([]Bin1D(nil), h1.Binning.XEdges...)
+	o.Binning.YEdges = append([]Bin1D(nil), h1.Binning.YEdges...)
+
+	for i := range o.Binning.Bins {
+		o.Binning.Bins[i].Dist.addScaled(1, 1, h2.Binning.Bins[i].Dist)
+	}
+	o.Binning.Dist.addScaled(1, 1, h2.Binning.Dist)
+	for i := range o.Binning.Outflows {
+		o.Binning.Outflows[i].addScaled(1, 1, h2.Binning.Outflows[i])
+	}
+	return o
+}
+
+// AddP1D returns the bin-by-bin summed profile of p1 and p2
+// assuming their statistical uncertainties are uncorrelated.
+func AddP1D(p1, p2 *P1D) *P1D {
+	if len(p1.bng.bins) != len(p2.bng.bins) {
+		panic(fmt.Errorf("hbook: p1 and p2 have different number of bins"))
+	}
+
+	if p1.XMin() != p2.XMin() || p1.XMax() != p2.XMax() {
+		panic(fmt.Errorf("hbook: p1 and p2 have different range"))
+	}
+
+	o := &P1D{
+		bng: p1.bng,
+		ann: p1.ann.clone(),
+	}
+	o.bng.bins = append([]BinP1D(nil), p1.bng.bins...)
+
+	for i := range o.bng.bins {
+		o.bng.bins[i].dist.addScaled(1, 1, p2.bng.bins[i].dist)
+	}
+	o.bng.dist.addScaled(1, 1, p2.bng.dist)
+	for i := range o.bng.outflows {
+		o.bng.outflows[i].addScaled(1, 1, p2.bng.outflows[i])
+	}
+	return o
+}
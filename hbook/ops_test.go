@@ -327,3 +327,104 @@ END YODA_HISTO1D_V2
 		)
 	}
 }
+
+func TestAddH2D(t *testing.T) {
+	var (
+		h1   = NewH2D(4, 0, 4, 3, 0, 3)
+		h2   = NewH2D(4, 0, 4, 3, 0, 3)
+		want = NewH2D(4, 0, 4, 3, 0, 3)
+	)
+
+	for _, v := range [][3]float64{
+		{-0.5, 0.5, 1}, {0.5, 1.5, 2}, {1.5, 1.5, 1}, {3.5, 2.5, 0.5}, {4.5, 3.5, 1},
+	} {
+		h1.Fill(v[0], v[1], v[2])
+		want.Fill(v[0], v[1], v[2])
+	}
+	for _, v := range [][3]float64{
+		{0.5, 0.5, 1}, {2.5, 1.5, 2}, {2.5, -1.5, 1}, {3.5, 2.5, 0.5},
+	} {
+		h2.Fill(v[0], v[1], v[2])
+		want.Fill(v[0], v[1], v[2])
+	}
+
+	n1 := h1.Entries()
+	got := AddH2D(h1, h2)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid sum:\ngot= %+v\nwant=%+v", got.Binning, want.Binning)
+	}
+	if h1.Entries() != n1 {
+		t.Fatalf("h1 was modified")
+	}
+
+	for _, tc := range []struct {
+		h2     *H2D
+		panics string
+	}{
+		{NewH2D(4, 0, 4, 2, 0, 3), "hbook: h1 and h2 have different number of bins"},
+		{NewH2D(4, 0, 4, 3, 0, 4), "hbook: h1 and h2 have different range"},
+	} {
+		func() {
+			defer func() {
+				err := recover()
+				if err == nil {
+					t.Fatalf("expected a panic")
+				}
+				if got, want := err.(error).Error(), tc.panics; got != want {
+					t.Fatalf("invalid panic message.\ngot= %v\nwant=%v", got, want)
+				}
+			}()
+			_ = AddH2D(h1, tc.h2)
+		}()
+	}
+}
+
+func TestAddP1D(t *testing.T) {
+	var (
+		p1   = NewP1D(4, 0, 4)
+		p2   = NewP1D(4, 0, 4)
+		want = NewP1D(4, 0, 4)
+	)
+
+	for _, v := range [][3]float64{
+		{-0.5, 0.5, 1}, {0.5, 1.5, 2}, {1.5, 1.5, 1}, {3.5, 2.5, 0.5},
+	} {
+		p1.Fill(v[0], v[1], v[2])
+		want.Fill(v[0], v[1], v[2])
+	}
+	for _, v := range [][3]float64{
+		{0.5, 0.5, 1}, {2.5, 1.5, 2}, {4.5, 3.5, 1},
+	} {
+		p2.Fill(v[0], v[1], v[2])
+		want.Fill(v[0], v[1], v[2])
+	}
+
+	got := AddP1D(p1, p2)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid sum:\ngot= %+v\nwant=%+v", got.bng, want.bng)
+	}
+	if got, want := p1.Entries(), int64(4); got != want {
+		t.Fatalf("p1 was modified: entries=%d, want=%d", got, want)
+	}
+
+	for _, tc := range []struct {
+		p2     *P1D
+		panics string
+	}{
+		{NewP1D(3, 0, 4), "hbook: p1 and p2 have different number of bins"},
+		{NewP1D(4, 1, 4), "hbook: p1 and p2 have different range"},
+	} {
+		func() {
+			defer func() {
+				err := recover()
+				if err == nil {
+					t.Fatalf("expected a panic")
+				}
+				if got, want := err.(error).Error(), tc.panics; got != want {
+					t.Fatalf("invalid panic message.\ngot= %v\nwant=%v", got, want)
+				}
+			}()
+			_ = AddP1D(p1, tc.p2)
+		}()
+	}
+}
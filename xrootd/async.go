@@ -0,0 +1,182 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go-hep.org/x/hep/xrootd/xrdfs"
+)
+
+// defaultPipelineDepth is the default number of requests in flight of a Pipeline.
+const defaultPipelineDepth = 16
+
+// Future is the pending result of a request issued asynchronously with Go.
+type Future[T any] struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	val    T
+	err    error
+}
+
+// Done returns a channel that is closed once the request has completed.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the request to complete and returns its result.
+func (f *Future[T]) Wait() (T, error) {
+	<-f.done
+	return f.val, f.err
+}
+
+// Cancel cancels the request if it has not completed yet.
+// The request then completes with the context.Canceled error.
+//
+// The request is only abandoned locally: no kXR_cancel request is sent and the
+// server may still perform it, e.g. write data. Its response, if any, is discarded.
+func (f *Future[T]) Cancel() {
+	f.cancel()
+}
+
+// Pipeline bounds the number of requests issued with Go that are in flight.
+// Requests sent concurrently to the same server are pipelined over the same
+// connection, each of them being identified by its own stream ID.
+// A Pipeline is safe for concurrent use.
+//
+// A nil *Pipeline does not bound the number of requests in flight.
+type Pipeline struct {
+	slots chan struct{}
+}
+
+// NewPipeline returns a Pipeline allowing up to n requests in flight.
+// A zero or negative value of n selects the default of 16 requests.
+func NewPipeline(n int) *Pipeline {
+	if n <= 0 {
+		n = defaultPipelineDepth
+	}
+	return &Pipeline{slots: make(chan struct{}, n)}
+}
+
+func (p *Pipeline) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pipeline) release() {
+	if p == nil {
+		return
+	}
+	<-p.slots
+}
+
+// Go issues asynchronously the request performed by fct and returns its pending result.
+// Go blocks until the pipeline p allows one more request in flight, or until ctx is done.
+// The request is canceled when ctx is done or when the Cancel method of the returned
+// Future is called. See Future.Cancel for the semantics of a cancellation.
+//
+// Any method of xrdfs.File and xrdfs.FileSystem may be issued asynchronously:
+//
+//	p := xrootd.NewPipeline(8)
+//	stat := xrootd.Go(ctx, p, func(ctx context.Context) (xrdfs.EntryStat, error) {
+//		return fs.Stat(ctx, "/tmp/file.txt")
+//	})
+//	read := xrootd.Go(ctx, p, func(ctx context.Context) (int, error) {
+//		return f.ReadAtContext(ctx, buf, 0)
+//	})
+//	n, err := read.Wait()
+//	es, err := stat.Wait()
+func Go[T any](ctx context.Context, p *Pipeline, fct func(ctx context.Context) (T, error)) *Future[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	f := &Future[T]{done: make(chan struct{}), cancel: cancel}
+
+	if err := p.acquire(ctx); err != nil {
+		cancel()
+		f.err = err
+		close(f.done)
+		return f
+	}
+
+	go func() {
+		defer close(f.done)
+		defer cancel()
+		defer p.release()
+		f.val, f.err = fct(ctx)
+	}()
+	return f
+}
+
+// ReadAt reads len(buf) bytes of the file f into buf, starting at offset off.
+// The data is read with concurrent requests of at most chunk bytes, issued through
+// the pipeline p, so that large reads of a single file are performed with a high throughput.
+// A zero or negative value of chunk selects a default of 1 MiB.
+//
+// ReadAt returns the number of bytes read and, if fewer than len(buf) bytes
+// were read, the first error encountered or io.EOF.
+// The outstanding requests are canceled upon the first error.
+func (p *Pipeline) ReadAt(ctx context.Context, f xrdfs.File, buf []byte, off int64, chunk int) (int, error) {
+	if chunk <= 0 {
+		chunk = 1 << 20
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	futures := make([]*Future[int], 0, (len(buf)+chunk-1)/chunk)
+	for beg := 0; beg < len(buf); beg += chunk {
+		var (
+			dst = buf[beg:min(beg+chunk, len(buf))]
+			pos = off + int64(beg)
+		)
+		futures = append(futures, Go(ctx, p, func(ctx context.Context) (int, error) {
+			n, err := f.ReadAtContext(ctx, dst, pos)
+			if err != nil {
+				cancel()
+			}
+			return n, err
+		}))
+	}
+
+	var (
+		n    int
+		err  error
+		stop bool // stop indicates whether a short read or an error occurred.
+	)
+	for i, fut := range futures {
+		nn, ferr := fut.Wait()
+		switch {
+		case ferr != nil:
+			// Requests canceled because of the failure of another one do not
+			// report the actual error.
+			if err == nil && (parent.Err() != nil || !errors.Is(ferr, context.Canceled)) {
+				err = ferr
+			}
+			stop = true
+		case !stop:
+			n += nn
+			if nn < min(chunk, len(buf)-i*chunk) {
+				stop = true
+			}
+		}
+	}
+
+	switch {
+	case err != nil:
+		return n, err
+	case n < len(buf):
+		return n, io.EOF
+	}
+	return n, nil
+}
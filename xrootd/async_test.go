@@ -0,0 +1,169 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/read"
+)
+
+// slowHandler is a handler delaying its read requests,
+// and recording the maximum number of read requests in flight.
+type slowHandler struct {
+	xrootd.Handler
+	delay   time.Duration
+	block   chan struct{} // block, if not nil, blocks the read requests until closed.
+	cur     atomic.Int32
+	maxReqs atomic.Int32
+}
+
+func (h *slowHandler) Read(sessionID [16]byte, request *read.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	cur := h.cur.Add(1)
+	defer h.cur.Add(-1)
+	for {
+		old := h.maxReqs.Load()
+		if cur <= old || h.maxReqs.CompareAndSwap(old, cur) {
+			break
+		}
+	}
+
+	time.Sleep(h.delay)
+	if h.block != nil {
+		<-h.block
+	}
+	return h.Handler.Read(sessionID, request)
+}
+
+func openTestFile(t *testing.T, h xrootd.Handler, name string) xrdfs.File {
+	t.Helper()
+
+	addr := serveHandler(t, h)
+	cli, err := xrootd.NewClient(context.Background(), addr, "gopher")
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+
+	f, err := cli.FS().Open(context.Background(), name, xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	t.Cleanup(func() { f.Close(context.Background()) })
+	return f
+}
+
+func TestPipelineReadAt(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 1<<20+42)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatalf("could not prepare test data: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "file.bin"), data, 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	h := &slowHandler{Handler: xrootd.NewFSHandler(dir), delay: 10 * time.Millisecond}
+	f := openTestFile(t, h, "/file.bin")
+
+	const depth = 4
+	p := xrootd.NewPipeline(depth)
+	for _, tc := range []struct {
+		off int64
+		n   int
+		err error
+	}{
+		{off: 0, n: len(data)},
+		{off: 100, n: 200000},
+		{off: int64(len(data)) - 70000, n: 100000, err: io.EOF},
+		{off: int64(len(data)), n: 10, err: io.EOF},
+	} {
+		buf := make([]byte, tc.n)
+		n, err := p.ReadAt(context.Background(), f, buf, tc.off, 64*1024)
+		if err != tc.err {
+			t.Fatalf("off=%d, n=%d: invalid error: got=%v, want=%v", tc.off, tc.n, err, tc.err)
+		}
+		want := data[min(tc.off, int64(len(data))):min(tc.off+int64(tc.n), int64(len(data)))]
+		if !bytes.Equal(buf[:n], want) {
+			t.Fatalf("off=%d, n=%d: invalid data", tc.off, tc.n)
+		}
+	}
+
+	switch got := h.maxReqs.Load(); {
+	case got > depth:
+		t.Fatalf("too many requests in flight: got=%d, max=%d", got, depth)
+	case got < 2:
+		t.Fatalf("requests were not pipelined: got=%d requests in flight", got)
+	}
+}
+
+func TestGoCancel(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello world"), 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	h := &slowHandler{Handler: xrootd.NewFSHandler(dir), block: make(chan struct{})}
+	f := openTestFile(t, h, "/file.txt")
+
+	var (
+		ctx = context.Background()
+		p   = xrootd.NewPipeline(1)
+		buf = make([]byte, 5)
+	)
+
+	fut := xrootd.Go(ctx, p, func(ctx context.Context) (int, error) {
+		return f.ReadAtContext(ctx, buf, 0)
+	})
+	// Cancel the request once it is being served.
+	for h.cur.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fut.Cancel()
+	select {
+	case <-fut.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("canceled request did not complete")
+	}
+	if _, err := fut.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+	}
+
+	// A canceled context does not issue the request.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := xrootd.Go(cctx, p, func(ctx context.Context) (int, error) {
+		return f.ReadAtContext(ctx, buf, 0)
+	}).Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("invalid error: got=%v, want=%v", err, context.Canceled)
+	}
+
+	// The late response to the canceled request does not prevent the next ones.
+	close(h.block)
+	n, err := xrootd.Go(ctx, p, func(ctx context.Context) (int, error) {
+		return f.ReadAtContext(ctx, buf, 6)
+	}).Wait()
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	if got, want := string(buf[:n]), "world"; got != want {
+		t.Fatalf("invalid data: got=%q, want=%q", got, want)
+	}
+}
@@ -13,10 +13,17 @@ import (
 	"go-hep.org/x/hep/xrootd/xrdproto/auth/host"
 	"go-hep.org/x/hep/xrootd/xrdproto/auth/krb5"
 	"go-hep.org/x/hep/xrootd/xrdproto/auth/unix"
+	"go-hep.org/x/hep/xrootd/xrdproto/auth/ztn"
 )
 
 // defaultProviders is the list of authentification providers a xrootd client will use by default.
+// Bearer tokens (ztn) are only sent over TLS-encrypted connections (see WithTLS).
+//
+// The sss provider is not enabled by default since its credentials are not
+// compatible with the ones of the reference XRootD implementation: it must be
+// selected explicitly with WithAuth.
 var defaultProviders = []auth.Auther{
+	ztn.Default,
 	krb5.Default,
 	unix.Default,
 	host.Default,
@@ -42,6 +49,11 @@ func (sess *cliSession) auth(ctx context.Context, securityInformation []byte) er
 			errs = append(errs, fmt.Errorf("xrootd: could not authorize using %s: provider was not found", provider))
 			continue
 		}
+		if provider == "ztn" && !sess.isTLS() {
+			// the token would be sent in clear text.
+			errs = append(errs, fmt.Errorf("xrootd: could not authorize using %s: connection is not TLS-encrypted", provider))
+			continue
+		}
 		r, err := auther.Request(params)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("xrootd: could not authorize using %s: %w", provider, err))
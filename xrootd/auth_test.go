@@ -0,0 +1,174 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd_test // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdproto/auth/sss"
+	"go-hep.org/x/hep/xrootd/xrdproto/auth/ztn"
+)
+
+// sessionInfoHandler records the information about the sessions it handles.
+type sessionInfoHandler struct {
+	xrootd.Handler
+
+	mu    sync.Mutex
+	infos []xrootd.SessionInfo
+}
+
+func (h *sessionInfoHandler) SetSessionInfo(sessionID [16]byte, info xrootd.SessionInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.infos = append(h.infos, info)
+	h.Handler.SetSessionInfo(sessionID, info)
+}
+
+func (h *sessionInfoHandler) last() xrootd.SessionInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.infos) == 0 {
+		return xrootd.SessionInfo{}
+	}
+	return h.infos[len(h.infos)-1]
+}
+
+func createAuthServer(t *testing.T, h xrootd.Handler, cfg *tls.Config, opts ...xrootd.ServerOption) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	srv := xrootd.NewServer(h, nil, opts...)
+	if cfg != nil {
+		srv = xrootd.NewTLSServer(h, nil, cfg, opts...)
+	}
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != xrootd.ErrServerClosed {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = srv.Shutdown(context.Background())
+	})
+
+	return listener.Addr().String()
+}
+
+func TestServerAuth(t *testing.T) {
+	key, err := sss.NewKey("test", "gopher", "users")
+	if err != nil {
+		t.Fatalf("could not create key: %v", err)
+	}
+	other, err := sss.NewKey("other", "gopher", "users")
+	if err != nil {
+		t.Fatalf("could not create key: %v", err)
+	}
+
+	const token = "secret-token"
+	verifiers := []xrootd.ServerOption{
+		xrootd.WithVerifier(&sss.Verifier{Keytab: sss.Keytab{key}}),
+		xrootd.WithVerifier(&ztn.Verifier{
+			Validate: func(tok string) (string, error) {
+				if tok != token {
+					return "", fmt.Errorf("invalid token")
+				}
+				return "gopher-ztn", nil
+			},
+		}),
+	}
+
+	cert, pool := newTestCert(t)
+	var (
+		plain  = &sessionInfoHandler{Handler: xrootd.NewFSHandler(t.TempDir())}
+		secure = &sessionInfoHandler{Handler: xrootd.NewFSHandler(t.TempDir())}
+	)
+	addrs := map[*sessionInfoHandler]string{
+		plain:  createAuthServer(t, plain, nil, verifiers...),
+		secure: createAuthServer(t, secure, &tls.Config{Certificates: []tls.Certificate{cert}}, verifiers...),
+	}
+	withTLS := xrootd.WithTLS(&tls.Config{RootCAs: pool})
+
+	for _, tc := range []struct {
+		name string
+		srv  *sessionInfoHandler
+		opts []xrootd.Option
+		want string
+		info xrootd.SessionInfo
+	}{
+		{
+			name: "sss",
+			srv:  plain,
+			opts: []xrootd.Option{xrootd.WithAuth(&sss.Auth{Key: key})},
+			info: xrootd.SessionInfo{Provider: "sss", Identity: "gopher"},
+		},
+		{
+			name: "ztn",
+			srv:  secure,
+			opts: []xrootd.Option{withTLS, xrootd.WithAuth(&ztn.Auth{Token: token})},
+			info: xrootd.SessionInfo{TLS: true, Provider: "ztn", Identity: "gopher-ztn"},
+		},
+		{
+			name: "ztn-no-tls",
+			srv:  plain,
+			opts: []xrootd.Option{xrootd.WithAuth(&ztn.Auth{Token: token})},
+			want: "connection is not TLS-encrypted",
+		},
+		{
+			name: "sss-unknown-key",
+			srv:  plain,
+			opts: []xrootd.Option{xrootd.WithAuth(&sss.Auth{Key: other})},
+			want: "unknown key",
+		},
+		{
+			name: "ztn-invalid-token",
+			srv:  secure,
+			opts: []xrootd.Option{withTLS, xrootd.WithAuth(&ztn.Auth{Token: "bad-token"})},
+			want: "invalid token",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			cli, err := xrootd.NewClient(ctx, addrs[tc.srv], "gopher", tc.opts...)
+			if tc.want != "" {
+				if err == nil {
+					cli.Close()
+					t.Fatalf("expected an error")
+				}
+				if !strings.Contains(err.Error(), tc.want) {
+					t.Fatalf("invalid error:\ngot = %v\nwant= %v", err, tc.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not create client: %v", err)
+			}
+			defer cli.Close()
+
+			_, err = cli.FS().Dirlist(ctx, "/")
+			if err != nil {
+				t.Fatalf("could not list directory: %v", err)
+			}
+
+			got := tc.srv.last()
+			if got.LocalAddr != addrs[tc.srv] || got.RemoteAddr == "" {
+				t.Fatalf("invalid session addresses: local=%q, remote=%q", got.LocalAddr, got.RemoteAddr)
+			}
+			got.LocalAddr, got.RemoteAddr = "", ""
+			if want := tc.info; got != want {
+				t.Fatalf("invalid session info:\ngot = %+v\nwant= %+v", got, want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ package xrootd // import "go-hep.org/x/hep/xrootd"
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"sync"
@@ -30,6 +31,8 @@ type Client struct {
 	sessions         map[string]*cliSession
 
 	maxRedirections int
+
+	tlsConfig *tls.Config // tlsConfig is the TLS configuration of the connections, if any.
 }
 
 // Option configures an XRootD client.
@@ -38,12 +41,31 @@ type Option func(*Client) error
 // WithAuth adds an authentication mechanism to the XRootD client.
 // If an authentication mechanism was already registered for that provider,
 // it will be silently replaced.
+// A nil a, e.g. sss.Default when no keytab could be found, is ignored.
 func WithAuth(a auth.Auther) Option {
 	return func(client *Client) error {
+		if a == nil {
+			return nil
+		}
 		return client.addAuth(a)
 	}
 }
 
+// WithTLS configures the XRootD client to use TLS-encrypted connections.
+// The connections are switched to TLS right after the protocol request,
+// before the login, and fail if the server does not support TLS.
+// If cfg is nil, a default configuration is used.
+// If cfg.ServerName is empty, the host name of the server is used.
+func WithTLS(cfg *tls.Config) Option {
+	return func(client *Client) error {
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		client.tlsConfig = cfg
+		return nil
+	}
+}
+
 func (client *Client) addAuth(auth auth.Auther) error {
 	client.auths[auth.Provider()] = auth
 	return nil
@@ -63,6 +85,12 @@ func (client *Client) initSecurityProviders() {
 // When the context expires, a response handling is stopped, however, it is
 // necessary to call Cancel to correctly free resources.
 func NewClient(ctx context.Context, address string, username string, opts ...Option) (*Client, error) {
+	return newClient(ctx, address, username, "", opts...)
+}
+
+// newClient creates a new xrootd client that connects to the given address
+// using username and token, sent as part of the login request.
+func newClient(ctx context.Context, address, username, token string, opts ...Option) (*Client, error) {
 	ctx, cancel := context.WithCancel(ctx)
 
 	client := &Client{
@@ -85,7 +113,7 @@ func NewClient(ctx context.Context, address string, username string, opts ...Opt
 		}
 	}
 
-	_, err := client.getSession(ctx, address, "")
+	_, err := client.getSession(ctx, address, token)
 	if err != nil {
 		client.Close()
 		return nil, err
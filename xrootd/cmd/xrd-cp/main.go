@@ -2,8 +2,11 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Command xrd-cp copies files and directories from a remote xrootd server
-// to local storage.
+// Command xrd-cp copies files and directories from, to and between xrootd servers.
+//
+// Remote to remote copies are performed with third-party copies (TPC),
+// where the destination server reads the file directly from the source server,
+// unless TPC is disabled or not supported by the servers.
 //
 // Usage:
 //
@@ -13,34 +16,51 @@
 //
 //	$> xrd-cp root://server.example.com/some/file1.txt .
 //	$> xrd-cp root://gopher@server.example.com/some/file1.txt .
+//	$> xrd-cp roots://server.example.com/some/file1.txt .
 //	$> xrd-cp root://server.example.com/some/file1.txt foo.txt
 //	$> xrd-cp root://server.example.com/some/file1.txt - > foo.txt
 //	$> xrd-cp -r root://server.example.com/some/dir .
 //	$> xrd-cp -r root://server.example.com/some/dir outdir
+//	$> xrd-cp foo.txt root://server.example.com/some/dir/
+//	$> xrd-cp -r -parallel=8 outdir root://server.example.com/some/dir
+//	$> xrd-cp root://server1.example.com/some/file1.txt root://server2.example.com/other/file1.txt
+//	$> xrd-cp -cksum=adler32 root://server.example.com/some/file1.txt .
 //
 // Options:
 //
+//	-cksum string
+//	  	verify the copies with the provided checksum type (adler32, crc32c or md5)
+//	-parallel int
+//	  	number of files copied in parallel (default 4)
 //	-r	copy directories recursively
+//	-tpc string
+//	  	third-party copy mode for remote to remote copies (first, only or none) (default "first")
 //	-v	enable verbose mode
 package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	stdpath "path"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
 
 	"go-hep.org/x/hep/xrootd"
 	"go-hep.org/x/hep/xrootd/xrdfs"
 	"go-hep.org/x/hep/xrootd/xrdio"
+	"go-hep.org/x/hep/xrootd/xrdproto"
 )
 
 func init() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, `xrd-cp copies files and directories from a remote xrootd server to local storage.
+		fmt.Fprintf(os.Stderr, `xrd-cp copies files and directories from, to and between xrootd servers.
 
 Usage:
 
@@ -50,10 +70,15 @@ Example:
 
  $> xrd-cp root://server.example.com/some/file1.txt .
  $> xrd-cp root://gopher@server.example.com/some/file1.txt .
+ $> xrd-cp roots://server.example.com/some/file1.txt .
  $> xrd-cp root://server.example.com/some/file1.txt foo.txt
  $> xrd-cp root://server.example.com/some/file1.txt - > foo.txt
  $> xrd-cp -r root://server.example.com/some/dir .
  $> xrd-cp -r root://server.example.com/some/dir outdir
+ $> xrd-cp foo.txt root://server.example.com/some/dir/
+ $> xrd-cp -r -parallel=8 outdir root://server.example.com/some/dir
+ $> xrd-cp root://server1.example.com/some/file1.txt root://server2.example.com/other/file1.txt
+ $> xrd-cp -cksum=adler32 root://server.example.com/some/file1.txt .
 
 Options:
 `)
@@ -61,6 +86,15 @@ Options:
 	}
 }
 
+// options configure the copies.
+type options struct {
+	recursive bool   // recursive enables the copy of directories.
+	verbose   bool   // verbose enables the verbose mode.
+	parallel  int    // parallel is the number of files copied in parallel.
+	cksum     string // cksum is the checksum type used to verify the copies, if any.
+	tpc       string // tpc is the third-party copy mode: first, only or none.
+}
+
 func main() {
 	log.SetPrefix("xrd-cp: ")
 	log.SetFlags(0)
@@ -68,10 +102,21 @@ func main() {
 	var (
 		recFlag     = flag.Bool("r", false, "copy directories recursively")
 		verboseFlag = flag.Bool("v", false, "enable verbose mode")
+		parFlag     = flag.Int("parallel", 4, "number of files copied in parallel")
+		cksumFlag   = flag.String("cksum", "", "verify the copies with the provided checksum type (adler32, crc32c or md5)")
+		tpcFlag     = flag.String("tpc", "first", "third-party copy mode for remote to remote copies (first, only or none)")
 	)
 
 	flag.Parse()
 
+	opts := options{
+		recursive: *recFlag,
+		verbose:   *verboseFlag,
+		parallel:  *parFlag,
+		cksum:     *cksumFlag,
+		tpc:       *tpcFlag,
+	}
+
 	switch n := flag.NArg(); n {
 	case 0:
 		flag.Usage()
@@ -80,14 +125,14 @@ func main() {
 		flag.Usage()
 		log.Fatalf("missing destination file operand after %q", flag.Arg(0))
 	case 2:
-		err := xrdcopy(flag.Arg(1), flag.Arg(0), *recFlag, *verboseFlag)
+		err := xrdcopy(flag.Arg(1), flag.Arg(0), opts)
 		if err != nil {
 			log.Fatalf("could not copy %q to %q: %v", flag.Arg(0), flag.Arg(1), err)
 		}
 	default:
 		dst := flag.Arg(flag.NArg() - 1)
 		for _, src := range flag.Args()[:flag.NArg()-1] {
-			err := xrdcopy(dst, src, *recFlag, *verboseFlag)
+			err := xrdcopy(dst, src, opts)
 			if err != nil {
 				log.Fatalf("could not copy %q to %q: %v", src, dst, err)
 			}
@@ -95,159 +140,387 @@ func main() {
 	}
 }
 
-func xrdcopy(dst, srcPath string, recursive, verbose bool) error {
-	cli, src, err := xrdremote(srcPath)
+func xrdcopy(dstPath, srcPath string, opts options) error {
+	switch opts.tpc {
+	case "first", "only", "none":
+	default:
+		return fmt.Errorf("invalid third-party copy mode %q", opts.tpc)
+	}
+	if opts.cksum != "" {
+		if _, err := xrdfs.NewChecksumHash(opts.cksum); err != nil {
+			return err
+		}
+	}
+
+	src, err := newLocation(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.close()
+
+	dst, err := newLocation(dstPath)
 	if err != nil {
 		return err
 	}
-	defer cli.Close()
+	defer dst.close()
 
 	ctx := context.Background()
 
-	fs := cli.FS()
-	var jobs jobs
-	var addDir func(root, src string) error
+	js := jobs{opts: opts}
+	var addDir func(root, name string) error
 
-	addDir = func(root, src string) error {
-		fi, err := fs.Stat(ctx, src)
+	addDir = func(root, name string) error {
+		fi, err := src.stat(ctx, name)
 		if err != nil {
-			return fmt.Errorf("could not stat remote src: %w", err)
+			return fmt.Errorf("could not stat src: %w", err)
 		}
 		switch {
 		case fi.IsDir():
-			if !recursive {
-				return fmt.Errorf("xrd-cp: -r not specified; omitting directory %q", src)
+			if !opts.recursive {
+				return fmt.Errorf("xrd-cp: -r not specified; omitting directory %q", name)
 			}
-			dst := stdpath.Join(root, stdpath.Base(src))
-			err = os.MkdirAll(dst, 0755)
+			dir := stdpath.Join(root, stdpath.Base(name))
+			err = dst.mkdirAll(ctx, dir)
 			if err != nil {
 				return fmt.Errorf("could not create output directory: %w", err)
 			}
 
-			ents, err := fs.Dirlist(ctx, src)
+			ents, err := src.readDir(ctx, name)
 			if err != nil {
 				return fmt.Errorf("could not list directory: %w", err)
 			}
 			for _, e := range ents {
-				err = addDir(dst, stdpath.Join(src, e.Name()))
+				err = addDir(dir, stdpath.Join(name, e))
 				if err != nil {
 					return err
 				}
 			}
 		default:
-			jobs.add(job{
-				fs:  fs,
-				src: src,
-				dst: stdpath.Join(root, stdpath.Base(src)),
+			js.add(job{
+				src:     src,
+				dst:     dst,
+				srcPath: name,
+				dstPath: stdpath.Join(root, stdpath.Base(name)),
 			})
 		}
 		return nil
 	}
 
-	fiSrc, err := fs.Stat(ctx, src)
+	fiSrc, err := src.stat(ctx, src.path)
 	if err != nil {
-		return fmt.Errorf("could not stat remote src: %w", err)
+		return fmt.Errorf("could not stat src: %w", err)
 	}
 
-	fiDst, errDst := os.Stat(dst)
+	fiDst, errDst := dst.stat(ctx, dst.path)
 	switch {
 	case fiSrc.IsDir():
+		if !opts.recursive {
+			return fmt.Errorf("xrd-cp: -r not specified; omitting directory %q", src.path)
+		}
 		switch {
-		case errDst != nil && os.IsNotExist(errDst):
-			err = os.MkdirAll(dst, 0755)
+		case errDst != nil && errors.Is(errDst, os.ErrNotExist):
+			err = dst.mkdirAll(ctx, dst.path)
 			if err != nil {
 				return fmt.Errorf("could not create output directory: %w", err)
 			}
-			ents, err := fs.Dirlist(ctx, src)
+			ents, err := src.readDir(ctx, src.path)
 			if err != nil {
 				return fmt.Errorf("could not list directory: %w", err)
 			}
 			for _, e := range ents {
-				err = addDir(dst, stdpath.Join(src, e.Name()))
+				err = addDir(dst.path, stdpath.Join(src.path, e))
 				if err != nil {
 					return err
 				}
 			}
 
 		case errDst != nil:
-			return fmt.Errorf("could not stat local dst: %w", errDst)
+			return fmt.Errorf("could not stat dst: %w", errDst)
 		case fiDst.IsDir():
-			err = addDir(dst, src)
+			err = addDir(dst.path, src.path)
 			if err != nil {
 				return err
 			}
+		default:
+			return fmt.Errorf("could not copy directory %q to file %q", src.path, dst.path)
 		}
 
 	default:
+		name := dst.path
 		switch {
-		case errDst != nil && os.IsNotExist(errDst):
+		case dst.cli == nil && (name == "-" || name == ""):
+			// ok... dst is stdout.
+		case errDst != nil && errors.Is(errDst, os.ErrNotExist):
 			// ok... dst will be the output file.
+			if strings.HasSuffix(name, "/") {
+				name = stdpath.Join(name, stdpath.Base(src.path))
+			}
 		case errDst != nil:
-			return fmt.Errorf("could not stat local dst: %w", errDst)
+			return fmt.Errorf("could not stat dst: %w", errDst)
 		case fiDst.IsDir():
-			dst = stdpath.Join(dst, stdpath.Base(src))
+			name = stdpath.Join(name, stdpath.Base(src.path))
 		}
 
-		jobs.add(job{
-			fs:  fs,
-			src: src,
-			dst: dst,
+		js.add(job{
+			src:     src,
+			dst:     dst,
+			srcPath: src.path,
+			dstPath: name,
 		})
 	}
 
-	n, err := jobs.run(ctx)
-	if verbose {
+	n, err := js.run(ctx)
+	if opts.verbose {
 		log.Printf("transferred %d bytes", n)
 	}
 	return err
 }
 
+// location is a local or remote filesystem.
+type location struct {
+	cli  *xrootd.Client // cli is the client to the remote server, nil for the local filesystem.
+	path string         // path is the path of the file or directory to copy from or to.
+}
+
+// newLocation returns the location of the named file, either a local path or a xrootd URL.
+func newLocation(name string) (*location, error) {
+	if !strings.Contains(name, "://") {
+		return &location{path: name}, nil
+	}
+
+	cli, path, err := xrdremote(name)
+	if err != nil {
+		return nil, err
+	}
+	return &location{cli: cli, path: path}, nil
+}
+
+func (loc *location) close() {
+	if loc.cli != nil {
+		loc.cli.Close()
+	}
+}
+
+func (loc *location) stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if loc.cli == nil {
+		return os.Stat(name)
+	}
+	fi, err := loc.cli.FS().Stat(ctx, name)
+	var serr xrdproto.ServerError
+	if errors.As(err, &serr) && serr.Code == xrdproto.NotFound {
+		return nil, fmt.Errorf("%w: %w", os.ErrNotExist, err)
+	}
+	return fi, err
+}
+
+func (loc *location) readDir(ctx context.Context, name string) ([]string, error) {
+	var names []string
+	if loc.cli == nil {
+		ents, err := os.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range ents {
+			names = append(names, e.Name())
+		}
+		return names, nil
+	}
+
+	ents, err := loc.cli.FS().Dirlist(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range ents {
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
+
+func (loc *location) mkdirAll(ctx context.Context, name string) error {
+	if loc.cli == nil {
+		return os.MkdirAll(name, 0755)
+	}
+	return loc.cli.FS().MkdirAll(ctx, name,
+		xrdfs.OpenModeOwnerRead|xrdfs.OpenModeOwnerWrite|xrdfs.OpenModeOwnerExecute|
+			xrdfs.OpenModeGroupRead|xrdfs.OpenModeGroupExecute|
+			xrdfs.OpenModeOtherRead|xrdfs.OpenModeOtherExecute,
+	)
+}
+
+func (loc *location) open(ctx context.Context, name string) (io.ReadCloser, error) {
+	if loc.cli == nil {
+		return os.Open(name)
+	}
+	return xrdio.OpenFrom(loc.cli.FS(), name)
+}
+
+func (loc *location) create(ctx context.Context, name string) (io.WriteCloser, error) {
+	if loc.cli == nil {
+		switch name {
+		case "-", "":
+			return nopCloser{os.Stdout}, nil
+		}
+		return os.Create(name)
+	}
+
+	if dir := stdpath.Dir(name); dir != "." && dir != "/" {
+		err := loc.mkdirAll(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := loc.cli.FS().Open(ctx, name,
+		xrdfs.OpenModeOwnerRead|xrdfs.OpenModeOwnerWrite|xrdfs.OpenModeGroupRead|xrdfs.OpenModeOtherRead,
+		xrdfs.OpenOptionsDelete|xrdfs.OpenOptionsOpenUpdate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &writer{ctx: ctx, f: f}, nil
+}
+
+func (loc *location) checksum(ctx context.Context, name, typ string) (xrdfs.Checksum, error) {
+	if loc.cli != nil {
+		return loc.cli.FS().Checksum(ctx, name, typ)
+	}
+
+	hash, err := xrdfs.NewChecksumHash(typ)
+	if err != nil {
+		return xrdfs.Checksum{}, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return xrdfs.Checksum{}, err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return xrdfs.Checksum{}, err
+	}
+	return xrdfs.ChecksumFrom(typ, hash.Sum(nil)), nil
+}
+
+// writer writes sequentially to a remote file.
+type writer struct {
+	ctx context.Context
+	f   xrdfs.File
+	pos int64
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	err := w.f.WriteAtContext(w.ctx, p, w.pos)
+	if err != nil {
+		return 0, err
+	}
+	w.pos += int64(len(p))
+	return len(p), nil
+}
+
+func (w *writer) Close() error {
+	return w.f.Close(w.ctx)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
 func xrdremote(name string) (client *xrootd.Client, path string, err error) {
 	url, err := xrdio.Parse(name)
 	if err != nil {
 		return nil, "", fmt.Errorf("could not parse %q: %w", name, err)
 	}
 
+	var opts []xrootd.Option
+	if url.TLS {
+		opts = append(opts, xrootd.WithTLS(nil))
+	}
+
 	path = url.Path
-	client, err = xrootd.NewClient(context.Background(), url.Addr, url.User)
+	client, err = xrootd.NewClient(context.Background(), url.Addr, url.User, opts...)
 	return client, path, err
 }
 
 type job struct {
-	fs  xrdfs.FileSystem
-	src string
-	dst string
+	src     *location
+	dst     *location
+	srcPath string
+	dstPath string
 }
 
-func (j job) run(ctx context.Context) (int, error) {
+func (j job) run(ctx context.Context, opts options) (int, error) {
+	if j.dst.cli == nil && j.dstPath == "." {
+		j.dstPath = stdpath.Base(j.srcPath)
+	}
+
 	var (
-		o   io.WriteCloser
+		n   int
 		err error
 	)
-	switch j.dst {
-	case "-", "":
-		o = os.Stdout
-	case ".":
-		j.dst = stdpath.Base(j.src)
-		fallthrough
+	switch {
+	case j.src.cli != nil && j.dst.cli != nil && opts.tpc != "none":
+		n, err = j.tpc(ctx)
+		if err != nil && opts.tpc == "first" {
+			if opts.verbose {
+				log.Printf("third-party copy of %q failed, falling back to streaming: %v", j.srcPath, err)
+			}
+			n, err = j.stream(ctx)
+		}
 	default:
-		o, err = os.Create(j.dst)
+		n, err = j.stream(ctx)
+	}
+	if err != nil {
+		return n, err
+	}
+
+	if opts.cksum != "" && !(j.dst.cli == nil && (j.dstPath == "-" || j.dstPath == "")) {
+		err = j.verify(ctx, opts.cksum)
 		if err != nil {
-			return 0, fmt.Errorf("could not create output file: %w", err)
+			return n, err
 		}
+		if opts.verbose {
+			log.Printf("verified %s checksum of %q", opts.cksum, j.dstPath)
+		}
+	}
+
+	return n, nil
+}
+
+// tpc performs a third-party copy between the source and destination servers.
+func (j job) tpc(ctx context.Context) (int, error) {
+	fi, err := j.src.stat(ctx, j.srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("could not stat src: %w", err)
+	}
+
+	err = xrootd.ThirdPartyCopy(ctx, j.dst.cli, j.dstPath, j.src.cli, j.srcPath)
+	if err != nil {
+		return 0, err
 	}
-	defer o.Close()
+	return int(fi.Size()), nil
+}
 
-	f, err := xrdio.OpenFrom(j.fs, j.src)
+// stream copies the source file to the destination file through this process.
+func (j job) stream(ctx context.Context) (int, error) {
+	f, err := j.src.open(ctx, j.srcPath)
 	if err != nil {
 		return 0, err
 	}
 	defer f.Close()
 
+	o, err := j.dst.create(ctx, j.dstPath)
+	if err != nil {
+		return 0, fmt.Errorf("could not create output file: %w", err)
+	}
+
 	// TODO(sbinet): make buffer a field of job to reduce memory pressure.
 	// TODO(sbinet): use clever heuristics for buffer size?
 	n, err := io.CopyBuffer(o, f, make([]byte, 16*1024*1024))
 	if err != nil {
+		_ = o.Close()
 		return int(n), fmt.Errorf("could not copy to output file: %w", err)
 	}
 
@@ -259,7 +532,24 @@ func (j job) run(ctx context.Context) (int, error) {
 	return int(n), nil
 }
 
+// verify verifies the checksums of the source and destination files match.
+func (j job) verify(ctx context.Context, typ string) error {
+	want, err := j.src.checksum(ctx, j.srcPath, typ)
+	if err != nil {
+		return fmt.Errorf("could not compute checksum of %q: %w", j.srcPath, err)
+	}
+	got, err := j.dst.checksum(ctx, j.dstPath, typ)
+	if err != nil {
+		return fmt.Errorf("could not compute checksum of %q: %w", j.dstPath, err)
+	}
+	if !strings.EqualFold(got.Value, want.Value) {
+		return fmt.Errorf("checksum mismatch for %q: got=%v, want=%v", j.dstPath, got, want)
+	}
+	return nil
+}
+
 type jobs struct {
+	opts  options
 	slice []job
 }
 
@@ -268,13 +558,18 @@ func (js *jobs) add(j job) {
 }
 
 func (js *jobs) run(ctx context.Context) (int, error) {
-	var n int
+	var (
+		n   atomic.Int64
+		grp errgroup.Group
+	)
+	grp.SetLimit(max(js.opts.parallel, 1))
 	for _, j := range js.slice {
-		nn, err := j.run(ctx)
-		n += nn
-		if err != nil {
-			return n, err
-		}
+		grp.Go(func() error {
+			nn, err := j.run(ctx, js.opts)
+			n.Add(int64(nn))
+			return err
+		})
 	}
-	return n, nil
+	err := grp.Wait()
+	return int(n.Load()), err
 }
@@ -5,9 +5,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"go-hep.org/x/hep/xrootd"
 )
 
 func TestXrdCp(t *testing.T) {
@@ -20,12 +26,7 @@ func TestXrdCp(t *testing.T) {
 	dst := filepath.Join(dir, "chain.1.root")
 	src := "root://ccxrootdgotest.in2p3.fr:9001/tmp/rootio/testdata/chain.1.root"
 
-	const (
-		recursive = false
-		verbose   = true
-	)
-
-	err = xrdcopy(dst, src, recursive, verbose)
+	err = xrdcopy(dst, src, options{verbose: true, parallel: 1, tpc: "first"})
 	if err != nil {
 		t.Fatalf("could not copy remote file: %v", err)
 	}
@@ -52,17 +53,156 @@ func benchmarkXrdCp(b *testing.B, src string) {
 
 	dst := filepath.Join(dir, filepath.Base(src))
 
-	const (
-		recursive = false
-		verbose   = false
-	)
-
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		os.RemoveAll(dst)
-		err = xrdcopy(dst, src, recursive, verbose)
+		err = xrdcopy(dst, src, options{parallel: 1, tpc: "first"})
 		if err != nil {
 			b.Fatalf("could not copy remote file: %v", err)
 		}
 	}
 }
+
+func TestXrdCpLocal(t *testing.T) {
+	var (
+		srcDir = t.TempDir()
+		dstDir = t.TempDir()
+		locDir = t.TempDir()
+	)
+
+	files := map[string]string{
+		"file1.txt":         "data from file1",
+		"dir/file2.txt":     "data from file2",
+		"dir/sub/file3.txt": "data from file3",
+	}
+	for name, data := range files {
+		name = filepath.Join(srcDir, name)
+		err := os.MkdirAll(filepath.Dir(name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(name, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srcAddr := serve(t, srcDir)
+	dstAddr := serve(t, dstDir)
+
+	opts := options{recursive: true, parallel: 2, cksum: "adler32", tpc: "only"}
+
+	// remote to local.
+	err := xrdcopy(locDir, "root://"+srcAddr+"/dir", opts)
+	if err != nil {
+		t.Fatalf("could not download directory: %v", err)
+	}
+	checkFiles(t, filepath.Join(locDir, "dir"), files, "dir/")
+
+	// local to remote.
+	err = xrdcopy("root://"+dstAddr+"/up/", filepath.Join(srcDir, "file1.txt"), opts)
+	if err != nil {
+		t.Fatalf("could not upload file: %v", err)
+	}
+	err = xrdcopy("root://"+dstAddr+"/up", filepath.Join(locDir, "dir"), opts)
+	if err != nil {
+		t.Fatalf("could not upload directory: %v", err)
+	}
+	checkFiles(t, filepath.Join(dstDir, "up"), files, "")
+
+	// remote to remote.
+	err = xrdcopy("root://"+dstAddr+"/tpc", "root://"+srcAddr+"/dir", opts)
+	if err != nil {
+		t.Fatalf("could not perform third-party copy: %v", err)
+	}
+	checkFiles(t, filepath.Join(dstDir, "tpc"), files, "dir/")
+
+	opts.tpc = "none"
+	err = xrdcopy("root://"+dstAddr+"/stream.txt", "root://"+srcAddr+"/file1.txt", opts)
+	if err != nil {
+		t.Fatalf("could not stream remote file: %v", err)
+	}
+	checkFiles(t, dstDir, map[string]string{"stream.txt": files["file1.txt"]}, "")
+
+	opts.cksum = "sha1"
+	err = xrdcopy(locDir, "root://"+srcAddr+"/file1.txt", opts)
+	if err == nil {
+		t.Fatalf("expected an error for an unsupported checksum type")
+	}
+}
+
+func serve(t *testing.T, dir string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	srv := xrootd.NewServer(xrootd.NewFSHandler(dir, xrootd.WithTPCSources("127.0.0.1")), func(err error) { t.Error(err) })
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != xrootd.ErrServerClosed {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = srv.Shutdown(context.Background())
+	})
+
+	return listener.Addr().String()
+}
+
+// checkFiles checks the files with the provided prefix were copied under dir.
+func checkFiles(t *testing.T, dir string, files map[string]string, prefix string) {
+	t.Helper()
+
+	for name, want := range files {
+		name, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("could not read copied file: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("invalid content of %q: got=%q, want=%q", name, got, want)
+		}
+	}
+}
+
+func TestLocationStat(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("data"), 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	loc, err := newLocation("root://" + serve(t, dir) + "//")
+	if err != nil {
+		t.Fatalf("could not create location: %v", err)
+	}
+	defer loc.cli.Close()
+
+	for _, tc := range []struct {
+		name     string
+		err      bool
+		notExist bool
+	}{
+		{name: "file.txt"},
+		{name: "missing.txt", err: true, notExist: true},
+		// an invalid name is reported by the server as an I/O error.
+		{name: "bad\x00name.txt", err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loc.stat(ctx, tc.name)
+			if got, want := err != nil, tc.err; got != want {
+				t.Fatalf("invalid error: %v", err)
+			}
+			if got, want := errors.Is(err, os.ErrNotExist), tc.notExist; got != want {
+				t.Fatalf("invalid not-exist error: got=%v, want=%v (err=%v)", got, want, err)
+			}
+		})
+	}
+}
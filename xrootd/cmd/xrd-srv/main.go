@@ -7,14 +7,21 @@ package main // import "go-hep.org/x/hep/xrootd/cmd/xrd-srv"
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 
 	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdhttp"
+	"go-hep.org/x/hep/xrootd/xrdproto/auth/sss"
+	"go-hep.org/x/hep/xrootd/xrdproto/auth/ztn"
 )
 
 func init() {
@@ -24,11 +31,45 @@ func init() {
 Usage:
 
  $> xrd-srv [OPTIONS] <base-dir>
+ $> xrd-srv -redirector [OPTIONS]
 
 Example:
 
  $> xrd-srv /tmp
  $> xrd-srv -addr=0.0.0.0:1094 /tmp
+ $> xrd-srv -sss-keytab=/etc/xrootd/sss.keytab /tmp
+
+ ## require TLS-encrypted connections.
+ $> xrd-srv -tls-cert=cert.pem -tls-key=key.pem /tmp
+
+ ## authenticate clients with bearer tokens (requires TLS).
+ $> xrd-srv -tls-cert=cert.pem -tls-key=key.pem -ztn-tokens=./tokens.txt /tmp
+
+ ## also serve the data over HTTP(S)/WebDAV.
+ $> xrd-srv -http=0.0.0.0:8080 /tmp
+ $> xrd-srv -http=0.0.0.0:8443 -http-cert=cert.pem -http-key=key.pem /tmp
+
+ ## also serve the data over HTTPS, requiring the ztn tokens as bearer tokens.
+ ## (HTTP requests are not authenticated with sss: -http is refused with -sss-keytab.)
+ $> xrd-srv -tls-cert=cert.pem -tls-key=key.pem -ztn-tokens=./tokens.txt \
+            -http=0.0.0.0:8443 -http-cert=cert.pem -http-key=key.pem /tmp
+
+ ## run a redirector and two data servers registering with it,
+ ## using a shared secret (requires TLS).
+ $> xrd-srv -addr=0.0.0.0:1094 -tls-cert=cert.pem -tls-key=key.pem -redirector -register-secret=./secret.txt
+ $> xrd-srv -addr=0.0.0.0:1095 -tls-cert=cert.pem -tls-key=key.pem -tls-ca=ca.pem \
+            -register=localhost:1094 -register-secret=./secret.txt /data1
+ $> xrd-srv -addr=0.0.0.0:1096 -tls-cert=cert.pem -tls-key=key.pem -tls-ca=ca.pem \
+            -register=localhost:1094 -register-secret=./secret.txt /data2
+
+ ## run a redirector only accepting registrations from a set of hosts.
+ $> xrd-srv -redirector -register-allow=host1,host2:1094
+
+ ## accept third-party copies reading files from the servers of a set of hosts.
+ $> xrd-srv -tpc-sources=host1,host2:1094 /data
+
+ ## run a redirector for a static set of data servers.
+ $> xrd-srv -redirector -servers=host1:1094,host2:1094
 
 Options:
 `)
@@ -40,25 +81,142 @@ func main() {
 	log.SetPrefix("xrd-srv: ")
 	log.SetFlags(0)
 
-	addr := flag.String("addr", "0.0.0.0:1094", "listen to the provided address")
+	var (
+		addr   = flag.String("addr", "0.0.0.0:1094", "listen to the provided address")
+		keytab = flag.String("sss-keytab", "", "path to a sss keytab used to authenticate clients")
+		tokens = flag.String("ztn-tokens", "", "path to a file listing the bearer tokens (one per line) accepted to authenticate clients (requires TLS)")
+		tcert  = flag.String("tls-cert", "", "path to the TLS certificate of the server, requiring TLS-encrypted connections")
+		tkey   = flag.String("tls-key", "", "path to the TLS key of the server")
+		tca    = flag.String("tls-ca", "", "path to the PEM certificates used to verify the redirector to register with (default: system roots)")
+		redir  = flag.Bool("redirector", false, "run as a redirector for a set of data servers")
+		srvs   = flag.String("servers", "", "comma-separated list of data servers of the redirector")
+		reg    = flag.String("register", "", "address of a redirector to register with")
+		secret = flag.String("register-secret", "", "path to a file holding the secret shared by a redirector and its data servers (requires TLS)")
+		allow  = flag.String("register-allow", "", "comma-separated list of hosts or addresses of the data servers allowed to register with the redirector")
+		tpcSrc = flag.String("tpc-sources", "", "comma-separated list of hosts or addresses of the servers third-party copies may read files from")
+		haddr  = flag.String("http", "", "listen to the provided address for HTTP/WebDAV requests")
+		hcert  = flag.String("http-cert", "", "path to the TLS certificate of the HTTPS server")
+		hkey   = flag.String("http-key", "", "path to the TLS key of the HTTPS server")
+	)
 
 	flag.Parse()
 
-	if flag.NArg() != 1 {
-		flag.Usage()
-		log.Fatalf("missing base dir operand")
+	var tlsConfig *tls.Config
+	switch {
+	case *tcert != "" || *tkey != "":
+		cert, err := tls.LoadX509KeyPair(*tcert, *tkey)
+		if err != nil {
+			log.Fatalf("could not load TLS certificate: %+v", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	case *tca != "":
+		log.Fatalf("-tls-ca requires -tls-cert and -tls-key")
+	}
+	if tlsConfig == nil {
+		// bearer tokens and registration secrets are sent as is.
+		switch {
+		case *tokens != "":
+			log.Fatalf("ztn tokens require TLS: -ztn-tokens needs -tls-cert and -tls-key")
+		case *secret != "":
+			log.Fatalf("registration secrets require TLS: -register-secret needs -tls-cert and -tls-key")
+		}
 	}
 
-	baseDir := flag.Arg(0)
+	var regSecret string
+	if *secret != "" {
+		raw, err := os.ReadFile(*secret)
+		if err != nil {
+			log.Fatalf("could not read registration secret: %+v", err)
+		}
+		regSecret = strings.TrimSpace(string(raw))
+		if regSecret == "" {
+			log.Fatalf("empty registration secret in %q", *secret)
+		}
+	}
+
+	var handler xrootd.Handler
+	switch {
+	case *redir:
+		if flag.NArg() != 0 {
+			flag.Usage()
+			log.Fatalf("unexpected base dir operand in redirector mode")
+		}
+		if *haddr != "" {
+			log.Fatalf("HTTP door not supported in redirector mode")
+		}
+		var ropts []xrootd.RedirectorOption
+		if regSecret != "" {
+			ropts = append(ropts, xrootd.WithRegistrationSecret(regSecret))
+		}
+		if *allow != "" {
+			ropts = append(ropts, xrootd.WithRegistrationAllowList(strings.Split(*allow, ",")...))
+		}
+		r := xrootd.NewRedirector(ropts...)
+		defer r.Disconnect()
+		for _, srv := range strings.Split(*srvs, ",") {
+			if srv == "" {
+				continue
+			}
+			if err := r.Register(srv); err != nil {
+				log.Fatalf("could not register data server: %+v", err)
+			}
+		}
+		handler = r
+	default:
+		if flag.NArg() != 1 {
+			flag.Usage()
+			log.Fatalf("missing base dir operand")
+		}
+		var hopts []xrootd.FSHandlerOption
+		if *tpcSrc != "" {
+			hopts = append(hopts, xrootd.WithTPCSources(strings.Split(*tpcSrc, ",")...))
+		}
+		handler = xrootd.NewFSHandler(flag.Arg(0), hopts...)
+	}
 
 	listener, err := net.Listen("tcp", *addr)
 	if err != nil {
 		log.Fatalf("could not listen on %q: %v", *addr, err)
 	}
 
-	srv := xrootd.NewServer(xrootd.NewFSHandler(baseDir), func(err error) {
+	var (
+		opts  []xrootd.ServerOption
+		hopts []xrdhttp.Option
+	)
+	if *haddr != "" {
+		switch {
+		case *keytab != "":
+			log.Fatalf("sss authentication is not supported over HTTP: -http can not be used with -sss-keytab")
+		case *tokens != "" && *hcert == "" && *hkey == "":
+			log.Fatalf("ztn tokens require HTTPS: -http needs -http-cert and -http-key when used with -ztn-tokens")
+		}
+	}
+	if *keytab != "" {
+		kt, err := sss.LoadKeytab(*keytab)
+		if err != nil {
+			log.Fatalf("could not load sss keytab: %+v", err)
+		}
+		opts = append(opts, xrootd.WithVerifier(&sss.Verifier{Keytab: kt}))
+	}
+	if *tokens != "" {
+		v, err := newTokenVerifier(*tokens)
+		if err != nil {
+			log.Fatalf("could not load ztn tokens: %+v", err)
+		}
+		opts = append(opts, xrootd.WithVerifier(v))
+		hopts = append(hopts, xrdhttp.WithBearerTokens(v.Validate))
+	}
+
+	errorHandler := func(err error) {
 		log.Printf("an error occured: %v", err)
-	})
+	}
+	var srv *xrootd.Server
+	switch tlsConfig {
+	case nil:
+		srv = xrootd.NewServer(handler, errorHandler, opts...)
+	default:
+		srv = xrootd.NewTLSServer(handler, errorHandler, tlsConfig, opts...)
+	}
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
@@ -70,9 +228,104 @@ func main() {
 		}
 	}()
 
+	if *haddr != "" {
+		hsrv := &http.Server{Addr: *haddr, Handler: xrdhttp.NewHandler(handler, hopts...)}
+		defer hsrv.Shutdown(context.Background())
+		go func() {
+			log.Printf("listening for HTTP requests on %v...", *haddr)
+			var err error
+			switch {
+			case *hcert != "" || *hkey != "":
+				err = hsrv.ListenAndServeTLS(*hcert, *hkey)
+			default:
+				err = hsrv.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatalf("could not serve HTTP: %v", err)
+			}
+		}()
+	}
+
+	if *reg != "" {
+		var copts []xrootd.Option
+		if tlsConfig != nil {
+			cfg, err := clientTLSConfig(*tca)
+			if err != nil {
+				log.Fatalf("could not load TLS CA certificates: %+v", err)
+			}
+			copts = append(copts, xrootd.WithTLS(cfg))
+		}
+		cli, err := xrootd.RegisterServer(context.Background(), *reg, advertisedAddr(listener.Addr()), regSecret, copts...)
+		if err != nil {
+			log.Fatalf("could not register with redirector %q: %+v", *reg, err)
+		}
+		defer cli.Close()
+	}
+
 	<-ch
 	err = srv.Shutdown(context.Background())
 	if err != nil {
 		log.Fatalf("could not shutdown: %v", err)
 	}
 }
+
+// advertisedAddr returns the address the data server listening on addr
+// registers with a redirector.
+func advertisedAddr(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		if name, err := os.Hostname(); err == nil {
+			host = name
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// clientTLSConfig returns the TLS configuration used to connect to a redirector,
+// verifying its certificate with the PEM certificates of the named file, or with
+// the system roots if fname is empty.
+func clientTLSConfig(fname string) (*tls.Config, error) {
+	if fname == "" {
+		return &tls.Config{}, nil
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("no certificate in %q", fname)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+// newTokenVerifier returns a ztn verifier accepting the bearer tokens listed in the named file.
+func newTokenVerifier(fname string) (*ztn.Verifier, error) {
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	valid := make(map[string]struct{})
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		valid[line] = struct{}{}
+	}
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("no token in %q", fname)
+	}
+
+	return &ztn.Verifier{
+		Validate: func(token string) (string, error) {
+			if _, ok := valid[token]; !ok {
+				return "", fmt.Errorf("invalid token")
+			}
+			return "ztn", nil
+		},
+	}, nil
+}
@@ -6,15 +6,22 @@ package xrootd // import "go-hep.org/x/hep/xrootd"
 
 import (
 	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/chmod"
 	"go-hep.org/x/hep/xrootd/xrdproto/dirlist"
 	"go-hep.org/x/hep/xrootd/xrdproto/handshake"
+	"go-hep.org/x/hep/xrootd/xrdproto/locate"
 	"go-hep.org/x/hep/xrootd/xrdproto/login"
 	"go-hep.org/x/hep/xrootd/xrdproto/mkdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/mv"
 	"go-hep.org/x/hep/xrootd/xrdproto/open"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgread"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgwrite"
 	"go-hep.org/x/hep/xrootd/xrdproto/ping"
+	"go-hep.org/x/hep/xrootd/xrdproto/prepare"
 	"go-hep.org/x/hep/xrootd/xrdproto/protocol"
+	"go-hep.org/x/hep/xrootd/xrdproto/query"
 	"go-hep.org/x/hep/xrootd/xrdproto/read"
+	"go-hep.org/x/hep/xrootd/xrdproto/readv"
 	"go-hep.org/x/hep/xrootd/xrdproto/rm"
 	"go-hep.org/x/hep/xrootd/xrdproto/rmdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/stat"
@@ -66,6 +73,9 @@ func (*defaultHandler) Handshake() (xrdproto.Marshaler, xrdproto.ResponseStatus)
 // CloseSession implements Handler.CloseSession.
 func (h *defaultHandler) CloseSession(sessionID [16]byte) error { return nil }
 
+// SetSessionInfo implements Handler.SetSessionInfo.
+func (h *defaultHandler) SetSessionInfo(sessionID [16]byte, info SessionInfo) {}
+
 // Open implements Handler.Open.
 func (h *defaultHandler) Open(sessionID [16]byte, request *open.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
 	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "Open request is not implemented"}
@@ -90,6 +100,48 @@ func (h *defaultHandler) Write(sessionID [16]byte, request *write.Request) (xrdp
 	return resp, xrdproto.Error
 }
 
+// ReadV implements Handler.ReadV.
+func (h *defaultHandler) ReadV(sessionID [16]byte, request *readv.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "ReadV request is not implemented"}
+	return resp, xrdproto.Error
+}
+
+// PgRead implements Handler.PgRead.
+func (h *defaultHandler) PgRead(sessionID [16]byte, request *pgread.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "PgRead request is not implemented"}
+	return resp, xrdproto.Error
+}
+
+// Locate implements Handler.Locate.
+func (h *defaultHandler) Locate(sessionID [16]byte, request *locate.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "Locate request is not implemented"}
+	return resp, xrdproto.Error
+}
+
+// Query implements Handler.Query.
+func (h *defaultHandler) Query(sessionID [16]byte, request *query.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "Query request is not implemented"}
+	return resp, xrdproto.Error
+}
+
+// Prepare implements Handler.Prepare.
+func (h *defaultHandler) Prepare(sessionID [16]byte, request *prepare.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "Prepare request is not implemented"}
+	return resp, xrdproto.Error
+}
+
+// Chmod implements Handler.Chmod.
+func (h *defaultHandler) Chmod(sessionID [16]byte, request *chmod.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "Chmod request is not implemented"}
+	return resp, xrdproto.Error
+}
+
+// PgWrite implements Handler.PgWrite.
+func (h *defaultHandler) PgWrite(sessionID [16]byte, request *pgwrite.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "PgWrite request is not implemented"}
+	return resp, xrdproto.Error
+}
+
 // Stat implements Handler.Stat.
 func (h *defaultHandler) Stat(sessionID [16]byte, request *stat.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
 	resp := xrdproto.ServerError{Code: xrdproto.InvalidRequest, Message: "Stat request is not implemented"}
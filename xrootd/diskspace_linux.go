@@ -0,0 +1,22 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package xrootd // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"syscall"
+)
+
+// diskSpace returns the total and free space, in bytes,
+// of the filesystem holding the named file.
+func diskSpace(name string) (total, free int64, err error) {
+	var st syscall.Statfs_t
+	err = syscall.Statfs(name, &st)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(st.Blocks) * st.Bsize, int64(st.Bavail) * st.Bsize, nil
+}
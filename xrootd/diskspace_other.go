@@ -0,0 +1,18 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package xrootd // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// diskSpace returns the total and free space, in bytes,
+// of the filesystem holding the named file.
+func diskSpace(name string) (total, free int64, err error) {
+	return 0, 0, fmt.Errorf("xrootd: disk space statistics are not supported on %s", runtime.GOOS)
+}
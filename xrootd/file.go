@@ -6,10 +6,14 @@ package xrootd // import "go-hep.org/x/hep/xrootd"
 
 import (
 	"context"
+	"fmt"
 	rsync "sync"
 
 	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgread"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgwrite"
 	"go-hep.org/x/hep/xrootd/xrdproto/read"
+	"go-hep.org/x/hep/xrootd/xrdproto/readv"
 	"go-hep.org/x/hep/xrootd/xrdproto/stat"
 	"go-hep.org/x/hep/xrootd/xrdproto/sync"
 	"go-hep.org/x/hep/xrootd/xrdproto/truncate"
@@ -154,6 +158,146 @@ func (f *file) VerifyWriteAt(ctx context.Context, p []byte, off int64) error {
 	})
 }
 
+// ReadV reads the provided chunks of the file using vectored reads.
+// The data of each chunk is read into its Data field, starting at its Offset.
+// The Data field is resliced to the number of bytes actually read,
+// which may be less than requested if the end of file was reached.
+// Chunks are split into as many requests as needed to stay within the
+// readv limits of the protocol.
+func (f *file) ReadV(ctx context.Context, chunks []xrdfs.Chunk) error {
+	// split chunks into pieces of at most readv.MaxChunkLength bytes,
+	// sharing the memory of their chunk.
+	var (
+		pieces = make([]xrdfs.Chunk, 0, len(chunks))
+		owners = make([]int, 0, len(chunks))
+		sizes  = make([]int, 0, len(chunks))
+	)
+	for i, chunk := range chunks {
+		data, off := chunk.Data, chunk.Offset
+		for {
+			n := min(len(data), readv.MaxChunkLength)
+			pieces = append(pieces, xrdfs.Chunk{Offset: off, Data: data[:n]})
+			owners = append(owners, i)
+			sizes = append(sizes, n)
+			data, off = data[n:], off+int64(n)
+			if len(data) == 0 {
+				break
+			}
+		}
+	}
+
+	// send the pieces in requests within the readv limits.
+	for beg := 0; beg < len(pieces); {
+		end, size := beg, 0
+		for end < len(pieces) && end-beg < readv.MaxChunks && size+sizes[end] <= readv.MaxLength {
+			size += sizes[end]
+			end++
+		}
+		err := f.readv(ctx, pieces[beg:end])
+		if err != nil {
+			return err
+		}
+		beg = end
+	}
+
+	// a chunk holds the data of its pieces, up to its first short piece.
+	ns := make([]int, len(chunks))
+	short := make([]bool, len(chunks))
+	for i, piece := range pieces {
+		j := owners[i]
+		if short[j] {
+			continue
+		}
+		ns[j] += len(piece.Data)
+		short[j] = len(piece.Data) < sizes[i]
+	}
+	for i := range chunks {
+		chunks[i].Data = chunks[i].Data[:ns[i]]
+	}
+	return nil
+}
+
+func (f *file) readv(ctx context.Context, chunks []xrdfs.Chunk) error {
+	req := &readv.Request{Chunks: make([]readv.Chunk, len(chunks))}
+	for i, chunk := range chunks {
+		req.Chunks[i] = readv.Chunk{
+			Handle: f.handle,
+			Length: int32(len(chunk.Data)),
+			Offset: chunk.Offset,
+		}
+	}
+
+	var resp readv.Response
+	err := f.do(ctx, func(ctx context.Context, sid string) (string, error) {
+		return f.fs.c.sendSession(ctx, sid, &resp, req)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Chunks) != len(chunks) {
+		return fmt.Errorf("xrootd: readv returned %d chunks, want %d", len(resp.Chunks), len(chunks))
+	}
+	for i, got := range resp.Chunks {
+		want := req.Chunks[i]
+		if got.Handle != want.Handle || got.Offset != want.Offset || got.Length > want.Length {
+			return fmt.Errorf(
+				"xrootd: readv chunk %d mismatch: got=(offset=%d, len=%d) want=(offset=%d, len=%d)",
+				i, got.Offset, got.Length, want.Offset, want.Length,
+			)
+		}
+		n := copy(chunks[i].Data, got.Data)
+		chunks[i].Data = chunks[i].Data[:n]
+	}
+	return nil
+}
+
+// PgReadAt reads len(p) bytes into p starting at offset off using page reads.
+// The CRC32C checksum of each page of data is verified.
+// Reads longer than pgread.MaxLength are split into several requests.
+func (f *file) PgReadAt(ctx context.Context, p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		sz := min(len(p)-n, pgread.MaxLength)
+		nn, err := f.pgread(ctx, p[n:n+sz], off+int64(n))
+		n += nn
+		if err != nil || nn < sz {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (f *file) pgread(ctx context.Context, p []byte, off int64) (n int, err error) {
+	resp := pgread.Response{Data: p}
+	req := &pgread.Request{Handle: f.handle, Offset: off, Length: int32(len(p))}
+	err = f.do(ctx, func(ctx context.Context, sid string) (string, error) {
+		return f.fs.c.sendSession(ctx, sid, &resp, req)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if resp.Offset != off {
+		return 0, fmt.Errorf("xrootd: pgread returned data at offset %d, want %d", resp.Offset, off)
+	}
+	return copy(p, resp.Data), nil
+}
+
+// PgWriteAt writes len(p) bytes from p to the file at offset off using page writes.
+// The CRC32C checksum of each page of data is sent along with the data.
+func (f *file) PgWriteAt(ctx context.Context, p []byte, off int64) error {
+	var resp pgwrite.Response
+	err := f.do(ctx, func(ctx context.Context, sid string) (string, error) {
+		return f.fs.c.sendSession(ctx, sid, &resp, &pgwrite.Request{Handle: f.handle, Offset: off, Data: p})
+	})
+	if err != nil {
+		return err
+	}
+	if len(resp.Corrupted) > 0 {
+		return fmt.Errorf("xrootd: pgwrite checksum mismatch for pages at offsets %v", resp.Corrupted)
+	}
+	return nil
+}
+
 func (f *file) do(ctx context.Context, fct func(ctx context.Context, sid string) (string, error)) error {
 	f.mu.RLock()
 	sid := f.sessionID
@@ -5,7 +5,9 @@
 package xrootd // import "go-hep.org/x/hep/xrootd"
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	stdpath "path"
 
 	"go-hep.org/x/hep/xrootd/xrdfs"
@@ -14,6 +16,7 @@ import (
 	"go-hep.org/x/hep/xrootd/xrdproto/mkdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/mv"
 	"go-hep.org/x/hep/xrootd/xrdproto/open"
+	"go-hep.org/x/hep/xrootd/xrdproto/query"
 	"go-hep.org/x/hep/xrootd/xrdproto/rm"
 	"go-hep.org/x/hep/xrootd/xrdproto/rmdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/stat"
@@ -146,6 +149,26 @@ func (fs *fileSystem) Chmod(ctx context.Context, path string, perm xrdfs.OpenMod
 	return err
 }
 
+// Checksum returns the checksum of the named file, computed by the server
+// with the typ algorithm (e.g. "adler32", "crc32c" or "md5").
+// If typ is empty, the default algorithm of the server is used.
+func (fs *fileSystem) Checksum(ctx context.Context, path, typ string) (xrdfs.Checksum, error) {
+	args := path
+	if typ != "" {
+		args += "?cks.type=" + typ
+	}
+	var resp query.Response
+	_, err := fs.c.Send(ctx, &resp, &query.Request{Query: query.Checksum, Args: []byte(args)})
+	if err != nil {
+		return xrdfs.Checksum{}, err
+	}
+	toks := bytes.Fields(bytes.TrimRight(resp.Data, "\x00"))
+	if len(toks) != 2 {
+		return xrdfs.Checksum{}, fmt.Errorf("xrootd: invalid checksum response %q", resp.Data)
+	}
+	return xrdfs.Checksum{Type: string(toks[0]), Value: string(toks[1])}, nil
+}
+
 // Statx obtains type information for one or more paths.
 // Only a limited number of flags is meaningful such as StatIsExecutable, StatIsDir, StatIsOther, StatIsOffline.
 func (fs *fileSystem) Statx(ctx context.Context, paths []string) ([]xrdfs.StatFlags, error) {
@@ -5,20 +5,37 @@
 package xrootd // import "go-hep.org/x/hep/xrootd"
 
 import (
+	"context"
 	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
+	"net"
+	"net/url"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"go-hep.org/x/hep/xrootd/xrdfs"
 	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/chmod"
 	"go-hep.org/x/hep/xrootd/xrdproto/dirlist"
+	"go-hep.org/x/hep/xrootd/xrdproto/locate"
 	"go-hep.org/x/hep/xrootd/xrdproto/mkdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/mv"
 	"go-hep.org/x/hep/xrootd/xrdproto/open"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgread"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgwrite"
+	"go-hep.org/x/hep/xrootd/xrdproto/prepare"
+	"go-hep.org/x/hep/xrootd/xrdproto/query"
 	"go-hep.org/x/hep/xrootd/xrdproto/read"
+	"go-hep.org/x/hep/xrootd/xrdproto/readv"
 	"go-hep.org/x/hep/xrootd/xrdproto/rm"
 	"go-hep.org/x/hep/xrootd/xrdproto/rmdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/stat"
@@ -37,25 +54,79 @@ type fshandler struct {
 	// than sync.Map for given scenarios (write to map once per session and a lot of reads per session).
 	mu       sync.RWMutex
 	sessions map[[16]byte]*srvSession
+	infos    map[[16]byte]SessionInfo // infos describe the connections of the sessions.
+	nlinks   int64                    // nlinks is the total number of sessions served so far.
+	start    time.Time
+
+	tpcMu      sync.Mutex
+	tpcKeys    map[string]tpcKey   // tpcKeys are the third-party copy keys registered with this server, as a source.
+	tpcSources map[string]struct{} // tpcSources are the hosts and addresses this server may copy files from, as a destination.
+}
+
+// FSHandlerOption configures a Handler created by NewFSHandler.
+type FSHandlerOption func(*fshandler)
+
+// WithTPCSources allows the handler to perform third-party copies, as a
+// destination, from the source servers listening on addrs.
+// Each address is either in the "host:port" form or a host name, allowing
+// any port of that host.
+// Without this option, third-party copies to the handler are refused.
+func WithTPCSources(addrs ...string) FSHandlerOption {
+	return func(h *fshandler) {
+		for _, addr := range addrs {
+			h.tpcSources[addr] = struct{}{}
+		}
+	}
 }
 
 type srvSession struct {
 	mu      sync.Mutex
 	handles map[xrdfs.FileHandle]*os.File
+	tpc     map[xrdfs.FileHandle]tpcJob // tpc are the third-party copies pending on this server, as a destination.
 }
 
 // NewFSHandler creates a Handler that passes requests to the backing filesystem at basePath.
-func NewFSHandler(basePath string) Handler {
-	return &fshandler{
-		Handler:  Default(),
-		basePath: basePath,
-		sessions: make(map[[16]byte]*srvSession),
+// Options opts configure the handler and are applied in the order they were specified.
+func NewFSHandler(basePath string, opts ...FSHandlerOption) Handler {
+	h := &fshandler{
+		Handler:    Default(),
+		basePath:   basePath,
+		sessions:   make(map[[16]byte]*srvSession),
+		infos:      make(map[[16]byte]SessionInfo),
+		start:      time.Now(),
+		tpcKeys:    make(map[string]tpcKey),
+		tpcSources: make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(h)
 	}
+	return h
+}
+
+// SetSessionInfo implements server.Handler.SetSessionInfo.
+// The local address of the connection is used to answer locate and statistics queries.
+func (h *fshandler) SetSessionInfo(sessionID [16]byte, info SessionInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.infos[sessionID]; !ok {
+		h.nlinks++
+	}
+	h.infos[sessionID] = info
+}
+
+// localPath returns the path of the named file on the backing filesystem,
+// stripped of its opaque data.
+func (h *fshandler) localPath(name string) string {
+	name, _, _ = strings.Cut(name, "?")
+	return path.Join(h.basePath, name)
 }
 
 // Dirlist implements server.Handler.Dirlist.
 func (h *fshandler) Dirlist(sessionID [16]byte, request *dirlist.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
-	files, err := os.ReadDir(path.Join(h.basePath, request.Path))
+	files, err := os.ReadDir(h.localPath(request.Path))
 	if err != nil {
 		return xrdproto.ServerError{
 			Code:    xrdproto.IOError,
@@ -105,7 +176,17 @@ func (h *fshandler) Open(sessionID [16]byte, request *open.Request) (xrdproto.Ma
 		}
 	}
 
-	filePath := path.Join(h.basePath, request.Path)
+	name, opaque, _ := strings.Cut(request.Path, "?")
+	cgi, _ := url.ParseQuery(opaque)
+	job, err := h.newTPCJob(name, cgi)
+	if err != nil {
+		return xrdproto.ServerError{
+			Code:    xrdproto.NotAuthorized,
+			Message: fmt.Sprintf("Could not perform third-party copy: %v", err),
+		}, xrdproto.Error
+	}
+
+	filePath := h.localPath(name)
 	if request.Options&xrdfs.OpenOptionsMkPath != 0 {
 		if err := os.MkdirAll(path.Dir(filePath), os.FileMode(request.Mode)); err != nil {
 			return xrdproto.ServerError{
@@ -165,6 +246,12 @@ func (h *fshandler) Open(sessionID [16]byte, request *open.Request) (xrdproto.Ma
 			}
 			// TODO: return compression info if requested.
 			sess.handles[handle] = file
+			if job != nil {
+				if sess.tpc == nil {
+					sess.tpc = make(map[xrdfs.FileHandle]tpcJob)
+				}
+				sess.tpc[handle] = *job
+			}
 
 			return resp, xrdproto.Ok
 		}
@@ -198,6 +285,7 @@ func (h *fshandler) Close(sessionID [16]byte, request *xrdclose.Request) (xrdpro
 		}, xrdproto.Error
 	}
 	delete(sess.handles, request.Handle)
+	delete(sess.tpc, request.Handle)
 	err := file.Close()
 	if err != nil {
 		return xrdproto.ServerError{
@@ -218,6 +306,10 @@ func (h *fshandler) Read(sessionID [16]byte, request *read.Request) (xrdproto.Ma
 		}, xrdproto.Error
 	}
 
+	if resp, ok := checkLength("read", request.Length, math.MaxInt32); !ok {
+		return resp, xrdproto.Error
+	}
+
 	buf := make([]byte, request.Length)
 	n, err := file.ReadAt(buf, request.Offset)
 	if err != nil && err != io.EOF {
@@ -251,6 +343,133 @@ func (h *fshandler) Write(sessionID [16]byte, request *write.Request) (xrdproto.
 	return nil, xrdproto.Ok
 }
 
+// ReadV implements server.Handler.ReadV.
+func (h *fshandler) ReadV(sessionID [16]byte, request *readv.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	if len(request.Chunks) > readv.MaxChunks {
+		return xrdproto.ServerError{
+			Code:    xrdproto.InvalidRequest,
+			Message: fmt.Sprintf("Too many readv chunks: %d (max=%d)", len(request.Chunks), readv.MaxChunks),
+		}, xrdproto.Error
+	}
+
+	total := 0
+	for _, chunk := range request.Chunks {
+		if resp, ok := checkLength("readv chunk", chunk.Length, readv.MaxChunkLength); !ok {
+			return resp, xrdproto.Error
+		}
+		total += int(chunk.Length)
+	}
+	if total > readv.MaxLength {
+		return xrdproto.ServerError{
+			Code:    xrdproto.ArgTooLong,
+			Message: fmt.Sprintf("Too long readv request: %d bytes (max=%d)", total, readv.MaxLength),
+		}, xrdproto.Error
+	}
+
+	resp := readv.Response{Chunks: make([]readv.Data, len(request.Chunks))}
+	for i, chunk := range request.Chunks {
+		file := h.getFile(sessionID, chunk.Handle)
+		if file == nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.InvalidRequest,
+				Message: fmt.Sprintf("Invalid file handle: %v", chunk.Handle),
+			}, xrdproto.Error
+		}
+
+		buf := make([]byte, chunk.Length)
+		n, err := file.ReadAt(buf, chunk.Offset)
+		if err != nil && err != io.EOF {
+			return xrdproto.ServerError{
+				Code:    xrdproto.IOError,
+				Message: fmt.Sprintf("An IO error occurred: %v", err),
+			}, xrdproto.Error
+		}
+		resp.Chunks[i] = readv.Data{Chunk: chunk, Data: buf[:n]}
+	}
+
+	return resp, xrdproto.Ok
+}
+
+// PgRead implements server.Handler.PgRead.
+func (h *fshandler) PgRead(sessionID [16]byte, request *pgread.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	file := h.getFile(sessionID, request.Handle)
+	if file == nil {
+		return xrdproto.ServerError{
+			Code:    xrdproto.InvalidRequest,
+			Message: fmt.Sprintf("Invalid file handle: %v", request.Handle),
+		}, xrdproto.Error
+	}
+
+	if resp, ok := checkLength("pgread", request.Length, pgread.MaxLength); !ok {
+		return resp, xrdproto.Error
+	}
+
+	buf := make([]byte, request.Length)
+	n, err := file.ReadAt(buf, request.Offset)
+	if err != nil && err != io.EOF {
+		return xrdproto.ServerError{
+			Code:    xrdproto.IOError,
+			Message: fmt.Sprintf("An IO error occurred: %v", err),
+		}, xrdproto.Error
+	}
+
+	return pgread.Response{Offset: request.Offset, Data: buf[:n]}, xrdproto.Status
+}
+
+// checkLength checks the client-provided length of the named read request
+// before any buffer is allocated for it.
+func checkLength(name string, length int32, max int) (xrdproto.Marshaler, bool) {
+	switch {
+	case length < 0:
+		return xrdproto.ServerError{
+			Code:    xrdproto.ArgInvalid,
+			Message: fmt.Sprintf("Invalid %s length: %d", name, length),
+		}, false
+	case int(length) > max:
+		return xrdproto.ServerError{
+			Code:    xrdproto.ArgTooLong,
+			Message: fmt.Sprintf("Too long %s: %d bytes (max=%d)", name, length, max),
+		}, false
+	}
+	return nil, true
+}
+
+// PgWrite implements server.Handler.PgWrite.
+// Pages received with a bad checksum are not written and are reported back to the client.
+func (h *fshandler) PgWrite(sessionID [16]byte, request *pgwrite.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	file := h.getFile(sessionID, request.Handle)
+	if file == nil {
+		return xrdproto.ServerError{
+			Code:    xrdproto.InvalidRequest,
+			Message: fmt.Sprintf("Invalid file handle: %v", request.Handle),
+		}, xrdproto.Error
+	}
+
+	var (
+		data      = request.Data
+		off       = request.Offset
+		corrupted = request.Corrupted
+	)
+	for len(data) > 0 {
+		n := min(xrdproto.PageSize-int(off%xrdproto.PageSize), len(data))
+		if len(corrupted) > 0 && corrupted[0] == off {
+			corrupted = corrupted[1:]
+		} else {
+			_, err := file.WriteAt(data[:n], off)
+			if err != nil {
+				return xrdproto.ServerError{
+					Code:    xrdproto.IOError,
+					Message: fmt.Sprintf("An IO error occurred: %v", err),
+				}, xrdproto.Error
+			}
+		}
+		data = data[n:]
+		off += int64(n)
+	}
+
+	return pgwrite.Response{Offset: request.Offset, Corrupted: request.Corrupted}, xrdproto.Status
+}
+
 func (h *fshandler) getFile(sessionID [16]byte, handle xrdfs.FileHandle) *os.File {
 	h.mu.RLock()
 	sess, ok := h.sessions[sessionID]
@@ -289,14 +508,11 @@ func (h *fshandler) Stat(sessionID [16]byte, request *stat.Request) (xrdproto.Ma
 		}
 		fi, err = file.Stat()
 	} else {
-		fi, err = os.Stat(path.Join(h.basePath, request.Path))
+		fi, err = os.Stat(h.localPath(request.Path))
 	}
 
 	if err != nil {
-		return xrdproto.ServerError{
-			Code:    xrdproto.IOError,
-			Message: fmt.Sprintf("An IO error occurred: %v", err),
-		}, xrdproto.Error
+		return newIOError(err)
 	}
 
 	return stat.DefaultResponse{EntryStat: xrdfs.EntryStatFrom(fi)}, xrdproto.Ok
@@ -315,7 +531,7 @@ func (h *fshandler) Truncate(sessionID [16]byte, request *truncate.Request) (xrd
 		}
 		err = file.Truncate(request.Size)
 	} else {
-		err = os.Truncate(path.Join(h.basePath, request.Path), request.Size)
+		err = os.Truncate(h.localPath(request.Path), request.Size)
 	}
 
 	if err != nil {
@@ -338,6 +554,18 @@ func (h *fshandler) Sync(sessionID [16]byte, request *xrdsync.Request) (xrdproto
 		}, xrdproto.Error
 	}
 
+	if job, ok := h.getTPCJob(sessionID, request.Handle); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), tpcTimeout)
+		defer cancel()
+		if err := job.run(ctx, file); err != nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.IOError,
+				Message: fmt.Sprintf("Could not perform third-party copy: %v", err),
+			}, xrdproto.Error
+		}
+		return nil, xrdproto.Ok
+	}
+
 	if err := file.Sync(); err != nil {
 		return xrdproto.ServerError{
 			Code:    xrdproto.IOError,
@@ -348,9 +576,223 @@ func (h *fshandler) Sync(sessionID [16]byte, request *xrdsync.Request) (xrdproto
 	return nil, xrdproto.Ok
 }
 
+// getTPCJob returns and removes the third-party copy pending on the file handle, if any.
+func (h *fshandler) getTPCJob(sessionID [16]byte, handle xrdfs.FileHandle) (tpcJob, bool) {
+	h.mu.RLock()
+	sess, ok := h.sessions[sessionID]
+	h.mu.RUnlock()
+	if !ok {
+		return tpcJob{}, false
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	job, ok := sess.tpc[handle]
+	delete(sess.tpc, handle)
+	return job, ok
+}
+
+// Query implements server.Handler.Query.
+func (h *fshandler) Query(sessionID [16]byte, request *query.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	switch request.Query {
+	case query.Checksum:
+		name, opaque, _ := strings.Cut(strings.TrimRight(string(request.Args), "\x00"), "?")
+		cgi, _ := url.ParseQuery(opaque)
+		typ := cgi.Get("cks.type")
+		if typ == "" {
+			typ = xrdfs.ChecksumAdler32
+		}
+		hash, err := xrdfs.NewChecksumHash(typ)
+		if err != nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.InvalidRequest,
+				Message: fmt.Sprintf("Invalid checksum type: %v", err),
+			}, xrdproto.Error
+		}
+
+		f, err := os.Open(h.localPath(name))
+		if err != nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.IOError,
+				Message: fmt.Sprintf("An IO error occurred: %v", err),
+			}, xrdproto.Error
+		}
+		defer f.Close()
+
+		_, err = io.Copy(hash, f)
+		if err != nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.IOError,
+				Message: fmt.Sprintf("An IO error occurred: %v", err),
+			}, xrdproto.Error
+		}
+
+		cks := xrdfs.ChecksumFrom(typ, hash.Sum(nil))
+		return &query.Response{Data: []byte(cks.Type + " " + cks.Value)}, xrdproto.Ok
+
+	case query.CancelChecksum:
+		// Checksums are computed synchronously: there is nothing to cancel.
+		return nil, xrdproto.Ok
+
+	case query.Config:
+		names := strings.Fields(strings.TrimRight(string(request.Args), "\x00"))
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = h.config(name)
+		}
+		return &query.Response{Data: []byte(strings.Join(values, "\n") + "\n")}, xrdproto.Ok
+
+	case query.Space:
+		name, _, _ := strings.Cut(strings.TrimRight(string(request.Args), "\x00"), "?")
+		total, free, err := diskSpace(h.localPath(name))
+		if err != nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.IOError,
+				Message: fmt.Sprintf("An IO error occurred: %v", err),
+			}, xrdproto.Error
+		}
+		data := fmt.Sprintf(
+			"oss.cgroup=default&oss.space=%d&oss.free=%d&oss.maxf=%d&oss.used=%d&oss.quota=-1",
+			total, free, free, total-free,
+		)
+		return &query.Response{Data: []byte(data)}, xrdproto.Ok
+
+	case query.Stats:
+		return &query.Response{Data: []byte(h.stats(sessionID))}, xrdproto.Ok
+	}
+
+	return h.Handler.Query(sessionID, request)
+}
+
+// config returns the value of the named configuration item,
+// or the name itself if the item is unknown, as real xrootd servers do.
+func (h *fshandler) config(name string) string {
+	switch name {
+	case "bind_max":
+		return "0"
+	case "chksum":
+		return "0:" + xrdfs.ChecksumAdler32 + ",1:" + xrdfs.ChecksumCRC32C + ",2:" + xrdfs.ChecksumMD5
+	case "readv_iov_max":
+		return strconv.Itoa(readv.MaxChunks)
+	case "role":
+		return "server"
+	case "tpc":
+		return "1"
+	case "tpcdlg":
+		return "0"
+	}
+	return name
+}
+
+// stats returns the statistics of the server, in the XML format of real xrootd servers.
+func (h *fshandler) stats(sessionID [16]byte) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+
+	h.mu.RLock()
+	var (
+		addr   = h.infos[sessionID].LocalAddr
+		nconns = len(h.infos)
+		nlinks = h.nlinks
+		nfiles = 0
+	)
+	for _, sess := range h.sessions {
+		sess.mu.Lock()
+		nfiles += len(sess.handles)
+		sess.mu.Unlock()
+	}
+	h.mu.RUnlock()
+
+	return fmt.Sprintf(
+		`<statistics tod="%d" ver="v5" src="%s" tos="%d" pgm="xrootd" ins="anon" pid="%d">`+
+			`<stats id="info"><host>%s</host><port>%s</port><name>anon</name></stats>`+
+			`<stats id="link"><num>%d</num><tot>%d</tot></stats>`+
+			`<stats id="xrootd"><num>%d</num></stats>`+
+			`</statistics>`,
+		time.Now().Unix(), addr, h.start.Unix(), os.Getpid(),
+		host, portOf(addr),
+		nconns, nlinks,
+		nfiles,
+	)
+}
+
+// portOf returns the port of the provided host:port address.
+func portOf(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// Locate implements server.Handler.Locate.
+// Files are located on this server, at the address the client connected to.
+func (h *fshandler) Locate(sessionID [16]byte, request *locate.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	name := strings.TrimPrefix(request.Path, "*")
+	fi, err := os.Stat(h.localPath(name))
+	if err != nil {
+		return newIOError(err)
+	}
+
+	h.mu.RLock()
+	addr := h.infos[sessionID].LocalAddr
+	h.mu.RUnlock()
+
+	access := "r"
+	if fi.Mode().Perm()&0200 != 0 {
+		access = "w"
+	}
+	return &locate.Response{Data: []byte("S" + access + addr)}, xrdproto.Ok
+}
+
+// Prepare implements server.Handler.Prepare.
+// Files are always online: preparing them only checks they exist.
+func (h *fshandler) Prepare(sessionID [16]byte, request *prepare.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	if request.Options&prepare.Cancel != 0 {
+		return nil, xrdproto.Ok
+	}
+
+	for _, name := range request.Paths {
+		if _, err := os.Stat(h.localPath(name)); err != nil {
+			return newIOError(err)
+		}
+	}
+
+	if request.Options&prepare.Stage == 0 {
+		return nil, xrdproto.Ok
+	}
+
+	// Staging requests are identified by a request ID that may be used to cancel them.
+	var id [8]byte
+	rand.Read(id[:])
+	return &prepare.Response{Data: []byte(hex.EncodeToString(id[:]))}, xrdproto.Ok
+}
+
+// Chmod implements server.Handler.Chmod.
+func (h *fshandler) Chmod(sessionID [16]byte, request *chmod.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	if err := os.Chmod(h.localPath(request.Path), os.FileMode(request.Mode)); err != nil {
+		return newIOError(err)
+	}
+	return nil, xrdproto.Ok
+}
+
+// newIOError returns the error response for the provided error,
+// reporting missing files as such.
+func newIOError(err error) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	code := xrdproto.IOError
+	if errors.Is(err, fs.ErrNotExist) {
+		code = xrdproto.NotFound
+	}
+	return xrdproto.ServerError{
+		Code:    code,
+		Message: fmt.Sprintf("An IO error occurred: %v", err),
+	}, xrdproto.Error
+}
+
 // Rename implements server.Handler.Rename.
 func (h *fshandler) Rename(sessionID [16]byte, request *mv.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
-	if err := os.Rename(path.Join(h.basePath, request.OldPath), path.Join(h.basePath, request.NewPath)); err != nil {
+	if err := os.Rename(h.localPath(request.OldPath), h.localPath(request.NewPath)); err != nil {
 		return xrdproto.ServerError{
 			Code:    xrdproto.IOError,
 			Message: fmt.Sprintf("An IO error occurred: %v", err),
@@ -367,7 +809,7 @@ func (h *fshandler) Mkdir(sessionID [16]byte, request *mkdir.Request) (xrdproto.
 		mkdirFunc = os.MkdirAll
 	}
 
-	if err := mkdirFunc(path.Join(h.basePath, request.Path), os.FileMode(request.Mode)); err != nil {
+	if err := mkdirFunc(h.localPath(request.Path), os.FileMode(request.Mode)); err != nil {
 		return xrdproto.ServerError{
 			Code:    xrdproto.IOError,
 			Message: fmt.Sprintf("An IO error occurred: %v", err),
@@ -378,7 +820,7 @@ func (h *fshandler) Mkdir(sessionID [16]byte, request *mkdir.Request) (xrdproto.
 
 // Remove implements server.Handler.Remove.
 func (h *fshandler) Remove(sessionID [16]byte, request *rm.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
-	if err := os.Remove(path.Join(h.basePath, request.Path)); err != nil {
+	if err := os.Remove(h.localPath(request.Path)); err != nil {
 		return xrdproto.ServerError{
 			Code:    xrdproto.IOError,
 			Message: fmt.Sprintf("An IO error occurred: %v", err),
@@ -389,7 +831,7 @@ func (h *fshandler) Remove(sessionID [16]byte, request *rm.Request) (xrdproto.Ma
 
 // RemoveDir implements server.Handler.RemoveDir.
 func (h *fshandler) RemoveDir(sessionID [16]byte, request *rmdir.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
-	if err := os.Remove(path.Join(h.basePath, request.Path)); err != nil {
+	if err := os.Remove(h.localPath(request.Path)); err != nil {
 		return xrdproto.ServerError{
 			Code:    xrdproto.IOError,
 			Message: fmt.Sprintf("An IO error occurred: %v", err),
@@ -401,6 +843,7 @@ func (h *fshandler) RemoveDir(sessionID [16]byte, request *rmdir.Request) (xrdpr
 // CloseSession implements server.Handler.CloseSession.
 func (h *fshandler) CloseSession(sessionID [16]byte) error {
 	h.mu.Lock()
+	delete(h.infos, sessionID)
 	sess, ok := h.sessions[sessionID]
 	if !ok {
 		// That means that no files were opened in that session and we have nothing to clear.
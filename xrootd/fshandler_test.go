@@ -5,20 +5,32 @@
 package xrootd_test // import "go-hep.org/x/hep/xrootd"
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path"
 	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 
 	"go-hep.org/x/hep/xrootd"
 	"go-hep.org/x/hep/xrootd/xrdfs"
 	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/locate"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgread"
 	"go-hep.org/x/hep/xrootd/xrdproto/ping"
+	"go-hep.org/x/hep/xrootd/xrdproto/prepare"
+	"go-hep.org/x/hep/xrootd/xrdproto/query"
+	"go-hep.org/x/hep/xrootd/xrdproto/read"
+	"go-hep.org/x/hep/xrootd/xrdproto/readv"
 )
 
 func getTCPAddr() (string, error) {
@@ -539,6 +551,375 @@ func TestHandler_Write(t *testing.T) {
 	}
 }
 
+func TestHandler_ReadV(t *testing.T) {
+	data := make([]byte, 10*1024)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatalf("could not prepare test data: %v", err)
+	}
+
+	srv, addr, baseDir, err := createServer(func(err error) {
+		t.Error(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	defer func() {
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	err = os.WriteFile(path.Join(baseDir, "file1.txt"), data, 0777)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	cli, err := createClient(addr)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	gotFile, err := cli.FS().Open(context.Background(), "file1.txt", xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	if err != nil {
+		t.Fatalf("could not call Open: %v", err)
+	}
+	defer gotFile.Close(context.Background())
+
+	for _, tc := range []struct {
+		testName string
+		chunks   [][2]int // offset, length
+	}{
+		{
+			testName: "Single chunk",
+			chunks:   [][2]int{{0, 10}},
+		},
+		{
+			testName: "Multiple chunks",
+			chunks:   [][2]int{{1, 10}, {4000, 200}, {20, 5}, {9000, 1024}},
+		},
+		{
+			testName: "With EOF",
+			chunks:   [][2]int{{10*1024 - 4, 10}, {10, 10}, {20 * 1024, 10}},
+		},
+		{
+			testName: "More chunks than allowed per request",
+			chunks: func() [][2]int {
+				chunks := make([][2]int, 2500)
+				for i := range chunks {
+					chunks[i] = [2]int{i * 4, 3}
+				}
+				return chunks
+			}(),
+		},
+	} {
+		t.Run(tc.testName, func(t *testing.T) {
+			chunks := make([]xrdfs.Chunk, len(tc.chunks))
+			for i, c := range tc.chunks {
+				chunks[i] = xrdfs.Chunk{Offset: int64(c[0]), Data: make([]byte, c[1])}
+			}
+
+			err := gotFile.ReadV(context.Background(), chunks)
+			if err != nil {
+				t.Fatalf("could not call ReadV: %v", err)
+			}
+
+			for i, c := range tc.chunks {
+				beg := min(c[0], len(data))
+				end := min(c[0]+c[1], len(data))
+				want := data[beg:end]
+				if got := chunks[i].Data; !bytes.Equal(got, want) {
+					t.Fatalf("wrong data for chunk %d:\ngot = %v\nwant = %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ReadLimits(t *testing.T) {
+	data := make([]byte, 5<<20)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatalf("could not prepare test data: %v", err)
+	}
+
+	srv, addr, baseDir, err := createServer(func(err error) {
+		t.Error(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	defer func() {
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	err = os.WriteFile(path.Join(baseDir, "file1.txt"), data, 0777)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	cli, err := createClient(addr)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	gotFile, err := cli.FS().Open(ctx, "file1.txt", xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	if err != nil {
+		t.Fatalf("could not call Open: %v", err)
+	}
+	defer gotFile.Close(ctx)
+
+	t.Run("Client splits large readv", func(t *testing.T) {
+		// 6 chunks of 3MiB: chunks and request are both above the limits.
+		chunks := make([]xrdfs.Chunk, 6)
+		for i := range chunks {
+			chunks[i] = xrdfs.Chunk{Offset: int64(i) << 20, Data: make([]byte, 3<<20)}
+		}
+		err := gotFile.ReadV(ctx, chunks)
+		if err != nil {
+			t.Fatalf("could not call ReadV: %v", err)
+		}
+		for i, chunk := range chunks {
+			beg := min(int(chunk.Offset), len(data))
+			end := min(int(chunk.Offset)+3<<20, len(data))
+			if !bytes.Equal(chunk.Data, data[beg:end]) {
+				t.Fatalf("wrong data for chunk %d: got %d bytes, want %d", i, len(chunk.Data), end-beg)
+			}
+		}
+	})
+
+	handle := gotFile.Handle()
+	manyChunks := make([]readv.Chunk, 9)
+	for i := range manyChunks {
+		manyChunks[i] = readv.Chunk{Handle: handle, Offset: int64(i), Length: readv.MaxChunkLength}
+	}
+
+	for _, tc := range []struct {
+		testName string
+		req      xrdproto.Request
+		errCode  xrdproto.ServerErrorCode
+	}{
+		{
+			testName: "Negative readv chunk",
+			req:      &readv.Request{Chunks: []readv.Chunk{{Handle: handle, Length: -1}}},
+			errCode:  xrdproto.ArgInvalid,
+		},
+		{
+			testName: "Too long readv chunk",
+			req:      &readv.Request{Chunks: []readv.Chunk{{Handle: handle, Length: readv.MaxChunkLength + 1}}},
+			errCode:  xrdproto.ArgTooLong,
+		},
+		{
+			testName: "Too long readv request",
+			req:      &readv.Request{Chunks: manyChunks},
+			errCode:  xrdproto.ArgTooLong,
+		},
+		{
+			testName: "Negative pgread",
+			req:      &pgread.Request{Handle: handle, Length: -1},
+			errCode:  xrdproto.ArgInvalid,
+		},
+		{
+			testName: "Too long pgread",
+			req:      &pgread.Request{Handle: handle, Length: pgread.MaxLength + 1},
+			errCode:  xrdproto.ArgTooLong,
+		},
+		{
+			testName: "Negative read",
+			req:      &read.Request{Handle: handle, Length: -1},
+			errCode:  xrdproto.ArgInvalid,
+		},
+	} {
+		t.Run(tc.testName, func(t *testing.T) {
+			_, err := cli.Send(ctx, nil, tc.req)
+			var serr xrdproto.ServerError
+			if !errors.As(err, &serr) || serr.Code != tc.errCode {
+				t.Fatalf("invalid error: got=%v, want code %d", err, tc.errCode)
+			}
+		})
+	}
+}
+
+func TestHandler_PgRead(t *testing.T) {
+	bigData := make([]byte, 3*xrdproto.PageSize+100)
+	_, err := rand.Read(bigData)
+	if err != nil {
+		t.Fatalf("could not prepare test data: %v", err)
+	}
+
+	hugeData := make([]byte, 2*pgread.MaxLength+100)
+	_, err = rand.Read(hugeData)
+	if err != nil {
+		t.Fatalf("could not prepare test data: %v", err)
+	}
+
+	for _, tc := range []struct {
+		testName string
+		data     []byte
+		want     []byte
+		offset   int64
+		length   int
+	}{
+		{
+			testName: "Without offset",
+			data:     []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			length:   6,
+			want:     []byte{1, 2, 3, 4, 5, 6},
+		},
+		{
+			testName: "With offset with EOF",
+			data:     []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			length:   20,
+			offset:   1,
+			want:     []byte{2, 3, 4, 5, 6, 7, 8},
+		},
+		{
+			testName: "With offset larger than file size",
+			data:     []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			length:   20,
+			offset:   40,
+			want:     []byte{},
+		},
+		{
+			testName: "With unaligned offset over multiple pages",
+			data:     bigData,
+			length:   len(bigData) - 40,
+			offset:   40,
+			want:     bigData[40:],
+		},
+		{
+			testName: "Longer than allowed per request",
+			data:     hugeData,
+			length:   len(hugeData) - 40,
+			offset:   40,
+			want:     hugeData[40:],
+		},
+	} {
+		t.Run(tc.testName, func(t *testing.T) {
+			srv, addr, baseDir, err := createServer(func(err error) {
+				t.Error(err)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(baseDir)
+			defer func() {
+				_ = srv.Shutdown(context.Background())
+			}()
+
+			err = os.WriteFile(path.Join(baseDir, "file1.txt"), tc.data, 0777)
+			if err != nil {
+				t.Fatalf("could not create test file: %v", err)
+			}
+
+			cli, err := createClient(addr)
+			if err != nil {
+				t.Fatalf("could not create client: %v", err)
+			}
+			defer cli.Close()
+
+			gotFile, err := cli.FS().Open(context.Background(), "file1.txt", xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+			if err != nil {
+				t.Fatalf("could not call Open: %v", err)
+			}
+			defer gotFile.Close(context.Background())
+
+			got := make([]byte, tc.length)
+			n, err := gotFile.PgReadAt(context.Background(), got, tc.offset)
+			if err != nil {
+				t.Fatalf("could not call PgReadAt: %v", err)
+			}
+
+			if !bytes.Equal(got[:n], tc.want) {
+				t.Fatalf("wrong data:\ngot = %v\nwant = %v", got[:n], tc.want)
+			}
+		})
+	}
+}
+
+func TestHandler_PgWrite(t *testing.T) {
+	bigData := make([]byte, 3*xrdproto.PageSize+100)
+	_, err := rand.Read(bigData)
+	if err != nil {
+		t.Fatalf("could not prepare test data: %v", err)
+	}
+
+	for _, tc := range []struct {
+		testName    string
+		initialData []byte
+		data        []byte
+		want        []byte
+		offset      int64
+	}{
+		{
+			testName:    "With offset, with partial rewrite",
+			initialData: []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			data:        []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			offset:      1,
+			want:        []byte{1, 1, 2, 3, 4, 5, 6, 7, 8},
+		},
+		{
+			testName: "With unaligned offset over multiple pages",
+			data:     bigData,
+			offset:   2,
+			want:     append([]byte{0, 0}, bigData...),
+		},
+	} {
+		t.Run(tc.testName, func(t *testing.T) {
+			srv, addr, baseDir, err := createServer(func(err error) {
+				t.Error(err)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(baseDir)
+			defer func() {
+				_ = srv.Shutdown(context.Background())
+			}()
+
+			file := path.Join(baseDir, "file1.txt")
+
+			err = os.WriteFile(file, tc.initialData, 0777)
+			if err != nil {
+				t.Fatalf("could not create test file: %v", err)
+			}
+
+			cli, err := createClient(addr)
+			if err != nil {
+				t.Fatalf("could not create client: %v", err)
+			}
+			defer cli.Close()
+
+			gotFile, err := cli.FS().Open(context.Background(), "file1.txt", xrdfs.OpenModeOwnerWrite, xrdfs.OpenOptionsOpenUpdate)
+			if err != nil {
+				t.Fatalf("could not call Open: %v", err)
+			}
+			defer gotFile.Close(context.Background())
+
+			err = gotFile.PgWriteAt(context.Background(), tc.data, tc.offset)
+			if err != nil {
+				t.Fatalf("could not call PgWriteAt: %v", err)
+			}
+
+			if err := gotFile.Sync(context.Background()); err != nil {
+				t.Fatalf("could not call Sync: %v", err)
+			}
+
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("could not read written data: %v", err)
+			}
+
+			if !bytes.Equal(got, tc.want) {
+				t.Fatalf("wrong data:\ngot = %v\nwant = %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestHandler_Stat(t *testing.T) {
 	for _, tc := range []struct {
 		testName string
@@ -1040,3 +1421,297 @@ func TestHandler_Ping(t *testing.T) {
 		t.Fatalf("could not call Ping: %v", err)
 	}
 }
+
+func TestHandler_Checksum(t *testing.T) {
+	srv, addr, baseDir, err := createServer(func(err error) {
+		t.Error(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	defer func() {
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	err = os.WriteFile(path.Join(baseDir, "file1.txt"), []byte("Wikipedia"), 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	cli, err := createClient(addr)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	for _, tc := range []struct {
+		typ  string
+		want xrdfs.Checksum
+		err  string
+	}{
+		{typ: "", want: xrdfs.Checksum{Type: "adler32", Value: "11e60398"}},
+		{typ: "adler32", want: xrdfs.Checksum{Type: "adler32", Value: "11e60398"}},
+		{typ: "crc32c", want: xrdfs.Checksum{Type: "crc32c", Value: "2d0e3663"}},
+		{typ: "md5", want: xrdfs.Checksum{Type: "md5", Value: "9c677286866aad38f8e9b660f5411814"}},
+		{typ: "sha1", err: "unsupported checksum type"},
+	} {
+		t.Run(tc.typ, func(t *testing.T) {
+			got, err := cli.FS().Checksum(context.Background(), "file1.txt", tc.typ)
+			switch {
+			case tc.err != "":
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("invalid error: got=%v, want=%v", err, tc.err)
+				}
+				return
+			case err != nil:
+				t.Fatalf("could not compute checksum: %v", err)
+			}
+
+			if got != tc.want {
+				t.Fatalf("invalid checksum: got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+
+	_, err = cli.FS().Checksum(context.Background(), "missing.txt", "")
+	if err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
+
+func TestHandler_Locate(t *testing.T) {
+	srv, addr, baseDir, err := createServer(func(err error) {
+		t.Error(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	defer func() {
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	err = os.WriteFile(path.Join(baseDir, "file1.txt"), []byte("data"), 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+	err = os.WriteFile(path.Join(baseDir, "file2.txt"), []byte("data"), 0444)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	cli, err := createClient(addr)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	for _, tc := range []struct {
+		path    string
+		want    string
+		errCode xrdproto.ServerErrorCode
+	}{
+		{path: "file1.txt", want: "Sw" + addr},
+		{path: "*file1.txt", want: "Sw" + addr},
+		{path: "file2.txt", want: "Sr" + addr},
+		{path: "missing.txt", errCode: xrdproto.NotFound},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			var resp locate.Response
+			_, err := cli.Send(context.Background(), &resp, &locate.Request{Path: tc.path})
+			if tc.errCode != 0 {
+				var serr xrdproto.ServerError
+				if !errors.As(err, &serr) || serr.Code != tc.errCode {
+					t.Fatalf("invalid error: got=%v, want code=%v", err, tc.errCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not locate file: %v", err)
+			}
+			if got := string(resp.Data); got != tc.want {
+				t.Fatalf("invalid location: got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandler_Query(t *testing.T) {
+	srv, addr, baseDir, err := createServer(func(err error) {
+		t.Error(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	defer func() {
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	cli, err := createClient(addr)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+
+	t.Run("config", func(t *testing.T) {
+		var resp query.Response
+		_, err := cli.Send(ctx, &resp, &query.Request{Query: query.Config, Args: []byte("chksum tpc readv_iov_max unknown")})
+		if err != nil {
+			t.Fatalf("could not query config: %v", err)
+		}
+		got := string(resp.Data)
+		want := "0:adler32,1:crc32c,2:md5\n1\n1024\nunknown\n"
+		if got != want {
+			t.Fatalf("invalid config:\ngot = %q\nwant= %q", got, want)
+		}
+	})
+
+	t.Run("space", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skipf("disk space statistics not supported on %s", runtime.GOOS)
+		}
+		var resp query.Response
+		_, err := cli.Send(ctx, &resp, &query.Request{Query: query.Space, Args: []byte("/")})
+		if err != nil {
+			t.Fatalf("could not query space: %v", err)
+		}
+		vs, err := url.ParseQuery(string(resp.Data))
+		if err != nil {
+			t.Fatalf("could not parse space response %q: %v", resp.Data, err)
+		}
+		for _, key := range []string{"oss.space", "oss.free", "oss.used"} {
+			if _, err := strconv.ParseInt(vs.Get(key), 10, 64); err != nil {
+				t.Fatalf("invalid %s in %q: %v", key, resp.Data, err)
+			}
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		var resp query.Response
+		_, err := cli.Send(ctx, &resp, &query.Request{Query: query.Stats, Args: []byte("a")})
+		if err != nil {
+			t.Fatalf("could not query stats: %v", err)
+		}
+		got := string(resp.Data)
+		if !strings.HasPrefix(got, "<statistics ") || !strings.Contains(got, `src="`+addr+`"`) {
+			t.Fatalf("invalid stats: %q", got)
+		}
+		if !strings.Contains(got, `<stats id="link"><num>1</num>`) {
+			t.Fatalf("invalid number of links in stats: %q", got)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := cli.Send(ctx, nil, &query.Request{Query: query.Visa})
+		var serr xrdproto.ServerError
+		if !errors.As(err, &serr) || serr.Code != xrdproto.InvalidRequest {
+			t.Fatalf("invalid error: %v", err)
+		}
+	})
+}
+
+func TestHandler_Prepare(t *testing.T) {
+	srv, addr, baseDir, err := createServer(func(err error) {
+		t.Error(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	defer func() {
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	for _, name := range []string{"file1.txt", "file2.txt"} {
+		err = os.WriteFile(path.Join(baseDir, name), []byte("data"), 0644)
+		if err != nil {
+			t.Fatalf("could not create test file: %v", err)
+		}
+	}
+
+	cli, err := createClient(addr)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	for _, tc := range []struct {
+		name    string
+		options byte
+		paths   []string
+		reqid   bool
+		errCode xrdproto.ServerErrorCode
+	}{
+		{name: "refresh", options: prepare.Refresh, paths: []string{"file1.txt", "file2.txt"}},
+		{name: "stage", options: prepare.Stage, paths: []string{"file1.txt", "file2.txt"}, reqid: true},
+		{name: "cancel", options: prepare.Cancel, paths: []string{"1234"}},
+		{name: "missing", options: prepare.Stage, paths: []string{"file1.txt", "missing.txt"}, errCode: xrdproto.NotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var resp prepare.Response
+			_, err := cli.Send(context.Background(), &resp, &prepare.Request{Options: tc.options, Paths: tc.paths})
+			if tc.errCode != 0 {
+				var serr xrdproto.ServerError
+				if !errors.As(err, &serr) || serr.Code != tc.errCode {
+					t.Fatalf("invalid error: got=%v, want code=%v", err, tc.errCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not prepare files: %v", err)
+			}
+			if got := len(resp.Data) != 0; got != tc.reqid {
+				t.Fatalf("invalid request ID: %q", resp.Data)
+			}
+		})
+	}
+}
+
+func TestHandler_Chmod(t *testing.T) {
+	srv, addr, baseDir, err := createServer(func(err error) {
+		t.Error(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	defer func() {
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	name := path.Join(baseDir, "file1.txt")
+	err = os.WriteFile(name, []byte("data"), 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	cli, err := createClient(addr)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	mode := xrdfs.OpenModeOwnerRead | xrdfs.OpenModeOwnerWrite | xrdfs.OpenModeGroupRead
+	err = cli.FS().Chmod(context.Background(), "file1.txt", mode)
+	if err != nil {
+		t.Fatalf("could not chmod file: %v", err)
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatalf("could not stat file: %v", err)
+	}
+	if got, want := fi.Mode().Perm(), os.FileMode(0640); got != want {
+		t.Fatalf("invalid permissions: got=%v, want=%v", got, want)
+	}
+
+	err = cli.FS().Chmod(context.Background(), "missing.txt", mode)
+	var serr xrdproto.ServerError
+	if !errors.As(err, &serr) || serr.Code != xrdproto.NotFound {
+		t.Fatalf("invalid error for missing file: %v", err)
+	}
+}
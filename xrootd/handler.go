@@ -6,14 +6,21 @@ package xrootd // import "go-hep.org/x/hep/xrootd"
 
 import (
 	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/chmod"
 	"go-hep.org/x/hep/xrootd/xrdproto/dirlist"
+	"go-hep.org/x/hep/xrootd/xrdproto/locate"
 	"go-hep.org/x/hep/xrootd/xrdproto/login"
 	"go-hep.org/x/hep/xrootd/xrdproto/mkdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/mv"
 	"go-hep.org/x/hep/xrootd/xrdproto/open"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgread"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgwrite"
 	"go-hep.org/x/hep/xrootd/xrdproto/ping"
+	"go-hep.org/x/hep/xrootd/xrdproto/prepare"
 	"go-hep.org/x/hep/xrootd/xrdproto/protocol"
+	"go-hep.org/x/hep/xrootd/xrdproto/query"
 	"go-hep.org/x/hep/xrootd/xrdproto/read"
+	"go-hep.org/x/hep/xrootd/xrdproto/readv"
 	"go-hep.org/x/hep/xrootd/xrdproto/rm"
 	"go-hep.org/x/hep/xrootd/xrdproto/rmdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/stat"
@@ -23,6 +30,16 @@ import (
 	"go-hep.org/x/hep/xrootd/xrdproto/xrdclose"
 )
 
+// SessionInfo describes the connection and the user of a session.
+type SessionInfo struct {
+	LocalAddr  string // LocalAddr is the local address of the connection of the session.
+	RemoteAddr string // RemoteAddr is the address of the client.
+
+	TLS      bool   // TLS reports whether the connection is TLS-encrypted.
+	Provider string // Provider is the security provider the user was authenticated with, if any.
+	Identity string // Identity is the name of the authenticated user, if any.
+}
+
 // Handler provides a high-level API for the XRootD server.
 // The Handler receives a parsed request and returns a response together with the status
 // that will be send via Server to the client.
@@ -45,18 +62,44 @@ type Handler interface {
 	// CloseSession handles the aborting of user session. This can be used to free some user-related data.
 	CloseSession(sessionID [16]byte) error
 
+	// SetSessionInfo handles the update of the information about a user session:
+	// it is called once the connection was accepted and whenever the information
+	// changes, e.g. once the connection was switched to TLS or the user was authenticated.
+	SetSessionInfo(sessionID [16]byte, info SessionInfo)
+
 	// Open handles the XRootD open request: http://xrootd.org/doc/dev45/XRdv310.htm#_Toc464248823.
 	Open(sessionID [16]byte, request *open.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
 
 	// Close handles the XRootD close request: http://xrootd.org/doc/dev45/XRdv310.htm#_Toc464248813.
 	Close(sessionID [16]byte, request *xrdclose.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
 
+	// Locate handles the XRootD locate request: http://xrootd.org/doc/dev45/XRdv310.htm#_Toc464248817.
+	Locate(sessionID [16]byte, request *locate.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
+
+	// Query handles the XRootD query request: http://xrootd.org/doc/dev45/XRdv310.htm#_Toc464248832.
+	Query(sessionID [16]byte, request *query.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
+
+	// Prepare handles the XRootD prepare request: http://xrootd.org/doc/dev45/XRdv310.pdf, p. 69.
+	Prepare(sessionID [16]byte, request *prepare.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
+
+	// Chmod handles the XRootD chmod request: http://xrootd.org/doc/dev45/XRdv310.pdf, p. 106.
+	Chmod(sessionID [16]byte, request *chmod.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
+
 	// Read handles the XRootD read request: http://xrootd.org/doc/dev45/XRdv310.htm#_Toc464248841.
 	Read(sessionID [16]byte, request *read.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
 
 	// Write handles the XRootD write request: http://xrootd.org/doc/dev45/XRdv310.htm#_Toc464248855.
 	Write(sessionID [16]byte, request *write.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
 
+	// ReadV handles the XRootD readv request: http://xrootd.org/doc/dev45/XRdv310.htm#_Toc464248842.
+	ReadV(sessionID [16]byte, request *readv.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
+
+	// PgRead handles the XRootD pgread request: https://xrootd.slac.stanford.edu/doc/dev50/XRdv500.htm#_Toc45172178.
+	PgRead(sessionID [16]byte, request *pgread.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
+
+	// PgWrite handles the XRootD pgwrite request: https://xrootd.slac.stanford.edu/doc/dev50/XRdv500.htm#_Toc45172183.
+	PgWrite(sessionID [16]byte, request *pgwrite.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
+
 	// Stat handles the XRootD stat request: http://xrootd.org/doc/dev45/XRdv310.htm#_Toc464248850.
 	Stat(sessionID [16]byte, request *stat.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus)
 
@@ -0,0 +1,582 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/chmod"
+	"go-hep.org/x/hep/xrootd/xrdproto/handshake"
+	"go-hep.org/x/hep/xrootd/xrdproto/locate"
+	"go-hep.org/x/hep/xrootd/xrdproto/login"
+	"go-hep.org/x/hep/xrootd/xrdproto/open"
+	"go-hep.org/x/hep/xrootd/xrdproto/protocol"
+	"go-hep.org/x/hep/xrootd/xrdproto/query"
+	"go-hep.org/x/hep/xrootd/xrdproto/stat"
+)
+
+const (
+	registerKey = "xrd.register" // registerKey is the login token key used by data servers to register with a redirector.
+	secretKey   = "xrd.secret"   // secretKey is the login token key holding the registration secret.
+)
+
+const (
+	// locateTimeout is the maximum duration of the lookup of a file on the data servers.
+	locateTimeout = 10 * time.Second
+
+	// locationTTL is the duration during which the locations of a file are cached.
+	locationTTL = 30 * time.Second
+
+	// maxLocations is the maximum number of files whose locations are cached.
+	maxLocations = 4096
+
+	// loadHalfLife is the half-life of the redirections accounted in the load of a data server.
+	loadHalfLife = time.Minute
+)
+
+// Redirector implements Handler as a XRootD redirector (a.k.a. manager)
+// for a set of data servers.
+//
+// The locate, open, stat, chmod and checksum query requests are answered with a redirection to one of
+// the data servers holding the requested file, looked up by issuing stat requests
+// to all the registered data servers.
+// The locations of a file are then cached for a short while.
+// Files opened for creation are redirected to any data server if none holds the file.
+// Among the eligible data servers, the least loaded one is chosen, the load of a data
+// server being the number of redirections recently issued to it.
+//
+// Data servers are registered with the Register method or, remotely, with the
+// RegisterServer function. Remote registrations are refused unless the redirector
+// requires a shared secret or restricts the data servers allowed to register
+// (see WithRegistrationSecret and WithRegistrationAllowList).
+type Redirector struct {
+	Handler
+
+	secret  string              // secret is the shared secret required to register remotely, if any.
+	allowed map[string]struct{} // allowed are the hosts and addresses allowed to register remotely, if any.
+
+	mu       sync.RWMutex
+	servers  map[string]*dataServer // servers are the registered data servers, by address.
+	sessions map[[16]byte][]string  // sessions are the addresses of the data servers registered by each session.
+
+	cacheMu   sync.Mutex
+	locations map[string]location // locations are the cached locations of the files, by name.
+}
+
+// RedirectorOption configures a Redirector.
+type RedirectorOption func(*Redirector)
+
+// WithRegistrationSecret requires the data servers registering remotely with
+// the redirector to supply secret (see RegisterServer).
+// Since the secret is sent as is, the connections should be TLS-encrypted.
+func WithRegistrationSecret(secret string) RedirectorOption {
+	return func(r *Redirector) {
+		r.secret = secret
+	}
+}
+
+// WithRegistrationAllowList restricts the data servers allowed to register
+// remotely with the redirector to the ones listening on addrs.
+// Each address is either in the "host:port" form or a host name, allowing
+// any port of that host.
+func WithRegistrationAllowList(addrs ...string) RedirectorOption {
+	return func(r *Redirector) {
+		if r.allowed == nil {
+			r.allowed = make(map[string]struct{}, len(addrs))
+		}
+		for _, addr := range addrs {
+			r.allowed[addr] = struct{}{}
+		}
+	}
+}
+
+// location holds the cached locations of a file.
+type location struct {
+	addrs   []string  // addrs are the addresses of the data servers holding the file.
+	expires time.Time // expires is the expiration time of the cached locations.
+}
+
+// dataServer is a data server registered with a redirector.
+type dataServer struct {
+	addr string
+	host string
+	port int32
+	refs int // refs is the number of registrations of the data server.
+
+	loadMu sync.Mutex
+	load   float64   // load is the number of redirections issued to the data server, decayed with loadHalfLife.
+	loaded time.Time // loaded is the time of the last update of load.
+
+	mu     sync.Mutex
+	client *Client // client is used to look up files on the data server.
+}
+
+// NewRedirector creates a Redirector without any registered data server.
+// Options opts configure the redirector and are applied in the order they were specified.
+func NewRedirector(opts ...RedirectorOption) *Redirector {
+	r := &Redirector{
+		Handler:   Default(),
+		servers:   make(map[string]*dataServer),
+		sessions:  make(map[[16]byte][]string),
+		locations: make(map[string]location),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(r)
+	}
+	return r
+}
+
+// RegisterServer registers the data server listening on addr with the redirector
+// at the provided address, supplying the registration secret of the redirector, if any,
+// and using the options opts to connect to the redirector.
+// The data server stays registered until the returned client is closed.
+func RegisterServer(ctx context.Context, redirector, addr, secret string, opts ...Option) (*Client, error) {
+	if _, _, err := splitAddr(addr); err != nil {
+		return nil, err
+	}
+	token := url.Values{registerKey: []string{addr}}
+	if secret != "" {
+		token.Set(secretKey, secret)
+	}
+	return newClient(ctx, redirector, "xrd-srv", token.Encode(), opts...)
+}
+
+// Register registers the data server listening on addr, in the "host:port" form.
+// A data server registered several times stays registered until it is
+// unregistered as many times.
+func (r *Redirector) Register(addr string) error {
+	host, port, err := splitAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	srv, ok := r.servers[addr]
+	if !ok {
+		srv = &dataServer{addr: addr, host: host, port: port}
+		r.servers[addr] = srv
+	}
+	srv.refs++
+	return nil
+}
+
+// Unregister unregisters the data server listening on addr.
+func (r *Redirector) Unregister(addr string) {
+	r.mu.Lock()
+	srv, ok := r.servers[addr]
+	if ok {
+		srv.refs--
+		if srv.refs > 0 {
+			ok = false
+		} else {
+			delete(r.servers, addr)
+		}
+	}
+	r.mu.Unlock()
+
+	if ok {
+		_ = srv.close()
+	}
+}
+
+// Servers returns the addresses of the registered data servers, sorted in increasing order.
+func (r *Redirector) Servers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	addrs := make([]string, 0, len(r.servers))
+	for addr := range r.servers {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Disconnect closes the connections to the data servers.
+// The connections are reopened as needed by subsequent requests.
+func (r *Redirector) Disconnect() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var errs []error
+	for _, srv := range r.servers {
+		if err := srv.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("xrootd: could not disconnect redirector: %v", errs)
+	}
+	return nil
+}
+
+// Handshake implements Handler.Handshake.
+func (*Redirector) Handshake() (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := handshake.Response{ProtocolVersion: 0x310, ServerType: xrdproto.LoadBalancingServer}
+	return &resp, xrdproto.Ok
+}
+
+// Protocol implements Handler.Protocol.
+func (*Redirector) Protocol(sessionID [16]byte, request *protocol.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	resp := &protocol.Response{BinaryProtocolVersion: 0x310, Flags: protocol.IsManager}
+	return resp, xrdproto.Ok
+}
+
+// Login implements Handler.Login.
+// Data servers register with the redirector by supplying their address, and the
+// registration secret, in the login token.
+func (r *Redirector) Login(sessionID [16]byte, request *login.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	token, err := url.ParseQuery(strings.TrimLeft(string(request.Token), "&"))
+	if addr := token.Get(registerKey); err == nil && addr != "" {
+		if err := r.authorize(addr, token.Get(secretKey)); err != nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.NotAuthorized,
+				Message: fmt.Sprintf("Could not register data server: %v", err),
+			}, xrdproto.Error
+		}
+		if err := r.Register(addr); err != nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.InvalidRequest,
+				Message: fmt.Sprintf("Could not register data server: %v", err),
+			}, xrdproto.Error
+		}
+		r.mu.Lock()
+		r.sessions[sessionID] = append(r.sessions[sessionID], addr)
+		r.mu.Unlock()
+	}
+	return r.Handler.Login(sessionID, request)
+}
+
+// authorize checks whether the data server listening on addr may register
+// remotely, supplying secret.
+func (r *Redirector) authorize(addr, secret string) error {
+	if r.secret == "" && r.allowed == nil {
+		return errors.New("xrootd: remote registration is disabled")
+	}
+	if r.secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(r.secret)) != 1 {
+		return errors.New("xrootd: invalid registration secret")
+	}
+	if r.allowed != nil {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("xrootd: invalid data server address %q: %w", addr, err)
+		}
+		_, okAddr := r.allowed[addr]
+		_, okHost := r.allowed[host]
+		if !okAddr && !okHost {
+			return fmt.Errorf("xrootd: data server %q is not allowed to register", addr)
+		}
+	}
+	return nil
+}
+
+// CloseSession implements Handler.CloseSession.
+// The data servers registered by the session are unregistered.
+func (r *Redirector) CloseSession(sessionID [16]byte) error {
+	r.mu.Lock()
+	addrs := r.sessions[sessionID]
+	delete(r.sessions, sessionID)
+	r.mu.Unlock()
+
+	for _, addr := range addrs {
+		r.Unregister(addr)
+	}
+	return r.Handler.CloseSession(sessionID)
+}
+
+// Locate implements Handler.Locate.
+func (r *Redirector) Locate(sessionID [16]byte, request *locate.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	name, _, _ := strings.Cut(request.Path, "?")
+	servers := r.locate(name)
+	if len(servers) == 0 {
+		return errNotLocated(name)
+	}
+
+	locations := make([]string, len(servers))
+	for i, srv := range servers {
+		locations[i] = "Sw" + srv.addr
+	}
+	return &locate.Response{Data: []byte(strings.Join(locations, " "))}, xrdproto.Ok
+}
+
+// Open implements Handler.Open.
+func (r *Redirector) Open(sessionID [16]byte, request *open.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	name, opaque, _ := strings.Cut(request.Path, "?")
+	servers := r.locate(name)
+	if len(servers) == 0 && request.Options&(xrdfs.OpenOptionsNew|xrdfs.OpenOptionsDelete) != 0 {
+		servers = r.list()
+	}
+	if len(servers) == 0 {
+		return errNotLocated(name)
+	}
+	return r.redirect(servers, opaque)
+}
+
+// Query implements Handler.Query.
+// Checksum queries are redirected to a data server holding the file.
+func (r *Redirector) Query(sessionID [16]byte, request *query.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	if request.Query != query.Checksum {
+		return r.Handler.Query(sessionID, request)
+	}
+	name, _, _ := strings.Cut(strings.TrimRight(string(request.Args), "\x00"), "?")
+	servers := r.locate(name)
+	if len(servers) == 0 {
+		return errNotLocated(name)
+	}
+	return r.redirect(servers, "")
+}
+
+// Stat implements Handler.Stat.
+func (r *Redirector) Stat(sessionID [16]byte, request *stat.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	if len(request.Path) == 0 {
+		return r.Handler.Stat(sessionID, request)
+	}
+	name, opaque, _ := strings.Cut(request.Path, "?")
+	servers := r.locate(name)
+	if len(servers) == 0 {
+		return errNotLocated(name)
+	}
+	return r.redirect(servers, opaque)
+}
+
+// Chmod implements Handler.Chmod.
+func (r *Redirector) Chmod(sessionID [16]byte, request *chmod.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	name, opaque, _ := strings.Cut(request.Path, "?")
+	servers := r.locate(name)
+	if len(servers) == 0 {
+		return errNotLocated(name)
+	}
+	return r.redirect(servers, opaque)
+}
+
+// redirect redirects the client to the least loaded data server among servers.
+// The opaque data of the request is passed along, since clients replace the
+// opaque data of the re-issued request with the one of the redirection.
+func (r *Redirector) redirect(servers []*dataServer, opaque string) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	srv := servers[0]
+	srv.addLoad(time.Now())
+	return xrdproto.RedirectResponse{Host: srv.host, Port: srv.port, Opaque: opaque}, xrdproto.Redirect
+}
+
+// list returns the registered data servers, sorted by increasing load.
+func (r *Redirector) list() []*dataServer {
+	r.mu.RLock()
+	servers := make([]*dataServer, 0, len(r.servers))
+	for _, srv := range r.servers {
+		servers = append(servers, srv)
+	}
+	r.mu.RUnlock()
+
+	var (
+		now   = time.Now()
+		loads = make(map[*dataServer]float64, len(servers))
+	)
+	for _, srv := range servers {
+		loads[srv] = srv.currentLoad(now)
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		li, lj := loads[servers[i]], loads[servers[j]]
+		if li != lj {
+			return li < lj
+		}
+		return servers[i].addr < servers[j].addr
+	})
+	return servers
+}
+
+// locate returns the data servers holding the named file, sorted by increasing load.
+// The locations of the file are looked up on all the data servers, unless they are cached.
+func (r *Redirector) locate(name string) []*dataServer {
+	servers := r.list()
+	now := time.Now()
+	if addrs, ok := r.cached(name, now); ok {
+		located := make([]*dataServer, 0, len(addrs))
+		for _, srv := range servers {
+			if slices.Contains(addrs, srv.addr) {
+				located = append(located, srv)
+			}
+		}
+		if len(located) > 0 {
+			return located
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), locateTimeout)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		found = make([]bool, len(servers))
+	)
+	for i, srv := range servers {
+		wg.Add(1)
+		go func(i int, srv *dataServer) {
+			defer wg.Done()
+			found[i] = srv.has(ctx, name)
+		}(i, srv)
+	}
+	wg.Wait()
+
+	located := servers[:0]
+	for i, srv := range servers {
+		if found[i] {
+			located = append(located, srv)
+		}
+	}
+	r.cache(name, located, now)
+	return located
+}
+
+// cached returns the cached addresses of the data servers holding the named file, at time now.
+func (r *Redirector) cached(name string, now time.Time) ([]string, bool) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	loc, ok := r.locations[name]
+	if !ok || now.After(loc.expires) {
+		return nil, false
+	}
+	return loc.addrs, true
+}
+
+// cache caches the data servers holding the named file, looked up at time now.
+// Files without any location are not cached, since they may be created
+// on a data server at any time.
+func (r *Redirector) cache(name string, servers []*dataServer, now time.Time) {
+	if len(servers) == 0 {
+		return
+	}
+	addrs := make([]string, len(servers))
+	for i, srv := range servers {
+		addrs[i] = srv.addr
+	}
+
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if len(r.locations) >= maxLocations {
+		for k, loc := range r.locations {
+			if now.After(loc.expires) {
+				delete(r.locations, k)
+			}
+		}
+		if len(r.locations) >= maxLocations {
+			clear(r.locations)
+		}
+	}
+	r.locations[name] = location{addrs: addrs, expires: now.Add(locationTTL)}
+}
+
+// addLoad accounts for a redirection to the data server issued at time now.
+func (srv *dataServer) addLoad(now time.Time) {
+	srv.loadMu.Lock()
+	defer srv.loadMu.Unlock()
+	srv.decay(now)
+	srv.load++
+}
+
+// currentLoad returns the load of the data server at time now.
+func (srv *dataServer) currentLoad(now time.Time) float64 {
+	srv.loadMu.Lock()
+	defer srv.loadMu.Unlock()
+	srv.decay(now)
+	return srv.load
+}
+
+// decay decays the load of the data server up to time now.
+func (srv *dataServer) decay(now time.Time) {
+	dt := now.Sub(srv.loaded)
+	if dt <= 0 {
+		return
+	}
+	srv.load *= math.Exp2(-dt.Seconds() / loadHalfLife.Seconds())
+	srv.loaded = now
+}
+
+// has returns whether the data server holds the named file.
+func (srv *dataServer) has(ctx context.Context, name string) bool {
+	srv.mu.Lock()
+	client := srv.client
+	if client == nil {
+		var err error
+		// The client outlives the lookup, hence the background context.
+		client, err = NewClient(context.Background(), srv.addr, "xrootd")
+		if err != nil {
+			srv.mu.Unlock()
+			return false
+		}
+		srv.client = client
+	}
+	srv.mu.Unlock()
+
+	_, err := client.FS().Stat(ctx, name)
+	if err == nil {
+		return true
+	}
+
+	var serr xrdproto.ServerError
+	if !errors.As(err, &serr) {
+		// The connection to the data server is broken: reconnect for the next lookup.
+		srv.mu.Lock()
+		if srv.client == client {
+			srv.client = nil
+		}
+		srv.mu.Unlock()
+		_ = client.Close()
+	}
+	return false
+}
+
+// close closes the connection to the data server, if any.
+func (srv *dataServer) close() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.client == nil {
+		return nil
+	}
+	err := srv.client.Close()
+	srv.client = nil
+	return err
+}
+
+// splitAddr splits the "host:port" address of a data server into the host,
+// as sent in redirect responses, and the port.
+func splitAddr(addr string) (string, int32, error) {
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, fmt.Errorf("xrootd: invalid data server address %q: %w", addr, err)
+	}
+	port, err := strconv.ParseUint(p, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("xrootd: invalid data server port %q: %w", addr, err)
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return host, int32(port), nil
+}
+
+func errNotLocated(name string) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	return xrdproto.ServerError{
+		Code:    xrdproto.NotFound,
+		Message: fmt.Sprintf("No data server holds %q", name),
+	}, xrdproto.Error
+}
+
+var _ Handler = (*Redirector)(nil)
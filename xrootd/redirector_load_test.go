@@ -0,0 +1,40 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDataServerLoad(t *testing.T) {
+	var (
+		srv dataServer
+		t0  = time.Now()
+	)
+	for range 4 {
+		srv.addLoad(t0)
+	}
+
+	for _, tc := range []struct {
+		dt   time.Duration
+		want float64
+	}{
+		{0, 4},
+		{loadHalfLife, 2},
+		{2 * loadHalfLife, 1},
+		{time.Hour, 4 * math.Exp2(-60)},
+	} {
+		if got := srv.currentLoad(t0.Add(tc.dt)); math.Abs(got-tc.want) > 1e-12 {
+			t.Fatalf("invalid load after %v: got=%v, want=%v", tc.dt, got, tc.want)
+		}
+	}
+
+	// the load does not go back in time.
+	if got, want := srv.currentLoad(t0), 4*math.Exp2(-60); math.Abs(got-want) > 1e-12 {
+		t.Fatalf("invalid load: got=%v, want=%v", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd_test // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/locate"
+)
+
+func serveHandler(t *testing.T, handler xrootd.Handler) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	srv := xrootd.NewServer(handler, func(err error) { t.Error(err) })
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != xrootd.ErrServerClosed {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = srv.Shutdown(context.Background())
+	})
+
+	return listener.Addr().String()
+}
+
+func TestRedirector(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		dirs  = []string{t.TempDir(), t.TempDir()}
+		addrs = make([]string, len(dirs))
+	)
+	for i, dir := range dirs {
+		name := path.Join(dir, "file"+string(rune('1'+i))+".txt")
+		err := os.WriteFile(name, []byte("data from "+name), 0644)
+		if err != nil {
+			t.Fatalf("could not create test file: %v", err)
+		}
+		err = os.WriteFile(path.Join(dir, "shared.txt"), []byte("shared"), 0644)
+		if err != nil {
+			t.Fatalf("could not create test file: %v", err)
+		}
+		addrs[i] = serveHandler(t, xrootd.NewFSHandler(dir))
+	}
+
+	const secret = "s3cr3t"
+	redir := xrootd.NewRedirector(xrootd.WithRegistrationSecret(secret))
+	defer redir.Disconnect()
+	addr := serveHandler(t, redir)
+
+	// Register the first data server locally and the second one remotely.
+	err := redir.Register(addrs[0])
+	if err != nil {
+		t.Fatalf("could not register data server: %v", err)
+	}
+	reg, err := xrootd.RegisterServer(ctx, addr, addrs[1], secret)
+	if err != nil {
+		t.Fatalf("could not register data server: %v", err)
+	}
+
+	want := append([]string(nil), addrs...)
+	sort.Strings(want)
+	if got := redir.Servers(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid servers:\ngot = %v\nwant= %v", got, want)
+	}
+
+	cli, err := xrootd.NewClient(ctx, addr, "gopher")
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+	fs := cli.FS()
+
+	for i, dir := range dirs {
+		name := "file" + string(rune('1'+i)) + ".txt"
+		f, err := fs.Open(ctx, name, xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+		if err != nil {
+			t.Fatalf("could not open %q: %v", name, err)
+		}
+		want := []byte("data from " + path.Join(dir, name))
+		got := make([]byte, len(want))
+		_, err = f.ReadAt(got, 0)
+		f.Close(ctx)
+		if err != nil {
+			t.Fatalf("could not read %q: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("invalid data:\ngot = %q\nwant= %q", got, want)
+		}
+
+		var resp locate.Response
+		_, err = cli.Send(ctx, &resp, &locate.Request{Path: name})
+		if err != nil {
+			t.Fatalf("could not locate %q: %v", name, err)
+		}
+		if got, want := string(resp.Data), "Sw"+addrs[i]; got != want {
+			t.Fatalf("invalid location of %q: got=%q, want=%q", name, got, want)
+		}
+	}
+
+	var resp locate.Response
+	_, err = cli.Send(ctx, &resp, &locate.Request{Path: "shared.txt"})
+	if err != nil {
+		t.Fatalf("could not locate shared file: %v", err)
+	}
+	if got := strings.Fields(string(resp.Data)); len(got) != 2 {
+		t.Fatalf("invalid locations of shared file: %q", resp.Data)
+	}
+
+	// The locations of a file are cached.
+	err = os.Remove(path.Join(dirs[0], "shared.txt"))
+	if err != nil {
+		t.Fatalf("could not remove shared file: %v", err)
+	}
+	_, err = cli.Send(ctx, &resp, &locate.Request{Path: "shared.txt"})
+	if err != nil {
+		t.Fatalf("could not locate shared file: %v", err)
+	}
+	if got := strings.Fields(string(resp.Data)); len(got) != 2 {
+		t.Fatalf("invalid cached locations of shared file: %q", resp.Data)
+	}
+
+	_, err = fs.Open(ctx, "missing.txt", xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	var serr xrdproto.ServerError
+	if !errors.As(err, &serr) || serr.Code != xrdproto.NotFound {
+		t.Fatalf("invalid error opening missing file: %v", err)
+	}
+
+	f, err := fs.Open(ctx, "new.txt", xrdfs.OpenModeOwnerRead|xrdfs.OpenModeOwnerWrite, xrdfs.OpenOptionsNew)
+	if err != nil {
+		t.Fatalf("could not create file: %v", err)
+	}
+	f.Close(ctx)
+
+	n := 0
+	for _, dir := range dirs {
+		if _, err := os.Stat(path.Join(dir, "new.txt")); err == nil {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Fatalf("new file created on %d data servers, want 1", n)
+	}
+
+	if _, err := fs.Stat(ctx, "new.txt"); err != nil {
+		t.Fatalf("could not stat new file: %v", err)
+	}
+
+	// Closing the registration unregisters the data server.
+	reg.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(redir.Servers()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, want := redir.Servers(), []string{addrs[0]}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid servers after unregistration:\ngot = %v\nwant= %v", got, want)
+	}
+
+	redir.Unregister(addrs[0])
+	_, err = fs.Open(ctx, "file1.txt", xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	if err == nil {
+		t.Fatalf("expected an error without data servers")
+	}
+}
+
+func TestRedirectorRegistration(t *testing.T) {
+	ctx := context.Background()
+	dsrv := serveHandler(t, xrootd.NewFSHandler(t.TempDir()))
+	_, port, err := net.SplitHostPort(dsrv)
+	if err != nil {
+		t.Fatalf("could not parse address: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		opts   []xrootd.RedirectorOption
+		secret string
+		want   string
+	}{
+		{
+			name: "disabled",
+			want: "remote registration is disabled",
+		},
+		{
+			name:   "secret",
+			opts:   []xrootd.RedirectorOption{xrootd.WithRegistrationSecret("s3cr3t")},
+			secret: "s3cr3t",
+		},
+		{
+			name:   "invalid-secret",
+			opts:   []xrootd.RedirectorOption{xrootd.WithRegistrationSecret("s3cr3t")},
+			secret: "guess",
+			want:   "invalid registration secret",
+		},
+		{
+			name: "allowed-host",
+			opts: []xrootd.RedirectorOption{xrootd.WithRegistrationAllowList("127.0.0.1")},
+		},
+		{
+			name: "allowed-addr",
+			opts: []xrootd.RedirectorOption{xrootd.WithRegistrationAllowList("localhost", dsrv)},
+		},
+		{
+			name: "not-allowed",
+			opts: []xrootd.RedirectorOption{xrootd.WithRegistrationAllowList("localhost", "127.0.0.1:"+port+"0")},
+			want: "is not allowed to register",
+		},
+		{
+			name: "allowed-without-secret",
+			opts: []xrootd.RedirectorOption{
+				xrootd.WithRegistrationSecret("s3cr3t"),
+				xrootd.WithRegistrationAllowList("127.0.0.1"),
+			},
+			want: "invalid registration secret",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			redir := xrootd.NewRedirector(tc.opts...)
+			defer redir.Disconnect()
+			addr := serveHandler(t, redir)
+
+			reg, err := xrootd.RegisterServer(ctx, addr, dsrv, tc.secret)
+			if tc.want != "" {
+				if err == nil {
+					reg.Close()
+					t.Fatalf("expected an error")
+				}
+				if !strings.Contains(err.Error(), tc.want) {
+					t.Fatalf("invalid error:\ngot = %v\nwant= %v", err, tc.want)
+				}
+				if got := redir.Servers(); len(got) != 0 {
+					t.Fatalf("data server registered: %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not register data server: %v", err)
+			}
+			defer reg.Close()
+
+			if got, want := redir.Servers(), []string{dsrv}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid servers:\ngot = %v\nwant= %v", got, want)
+			}
+		})
+	}
+}
@@ -7,24 +7,35 @@ package xrootd // import "go-hep.org/x/hep/xrootd"
 import (
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"go-hep.org/x/hep/xrootd/internal/xrdenc"
 	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/auth"
+	"go-hep.org/x/hep/xrootd/xrdproto/chmod"
 	"go-hep.org/x/hep/xrootd/xrdproto/dirlist"
 	"go-hep.org/x/hep/xrootd/xrdproto/handshake"
+	"go-hep.org/x/hep/xrootd/xrdproto/locate"
 	"go-hep.org/x/hep/xrootd/xrdproto/login"
 	"go-hep.org/x/hep/xrootd/xrdproto/mkdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/mv"
 	"go-hep.org/x/hep/xrootd/xrdproto/open"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgread"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgwrite"
 	"go-hep.org/x/hep/xrootd/xrdproto/ping"
+	"go-hep.org/x/hep/xrootd/xrdproto/prepare"
 	"go-hep.org/x/hep/xrootd/xrdproto/protocol"
+	"go-hep.org/x/hep/xrootd/xrdproto/query"
 	"go-hep.org/x/hep/xrootd/xrdproto/read"
+	"go-hep.org/x/hep/xrootd/xrdproto/readv"
 	"go-hep.org/x/hep/xrootd/xrdproto/rm"
 	"go-hep.org/x/hep/xrootd/xrdproto/rmdir"
 	"go-hep.org/x/hep/xrootd/xrdproto/stat"
@@ -48,6 +59,8 @@ type ErrorHandler func(error)
 type Server struct {
 	handler      Handler
 	errorHandler ErrorHandler
+	tlsConfig    *tls.Config
+	verifiers    []auth.Verifier
 
 	mu        sync.Mutex
 	listeners []net.Listener
@@ -59,18 +72,57 @@ type Server struct {
 	activeConn map[net.Conn]struct{}
 }
 
+// ServerOption configures an XRootD server.
+type ServerOption func(*Server)
+
+// WithVerifier adds a security provider verifying the credentials of the clients.
+// Once at least one security provider is registered, the clients are required
+// to authenticate with one of them after the login, before issuing any other request.
+// If a security provider was already registered with the same name,
+// it will be silently replaced.
+func WithVerifier(v auth.Verifier) ServerOption {
+	return func(srv *Server) {
+		for i, vv := range srv.verifiers {
+			if vv.Provider() == v.Provider() {
+				srv.verifiers[i] = v
+				return
+			}
+		}
+		srv.verifiers = append(srv.verifiers, v)
+	}
+}
+
 // NewServer creates a XRootD server which uses specified handler to handle requests
 // and errorHandler to handle errors. If the errorHandler is nil,
 // then a default error handler is used that does nothing.
-func NewServer(handler Handler, errorHandler ErrorHandler) *Server {
+// Options opts configure the server and are applied in the order they were specified.
+func NewServer(handler Handler, errorHandler ErrorHandler, opts ...ServerOption) *Server {
 	if errorHandler == nil {
 		errorHandler = func(error) {}
 	}
-	return &Server{
+	srv := &Server{
 		handler:      handler,
 		errorHandler: errorHandler,
 		activeConn:   make(map[net.Conn]struct{}),
 	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(srv)
+	}
+	return srv
+}
+
+// NewTLSServer creates a XRootD server which uses specified handler to handle requests
+// and errorHandler to handle errors, like NewServer.
+// The connections to the server are required to be switched to TLS, using config,
+// right after the protocol request: any other request is rejected until then.
+// The config must contain at least one certificate or set GetCertificate.
+func NewTLSServer(handler Handler, errorHandler ErrorHandler, config *tls.Config, opts ...ServerOption) *Server {
+	srv := NewServer(handler, errorHandler, opts...)
+	srv.tlsConfig = config
+	return srv
 }
 
 // Shutdown stops Server and closes all listeners and active connections.
@@ -127,6 +179,23 @@ func (s *Server) Serve(l net.Listener) error {
 	}
 }
 
+// srvConn is the state of a client connection, shared by the goroutines
+// handling its requests.
+type srvConn struct {
+	authenticated atomic.Bool
+
+	mu   sync.Mutex
+	info SessionInfo
+}
+
+// update applies fn to the information about the session and returns the updated information.
+func (sess *srvConn) update(fn func(info *SessionInfo)) SessionInfo {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	fn(&sess.info)
+	return sess.info
+}
+
 // handleConnection handles the client connection.
 // handleConnection reads the handshake and checks it correctness.
 // In case of success, main loop is started that reads requests and
@@ -143,6 +212,12 @@ func (s *Server) handleConnection(conn net.Conn) {
 	if _, err := rand.Read(sessionID[:]); err != nil {
 		s.errorHandler(fmt.Errorf("could not read session ID: %w", err))
 	}
+	var sess srvConn
+	sess.authenticated.Store(len(s.verifiers) == 0)
+	s.handler.SetSessionInfo(sessionID, sess.update(func(info *SessionInfo) {
+		info.LocalAddr = conn.LocalAddr().String()
+		info.RemoteAddr = conn.RemoteAddr().String()
+	}))
 	defer func() {
 		if err := s.handler.CloseSession(sessionID); err != nil {
 			s.errorHandler(fmt.Errorf("could not close session ID %q: %w", sessionID, err))
@@ -155,6 +230,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 		return
 	}
 
+	secure := s.tlsConfig == nil
 	for {
 		// We are using conn for read access only in that place
 		// and only once at time for each conn, so no additional
@@ -176,11 +252,27 @@ func (s *Server) handleConnection(conn net.Conn) {
 			return
 		}
 
+		if !secure {
+			// Requests are handled synchronously until the connection
+			// is switched to TLS, since the connection is replaced then.
+			conn, secure, err = s.handleInsecureRequest(sessionID, conn, reqData)
+			if err != nil {
+				s.errorHandler(fmt.Errorf("could not switch connection to TLS: %w", err))
+				return
+			}
+			if secure {
+				s.handler.SetSessionInfo(sessionID, sess.update(func(info *SessionInfo) {
+					info.TLS = true
+				}))
+			}
+			continue
+		}
+
 		// Performing a request may take some time so we are running it
 		// in the separate goroutine. We follow the XRootD protocol and
 		// write results back with StreamID provided in the request,
 		// so Client will match the responses to the corresponding request calls.
-		go func(req []byte) {
+		go func(conn net.Conn, req []byte) {
 			var (
 				reqHeader xrdproto.RequestHeader
 				resp      xrdproto.Marshaler
@@ -191,7 +283,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 			if err := reqHeader.UnmarshalXrd(rBuffer); err != nil {
 				resp, status = newUnmarshalingErrorResponse(err)
 			} else {
-				resp, status = s.handleRequest(sessionID, reqHeader.RequestID, rBuffer)
+				resp, status = s.handleAuthRequest(sessionID, &sess, reqHeader.RequestID, rBuffer)
 			}
 
 			if err := xrdproto.WriteResponse(conn, reqHeader.StreamID, status, resp); err != nil {
@@ -205,8 +297,67 @@ func (s *Server) handleConnection(conn net.Conn) {
 				// the writing phase because we can't recover from it.
 				return
 			}
-		}(reqData)
+		}(conn, reqData)
+	}
+}
+
+// handleInsecureRequest handles a request received before the connection is switched to TLS.
+// Only the protocol request is allowed: if the client wants TLS, the response
+// requests the client to switch to TLS and the TLS connection is returned.
+func (s *Server) handleInsecureRequest(sessionID [16]byte, conn net.Conn, req []byte) (net.Conn, bool, error) {
+	var (
+		reqHeader xrdproto.RequestHeader
+		resp      xrdproto.Marshaler
+		status    xrdproto.ResponseStatus
+		request   protocol.Request
+		gotoTLS   bool
+	)
+
+	rBuffer := xrdenc.NewRBuffer(req)
+	err := reqHeader.UnmarshalXrd(rBuffer)
+	switch {
+	case err != nil:
+		resp, status = newUnmarshalingErrorResponse(err)
+	case reqHeader.RequestID != protocol.RequestID:
+		resp, status = xrdproto.ServerError{
+			Code:    xrdproto.NotAuthorized,
+			Message: "TLS is required by the server",
+		}, xrdproto.Error
+	default:
+		err = request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			resp, status = newUnmarshalingErrorResponse(err)
+			break
+		}
+		resp, status = s.handler.Protocol(sessionID, &request)
+		if status != xrdproto.Ok {
+			break
+		}
+		gotoTLS = request.Options&protocol.WantTLS != 0
+		flags := protocol.HaveTLS
+		if gotoTLS {
+			flags |= protocol.GotoTLS | protocol.TLSLogin
+		}
+		switch r := resp.(type) {
+		case *protocol.Response:
+			r.Flags |= flags
+		case protocol.Response:
+			r.Flags |= flags
+			resp = r
+		}
+	}
+
+	err = xrdproto.WriteResponse(conn, reqHeader.StreamID, status, resp)
+	if err != nil || !gotoTLS {
+		return conn, false, err
 	}
+
+	tlsConn := tls.Server(conn, s.tlsConfig)
+	err = tlsConn.Handshake()
+	if err != nil {
+		return conn, false, err
+	}
+	return tlsConn, true, nil
 }
 
 func (s *Server) handleHandshake(conn net.Conn) error {
@@ -239,6 +390,86 @@ func newUnmarshalingErrorResponse(err error) (xrdproto.Marshaler, xrdproto.Respo
 	return response, xrdproto.Error
 }
 
+// handleAuthRequest handles a request, enforcing the authentication of the client
+// with one of the registered security providers, if any.
+// Until the client is authenticated, only the protocol, login, auth and ping requests are allowed.
+func (s *Server) handleAuthRequest(sessionID [16]byte, sess *srvConn, requestID uint16, rBuffer *xrdenc.RBuffer) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	switch requestID {
+	case auth.RequestID:
+		var request auth.Request
+		err := request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return newUnmarshalingErrorResponse(err)
+		}
+		return s.authenticate(sessionID, sess, &request)
+	case login.RequestID:
+		resp, status := s.handleRequest(sessionID, requestID, rBuffer)
+		if status != xrdproto.Ok || len(s.verifiers) == 0 {
+			return resp, status
+		}
+		switch r := resp.(type) {
+		case *login.Response:
+			r.SecurityInformation = append(r.SecurityInformation, s.securityInformation()...)
+		case login.Response:
+			r.SecurityInformation = append(r.SecurityInformation, s.securityInformation()...)
+			resp = r
+		}
+		return resp, status
+	case protocol.RequestID, ping.RequestID:
+		return s.handleRequest(sessionID, requestID, rBuffer)
+	}
+
+	if !sess.authenticated.Load() {
+		return xrdproto.ServerError{
+			Code:    xrdproto.NotAuthorized,
+			Message: "Authentication is required by the server",
+		}, xrdproto.Error
+	}
+	return s.handleRequest(sessionID, requestID, rBuffer)
+}
+
+// securityInformation returns the security information sent to the clients upon login,
+// listing the registered security providers and their parameters.
+func (s *Server) securityInformation() []byte {
+	var o strings.Builder
+	for _, v := range s.verifiers {
+		o.WriteString("&P=" + v.Provider())
+		if params := v.Params(); params != "" {
+			o.WriteString("," + params)
+		}
+	}
+	return []byte(o.String())
+}
+
+// authenticate verifies the credentials of the auth request with the corresponding security provider.
+// Upon success, the authenticated identity is stored on the session and passed to the handler.
+func (s *Server) authenticate(sessionID [16]byte, sess *srvConn, request *auth.Request) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
+	provider := request.Provider()
+	for _, v := range s.verifiers {
+		if v.Provider() != provider {
+			continue
+		}
+		id, err := v.Verify(request)
+		if err != nil {
+			return xrdproto.ServerError{
+				Code:    xrdproto.NotAuthorized,
+				Message: fmt.Sprintf("Could not authenticate using %s: %v", provider, err),
+			}, xrdproto.Error
+		}
+		s.handler.SetSessionInfo(sessionID, sess.update(func(info *SessionInfo) {
+			info.Provider = provider
+			info.Identity = id
+		}))
+		sess.authenticated.Store(true)
+		return nil, xrdproto.Ok
+	}
+
+	return xrdproto.ServerError{
+		Code:    xrdproto.NotAuthorized,
+		Message: fmt.Sprintf("Security provider %q is not supported", provider),
+	}, xrdproto.Error
+}
+
 func (s *Server) handleRequest(sessionID [16]byte, requestID uint16, rBuffer *xrdenc.RBuffer) (xrdproto.Marshaler, xrdproto.ResponseStatus) {
 	switch requestID {
 	case login.RequestID:
@@ -290,6 +521,55 @@ func (s *Server) handleRequest(sessionID [16]byte, requestID uint16, rBuffer *xr
 			return newUnmarshalingErrorResponse(err)
 		}
 		return s.handler.Write(sessionID, &request)
+	case readv.RequestID:
+		var request readv.Request
+		err := request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return newUnmarshalingErrorResponse(err)
+		}
+		return s.handler.ReadV(sessionID, &request)
+	case pgread.RequestID:
+		var request pgread.Request
+		err := request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return newUnmarshalingErrorResponse(err)
+		}
+		return s.handler.PgRead(sessionID, &request)
+	case pgwrite.RequestID:
+		var request pgwrite.Request
+		err := request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return newUnmarshalingErrorResponse(err)
+		}
+		return s.handler.PgWrite(sessionID, &request)
+	case locate.RequestID:
+		var request locate.Request
+		err := request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return newUnmarshalingErrorResponse(err)
+		}
+		return s.handler.Locate(sessionID, &request)
+	case query.RequestID:
+		var request query.Request
+		err := request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return newUnmarshalingErrorResponse(err)
+		}
+		return s.handler.Query(sessionID, &request)
+	case prepare.RequestID:
+		var request prepare.Request
+		err := request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return newUnmarshalingErrorResponse(err)
+		}
+		return s.handler.Prepare(sessionID, &request)
+	case chmod.RequestID:
+		var request chmod.Request
+		err := request.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return newUnmarshalingErrorResponse(err)
+		}
+		return s.handler.Chmod(sessionID, &request)
 	case stat.RequestID:
 		var request stat.Request
 		err := request.UnmarshalXrd(rBuffer)
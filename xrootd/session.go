@@ -101,9 +101,7 @@ func newSession(ctx context.Context, address, username, token string, client *Cl
 		maxSubs:   8, // TODO: The value of 8 is just a guess. Change it?
 	}
 
-	go sess.consume()
-
-	if err := sess.handshake(ctx); err != nil {
+	if err := sess.connect(ctx); err != nil {
 		sess.Close()
 		return nil, err
 	}
@@ -135,6 +133,21 @@ func newSession(ctx context.Context, address, username, token string, client *Cl
 	return sess, nil
 }
 
+// connect starts consuming the responses of the server and performs the handshake.
+// If the client is configured to use TLS, the connection is switched to TLS beforehand.
+func (sess *cliSession) connect(ctx context.Context) error {
+	if cfg := sess.client.tlsConfig; cfg != nil {
+		if err := sess.startTLS(ctx, cfg); err != nil {
+			return err
+		}
+		go sess.consume()
+		return nil
+	}
+
+	go sess.consume()
+	return sess.handshake(ctx)
+}
+
 // Close closes the connection. Any blocked operation will be unblocked and return error.
 func (sess *cliSession) Close() error {
 	if sess == nil {
@@ -167,17 +180,17 @@ func (sess *cliSession) Close() error {
 }
 
 // handleReadError handles an error encountered while reading and parsing a response.
-// If the current session is equal to the initial, the error is considered critical and handleReadError panics.
-// Otherwise, the current session is closed and all requests are redirected to the initial session.
+// If the current session is equal to the initial, the error is sent to all pending requests.
+// Otherwise, all requests are redirected to the initial session.
+// In both cases, the current session is closed.
 // See http://xrootd.org/doc/dev45/XRdv310.pdf, p. 11 for details.
 func (sess *cliSession) handleReadError(err error) {
+	resp := mux.ServerResponse{Redirection: &mux.Redirection{Addr: sess.client.initialSessionID}}
 	if sess.sessionID == sess.client.initialSessionID {
-		// TODO: what should we do in case initial session is aborted?
-		// Should we try to reconnect to the server and re-issue all requests?
-		panic(err)
+		// TODO: should we try to reconnect to the server and re-issue all requests?
+		resp = mux.ServerResponse{Err: fmt.Errorf("xrootd: could not read response from %s: %w", sess.sessionID, err)}
 	}
 	sess.mu.RLock()
-	resp := mux.ServerResponse{Redirection: &mux.Redirection{Addr: sess.client.initialSessionID}}
 	for streamID := range sess.requests {
 		err := sess.mux.SendData(streamID, resp)
 		// TODO: should we log error somehow? We have nowhere to send it.
@@ -238,9 +251,11 @@ func (sess *cliSession) consume() {
 					return
 				}
 				sess.handleReadError(err)
+				return
 			}
 			resp.Err = nil
 			resp.Redirection = nil
+			status := header.Status
 
 			switch header.Status {
 			case xrdproto.Error:
@@ -252,25 +267,46 @@ func (sess *cliSession) consume() {
 				}
 			case xrdproto.Redirect:
 				resp.Redirection, resp.Err = mux.ParseRedirection(resp.Data)
+			case xrdproto.Status:
+				final, err := isFinalStatus(resp.Data)
+				if err != nil {
+					resp.Err = err
+					break
+				}
+				if !final {
+					status = xrdproto.OkSoFar
+				}
 			}
 
 			if err := sess.mux.SendData(header.StreamID, resp); err != nil {
-				if sess.ctx.Err() != nil {
-					// something happened to the context.
-					// ignore this error.
-					continue
-				}
-				panic(err)
-				// TODO: should we just ignore responses to unclaimed stream IDs?
+				// the server sent a response to a stream ID that was never
+				// claimed (or was already released): there is nobody to
+				// deliver it to, so drop it.
+				continue
 			}
 
-			if header.Status != xrdproto.OkSoFar {
+			if status != xrdproto.OkSoFar {
 				sess.cleanupRequest(header.StreamID)
 			}
 		}
 	}
 }
 
+// isFinalStatus returns whether the kXR_status response held in data is
+// the last response to a request.
+// Partial results are followed by other responses with the same stream ID.
+func isFinalStatus(data []byte) (bool, error) {
+	if len(data) < xrdproto.StatusResponseLength {
+		return false, fmt.Errorf("xrootd: invalid status response length: %d", len(data))
+	}
+	var status xrdproto.StatusResponse
+	err := status.UnmarshalXrd(xrdenc.NewRBuffer(data[:xrdproto.StatusResponseLength]))
+	if err != nil {
+		return false, err
+	}
+	return status.Type == xrdproto.FinalResult, nil
+}
+
 func (sess *cliSession) cleanupRequest(streamID xrdproto.StreamID) {
 	sess.mux.Unclaim(streamID)
 	sess.mu.Lock()
@@ -333,15 +369,32 @@ func (sess *cliSession) send(ctx context.Context, streamID xrdproto.StreamID, re
 
 			data = append(data, resp.Data...)
 		case <-ctx.Done():
-			if err := ctx.Err(); err != nil {
-				return nil, nil, err
-			}
+			// The response may still be sent by the server:
+			// drain it so that the stream ID is released and the other requests are not blocked,
+			// for as long as the session is alive.
+			go func() {
+				for {
+					select {
+					case _, more := <-responseChannel:
+						if !more {
+							return
+						}
+					case <-sess.ctx.Done():
+						return
+					}
+				}
+			}()
+			return nil, nil, ctx.Err()
 		}
 	}
 }
 
 // Send sends the request to the server and stores the response inside the resp.
 func (sess *cliSession) Send(ctx context.Context, resp xrdproto.Response, req xrdproto.Request) (*mux.Redirection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	streamID, responseChannel, err := sess.mux.Claim()
 	if err != nil {
 		return nil, err
@@ -468,9 +521,7 @@ func newSubSession(ctx context.Context, parent *cliSession) (*cliSession, error)
 		isSub:     true,
 	}
 
-	go sess.consume()
-
-	if err := sess.handshake(ctx); err != nil {
+	if err := sess.connect(ctx); err != nil {
 		sess.Close()
 		return nil, err
 	}
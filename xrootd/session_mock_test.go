@@ -5,6 +5,7 @@
 package xrootd // import "go-hep.org/x/hep/xrootd"
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"net"
@@ -13,7 +14,9 @@ import (
 	"time"
 
 	"go-hep.org/x/hep/xrootd/internal/mux"
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
 	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgread"
 	"go-hep.org/x/hep/xrootd/xrdproto/ping"
 	"go-hep.org/x/hep/xrootd/xrdproto/signing"
 	"go-hep.org/x/hep/xrootd/xrdproto/truncate"
@@ -135,6 +138,77 @@ func TestSession_ConnectionAbort(t *testing.T) {
 	testClientWithMockServer(serverFunc, clientFunc)
 }
 
+// partialPgRead is a pgread response sent as a partial result.
+type partialPgRead pgread.Response
+
+func (o partialPgRead) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	status := xrdproto.StatusResponse{
+		RequestID:  pgread.RequestID,
+		Type:       xrdproto.PartialResult,
+		DataLength: int32(xrdproto.PagesLength(o.Offset, len(o.Data))),
+	}
+	err := status.MarshalXrd(wBuffer)
+	if err != nil {
+		return err
+	}
+	wBuffer.WriteI64(o.Offset)
+	xrdproto.WritePages(wBuffer, o.Offset, o.Data)
+	return nil
+}
+
+func TestSession_PartialStatus(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	serverFunc := func(cancel func(), conn net.Conn) {
+		raw, err := xrdproto.ReadRequest(conn)
+		if err != nil {
+			cancel()
+			t.Errorf("could not read request: %v", err)
+			return
+		}
+
+		var req pgread.Request
+		hdr, err := unmarshalRequest(raw, &req)
+		if err != nil {
+			cancel()
+			t.Errorf("could not unmarshal request: %v", err)
+			return
+		}
+
+		for _, v := range []struct {
+			streamID xrdproto.StreamID
+			status   xrdproto.ResponseStatus
+			resp     xrdproto.Marshaler
+		}{
+			// a response to a stream ID that was never claimed is dropped.
+			{xrdproto.StreamID{0xff, 0xff}, xrdproto.Ok, nil},
+			{hdr.StreamID, xrdproto.Status, partialPgRead{Offset: 0, Data: data[:5000]}},
+			{hdr.StreamID, xrdproto.Status, pgread.Response{Offset: 5000, Data: data[5000:]}},
+		} {
+			err = xrdproto.WriteResponse(conn, v.streamID, v.status, v.resp)
+			if err != nil {
+				cancel()
+				t.Errorf("could not write response: %v", err)
+				return
+			}
+		}
+	}
+
+	clientFunc := func(cancel func(), client *Client) {
+		var resp pgread.Response
+		sess := client.sessions[client.initialSessionID]
+		_, err := sess.Send(context.Background(), &resp, &pgread.Request{Length: int32(len(data))})
+		if err != nil {
+			t.Fatalf("invalid pgread call: %v", err)
+		}
+		if !bytes.Equal(resp.Data, data) {
+			t.Fatalf("invalid pgread data")
+		}
+	}
+
+	testClientWithMockServer(serverFunc, clientFunc)
+}
+
 func TestSessionCloseNil(t *testing.T) {
 	var sess *cliSession
 	err := sess.Close()
@@ -0,0 +1,119 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/handshake"
+	"go-hep.org/x/hep/xrootd/xrdproto/protocol"
+)
+
+// startTLS performs the handshake and the protocol request over the plain
+// connection and switches the connection to TLS, as requested by the server.
+//
+// startTLS must be called before the responses are consumed, since the
+// requests and the responses are exchanged synchronously over the connection.
+func (sess *cliSession) startTLS(ctx context.Context, cfg *tls.Config) error {
+	stop := context.AfterFunc(ctx, func() {
+		_ = sess.conn.SetDeadline(time.Now())
+	})
+	defer func() {
+		if stop() {
+			_ = sess.conn.SetDeadline(time.Time{})
+		}
+	}()
+
+	var (
+		hs      handshake.Response
+		wBuffer xrdenc.WBuffer
+	)
+	err := handshake.NewRequest().MarshalXrd(&wBuffer)
+	if err != nil {
+		return err
+	}
+	err = sess.exchange(xrdproto.StreamID{0, 0}, &hs, wBuffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("xrootd: could not perform handshake: %w", err)
+	}
+	sess.protocolVersion = hs.ProtocolVersion
+
+	var (
+		resp protocol.Response
+		req  = protocol.NewRequest(sess.protocolVersion, true)
+	)
+	req.Options |= protocol.AbleTLS | protocol.WantTLS
+	wBuffer = xrdenc.WBuffer{}
+	header := xrdproto.RequestHeader{StreamID: xrdproto.StreamID{0, 1}, RequestID: req.ReqID()}
+	err = header.MarshalXrd(&wBuffer)
+	if err != nil {
+		return err
+	}
+	err = req.MarshalXrd(&wBuffer)
+	if err != nil {
+		return err
+	}
+	err = sess.exchange(header.StreamID, &resp, wBuffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("xrootd: could not negotiate TLS: %w", err)
+	}
+	if !resp.HaveTLS() || !resp.GotoTLS() {
+		return fmt.Errorf("xrootd: server %s does not support TLS", sess.addr)
+	}
+
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(sess.addr)
+		if err != nil {
+			return fmt.Errorf("xrootd: could not extract host from %q: %w", sess.addr, err)
+		}
+		cfg = cfg.Clone()
+		cfg.ServerName = host
+	}
+
+	conn := tls.Client(sess.conn, cfg)
+	err = conn.HandshakeContext(ctx)
+	if err != nil {
+		return fmt.Errorf("xrootd: could not perform TLS handshake with %s: %w", sess.addr, err)
+	}
+	sess.conn = conn
+
+	return nil
+}
+
+// exchange synchronously writes the raw request to the connection and
+// reads the response with the provided stream ID into resp.
+func (sess *cliSession) exchange(streamID xrdproto.StreamID, resp xrdproto.Unmarshaler, request []byte) error {
+	if _, err := sess.conn.Write(request); err != nil {
+		return err
+	}
+
+	header, data, err := xrdproto.ReadResponse(sess.conn)
+	if err != nil {
+		return err
+	}
+	if header.StreamID != streamID {
+		return fmt.Errorf("xrootd: unexpected stream id %v, want %v", header.StreamID, streamID)
+	}
+	switch header.Status {
+	case xrdproto.Ok:
+		return resp.UnmarshalXrd(xrdenc.NewRBuffer(data))
+	case xrdproto.Error:
+		return header.Error(data)
+	default:
+		return fmt.Errorf("xrootd: unexpected response status %d", header.Status)
+	}
+}
+
+// isTLS returns whether the connection of the session is TLS-encrypted.
+func (sess *cliSession) isTLS() bool {
+	_, ok := sess.conn.(*tls.Conn)
+	return ok
+}
@@ -0,0 +1,186 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd_test // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+)
+
+// newTestCert generates a self-signed certificate for localhost.
+func newTestCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"go-hep"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+func createTLSServer(t *testing.T, cert tls.Certificate, errorHandler func(err error)) (srv *xrootd.Server, addr, baseDir string) {
+	t.Helper()
+
+	baseDir = t.TempDir()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	addr = listener.Addr().String()
+
+	srv = xrootd.NewTLSServer(xrootd.NewFSHandler(baseDir), errorHandler, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})
+
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != xrootd.ErrServerClosed {
+			errorHandler(err)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = srv.Shutdown(context.Background())
+	})
+
+	return srv, addr, baseDir
+}
+
+func TestTLS(t *testing.T) {
+	cert, pool := newTestCert(t)
+	_, addr, baseDir := createTLSServer(t, cert, func(err error) {
+		t.Error(err)
+	})
+
+	want := []byte("hello, TLS world")
+	err := os.WriteFile(path.Join(baseDir, "file1.txt"), want, 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	ctx := context.Background()
+	cli, err := xrootd.NewClient(ctx, addr, "gopher", xrootd.WithTLS(&tls.Config{RootCAs: pool}))
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	err = cli.FS().MkdirAll(ctx, "dir1", xrdfs.OpenModeOwnerRead|xrdfs.OpenModeOwnerWrite|xrdfs.OpenModeOwnerExecute)
+	if err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+
+	f, err := cli.FS().Open(ctx, "file1.txt", xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	defer f.Close(ctx)
+
+	got := make([]byte, len(want))
+	n, err := f.ReadAtContext(ctx, got, 0)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+
+	if !bytes.Equal(got[:n], want) {
+		t.Fatalf("invalid data:\ngot = %q\nwant= %q", got[:n], want)
+	}
+
+	if _, err := os.Stat(path.Join(baseDir, "dir1")); err != nil {
+		t.Fatalf("could not stat created directory: %v", err)
+	}
+}
+
+func TestTLS_Errors(t *testing.T) {
+	cert, pool := newTestCert(t)
+	_, tlsAddr, _ := createTLSServer(t, cert, func(err error) {})
+
+	srv, addr, baseDir, err := createServer(func(err error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	defer func() {
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	for _, tc := range []struct {
+		name string
+		addr string
+		opts []xrootd.Option
+		want string
+	}{
+		{
+			name: "plain client with TLS server",
+			addr: tlsAddr,
+			want: "TLS is required by the server",
+		},
+		{
+			name: "TLS client with plain server",
+			addr: addr,
+			opts: []xrootd.Option{xrootd.WithTLS(&tls.Config{RootCAs: pool})},
+			want: "does not support TLS",
+		},
+		{
+			name: "TLS client with unknown authority",
+			addr: tlsAddr,
+			opts: []xrootd.Option{xrootd.WithTLS(nil)},
+			want: "could not perform TLS handshake",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cli, err := xrootd.NewClient(context.Background(), tc.addr, "gopher", tc.opts...)
+			if err == nil {
+				cli.Close()
+				t.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("invalid error:\ngot = %v\nwant= %v", err, tc.want)
+			}
+		})
+	}
+}
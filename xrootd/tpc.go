@@ -0,0 +1,235 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go-hep.org/x/hep/xrootd/xrdfs"
+)
+
+// tpcKeyTTL is the duration during which a third-party copy key registered
+// by the source server may be used by the destination server.
+const tpcKeyTTL = 2 * time.Minute
+
+// tpcBufferSize is the size of the buffer used by the destination server to
+// read the source file of a third-party copy.
+const tpcBufferSize = 8 * 1024 * 1024
+
+// tpcTimeout is the maximum duration of a third-party copy performed by the destination server.
+const tpcTimeout = 30 * time.Minute
+
+// ThirdPartyCopy copies the file at srcPath on the server of src to dstPath on the server of dst.
+// The transfer is delegated to the destination server, which reads the file directly
+// from the source server, using a one-time key registered with the source server.
+// The destination file is created, or truncated if it already exists.
+//
+// See https://xrootd.slac.stanford.edu/doc/dev56/tpc_protocol.htm for details.
+func ThirdPartyCopy(ctx context.Context, dst *Client, dstPath string, src *Client, srcPath string) error {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return fmt.Errorf("xrootd: could not generate third-party copy key: %w", err)
+	}
+	key := hex.EncodeToString(raw[:])
+
+	dstHost, _, err := net.SplitHostPort(dst.initialSessionID)
+	if err != nil {
+		dstHost = dst.initialSessionID
+	}
+	org := src.username + "@" + dstHost
+
+	sf, err := src.FS().Open(ctx, srcPath+"?"+url.Values{
+		"tpc.key": {key},
+		"tpc.dst": {dstHost},
+		"tpc.org": {org},
+	}.Encode(), xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	if err != nil {
+		return fmt.Errorf("xrootd: could not open third-party copy source: %w", err)
+	}
+	defer sf.Close(ctx)
+
+	// The source file may have been opened on another server, following a redirection.
+	srcAddr := sf.(*file).sessionID
+	proto := "root"
+	if src.tlsConfig != nil {
+		proto = "roots"
+	}
+
+	df, err := dst.FS().Open(ctx, dstPath+"?"+url.Values{
+		"tpc.key":   {key},
+		"tpc.src":   {srcAddr},
+		"tpc.lfn":   {srcPath},
+		"tpc.org":   {org},
+		"tpc.spr":   {proto},
+		"tpc.stage": {"copy"},
+	}.Encode(),
+		xrdfs.OpenModeOwnerRead|xrdfs.OpenModeOwnerWrite|xrdfs.OpenModeGroupRead|xrdfs.OpenModeOtherRead,
+		xrdfs.OpenOptionsDelete|xrdfs.OpenOptionsOpenUpdate,
+	)
+	if err != nil {
+		return fmt.Errorf("xrootd: could not open third-party copy destination: %w", err)
+	}
+
+	// The copy is performed by the destination server upon the sync request.
+	err = df.Sync(ctx)
+	if err != nil {
+		_ = df.Close(ctx)
+		return fmt.Errorf("xrootd: could not perform third-party copy: %w", err)
+	}
+
+	err = df.Close(ctx)
+	if err != nil {
+		return fmt.Errorf("xrootd: could not close third-party copy destination: %w", err)
+	}
+	return nil
+}
+
+// tpcKey is a third-party copy key registered with the source server.
+type tpcKey struct {
+	path    string
+	expires time.Time
+}
+
+// tpcJob is a third-party copy pending on the destination server.
+type tpcJob struct {
+	src string // src is the address of the source server.
+	lfn string // lfn is the path of the file on the source server.
+	key string // key is the third-party copy key registered with the source server.
+	org string // org identifies the origin of the third-party copy.
+	tls bool   // tls indicates whether the source server must be contacted over TLS.
+}
+
+// newTPCJob returns the third-party copy job described by the opaque data
+// of an open request, if any.
+//
+// Three kinds of requests take part in a third-party copy:
+//   - the client registers the key with the source server (tpc.dst is set),
+//   - the client opens the destination file (tpc.stage is "copy"),
+//   - the destination server opens the source file with the registered key.
+//
+// The destination server only copies files from the source servers it was
+// configured with (see WithTPCSources).
+func (h *fshandler) newTPCJob(name string, cgi url.Values) (*tpcJob, error) {
+	key := cgi.Get("tpc.key")
+	if key == "" {
+		for k := range cgi {
+			if strings.HasPrefix(k, "tpc.") {
+				return nil, errors.New("missing third-party copy key")
+			}
+		}
+		return nil, nil
+	}
+	name = path.Clean(name)
+
+	switch {
+	case cgi.Get("tpc.stage") == "copy":
+		job := &tpcJob{
+			src: cgi.Get("tpc.src"),
+			lfn: cgi.Get("tpc.lfn"),
+			key: key,
+			org: cgi.Get("tpc.org"),
+			tls: cgi.Get("tpc.spr") == "roots" || cgi.Get("tpc.spr") == "xroots",
+		}
+		if job.src == "" || job.lfn == "" {
+			return nil, errors.New("missing third-party copy source")
+		}
+		if !h.tpcAllowed(job.src) {
+			return nil, fmt.Errorf("third-party copy from %q is not allowed", job.src)
+		}
+		return job, nil
+
+	case cgi.Get("tpc.dst") != "":
+		h.tpcMu.Lock()
+		defer h.tpcMu.Unlock()
+		now := time.Now()
+		for k, v := range h.tpcKeys {
+			if now.After(v.expires) {
+				delete(h.tpcKeys, k)
+			}
+		}
+		h.tpcKeys[key] = tpcKey{path: name, expires: now.Add(tpcKeyTTL)}
+		return nil, nil
+
+	default:
+		h.tpcMu.Lock()
+		defer h.tpcMu.Unlock()
+		v, ok := h.tpcKeys[key]
+		if !ok || v.path != name || time.Now().After(v.expires) {
+			return nil, errors.New("invalid third-party copy key")
+		}
+		delete(h.tpcKeys, key)
+		return nil, nil
+	}
+}
+
+// tpcAllowed returns whether the handler may copy files from the source server listening on addr.
+func (h *fshandler) tpcAllowed(addr string) bool {
+	if _, ok := h.tpcSources[addr]; ok {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	_, ok := h.tpcSources[host]
+	return ok
+}
+
+// run performs the third-party copy by reading the source file into dst.
+func (job tpcJob) run(ctx context.Context, dst *os.File) error {
+	var opts []Option
+	if job.tls {
+		opts = append(opts, WithTLS(nil))
+	}
+	cli, err := NewClient(ctx, job.src, "xrootd", opts...)
+	if err != nil {
+		return fmt.Errorf("could not connect to source server %q: %w", job.src, err)
+	}
+	defer cli.Close()
+
+	f, err := cli.FS().Open(ctx, job.lfn+"?"+url.Values{
+		"tpc.key": {job.key},
+		"tpc.org": {job.org},
+	}.Encode(), xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	if err != nil {
+		return fmt.Errorf("could not open source file %q: %w", job.lfn, err)
+	}
+	defer f.Close(ctx)
+
+	var (
+		buf = make([]byte, tpcBufferSize)
+		off int64
+	)
+	for {
+		n, err := f.ReadAtContext(ctx, buf, off)
+		if err != nil {
+			return fmt.Errorf("could not read source file %q: %w", job.lfn, err)
+		}
+		if n == 0 {
+			break
+		}
+		_, err = dst.WriteAt(buf[:n], off)
+		if err != nil {
+			return fmt.Errorf("could not write destination file: %w", err)
+		}
+		off += int64(n)
+	}
+
+	err = dst.Truncate(off)
+	if err != nil {
+		return fmt.Errorf("could not truncate destination file: %w", err)
+	}
+	return dst.Sync()
+}
@@ -0,0 +1,178 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrootd_test // import "go-hep.org/x/hep/xrootd"
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+)
+
+func TestThirdPartyCopy(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		srcDir = t.TempDir()
+		dstDir = t.TempDir()
+		data   = make([]byte, 10*1024*1024+42)
+	)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatalf("could not prepare test data: %v", err)
+	}
+	err = os.WriteFile(path.Join(srcDir, "file1.bin"), data, 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	srcAddr := serveHandler(t, xrootd.NewFSHandler(srcDir))
+	dstAddr := serveHandler(t, xrootd.NewFSHandler(dstDir, xrootd.WithTPCSources("127.0.0.1")))
+
+	redir := xrootd.NewRedirector()
+	defer redir.Disconnect()
+	err = redir.Register(srcAddr)
+	if err != nil {
+		t.Fatalf("could not register data server: %v", err)
+	}
+	redirAddr := serveHandler(t, redir)
+
+	for _, tc := range []struct {
+		name string
+		src  string
+		dst  string
+	}{
+		{name: "direct", src: srcAddr, dst: "file2.bin"},
+		{name: "redirected", src: redirAddr, dst: "file3.bin"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			src, err := xrootd.NewClient(ctx, tc.src, "gopher")
+			if err != nil {
+				t.Fatalf("could not create source client: %v", err)
+			}
+			defer src.Close()
+
+			dst, err := xrootd.NewClient(ctx, dstAddr, "gopher")
+			if err != nil {
+				t.Fatalf("could not create destination client: %v", err)
+			}
+			defer dst.Close()
+
+			err = xrootd.ThirdPartyCopy(ctx, dst, tc.dst, src, "file1.bin")
+			if err != nil {
+				t.Fatalf("could not perform third-party copy: %v", err)
+			}
+
+			got, err := os.ReadFile(path.Join(dstDir, tc.dst))
+			if err != nil {
+				t.Fatalf("could not read copied file: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("invalid copied data")
+			}
+
+			want, err := src.FS().Checksum(ctx, "file1.bin", xrdfs.ChecksumAdler32)
+			if err != nil {
+				t.Fatalf("could not compute source checksum: %v", err)
+			}
+			cks, err := dst.FS().Checksum(ctx, tc.dst, xrdfs.ChecksumAdler32)
+			if err != nil {
+				t.Fatalf("could not compute destination checksum: %v", err)
+			}
+			if cks != want {
+				t.Fatalf("invalid checksum: got=%v, want=%v", cks, want)
+			}
+		})
+	}
+
+	// The source server only serves files to destination servers with a registered key.
+	cli, err := xrootd.NewClient(ctx, srcAddr, "gopher")
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	_, err = cli.FS().Open(ctx, "file1.bin?tpc.key=1234&tpc.org=gopher", xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	var serr xrdproto.ServerError
+	if !errors.As(err, &serr) || serr.Code != xrdproto.NotAuthorized {
+		t.Fatalf("invalid error for unregistered key: %v", err)
+	}
+}
+
+func TestThirdPartyCopyRefused(t *testing.T) {
+	ctx := context.Background()
+
+	srcDir := t.TempDir()
+	err := os.WriteFile(path.Join(srcDir, "file1.bin"), []byte("data"), 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+	srcAddr := serveHandler(t, xrootd.NewFSHandler(srcDir))
+
+	for _, tc := range []struct {
+		name string
+		opts []xrootd.FSHandlerOption
+		path string // path of the destination file, opened directly
+		want string
+	}{
+		{
+			name: "no-sources",
+			want: "is not allowed",
+		},
+		{
+			name: "other-source",
+			opts: []xrootd.FSHandlerOption{xrootd.WithTPCSources("localhost", "127.0.0.1:1")},
+			want: "is not allowed",
+		},
+		{
+			name: "missing-key",
+			opts: []xrootd.FSHandlerOption{xrootd.WithTPCSources("127.0.0.1")},
+			path: "file2.bin?tpc.stage=copy&tpc.src=" + srcAddr + "&tpc.lfn=file1.bin",
+			want: "missing third-party copy key",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dstDir := t.TempDir()
+			dstAddr := serveHandler(t, xrootd.NewFSHandler(dstDir, tc.opts...))
+
+			src, err := xrootd.NewClient(ctx, srcAddr, "gopher")
+			if err != nil {
+				t.Fatalf("could not create source client: %v", err)
+			}
+			defer src.Close()
+
+			dst, err := xrootd.NewClient(ctx, dstAddr, "gopher")
+			if err != nil {
+				t.Fatalf("could not create destination client: %v", err)
+			}
+			defer dst.Close()
+
+			switch tc.path {
+			case "":
+				err = xrootd.ThirdPartyCopy(ctx, dst, "file2.bin", src, "file1.bin")
+			default:
+				_, err = dst.FS().Open(ctx, tc.path, xrdfs.OpenModeOwnerRead|xrdfs.OpenModeOwnerWrite, xrdfs.OpenOptionsDelete)
+			}
+			var serr xrdproto.ServerError
+			if !errors.As(err, &serr) || serr.Code != xrdproto.NotAuthorized {
+				t.Fatalf("invalid error: %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("invalid error:\ngot = %v\nwant= %v", err, tc.want)
+			}
+			if _, err := os.Stat(path.Join(dstDir, "file2.bin")); err == nil {
+				t.Fatalf("destination file was created")
+			}
+		})
+	}
+}
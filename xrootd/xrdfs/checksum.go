@@ -0,0 +1,53 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdfs
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"strings"
+)
+
+// Checksum algorithms supported by the checksum queries.
+const (
+	ChecksumAdler32 = "adler32" // ChecksumAdler32 is the Adler-32 checksum.
+	ChecksumCRC32C  = "crc32c"  // ChecksumCRC32C is the CRC-32 checksum, using the Castagnoli polynomial.
+	ChecksumMD5     = "md5"     // ChecksumMD5 is the MD5 hash.
+)
+
+// Checksum is the checksum of a file.
+type Checksum struct {
+	Type  string // Type is the name of the checksum algorithm, e.g. "adler32".
+	Value string // Value is the hexadecimal representation of the checksum.
+}
+
+// String returns the checksum in the "type:value" form.
+func (cks Checksum) String() string {
+	return cks.Type + ":" + cks.Value
+}
+
+// NewChecksumHash returns a hash computing checksums with the named algorithm.
+func NewChecksumHash(typ string) (hash.Hash, error) {
+	switch strings.ToLower(typ) {
+	case ChecksumAdler32:
+		return adler32.New(), nil
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case ChecksumMD5:
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("xrdfs: unsupported checksum type %q", typ)
+	}
+}
+
+// ChecksumFrom returns the checksum of the provided type from the sum of a hash
+// created with NewChecksumHash.
+func ChecksumFrom(typ string, sum []byte) Checksum {
+	return Checksum{Type: strings.ToLower(typ), Value: hex.EncodeToString(sum)}
+}
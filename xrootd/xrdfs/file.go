@@ -58,6 +58,26 @@ type File interface {
 	// TODO: note that verifyw is not supported by the XRootD server.
 	// See https://github.com/xrootd/xrootd/issues/738 for the details.
 	VerifyWriteAt(ctx context.Context, p []byte, off int64) error
+
+	// ReadV reads the provided chunks of the file using vectored reads.
+	// The data of each chunk is read into its Data field, starting at its Offset.
+	// The Data field is resliced to the number of bytes actually read,
+	// which may be less than requested if the end of file was reached.
+	ReadV(ctx context.Context, chunks []Chunk) error
+
+	// PgReadAt reads len(p) bytes into p starting at offset off using page reads.
+	// The CRC32C checksum of each page of data is verified.
+	PgReadAt(ctx context.Context, p []byte, off int64) (n int, err error)
+
+	// PgWriteAt writes len(p) bytes from p to the file at offset off using page writes.
+	// The CRC32C checksum of each page of data is sent along with the data.
+	PgWriteAt(ctx context.Context, p []byte, off int64) error
+}
+
+// Chunk is a chunk of a file, used by vectored reads.
+type Chunk struct {
+	Offset int64
+	Data   []byte
 }
 
 // FileHandle is the file handle, which should be treated as opaque data.
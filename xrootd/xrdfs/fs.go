@@ -55,6 +55,11 @@ type FileSystem interface {
 	// Chmod changes the permissions of the named file to perm.
 	Chmod(ctx context.Context, path string, mode OpenMode) error
 
+	// Checksum returns the checksum of the named file, computed by the server
+	// with the typ algorithm (e.g. "adler32", "crc32c" or "md5").
+	// If typ is empty, the default algorithm of the server is used.
+	Checksum(ctx context.Context, path, typ string) (Checksum, error)
+
 	// Statx obtains type information for one or more paths.
 	// Only a limited number of flags is meaningful such as StatIsExecutable, StatIsDir, StatIsOther, StatIsOffline.
 	Statx(ctx context.Context, paths []string) ([]StatFlags, error)
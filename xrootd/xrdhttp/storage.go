@@ -0,0 +1,217 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdhttp // import "go-hep.org/x/hep/xrootd/xrdhttp"
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/dirlist"
+	"go-hep.org/x/hep/xrootd/xrdproto/mkdir"
+	"go-hep.org/x/hep/xrootd/xrdproto/open"
+	"go-hep.org/x/hep/xrootd/xrdproto/read"
+	"go-hep.org/x/hep/xrootd/xrdproto/rm"
+	"go-hep.org/x/hep/xrootd/xrdproto/rmdir"
+	"go-hep.org/x/hep/xrootd/xrdproto/stat"
+	"go-hep.org/x/hep/xrootd/xrdproto/write"
+	"go-hep.org/x/hep/xrootd/xrdproto/xrdclose"
+)
+
+// backend provides the storages serving HTTP requests.
+type backend interface {
+	// session returns the storage serving a single HTTP request,
+	// described by info, together with a function releasing it.
+	session(info xrootd.SessionInfo) (storage, func())
+}
+
+// storage is the subset of the xrootd file system operations used by the HTTP door.
+type storage interface {
+	Stat(ctx context.Context, path string) (xrdfs.EntryStat, error)
+	Dirlist(ctx context.Context, path string) ([]xrdfs.EntryStat, error)
+	Open(ctx context.Context, path string, mode xrdfs.OpenMode, options xrdfs.OpenOptions) (file, error)
+	RemoveFile(ctx context.Context, path string) error
+	RemoveDir(ctx context.Context, path string) error
+	Mkdir(ctx context.Context, path string, perm xrdfs.OpenMode) error
+}
+
+// file is the subset of the xrootd file operations used by the HTTP door.
+type file interface {
+	io.ReaderAt
+	WriteAtContext(ctx context.Context, p []byte, off int64) error
+	Close(ctx context.Context) error
+}
+
+// fsBackend serves HTTP requests from an xrdfs.FileSystem.
+type fsBackend struct {
+	fs xrdfs.FileSystem
+}
+
+func (b fsBackend) session(xrootd.SessionInfo) (storage, func()) {
+	return fsStorage{b.fs}, func() {}
+}
+
+type fsStorage struct {
+	xrdfs.FileSystem
+}
+
+func (st fsStorage) Open(ctx context.Context, path string, mode xrdfs.OpenMode, options xrdfs.OpenOptions) (file, error) {
+	return st.FileSystem.Open(ctx, path, mode, options)
+}
+
+// handlerBackend serves HTTP requests from an xrootd.Handler,
+// each HTTP request being served within its own xrootd session.
+type handlerBackend struct {
+	h xrootd.Handler
+}
+
+func (b handlerBackend) session(info xrootd.SessionInfo) (storage, func()) {
+	sess := &handlerSession{h: b.h}
+	_, _ = rand.Read(sess.id[:])
+	b.h.SetSessionInfo(sess.id, info)
+	return sess, func() { _ = b.h.CloseSession(sess.id) }
+}
+
+// handlerSession is an xrootd session of a handlerBackend.
+type handlerSession struct {
+	h  xrootd.Handler
+	id [16]byte
+}
+
+// decode decodes the response of a request into dst.
+// It returns the error reported by the handler, if any.
+func decode(resp xrdproto.Marshaler, status xrdproto.ResponseStatus, dst xrdproto.Unmarshaler) error {
+	switch status {
+	case xrdproto.Ok:
+	case xrdproto.Error:
+		switch resp := resp.(type) {
+		case xrdproto.ServerError:
+			return resp
+		case *xrdproto.ServerError:
+			return *resp
+		}
+		return fmt.Errorf("xrdhttp: invalid error response %T", resp)
+	case xrdproto.Redirect:
+		return fmt.Errorf("xrdhttp: redirections are not supported")
+	default:
+		return fmt.Errorf("xrdhttp: unexpected response status %d", status)
+	}
+
+	if dst == nil || resp == nil {
+		return nil
+	}
+
+	var wbuf xrdenc.WBuffer
+	err := resp.MarshalXrd(&wbuf)
+	if err != nil {
+		return fmt.Errorf("xrdhttp: could not encode response: %w", err)
+	}
+	err = dst.UnmarshalXrd(xrdenc.NewRBuffer(wbuf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("xrdhttp: could not decode response: %w", err)
+	}
+	return nil
+}
+
+func (sess *handlerSession) Stat(ctx context.Context, path string) (xrdfs.EntryStat, error) {
+	var dst stat.DefaultResponse
+	resp, status := sess.h.Stat(sess.id, &stat.Request{Path: path})
+	err := decode(resp, status, &dst)
+	return dst.EntryStat, err
+}
+
+func (sess *handlerSession) Dirlist(ctx context.Context, path string) ([]xrdfs.EntryStat, error) {
+	var dst dirlist.Response
+	resp, status := sess.h.Dirlist(sess.id, &dirlist.Request{Path: path, Options: dirlist.WithStatInfo})
+	err := decode(resp, status, &dst)
+	return dst.Entries, err
+}
+
+func (sess *handlerSession) Open(ctx context.Context, path string, mode xrdfs.OpenMode, options xrdfs.OpenOptions) (file, error) {
+	var dst open.Response
+	resp, status := sess.h.Open(sess.id, &open.Request{Path: path, Mode: mode, Options: options})
+	err := decode(resp, status, &dst)
+	if err != nil {
+		return nil, err
+	}
+	return &handlerFile{sess: sess, handle: dst.FileHandle}, nil
+}
+
+func (sess *handlerSession) RemoveFile(ctx context.Context, path string) error {
+	resp, status := sess.h.Remove(sess.id, &rm.Request{Path: path})
+	return decode(resp, status, nil)
+}
+
+func (sess *handlerSession) RemoveDir(ctx context.Context, path string) error {
+	resp, status := sess.h.RemoveDir(sess.id, &rmdir.Request{Path: path})
+	return decode(resp, status, nil)
+}
+
+func (sess *handlerSession) Mkdir(ctx context.Context, path string, perm xrdfs.OpenMode) error {
+	resp, status := sess.h.Mkdir(sess.id, &mkdir.Request{Path: path, Mode: perm})
+	return decode(resp, status, nil)
+}
+
+// maxChunk is the maximum size of the data of the read and write requests
+// sent to a Handler.
+const maxChunk = 1 << 20
+
+// handlerFile is a file opened within a handlerSession.
+type handlerFile struct {
+	sess   *handlerSession
+	handle xrdfs.FileHandle
+}
+
+func (f *handlerFile) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		var dst read.Response
+		resp, status := f.sess.h.Read(f.sess.id, &read.Request{
+			Handle: f.handle,
+			Offset: off + int64(n),
+			Length: int32(min(len(p)-n, maxChunk)),
+		})
+		err := decode(resp, status, &dst)
+		if err != nil {
+			return n, err
+		}
+		if len(dst.Data) == 0 {
+			return n, io.EOF
+		}
+		n += copy(p[n:], dst.Data)
+	}
+	return n, nil
+}
+
+func (f *handlerFile) WriteAtContext(ctx context.Context, p []byte, off int64) error {
+	for len(p) > 0 {
+		n := min(len(p), maxChunk)
+		resp, status := f.sess.h.Write(f.sess.id, &write.Request{Handle: f.handle, Offset: off, Data: p[:n]})
+		err := decode(resp, status, nil)
+		if err != nil {
+			return err
+		}
+		p = p[n:]
+		off += int64(n)
+	}
+	return nil
+}
+
+func (f *handlerFile) Close(ctx context.Context) error {
+	resp, status := f.sess.h.Close(f.sess.id, &xrdclose.Request{Handle: f.handle})
+	return decode(resp, status, nil)
+}
+
+var (
+	_ storage = fsStorage{}
+	_ storage = (*handlerSession)(nil)
+	_ file    = (xrdfs.File)(nil)
+	_ file    = (*handlerFile)(nil)
+)
@@ -0,0 +1,498 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xrdhttp provides an HTTP(S)/WebDAV door to XRootD storages,
+// in the spirit of the XrdHttp plugin of the reference XRootD server.
+//
+// The door serves the files of an xrootd.Handler, such as the one used by
+// xrd-srv, or of an xrdfs.FileSystem, so that they are also reachable with
+// standard HTTP tools (curl, davix, web browsers...) and with the HTTP reader
+// of groot/riofs.
+//
+// The following methods are supported:
+//   - GET and HEAD, with byte ranges, return the content of a file or the listing of a directory;
+//   - PUT creates or replaces a file;
+//   - DELETE removes a file or an empty directory;
+//   - MKCOL creates a directory;
+//   - PROPFIND returns the properties of a file or of a directory and of its entries;
+//   - OPTIONS reports the supported methods.
+//
+// HTTPS is provided by serving the returned http.Handler with http.ListenAndServeTLS.
+// Clients may be required to authenticate with a bearer token, sent in the
+// Authorization header of HTTPS requests, with WithBearerTokens.
+package xrdhttp // import "go-hep.org/x/hep/xrootd/xrdhttp"
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+)
+
+// allowed lists the methods supported by the door.
+const allowed = "OPTIONS, GET, HEAD, PUT, DELETE, MKCOL, PROPFIND"
+
+const (
+	dirMode  = xrdfs.OpenModeOwnerRead | xrdfs.OpenModeOwnerWrite | xrdfs.OpenModeOwnerExecute | xrdfs.OpenModeGroupRead | xrdfs.OpenModeGroupExecute | xrdfs.OpenModeOtherRead | xrdfs.OpenModeOtherExecute
+	fileMode = xrdfs.OpenModeOwnerRead | xrdfs.OpenModeOwnerWrite | xrdfs.OpenModeGroupRead | xrdfs.OpenModeOtherRead
+)
+
+// door serves HTTP requests from a backend.
+type door struct {
+	backend  backend
+	validate func(token string) (string, error)
+}
+
+// Option configures an HTTP door.
+type Option func(*door)
+
+// WithBearerTokens requires clients to authenticate with a bearer token,
+// sent in the Authorization header of the request, in the spirit of the
+// ztn authentication of the XRootD protocol.
+// The validate function validates the token and returns the authenticated name.
+//
+// Bearer tokens are only accepted over HTTPS: plain HTTP requests are refused.
+func WithBearerTokens(validate func(token string) (string, error)) Option {
+	return func(d *door) {
+		d.validate = validate
+	}
+}
+
+func newDoor(b backend, opts []Option) *door {
+	d := &door{backend: b}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(d)
+	}
+	return d
+}
+
+// NewHandler returns an http.Handler serving HTTP and WebDAV requests
+// with the files served by the provided XRootD handler.
+// Each HTTP request is served within its own XRootD session.
+//
+// Redirections issued by the handler, e.g. by a redirector, are not followed
+// and are reported as server errors.
+//
+// Example:
+//
+//	h := xrootd.NewFSHandler("/data")
+//	go xrootd.NewServer(h, nil).Serve(listener)
+//	log.Fatal(http.ListenAndServe(":8080", xrdhttp.NewHandler(h)))
+func NewHandler(h xrootd.Handler, opts ...Option) http.Handler {
+	return newDoor(handlerBackend{h}, opts)
+}
+
+// NewFileSystemHandler returns an http.Handler serving HTTP and WebDAV requests
+// with the files of the provided XRootD file system, e.g. the one of a remote
+// server obtained with xrootd.Client.FS.
+func NewFileSystemHandler(fsys xrdfs.FileSystem, opts ...Option) http.Handler {
+	return newDoor(fsBackend{fsys}, opts)
+}
+
+// ServeHTTP implements http.Handler.
+func (d *door) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	info := xrootd.SessionInfo{
+		RemoteAddr: r.RemoteAddr,
+		TLS:        r.TLS != nil,
+	}
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		info.LocalAddr = addr.String()
+	}
+
+	if d.validate != nil {
+		id, code, err := d.authenticate(r)
+		if err != nil {
+			if code == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		info.Provider = "ztn"
+		info.Identity = id
+	}
+
+	st, done := d.backend.session(info)
+	defer done()
+
+	name := path.Clean("/" + r.URL.Path)
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Allow", allowed)
+		w.Header().Set("DAV", "1")
+	case http.MethodGet, http.MethodHead:
+		d.serveGet(st, w, r, name)
+	case http.MethodPut:
+		d.servePut(st, w, r, name)
+	case http.MethodDelete:
+		d.serveDelete(st, w, r, name)
+	case "MKCOL":
+		d.serveMkcol(st, w, r, name)
+	case "PROPFIND":
+		d.servePropfind(st, w, r, name)
+	default:
+		w.Header().Set("Allow", allowed)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authenticate validates the bearer token of the request.
+// It returns the authenticated name, or the HTTP status code and the error
+// refusing the request.
+func (d *door) authenticate(r *http.Request) (string, int, error) {
+	if r.TLS == nil {
+		return "", http.StatusForbidden, fmt.Errorf("bearer tokens are only accepted over HTTPS")
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", http.StatusUnauthorized, fmt.Errorf("missing bearer token")
+	}
+	id, err := d.validate(strings.TrimSpace(token))
+	if err != nil {
+		return "", http.StatusUnauthorized, fmt.Errorf("invalid bearer token")
+	}
+	return id, 0, nil
+}
+
+func (d *door) serveGet(st storage, w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+	es, err := st.Stat(ctx, name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	if es.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			localRedirect(w, r, path.Base(r.URL.Path)+"/")
+			return
+		}
+		ents, err := st.Dirlist(ctx, name)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		dirList(w, r, ents)
+		return
+	}
+
+	f, err := st.Open(ctx, name, 0, xrdfs.OpenOptionsOpenRead)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	defer f.Close(context.Background())
+
+	http.ServeContent(w, r, name, es.ModTime(), io.NewSectionReader(f, 0, es.Size()))
+}
+
+func (d *door) servePut(st storage, w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+	if name == "/" {
+		http.Error(w, "cannot write to the root directory", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parent, err := st.Stat(ctx, path.Dir(name))
+	switch {
+	case isNotExist(err):
+		http.Error(w, "parent directory does not exist", http.StatusConflict)
+		return
+	case err != nil:
+		httpError(w, err)
+		return
+	case !parent.IsDir():
+		http.Error(w, "parent is not a directory", http.StatusConflict)
+		return
+	}
+
+	code := http.StatusNoContent
+	es, err := st.Stat(ctx, name)
+	switch {
+	case isNotExist(err):
+		code = http.StatusCreated
+	case err != nil:
+		httpError(w, err)
+		return
+	case es.IsDir():
+		http.Error(w, "cannot overwrite a directory", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := st.Open(ctx, name, fileMode, xrdfs.OpenOptionsDelete|xrdfs.OpenOptionsOpenUpdate)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	var (
+		buf = make([]byte, maxChunk)
+		off int64
+	)
+	for {
+		n, rerr := io.ReadFull(r.Body, buf)
+		if n > 0 {
+			if err := f.WriteAtContext(ctx, buf[:n], off); err != nil {
+				_ = f.Close(context.Background())
+				httpError(w, err)
+				return
+			}
+			off += int64(n)
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			_ = f.Close(context.Background())
+			http.Error(w, fmt.Sprintf("could not read request body: %v", rerr), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := f.Close(ctx); err != nil {
+		httpError(w, err)
+		return
+	}
+	w.WriteHeader(code)
+}
+
+func (d *door) serveDelete(st storage, w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+	if name == "/" {
+		http.Error(w, "cannot remove the root directory", http.StatusForbidden)
+		return
+	}
+
+	es, err := st.Stat(ctx, name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	switch {
+	case es.IsDir():
+		ents, err := st.Dirlist(ctx, name)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		if len(ents) != 0 {
+			http.Error(w, "directory is not empty", http.StatusConflict)
+			return
+		}
+		err = st.RemoveDir(ctx, name)
+	default:
+		err = st.RemoveFile(ctx, name)
+	}
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d *door) serveMkcol(st storage, w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+	if r.ContentLength > 0 {
+		http.Error(w, "request body is not supported", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	_, err := st.Stat(ctx, name)
+	switch {
+	case err == nil:
+		http.Error(w, "resource already exists", http.StatusMethodNotAllowed)
+		return
+	case !isNotExist(err):
+		httpError(w, err)
+		return
+	}
+
+	parent, err := st.Stat(ctx, path.Dir(name))
+	switch {
+	case isNotExist(err):
+		http.Error(w, "parent directory does not exist", http.StatusConflict)
+		return
+	case err != nil:
+		httpError(w, err)
+		return
+	case !parent.IsDir():
+		http.Error(w, "parent is not a directory", http.StatusConflict)
+		return
+	}
+
+	err = st.Mkdir(ctx, name, dirMode)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (d *door) servePropfind(st storage, w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+	depth := r.Header.Get("Depth")
+	switch depth {
+	case "0", "1":
+	case "", "infinity":
+		http.Error(w, "infinite depth is not supported", http.StatusForbidden)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("invalid depth %q", depth), http.StatusBadRequest)
+		return
+	}
+	// The requested properties are ignored: all the supported ones are returned.
+	_, _ = io.Copy(io.Discard, r.Body)
+
+	es, err := st.Stat(ctx, name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	ms := multistatus{NS: "DAV:"}
+	ms.Responses = append(ms.Responses, newResponse(name, path.Base(name), es))
+	if depth == "1" && es.IsDir() {
+		ents, err := st.Dirlist(ctx, name)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		sortEntries(ents)
+		for _, ent := range ents {
+			ms.Responses = append(ms.Responses, newResponse(path.Join(name, ent.Name()), ent.Name(), ent))
+		}
+	}
+
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	_ = enc.Encode(ms)
+}
+
+// multistatus is the body of the response to a PROPFIND request.
+// See RFC 4918, section 14.16.
+type multistatus struct {
+	XMLName   xml.Name   `xml:"D:multistatus"`
+	NS        string     `xml:"xmlns:D,attr"`
+	Responses []response `xml:"D:response"`
+}
+
+type response struct {
+	Href     string   `xml:"D:href"`
+	Propstat propstat `xml:"D:propstat"`
+}
+
+type propstat struct {
+	Prop   prop   `xml:"D:prop"`
+	Status string `xml:"D:status"`
+}
+
+type prop struct {
+	DisplayName   string       `xml:"D:displayname"`
+	ContentLength *int64       `xml:"D:getcontentlength,omitempty"`
+	LastModified  string       `xml:"D:getlastmodified"`
+	ResourceType  resourceType `xml:"D:resourcetype"`
+}
+
+type resourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+}
+
+func newResponse(name, display string, es xrdfs.EntryStat) response {
+	resp := response{
+		Href: (&url.URL{Path: name}).EscapedPath(),
+		Propstat: propstat{
+			Prop: prop{
+				DisplayName:  display,
+				LastModified: es.ModTime().UTC().Format(http.TimeFormat),
+			},
+			Status: "HTTP/1.1 200 OK",
+		},
+	}
+	switch {
+	case es.IsDir():
+		if !strings.HasSuffix(resp.Href, "/") {
+			resp.Href += "/"
+		}
+		resp.Propstat.Prop.ResourceType.Collection = &struct{}{}
+	default:
+		size := es.Size()
+		resp.Propstat.Prop.ContentLength = &size
+	}
+	return resp
+}
+
+// dirList writes the HTML listing of the entries of a directory.
+func dirList(w http.ResponseWriter, r *http.Request, ents []xrdfs.EntryStat) {
+	sortEntries(ents)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	fmt.Fprintf(w, "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, ent := range ents {
+		name := ent.Name()
+		if ent.IsDir() {
+			name += "/"
+		}
+		href := (&url.URL{Path: name}).String()
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+	fmt.Fprintf(w, "</pre>\n")
+}
+
+func sortEntries(ents []xrdfs.EntryStat) {
+	sort.Slice(ents, func(i, j int) bool { return ents[i].Name() < ents[j].Name() })
+}
+
+// localRedirect redirects the request to the provided path, relative to the current directory.
+func localRedirect(w http.ResponseWriter, r *http.Request, newPath string) {
+	if q := r.URL.RawQuery; q != "" {
+		newPath += "?" + q
+	}
+	w.Header().Set("Location", newPath)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// isNotExist reports whether err reports a missing file.
+func isNotExist(err error) bool {
+	var serr xrdproto.ServerError
+	if errors.As(err, &serr) {
+		return serr.Code == xrdproto.NotFound
+	}
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// httpError replies to the request with the HTTP error corresponding to err.
+func httpError(w http.ResponseWriter, err error) {
+	var serr xrdproto.ServerError
+	switch {
+	case isNotExist(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &serr) && serr.Code == xrdproto.NotAuthorized,
+		errors.Is(err, fs.ErrPermission):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdhttp_test
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-hep.org/x/hep/groot/riofs"
+	_ "go-hep.org/x/hep/groot/riofs/plugin/http"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdhttp"
+)
+
+// newHandlers returns the HTTP doors under test, serving the files of dir.
+func newHandlers(t *testing.T, dir string) map[string]http.Handler {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	srv := xrootd.NewServer(xrootd.NewFSHandler(dir), func(err error) { t.Error(err) })
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != xrootd.ErrServerClosed {
+			t.Error(err)
+		}
+	}()
+
+	cli, err := xrootd.NewClient(context.Background(), listener.Addr().String(), "gopher")
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	t.Cleanup(func() {
+		cli.Close()
+		_ = srv.Shutdown(context.Background())
+	})
+
+	return map[string]http.Handler{
+		"handler": xrdhttp.NewHandler(xrootd.NewFSHandler(dir)),
+		"fs":      xrdhttp.NewFileSystemHandler(cli.FS()),
+	}
+}
+
+func do(t *testing.T, method, url string, body io.Reader, hdr map[string]string) (int, http.Header, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatalf("could not create %s request: %v", method, err)
+	}
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("could not send %s request: %v", method, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read %s response: %v", method, err)
+	}
+	return resp.StatusCode, resp.Header, string(raw)
+}
+
+func TestDoor(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello world"), 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+	err = os.Mkdir(filepath.Join(dir, "dir"), 0755)
+	if err != nil {
+		t.Fatalf("could not create test dir: %v", err)
+	}
+
+	for name, h := range newHandlers(t, dir) {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			code, hdr, body := do(t, http.MethodGet, srv.URL+"/file.txt", nil, nil)
+			if code != http.StatusOK || body != "hello world" {
+				t.Fatalf("invalid GET response: code=%d, body=%q", code, body)
+			}
+			if got := hdr.Get("Accept-Ranges"); got != "bytes" {
+				t.Fatalf("invalid Accept-Ranges: %q", got)
+			}
+
+			code, _, body = do(t, http.MethodGet, srv.URL+"/file.txt", nil, map[string]string{"Range": "bytes=6-"})
+			if code != http.StatusPartialContent || body != "world" {
+				t.Fatalf("invalid ranged GET response: code=%d, body=%q", code, body)
+			}
+
+			code, _, _ = do(t, http.MethodGet, srv.URL+"/missing.txt", nil, nil)
+			if code != http.StatusNotFound {
+				t.Fatalf("invalid GET response for missing file: code=%d", code)
+			}
+
+			code, _, _ = do(t, "MKCOL", srv.URL+"/"+name, nil, nil)
+			if code != http.StatusCreated {
+				t.Fatalf("invalid MKCOL response: code=%d", code)
+			}
+			code, _, _ = do(t, "MKCOL", srv.URL+"/"+name, nil, nil)
+			if code != http.StatusMethodNotAllowed {
+				t.Fatalf("invalid MKCOL response for existing dir: code=%d", code)
+			}
+			code, _, _ = do(t, "MKCOL", srv.URL+"/missing/"+name, nil, nil)
+			if code != http.StatusConflict {
+				t.Fatalf("invalid MKCOL response for missing parent: code=%d", code)
+			}
+
+			data := strings.Repeat("data", 1<<19)
+			code, _, _ = do(t, http.MethodPut, srv.URL+"/"+name+"/new.txt", strings.NewReader(data), nil)
+			if code != http.StatusCreated {
+				t.Fatalf("invalid PUT response: code=%d", code)
+			}
+			raw, err := os.ReadFile(filepath.Join(dir, name, "new.txt"))
+			if err != nil || string(raw) != data {
+				t.Fatalf("invalid uploaded file: err=%v, size=%d", err, len(raw))
+			}
+			code, _, _ = do(t, http.MethodPut, srv.URL+"/"+name+"/new.txt", strings.NewReader("new"), nil)
+			if code != http.StatusNoContent {
+				t.Fatalf("invalid PUT response for existing file: code=%d", code)
+			}
+			code, _, body = do(t, http.MethodGet, srv.URL+"/"+name+"/new.txt", nil, nil)
+			if code != http.StatusOK || body != "new" {
+				t.Fatalf("invalid GET response for overwritten file: code=%d, body=%q", code, body)
+			}
+			code, _, _ = do(t, http.MethodPut, srv.URL+"/missing/new.txt", strings.NewReader("new"), nil)
+			if code != http.StatusConflict {
+				t.Fatalf("invalid PUT response for missing parent: code=%d", code)
+			}
+
+			code, _, body = do(t, "PROPFIND", srv.URL+"/"+name, nil, map[string]string{"Depth": "1"})
+			if code != http.StatusMultiStatus {
+				t.Fatalf("invalid PROPFIND response: code=%d", code)
+			}
+			var ms struct {
+				Responses []struct {
+					Href string `xml:"href"`
+					Prop struct {
+						Length     string    `xml:"getcontentlength"`
+						Collection *struct{} `xml:"resourcetype>collection"`
+					} `xml:"propstat>prop"`
+				} `xml:"response"`
+			}
+			err = xml.Unmarshal([]byte(body), &ms)
+			if err != nil {
+				t.Fatalf("could not decode PROPFIND response: %v\n%s", err, body)
+			}
+			if len(ms.Responses) != 2 {
+				t.Fatalf("invalid number of PROPFIND responses: %d\n%s", len(ms.Responses), body)
+			}
+			if r := ms.Responses[0]; r.Href != "/"+name+"/" || r.Prop.Collection == nil {
+				t.Fatalf("invalid PROPFIND response for directory: %+v", r)
+			}
+			if r := ms.Responses[1]; r.Href != "/"+name+"/new.txt" || r.Prop.Collection != nil || r.Prop.Length != "3" {
+				t.Fatalf("invalid PROPFIND response for file: %+v", r)
+			}
+			code, _, _ = do(t, "PROPFIND", srv.URL+"/"+name, nil, map[string]string{"Depth": "infinity"})
+			if code != http.StatusForbidden {
+				t.Fatalf("invalid PROPFIND response for infinite depth: code=%d", code)
+			}
+
+			code, _, body = do(t, http.MethodGet, srv.URL+"/"+name+"/", nil, nil)
+			if code != http.StatusOK || !strings.Contains(body, `<a href="new.txt">new.txt</a>`) {
+				t.Fatalf("invalid directory listing: code=%d, body=%q", code, body)
+			}
+
+			code, _, _ = do(t, http.MethodDelete, srv.URL+"/"+name, nil, nil)
+			if code != http.StatusConflict {
+				t.Fatalf("invalid DELETE response for non-empty dir: code=%d", code)
+			}
+			code, _, _ = do(t, http.MethodDelete, srv.URL+"/"+name+"/new.txt", nil, nil)
+			if code != http.StatusNoContent {
+				t.Fatalf("invalid DELETE response: code=%d", code)
+			}
+			code, _, _ = do(t, http.MethodDelete, srv.URL+"/"+name, nil, nil)
+			if code != http.StatusNoContent {
+				t.Fatalf("invalid DELETE response for dir: code=%d", code)
+			}
+			code, _, _ = do(t, http.MethodDelete, srv.URL+"/"+name, nil, nil)
+			if code != http.StatusNotFound {
+				t.Fatalf("invalid DELETE response for missing dir: code=%d", code)
+			}
+			if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+				t.Fatalf("directory was not removed: %v", err)
+			}
+
+			code, hdr, _ = do(t, http.MethodOptions, srv.URL+"/", nil, nil)
+			if code != http.StatusOK || hdr.Get("DAV") != "1" {
+				t.Fatalf("invalid OPTIONS response: code=%d, hdr=%v", code, hdr)
+			}
+		})
+	}
+}
+
+func TestDoorROOT(t *testing.T) {
+	dir := t.TempDir()
+	raw, err := os.ReadFile("../../groot/testdata/simple.root")
+	if err != nil {
+		t.Fatalf("could not read ROOT file: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "simple.root"), raw, 0644)
+	if err != nil {
+		t.Fatalf("could not create ROOT file: %v", err)
+	}
+
+	srv := httptest.NewServer(xrdhttp.NewHandler(xrootd.NewFSHandler(dir)))
+	defer srv.Close()
+
+	f, err := riofs.Open(srv.URL + "/simple.root")
+	if err != nil {
+		t.Fatalf("could not open ROOT file over HTTP: %v", err)
+	}
+	defer f.Close()
+
+	obj, err := riofs.Dir(f).Get("tree")
+	if err != nil {
+		t.Fatalf("could not retrieve tree: %v", err)
+	}
+	if got, want := obj.(rtree.Tree).Entries(), int64(4); got != want {
+		t.Fatalf("invalid number of entries: got=%d, want=%d", got, want)
+	}
+}
+
+// infoHandler records the description of the sessions of a handler.
+type infoHandler struct {
+	xrootd.Handler
+
+	mu    sync.Mutex
+	infos []xrootd.SessionInfo
+}
+
+func (h *infoHandler) SetSessionInfo(sessionID [16]byte, info xrootd.SessionInfo) {
+	h.mu.Lock()
+	h.infos = append(h.infos, info)
+	h.mu.Unlock()
+	h.Handler.SetSessionInfo(sessionID, info)
+}
+
+func TestDoorBearerTokens(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello world"), 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+
+	h := &infoHandler{Handler: xrootd.NewFSHandler(dir)}
+	door := xrdhttp.NewHandler(h, xrdhttp.WithBearerTokens(func(token string) (string, error) {
+		if token != "secret" {
+			return "", fmt.Errorf("invalid token")
+		}
+		return "gopher", nil
+	}))
+
+	get := func(t *testing.T, cli *http.Client, url, auth string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("could not create request: %v", err)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := cli.Do(req)
+		if err != nil {
+			t.Fatalf("could not send request: %v", err)
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode
+	}
+
+	t.Run("http", func(t *testing.T) {
+		srv := httptest.NewServer(door)
+		defer srv.Close()
+
+		if got, want := get(t, srv.Client(), srv.URL+"/file.txt", "Bearer secret"), http.StatusForbidden; got != want {
+			t.Fatalf("invalid status code: got=%d, want=%d", got, want)
+		}
+	})
+
+	t.Run("https", func(t *testing.T) {
+		srv := httptest.NewTLSServer(door)
+		defer srv.Close()
+
+		for _, tc := range []struct {
+			auth string
+			want int
+		}{
+			{"", http.StatusUnauthorized},
+			{"Basic c2VjcmV0", http.StatusUnauthorized},
+			{"Bearer wrong", http.StatusUnauthorized},
+			{"Bearer secret", http.StatusOK},
+		} {
+			if got := get(t, srv.Client(), srv.URL+"/file.txt", tc.auth); got != tc.want {
+				t.Fatalf("auth=%q: invalid status code: got=%d, want=%d", tc.auth, got, tc.want)
+			}
+		}
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if len(h.infos) != 1 {
+			t.Fatalf("invalid number of sessions: got=%d, want=1", len(h.infos))
+		}
+		info := h.infos[0]
+		if info.LocalAddr != srv.Listener.Addr().String() || info.RemoteAddr == "" {
+			t.Fatalf("invalid session addresses: %+v", info)
+		}
+		if !info.TLS || info.Provider != "ztn" || info.Identity != "gopher" {
+			t.Fatalf("invalid session info: %+v", info)
+		}
+	})
+}
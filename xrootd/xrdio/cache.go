@@ -0,0 +1,222 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdio // import "go-hep.org/x/hep/xrootd/xrdio"
+
+import (
+	"container/list"
+	"io"
+	"sync"
+)
+
+const (
+	defaultBlockSize = 1 << 20  // defaultBlockSize is the default size of the blocks of a Cache.
+	defaultReadAhead = 4        // defaultReadAhead is the default number of blocks read ahead by a Cache.
+	defaultCacheSize = 64 << 20 // defaultCacheSize is the default size of a Cache.
+)
+
+// Cache is a block cache for files opened with Open and OpenFrom,
+// which reads blocks ahead asynchronously when files are read sequentially.
+// A Cache may be shared by several files, the blocks of files with the same name
+// on the same server being shared as well.
+// A Cache is safe for concurrent use.
+//
+// The blocks of a file are discarded when the file is opened again and its size or
+// modification time has changed, or when the file is written to through the cache.
+type Cache struct {
+	blockSize int64
+	readAhead int64
+	maxBlocks int
+
+	mu     sync.Mutex
+	blocks map[blockKey]*list.Element // blocks are the cached blocks, referenced from lru.
+	lru    *list.List                 // lru holds the cached blocks, most recently used first.
+	stamps map[fileKey]fileStamp      // stamps are the versions of the files with cached blocks.
+}
+
+// NewCache returns a Cache holding up to size bytes, in blocks of blockSize bytes,
+// reading readAhead blocks ahead of sequential reads.
+// Zero or negative values of blockSize and size select the defaults, 1 MiB blocks
+// and 64 MiB of cache, and a negative readAhead the default 4 blocks.
+// A zero readAhead disables reading ahead.
+func NewCache(blockSize, readAhead int, size int64) *Cache {
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	}
+	if readAhead < 0 {
+		readAhead = defaultReadAhead
+	}
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	// Keep room for the blocks of a read and the ones read ahead of it.
+	maxBlocks := max(int(size/int64(blockSize)), readAhead+2)
+
+	return &Cache{
+		blockSize: int64(blockSize),
+		readAhead: int64(readAhead),
+		maxBlocks: maxBlocks,
+		blocks:    make(map[blockKey]*list.Element),
+		lru:       list.New(),
+		stamps:    make(map[fileKey]fileStamp),
+	}
+}
+
+// fileKey identifies a file on a server.
+type fileKey struct {
+	src  any // src identifies the server, e.g. with a connKey.
+	name string
+}
+
+// fileStamp identifies the version of a file.
+type fileStamp struct {
+	size  int64
+	mtime int64
+}
+
+type blockKey struct {
+	file  fileKey
+	index int64
+}
+
+// block is a block of a file, possibly being read from the server.
+type block struct {
+	key  blockKey
+	done chan struct{} // done is closed once the block has been read.
+	data []byte
+	err  error
+}
+
+// register registers a file opened with the provided version,
+// discarding the cached blocks of any other version of that file.
+func (c *Cache) register(key fileKey, stamp fileStamp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.stamps[key]; ok && old != stamp {
+		c.invalidateLocked(key)
+	}
+	c.stamps[key] = stamp
+}
+
+// invalidate discards the cached blocks of the provided file.
+func (c *Cache) invalidate(key fileKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(key)
+}
+
+func (c *Cache) invalidateLocked(key fileKey) {
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if b := e.Value.(*block); b.key.file == key {
+			c.lru.Remove(e)
+			delete(c.blocks, b.key)
+		}
+		e = next
+	}
+}
+
+// readAt reads len(p) bytes of f into p, starting at offset off, through the cache.
+func (c *Cache) readAt(f *File, p []byte, off int64) (int, error) {
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	end := min(off+int64(len(p)), f.size)
+	if end == off {
+		return 0, nil
+	}
+
+	var (
+		first = off / c.blockSize
+		last  = (end - 1) / c.blockSize
+		prev  = f.last.Swap(last)
+	)
+	blocks := make([]*block, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		blocks = append(blocks, c.block(f, i))
+	}
+	if first == prev || first == prev+1 {
+		// Sequential read: read the next blocks ahead.
+		for i := last + 1; i <= last+c.readAhead && i*c.blockSize < f.size; i++ {
+			c.block(f, i)
+		}
+	}
+
+	n := 0
+	for _, b := range blocks {
+		<-b.done
+		pos := off + int64(n)
+		if b.err != nil {
+			// The block may have been read through another, since closed, file:
+			// read the remaining data directly.
+			nn, err := f.f.ReadAtContext(f.ctx, p[n:end-off], pos)
+			n += nn
+			if err != nil {
+				return n, err
+			}
+			break
+		}
+		n += copy(p[n:end-off], b.data[pos-b.key.index*c.blockSize:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// block returns the block of f with the provided index,
+// reading it asynchronously if it is not cached.
+func (c *Cache) block(f *File, index int64) *block {
+	key := blockKey{file: f.key, index: index}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.blocks[key]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*block)
+	}
+
+	b := &block{key: key, done: make(chan struct{})}
+	c.blocks[key] = c.lru.PushFront(b)
+	for c.lru.Len() > c.maxBlocks {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.blocks, e.Value.(*block).key)
+	}
+
+	go c.fetch(f, b)
+	return b
+}
+
+// fetch reads the block b of f from the server.
+func (c *Cache) fetch(f *File, b *block) {
+	defer close(b.done)
+
+	var (
+		off  = b.key.index * c.blockSize
+		data = make([]byte, min(c.blockSize, f.size-off))
+		n    = 0
+	)
+	for n < len(data) {
+		nn, err := f.f.ReadAtContext(f.ctx, data[n:], off+int64(n))
+		if err == nil && nn == 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			b.err = err
+			c.mu.Lock()
+			if e, ok := c.blocks[b.key]; ok && e.Value.(*block) == b {
+				c.lru.Remove(e)
+				delete(c.blocks, b.key)
+			}
+			c.mu.Unlock()
+			return
+		}
+		n += nn
+	}
+	b.data = data
+}
@@ -0,0 +1,302 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdio
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/xrootd"
+)
+
+func serve(t *testing.T, dir string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	srv := xrootd.NewServer(xrootd.NewFSHandler(dir), func(err error) { t.Error(err) })
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != xrootd.ErrServerClosed {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		CloseIdleConnections()
+		_ = srv.Shutdown(context.Background())
+	})
+
+	return listener.Addr().String()
+}
+
+func TestOpenPool(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"file1.txt", "file2.txt"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte("data from "+name), 0644)
+		if err != nil {
+			t.Fatalf("could not create test file: %v", err)
+		}
+	}
+	addr := serve(t, dir)
+
+	defer func(v time.Duration) { idleTimeout = v }(idleTimeout)
+	idleTimeout = 50 * time.Millisecond
+
+	f1, err := Open("root://" + addr + "/file1.txt")
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	f2, err := Open("root://" + addr + "/file2.txt")
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	if f1.conn != f2.conn {
+		t.Fatalf("files from the same server do not share their connection")
+	}
+
+	f3, err := Open("root://other@" + addr + "/file2.txt")
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	if f3.conn == f1.conn {
+		t.Fatalf("files opened by different users share their connection")
+	}
+	f3.Close()
+
+	_, err = Open("root://" + addr + "/missing.txt")
+	if err == nil {
+		t.Fatalf("expected an error opening a missing file")
+	}
+
+	err = f1.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %v", err)
+	}
+	err = f2.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %v", err)
+	}
+
+	// The connection is kept open for a while.
+	f4, err := Open("root://" + addr + "/file1.txt")
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	if f4.conn != f1.conn {
+		t.Fatalf("idle connection was not reused")
+	}
+	got, err := io.ReadAll(f4)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	if want := "data from file1.txt"; string(got) != want {
+		t.Fatalf("invalid data: got=%q, want=%q", got, want)
+	}
+	f4.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		pool.mu.Lock()
+		_, ok := pool.conns[f1.conn.key]
+		pool.mu.Unlock()
+		if !ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("idle connection was not closed")
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 3*1024*1024+42)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatalf("could not prepare test data: %v", err)
+	}
+	name := filepath.Join(dir, "file.bin")
+	err = os.WriteFile(name, data, 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+	addr := serve(t, dir)
+
+	const blockSize = 64 * 1024
+	cache := NewCache(blockSize, 4, 1024*1024)
+
+	f1, err := Open("root://"+addr+"/file.bin", WithCache(cache))
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	defer f1.Close()
+
+	// Sequential reads.
+	var (
+		got = new(bytes.Buffer)
+		buf = make([]byte, 10000)
+	)
+	_, err = io.CopyBuffer(got, struct{ io.Reader }{f1}, buf)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Fatalf("invalid data read sequentially")
+	}
+
+	cache.mu.Lock()
+	n := cache.lru.Len()
+	cache.mu.Unlock()
+	if n == 0 || n > cache.maxBlocks {
+		t.Fatalf("invalid number of cached blocks: %d (max=%d)", n, cache.maxBlocks)
+	}
+
+	// Random reads, across blocks and at the end of the file.
+	for _, tc := range []struct {
+		off int64
+		n   int
+		err error
+	}{
+		{off: 0, n: 10},
+		{off: blockSize - 5, n: 10},
+		{off: 2*blockSize - 5, n: 3 * blockSize},
+		{off: int64(len(data)) - 10, n: 10},
+		{off: int64(len(data)) - 10, n: 20, err: io.EOF},
+		{off: int64(len(data)), n: 10, err: io.EOF},
+	} {
+		p := make([]byte, tc.n)
+		n, err := f1.ReadAt(p, tc.off)
+		if err != tc.err {
+			t.Fatalf("off=%d, n=%d: invalid error: got=%v, want=%v", tc.off, tc.n, err, tc.err)
+		}
+		want := data[min(tc.off, int64(len(data))):min(tc.off+int64(tc.n), int64(len(data)))]
+		if !bytes.Equal(p[:n], want) {
+			t.Fatalf("off=%d, n=%d: invalid data", tc.off, tc.n)
+		}
+	}
+
+	// Blocks are shared across files.
+	f2, err := Open("root://"+addr+"/file.bin", WithCache(cache))
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	defer f2.Close()
+	if f2.key != f1.key {
+		t.Fatalf("files do not share their blocks")
+	}
+	p := make([]byte, 10)
+	_, err = f2.ReadAt(p, 0)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	if !bytes.Equal(p, data[:10]) {
+		t.Fatalf("invalid data read from shared blocks")
+	}
+
+	// Blocks are discarded when the file changes.
+	data = append(data[:1024:1024], "modified"...)
+	err = os.WriteFile(name, data, 0644)
+	if err != nil {
+		t.Fatalf("could not modify test file: %v", err)
+	}
+	f3, err := Open("root://"+addr+"/file.bin", WithCache(cache))
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	defer f3.Close()
+	got.Reset()
+	_, err = io.Copy(got, struct{ io.Reader }{f3})
+	if err != nil {
+		t.Fatalf("could not read modified file: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Fatalf("invalid data read from modified file")
+	}
+}
+
+func TestFileSeekReadAt(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte("0123456789abcdefghij")
+	err := os.WriteFile(filepath.Join(dir, "file.txt"), data, 0644)
+	if err != nil {
+		t.Fatalf("could not create test file: %v", err)
+	}
+	addr := serve(t, dir)
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "direct"},
+		{name: "cached", opts: []Option{WithCache(NewCache(8, 4, 1024))}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := Open("root://"+addr+"/file.txt", tc.opts...)
+			if err != nil {
+				t.Fatalf("could not open file: %v", err)
+			}
+			defer f.Close()
+
+			for _, v := range []struct {
+				off int64
+				n   int
+				err error
+			}{
+				{off: 0, n: len(data)},
+				{off: 15, n: 10, err: io.EOF},
+				{off: int64(len(data)), n: 1, err: io.EOF},
+			} {
+				p := make([]byte, v.n)
+				n, err := f.ReadAt(p, v.off)
+				if err != v.err {
+					t.Fatalf("off=%d, n=%d: invalid error: got=%v, want=%v", v.off, v.n, err, v.err)
+				}
+				want := data[min(v.off, int64(len(data))):min(v.off+int64(v.n), int64(len(data)))]
+				if !bytes.Equal(p[:n], want) {
+					t.Fatalf("off=%d, n=%d: invalid data: got=%q, want=%q", v.off, v.n, p[:n], want)
+				}
+			}
+
+			for _, v := range []struct {
+				off    int64
+				whence int
+				want   int64
+			}{
+				{off: 0, whence: io.SeekEnd, want: int64(len(data))},
+				{off: -5, whence: io.SeekEnd, want: int64(len(data)) - 5},
+				{off: 3, whence: io.SeekStart, want: 3},
+				{off: 2, whence: io.SeekCurrent, want: 5},
+			} {
+				pos, err := f.Seek(v.off, v.whence)
+				if err != nil {
+					t.Fatalf("could not seek (off=%d, whence=%d): %v", v.off, v.whence, err)
+				}
+				if pos != v.want {
+					t.Fatalf("invalid position (off=%d, whence=%d): got=%d, want=%d", v.off, v.whence, pos, v.want)
+				}
+			}
+
+			_, err = f.Seek(-4, io.SeekEnd)
+			if err != nil {
+				t.Fatalf("could not seek: %v", err)
+			}
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatalf("could not read file: %v", err)
+			}
+			if want := data[len(data)-4:]; !bytes.Equal(got, want) {
+				t.Fatalf("invalid data: got=%q, want=%q", got, want)
+			}
+		})
+	}
+}
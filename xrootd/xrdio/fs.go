@@ -0,0 +1,180 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdio // import "go-hep.org/x/hep/xrootd/xrdio"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+)
+
+// DirFS returns a file system (an fs.FS) for the tree of files rooted at the
+// directory dir of the provided xrootd file system.
+//
+// The returned file system implements fs.ReadDirFS and fs.StatFS.
+// Its files are opened for reading with OpenFrom, using the provided options.
+//
+// Example:
+//
+//	cli, err := xrootd.NewClient(ctx, "server.example.com:1094", "gopher")
+//	fsys := xrdio.DirFS(cli.FS(), "/some/path")
+//	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error { ... })
+func DirFS(fsys xrdfs.FileSystem, dir string, opts ...Option) fs.FS {
+	return &dirFS{fsys: fsys, dir: dir, opts: opts}
+}
+
+type dirFS struct {
+	fsys xrdfs.FileSystem
+	dir  string
+	opts []Option
+}
+
+// join returns the path on the server of the named file.
+func (fsys *dirFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(fsys.dir, name), nil
+}
+
+// Open implements fs.FS.
+func (fsys *dirFS) Open(name string) (fs.File, error) {
+	fi, err := fsys.Stat(name)
+	if err != nil {
+		err.(*fs.PathError).Op = "open"
+		return nil, err
+	}
+
+	full, _ := fsys.join("open", name)
+	if fi.IsDir() {
+		return &dirFile{fsys: fsys, name: name, info: fi}, nil
+	}
+
+	f, err := OpenFrom(fsys.fsys, full, fsys.opts...)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	return &file{File: f, info: fi}, nil
+}
+
+// Stat implements fs.StatFS.
+func (fsys *dirFS) Stat(name string) (fs.FileInfo, error) {
+	full, err := fsys.join("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	es, err := fsys.fsys.Stat(context.Background(), full)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return fileInfo{es, path.Base(name)}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (fsys *dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, err := fsys.join("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	ents, err := fsys.fsys.Dirlist(context.Background(), full)
+	if err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+
+	dirs := make([]fs.DirEntry, len(ents))
+	for i, es := range ents {
+		dirs[i] = fs.FileInfoToDirEntry(fileInfo{es, es.Name()})
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() < dirs[j].Name() })
+	return dirs, nil
+}
+
+// pathError returns the error of the operation op on the named file,
+// reporting missing files and denied accesses as fs.ErrNotExist and fs.ErrPermission.
+func pathError(op, name string, err error) error {
+	var serr xrdproto.ServerError
+	if errors.As(err, &serr) {
+		switch serr.Code {
+		case xrdproto.NotFound:
+			err = fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+		case xrdproto.NotAuthorized:
+			err = fmt.Errorf("%w: %w", fs.ErrPermission, err)
+		}
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// fileInfo is the stat information of a file, named after its path in a dirFS.
+type fileInfo struct {
+	xrdfs.EntryStat
+	name string
+}
+
+func (fi fileInfo) Name() string { return fi.name }
+
+// file is a regular file of a dirFS.
+type file struct {
+	*File
+	info fs.FileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// dirFile is a directory of a dirFS.
+type dirFile struct {
+	fsys *dirFS
+	name string
+	info fs.FileInfo
+	ents []fs.DirEntry // ents are the entries not yet returned by ReadDir.
+	read bool          // read indicates whether the entries have been listed.
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) Close() error { return nil }
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		ents, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.ents = ents
+		d.read = true
+	}
+
+	if n <= 0 {
+		ents := d.ents
+		d.ents = nil
+		return ents, nil
+	}
+	if len(d.ents) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.ents))
+	ents := d.ents[:n:n]
+	d.ents = d.ents[n:]
+	return ents, nil
+}
+
+var (
+	_ fs.ReadDirFS   = (*dirFS)(nil)
+	_ fs.StatFS      = (*dirFS)(nil)
+	_ fs.ReadDirFile = (*dirFile)(nil)
+)
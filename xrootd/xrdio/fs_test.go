@@ -0,0 +1,73 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdio
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"go-hep.org/x/hep/xrootd"
+)
+
+func TestDirFS(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"file1.txt":         "data from file1",
+		"dir/file2.txt":     "data from file2",
+		"dir/sub/file3.txt": "data from file3",
+		"dir/empty.txt":     "",
+	} {
+		name = filepath.Join(dir, "root", name)
+		err := os.MkdirAll(filepath.Dir(name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(name, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addr := serve(t, dir)
+
+	cli, err := xrootd.NewClient(context.Background(), addr, "gopher")
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer cli.Close()
+
+	for _, opts := range [][]Option{nil, {WithCache(NewCache(4, 1, 0))}} {
+		fsys := DirFS(cli.FS(), "/root", opts...)
+		err = fstest.TestFS(fsys, "file1.txt", "dir/file2.txt", "dir/sub/file3.txt", "dir/empty.txt")
+		if err != nil {
+			t.Fatalf("invalid file system: %v", err)
+		}
+
+		got, err := fs.ReadFile(fsys, "dir/sub/file3.txt")
+		if err != nil {
+			t.Fatalf("could not read file: %v", err)
+		}
+		if want := "data from file3"; string(got) != want {
+			t.Fatalf("invalid data: got=%q, want=%q", got, want)
+		}
+	}
+
+	fsys := DirFS(cli.FS(), "/root")
+	for _, name := range []string{"/file1.txt", "../file1.txt", "dir/"} {
+		_, err := fsys.Open(name)
+		if !errors.Is(err, fs.ErrInvalid) {
+			t.Fatalf("invalid error for %q: %v", name, err)
+		}
+	}
+	_, err = fsys.Open("missing.txt")
+	var perr *fs.PathError
+	if !errors.As(err, &perr) || perr.Op != "open" || perr.Path != "missing.txt" {
+		t.Fatalf("invalid error for missing file: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdio // import "go-hep.org/x/hep/xrootd/xrdio"
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+)
+
+// idleTimeout is the duration during which a connection to a server
+// is kept open once no file opened with Open uses it anymore.
+var idleTimeout = 30 * time.Second
+
+// pool holds the connections to the servers shared by the files opened with Open.
+var pool = connPool{conns: make(map[connKey]*conn)}
+
+// connKey identifies the connections that may be shared.
+type connKey struct {
+	addr string
+	user string
+	tls  bool
+}
+
+// conn is a connection to a server, shared by the files opened with Open.
+type conn struct {
+	key  connKey
+	cli  *xrootd.Client
+	fs   xrdfs.FileSystem
+	refs int         // refs is the number of files using the connection.
+	idle *time.Timer // idle closes the connection once unused for idleTimeout.
+}
+
+type connPool struct {
+	mu    sync.Mutex
+	conns map[connKey]*conn
+}
+
+// get returns a connection to the server of urn, reusing an existing one if possible.
+func (p *connPool) get(ctx context.Context, urn URL) (*conn, error) {
+	key := connKey{addr: urn.Addr, user: urn.User, tls: urn.TLS}
+	if c := p.reuse(key); c != nil {
+		return c, nil
+	}
+
+	var opts []xrootd.Option
+	if urn.TLS {
+		opts = append(opts, xrootd.WithTLS(nil))
+	}
+
+	cli, err := xrootd.NewClient(ctx, urn.Addr, urn.User, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	// Another connection to the same server may have been created concurrently.
+	if c := p.reuseLocked(key); c != nil {
+		p.mu.Unlock()
+		_ = cli.Close()
+		return c, nil
+	}
+	c := &conn{key: key, cli: cli, fs: cli.FS(), refs: 1}
+	p.conns[key] = c
+	p.mu.Unlock()
+
+	return c, nil
+}
+
+// reuse returns the pooled connection for key, if any.
+func (p *connPool) reuse(key connKey) *conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reuseLocked(key)
+}
+
+// reuseLocked is like reuse but must be called with p.mu held.
+func (p *connPool) reuseLocked(key connKey) *conn {
+	c, ok := p.conns[key]
+	if !ok {
+		return nil
+	}
+	if c.idle != nil {
+		c.idle.Stop()
+		c.idle = nil
+	}
+	c.refs++
+	return c
+}
+
+// put releases the connection.
+// The connection is closed once unused for idleTimeout.
+func (p *connPool) put(c *conn) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c.refs--
+	if c.refs > 0 {
+		return nil
+	}
+	if p.conns[c.key] != c {
+		// The connection was discarded.
+		return c.cli.Close()
+	}
+
+	c.idle = time.AfterFunc(idleTimeout, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if c.refs > 0 || p.conns[c.key] != c {
+			return
+		}
+		delete(p.conns, c.key)
+		_ = c.cli.Close()
+	})
+	return nil
+}
+
+// discard removes the connection from the pool, e.g. after a connection failure,
+// so that it is not reused by subsequent calls to Open.
+// The connection is closed once released by all of its files.
+func (p *connPool) discard(c *conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conns[c.key] == c {
+		delete(p.conns, c.key)
+	}
+}
+
+// CloseIdleConnections closes the connections to the servers kept open
+// after all the files opened with Open using them have been closed.
+// It does not interrupt any connection currently in use.
+func CloseIdleConnections() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for key, c := range pool.conns {
+		if c.refs > 0 {
+			continue
+		}
+		if c.idle != nil {
+			c.idle.Stop()
+			c.idle = nil
+		}
+		delete(pool.conns, key)
+		_ = c.cli.Close()
+	}
+}
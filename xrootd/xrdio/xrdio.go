@@ -7,13 +7,15 @@ package xrdio // import "go-hep.org/x/hep/xrootd/xrdio"
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"sync/atomic"
 
-	"go-hep.org/x/hep/xrootd"
 	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
 )
 
 // File wraps a xrdfs.File and implements the following interfaces:
@@ -25,46 +27,70 @@ import (
 //   - io.Seeker
 //   - fs.File
 type File struct {
-	cli *xrootd.Client
-	fs  xrdfs.FileSystem
-	f   xrdfs.File
+	conn *conn // conn is the pooled connection of files opened with Open.
+	fs   xrdfs.FileSystem
+	f    xrdfs.File
 
 	name string
 	pos  int64
 	size int64
+
+	cache  *Cache
+	key    fileKey      // key identifies the file in the cache.
+	last   atomic.Int64 // last is the index of the last block read through the cache.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Option configures how files are opened.
+type Option func(*config)
+
+type config struct {
+	cache *Cache
+}
+
+// WithCache reads the files through the provided block cache.
+// The same cache may be used for several files.
+func WithCache(c *Cache) Option {
+	return func(cfg *config) {
+		cfg.cache = c
+	}
 }
 
 // Open opens the name file, where name is the absolute location of that file
 // (xrootd server address and path to the file on that server.)
+// The roots:// and xroots:// schemes use a TLS-encrypted connection to the server.
+//
+// Files opened from the same server, as the same user, share the same connection,
+// which is kept open for a while after the last of these files has been closed,
+// to be reused by subsequent calls to Open.
 //
 // Example:
 //
 //	f, err := xrdio.Open("root://server.example.com:1094//some/path/to/file")
-func Open(name string) (*File, error) {
+//	f, err := xrdio.Open("root://server.example.com:1094//some/path/to/file", xrdio.WithCache(cache))
+func Open(name string, opts ...Option) (*File, error) {
 	urn, err := Parse(name)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse %q: %w", name, err)
 	}
 
-	xrd, err := xrootd.NewClient(context.Background(), urn.Addr, urn.User)
+	c, err := pool.get(context.Background(), urn)
 	if err != nil {
 		return nil, fmt.Errorf("xrdio: could not connect to xrootd server %q: %w", urn.Addr, err)
 	}
 
-	fs := xrd.FS()
-	f, err := fs.Open(context.Background(), urn.Path, xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	xf, err := open(c.fs, urn.Path, c.key, opts)
 	if err != nil {
-		xrd.Close()
+		var serr xrdproto.ServerError
+		if !errors.As(err, &serr) {
+			// Do not reuse a connection that may be broken.
+			pool.discard(c)
+		}
+		_ = pool.put(c)
 		return nil, fmt.Errorf("xrdio: could not open %q: %w", name, err)
 	}
-
-	xf := &File{cli: xrd, fs: fs, f: f, name: urn.Path}
-	fi, err := xf.Stat()
-	if err != nil {
-		xrd.Close()
-		return nil, fmt.Errorf("xrdio: could not stat %q: %w", name, err)
-	}
-	xf.size = fi.Size()
+	xf.conn = c
 
 	return xf, nil
 }
@@ -75,18 +101,41 @@ func Open(name string) (*File, error) {
 // Example:
 //
 //	f, err := xrdio.OpenFrom(fs, "/some/path/to/file")
-func OpenFrom(fs xrdfs.FileSystem, name string) (*File, error) {
-	f, err := fs.Open(context.Background(), name, xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+func OpenFrom(fs xrdfs.FileSystem, name string, opts ...Option) (*File, error) {
+	xf, err := open(fs, name, fs, opts)
 	if err != nil {
 		return nil, fmt.Errorf("xrdio: could not open %q: %w", name, err)
 	}
+	return xf, nil
+}
+
+// open opens the file name via the given filesystem handle.
+// src identifies the server of the filesystem in the cache.
+func open(fs xrdfs.FileSystem, name string, src any, opts []Option) (*File, error) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	f, err := fs.Open(context.Background(), name, xrdfs.OpenModeOwnerRead, xrdfs.OpenOptionsOpenRead)
+	if err != nil {
+		return nil, err
+	}
 
-	xf := &File{fs: fs, f: f, name: name}
+	xf := &File{fs: fs, f: f, name: name, cache: cfg.cache}
 	fi, err := xf.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("xrdio: could not stat %q: %w", name, err)
+		_ = f.Close(context.Background())
+		return nil, fmt.Errorf("could not stat: %w", err)
 	}
 	xf.size = fi.Size()
+	xf.ctx, xf.cancel = context.WithCancel(context.Background())
+	xf.last.Store(-1)
+
+	if xf.cache != nil {
+		xf.key = fileKey{src: src, name: name}
+		xf.cache.register(xf.key, fileStamp{size: fi.Size(), mtime: fi.ModTime().Unix()})
+	}
 
 	return xf, nil
 }
@@ -102,13 +151,16 @@ func (f *File) Close() error {
 		return os.ErrInvalid
 	}
 
+	// Stop reading ahead.
+	f.cancel()
+
 	var (
 		err1 = f.f.Close(context.Background())
 		err2 error
 	)
 
-	if f.cli != nil {
-		err2 = f.cli.Close()
+	if f.conn != nil {
+		err2 = pool.put(f.conn)
 	}
 	if err1 != nil {
 		return fmt.Errorf("xrdio: could not close file %q: %w", f.name, err1)
@@ -121,7 +173,7 @@ func (f *File) Close() error {
 
 // Read implements io.Reader.
 func (f *File) Read(data []byte) (int, error) {
-	n, err := f.f.ReadAt(data, f.pos)
+	n, err := f.ReadAt(data, f.pos)
 	f.pos += int64(n)
 	if err != nil {
 		return n, err
@@ -134,18 +186,28 @@ func (f *File) Read(data []byte) (int, error) {
 
 // ReadAt implements io.ReaderAt.
 func (f *File) ReadAt(data []byte, offset int64) (int, error) {
-	return f.f.ReadAt(data, offset)
+	if f.cache != nil {
+		return f.cache.readAt(f, data, offset)
+	}
+	n, err := f.f.ReadAt(data, offset)
+	if err == nil && n < len(data) {
+		err = io.EOF
+	}
+	return n, err
 }
 
 // Write implements io.Writer.
 func (f *File) Write(data []byte) (int, error) {
-	n, err := f.f.WriteAt(data, f.pos)
+	n, err := f.WriteAt(data, f.pos)
 	f.pos += int64(n)
 	return n, err
 }
 
 // WriteAt implements io.WriterAt.
 func (f *File) WriteAt(data []byte, offset int64) (int, error) {
+	if f.cache != nil {
+		defer f.cache.invalidate(f.key)
+	}
 	return f.f.WriteAt(data, offset)
 }
 
@@ -160,7 +222,7 @@ func (f *File) Seek(offset int64, whence int) (int64, error) {
 		if err != nil {
 			return 0, fmt.Errorf("xrdio: could not xrootd-stat %q: %w", f.Name(), err)
 		}
-		f.pos = st.Size() - offset
+		f.pos = st.Size() + offset
 	case io.SeekCurrent:
 		f.pos += offset
 	}
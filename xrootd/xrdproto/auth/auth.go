@@ -6,6 +6,8 @@
 package auth // import "go-hep.org/x/hep/xrootd/xrdproto/auth"
 
 import (
+	"strings"
+
 	"go-hep.org/x/hep/xrootd/internal/xrdenc"
 )
 
@@ -42,8 +44,21 @@ func (o *Request) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
 	return nil
 }
 
+// Provider returns the name of the security provider used by the request.
+func (o *Request) Provider() string {
+	return strings.TrimRight(string(o.Type[:]), "\x00")
+}
+
 // Auther is the interface that must be implemented by a security provider.
 type Auther interface {
 	Provider() string                          // Provider returns the name of the security provider.
 	Request(params []string) (*Request, error) // Request forms an authorization Request according to passed parameters.
 }
+
+// Verifier is the interface that must be implemented by a security provider
+// to verify the credentials sent by the clients, on the server side.
+type Verifier interface {
+	Provider() string                    // Provider returns the name of the security provider.
+	Params() string                      // Params returns the parameters of the security provider sent to the clients upon login.
+	Verify(req *Request) (string, error) // Verify verifies the credentials of the request and returns the authenticated name.
+}
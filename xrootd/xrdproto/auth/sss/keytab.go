@@ -0,0 +1,182 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sss // import "go-hep.org/x/hep/xrootd/xrdproto/auth/sss"
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key is a shared secret key of a keytab.
+type Key struct {
+	ID      int64     // ID is the number identifying the key.
+	Name    string    // Name is the name of the key.
+	User    string    // User is the name of the user associated with the key.
+	Group   string    // Group is the name of the group associated with the key.
+	Created time.Time // Created is the creation time of the key.
+	Expires time.Time // Expires is the expiration time of the key, if any.
+	Data    []byte    // Data is the secret key.
+}
+
+// NewKey creates a new random key with the provided name, user and group.
+func NewKey(name, user, group string) (Key, error) {
+	var id [8]byte
+	data := make([]byte, 32)
+	for _, buf := range [][]byte{id[:], data} {
+		if _, err := rand.Read(buf); err != nil {
+			return Key{}, fmt.Errorf("auth/sss: could not generate key: %w", err)
+		}
+	}
+	return Key{
+		ID:      int64(binary.BigEndian.Uint64(id[:]) >> 1),
+		Name:    name,
+		User:    user,
+		Group:   group,
+		Created: time.Now().Truncate(time.Second),
+		Data:    data,
+	}, nil
+}
+
+// expired returns whether the key is expired at time t.
+func (k Key) expired(t time.Time) bool {
+	return !k.Expires.IsZero() && t.After(k.Expires)
+}
+
+// Keytab is a list of shared secret keys.
+//
+// Keytabs are stored in text files, with one key per line:
+//
+//	0 u:<user> g:<group> n:<name> N:<id> c:<created> e:<expires> f:<flags> k:<hex-key>
+//
+// where times are expressed in seconds since the Unix epoch and
+// a zero expiration time means that the key does not expire.
+type Keytab []Key
+
+// LoadKeytab loads the keytab from the named file.
+func LoadKeytab(fname string) (Keytab, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("auth/sss: could not open keytab: %w", err)
+	}
+	defer f.Close()
+
+	return ReadKeytab(f)
+}
+
+// ReadKeytab reads a keytab from r.
+func ReadKeytab(r io.Reader) (Keytab, error) {
+	var (
+		kt   Keytab
+		scan = bufio.NewScanner(r)
+		line = 0
+	)
+	for scan.Scan() {
+		line++
+		txt := strings.TrimSpace(scan.Text())
+		if txt == "" || strings.HasPrefix(txt, "#") {
+			continue
+		}
+		key, err := parseKey(txt)
+		if err != nil {
+			return nil, fmt.Errorf("auth/sss: invalid keytab line %d: %w", line, err)
+		}
+		kt = append(kt, key)
+	}
+	if err := scan.Err(); err != nil {
+		return nil, fmt.Errorf("auth/sss: could not read keytab: %w", err)
+	}
+	return kt, nil
+}
+
+func parseKey(txt string) (Key, error) {
+	var (
+		key Key
+		err error
+	)
+	toks := strings.Fields(txt)
+	if toks[0] != "0" {
+		return key, fmt.Errorf("unsupported keytab format %q", toks[0])
+	}
+	for _, tok := range toks[1:] {
+		k, v, ok := strings.Cut(tok, ":")
+		if !ok {
+			return key, fmt.Errorf("invalid field %q", tok)
+		}
+		switch k {
+		case "u":
+			key.User = v
+		case "g":
+			key.Group = v
+		case "n":
+			key.Name = v
+		case "N":
+			key.ID, err = strconv.ParseInt(v, 10, 64)
+		case "c":
+			key.Created, err = parseTime(v)
+		case "e":
+			key.Expires, err = parseTime(v)
+		case "k":
+			key.Data, err = hex.DecodeString(v)
+		default:
+			// ignore flags and unknown fields.
+		}
+		if err != nil {
+			return key, fmt.Errorf("invalid field %q: %w", tok, err)
+		}
+	}
+	if len(key.Data) < minKeyLen || len(key.Data) > maxKeyLen {
+		return key, fmt.Errorf("invalid key length %d", len(key.Data))
+	}
+	return key, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec == 0 {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+func formatTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// WriteTo writes the keytab to w.
+func (kt Keytab) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, k := range kt {
+		nn, err := fmt.Fprintf(w, "0 u:%s g:%s n:%s N:%d c:%d e:%d f:0 k:%s\n",
+			k.User, k.Group, k.Name, k.ID, formatTime(k.Created), formatTime(k.Expires),
+			hex.EncodeToString(k.Data),
+		)
+		n += int64(nn)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Key returns the key with the provided id.
+func (kt Keytab) Key(id int64) (Key, bool) {
+	for _, k := range kt {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
@@ -0,0 +1,283 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sss contains the implementation of the sss (simple shared secret) security provider.
+//
+// The sss security provider sends the identity of the user, encrypted with a
+// key shared between the client and the server, identified by its number in
+// a keytab (see Keytab).
+// The credentials are encrypted with Blowfish in CFB mode and protected by a
+// CRC32 checksum.
+//
+// The layout of the credentials is modeled after the one of XRootD but it has
+// not been checked against the reference implementation: clients and servers
+// using this package are only guaranteed to interoperate with each other.
+// Hence, xrootd clients do not use this provider unless it is selected
+// explicitly, e.g. with xrootd.WithAuth(sss.Default).
+package sss // import "go-hep.org/x/hep/xrootd/xrdproto/auth/sss"
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-hep.org/x/hep/xrootd/xrdproto/auth"
+	"golang.org/x/crypto/blowfish"
+)
+
+// Default is a sss security provider configured from the first valid key of the keytab
+// named by the XrdSecSSSKT environment variable, or of the $HOME/.xrd/sss.keytab file.
+// If no key could be found, Default will be nil.
+// Default is not used by xrootd clients unless it is selected with xrootd.WithAuth.
+var Default auth.Auther
+
+func init() {
+	fname := os.Getenv("XrdSecSSSKT")
+	if fname == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return
+		}
+		fname = filepath.Join(home, ".xrd", "sss.keytab")
+	}
+	kt, err := LoadKeytab(fname)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, key := range kt {
+		if !key.expired(now) {
+			Default = &Auth{Key: key}
+			return
+		}
+	}
+}
+
+const (
+	minKeyLen = 4  // minimum length of a key, in bytes.
+	maxKeyLen = 56 // maximum length of a key, in bytes.
+
+	hdrLen  = 4 + 3 + 1 + 8 // length of the credentials header.
+	randLen = 32            // length of the random prefix of the credentials data.
+
+	tagName  = 0x01 // tagName identifies the name of the user.
+	tagGroup = 0x04 // tagGroup identifies the group of the user.
+	tagHost  = 0x20 // tagHost identifies the host of the user.
+
+	// DefaultMaxSkew is the default maximum allowed difference between the
+	// generation time of the credentials and the time of their verification.
+	DefaultMaxSkew = 30 * time.Second
+)
+
+// Type indicates that sss authentication protocol is used.
+var Type = [4]byte{'s', 's', 's', 0}
+
+// Auth implements the sss security provider.
+type Auth struct {
+	Key  Key    // Key is the shared secret key used to encrypt the credentials.
+	User string // User is the name of the user. If empty, the user of the key is used.
+	Host string // Host is the name of the host of the user, if any.
+}
+
+// Provider implements auth.Auther
+func (*Auth) Provider() string {
+	return "sss"
+}
+
+// Request implements auth.Auther
+func (a *Auth) Request(params []string) (*auth.Request, error) {
+	user := a.User
+	if user == "" {
+		user = a.Key.User
+	}
+
+	var data bytes.Buffer
+	data.Write(make([]byte, randLen))
+	_, err := rand.Read(data.Bytes()[:randLen])
+	if err != nil {
+		return nil, fmt.Errorf("auth/sss: could not generate random data: %w", err)
+	}
+	_ = binary.Write(&data, binary.BigEndian, int32(time.Now().Unix()))
+	data.Write([]byte{0, 0, 0, 0}) // padding and options.
+	for _, v := range []struct {
+		tag byte
+		val string
+	}{
+		{tagName, user},
+		{tagGroup, a.Key.Group},
+		{tagHost, a.Host},
+	} {
+		if v.val == "" {
+			continue
+		}
+		data.WriteByte(v.tag)
+		_ = binary.Write(&data, binary.BigEndian, uint16(len(v.val)))
+		data.WriteString(v.val)
+	}
+
+	blob, err := encrypt(a.Key.Data, data.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	cred := make([]byte, hdrLen, hdrLen+len(blob))
+	copy(cred, Type[:])
+	binary.BigEndian.PutUint64(cred[8:], uint64(a.Key.ID))
+	cred = append(cred, blob...)
+
+	return &auth.Request{Type: Type, Credentials: string(cred)}, nil
+}
+
+// Identity is the identity of a user, as sent in sss credentials.
+type Identity struct {
+	Name  string
+	Group string
+	Host  string
+	Time  time.Time // Time is the generation time of the credentials.
+}
+
+// ParseCredentials decrypts the sss credentials using the key of the keytab they were encrypted with.
+func ParseCredentials(kt Keytab, cred string) (Identity, Key, error) {
+	var id Identity
+	if len(cred) < hdrLen || cred[:4] != string(Type[:]) {
+		return id, Key{}, errors.New("auth/sss: invalid credentials header")
+	}
+	keyID := int64(binary.BigEndian.Uint64([]byte(cred[8:hdrLen])))
+	key, ok := kt.Key(keyID)
+	if !ok {
+		return id, key, fmt.Errorf("auth/sss: unknown key %d", keyID)
+	}
+
+	data, err := decrypt(key.Data, []byte(cred[hdrLen:]))
+	if err != nil {
+		return id, key, err
+	}
+	if len(data) < randLen+8 {
+		return id, key, errors.New("auth/sss: invalid credentials data")
+	}
+	id.Time = time.Unix(int64(int32(binary.BigEndian.Uint32(data[randLen:]))), 0)
+
+	data = data[randLen+8:]
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return id, key, errors.New("auth/sss: invalid credentials field")
+		}
+		tag := data[0]
+		n := int(binary.BigEndian.Uint16(data[1:3]))
+		if 3+n > len(data) {
+			return id, key, errors.New("auth/sss: invalid credentials field length")
+		}
+		val := string(data[3 : 3+n])
+		switch tag {
+		case tagName:
+			id.Name = val
+		case tagGroup:
+			id.Group = val
+		case tagHost:
+			id.Host = val
+		}
+		data = data[3+n:]
+	}
+
+	return id, key, nil
+}
+
+// Verifier verifies the credentials sent by the clients using the sss security provider.
+type Verifier struct {
+	// Keytab holds the keys the credentials may be encrypted with.
+	Keytab Keytab
+
+	// MaxSkew is the maximum allowed difference between the generation time
+	// of the credentials and the time of their verification.
+	// If zero, DefaultMaxSkew is used.
+	MaxSkew time.Duration
+}
+
+// Provider implements auth.Verifier
+func (*Verifier) Provider() string {
+	return "sss"
+}
+
+// Params implements auth.Verifier
+func (*Verifier) Params() string {
+	return "0.+"
+}
+
+// Verify implements auth.Verifier.
+// The name of the user must be the user of the key, unless that user is
+// empty or "anybody".
+func (v *Verifier) Verify(req *auth.Request) (string, error) {
+	id, key, err := ParseCredentials(v.Keytab, req.Credentials)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if key.expired(now) {
+		return "", fmt.Errorf("auth/sss: key %d expired", key.ID)
+	}
+	skew := v.MaxSkew
+	if skew <= 0 {
+		skew = DefaultMaxSkew
+	}
+	if d := now.Sub(id.Time); d > skew || d < -skew {
+		return "", fmt.Errorf("auth/sss: credentials are too old or in the future (skew=%v)", d)
+	}
+
+	if id.Name == "" {
+		return "", errors.New("auth/sss: no user name in credentials")
+	}
+	switch key.User {
+	case "", "anybody", id.Name:
+		return id.Name, nil
+	default:
+		return "", fmt.Errorf("auth/sss: user %q is not allowed to use key %d", id.Name, key.ID)
+	}
+}
+
+// encrypt encrypts data with key, prefixing it with its CRC32 checksum.
+func encrypt(key, data []byte) ([]byte, error) {
+	block, err := blowfish.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("auth/sss: invalid key: %w", err)
+	}
+	out := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(out, crc32.ChecksumIEEE(data))
+	copy(out[4:], data)
+
+	iv := make([]byte, blowfish.BlockSize)
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(out, out)
+	return out, nil
+}
+
+// decrypt decrypts data with key and verifies its CRC32 checksum.
+func decrypt(key, data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, errors.New("auth/sss: invalid encrypted credentials")
+	}
+	block, err := blowfish.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("auth/sss: invalid key: %w", err)
+	}
+	out := make([]byte, len(data))
+	iv := make([]byte, blowfish.BlockSize)
+	cipher.NewCFBDecrypter(block, iv).XORKeyStream(out, data)
+
+	if crc32.ChecksumIEEE(out[4:]) != binary.BigEndian.Uint32(out) {
+		return nil, errors.New("auth/sss: could not decrypt credentials: checksum mismatch")
+	}
+	return out[4:], nil
+}
+
+var (
+	_ auth.Auther   = (*Auth)(nil)
+	_ auth.Verifier = (*Verifier)(nil)
+)
@@ -0,0 +1,156 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sss_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/xrootd/xrdproto/auth/sss"
+)
+
+func TestKeytab(t *testing.T) {
+	k1, err := sss.NewKey("key1", "gopher", "users")
+	if err != nil {
+		t.Fatalf("could not create key: %v", err)
+	}
+	k2, err := sss.NewKey("key2", "anybody", "")
+	if err != nil {
+		t.Fatalf("could not create key: %v", err)
+	}
+	k2.Expires = k2.Created.Add(time.Hour)
+
+	want := sss.Keytab{k1, k2}
+
+	var buf bytes.Buffer
+	n, err := want.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("could not write keytab: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("invalid number of bytes written: got=%d, want=%d", n, buf.Len())
+	}
+
+	got, err := sss.ReadKeytab(&buf)
+	if err != nil {
+		t.Fatalf("could not read keytab: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid keytab:\ngot= %#v\nwant=%#v", got, want)
+	}
+
+	if k, ok := got.Key(k2.ID); !ok || k.Name != "key2" {
+		t.Fatalf("could not find key %d", k2.ID)
+	}
+	if _, ok := got.Key(-1); ok {
+		t.Fatalf("found unexpected key")
+	}
+
+	_, err = sss.ReadKeytab(strings.NewReader("0 u:gopher N:1 k:0102\n"))
+	if err == nil {
+		t.Fatalf("expected an error for a too short key")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	mustKey := func(name, user string) sss.Key {
+		k, err := sss.NewKey(name, user, "users")
+		if err != nil {
+			t.Fatalf("could not create key: %v", err)
+		}
+		return k
+	}
+
+	var (
+		gopher  = mustKey("gopher", "gopher")
+		anybody = mustKey("anybody", "anybody")
+		expired = mustKey("expired", "gopher")
+		unknown = mustKey("unknown", "gopher")
+	)
+	expired.Expires = time.Now().Add(-time.Hour)
+
+	v := sss.Verifier{Keytab: sss.Keytab{gopher, anybody, expired}}
+
+	for _, tc := range []struct {
+		name string
+		auth sss.Auth
+		want string
+		err  string
+	}{
+		{
+			name: "key-user",
+			auth: sss.Auth{Key: gopher},
+			want: "gopher",
+		},
+		{
+			name: "anybody",
+			auth: sss.Auth{Key: anybody, User: "bob", Host: "example.org"},
+			want: "bob",
+		},
+		{
+			name: "user-mismatch",
+			auth: sss.Auth{Key: gopher, User: "bob"},
+			err:  "is not allowed to use key",
+		},
+		{
+			name: "expired",
+			auth: sss.Auth{Key: expired},
+			err:  "expired",
+		},
+		{
+			name: "unknown-key",
+			auth: sss.Auth{Key: unknown},
+			err:  "unknown key",
+		},
+		{
+			name: "wrong-key",
+			auth: sss.Auth{Key: sss.Key{ID: gopher.ID, User: "gopher", Data: unknown.Data}},
+			err:  "checksum mismatch",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := tc.auth.Request([]string{v.Params()})
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			if req.Type != sss.Type {
+				t.Fatalf("invalid request type: got=%q, want=%q", req.Type, sss.Type)
+			}
+
+			got, err := v.Verify(req)
+			switch {
+			case tc.err != "":
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("invalid error: got=%v, want=%v", err, tc.err)
+				}
+			case err != nil:
+				t.Fatalf("could not verify: %v", err)
+			case got != tc.want:
+				t.Fatalf("invalid name: got=%q, want=%q", got, tc.want)
+			}
+		})
+	}
+
+	req, err := (&sss.Auth{Key: anybody, User: "bob", Host: "example.org"}).Request(nil)
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+	id, key, err := sss.ParseCredentials(v.Keytab, req.Credentials)
+	if err != nil {
+		t.Fatalf("could not parse credentials: %v", err)
+	}
+	if key.ID != anybody.ID {
+		t.Fatalf("invalid key: got=%d, want=%d", key.ID, anybody.ID)
+	}
+	if id.Name != "bob" || id.Group != "users" || id.Host != "example.org" {
+		t.Fatalf("invalid identity: %#v", id)
+	}
+	if d := time.Since(id.Time); d < 0 || d > time.Minute {
+		t.Fatalf("invalid generation time: %v", id.Time)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ztn contains the implementation of the ztn (bearer token) security provider.
+//
+// The ztn security provider sends a bearer token (e.g. a WLCG JWT token) to the server.
+// Since the token is sent as is, the xrootd client only sends it over
+// TLS-encrypted connections.
+package ztn // import "go-hep.org/x/hep/xrootd/xrdproto/auth/ztn"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go-hep.org/x/hep/xrootd/xrdproto/auth"
+)
+
+// Default is a ztn security provider configured from the bearer token
+// found following the WLCG bearer token discovery procedure (see Discover).
+// If no token could be found, Default will be nil.
+var Default auth.Auther
+
+func init() {
+	tok, err := Discover()
+	if err != nil {
+		return
+	}
+	Default = &Auth{Token: tok}
+}
+
+const (
+	version = 0   // version of the ztn protocol.
+	opToken = 'T' // opToken identifies a credentials message holding a token.
+
+	hdrLen = 4 + 1 + 1 + 2 + 2 // length of the credentials header.
+
+	// DefaultMaxTokenSize is the default maximum size of a token, in bytes.
+	DefaultMaxTokenSize = 4096
+)
+
+// Type indicates that ztn authentication protocol is used.
+var Type = [4]byte{'z', 't', 'n', 0}
+
+// Discover returns the bearer token found following the WLCG bearer token discovery:
+//   - the content of the BEARER_TOKEN environment variable,
+//   - the content of the file named by the BEARER_TOKEN_FILE environment variable,
+//   - the content of the $XDG_RUNTIME_DIR/bt_u$UID file,
+//   - the content of the /tmp/bt_u$UID file.
+//
+// See https://zenodo.org/record/3937438 for details.
+func Discover() (string, error) {
+	if tok := strings.TrimSpace(os.Getenv("BEARER_TOKEN")); tok != "" {
+		return tok, nil
+	}
+
+	var fnames []string
+	if fname := os.Getenv("BEARER_TOKEN_FILE"); fname != "" {
+		fnames = append(fnames, fname)
+	}
+	uid := "bt_u" + strconv.Itoa(os.Getuid())
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		fnames = append(fnames, filepath.Join(dir, uid))
+	}
+	fnames = append(fnames, filepath.Join(os.TempDir(), uid))
+
+	for _, fname := range fnames {
+		raw, err := os.ReadFile(fname)
+		if err != nil {
+			continue
+		}
+		if tok := strings.TrimSpace(string(raw)); tok != "" {
+			return tok, nil
+		}
+	}
+
+	return "", errors.New("auth/ztn: could not find a bearer token")
+}
+
+// Auth implements the ztn security provider.
+type Auth struct {
+	Token string
+}
+
+// Provider implements auth.Auther
+func (*Auth) Provider() string {
+	return "ztn"
+}
+
+// Request implements auth.Auther
+func (a *Auth) Request(params []string) (*auth.Request, error) {
+	if a.Token == "" {
+		return nil, errors.New("auth/ztn: empty token")
+	}
+
+	maxSize := DefaultMaxTokenSize
+	if len(params) > 0 {
+		// params are of the form "<version>:<max-token-size>:".
+		toks := strings.Split(params[0], ":")
+		if len(toks) > 1 && toks[1] != "" {
+			v, err := strconv.Atoi(toks[1])
+			if err != nil {
+				return nil, fmt.Errorf("auth/ztn: invalid maximum token size %q: %w", toks[1], err)
+			}
+			maxSize = v
+		}
+	}
+	if len(a.Token)+1 > maxSize {
+		return nil, fmt.Errorf("auth/ztn: token too long (%d > %d)", len(a.Token)+1, maxSize)
+	}
+
+	cred := make([]byte, hdrLen, hdrLen+len(a.Token)+1)
+	copy(cred, Type[:])
+	cred[4] = version
+	cred[5] = opToken
+	binary.BigEndian.PutUint16(cred[8:], uint16(len(a.Token)+1))
+	cred = append(cred, a.Token...)
+	cred = append(cred, 0)
+
+	return &auth.Request{Type: Type, Credentials: string(cred)}, nil
+}
+
+// ParseCredentials returns the token held by the ztn credentials.
+func ParseCredentials(cred string) (string, error) {
+	if len(cred) < hdrLen || cred[:4] != string(Type[:]) {
+		return "", errors.New("auth/ztn: invalid credentials header")
+	}
+	if cred[4] != version || cred[5] != opToken {
+		return "", fmt.Errorf("auth/ztn: unsupported credentials (version=%d, operation=%q)", cred[4], cred[5])
+	}
+	n := int(binary.BigEndian.Uint16([]byte(cred[8:hdrLen])))
+	if n < 2 || hdrLen+n > len(cred) || cred[hdrLen+n-1] != 0 {
+		return "", fmt.Errorf("auth/ztn: invalid token length %d", n)
+	}
+	return cred[hdrLen : hdrLen+n-1], nil
+}
+
+// Verifier verifies the tokens sent by the clients using the ztn security provider.
+type Verifier struct {
+	// MaxTokenSize is the maximum size of a token, in bytes.
+	// If zero, DefaultMaxTokenSize is used.
+	MaxTokenSize int
+
+	// Validate validates the token and returns the authenticated name.
+	Validate func(token string) (string, error)
+}
+
+// Provider implements auth.Verifier
+func (*Verifier) Provider() string {
+	return "ztn"
+}
+
+// Params implements auth.Verifier
+func (v *Verifier) Params() string {
+	return fmt.Sprintf("%d:%d:", version, v.maxTokenSize())
+}
+
+// Verify implements auth.Verifier
+func (v *Verifier) Verify(req *auth.Request) (string, error) {
+	tok, err := ParseCredentials(req.Credentials)
+	if err != nil {
+		return "", err
+	}
+	if len(tok)+1 > v.maxTokenSize() {
+		return "", fmt.Errorf("auth/ztn: token too long (%d > %d)", len(tok)+1, v.maxTokenSize())
+	}
+	if v.Validate == nil {
+		return "", errors.New("auth/ztn: no token validation")
+	}
+	return v.Validate(tok)
+}
+
+func (v *Verifier) maxTokenSize() int {
+	if v.MaxTokenSize > 0 {
+		return v.MaxTokenSize
+	}
+	return DefaultMaxTokenSize
+}
+
+var (
+	_ auth.Auther   = (*Auth)(nil)
+	_ auth.Verifier = (*Verifier)(nil)
+)
@@ -0,0 +1,100 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ztn_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/xrootd/xrdproto/auth/ztn"
+)
+
+func TestAuthZTN(t *testing.T) {
+	const token = "eyJhbGciOiJIUzI1NiJ9.e30.ZRrHA1JJJW8opsbCGfG_HACGpVUMN_a9IV7pAx_Zmeo"
+
+	zauth := ztn.Auth{Token: token}
+	if got, want := zauth.Provider(), "ztn"; got != want {
+		t.Fatalf("invalid auth type: got=%q, want=%q", got, want)
+	}
+
+	req, err := zauth.Request([]string{"0:4096:"})
+	if err != nil {
+		t.Fatalf("got err=%v", err)
+	}
+	if req.Type != ztn.Type {
+		t.Fatalf("invalid request type: got=%q, want=%q", req.Type, ztn.Type)
+	}
+
+	got, err := ztn.ParseCredentials(req.Credentials)
+	if err != nil {
+		t.Fatalf("could not parse credentials: %v", err)
+	}
+	if got != token {
+		t.Fatalf("invalid token:\ngot= %q\nwant=%q", got, token)
+	}
+
+	_, err = zauth.Request([]string{"0:16:"})
+	if err == nil || !strings.Contains(err.Error(), "token too long") {
+		t.Fatalf("expected a token too long error, got=%v", err)
+	}
+
+	_, err = (&ztn.Auth{}).Request(nil)
+	if err == nil {
+		t.Fatalf("expected an error for an empty token")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	const token = "secret-token"
+
+	v := ztn.Verifier{
+		Validate: func(tok string) (string, error) {
+			if tok != token {
+				return "", fmt.Errorf("invalid token %q", tok)
+			}
+			return "gopher", nil
+		},
+	}
+	if got, want := v.Params(), "0:4096:"; got != want {
+		t.Fatalf("invalid params: got=%q, want=%q", got, want)
+	}
+
+	for _, tc := range []struct {
+		token string
+		name  string
+		err   string
+	}{
+		{token: token, name: "gopher"},
+		{token: "other-token", err: `invalid token "other-token"`},
+	} {
+		t.Run(tc.token, func(t *testing.T) {
+			req, err := (&ztn.Auth{Token: tc.token}).Request([]string{v.Params()})
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			name, err := v.Verify(req)
+			switch {
+			case tc.err != "":
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("invalid error: got=%v, want=%v", err, tc.err)
+				}
+			case err != nil:
+				t.Fatalf("could not verify: %v", err)
+			case name != tc.name:
+				t.Fatalf("invalid name: got=%q, want=%q", name, tc.name)
+			}
+		})
+	}
+
+	req, err := (&ztn.Auth{Token: token}).Request(nil)
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+	req.Credentials = req.Credentials[:len(req.Credentials)-2]
+	if _, err := v.Verify(req); err == nil {
+		t.Fatalf("expected an error for truncated credentials")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgread contains the structures describing request and response for pgread request.
+// The pgread request reads data as pages, each page being protected by its CRC32C checksum.
+// See xrootd protocol specification (https://xrootd.slac.stanford.edu/doc/dev50/XRdv500.htm#_Toc45172178) for details.
+package pgread // import "go-hep.org/x/hep/xrootd/xrdproto/pgread"
+
+import (
+	"fmt"
+
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+)
+
+// RequestID is the id of the request, it is sent as part of message.
+// See xrootd protocol specification for details: https://xrootd.slac.stanford.edu/doc/dev50/XRdv500.htm#_Toc45172178.
+const RequestID uint16 = 3030
+
+// MaxLength is the maximum length, in bytes, of the data a single pgread request may ask for.
+const MaxLength = 2 << 20
+
+// Flags are the pgread request flags.
+type Flags uint8
+
+const (
+	Retry Flags = 1 // Retry indicates that the request is a retry of pages with a bad checksum.
+)
+
+// Request holds pgread request parameters.
+type Request struct {
+	Handle xrdfs.FileHandle
+	Offset int64
+	Length int32
+	// PathID is the path id returned by bind request.
+	// The response data is sent to this path, if possible.
+	PathID xrdproto.PathID
+	Flags  Flags
+}
+
+// MarshalXrd implements xrdproto.Marshaler.
+func (o Request) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	wBuffer.WriteBytes(o.Handle[:])
+	wBuffer.WriteI64(o.Offset)
+	wBuffer.WriteI32(o.Length)
+	if o.PathID == 0 && o.Flags == 0 {
+		wBuffer.WriteLen(0)
+		return nil
+	}
+	wBuffer.WriteLen(2)
+	wBuffer.WriteU8(uint8(o.PathID))
+	wBuffer.WriteU8(uint8(o.Flags))
+	return nil
+}
+
+// UnmarshalXrd implements xrdproto.Unmarshaler.
+func (o *Request) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	rBuffer.ReadBytes(o.Handle[:])
+	o.Offset = rBuffer.ReadI64()
+	o.Length = rBuffer.ReadI32()
+	alen := rBuffer.ReadLen()
+	switch {
+	case alen == 0:
+		return nil
+	case alen < 2 || alen > rBuffer.Len():
+		return fmt.Errorf("xrootd: invalid pgread alen: %d", alen)
+	}
+	o.PathID = xrdproto.PathID(rBuffer.ReadU8())
+	o.Flags = Flags(rBuffer.ReadU8())
+	rBuffer.Skip(alen - 2)
+	return nil
+}
+
+// ReqID implements xrdproto.Request.ReqID.
+func (req *Request) ReqID() uint16 { return RequestID }
+
+// ShouldSign implements xrdproto.Request.ShouldSign.
+func (req *Request) ShouldSign() bool { return false }
+
+// Response is a response for the pgread request, which contains the read data.
+// Response is sent with the xrdproto.Status status.
+type Response struct {
+	Offset int64
+	Data   []uint8
+}
+
+// MarshalXrd implements xrdproto.Marshaler.
+func (o Response) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	status := xrdproto.StatusResponse{
+		RequestID:  RequestID,
+		Type:       xrdproto.FinalResult,
+		DataLength: int32(xrdproto.PagesLength(o.Offset, len(o.Data))),
+	}
+	err := status.MarshalXrd(wBuffer)
+	if err != nil {
+		return err
+	}
+	wBuffer.WriteI64(o.Offset)
+	xrdproto.WritePages(wBuffer, o.Offset, o.Data)
+	return nil
+}
+
+// UnmarshalXrd implements xrdproto.Unmarshaler.
+// UnmarshalXrd verifies the checksums of the status response and of all the
+// pages of data and fails if any of them does not match.
+// The response may be made of several status responses, the last of which is
+// a final result: the data of all of them is then concatenated.
+func (o *Response) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	var (
+		body      = rBuffer.Bytes()
+		data      = o.Data[:0]
+		corrupted []int64
+	)
+	for i := 0; ; i++ {
+		var hdr xrdproto.StatusResponse
+		if len(body) < xrdproto.StatusResponseLength+8 {
+			return fmt.Errorf("xrootd: invalid pgread response length: %d", len(body))
+		}
+		err := hdr.UnmarshalXrd(xrdenc.NewRBuffer(body[:xrdproto.StatusResponseLength]))
+		if err != nil {
+			return err
+		}
+		n := xrdproto.StatusResponseLength + 8 + int(hdr.DataLength)
+		if hdr.DataLength < 0 || n > len(body) {
+			return fmt.Errorf("xrootd: invalid pgread data length: %d", hdr.DataLength)
+		}
+
+		r := xrdenc.NewRBuffer(body[:n])
+		status, err := xrdproto.UnmarshalStatus(r, RequestID)
+		if err != nil {
+			return err
+		}
+		off := r.ReadI64()
+		switch {
+		case i == 0:
+			o.Offset = off
+		case off != o.Offset+int64(len(data)):
+			return fmt.Errorf("xrootd: pgread response at offset %d, want %d", off, o.Offset+int64(len(data)))
+		}
+		var bad []int64
+		data, bad, err = xrdproto.ReadPages(data, off, r.Bytes())
+		if err != nil {
+			return err
+		}
+		corrupted = append(corrupted, bad...)
+		body = body[n:]
+
+		if status.Type == xrdproto.FinalResult {
+			break
+		}
+	}
+	o.Data = data
+	if len(body) != 0 {
+		return fmt.Errorf("xrootd: %d trailing bytes after pgread final result", len(body))
+	}
+	if len(corrupted) > 0 {
+		return fmt.Errorf("xrootd: pgread checksum mismatch for pages at offsets %v", corrupted)
+	}
+	return nil
+}
+
+// RespID implements xrdproto.Response.RespID.
+func (resp *Response) RespID() uint16 { return RequestID }
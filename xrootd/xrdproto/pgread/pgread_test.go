@@ -0,0 +1,154 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgread_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgread"
+)
+
+func TestRequest(t *testing.T) {
+	for _, want := range []pgread.Request{
+		{
+			Handle: [4]byte{1, 2, 3, 4},
+			Offset: 10,
+			Length: 4096,
+		},
+		{
+			Handle: [4]byte{1, 2, 3, 4},
+			Offset: 4096,
+			Length: 10,
+			PathID: 1,
+			Flags:  pgread.Retry,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			var (
+				err error
+				w   = new(xrdenc.WBuffer)
+				got pgread.Request
+			)
+
+			if want.ReqID() != pgread.RequestID {
+				t.Fatalf("invalid request ID: got=%d want=%d", want.ReqID(), pgread.RequestID)
+			}
+
+			if want.ShouldSign() {
+				t.Fatalf("invalid")
+			}
+
+			err = want.MarshalXrd(w)
+			if err != nil {
+				t.Fatalf("could not marshal request: %v", err)
+			}
+
+			r := xrdenc.NewRBuffer(w.Bytes())
+			err = got.UnmarshalXrd(r)
+			if err != nil {
+				t.Fatalf("could not unmarshal request: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip failed:\ngot = %#v\nwant= %#v\n", got, want)
+			}
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	for _, want := range []pgread.Response{
+		{
+			Offset: 0,
+		},
+		{
+			Offset: 42,
+			Data:   data,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			var (
+				err error
+				got pgread.Response
+			)
+
+			if want.RespID() != pgread.RequestID {
+				t.Fatalf("invalid response ID: got=%d want=%d", want.RespID(), pgread.RequestID)
+			}
+
+			body := marshal(t, want)
+			r := xrdenc.NewRBuffer(body)
+			err = got.UnmarshalXrd(r)
+			if err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip failed:\ngot = %#v\nwant= %#v\n", got, want)
+			}
+		})
+	}
+}
+
+func TestResponseCorrupted(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	body := marshal(t, pgread.Response{Offset: 42, Data: data})
+	body[len(body)-1] ^= 0xff
+
+	var resp pgread.Response
+	err := resp.UnmarshalXrd(xrdenc.NewRBuffer(body))
+	if err == nil {
+		t.Fatalf("expected a checksum mismatch error")
+	}
+}
+
+func TestResponsePartial(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	want := pgread.Response{Offset: 42, Data: data}
+
+	// the first response is a partial result and holds the beginning of the data.
+	head := marshal(t, pgread.Response{Offset: 42, Data: data[:5000]})
+	head[7] = byte(xrdproto.PartialResult)
+	binary.BigEndian.PutUint32(head[:4], xrdproto.Checksum(head[4:xrdproto.StatusResponseLength+8]))
+	tail := marshal(t, pgread.Response{Offset: 5042, Data: data[5000:]})
+
+	var got pgread.Response
+	err := got.UnmarshalXrd(xrdenc.NewRBuffer(append(head, tail...)))
+	if err != nil {
+		t.Fatalf("could not unmarshal response: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("invalid response:\ngot = %#v\nwant= %#v\n", got, want)
+	}
+
+	// the partial result alone is not a complete response.
+	err = got.UnmarshalXrd(xrdenc.NewRBuffer(head))
+	if err == nil {
+		t.Fatalf("expected an error for a response without a final result")
+	}
+}
+
+// marshal marshals resp as it is sent by a server.
+func marshal(t *testing.T, resp pgread.Response) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := xrdproto.WriteResponse(&buf, xrdproto.StreamID{1, 2}, xrdproto.Status, resp)
+	if err != nil {
+		t.Fatalf("could not marshal response: %v", err)
+	}
+	_, body, err := xrdproto.ReadResponse(&buf)
+	if err != nil {
+		t.Fatalf("could not read response: %v", err)
+	}
+	return body
+}
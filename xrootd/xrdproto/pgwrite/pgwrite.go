@@ -0,0 +1,126 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgwrite contains the structures describing request and response for pgwrite request.
+// The pgwrite request writes data as pages, each page being protected by its CRC32C checksum.
+// See xrootd protocol specification (https://xrootd.slac.stanford.edu/doc/dev50/XRdv500.htm#_Toc45172183) for details.
+package pgwrite // import "go-hep.org/x/hep/xrootd/xrdproto/pgwrite"
+
+import (
+	"fmt"
+
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+)
+
+// RequestID is the id of the request, it is sent as part of message.
+// It is the same id as the one of the legacy verifyw request, which pgwrite replaces.
+// See xrootd protocol specification for details: https://xrootd.slac.stanford.edu/doc/dev50/XRdv500.htm#_Toc45172183.
+const RequestID uint16 = 3026
+
+// Flags are the pgwrite request flags.
+type Flags uint8
+
+const (
+	Retry Flags = 1 // Retry indicates that the request is a retry of pages with a bad checksum.
+)
+
+// Request holds pgwrite request parameters.
+type Request struct {
+	Handle xrdfs.FileHandle
+	Offset int64
+	PathID xrdproto.PathID
+	Flags  Flags
+	_      [2]uint8
+	Data   []uint8
+
+	// Corrupted holds the file offsets of the received pages whose checksum did not match.
+	// Corrupted is filled by UnmarshalXrd and is not sent over the wire.
+	Corrupted []int64
+}
+
+// MarshalXrd implements xrdproto.Marshaler.
+func (o Request) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	wBuffer.WriteBytes(o.Handle[:])
+	wBuffer.WriteI64(o.Offset)
+	wBuffer.WriteU8(uint8(o.PathID))
+	wBuffer.WriteU8(uint8(o.Flags))
+	wBuffer.Next(2)
+	wBuffer.WriteLen(xrdproto.PagesLength(o.Offset, len(o.Data)))
+	xrdproto.WritePages(wBuffer, o.Offset, o.Data)
+	return nil
+}
+
+// UnmarshalXrd implements xrdproto.Unmarshaler.
+func (o *Request) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	rBuffer.ReadBytes(o.Handle[:])
+	o.Offset = rBuffer.ReadI64()
+	o.PathID = xrdproto.PathID(rBuffer.ReadU8())
+	o.Flags = Flags(rBuffer.ReadU8())
+	rBuffer.Skip(2)
+	dlen := rBuffer.ReadLen()
+	if dlen < 0 || dlen > rBuffer.Len() {
+		return fmt.Errorf("xrootd: invalid pgwrite data length: %d", dlen)
+	}
+	raw := make([]uint8, dlen)
+	rBuffer.ReadBytes(raw)
+
+	var err error
+	o.Data, o.Corrupted, err = xrdproto.ReadPages(make([]uint8, 0, dlen), o.Offset, raw)
+	return err
+}
+
+// ReqID implements xrdproto.Request.ReqID.
+func (req *Request) ReqID() uint16 { return RequestID }
+
+// ShouldSign implements xrdproto.Request.ShouldSign.
+func (req *Request) ShouldSign() bool { return false }
+
+// Response is a response for the pgwrite request.
+// Response is sent with the xrdproto.Status status.
+type Response struct {
+	Offset int64
+	// Corrupted holds the file offsets of the pages that were received with a
+	// bad checksum and were not written. These pages should be sent again.
+	Corrupted []int64
+}
+
+// MarshalXrd implements xrdproto.Marshaler.
+func (o Response) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	status := xrdproto.StatusResponse{
+		RequestID:  RequestID,
+		Type:       xrdproto.FinalResult,
+		DataLength: int32(8 * len(o.Corrupted)),
+	}
+	err := status.MarshalXrd(wBuffer)
+	if err != nil {
+		return err
+	}
+	wBuffer.WriteI64(o.Offset)
+	for _, off := range o.Corrupted {
+		wBuffer.WriteI64(off)
+	}
+	return nil
+}
+
+// UnmarshalXrd implements xrdproto.Unmarshaler.
+func (o *Response) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	status, err := xrdproto.UnmarshalStatus(rBuffer, RequestID)
+	if err != nil {
+		return err
+	}
+	if rBuffer.Len() < 8 || status.DataLength%8 != 0 {
+		return fmt.Errorf("xrootd: invalid pgwrite response length: %d", rBuffer.Len())
+	}
+	o.Offset = rBuffer.ReadI64()
+	o.Corrupted = nil
+	for range status.DataLength / 8 {
+		o.Corrupted = append(o.Corrupted, rBuffer.ReadI64())
+	}
+	return nil
+}
+
+// RespID implements xrdproto.Response.RespID.
+func (resp *Response) RespID() uint16 { return RequestID }
@@ -0,0 +1,125 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgwrite_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/pgwrite"
+)
+
+func TestRequest(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	for _, tc := range []struct {
+		name      string
+		req       pgwrite.Request
+		corrupt   bool
+		corrupted []int64
+	}{
+		{
+			name: "Single page",
+			req:  pgwrite.Request{Handle: [4]byte{1, 2, 3, 4}, Offset: 1, Data: []byte("1234")},
+		},
+		{
+			name: "Multiple pages",
+			req:  pgwrite.Request{Handle: [4]byte{1, 2, 3, 4}, Offset: 42, PathID: 1, Flags: pgwrite.Retry, Data: data},
+		},
+		{
+			name:      "Corrupted last page",
+			req:       pgwrite.Request{Handle: [4]byte{1, 2, 3, 4}, Offset: 42, Data: data},
+			corrupt:   true,
+			corrupted: []int64{2 * xrdproto.PageSize},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err error
+				w   = new(xrdenc.WBuffer)
+				got pgwrite.Request
+			)
+
+			if tc.req.ReqID() != pgwrite.RequestID {
+				t.Fatalf("invalid request ID: got=%d want=%d", tc.req.ReqID(), pgwrite.RequestID)
+			}
+
+			if tc.req.ShouldSign() {
+				t.Fatalf("invalid")
+			}
+
+			err = tc.req.MarshalXrd(w)
+			if err != nil {
+				t.Fatalf("could not marshal request: %v", err)
+			}
+
+			raw := w.Bytes()
+			if tc.corrupt {
+				raw[len(raw)-1] ^= 0xff
+			}
+
+			err = got.UnmarshalXrd(xrdenc.NewRBuffer(raw))
+			if err != nil {
+				t.Fatalf("could not unmarshal request: %v", err)
+			}
+
+			if !reflect.DeepEqual(got.Corrupted, tc.corrupted) {
+				t.Fatalf("invalid corrupted pages:\ngot = %v\nwant= %v", got.Corrupted, tc.corrupted)
+			}
+
+			want := tc.req
+			want.Corrupted = tc.corrupted
+			if tc.corrupt {
+				want.Data = bytes.Clone(want.Data)
+				want.Data[len(want.Data)-1] ^= 0xff
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip failed:\ngot = %#v\nwant= %#v\n", got, want)
+			}
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	for _, want := range []pgwrite.Response{
+		{
+			Offset: 42,
+		},
+		{
+			Offset:    42,
+			Corrupted: []int64{4096, 3 * 4096},
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			var got pgwrite.Response
+
+			if want.RespID() != pgwrite.RequestID {
+				t.Fatalf("invalid response ID: got=%d want=%d", want.RespID(), pgwrite.RequestID)
+			}
+
+			var buf bytes.Buffer
+			err := xrdproto.WriteResponse(&buf, xrdproto.StreamID{1, 2}, xrdproto.Status, want)
+			if err != nil {
+				t.Fatalf("could not marshal response: %v", err)
+			}
+			_, body, err := xrdproto.ReadResponse(&buf)
+			if err != nil {
+				t.Fatalf("could not read response: %v", err)
+			}
+
+			err = got.UnmarshalXrd(xrdenc.NewRBuffer(body))
+			if err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip failed:\ngot = %#v\nwant= %#v\n", got, want)
+			}
+		})
+	}
+}
@@ -27,7 +27,7 @@ import (
 const RequestID uint16 = 3006
 
 // Flags are the Flags that define xrootd server type. See xrootd protocol specification for further info.
-type Flags int32
+type Flags uint32
 
 const (
 	IsServer     Flags = 0x00000001 // IsServer indicates whether this server has server role.
@@ -35,6 +35,12 @@ const (
 	IsMeta       Flags = 0x00000100 // IsMeta indicates whether this server has meta attribute.
 	IsProxy      Flags = 0x00000200 // IsProxy indicates whether this server has proxy attribute.
 	IsSupervisor Flags = 0x00000400 // IsSupervisor indicates whether this server has supervisor attribute.
+
+	TLSData  Flags = 0x01000000 // TLSData indicates that the server requires TLS for the data connections.
+	TLSLogin Flags = 0x04000000 // TLSLogin indicates that the server requires TLS before the login.
+	TLSSess  Flags = 0x08000000 // TLSSess indicates that the server requires TLS after the login.
+	GotoTLS  Flags = 0x40000000 // GotoTLS indicates that the client must switch the connection to TLS right after the response.
+	HaveTLS  Flags = 0x80000000 // HaveTLS indicates that the server supports TLS connections.
 )
 
 // SecurityOptions are the security-related options.
@@ -55,7 +61,11 @@ const (
 	RequestOptionsNone RequestOptions = 0
 	// ReturnSecurityRequirements specifies that security requirements should be returned
 	// if that's supported by the server.
-	ReturnSecurityRequirements RequestOptions = 1
+	ReturnSecurityRequirements RequestOptions = 0x01
+	// AbleTLS specifies that the client is able to use TLS connections.
+	AbleTLS RequestOptions = 0x02
+	// WantTLS specifies that the client wants the connection to be switched to TLS.
+	WantTLS RequestOptions = 0x04
 )
 
 // Request holds protocol request parameters.
@@ -132,6 +142,16 @@ func (resp *Response) IsSupervisor() bool {
 	return resp.Flags&IsSupervisor != 0
 }
 
+// HaveTLS indicates whether this server supports TLS connections.
+func (resp *Response) HaveTLS() bool {
+	return resp.Flags&HaveTLS != 0
+}
+
+// GotoTLS indicates whether the client must switch the connection to TLS right after this response.
+func (resp *Response) GotoTLS() bool {
+	return resp.Flags&GotoTLS != 0
+}
+
 // ForceSecurity indicates whether signing is required even if the authentication
 // protocol does not support generic encryption.
 func (resp *Response) ForceSecurity() bool {
@@ -163,7 +183,7 @@ func (o Response) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
 // UnmarshalXrd implements xrdproto.Unmarshaler.
 func (o *Response) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
 	o.BinaryProtocolVersion = rBuffer.ReadI32()
-	o.Flags = Flags(rBuffer.ReadI32())
+	o.Flags = Flags(uint32(rBuffer.ReadI32()))
 	if rBuffer.Len() == 0 {
 		return nil
 	}
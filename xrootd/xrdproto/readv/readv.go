@@ -0,0 +1,149 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package readv contains the structures describing request and response for readv request.
+// See xrootd protocol specification (http://xrootd.org/doc/dev45/XRdv310.pdf, p. 101) for details.
+package readv // import "go-hep.org/x/hep/xrootd/xrdproto/readv"
+
+import (
+	"fmt"
+
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+	"go-hep.org/x/hep/xrootd/xrdfs"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+)
+
+// RequestID is the id of the request, it is sent as part of message.
+// See xrootd protocol specification for details: http://xrootd.org/doc/dev45/XRdv310.pdf, 2.3 Client Request Format.
+const RequestID uint16 = 3025
+
+const (
+	MaxChunks      = 1024     // MaxChunks is the maximum number of chunks a single readv request may hold.
+	MaxChunkLength = 2 << 20  // MaxChunkLength is the maximum length, in bytes, of a single chunk.
+	MaxLength      = 16 << 20 // MaxLength is the maximum total length, in bytes, of the chunks of a request.
+)
+
+// chunkLength is the length of the chunk description in bytes.
+const chunkLength = 4 + 4 + 8
+
+// Chunk describes a chunk of a file to read.
+type Chunk struct {
+	Handle xrdfs.FileHandle
+	Length int32
+	Offset int64
+}
+
+// MarshalXrd implements xrdproto.Marshaler.
+func (o Chunk) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	wBuffer.WriteBytes(o.Handle[:])
+	wBuffer.WriteI32(o.Length)
+	wBuffer.WriteI64(o.Offset)
+	return nil
+}
+
+// UnmarshalXrd implements xrdproto.Unmarshaler.
+func (o *Chunk) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	rBuffer.ReadBytes(o.Handle[:])
+	o.Length = rBuffer.ReadI32()
+	o.Offset = rBuffer.ReadI64()
+	return nil
+}
+
+// Request holds readv request parameters.
+type Request struct {
+	_ [15]uint8
+	// PathID is the path id returned by bind request.
+	// The response data is sent to this path, if possible.
+	PathID xrdproto.PathID
+	Chunks []Chunk
+}
+
+// MarshalXrd implements xrdproto.Marshaler.
+func (o Request) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	wBuffer.Next(15)
+	wBuffer.WriteU8(uint8(o.PathID))
+	wBuffer.WriteLen(len(o.Chunks) * chunkLength)
+	for _, chunk := range o.Chunks {
+		err := chunk.MarshalXrd(wBuffer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalXrd implements xrdproto.Unmarshaler.
+func (o *Request) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	rBuffer.Skip(15)
+	o.PathID = xrdproto.PathID(rBuffer.ReadU8())
+	dlen := rBuffer.ReadLen()
+	if dlen%chunkLength != 0 || dlen > rBuffer.Len() {
+		return fmt.Errorf("xrootd: invalid readv data length: %d", dlen)
+	}
+	o.Chunks = make([]Chunk, dlen/chunkLength)
+	for i := range o.Chunks {
+		err := o.Chunks[i].UnmarshalXrd(rBuffer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReqID implements xrdproto.Request.ReqID.
+func (req *Request) ReqID() uint16 { return RequestID }
+
+// ShouldSign implements xrdproto.Request.ShouldSign.
+func (req *Request) ShouldSign() bool { return false }
+
+// Data is a chunk of data read by the readv request.
+// Length is the number of bytes actually read, which may be less than
+// the requested length if the end of file was reached.
+type Data struct {
+	Chunk
+	Data []uint8
+}
+
+// Response is a response for the readv request, which contains the read chunks.
+type Response struct {
+	Chunks []Data
+}
+
+// MarshalXrd implements xrdproto.Marshaler.
+func (o Response) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	for _, chunk := range o.Chunks {
+		chunk.Length = int32(len(chunk.Data))
+		err := chunk.Chunk.MarshalXrd(wBuffer)
+		if err != nil {
+			return err
+		}
+		wBuffer.WriteBytes(chunk.Data)
+	}
+	return nil
+}
+
+// UnmarshalXrd implements xrdproto.Unmarshaler.
+func (o *Response) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	o.Chunks = o.Chunks[:0]
+	for rBuffer.Len() > 0 {
+		if rBuffer.Len() < chunkLength {
+			return fmt.Errorf("xrootd: invalid readv chunk header length: %d", rBuffer.Len())
+		}
+		var chunk Data
+		err := chunk.Chunk.UnmarshalXrd(rBuffer)
+		if err != nil {
+			return err
+		}
+		if chunk.Length < 0 || int(chunk.Length) > rBuffer.Len() {
+			return fmt.Errorf("xrootd: invalid readv chunk length: %d", chunk.Length)
+		}
+		chunk.Data = make([]uint8, chunk.Length)
+		rBuffer.ReadBytes(chunk.Data)
+		o.Chunks = append(o.Chunks, chunk)
+	}
+	return nil
+}
+
+// RespID implements xrdproto.Response.RespID.
+func (resp *Response) RespID() uint16 { return RequestID }
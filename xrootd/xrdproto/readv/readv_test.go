@@ -0,0 +1,109 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package readv_test
+
+import (
+	"reflect"
+	"testing"
+
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+	"go-hep.org/x/hep/xrootd/xrdproto/readv"
+)
+
+func TestRequest(t *testing.T) {
+	for _, want := range []readv.Request{
+		{
+			Chunks: []readv.Chunk{},
+		},
+		{
+			PathID: 2,
+			Chunks: []readv.Chunk{
+				{Handle: [4]byte{1, 2, 3, 4}, Length: 10, Offset: 0},
+				{Handle: [4]byte{1, 2, 3, 4}, Length: 1024, Offset: 4096},
+				{Handle: [4]byte{5, 6, 7, 8}, Length: 3, Offset: 1},
+			},
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			var (
+				err error
+				w   = new(xrdenc.WBuffer)
+				got readv.Request
+			)
+
+			if want.ReqID() != readv.RequestID {
+				t.Fatalf("invalid request ID: got=%d want=%d", want.ReqID(), readv.RequestID)
+			}
+
+			if want.ShouldSign() {
+				t.Fatalf("invalid")
+			}
+
+			err = want.MarshalXrd(w)
+			if err != nil {
+				t.Fatalf("could not marshal request: %v", err)
+			}
+
+			r := xrdenc.NewRBuffer(w.Bytes())
+			err = got.UnmarshalXrd(r)
+			if err != nil {
+				t.Fatalf("could not unmarshal request: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip failed:\ngot = %#v\nwant= %#v\n", got, want)
+			}
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	for _, want := range []readv.Response{
+		{},
+		{
+			Chunks: []readv.Data{
+				{
+					Chunk: readv.Chunk{Handle: [4]byte{1, 2, 3, 4}, Length: 4, Offset: 0},
+					Data:  []byte("1234"),
+				},
+				{
+					Chunk: readv.Chunk{Handle: [4]byte{1, 2, 3, 4}, Length: 0, Offset: 4096},
+					Data:  []byte{},
+				},
+				{
+					Chunk: readv.Chunk{Handle: [4]byte{5, 6, 7, 8}, Length: 5, Offset: 1},
+					Data:  []byte("hello"),
+				},
+			},
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			var (
+				err error
+				w   = new(xrdenc.WBuffer)
+				got readv.Response
+			)
+
+			if want.RespID() != readv.RequestID {
+				t.Fatalf("invalid response ID: got=%d want=%d", want.RespID(), readv.RequestID)
+			}
+
+			err = want.MarshalXrd(w)
+			if err != nil {
+				t.Fatalf("could not marshal response: %v", err)
+			}
+
+			r := xrdenc.NewRBuffer(w.Bytes())
+			err = got.UnmarshalXrd(r)
+			if err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip failed:\ngot = %#v\nwant= %#v\n", got, want)
+			}
+		})
+	}
+}
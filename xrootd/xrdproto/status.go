@@ -0,0 +1,163 @@
+// Copyright ©2018 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xrdproto // import "go-hep.org/x/hep/xrootd/xrdproto"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"go-hep.org/x/hep/xrootd/internal/xrdenc"
+)
+
+// StatusResponseLength is the length of the StatusResponse in bytes.
+const StatusResponseLength = 4 + 2 + 1 + 1 + 4 + 4
+
+// StatusType identifies whether a Status response is final or partial.
+type StatusType uint8
+
+const (
+	FinalResult   StatusType = 0 // FinalResult indicates that the response is the final one.
+	PartialResult StatusType = 1 // PartialResult indicates that more responses will follow.
+)
+
+// StatusResponse is the header that precedes the body of all Status responses.
+//
+// The header is followed by the request-specific response and then by
+// DataLength bytes of data.
+// CRC32C is the CRC32C checksum of the remaining fields of the header and of
+// the request-specific response, not including the trailing data.
+type StatusResponse struct {
+	CRC32C     uint32
+	StreamID   StreamID
+	RequestID  uint16 // RequestID is the id of the request the response is for.
+	Type       StatusType
+	_          [4]uint8
+	DataLength int32
+}
+
+// MarshalXrd implements xrdproto.Marshaler.
+func (o StatusResponse) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	wBuffer.WriteI32(int32(o.CRC32C))
+	wBuffer.WriteBytes(o.StreamID[:])
+	wBuffer.WriteU8(uint8(o.RequestID - 3000))
+	wBuffer.WriteU8(uint8(o.Type))
+	wBuffer.Next(4)
+	wBuffer.WriteI32(o.DataLength)
+	return nil
+}
+
+// UnmarshalXrd implements xrdproto.Unmarshaler.
+func (o *StatusResponse) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	o.CRC32C = uint32(rBuffer.ReadI32())
+	rBuffer.ReadBytes(o.StreamID[:])
+	o.RequestID = 3000 + uint16(rBuffer.ReadU8())
+	o.Type = StatusType(rBuffer.ReadU8())
+	rBuffer.Skip(4)
+	o.DataLength = rBuffer.ReadI32()
+	return nil
+}
+
+// UnmarshalStatus unmarshals the StatusResponse at the beginning of rBuffer
+// and verifies its checksum.
+// reqID is the id of the request the response is expected for.
+func UnmarshalStatus(rBuffer *xrdenc.RBuffer, reqID uint16) (StatusResponse, error) {
+	var status StatusResponse
+	body := rBuffer.Bytes()
+	if len(body) < StatusResponseLength {
+		return status, fmt.Errorf("xrootd: invalid status response length: %d", len(body))
+	}
+	if err := status.UnmarshalXrd(rBuffer); err != nil {
+		return status, err
+	}
+	if status.RequestID != reqID {
+		return status, fmt.Errorf("xrootd: status response for request %d, want %d", status.RequestID, reqID)
+	}
+	end := len(body) - int(status.DataLength)
+	if status.DataLength < 0 || end < StatusResponseLength {
+		return status, fmt.Errorf("xrootd: invalid status data length: %d", status.DataLength)
+	}
+	if crc := Checksum(body[4:end]); crc != status.CRC32C {
+		return status, fmt.Errorf("xrootd: status response checksum mismatch: got=0x%08x want=0x%08x", crc, status.CRC32C)
+	}
+	return status, nil
+}
+
+// sealStatus fills the stream id and the checksum of the marshaled Status response body.
+func sealStatus(body []byte, streamID StreamID) error {
+	if len(body) < StatusResponseLength {
+		return fmt.Errorf("xrootd: invalid status response length: %d", len(body))
+	}
+	copy(body[4:6], streamID[:])
+	dlen := int(int32(binary.BigEndian.Uint32(body[12:16])))
+	end := len(body) - dlen
+	if dlen < 0 || end < StatusResponseLength {
+		return fmt.Errorf("xrootd: invalid status data length: %d", dlen)
+	}
+	binary.BigEndian.PutUint32(body[:4], Checksum(body[4:end]))
+	return nil
+}
+
+// PageSize is the size of the pages transferred by the page read and page write requests.
+const PageSize = 4096
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksum returns the CRC32C checksum of data.
+func Checksum(data []byte) uint32 {
+	return crc32.Checksum(data, castagnoli)
+}
+
+// PagesLength returns the length of n bytes of data, starting at offset off, when
+// transferred as pages.
+func PagesLength(off int64, n int) int {
+	return n + 4*pageCount(off, n)
+}
+
+func pageCount(off int64, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	head := PageSize - int(off%PageSize)
+	if n <= head {
+		return 1
+	}
+	return 1 + (n-head+PageSize-1)/PageSize
+}
+
+// WritePages writes data, starting at offset off of a file, as pages to wBuffer.
+// Pages are aligned on PageSize boundaries of the file and each page is preceded
+// by its CRC32C checksum.
+func WritePages(wBuffer *xrdenc.WBuffer, off int64, data []byte) {
+	for len(data) > 0 {
+		n := min(PageSize-int(off%PageSize), len(data))
+		wBuffer.WriteI32(int32(Checksum(data[:n])))
+		wBuffer.WriteBytes(data[:n])
+		data = data[n:]
+		off += int64(n)
+	}
+}
+
+// ReadPages reads pages of data, starting at offset off of a file, from raw (see WritePages).
+// ReadPages appends the data of all pages to dst and returns the extended slice, along with
+// the file offsets of the pages whose checksum did not match.
+func ReadPages(dst []byte, off int64, raw []byte) ([]byte, []int64, error) {
+	var corrupted []int64
+	for len(raw) > 0 {
+		if len(raw) < 4 {
+			return dst, corrupted, fmt.Errorf("xrootd: invalid page of length %d at offset %d", len(raw), off)
+		}
+		n := min(PageSize-int(off%PageSize), len(raw)-4)
+		crc := binary.BigEndian.Uint32(raw[:4])
+		page := raw[4 : 4+n]
+		if Checksum(page) != crc {
+			corrupted = append(corrupted, off)
+		}
+		dst = append(dst, page...)
+		raw = raw[4+n:]
+		off += int64(n)
+	}
+	return dst, corrupted, nil
+}
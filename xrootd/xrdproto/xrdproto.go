@@ -71,6 +71,9 @@ const (
 	Redirect ResponseStatus = 4004
 	// Wait indicates that the client must wait the indicated number of seconds and retry the request.
 	Wait ResponseStatus = 4005
+	// Status indicates that the response body starts with a checksummed status header,
+	// followed by the request-specific response (see StatusResponse).
+	Status ResponseStatus = 4007
 )
 
 // WaitResponse is the response indicating that the client must wait and retry the request.
@@ -91,6 +94,45 @@ func (o *WaitResponse) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
 	return nil
 }
 
+// RedirectResponse is the response indicating that the client must re-issue the request to another server.
+// See http://xrootd.org/doc/dev45/XRdv310.pdf, p. 33 for details.
+type RedirectResponse struct {
+	Host   string // Host is the name of the server to which the client must connect.
+	Port   int32  // Port is the port of the server to which the client must connect.
+	Opaque string // Opaque is the data that must be added to the file name of the re-issued request.
+	Token  string // Token is the data that must be sent to the new server as part of the login request.
+}
+
+// MarshalXrd implements Marshaler.
+func (o RedirectResponse) MarshalXrd(wBuffer *xrdenc.WBuffer) error {
+	wBuffer.WriteI32(o.Port)
+	url := o.Host
+	if o.Opaque != "" || o.Token != "" {
+		url += "?" + o.Opaque
+	}
+	if o.Token != "" {
+		url += "?" + o.Token
+	}
+	wBuffer.WriteBytes([]byte(url))
+	return nil
+}
+
+// UnmarshalXrd implements Unmarshaler.
+func (o *RedirectResponse) UnmarshalXrd(rBuffer *xrdenc.RBuffer) error {
+	o.Port = rBuffer.ReadI32()
+	parts := strings.SplitN(string(rBuffer.Bytes()), "?", 3)
+	o.Host = parts[0]
+	o.Opaque = ""
+	o.Token = ""
+	if len(parts) > 1 {
+		o.Opaque = parts[1]
+	}
+	if len(parts) > 2 {
+		o.Token = parts[2]
+	}
+	return nil
+}
+
 // ServerError is the error returned by the XRootD server as part of response to the request.
 type ServerError struct {
 	Code    ServerErrorCode
@@ -101,6 +143,8 @@ type ServerError struct {
 type ServerErrorCode int32
 
 const (
+	ArgInvalid     ServerErrorCode = 3000 // ArgInvalid indicates that a request argument is invalid.
+	ArgTooLong     ServerErrorCode = 3002 // ArgTooLong indicates that a request argument exceeds the server limits.
 	InvalidRequest ServerErrorCode = 3006 // InvalidRequest indicates that request is invalid.
 	IOError        ServerErrorCode = 3007 // IOError indicates that an IO error has occurred on the server side.
 	NotAuthorized  ServerErrorCode = 3010 // NotAuthorized indicates that user was not authorized for operation.
@@ -353,6 +397,8 @@ func ReadRequest(r io.Reader) ([]byte, error) {
 // WriteResponse writes a XRootD response resp to the w.
 // The response is directed to the stream with id equal to the streamID.
 // The status is sent as part of response header.
+// For the Status responses, the stream id and the checksum of the status header
+// are filled by WriteResponse.
 // WriteResponse writes all data to the w as single Write call, so no
 // serialization is required.
 func WriteResponse(w io.Writer, streamID StreamID, status ResponseStatus, resp Marshaler) error {
@@ -362,6 +408,11 @@ func WriteResponse(w io.Writer, streamID StreamID, status ResponseStatus, resp M
 			return err
 		}
 	}
+	if status == Status {
+		if err := sealStatus(respWBuffer.Bytes(), streamID); err != nil {
+			return err
+		}
+	}
 
 	header := ResponseHeader{
 		StreamID:   streamID,
@@ -194,6 +194,38 @@ func TestWaitResponse(t *testing.T) {
 	}
 }
 
+func TestRedirectResponse(t *testing.T) {
+	for _, want := range []RedirectResponse{
+		{Host: "example.org", Port: 1094},
+		{Host: "[::1]", Port: 1094, Opaque: "tried=example.org"},
+		{Host: "127.0.0.1", Port: 1095, Opaque: "", Token: "xrd.cc=fr"},
+		{Host: "127.0.0.1", Port: 1095, Opaque: "tried=example.org", Token: "xrd.cc=fr"},
+	} {
+		t.Run("", func(t *testing.T) {
+			var (
+				err error
+				w   = new(xrdenc.WBuffer)
+				got RedirectResponse
+			)
+
+			err = want.MarshalXrd(w)
+			if err != nil {
+				t.Fatalf("could not marshal response: %v", err)
+			}
+
+			r := xrdenc.NewRBuffer(w.Bytes())
+			err = got.UnmarshalXrd(r)
+			if err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip failed\ngot = %#v\nwant= %#v\n", got, want)
+			}
+		})
+	}
+}
+
 func TestServerError(t *testing.T) {
 	for _, want := range []ServerError{
 		{Code: IOError, Message: ""},
@@ -387,3 +419,116 @@ func TestSetOpaque(t *testing.T) {
 		})
 	}
 }
+
+func TestPages(t *testing.T) {
+	data := make([]byte, 3*PageSize+100)
+	_, _ = rand.Read(data)
+
+	for _, tc := range []struct {
+		name  string
+		off   int64
+		n     int
+		pages int
+	}{
+		{name: "Empty", off: 0, n: 0, pages: 0},
+		{name: "Single page", off: 0, n: 10, pages: 1},
+		{name: "Aligned full pages", off: PageSize, n: 2 * PageSize, pages: 2},
+		{name: "Unaligned offset", off: 10, n: PageSize, pages: 2},
+		{name: "Unaligned offset over multiple pages", off: PageSize - 1, n: len(data), pages: 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var w xrdenc.WBuffer
+			WritePages(&w, tc.off, data[:tc.n])
+
+			if got, want := len(w.Bytes()), PagesLength(tc.off, tc.n); got != want {
+				t.Fatalf("invalid pages length: got=%d want=%d", got, want)
+			}
+			if got, want := len(w.Bytes()), tc.n+4*tc.pages; got != want {
+				t.Fatalf("invalid number of pages: got=%d want=%d", (got-tc.n)/4, tc.pages)
+			}
+
+			got, corrupted, err := ReadPages(nil, tc.off, w.Bytes())
+			if err != nil {
+				t.Fatalf("could not read pages: %v", err)
+			}
+			if len(corrupted) != 0 {
+				t.Fatalf("unexpected corrupted pages: %v", corrupted)
+			}
+			if !bytes.Equal(got, data[:tc.n]) {
+				t.Fatalf("pages round trip failed")
+			}
+		})
+	}
+
+	t.Run("Corrupted", func(t *testing.T) {
+		var w xrdenc.WBuffer
+		WritePages(&w, 10, data[:2*PageSize])
+		raw := w.Bytes()
+		raw[len(raw)-1] ^= 0xff
+
+		_, corrupted, err := ReadPages(nil, 10, raw)
+		if err != nil {
+			t.Fatalf("could not read pages: %v", err)
+		}
+		if want := []int64{2 * PageSize}; !reflect.DeepEqual(corrupted, want) {
+			t.Fatalf("invalid corrupted pages:\ngot = %v\nwant = %v", corrupted, want)
+		}
+	})
+}
+
+func TestStatusResponse(t *testing.T) {
+	const reqID = 3030
+	streamID := StreamID{1, 2}
+
+	var w xrdenc.WBuffer
+	status := StatusResponse{RequestID: reqID, Type: FinalResult, DataLength: 3}
+	if err := status.MarshalXrd(&w); err != nil {
+		t.Fatalf("could not marshal status: %v", err)
+	}
+	w.WriteI64(42)
+	w.WriteBytes([]byte{1, 2, 3})
+
+	var buf bytes.Buffer
+	err := WriteResponse(&buf, streamID, Status, marshalerFunc(func(wBuffer *xrdenc.WBuffer) error {
+		wBuffer.WriteBytes(w.Bytes())
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("could not write response: %v", err)
+	}
+
+	hdr, body, err := ReadResponse(&buf)
+	if err != nil {
+		t.Fatalf("could not read response: %v", err)
+	}
+	if hdr.Status != Status {
+		t.Fatalf("invalid status: got=%d want=%d", hdr.Status, Status)
+	}
+
+	got, err := UnmarshalStatus(xrdenc.NewRBuffer(body), reqID)
+	if err != nil {
+		t.Fatalf("could not unmarshal status: %v", err)
+	}
+	if got.StreamID != streamID || got.DataLength != 3 || got.Type != FinalResult {
+		t.Fatalf("invalid status response: %#v", got)
+	}
+
+	// the trailing data is not covered by the status checksum.
+	body[len(body)-1] ^= 0xff
+	if _, err := UnmarshalStatus(xrdenc.NewRBuffer(body), reqID); err != nil {
+		t.Fatalf("could not unmarshal status: %v", err)
+	}
+
+	body[20] ^= 0xff
+	if _, err := UnmarshalStatus(xrdenc.NewRBuffer(body), reqID); err == nil {
+		t.Fatalf("expected a checksum mismatch error")
+	}
+
+	if _, err := UnmarshalStatus(xrdenc.NewRBuffer(body), 3013); err == nil {
+		t.Fatalf("expected a request id mismatch error")
+	}
+}
+
+type marshalerFunc func(wBuffer *xrdenc.WBuffer) error
+
+func (f marshalerFunc) MarshalXrd(wBuffer *xrdenc.WBuffer) error { return f(wBuffer) }
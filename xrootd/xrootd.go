@@ -27,4 +27,39 @@
 //
 //	srv := xrootd.NewServer(xrootd.Default(), nil)
 //	err := srv.Serve(listener)
+//
+// TLS-encrypted connections are created with the WithTLS option of NewClient
+// and the NewTLSServer function:
+//
+//	client, err := xrootd.NewClient(ctx, addr, username, xrootd.WithTLS(nil))
+//
+//	srv := xrootd.NewTLSServer(xrootd.Default(), nil, &tls.Config{Certificates: certs})
+//
+// Servers may require the clients to authenticate with the WithVerifier option,
+// e.g. using shared-secret (sss) keytabs or bearer tokens (ztn):
+//
+//	srv := xrootd.NewServer(xrootd.Default(), nil, xrootd.WithVerifier(&sss.Verifier{Keytab: kt}))
+//
+//	client, err := xrootd.NewClient(ctx, addr, username, xrootd.WithAuth(&sss.Auth{Key: key}))
+//
+// The NewRedirector function creates a handler redirecting the clients to a set of data servers,
+// registered locally or remotely with the RegisterServer function, provided the
+// redirector requires a shared secret or an allow-list for remote registrations:
+//
+//	redir := xrootd.NewRedirector(xrootd.WithRegistrationSecret(secret))
+//	err := redir.Register("dataserver1:1094")
+//	srv := xrootd.NewServer(redir, nil)
+//
+//	cli, err := xrootd.RegisterServer(ctx, "redirector:1094", "dataserver2:1094", secret)
+//
+// Requests may be issued asynchronously with the Go function, the requests in flight
+// on the same connection being pipelined and bounded by a Pipeline:
+//
+//	p := xrootd.NewPipeline(16)
+//	fut := xrootd.Go(ctx, p, func(ctx context.Context) (int, error) {
+//		return f.ReadAtContext(ctx, buf, off)
+//	})
+//	n, err := fut.Wait()
+//
+//	n, err = p.ReadAt(ctx, f, buf, off, 1<<20) // concurrent reads of 1 MiB chunks.
 package xrootd // import "go-hep.org/x/hep/xrootd"